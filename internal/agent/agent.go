@@ -0,0 +1,107 @@
+// Package agent implements the sstart agent: a long-running process that keeps SSO sessions,
+// provider clients, and collected secrets in memory and serves them over a unix socket.
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SocketEnv overrides the agent socket path
+	SocketEnv = "SSTART_AGENT_SOCK"
+	// DisableEnv disables the use of a running agent when set to "1" or "true", like --no-agent
+	DisableEnv = "SSTART_NO_AGENT"
+	// tokenSuffix is appended to the socket path to get the path of the file holding the auth token
+	tokenSuffix = ".token"
+)
+
+// Operations understood by the agent
+const (
+	OpCollect = "collect" // Collect secrets, like sstart env
+	OpGet     = "get"     // Collect secrets and return a single key
+	OpStatus  = "status"  // Report the agent status
+	OpStop    = "stop"    // Stop the agent
+)
+
+// Request is sent by a client, as a single JSON document per connection
+type Request struct {
+	// Token authenticates the client; it is read from the token file only the agent's user can read
+	Token string `json:"token"`
+	Op    string `json:"op"`
+	// Config is the absolute path of the project config file (empty for the global config alone)
+	Config string `json:"config,omitempty"`
+	// GlobalConfig is the global config file layered under Config
+	GlobalConfig string `json:"global_config,omitempty"`
+	// VerifyKey is the public key the client verified Config with; the agent verifies it again
+	// when it loads the file, so that it never uses a file changed after the client's check
+	VerifyKey string   `json:"verify_key,omitempty"`
+	Profile   string   `json:"profile,omitempty"`
+	Providers []string `json:"providers,omitempty"`
+	// Key is the secret returned by OpGet
+	Key string `json:"key,omitempty"`
+	// Dir and Env are the client's working directory and environment, which templates and env
+	// providers are resolved against instead of the agent's
+	Dir string   `json:"dir,omitempty"`
+	Env []string `json:"env,omitempty"`
+}
+
+// Response is the agent's reply to a Request
+type Response struct {
+	Error string `json:"error,omitempty"`
+	// ErrorType is the type of Error (config, auth or provider; empty for other errors)
+	ErrorType string `json:"error_type,omitempty"`
+	// Provider, Class and Hint describe a provider error: the provider that failed, the class
+	// of the error, and how to fix it
+	Provider string            `json:"provider,omitempty"`
+	Class    string            `json:"class,omitempty"`
+	Hint     string            `json:"hint,omitempty"`
+	Secrets  map[string]string `json:"secrets,omitempty"`
+	// Sources maps each key to the ID of the provider it came from
+	Sources map[string]string `json:"sources,omitempty"`
+	Status  *Status           `json:"status,omitempty"`
+}
+
+// Status describes a running agent
+type Status struct {
+	PID int `json:"pid"`
+	// Sessions is the number of config files (and profiles) the agent holds collectors for
+	Sessions int    `json:"sessions"`
+	TTL      string `json:"ttl"`
+}
+
+// DefaultSocketPath returns the agent socket: $SSTART_AGENT_SOCK, or agent.sock in
+// $XDG_RUNTIME_DIR/sstart, falling back to a per-user directory in the temp directory
+func DefaultSocketPath() string {
+	if path := os.Getenv(SocketEnv); path != "" {
+		return path
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "sstart", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sstart-%d", os.Getuid()), "agent.sock")
+}
+
+// Disabled reports whether $SSTART_NO_AGENT disables the use of a running agent
+func Disabled() bool {
+	value := strings.ToLower(os.Getenv(DisableEnv))
+	return value == "1" || value == "true"
+}
+
+// tokenPath returns the path of the auth token file of the agent listening on socketPath
+func tokenPath(socketPath string) string {
+	return socketPath + tokenSuffix
+}
+
+// newToken returns a random auth token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate agent token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
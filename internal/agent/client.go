@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// dialTimeout bounds connecting to the agent, so a hung agent falls back to collecting locally quickly
+const dialTimeout = time.Second
+
+// Client sends requests to a running agent
+type Client struct {
+	socketPath   string
+	globalConfig string
+}
+
+// NewClient returns a client for the agent listening on socketPath (DefaultSocketPath if empty)
+// globalConfig is the global config file the agent layers under the project config
+func NewClient(socketPath, globalConfig string) *Client {
+	if socketPath == "" {
+		socketPath = DefaultSocketPath()
+	}
+	return &Client{socketPath: socketPath, globalConfig: globalConfig}
+}
+
+// Collect implements secrets.Agent, collecting the secrets of cfg in the agent
+// It returns secrets.ErrAgentUnavailable when no agent is running
+func (c *Client) Collect(ctx context.Context, cfg *config.Config, providerIDs []string) (provider.Secrets, map[string]string, error) {
+	resp, err := c.Do(ctx, &Request{Op: OpCollect, Config: cfg.Path, GlobalConfig: c.globalConfig, VerifyKey: cfg.VerifyKey, Profile: cfg.Profile, Providers: providerIDs})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Secrets, resp.Sources, nil
+}
+
+// Get returns a single secret of cfg from the agent
+func (c *Client) Get(ctx context.Context, cfg *config.Config, providerIDs []string, key string) (string, error) {
+	resp, err := c.Do(ctx, &Request{Op: OpGet, Config: cfg.Path, GlobalConfig: c.globalConfig, VerifyKey: cfg.VerifyKey, Profile: cfg.Profile, Providers: providerIDs, Key: key})
+	if err != nil {
+		return "", err
+	}
+	return resp.Secrets[key], nil
+}
+
+// Status returns the status of the running agent
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	resp, err := c.Do(ctx, &Request{Op: OpStatus})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// Stop asks the running agent to exit
+func (c *Client) Stop(ctx context.Context) error {
+	_, err := c.Do(ctx, &Request{Op: OpStop})
+	return err
+}
+
+// Do sends req, authenticated with the agent's token, and returns the response
+// Errors reported by the agent are returned as errors of the same type as a local collection's
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	token, err := os.ReadFile(tokenPath(c.socketPath))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", secrets.ErrAgentUnavailable, err)
+	}
+	req.Token = strings.TrimSpace(string(token))
+	if req.Op == OpCollect || req.Op == OpGet {
+		if req.Dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		req.Env = os.Environ()
+	}
+
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", secrets.ErrAgentUnavailable, err)
+	}
+	defer conn.Close()
+
+	// Unblock reads when ctx is cancelled, e.g. on Ctrl+C while the agent logs in
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send agent request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, resp.err()
+	}
+	return &resp, nil
+}
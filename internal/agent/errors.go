@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"errors"
+
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// Types of the errors reported by the agent, so that clients exit like a local collection would
+const (
+	errorTypeConfig   = "config"   // the config could not be loaded (ConfigError)
+	errorTypeAuth     = "auth"     // SSO authentication failed (secrets.AuthError)
+	errorTypeProvider = "provider" // a provider failed to fetch its secrets (secrets.FetchError)
+)
+
+// errorClasses name the classes of provider errors in responses
+var errorClasses = map[string]error{
+	"auth":         provider.ErrAuth,
+	"not_found":    provider.ErrNotFound,
+	"rate_limited": provider.ErrRateLimited,
+	"network":      provider.ErrNetwork,
+}
+
+// ConfigError is returned when the agent cannot load the config of a request
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string { return e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// remoteError is an error reported by the agent, with the hint of the provider error it came from
+type remoteError struct {
+	msg  string
+	hint string
+}
+
+func (e *remoteError) Error() string { return e.msg }
+
+// Hint implements the hint looked up by provider.ErrorHint
+func (e *remoteError) Hint() string { return e.hint }
+
+// errorResponse returns the response reporting err, with its type and provider details
+func errorResponse(err error) *Response {
+	resp := &Response{Error: err.Error()}
+	var configErr *ConfigError
+	var authErr *secrets.AuthError
+	var fetchErr *secrets.FetchError
+	switch {
+	case errors.As(err, &configErr):
+		resp.ErrorType = errorTypeConfig
+	case errors.As(err, &authErr):
+		resp.ErrorType = errorTypeAuth
+	case errors.As(err, &fetchErr):
+		resp.ErrorType = errorTypeProvider
+		resp.Provider = fetchErr.Provider
+		resp.Hint = provider.ErrorHint(err)
+		class := fetchErr.Class()
+		for name, c := range errorClasses {
+			if class == c {
+				resp.Class = name
+			}
+		}
+	}
+	return resp
+}
+
+// err rebuilds the error reported in the response, typed like the error of a local collection
+func (r *Response) err() error {
+	err := error(&remoteError{msg: "agent: " + r.Error, hint: r.Hint})
+	switch r.ErrorType {
+	case errorTypeConfig:
+		return &ConfigError{Err: err}
+	case errorTypeAuth:
+		return &secrets.AuthError{Err: err}
+	case errorTypeProvider:
+		return &secrets.FetchError{Provider: r.Provider, Err: provider.Classify(errorClasses[r.Class], err)}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,470 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/peercred"
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// DefaultTTL is how long collected secrets are served from memory before they are collected again
+const DefaultTTL = 5 * time.Minute
+
+// DefaultWarmIdle is how long collections are kept warm after they were last requested
+const DefaultWarmIdle = 30 * time.Minute
+
+// warmSpacing is the minimum time between two refreshes of the warming loop, so that warming
+// many collections does not burst requests to the providers
+const warmSpacing = time.Second
+
+// sessionIdle is how long a session is kept after it was last requested (at least the warming
+// idle time plus the TTL, so that warmed collections are not dropped)
+const sessionIdle = time.Hour
+
+// Server serves collect requests over a unix socket
+type Server struct {
+	socketPath string
+	ttl        time.Duration
+	// warmIdle is how long collections are refreshed before they expire after their last request
+	// (0 disables warming)
+	warmIdle time.Duration
+	logger   *slog.Logger
+	// collectorOpts are applied to the collector created for each config file
+	collectorOpts []secrets.CollectorOption
+
+	token    string
+	listener net.Listener
+	stop     context.CancelFunc
+
+	mu sync.Mutex
+	// configs holds the loaded configs by config file, global config and profile, one for each
+	// outcome of the 'enabled' conditions of providers in the environments of clients
+	configs  map[string][]*loadedConfig
+	sessions map[string]*session
+}
+
+// loadedConfig is a config loaded by the agent, with the modification times of the files it was
+// read from so that it is loaded again when one of them changes
+type loadedConfig struct {
+	cfg      *config.Config
+	modTimes map[string]time.Time
+	envUsage config.EnvUsage
+	// enabledEnv holds the values of the variables read by 'enabled' conditions when the config
+	// was loaded, which clients must share to use it
+	enabledEnv map[string]string
+}
+
+// session holds the collector of a config file and profile, in a working directory and the
+// environment variables the config reads, and the secrets it collected
+type session struct {
+	mu        sync.Mutex
+	loaded    *loadedConfig
+	collector *secrets.Collector
+	results   map[string]*result
+	// lastUsed is when the session was last requested (guarded by Server.mu)
+	lastUsed time.Time
+}
+
+// result is a collection kept in memory until it expires
+type result struct {
+	providers []string
+	secrets   provider.Secrets
+	sources   map[string]string
+	expiresAt time.Time
+	// lastUsed is when the collection was last requested
+	lastUsed time.Time
+	// retryAt delays warming after a failed refresh
+	retryAt time.Time
+	backoff time.Duration
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithTTL sets how long collected secrets are served from memory (DefaultTTL by default)
+func WithTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		s.ttl = ttl
+	}
+}
+
+// WithWarming refreshes collections shortly before they expire, as long as they were requested
+// within idle, so that commands keep finding them in memory. 0 disables warming
+func WithWarming(idle time.Duration) ServerOption {
+	return func(s *Server) {
+		s.warmIdle = idle
+	}
+}
+
+// WithLogger sets the logger for request and error logging
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithCollectorOptions sets options applied to every collector the agent creates
+func WithCollectorOptions(opts ...secrets.CollectorOption) ServerOption {
+	return func(s *Server) {
+		s.collectorOpts = opts
+	}
+}
+
+// NewServer creates an agent listening on socketPath (DefaultSocketPath if empty)
+func NewServer(socketPath string, opts ...ServerOption) *Server {
+	if socketPath == "" {
+		socketPath = DefaultSocketPath()
+	}
+	s := &Server{
+		socketPath: socketPath,
+		ttl:        DefaultTTL,
+		warmIdle:   DefaultWarmIdle,
+		logger:     slog.New(slog.DiscardHandler),
+		configs:    make(map[string][]*loadedConfig),
+		sessions:   make(map[string]*session),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SocketPath returns the path of the unix socket the agent listens on
+func (s *Server) SocketPath() string {
+	return s.socketPath
+}
+
+// Serve listens on the socket and serves requests until ctx is cancelled or a stop request is received
+// Only the user running the agent can connect: the socket directory is 0700, and every request must
+// carry the token written to a 0600 file next to the socket
+func (s *Server) Serve(ctx context.Context) error {
+	if err := s.listen(); err != nil {
+		return err
+	}
+	defer s.cleanup()
+
+	ctx, s.stop = context.WithCancel(ctx)
+	defer s.stop()
+	go func() {
+		<-ctx.Done()
+		_ = s.listener.Close()
+	}()
+	if s.warmIdle > 0 {
+		go s.warm(ctx)
+	}
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("agent: failed to accept connection: %w", err)
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+// listen creates the socket and the token file, replacing a stale socket left by an agent that exited
+func (s *Server) listen() error {
+	dir := filepath.Dir(s.socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("agent: failed to create socket directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("agent: failed to secure socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(s.socketPath); err == nil {
+		if conn, err := net.DialTimeout("unix", s.socketPath, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("agent: an agent is already running on %s", s.socketPath)
+		}
+		if err := os.Remove(s.socketPath); err != nil {
+			return fmt.Errorf("agent: failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("agent: failed to listen on %s: %w", s.socketPath, err)
+	}
+	s.listener = listener
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		s.cleanup()
+		return fmt.Errorf("agent: failed to secure socket: %w", err)
+	}
+
+	// The token file is written last, so clients only find it once the agent accepts connections
+	token, err := newToken()
+	if err == nil {
+		s.token = token
+		err = os.WriteFile(tokenPath(s.socketPath), []byte(token+"\n"), 0600)
+	}
+	if err != nil {
+		s.cleanup()
+		return fmt.Errorf("agent: failed to write token file: %w", err)
+	}
+	return nil
+}
+
+// cleanup removes the socket and token file
+func (s *Server) cleanup() {
+	_ = s.listener.Close()
+	_ = os.Remove(s.socketPath)
+	_ = os.Remove(tokenPath(s.socketPath))
+}
+
+// handle serves a single request
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	if err := peercred.Check(conn); err != nil {
+		s.logger.Warn("agent connection rejected", "error", err)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		s.logger.Debug("invalid agent request", "error", err)
+		return
+	}
+
+	resp := s.dispatch(ctx, &req)
+	if resp.Error != "" {
+		s.logger.Warn("agent request failed", "op", req.Op, "config", req.Config, "error", resp.Error)
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// dispatch authenticates a request and runs its operation
+func (s *Server) dispatch(ctx context.Context, req *Request) *Response {
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.token)) != 1 {
+		return &Response{Error: "invalid agent token"}
+	}
+
+	switch req.Op {
+	case OpStatus:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return &Response{Status: &Status{PID: os.Getpid(), Sessions: len(s.sessions), TTL: s.ttl.String()}}
+	case OpStop:
+		s.stop()
+		return &Response{}
+	case OpCollect, OpGet:
+		collected, sources, err := s.collect(ctx, req)
+		if err != nil {
+			return errorResponse(err)
+		}
+		if req.Op == OpGet {
+			value, ok := collected[req.Key]
+			if !ok {
+				return &Response{Error: fmt.Sprintf("secret '%s' not found", req.Key)}
+			}
+			return &Response{Secrets: map[string]string{req.Key: value}, Sources: map[string]string{req.Key: sources[req.Key]}}
+		}
+		return &Response{Secrets: collected, Sources: sources}
+	default:
+		return &Response{Error: fmt.Sprintf("unknown agent operation '%s'", req.Op)}
+	}
+}
+
+// collect returns the secrets of the requested config, from memory while they have not expired
+func (s *Server) collect(ctx context.Context, req *Request) (provider.Secrets, map[string]string, error) {
+	sess, err := s.session(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	resultKey := strings.Join(req.Providers, ",")
+	if r, ok := sess.results[resultKey]; ok && time.Now().Before(r.expiresAt) {
+		r.lastUsed = time.Now()
+		return r.secrets, r.sources, nil
+	}
+
+	collected, sources, err := sess.collector.CollectWithSources(ctx, req.Providers)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	sess.results[resultKey] = &result{providers: req.Providers, secrets: collected, sources: sources, expiresAt: now.Add(s.ttl), lastUsed: now}
+	return collected, sources, nil
+}
+
+// session returns the session of the requested config and profile, in the client's directory and
+// with the values of the environment variables the config reads. The config is loaded again,
+// dropping collected secrets, when one of the files it was read from changed
+func (s *Server) session(req *Request) (*session, error) {
+	if req.Config != "" {
+		if _, err := os.Stat(req.Config); err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("failed to read config file: %w", err)}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictIdle(now)
+
+	configKey := strings.Join([]string{req.Config, req.GlobalConfig, req.Profile, req.VerifyKey}, "\x00")
+	clientEnv := &config.Environment{Dir: req.Dir, Env: req.Env}
+	i := slices.IndexFunc(s.configs[configKey], func(l *loadedConfig) bool { return l.matches(clientEnv) })
+	var loaded *loadedConfig
+	if i >= 0 {
+		loaded = s.configs[configKey][i]
+	}
+	if loaded == nil || loaded.changed() {
+		if loaded != nil {
+			// Drop the outdated config and its sessions with their collected secrets
+			s.configs[configKey] = slices.Delete(s.configs[configKey], i, i+1)
+			for key, sess := range s.sessions {
+				if sess.loaded == loaded {
+					delete(s.sessions, key)
+				}
+			}
+		}
+		// 'enabled' conditions are evaluated in the client's environment, like a local run
+		opts := []config.LoadOption{config.WithGlobalConfig(req.GlobalConfig), config.WithProfile(req.Profile), config.WithEnvironment(clientEnv)}
+		if req.VerifyKey != "" {
+			opts = append(opts, config.WithVerifyKey(req.VerifyKey))
+		}
+		cfg, err := config.Load(req.Config, opts...)
+		if err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("failed to load config: %w", err)}
+		}
+		// A global config created later changes the config too
+		files := cfg.Files
+		if req.GlobalConfig != "" && !slices.Contains(files, req.GlobalConfig) {
+			files = append(slices.Clone(files), req.GlobalConfig)
+		}
+		enabledEnv := make(map[string]string, len(cfg.EnabledEnv))
+		for _, name := range cfg.EnabledEnv {
+			enabledEnv[name] = clientEnv.Getenv(name)
+		}
+		loaded = &loadedConfig{cfg: cfg, modTimes: modTimes(files), envUsage: cfg.EnvUsage(), enabledEnv: enabledEnv}
+		s.configs[configKey] = append(s.configs[configKey], loaded)
+		s.logger.Debug("agent loaded config", "config", req.Config, "profile", req.Profile)
+	}
+
+	// Clients in other directories, or with other values of the variables the config reads,
+	// may resolve the same config differently
+	env := usedEnv(loaded.envUsage, req.Env)
+	sorted := slices.Clone(env)
+	slices.Sort(sorted)
+	envHash := sha256.Sum256([]byte(strings.Join(sorted, "\x00")))
+	key := strings.Join([]string{configKey, req.Dir, string(envHash[:])}, "\x00")
+	if sess, ok := s.sessions[key]; ok && sess.loaded == loaded {
+		sess.lastUsed = now
+		return sess, nil
+	}
+
+	sess := &session{
+		loaded:    loaded,
+		collector: secrets.NewCollector(loaded.cfg, append(slices.Clone(s.collectorOpts), secrets.WithEnvironment(&config.Environment{Dir: req.Dir, Env: env}))...),
+		results:   make(map[string]*result),
+		lastUsed:  now,
+	}
+	s.sessions[key] = sess
+	return sess, nil
+}
+
+// evictIdle drops the sessions that were not requested for a while, and the configs no session
+// uses anymore. s.mu must be held
+func (s *Server) evictIdle(now time.Time) {
+	idle := max(sessionIdle, s.warmIdle+s.ttl)
+	used := make(map[*loadedConfig]bool)
+	for key, sess := range s.sessions {
+		if now.Sub(sess.lastUsed) > idle {
+			delete(s.sessions, key)
+			continue
+		}
+		used[sess.loaded] = true
+	}
+	for key, configs := range s.configs {
+		configs = slices.DeleteFunc(configs, func(l *loadedConfig) bool { return !used[l] })
+		if len(configs) == 0 {
+			delete(s.configs, key)
+		} else {
+			s.configs[key] = configs
+		}
+	}
+}
+
+// matches reports whether the 'enabled' conditions of the config evaluate in env like they did
+// when it was loaded, since they read the same values
+func (l *loadedConfig) matches(env *config.Environment) bool {
+	for name, value := range l.enabledEnv {
+		if env.Getenv(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// changed reports whether one of the files the config was read from changed since it was loaded
+func (l *loadedConfig) changed() bool {
+	for file, modTime := range l.modTimes {
+		var current time.Time
+		if info, err := os.Stat(file); err == nil {
+			current = info.ModTime()
+		}
+		if !current.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// modTimes returns the modification times of files (zero for files that do not exist)
+func modTimes(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		times[file] = modTime
+	}
+	return times
+}
+
+// usedEnv returns the entries of env that a config with the given usage reads (all of them but
+// config.VolatileEnv if it may read any variable)
+func usedEnv(usage config.EnvUsage, env []string) []string {
+	if usage.All {
+		return slices.DeleteFunc(slices.Clone(env), func(entry string) bool {
+			name, _, _ := strings.Cut(entry, "=")
+			return slices.Contains(config.VolatileEnv, name)
+		})
+	}
+	used := make([]string, 0, len(usage.Names))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if slices.Contains(usage.Names, name) || slices.ContainsFunc(usage.Patterns, func(pattern string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}) {
+			used = append(used, entry)
+		}
+	}
+	return used
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// warmTarget is a collection due for a refresh
+type warmTarget struct {
+	sess *session
+	key  string
+}
+
+// warm refreshes collections shortly before they expire until ctx is done. Collections are
+// refreshed one at a time, at least warmSpacing apart, and a failed refresh is retried with an
+// exponential backoff while the old secrets are served until they expire
+func (s *Server) warm(ctx context.Context) {
+	// Refresh during the last fifth of the TTL, checking twice within that window
+	window := s.ttl / 5
+	ticker := time.NewTicker(max(window/2, 100*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for i, target := range s.warmTargets(window) {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(warmSpacing):
+				}
+			}
+			s.refresh(ctx, target, window)
+		}
+	}
+}
+
+// warmTargets returns the collections that expire within window and were requested within the
+// warming idle time
+func (s *Server) warmTargets(window time.Duration) []warmTarget {
+	s.mu.Lock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	var targets []warmTarget
+	for _, sess := range sessions {
+		sess.mu.Lock()
+		for key, r := range sess.results {
+			if now.Sub(r.lastUsed) > s.warmIdle {
+				// Nobody asked for these secrets for a while; let them expire
+				if now.After(r.expiresAt) {
+					delete(sess.results, key)
+				}
+				continue
+			}
+			if now.After(r.expiresAt.Add(-window)) && !now.Before(r.retryAt) {
+				targets = append(targets, warmTarget{sess: sess, key: key})
+			}
+		}
+		sess.mu.Unlock()
+	}
+	return targets
+}
+
+// refresh collects the secrets of a collection again, bypassing the secret cache
+func (s *Server) refresh(ctx context.Context, target warmTarget, window time.Duration) {
+	sess := target.sess
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	r, ok := sess.results[target.key]
+	if !ok {
+		return
+	}
+	collected, sources, err := sess.collector.RefreshWithSources(ctx, r.providers)
+	now := time.Now()
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		r.backoff = min(max(2*r.backoff, window/2), s.ttl)
+		r.retryAt = now.Add(r.backoff)
+		s.logger.Warn("agent failed to refresh secrets, keeping the current ones until they expire", "providers", r.providers, "retry_in", r.backoff, "error", err)
+		return
+	}
+	s.logger.Debug("agent refreshed secrets before they expire", "providers", r.providers)
+	sess.results[target.key] = &result{
+		providers: r.providers,
+		secrets:   collected,
+		sources:   sources,
+		expiresAt: now.Add(s.ttl),
+		lastUsed:  r.lastUsed,
+	}
+}
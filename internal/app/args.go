@@ -0,0 +1,177 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// hyphenatedRef matches references to secrets of providers whose IDs contain hyphens, such as
+// .vault-db.PG_DSN, which Go templates cannot parse as fields
+var hyphenatedRef = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*(?:-[A-Za-z0-9_]+)+)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// SecretRefs holds the secrets that templates can reference, by provider ID then key, like the
+// Go templates of template providers: {{ .provider_id.KEY }} is the value of KEY from the provider
+type SecretRefs map[string]map[string]string
+
+// NewSecretRefs returns the secrets that templates can reference, given the secrets and the
+// provider each one came from
+func NewSecretRefs(envSecrets, sources map[string]string) SecretRefs {
+	refs := make(SecretRefs)
+	for key, value := range envSecrets {
+		providerID := sources[key]
+		if refs[providerID] == nil {
+			refs[providerID] = make(map[string]string)
+		}
+		refs[providerID][key] = value
+	}
+	return refs
+}
+
+// Render renders the references to secrets in text. The name identifies the text in errors,
+// which name the reference but never a value
+func (refs SecretRefs) Render(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	// secret looks up references rewritten from hyphenated provider IDs, failing like fields do
+	secret := func(providerID, key string) (string, error) {
+		value, ok := refs[providerID][key]
+		if !ok {
+			return "", fmt.Errorf("no secret %s from provider '%s'", key, providerID)
+		}
+		return value, nil
+	}
+
+	text = rewriteActions(text, func(action string) string {
+		return hyphenatedRef.ReplaceAllString(action, `(secret "$1" "$2")`)
+	})
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"secret": secret}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template in %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]map[string]string(refs)); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// renderArgs renders references to secrets in the arguments of command (see SecretRefs). The
+// command is returned as is unless argument templating is enabled
+func (r *Runner) renderArgs(command []string, envSecrets, sources map[string]string) ([]string, error) {
+	if !r.templateArgs {
+		return command, nil
+	}
+
+	refs := NewSecretRefs(envSecrets, sources)
+	rendered := make([]string, len(command))
+	for i, arg := range command {
+		var err error
+		if rendered[i], err = refs.Render(fmt.Sprintf("argument %d", i), arg); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// renderShellCommand returns the command running command with the shell, with references to
+// secrets rendered when argument templating is enabled. Each reference is rendered on its own and
+// passed to the shell as a positional parameter that the command expands, so that secrets are
+// never parsed as shell syntax
+func (r *Runner) renderShellCommand(command string, envSecrets, sources map[string]string) ([]string, error) {
+	if !r.templateArgs || !strings.Contains(command, "{{") {
+		return shellCommand(command), nil
+	}
+
+	refs := NewSecretRefs(envSecrets, sources)
+	var script strings.Builder
+	var values []string
+	var quote byte
+	for {
+		start := strings.Index(command, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(command[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+		quote = shellQuote(quote, command[:start])
+		script.WriteString(command[:start])
+		value, err := refs.Render("command", command[start:end])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		script.WriteString(shellParam(len(values), quote))
+		command = command[end:]
+	}
+	script.WriteString(command)
+
+	args, ok := shellCommandWithArgs(script.String(), values)
+	if !ok {
+		return nil, fmt.Errorf("secrets cannot be rendered into commands run with the shell on this platform; give the command's arguments separately")
+	}
+	return args, nil
+}
+
+// shellQuote returns the quote that text leaves open in a POSIX shell command, given the quote
+// open before it: 0 for none, or a single or double quote
+func shellQuote(quote byte, text string) byte {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++ // the next character is escaped
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return quote
+}
+
+// shellParam returns the expansion of positional parameter n as a single word, within the quote
+// open where it is inserted
+func shellParam(n int, quote byte) string {
+	switch quote {
+	case '\'':
+		return fmt.Sprintf(`'"${%d}"'`, n)
+	case '"':
+		return fmt.Sprintf("${%d}", n)
+	default:
+		return fmt.Sprintf(`"${%d}"`, n)
+	}
+}
+
+// rewriteActions applies replace to the {{ ... }} actions of text, leaving the rest untouched
+func rewriteActions(text string, replace func(action string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			break
+		}
+		end += start + 2
+		b.WriteString(text[:start])
+		b.WriteString(replace(text[start:end]))
+		text = text[end:]
+	}
+	b.WriteString(text)
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/dirathea/sstart/internal/config"
+)
+
+const (
+	preRunHook  = "pre_run"
+	postRunHook = "post_run"
+)
+
+// WithRunHooks returns an option that runs the pre_run hook commands of cfg before the command is
+// started, e.g. database migrations, and the post_run commands after it exits, e.g. to clean up
+// temporary credentials. They fail unless the config file is trusted (see config.Config.CheckHooksTrusted)
+func WithRunHooks(cfg *config.Config) RunnerOption {
+	return func(r *Runner) {
+		if cfg.Hooks != nil {
+			r.hooksTrusted = cfg.CheckHooksTrusted
+			r.preRunHooks = cfg.Hooks.PreRun
+			r.postRunHooks = cfg.Hooks.PostRun
+		}
+	}
+}
+
+// preRun runs the pre_run commands with the secrets the command is started with
+func (r *Runner) preRun(ctx context.Context, envSecrets map[string]string) error {
+	return r.runHooks(ctx, preRunHook, r.preRunHooks, envSecrets)
+}
+
+// postRun runs the post_run commands with the secrets the command ran with, and its exit code in
+// SSTART_EXIT_CODE
+func (r *Runner) postRun(ctx context.Context, envSecrets map[string]string, waitErr error) error {
+	code := 0
+	var exitError *exec.ExitError
+	if errors.As(waitErr, &exitError) {
+		code = exitCode(exitError)
+	} else if waitErr != nil {
+		code = 1
+	}
+	return r.runHooks(ctx, postRunHook, r.postRunHooks, envSecrets, fmt.Sprintf("SSTART_EXIT_CODE=%d", code))
+}
+
+// runHooks runs hook commands in order, with the environment of the command plus SSTART_HOOK, and
+// stops at the first one that fails. Their output goes to stderr, leaving stdout to the command
+func (r *Runner) runHooks(ctx context.Context, event string, commands []config.HookCommand, envSecrets map[string]string, env ...string) error {
+	if len(commands) > 0 {
+		if err := r.hooksTrusted(); err != nil {
+			return err
+		}
+	}
+	for _, command := range commands {
+		cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+		cmd.Env = append(append(r.environ(envSecrets), "SSTART_HOOK="+event), env...)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook: command '%s' failed: %w", event, command.Command, err)
+		}
+	}
+	return nil
+}
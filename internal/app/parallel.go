@@ -0,0 +1,161 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+)
+
+// RunParallel runs several commands at the same time with the secrets of one collection. The
+// lines each command writes are prefixed with "[n] ", n being its position starting at 1, and a
+// command given as a single argument is run with the shell. It waits
+// for all commands and returns an ExitError with the exit code of the first command that failed
+func (r *Runner) RunParallel(ctx context.Context, providerIDs []string, commands [][]string) error {
+	if r.watch {
+		return fmt.Errorf("watch mode cannot run commands in parallel")
+	}
+	if len(commands) == 0 {
+		return fmt.Errorf("no command specified")
+	}
+	envSecrets, sources, err := r.collect(ctx, providerIDs)
+	if err != nil {
+		return err
+	}
+	env := r.environ(envSecrets)
+
+	// Render all commands before starting any
+	rendered := make([][]string, len(commands))
+	for i, command := range commands {
+		switch len(command) {
+		case 0:
+			return fmt.Errorf("command %d is empty", i+1)
+		case 1:
+			rendered[i], err = r.renderShellCommand(command[0], envSecrets, sources)
+		default:
+			rendered[i], err = r.renderArgs(command, envSecrets, sources)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.preRun(ctx, envSecrets); err != nil {
+		return err
+	}
+
+	var outMu sync.Mutex
+	cmds := make([]*exec.Cmd, 0, len(commands))
+	outputs := make([]*prefixWriter, 0, 2*len(commands))
+	for i, command := range rendered {
+		prefix := fmt.Sprintf("[%d] ", i+1)
+		stdout := &prefixWriter{w: os.Stdout, mu: &outMu, prefix: prefix}
+		stderr := &prefixWriter{w: os.Stderr, mu: &outMu, prefix: prefix}
+		outputs = append(outputs, stdout, stderr)
+
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Env = env
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		setProcessGroup(cmd)
+		if err := cmd.Start(); err != nil {
+			for _, started := range cmds {
+				_ = started.Process.Kill()
+				_ = started.Wait()
+			}
+			return fmt.Errorf("failed to start command %d: %w", i+1, err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	// Forward signals to every command
+	sigChan := make(chan os.Signal, 1)
+	registerSignals(sigChan)
+	go func() {
+		for sig := range sigChan {
+			for _, cmd := range cmds {
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	// Record the commands in the order they fail
+	var failMu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for _, cmd := range cmds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cmd.Wait(); err != nil {
+				failMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				failMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, output := range outputs {
+		output.flush()
+	}
+
+	// post_run commands run once all commands have exited, with the exit code of the first that failed
+	postErr := r.postRun(ctx, envSecrets, firstErr)
+	signal.Stop(sigChan)
+	close(sigChan)
+
+	if firstErr != nil && postErr != nil {
+		fmt.Fprintf(os.Stderr, "sstart: %v\n", postErr)
+	}
+	var exitError *exec.ExitError
+	if errors.As(firstErr, &exitError) {
+		return &ExitError{Code: exitCode(exitError)}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return postErr
+}
+
+// prefixWriter writes complete lines to w with a prefix. Writers sharing mu never interleave
+// parts of lines
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// flush writes the last line if it does not end with a newline
+func (p *prefixWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+		p.buf = nil
+	}
+}
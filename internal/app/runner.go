@@ -3,44 +3,155 @@ package app
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync"
+	"time"
 
+	"github.com/dirathea/sstart/internal/config"
 	"github.com/dirathea/sstart/internal/secrets"
 )
 
+// OverrideSource is the source of the values injected with WithOverrides
+const OverrideSource = "override"
+
+// stopTimeout is how long a command restarted by watch mode has to exit before it is killed
+const stopTimeout = 10 * time.Second
+
 // Runner executes subprocesses with injected secrets
 type Runner struct {
 	collector *secrets.Collector
 	inherit   bool
+	only      []string
+	exclude   []string
+	// watch restarts the command when the secrets change
+	watch bool
+	// pollInterval is how often providers that cannot be watched are fetched again in watch mode
+	pollInterval time.Duration
+	// secrets are injected instead of collecting them, e.g. from a bundle
+	secrets map[string]string
+	// templateArgs renders secret references in the arguments of the command
+	templateArgs bool
+	// overrides are injected over the secrets, after the key filter
+	overrides map[string]string
+	// preRunHooks and postRunHooks are run before the command is started and after it exits
+	preRunHooks  []config.HookCommand
+	postRunHooks []config.HookCommand
+	// hooksTrusted checks that the hook commands may run
+	hooksTrusted func() error
+}
+
+// ExitError is returned by Run when the command fails. Code is the exit code of the command,
+// or 128 plus the signal number if it was killed by a signal
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// RunnerOption configures a Runner
+type RunnerOption func(*Runner)
+
+// WithKeyFilter limits the injected secrets to keys matching any of the only globs
+// (all keys if empty) and none of the exclude globs
+func WithKeyFilter(only, exclude []string) RunnerOption {
+	return func(r *Runner) {
+		r.only = only
+		r.exclude = exclude
+	}
+}
+
+// WithWatch returns an option that restarts the command with the new secrets when they change.
+// Providers that can notify of changes are watched; the others are fetched again every pollInterval
+func WithWatch(pollInterval time.Duration) RunnerOption {
+	return func(r *Runner) {
+		r.watch = true
+		r.pollInterval = pollInterval
+	}
+}
+
+// WithSecrets returns an option that injects the given secrets instead of collecting them,
+// e.g. the secrets of a bundle on a host that cannot reach the secret backends
+func WithSecrets(secrets map[string]string) RunnerOption {
+	return func(r *Runner) {
+		r.secrets = secrets
+	}
+}
+
+// WithArgTemplates returns an option that renders references to secrets in the arguments of the
+// command, such as {{ .vault-db.PG_DSN }}, right before it is started
+func WithArgTemplates() RunnerOption {
+	return func(r *Runner) {
+		r.templateArgs = true
+	}
+}
+
+// WithOverrides returns an option that injects values over the collected secrets, e.g. for a
+// quick experiment without editing the config. The key filter does not apply to them
+func WithOverrides(overrides map[string]string) RunnerOption {
+	return func(r *Runner) {
+		r.overrides = overrides
+	}
 }
 
 // NewRunner creates a new runner instance
-func NewRunner(collector *secrets.Collector, inherit bool) *Runner {
-	return &Runner{
+func NewRunner(collector *secrets.Collector, inherit bool, opts ...RunnerOption) *Runner {
+	r := &Runner{
 		collector: collector,
 		inherit:   inherit,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-// Run executes a command with injected secrets
-func (r *Runner) Run(ctx context.Context, providerIDs []string, command []string) error {
-	// Collect secrets
-	envSecrets, err := r.collector.Collect(ctx, providerIDs)
+// collect returns the secrets to inject and the provider each one came from: the secrets given
+// with WithSecrets, which have no providers, or the collected ones
+func (r *Runner) collect(ctx context.Context, providerIDs []string) (map[string]string, map[string]string, error) {
+	envSecrets := r.secrets
+	var sources map[string]string
+	if envSecrets == nil {
+		collected, origins, err := r.collector.CollectWithSources(ctx, providerIDs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to collect secrets: %w", err)
+		}
+		envSecrets, sources = collected, origins
+	}
+	envSecrets, err := secrets.FilterKeys(envSecrets, r.only, r.exclude)
 	if err != nil {
-		return fmt.Errorf("failed to collect secrets: %w", err)
+		return nil, nil, err
 	}
+	envSecrets, sources = r.applyOverrides(envSecrets, sources)
+	return envSecrets, sources, nil
+}
 
-	// Prepare environment
-	env := os.Environ()
-	if !r.inherit {
-		env = make([]string, 0)
+// applyOverrides returns copies of the secrets and their sources with the overrides applied
+func (r *Runner) applyOverrides(envSecrets, sources map[string]string) (map[string]string, map[string]string) {
+	if len(r.overrides) == 0 {
+		return envSecrets, sources
+	}
+	envSecrets = maps.Clone(envSecrets)
+	sources = maps.Clone(sources)
+	if sources == nil {
+		sources = make(map[string]string, len(r.overrides))
 	}
+	for key, value := range r.overrides {
+		envSecrets[key] = value
+		sources[key] = OverrideSource
+	}
+	return envSecrets, sources
+}
 
-	// Merge secrets into environment
-	for key, value := range envSecrets {
-		env = append(env, fmt.Sprintf("%s=%s", key, value))
+// Run executes a command with injected secrets
+func (r *Runner) Run(ctx context.Context, providerIDs []string, command []string) error {
+	envSecrets, sources, err := r.collect(ctx, providerIDs)
+	if err != nil {
+		return err
 	}
 
 	// Prepare command
@@ -48,50 +159,168 @@ func (r *Runner) Run(ctx context.Context, providerIDs []string, command []string
 		return fmt.Errorf("no command specified")
 	}
 
-	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
-	cmd.Env = env
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	// Set up process group so subprocess runs in its own process group (Unix only)
-	setProcessGroup(cmd)
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
-	}
-
 	// Set up signal forwarding for kill signals only (cross-platform compatible)
 	sigChan := make(chan os.Signal, 1)
 	// Only register for interrupt and terminate signals to ensure Windows compatibility
 	registerSignals(sigChan)
 
-	// Goroutine to forward signals to subprocess
+	// Goroutine to forward signals to the current subprocess
+	var mu sync.Mutex
+	var current *exec.Cmd
 	go func() {
 		for sig := range sigChan {
-			if cmd.Process != nil {
+			mu.Lock()
+			if current != nil && current.Process != nil {
 				// Forward the signal directly to the subprocess (cross-platform)
-				_ = cmd.Process.Signal(sig)
+				_ = current.Process.Signal(sig)
 			}
+			mu.Unlock()
 		}
 	}()
 
-	// Wait for command to complete
-	waitErr := cmd.Wait()
+	var waitErr error
+	for {
+		if err := r.preRun(ctx, envSecrets); err != nil {
+			signal.Stop(sigChan)
+			close(sigChan)
+			return err
+		}
+		cmd, err := r.start(ctx, command, envSecrets, sources)
+		if err != nil {
+			signal.Stop(sigChan)
+			close(sigChan)
+			return err
+		}
+		mu.Lock()
+		current = cmd
+		mu.Unlock()
+
+		if !r.watch {
+			waitErr = cmd.Wait()
+			break
+		}
+
+		// Restart the command when the secrets change, until it exits on its own
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+		var changed, changedSources map[string]string
+		changed, changedSources, waitErr = r.waitForChange(ctx, providerIDs, envSecrets, exited)
+		if changed == nil {
+			break
+		}
+		fmt.Fprintln(os.Stderr, "sstart: secrets changed, restarting the command")
+		stopProcess(cmd)
+		var stopErr error
+		select {
+		case stopErr = <-exited:
+		case <-time.After(stopTimeout):
+			_ = cmd.Process.Kill()
+			stopErr = <-exited
+		}
+		if err := r.postRun(ctx, envSecrets, stopErr); err != nil {
+			signal.Stop(sigChan)
+			close(sigChan)
+			return err
+		}
+		envSecrets, sources = changed, changedSources
+	}
+
+	// Signals are still forwarded while post_run commands clean up, so that they are not cut short
+	postErr := r.postRun(ctx, envSecrets, waitErr)
 
 	// Stop forwarding signals
 	signal.Stop(sigChan)
 	close(sigChan)
 
 	if waitErr != nil {
-		// Get exit code if available (cross-platform compatible)
+		// The exit code of the command takes precedence over a failed post_run command
+		if postErr != nil {
+			fmt.Fprintf(os.Stderr, "sstart: %v\n", postErr)
+		}
+		// Report the exit code so that sstart exits with it
 		if exitError, ok := waitErr.(*exec.ExitError); ok {
-			// ExitCode() method is available on all platforms (Go 1.12+)
-			os.Exit(exitError.ExitCode())
-			return nil
+			return &ExitError{Code: exitCode(exitError)}
 		}
 		return waitErr
 	}
 
-	return nil
+	return postErr
+}
+
+// environ returns the environment of commands: the secrets, merged into the environment of
+// sstart if it is inherited
+func (r *Runner) environ(envSecrets map[string]string) []string {
+	env := os.Environ()
+	if !r.inherit {
+		env = make([]string, 0)
+	}
+
+	// Merge secrets into environment
+	for key, value := range envSecrets {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	return env
+}
+
+// start starts the command with the secrets merged into its environment
+func (r *Runner) start(ctx context.Context, command []string, envSecrets, sources map[string]string) (*exec.Cmd, error) {
+	command, err := r.renderArgs(command, envSecrets, sources)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = r.environ(envSecrets)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Set up process group so subprocess runs in its own process group (Unix only)
+	setProcessGroup(cmd)
+
+	// Start the command
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+	return cmd, nil
+}
+
+// waitForChange fetches the secrets again whenever they may have changed, and returns them and
+// their sources once they differ from envSecrets. It returns nil secrets and the command's result
+// if it exits first
+func (r *Runner) waitForChange(ctx context.Context, providerIDs []string, envSecrets map[string]string, exited <-chan error) (map[string]string, map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Secrets are sent with their sources, which templated arguments refer to
+	changed := make(chan [2]map[string]string, 1)
+	go func() {
+		for {
+			if err := r.collector.WaitForChange(ctx, providerIDs, r.pollInterval); err != nil {
+				return
+			}
+			fresh, sources, err := r.collector.RefreshWithSources(ctx, providerIDs)
+			if err == nil {
+				fresh, err = secrets.FilterKeys(fresh, r.only, r.exclude)
+			}
+			if err == nil {
+				fresh, sources = r.applyOverrides(fresh, sources)
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "sstart: failed to refresh secrets, keeping the current ones: %v\n", err)
+				}
+				continue
+			}
+			if !maps.Equal(fresh, envSecrets) {
+				changed <- [2]map[string]string{fresh, sources}
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-exited:
+		return nil, nil, err
+	case fresh := <-changed:
+		return fresh[0], fresh[1], nil
+	}
 }
@@ -22,3 +22,27 @@ func registerSignals(sigChan chan os.Signal) {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 }
 
+// stopProcess asks the command to exit, so it can shut down cleanly before being restarted
+func stopProcess(cmd *exec.Cmd) {
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+}
+
+// exitCode returns the exit code of a command that failed, or 128 plus the signal number
+// if it was killed by a signal, like shells do
+func exitCode(exitErr *exec.ExitError) int {
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
+
+// shellCommand returns the command running command with the shell
+func shellCommand(command string) []string {
+	return []string{"sh", "-c", command}
+}
+
+// shellCommandWithArgs returns the command running script with the shell, with args as its
+// positional parameters $1, $2, ...
+func shellCommandWithArgs(script string, args []string) ([]string, bool) {
+	return append([]string{"sh", "-c", script, "sh"}, args...), true
+}
@@ -19,3 +19,23 @@ func registerSignals(sigChan chan os.Signal) {
 	signal.Notify(sigChan, os.Interrupt)
 }
 
+// stopProcess stops the command before it is restarted; Windows cannot deliver SIGTERM
+func stopProcess(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
+
+// exitCode returns the exit code of a command that failed
+func exitCode(exitErr *exec.ExitError) int {
+	return exitErr.ExitCode()
+}
+
+// shellCommand returns the command running command with the command interpreter
+func shellCommand(command string) []string {
+	return []string{"cmd", "/C", command}
+}
+
+// shellCommandWithArgs reports that the command interpreter cannot be given positional
+// parameters, so values cannot be passed to it without being parsed
+func shellCommandWithArgs(script string, args []string) ([]string, bool) {
+	return nil, false
+}
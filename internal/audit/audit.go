@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DirName is the directory under the state home holding the audit log
+	DirName = "sstart"
+	// FileName is the default audit log file name
+	FileName = "audit.jsonl"
+)
+
+// Sources of the secrets recorded in an entry
+const (
+	SourceProvider = "provider" // Fetched from the provider backend
+	SourceCache    = "cache"    // Served from the secret cache
+)
+
+// Entry is a single audit log record, written as one JSON line
+// Secret values are never stored; Keys maps each key to a hash of its value
+type Entry struct {
+	Time     time.Time         `json:"time"`
+	User     string            `json:"user,omitempty"`
+	Host     string            `json:"host,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Config   string            `json:"config,omitempty"`
+	Profile  string            `json:"profile,omitempty"`
+	Provider string            `json:"provider"`
+	Kind     string            `json:"kind"`
+	Source   string            `json:"source"`
+	Keys     map[string]string `json:"keys"`
+}
+
+// Logger appends entries to a JSONL audit log
+type Logger struct {
+	path    string
+	command string
+	mu      sync.Mutex
+}
+
+// DefaultPath returns the default audit log path: $XDG_STATE_HOME/sstart/audit.jsonl,
+// or ~/.local/state/sstart/audit.jsonl
+func DefaultPath() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".", DirName, FileName)
+		}
+		stateHome = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateHome, DirName, FileName)
+}
+
+// New creates a logger appending to path (DefaultPath if empty) that records command in every entry
+func New(path, command string) (*Logger, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve audit log path: %w", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return &Logger{path: path, command: command}, nil
+}
+
+// Path returns the audit log file path
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Record appends entry to the audit log, filling in the time, user, host and command
+func (l *Logger) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if entry.User == "" {
+		entry.User = currentUser()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+	if entry.Command == "" {
+		entry.Command = l.command
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// HashValues returns the keys of secrets mapped to hashes of their values
+func HashValues(secrets map[string]string) map[string]string {
+	hashed := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		hashed[key] = HashValue(value)
+	}
+	return hashed
+}
+
+// HashValue returns the SHA-256 hash of value, prefixed with the algorithm
+func HashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// currentUser returns the name of the user running sstart
+func currentUser() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME") // Windows
+}
@@ -0,0 +1,114 @@
+// Package bundle reads and writes encrypted, expiring snapshots of collected secrets, for running
+// commands where the secret backends cannot be reached
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"filippo.io/age"
+)
+
+// PassphraseEnv is the environment variable holding the passphrase of bundles encrypted without recipients
+const PassphraseEnv = "SSTART_BUNDLE_PASSPHRASE"
+
+// IdentityEnv is the environment variable holding the age identity bundles are decrypted with.
+// The bucket provider also reads it for age-encrypted objects when no identity is configured
+const IdentityEnv = "SSTART_AGE_IDENTITY"
+
+// formatVersion is the current version of the bundle payload
+const formatVersion = 1
+
+// Bundle is the payload of a bundle file, encrypted with age
+type Bundle struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Inherit records whether the config lets commands inherit the environment of sstart
+	Inherit bool              `json:"inherit"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+// New returns a bundle of secrets that expires after ttl
+func New(secrets map[string]string, inherit bool, ttl time.Duration) *Bundle {
+	now := time.Now().UTC()
+	return &Bundle{
+		Version:   formatVersion,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Inherit:   inherit,
+		Secrets:   secrets,
+	}
+}
+
+// Write encrypts the bundle to w for the given age recipients, or with passphrase if there are none
+func (b *Bundle) Write(w io.Writer, recipients []string, passphrase string) error {
+	var ageRecipients []age.Recipient
+	if len(recipients) > 0 {
+		for _, recipient := range recipients {
+			r, err := age.ParseX25519Recipient(recipient)
+			if err != nil {
+				return fmt.Errorf("invalid age recipient '%s': %w", recipient, err)
+			}
+			ageRecipients = append(ageRecipients, r)
+		}
+	} else {
+		if passphrase == "" {
+			return fmt.Errorf("bundles are encrypted for --recipient age keys or with $%s", PassphraseEnv)
+		}
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return err
+		}
+		ageRecipients = append(ageRecipients, r)
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	encrypted, err := age.Encrypt(w, ageRecipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	if _, err := encrypted.Write(data); err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return encrypted.Close()
+}
+
+// Read decrypts a bundle with the given age identities, or with passphrase if there are none,
+// and fails if it has expired
+func Read(r io.Reader, identities []age.Identity, passphrase string) (*Bundle, error) {
+	if len(identities) == 0 {
+		if passphrase == "" {
+			return nil, fmt.Errorf("no key to decrypt the bundle: set --identity, $SSTART_AGE_IDENTITY or $%s", PassphraseEnv)
+		}
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = []age.Identity{identity}
+	}
+
+	decrypted, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bundle: %w", err)
+	}
+	var b Bundle
+	if err := json.NewDecoder(decrypted).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+	if b.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if time.Now().After(b.ExpiresAt) {
+		return nil, fmt.Errorf("bundle expired at %s", b.ExpiresAt.Format(time.RFC3339))
+	}
+	if b.Secrets == nil {
+		b.Secrets = make(map[string]string)
+	}
+	return &b, nil
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sort"
 	"sync"
 	"time"
@@ -15,10 +16,13 @@ import (
 )
 
 const (
-	// KeyringService is the service name used for keyring storage
+	// KeyringService is the default service name used for keyring storage
 	KeyringService = "sstart-cache"
 	// DefaultTTL is the default cache TTL (5 minutes)
 	DefaultTTL = 5 * time.Minute
+	// StoreVersion is the current schema version of the cache store.
+	// Bump this and register a migration in storeMigrations whenever the format changes.
+	StoreVersion = 1
 )
 
 // CachedSecrets represents cached secrets with metadata
@@ -26,18 +30,68 @@ type CachedSecrets struct {
 	Secrets   map[string]string `json:"secrets"`
 	ExpiresAt time.Time         `json:"expires_at"`
 	CachedAt  time.Time         `json:"cached_at"`
+	// Identity is the SSO identity the secrets were fetched under (see oidc.Identity), empty if
+	// the provider does not use SSO
+	Identity string `json:"identity,omitempty"`
 }
 
 // CacheStore represents the entire cache storage
 type CacheStore struct {
+	// Version is the schema version of the store (0 for stores written before versioning)
+	Version   int                       `json:"version"`
 	Providers map[string]*CachedSecrets `json:"providers"`
 }
 
+// Metrics tracks how effective the cache is across invocations
+type Metrics struct {
+	Hits    int64     `json:"hits"`    // Lookups served from the cache
+	Misses  int64     `json:"misses"`  // Lookups with no cached entry
+	Expired int64     `json:"expired"` // Lookups that found an entry past its TTL
+	Since   time.Time `json:"since"`   // When counting started (or was last reset)
+}
+
+// add adds the counters of other to m
+func (m *Metrics) add(other Metrics) {
+	m.Hits += other.Hits
+	m.Misses += other.Misses
+	m.Expired += other.Expired
+}
+
+// Lookups returns the total number of recorded cache lookups
+func (m Metrics) Lookups() int64 {
+	return m.Hits + m.Misses + m.Expired
+}
+
+// HitRatio returns the fraction of lookups served from the cache (0 if there were none)
+func (m Metrics) HitRatio() float64 {
+	if m.Lookups() == 0 {
+		return 0
+	}
+	return float64(m.Hits) / float64(m.Lookups())
+}
+
+// storeMigration upgrades a raw cache store from one version to the next
+type storeMigration func(raw map[string]json.RawMessage) error
+
+// storeMigrations maps a store version to the migration that upgrades it to version+1
+var storeMigrations = map[int]storeMigration{
+	// Version 0 (unversioned) has the same layout as version 1, only the version field is added
+	0: func(raw map[string]json.RawMessage) error { return nil },
+}
+
+// errStoreTooNew is returned when the store was written by a newer sstart version
+var errStoreTooNew = fmt.Errorf("cache store was written by a newer version of sstart")
+
 // Cache provides caching functionality for secrets
 type Cache struct {
 	ttl             time.Duration
+	service         string
+	logger          *slog.Logger
 	keyringDisabled bool
 	keyringOnce     sync.Once
+	// metricsMu guards pending, the lookups not yet written by FlushMetrics
+	metricsMu sync.Mutex
+	pending   Metrics
 }
 
 // Option is a functional option for configuring the Cache
@@ -50,10 +104,27 @@ func WithTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithKeyringService sets the keyring service name the cache is stored under, so that separate
+// deployments on the same machine keep separate caches
+func WithKeyringService(service string) Option {
+	return func(c *Cache) {
+		c.service = service
+	}
+}
+
+// WithLogger sets a logger used to report cache hits and misses at debug level
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
+}
+
 // New creates a new Cache instance
 func New(opts ...Option) *Cache {
 	cache := &Cache{
-		ttl: DefaultTTL,
+		ttl:     DefaultTTL,
+		service: KeyringService,
+		logger:  slog.New(slog.DiscardHandler),
 	}
 
 	for _, opt := range opts {
@@ -64,14 +135,28 @@ func New(opts ...Option) *Cache {
 }
 
 // GenerateCacheKey generates a unique cache key based on provider configuration.
-// The key is a hash of the provider kind, id, and configuration.
-func GenerateCacheKey(providerID string, kind string, config map[string]interface{}) string {
+// The key is a hash of the provider kind, id, resolved configuration and key mappings, and of
+// the active profile, so that profiles never share cached values. transforms holds the other
+// settings that change the cached values, e.g. post-fetch hooks, by name. The profile, key
+// mappings and transforms are left out when empty, keeping the keys of configs without them unchanged
+func GenerateCacheKey(providerID string, kind string, profile string, config map[string]interface{}, keys map[string]string, transforms map[string]interface{}) string {
 	// Create a deterministic representation of the config
 	data := map[string]interface{}{
 		"provider_id": providerID,
 		"kind":        kind,
 		"config":      sortedConfigString(config),
 	}
+	if profile != "" {
+		data["profile"] = profile
+	}
+	if len(keys) > 0 {
+		data["keys"] = keys
+	}
+	for name, transform := range transforms {
+		if transform != nil {
+			data["transform_"+name] = transform
+		}
+	}
 
 	jsonBytes, err := json.Marshal(data)
 	if err != nil {
@@ -92,8 +177,8 @@ func sortedConfigString(config map[string]interface{}) string {
 	// Get sorted keys
 	keys := make([]string, 0, len(config))
 	for k := range config {
-		// Skip internal SSO tokens as they change
-		if k == "_sso_access_token" || k == "_sso_id_token" {
+		// Skip internal SSO tokens as they change, and the injected keyring service
+		if k == "_sso_access_token" || k == "_sso_id_token" || k == "_keyring_service" {
 			continue
 		}
 		keys = append(keys, k)
@@ -112,40 +197,89 @@ func sortedConfigString(config map[string]interface{}) string {
 
 // Get retrieves cached secrets for a provider if they exist and are not expired
 func (c *Cache) Get(cacheKey string) (map[string]string, bool) {
+	secrets, _, found := c.GetWithTime(cacheKey)
+	return secrets, found
+}
+
+// GetWithTime is like Get, and also returns when the secrets were cached
+func (c *Cache) GetWithTime(cacheKey string) (map[string]string, time.Time, bool) {
 	if !c.isKeyringAvailable() {
-		return nil, false
+		return nil, time.Time{}, false
 	}
 
-	store := c.loadStore()
-	if store == nil {
-		return nil, false
+	// Lookups never write the store, so that they stay fast and never overwrite entries written
+	// by another process in the meantime
+	store, err := c.readStore()
+	if err != nil {
+		c.logger.Debug("cache miss", "key", cacheKey)
+		c.recordLookup(func(m *Metrics) { m.Misses++ })
+		return nil, time.Time{}, false
 	}
 
 	cached, exists := store.Providers[cacheKey]
 	if !exists || cached == nil {
-		return nil, false
+		c.logger.Debug("cache miss", "key", cacheKey)
+		c.recordLookup(func(m *Metrics) { m.Misses++ })
+		return nil, time.Time{}, false
 	}
 
-	// Check if expired
+	// Check if expired; expired entries are removed by CleanExpired or replaced by Set
 	if time.Now().After(cached.ExpiresAt) {
-		// Clean up expired entry
-		delete(store.Providers, cacheKey)
-		_ = c.saveStore(store)
-		return nil, false
+		c.logger.Debug("cache entry expired", "key", cacheKey, "expired_at", cached.ExpiresAt)
+		c.recordLookup(func(m *Metrics) { m.Expired++ })
+		return nil, time.Time{}, false
 	}
 
-	return cached.Secrets, true
+	c.logger.Debug("cache hit", "key", cacheKey, "expires_at", cached.ExpiresAt)
+	c.recordLookup(func(m *Metrics) { m.Hits++ })
+
+	return cached.Secrets, cached.CachedAt, true
+}
+
+// Peek returns cached secrets for a provider and when they were cached, even if they have expired.
+// Unlike Get, it neither records a lookup in the metrics nor removes expired entries
+func (c *Cache) Peek(cacheKey string) (map[string]string, time.Time, bool) {
+	if !c.isKeyringAvailable() {
+		return nil, time.Time{}, false
+	}
+
+	store := c.loadStore()
+	if store == nil {
+		return nil, time.Time{}, false
+	}
+	cached, exists := store.Providers[cacheKey]
+	if !exists || cached == nil {
+		return nil, time.Time{}, false
+	}
+	return cached.Secrets, cached.CachedAt, true
+}
+
+// recordLookup applies an update to the lookups not yet written by FlushMetrics
+func (c *Cache) recordLookup(update func(m *Metrics)) {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+	update(&c.pending)
 }
 
 // Set stores secrets in the cache with the configured TTL.
 // If keyring is not available, this is a no-op (returns nil).
 func (c *Cache) Set(cacheKey string, secrets map[string]string) error {
+	return c.SetWithIdentity(cacheKey, secrets, "")
+}
+
+// SetWithIdentity stores secrets fetched under an SSO identity, so that ClearIdentity can
+// purge them when that identity logs out
+func (c *Cache) SetWithIdentity(cacheKey string, secrets map[string]string, identity string) error {
 	if !c.isKeyringAvailable() {
 		// Silently skip caching when keyring is not available
 		return nil
 	}
 
-	store := c.loadStore()
+	store, err := c.readStore()
+	if err == errStoreTooNew {
+		// Never overwrite a cache we don't understand
+		return nil
+	}
 	if store == nil {
 		store = &CacheStore{
 			Providers: make(map[string]*CachedSecrets),
@@ -161,6 +295,7 @@ func (c *Cache) Set(cacheKey string, secrets map[string]string) error {
 		Secrets:   secrets,
 		CachedAt:  now,
 		ExpiresAt: now.Add(c.ttl),
+		Identity:  identity,
 	}
 
 	return c.saveStore(store)
@@ -172,7 +307,7 @@ func (c *Cache) Clear() error {
 		return nil
 	}
 
-	if err := keyring.Delete(KeyringService, "cache"); err != nil && err != keyring.ErrNotFound {
+	if err := keyring.Delete(c.service, "cache"); err != nil && err != keyring.ErrNotFound {
 		return fmt.Errorf("failed to remove cache from keyring: %w", err)
 	}
 
@@ -194,6 +329,31 @@ func (c *Cache) ClearProvider(cacheKey string) error {
 	return c.saveStore(store)
 }
 
+// ClearIdentity removes the cached secrets fetched under an SSO identity and returns how many
+// entries were removed
+func (c *Cache) ClearIdentity(identity string) (int, error) {
+	if identity == "" || !c.isKeyringAvailable() {
+		return 0, nil
+	}
+
+	store := c.loadStore()
+	if store == nil {
+		return 0, nil
+	}
+
+	removed := 0
+	for key, entry := range store.Providers {
+		if entry != nil && entry.Identity == identity {
+			delete(store.Providers, key)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.saveStore(store)
+}
+
 // CleanExpired removes all expired cache entries
 func (c *Cache) CleanExpired() error {
 	if !c.isKeyringAvailable() {
@@ -224,7 +384,7 @@ func (c *Cache) CleanExpired() error {
 func (c *Cache) isKeyringAvailable() bool {
 	c.keyringOnce.Do(func() {
 		// Try to access keyring with a test operation
-		_, err := keyring.Get(KeyringService, "test-availability")
+		_, err := keyring.Get(c.service, "test-availability")
 		if err != nil && err != keyring.ErrNotFound {
 			c.keyringDisabled = true
 		}
@@ -235,29 +395,84 @@ func (c *Cache) isKeyringAvailable() bool {
 
 // loadStore loads the cache store from keyring
 func (c *Cache) loadStore() *CacheStore {
-	data, err := keyring.Get(KeyringService, "cache")
+	store, _ := c.readStore()
+	return store
+}
+
+// readStore loads the cache store from keyring, migrating older formats to the current version.
+// A store written by a newer version is left untouched and errStoreTooNew is returned.
+func (c *Cache) readStore() (*CacheStore, error) {
+	data, err := keyring.Get(c.service, "cache")
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	var store CacheStore
-	if err := json.Unmarshal([]byte(data), &store); err != nil {
+	store, err := decodeStore([]byte(data))
+	if err == errStoreTooNew {
+		return nil, err
+	}
+	if err != nil {
 		// Invalid data, clean up
-		_ = keyring.Delete(KeyringService, "cache")
-		return nil
+		_ = keyring.Delete(c.service, "cache")
+		return nil, err
 	}
 
-	return &store
+	return store, nil
+}
+
+// decodeStore parses raw cache store data and migrates it to StoreVersion
+func decodeStore(data []byte) (*CacheStore, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse cache store: %w", err)
+	}
+
+	version := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("invalid cache store version: %w", err)
+		}
+	}
+
+	if version > StoreVersion {
+		return nil, errStoreTooNew
+	}
+
+	// Apply migrations one version at a time
+	for version < StoreVersion {
+		migrate, ok := storeMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from cache store version %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate cache store from version %d: %w", version, err)
+		}
+		version++
+		raw["version"], _ = json.Marshal(version)
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated cache store: %w", err)
+	}
+
+	var store CacheStore
+	if err := json.Unmarshal(migrated, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse cache store: %w", err)
+	}
+
+	return &store, nil
 }
 
 // saveStore saves the cache store to keyring
 func (c *Cache) saveStore(store *CacheStore) error {
+	store.Version = StoreVersion
 	data, err := json.Marshal(store)
 	if err != nil {
 		return fmt.Errorf("failed to marshal cache store: %w", err)
 	}
 
-	if err := keyring.Set(KeyringService, "cache", string(data)); err != nil {
+	if err := keyring.Set(c.service, "cache", string(data)); err != nil {
 		return fmt.Errorf("failed to save cache to keyring: %w", err)
 	}
 
@@ -295,6 +510,81 @@ func (c *Cache) Stats() (total int, valid int, expired int) {
 	return total, valid, expired
 }
 
+// Metrics returns the persisted hit/miss counters, including the lookups not yet flushed
+func (c *Cache) Metrics() Metrics {
+	if !c.isKeyringAvailable() {
+		return Metrics{}
+	}
+
+	metrics := c.loadMetrics()
+	c.metricsMu.Lock()
+	metrics.add(c.pending)
+	c.metricsMu.Unlock()
+	return metrics
+}
+
+// FlushMetrics adds the lookups recorded since the last flush to the persisted counters.
+// The counters are kept apart from the cached secrets, so that writing them never replaces
+// entries; writing them is best-effort and lookups are dropped if it fails
+func (c *Cache) FlushMetrics() {
+	if !c.isKeyringAvailable() {
+		return
+	}
+
+	c.metricsMu.Lock()
+	pending := c.pending
+	c.pending = Metrics{}
+	c.metricsMu.Unlock()
+	if pending.Lookups() == 0 {
+		return
+	}
+
+	metrics := c.loadMetrics()
+	if metrics.Since.IsZero() {
+		metrics.Since = time.Now()
+	}
+	metrics.add(pending)
+	_ = c.saveMetrics(metrics)
+}
+
+// ResetMetrics resets the persisted hit/miss counters without touching cached secrets
+func (c *Cache) ResetMetrics() error {
+	if !c.isKeyringAvailable() {
+		return nil
+	}
+
+	c.metricsMu.Lock()
+	c.pending = Metrics{}
+	c.metricsMu.Unlock()
+	return c.saveMetrics(Metrics{Since: time.Now()})
+}
+
+// loadMetrics loads the persisted hit/miss counters from keyring (zero if there are none or they
+// cannot be read)
+func (c *Cache) loadMetrics() Metrics {
+	var metrics Metrics
+	data, err := keyring.Get(c.service, "metrics")
+	if err != nil {
+		return Metrics{}
+	}
+	if err := json.Unmarshal([]byte(data), &metrics); err != nil {
+		return Metrics{}
+	}
+	return metrics
+}
+
+// saveMetrics saves the hit/miss counters to keyring
+func (c *Cache) saveMetrics(metrics Metrics) error {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache metrics: %w", err)
+	}
+	if err := keyring.Set(c.service, "metrics", string(data)); err != nil {
+		return fmt.Errorf("failed to save cache metrics to keyring: %w", err)
+	}
+	return nil
+}
+
 // IsAvailable returns whether the cache backend (keyring) is available
 func (c *Cache) IsAvailable() bool {
 	return c.isKeyringAvailable()
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -42,12 +43,12 @@ func TestGenerateCacheKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			key := GenerateCacheKey(tt.providerID, tt.kind, tt.config)
+			key := GenerateCacheKey(tt.providerID, tt.kind, "", tt.config, nil, nil)
 			if key == "" {
 				t.Error("expected non-empty cache key")
 			}
 			// Key should be deterministic
-			key2 := GenerateCacheKey(tt.providerID, tt.kind, tt.config)
+			key2 := GenerateCacheKey(tt.providerID, tt.kind, "", tt.config, nil, nil)
 			if key != key2 {
 				t.Errorf("cache key should be deterministic, got %s and %s", key, key2)
 			}
@@ -59,8 +60,8 @@ func TestGenerateCacheKey_DifferentConfigs(t *testing.T) {
 	config1 := map[string]interface{}{"region": "us-east-1"}
 	config2 := map[string]interface{}{"region": "us-west-2"}
 
-	key1 := GenerateCacheKey("aws", "aws_secretsmanager", config1)
-	key2 := GenerateCacheKey("aws", "aws_secretsmanager", config2)
+	key1 := GenerateCacheKey("aws", "aws_secretsmanager", "", config1, nil, nil)
+	key2 := GenerateCacheKey("aws", "aws_secretsmanager", "", config2, nil, nil)
 
 	if key1 == key2 {
 		t.Error("different configs should produce different cache keys")
@@ -77,14 +78,41 @@ func TestGenerateCacheKey_SSOTokensIgnored(t *testing.T) {
 		"_sso_id_token":     "idtoken456",
 	}
 
-	key1 := GenerateCacheKey("vault", "vault", configWithoutToken)
-	key2 := GenerateCacheKey("vault", "vault", configWithToken)
+	key1 := GenerateCacheKey("vault", "vault", "", configWithoutToken, nil, nil)
+	key2 := GenerateCacheKey("vault", "vault", "", configWithToken, nil, nil)
 
 	if key1 != key2 {
 		t.Error("SSO tokens should be ignored when generating cache key")
 	}
 }
 
+func TestGenerateCacheKey_ProfilesKeysAndTransforms(t *testing.T) {
+	config := map[string]interface{}{"path": "secret/app"}
+
+	dev := GenerateCacheKey("vault", "vault", "dev", config, nil, nil)
+	prod := GenerateCacheKey("vault", "vault", "prod", config, nil, nil)
+	none := GenerateCacheKey("vault", "vault", "", config, nil, nil)
+	if dev == prod || dev == none {
+		t.Error("different profiles should produce different cache keys")
+	}
+
+	mapped := GenerateCacheKey("vault", "vault", "", config, map[string]string{"TOKEN": "API_TOKEN"}, nil)
+	if mapped == none {
+		t.Error("different key mappings should produce different cache keys")
+	}
+	if GenerateCacheKey("vault", "vault", "", config, map[string]string{}, nil) != none {
+		t.Error("empty key mappings should produce the same cache key as none")
+	}
+
+	hooked := GenerateCacheKey("vault", "vault", "", config, nil, map[string]interface{}{"post_fetch": []string{"./rename.sh"}})
+	if hooked == none {
+		t.Error("different transforms should produce different cache keys")
+	}
+	if GenerateCacheKey("vault", "vault", "", config, nil, map[string]interface{}{}) != none {
+		t.Error("empty transforms should produce the same cache key as none")
+	}
+}
+
 func TestCache_SetAndGet(t *testing.T) {
 	cache := New(WithTTL(time.Minute))
 
@@ -235,6 +263,112 @@ func TestCache_ClearProvider(t *testing.T) {
 	_ = cache.Clear()
 }
 
+func TestCache_ClearIdentity(t *testing.T) {
+	cache := New()
+
+	// Skip if keyring not available
+	if !cache.IsAvailable() {
+		t.Skip("keyring not available")
+	}
+
+	// Clean up before test
+	_ = cache.Clear()
+	defer func() { _ = cache.Clear() }()
+
+	_ = cache.SetWithIdentity("prod", map[string]string{"KEY1": "value1"}, "alice")
+	_ = cache.SetWithIdentity("staging", map[string]string{"KEY2": "value2"}, "bob")
+	_ = cache.Set("local", map[string]string{"KEY3": "value3"})
+
+	removed, err := cache.ClearIdentity("alice")
+	if err != nil {
+		t.Fatalf("failed to clear identity: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 entry removed, got %d", removed)
+	}
+	if _, found := cache.Get("prod"); found {
+		t.Error("expected the entry of alice to be cleared")
+	}
+	for _, key := range []string{"staging", "local"} {
+		if _, found := cache.Get(key); !found {
+			t.Errorf("expected %s to still exist", key)
+		}
+	}
+
+	// An empty identity never matches the entries of providers without SSO
+	if removed, _ := cache.ClearIdentity(""); removed != 0 {
+		t.Errorf("expected no entries removed for an empty identity, got %d", removed)
+	}
+}
+
+func TestCache_Peek(t *testing.T) {
+	c := New(WithTTL(50 * time.Millisecond))
+
+	// Skip if keyring not available
+	if !c.IsAvailable() {
+		t.Skip("keyring not available")
+	}
+
+	// Clean up before test
+	_ = c.Clear()
+
+	if _, _, found := c.Peek("peek-key"); found {
+		t.Fatal("expected nothing cached")
+	}
+	before := time.Now()
+	_ = c.Set("peek-key", map[string]string{"KEY": "value"})
+	metrics := c.Metrics()
+
+	// Expired entries are still returned
+	time.Sleep(100 * time.Millisecond)
+	secrets, cachedAt, found := c.Peek("peek-key")
+	if !found || secrets["KEY"] != "value" {
+		t.Fatalf("expected the expired entry, got %v (found %v)", secrets, found)
+	}
+	if cachedAt.Before(before) {
+		t.Errorf("expected the time the entry was cached, got %v", cachedAt)
+	}
+	if c.Metrics() != metrics {
+		t.Error("expected Peek not to record a lookup")
+	}
+
+	// Clean up
+	_ = c.Clear()
+}
+
+func TestCache_KeyringService(t *testing.T) {
+	work := New(WithKeyringService("sstart-cache-test-work"))
+	personal := New(WithKeyringService("sstart-cache-test-personal"))
+
+	// Skip if keyring not available
+	if !work.IsAvailable() {
+		t.Skip("keyring not available")
+	}
+
+	// Clean up before test
+	_ = work.Clear()
+	_ = personal.Clear()
+
+	_ = work.Set("shared-key", map[string]string{"KEY": "work"})
+	_ = personal.Set("shared-key", map[string]string{"KEY": "personal"})
+
+	if secrets, found := work.Get("shared-key"); !found || secrets["KEY"] != "work" {
+		t.Errorf("expected the work entry, got %v (found %v)", secrets, found)
+	}
+	if secrets, found := personal.Get("shared-key"); !found || secrets["KEY"] != "personal" {
+		t.Errorf("expected the personal entry, got %v (found %v)", secrets, found)
+	}
+
+	// Clearing one cache leaves the other alone
+	_ = work.Clear()
+	if _, found := personal.Get("shared-key"); !found {
+		t.Error("expected clearing another service to keep the entry")
+	}
+
+	// Clean up
+	_ = personal.Clear()
+}
+
 func TestCache_CleanExpired(t *testing.T) {
 	c := New(WithTTL(50 * time.Millisecond))
 
@@ -343,7 +477,7 @@ func TestCache_IsAvailable(t *testing.T) {
 func TestCache_KeyringNotAvailable(t *testing.T) {
 	cache := New()
 	// Force keyring to be disabled
-	cache.keyringTested = true
+	cache.keyringOnce.Do(func() {})
 	cache.keyringDisabled = true
 
 	// All operations should gracefully handle unavailable keyring
@@ -382,3 +516,125 @@ func TestCache_KeyringNotAvailable(t *testing.T) {
 		t.Error("expected IsAvailable to return false")
 	}
 }
+
+func TestDecodeStore_LegacyUnversioned(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	legacy := fmt.Sprintf(`{"providers":{"key1":{"secrets":{"API_KEY":"secret"},"expires_at":%q,"cached_at":%q}}}`,
+		expiresAt.Format(time.RFC3339), expiresAt.Format(time.RFC3339))
+
+	store, err := decodeStore([]byte(legacy))
+	if err != nil {
+		t.Fatalf("expected legacy store to migrate, got error: %v", err)
+	}
+
+	if store.Version != StoreVersion {
+		t.Errorf("expected migrated store version %d, got %d", StoreVersion, store.Version)
+	}
+
+	cached, ok := store.Providers["key1"]
+	if !ok || cached == nil {
+		t.Fatal("expected legacy entry to survive migration")
+	}
+	if cached.Secrets["API_KEY"] != "secret" {
+		t.Errorf("expected API_KEY=secret, got %q", cached.Secrets["API_KEY"])
+	}
+	if !cached.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected expiry %v, got %v", expiresAt, cached.ExpiresAt)
+	}
+}
+
+func TestDecodeStore_CurrentVersion(t *testing.T) {
+	original := &CacheStore{
+		Version: StoreVersion,
+		Providers: map[string]*CachedSecrets{
+			"key1": {Secrets: map[string]string{"K": "V"}},
+		},
+	}
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("failed to marshal store: %v", err)
+	}
+
+	store, err := decodeStore(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.Providers["key1"].Secrets["K"] != "V" {
+		t.Error("expected entry to round-trip unchanged")
+	}
+}
+
+func TestDecodeStore_NewerVersion(t *testing.T) {
+	data := fmt.Sprintf(`{"version":%d,"providers":{}}`, StoreVersion+1)
+
+	_, err := decodeStore([]byte(data))
+	if err != errStoreTooNew {
+		t.Errorf("expected errStoreTooNew, got %v", err)
+	}
+}
+
+func TestDecodeStore_InvalidData(t *testing.T) {
+	if _, err := decodeStore([]byte("not json")); err == nil {
+		t.Error("expected error for invalid store data")
+	}
+}
+
+func TestMetrics_HitRatio(t *testing.T) {
+	empty := Metrics{}
+	if empty.HitRatio() != 0 {
+		t.Errorf("expected 0 hit ratio with no lookups, got %v", empty.HitRatio())
+	}
+
+	m := Metrics{Hits: 3, Misses: 1}
+	if m.Lookups() != 4 {
+		t.Errorf("expected 4 lookups, got %d", m.Lookups())
+	}
+	if m.HitRatio() != 0.75 {
+		t.Errorf("expected hit ratio 0.75, got %v", m.HitRatio())
+	}
+}
+
+func TestCache_Metrics(t *testing.T) {
+	c := New(WithTTL(time.Minute))
+	if !c.IsAvailable() {
+		t.Skip("keyring not available")
+	}
+
+	_ = c.Clear()
+	_ = c.ResetMetrics()
+
+	key := fmt.Sprintf("metrics-key-%d", time.Now().UnixNano())
+	_, _ = c.Get(key) // miss
+	_ = c.Set(key, map[string]string{"K": "V"})
+	_, _ = c.Get(key) // hit
+
+	metrics := c.Metrics()
+	if metrics.Hits != 1 || metrics.Misses != 1 {
+		t.Errorf("expected 1 hit and 1 miss, got hits=%d misses=%d", metrics.Hits, metrics.Misses)
+	}
+
+	// Flushed lookups are persisted for later invocations
+	c.FlushMetrics()
+	if persisted := New().Metrics(); persisted.Hits != 1 || persisted.Misses != 1 {
+		t.Errorf("expected the flushed lookups to be persisted, got hits=%d misses=%d", persisted.Hits, persisted.Misses)
+	}
+
+	// Lookups never write the cached secrets, so a lookup cannot overwrite an entry set meanwhile
+	other := New(WithTTL(time.Minute))
+	_, _ = c.Get("metrics-missing-key")
+	_ = other.Set("metrics-other-key", map[string]string{"K": "V"})
+	_, _ = c.Get("metrics-missing-key")
+	c.FlushMetrics()
+	if _, found := other.Get("metrics-other-key"); !found {
+		t.Error("expected the entry set by another cache to survive lookups and metrics writes")
+	}
+
+	if err := c.ResetMetrics(); err != nil {
+		t.Fatalf("failed to reset metrics: %v", err)
+	}
+	if c.Metrics().Lookups() != 0 {
+		t.Error("expected metrics to be reset")
+	}
+
+	_ = c.Clear()
+}
@@ -0,0 +1,147 @@
+// Package ci detects CI environments and registers secret values with their native log masking
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// ModeEnv forces CI mode when set to 1 or true, like the --ci flag, and turns off detection when set to 0 or false
+const ModeEnv = "SSTART_CI"
+
+// Names of the detected CI environments
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	CircleCI  = "circleci"
+	Buildkite = "buildkite"
+	// Generic is any other CI that sets CI=true
+	Generic = "generic"
+)
+
+// detectors map the environment variable set by each CI to its name, in the order they are checked
+var detectors = []struct {
+	env  string
+	name string
+}{
+	{"GITHUB_ACTIONS", GitHub},
+	{"GITLAB_CI", GitLab},
+	{"CIRCLECI", CircleCI},
+	{"BUILDKITE", Buildkite},
+	{"CI", Generic},
+}
+
+// Detect returns the name of the CI environment sstart runs in, or an empty string outside CI
+func Detect() string {
+	for _, d := range detectors {
+		if isTrue(os.Getenv(d.env)) {
+			return d.name
+		}
+	}
+	return ""
+}
+
+// Mode returns the name of the CI environment to run in, or an empty string to run normally
+// CI mode is forced by force or $SSTART_CI=1, using Generic outside a detected CI, and turned
+// off by $SSTART_CI=0
+func Mode(force bool) string {
+	value := os.Getenv(ModeEnv)
+	if !force && value != "" && !isTrue(value) {
+		return ""
+	}
+	if name := Detect(); name != "" {
+		return name
+	}
+	if force || isTrue(value) {
+		return Generic
+	}
+	return ""
+}
+
+func isTrue(value string) bool {
+	value = strings.ToLower(value)
+	return value == "1" || value == "true"
+}
+
+// Masker registers secret values with the log masking of a CI, so that they are redacted
+// from the job log even when a command prints them
+type Masker struct {
+	name   string
+	out    io.Writer
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	masked map[string]bool
+}
+
+// NewMasker returns a masker for the CI called name (see Detect). Workflow commands are written to out.
+func NewMasker(name string, out io.Writer, logger *slog.Logger) *Masker {
+	return &Masker{name: name, out: out, logger: logger, masked: make(map[string]bool)}
+}
+
+// Mask registers values that were not registered before
+// GitHub Actions masks them with ::add-mask:: workflow commands, and Buildkite with
+// 'buildkite-agent redactor add'. GitLab CI and CircleCI only mask variables defined in
+// their settings, so values are not registered there.
+func (m *Masker) Mask(values []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []string
+	for _, value := range values {
+		if value != "" && !m.masked[value] {
+			m.masked[value] = true
+			pending = append(pending, value)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	switch m.name {
+	case GitHub:
+		for _, value := range pending {
+			// Workflow commands apply to a single line, so each line of a multi-line value is masked
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimRight(line, "\r"); line != "" {
+					fmt.Fprintf(m.out, "::add-mask::%s\n", line)
+				}
+			}
+		}
+	case Buildkite:
+		if err := redactBuildkite(pending); err != nil {
+			m.logger.Warn("failed to register secrets with the Buildkite log redactor", "error", err)
+		}
+	default:
+		m.logger.Debug("no native secret masking in this CI", "ci", m.name)
+	}
+}
+
+// redactBuildkite adds values to the redactor of the Buildkite agent running the job
+func redactBuildkite(values []string) error {
+	agentPath, err := exec.LookPath("buildkite-agent")
+	if err != nil {
+		return err
+	}
+	named := make(map[string]string, len(values))
+	for i, value := range values {
+		named[fmt.Sprintf("SSTART_SECRET_%d", i)] = value
+	}
+	data, err := json.Marshal(named)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(agentPath, "redactor", "add", "--format", "json")
+	cmd.Stdin = bytes.NewReader(data)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
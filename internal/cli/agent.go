@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dirathea/sstart/internal/agent"
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocket string
+	agentTTL    time.Duration
+	agentWarm   time.Duration
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the sstart agent, which keeps SSO sessions and secrets in memory",
+	Long: `Run the sstart agent in the foreground. The agent keeps SSO sessions, provider clients,
+and collected secrets in memory, and serves them over a unix socket.
+
+While the agent runs, other sstart commands (run, env, show, sh, ls, mcp) collect their
+secrets through it instead of authenticating and fetching on every invocation. Collected
+secrets are kept for --ttl, and a config file is loaded again when it changes.
+
+Secrets requested within --warm-idle are fetched again shortly before they expire, so that
+commands keep finding them in memory. Refreshes run one at a time, and failed ones are retried
+with a backoff while the current secrets are served until they expire.
+
+The socket is only usable by the user running the agent. Commands fall back to
+collecting locally when no agent is running; use --no-agent or SSTART_NO_AGENT=1 to
+bypass a running agent.
+
+Example:
+  sstart agent &
+  sstart agent status
+  sstart agent stop`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		logger := newLogger()
+		server := agent.NewServer(agentSocket,
+			agent.WithTTL(agentTTL),
+			agent.WithWarming(agentWarm),
+			agent.WithLogger(logger),
+			agent.WithCollectorOptions(secrets.WithLogger(logger), secrets.WithCommand(commandLine), secrets.WithNotifications(true)),
+		)
+		fmt.Fprintf(os.Stderr, "sstart agent listening on %s\n", server.SocketPath())
+		return server.Serve(ctx)
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the sstart agent is running",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := agent.NewClient(agentSocket, config.GlobalConfigPath())
+		status, err := client.Status(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Agent running (pid %d) on %s\n", status.PID, socketPathOrDefault())
+		fmt.Printf("Configs: %d\n", status.Sessions)
+		fmt.Printf("TTL:     %s\n", status.TTL)
+		return nil
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running sstart agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := agent.NewClient(agentSocket, config.GlobalConfigPath())
+		if err := client.Stop(context.Background()); err != nil {
+			return err
+		}
+		fmt.Println("Agent stopped")
+		return nil
+	},
+}
+
+var agentGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a single secret from the running sstart agent",
+	Long: `Print the value of a single secret of the current config, served by the running agent.
+
+Example:
+  export DATABASE_URL="$(sstart agent get DATABASE_URL)"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		client := agent.NewClient(agentSocket, config.GlobalConfigPath())
+		value, err := client.Get(context.Background(), cfg, providers, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// socketPathOrDefault returns the --socket flag, falling back to the default agent socket
+func socketPathOrDefault() string {
+	if agentSocket != "" {
+		return agentSocket
+	}
+	return agent.DefaultSocketPath()
+}
+
+// useAgent reports whether commands should collect through a running agent
+// Flags that change how secrets are collected (--force-auth, --timings) collect locally
+func useAgent() bool {
+	return !noAgent && !agent.Disabled() && !forceAuth && !timings
+}
+
+func init() {
+	agentCmd.PersistentFlags().StringVar(&agentSocket, "socket", "", "Path of the agent socket (default: $SSTART_AGENT_SOCK or $XDG_RUNTIME_DIR/sstart/agent.sock)")
+	agentCmd.Flags().DurationVar(&agentTTL, "ttl", agent.DefaultTTL, "How long collected secrets are kept in memory")
+	agentCmd.Flags().DurationVar(&agentWarm, "warm-idle", agent.DefaultWarmIdle, "Refresh secrets before they expire while they were requested within this time (0 disables warming)")
+	agentGetCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentStopCmd)
+	agentCmd.AddCommand(agentGetCmd)
+	rootCmd.AddCommand(agentCmd)
+}
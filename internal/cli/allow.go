@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var allowCmd = &cobra.Command{
+	Use:   "allow [config]",
+	Short: "Allow the shell hook to load the secrets of a config file",
+	Long: `Allow 'sstart hook' to load the secrets of a config file (default: the config found from the
+current directory) when you enter its directory. Config files run commands, e.g. with exec()
+templates and hooks, so the hook only loads files you allowed, like direnv's 'direnv allow'.
+
+Changing the config file, or a file it includes, revokes the permission until you allow it again.
+
+Example:
+  sstart allow
+  sstart allow ~/src/app/.sstart.yml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := trustConfigPath(args)
+		if err != nil {
+			return err
+		}
+		if err := config.Trust(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Allowed %s\n", path)
+		return nil
+	},
+}
+
+var denyCmd = &cobra.Command{
+	Use:   "deny [config]",
+	Short: "Revoke the permission of the shell hook to load the secrets of a config file",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := trustConfigPath(args)
+		if err != nil {
+			return err
+		}
+		if err := config.Untrust(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Denied %s\n", path)
+		return nil
+	},
+}
+
+// trustConfigPath returns the config file given in args, or the one found from the current directory
+func trustConfigPath(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	path, err := resolveConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", &configError{err: errors.New("no config file found in the current directory or its parents")}
+	}
+	return path, nil
+}
+
+func init() {
+	rootCmd.AddCommand(allowCmd)
+	rootCmd.AddCommand(denyCmd)
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browse collected secrets in an interactive terminal UI",
+	Long: `Open a terminal UI listing the secrets that would be injected, grouped by the provider
+they come from. Values are masked until revealed, one at a time.
+
+Keys:
+  ↑/k, ↓/j    move the selection
+  enter/space reveal or mask the selected value
+  c/y         copy the selected value to the clipboard
+  r           fetch the secrets again, bypassing the cache
+  q/esc       quit
+
+When no system clipboard is available (e.g. over SSH), values are copied with the OSC 52
+terminal escape sequence, which most terminals support.
+
+Example:
+  sstart browse
+  sstart browse --providers aws-prod --only 'DB_*'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		collector := newCollector(cfg)
+		load := func(refresh bool) ([]browseItem, error) {
+			collect := collector.CollectWithSources
+			if refresh {
+				collect = collector.RefreshWithSources
+			}
+			collected, sources, err := collect(context.Background(), providers)
+			if err != nil {
+				return nil, fmt.Errorf("failed to collect secrets: %w", err)
+			}
+			collected, err = secrets.FilterKeys(collected, onlyKeys, excludeKeys)
+			if err != nil {
+				return nil, err
+			}
+			return browseItems(collected, sources), nil
+		}
+
+		// Collect before starting the UI, so that SSO logins and errors use the terminal as usual
+		items, err := load(false)
+		if err != nil {
+			return err
+		}
+
+		model := newBrowseModel(items, load, os.Stdout)
+		_, err = tea.NewProgram(model, tea.WithAltScreen(), tea.WithInput(os.Stdin)).Run()
+		return err
+	},
+}
+
+// browseItem is a secret listed by sstart browse
+type browseItem struct {
+	Key      string
+	Provider string
+	Value    string
+}
+
+// browseItems returns the secrets sorted by provider, then key
+func browseItems(collected map[string]string, sources map[string]string) []browseItem {
+	items := make([]browseItem, 0, len(collected))
+	for key, value := range collected {
+		items = append(items, browseItem{Key: key, Provider: sources[key], Value: value})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Provider != items[j].Provider {
+			return items[i].Provider < items[j].Provider
+		}
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+// browseLoadedMsg carries the result of a refresh
+type browseLoadedMsg struct {
+	items []browseItem
+	err   error
+}
+
+// browseModel is the bubbletea model of sstart browse
+type browseModel struct {
+	items []browseItem
+	load  func(refresh bool) ([]browseItem, error)
+	// out receives the OSC 52 sequence when no system clipboard is available
+	out io.Writer
+
+	cursor   int
+	offset   int
+	height   int
+	revealed map[string]bool
+	loading  bool
+	status   string
+}
+
+func newBrowseModel(items []browseItem, load func(refresh bool) ([]browseItem, error), out io.Writer) *browseModel {
+	return &browseModel{
+		items:    items,
+		load:     load,
+		out:      out,
+		revealed: make(map[string]bool),
+	}
+}
+
+func (m *browseModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.scroll()
+	case browseLoadedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			break
+		}
+		m.items = msg.items
+		if m.cursor >= len(m.items) {
+			m.cursor = max(len(m.items)-1, 0)
+		}
+		m.scroll()
+		m.status = fmt.Sprintf("Refreshed %d secrets", len(m.items))
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			m.scroll()
+		case "down", "j":
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+			m.scroll()
+		case "enter", " ":
+			if item, ok := m.selected(); ok {
+				m.revealed[item.Key] = !m.revealed[item.Key]
+			}
+		case "c", "y":
+			if item, ok := m.selected(); ok {
+				m.copy(item)
+			}
+		case "r":
+			if !m.loading {
+				m.loading = true
+				m.status = "Refreshing..."
+				return m, m.refresh
+			}
+		}
+	}
+	return m, nil
+}
+
+// refresh fetches the secrets again, bypassing the cache
+func (m *browseModel) refresh() tea.Msg {
+	items, err := m.load(true)
+	return browseLoadedMsg{items: items, err: err}
+}
+
+// copy copies the value of item to the system clipboard, or through the terminal with OSC 52
+func (m *browseModel) copy(item browseItem) {
+	if err := clipboard.WriteAll(item.Value); err != nil {
+		fmt.Fprintf(m.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(item.Value)))
+	}
+	m.status = fmt.Sprintf("Copied %s to the clipboard", item.Key)
+}
+
+func (m *browseModel) selected() (browseItem, bool) {
+	if m.cursor < len(m.items) {
+		return m.items[m.cursor], true
+	}
+	return browseItem{}, false
+}
+
+// visibleRows returns how many secrets fit on the screen besides the header and footer
+func (m *browseModel) visibleRows() int {
+	if m.height == 0 {
+		return len(m.items)
+	}
+	return max(m.height-6, 1)
+}
+
+// scroll keeps the selected secret on the screen
+func (m *browseModel) scroll() {
+	rows := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+}
+
+func (m *browseModel) View() string {
+	var b strings.Builder
+	providerCount := make(map[string]bool)
+	keyWidth, providerWidth := len("KEY"), len("PROVIDER")
+	for _, item := range m.items {
+		providerCount[item.Provider] = true
+		keyWidth = max(keyWidth, len(item.Key))
+		providerWidth = max(providerWidth, len(item.Provider))
+	}
+
+	fmt.Fprintf(&b, "sstart browse: %d secrets from %d providers\n\n", len(m.items), len(providerCount))
+	fmt.Fprintf(&b, "  %-*s  %-*s  %s\n", providerWidth, "PROVIDER", keyWidth, "KEY", "VALUE")
+	end := min(m.offset+m.visibleRows(), len(m.items))
+	for i := m.offset; i < end; i++ {
+		item := m.items[i]
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		value := secrets.Mask(item.Value)
+		if m.revealed[item.Key] {
+			// Keep multi-line values on their row
+			value = strings.ReplaceAll(item.Value, "\n", `\n`)
+		}
+		fmt.Fprintf(&b, "%s %-*s  %-*s  %s\n", cursor, providerWidth, item.Provider, keyWidth, item.Key, value)
+	}
+	if len(m.items) == 0 {
+		b.WriteString("  (no secrets)\n")
+	}
+
+	b.WriteString("\n↑/↓ move • enter reveal • c copy • r refresh • q quit\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	return b.String()
+}
+
+func init() {
+	browseCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	addKeyFilterFlags(browseCmd)
+	rootCmd.AddCommand(browseCmd)
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/dirathea/sstart/internal/bundle"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOutput     string
+	bundleExpires    time.Duration
+	bundleRecipients []string
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle --file <file>",
+	Short: "Write collected secrets to an encrypted, expiring bundle for offline use",
+	Long: `Collect secrets and write them to an encrypted bundle that 'sstart run --from-bundle' can
+use where the secret backends cannot be reached, e.g. air-gapped deployments.
+
+The bundle is encrypted with age for the --recipient public keys, or with the passphrase in
+$SSTART_BUNDLE_PASSPHRASE. It expires after --expires, after which it is refused.
+
+Example:
+  sstart bundle --file secrets.bundle --recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+  SSTART_BUNDLE_PASSPHRASE=... sstart bundle --file secrets.bundle --expires 2h --providers aws-prod
+  sstart run --from-bundle secrets.bundle --identity key.txt -- ./app`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if bundleOutput == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if bundleExpires <= 0 {
+			return fmt.Errorf("--expires must be positive")
+		}
+		passphrase := os.Getenv(bundle.PassphraseEnv)
+		if len(bundleRecipients) == 0 && passphrase == "" {
+			return fmt.Errorf("set --recipient or $%s to encrypt the bundle", bundle.PassphraseEnv)
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		collected, err := newCollector(cfg).Collect(context.Background(), providers)
+		if err != nil {
+			return fmt.Errorf("failed to collect secrets: %w", err)
+		}
+		collected, err = secrets.FilterKeys(collected, onlyKeys, excludeKeys)
+		if err != nil {
+			return err
+		}
+
+		b := bundle.New(collected, cfg.Inherit, bundleExpires)
+		var encrypted bytes.Buffer
+		if err := b.Write(&encrypted, bundleRecipients, passphrase); err != nil {
+			return err
+		}
+		err = writePrivateFile(bundleOutput, func(w io.Writer) error {
+			_, err := encrypted.WriteTo(w)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d secrets to %s, expiring at %s\n", len(collected), bundleOutput, b.ExpiresAt.Format(time.RFC3339))
+		return nil
+	},
+}
+
+// readBundle decrypts a bundle with the age identity file, $SSTART_AGE_IDENTITY, or $SSTART_BUNDLE_PASSPHRASE
+func readBundle(path, identityFile string) (*bundle.Bundle, error) {
+	identityData := os.Getenv(bundle.IdentityEnv)
+	if identityFile != "" {
+		content, err := os.ReadFile(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read age identity: %w", err)
+		}
+		identityData = string(content)
+	}
+	var identities []age.Identity
+	if identityData != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(identityData))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity: %w", err)
+		}
+		identities = parsed
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer f.Close()
+	return bundle.Read(f, identities, os.Getenv(bundle.PassphraseEnv))
+}
+
+func init() {
+	bundleCmd.Flags().StringVarP(&bundleOutput, "file", "f", "", "File to write the bundle to, with mode 0600 (required)")
+	bundleCmd.Flags().DurationVar(&bundleExpires, "expires", 24*time.Hour, "How long the bundle can be used")
+	bundleCmd.Flags().StringSliceVar(&bundleRecipients, "recipient", []string{}, "age public key to encrypt the bundle for; repeat for several (default: $SSTART_BUNDLE_PASSPHRASE)")
+	bundleCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	addKeyFilterFlags(bundleCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
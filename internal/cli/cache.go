@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/dirathea/sstart/internal/cache"
+	"github.com/spf13/cobra"
+)
+
+var cacheResetMetrics bool
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect the secrets cache",
+	Long:  `Inspect the secrets cache stored in the system keyring.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache entries and hit/miss counters",
+	Long: `Show the number of cached entries and the hit/miss counters recorded across invocations.
+
+Use this to verify whether your cache TTL actually reduces provider API calls.
+
+Example:
+  sstart cache stats
+  sstart cache stats --reset`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cacheOpts := []cache.Option{}
+
+		// Use the configured TTL and keyring service if a config file is available; stats don't require one
+		if cfg, err := loadConfig(); err == nil {
+			if ttl := cfg.GetCacheTTL(); ttl > 0 {
+				cacheOpts = append(cacheOpts, cache.WithTTL(ttl))
+			}
+			if service := cfg.GetCacheKeyringService(); service != "" {
+				cacheOpts = append(cacheOpts, cache.WithKeyringService(service))
+			}
+		}
+
+		c := cache.New(cacheOpts...)
+		if !c.IsAvailable() {
+			return fmt.Errorf("cache is not available: system keyring could not be accessed")
+		}
+
+		if cacheResetMetrics {
+			if err := c.ResetMetrics(); err != nil {
+				return fmt.Errorf("failed to reset cache metrics: %w", err)
+			}
+			fmt.Println("Cache metrics reset")
+			return nil
+		}
+
+		total, valid, expired := c.Stats()
+		metrics := c.Metrics()
+
+		fmt.Printf("Entries:  %d (valid: %d, expired: %d)\n", total, valid, expired)
+		fmt.Printf("TTL:      %s\n", c.GetTTL())
+		fmt.Printf("Lookups:  %d\n", metrics.Lookups())
+		fmt.Printf("  Hits:    %d\n", metrics.Hits)
+		fmt.Printf("  Misses:  %d\n", metrics.Misses)
+		fmt.Printf("  Expired: %d\n", metrics.Expired)
+		fmt.Printf("Hit ratio: %.1f%%\n", metrics.HitRatio()*100)
+		if !metrics.Since.IsZero() {
+			fmt.Printf("Since:    %s\n", metrics.Since.Format("2006-01-02 15:04:05"))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	cacheStatsCmd.Flags().BoolVar(&cacheResetMetrics, "reset", false, "Reset the hit/miss counters")
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
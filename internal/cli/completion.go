@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// configFileExtensions are the extensions of config files completed for --config when none of the
+// discovered config files matches
+var configFileExtensions = []string{"yml", "yaml", "toml", "json"}
+
+// registerCompletions completes the values of the --config and --profile flags of cmd
+func registerCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("config", completeConfigPath)
+	_ = cmd.RegisterFlagCompletionFunc("profile", completeProfile)
+}
+
+// completeConfigPath suggests the config files in the current and parent directories, relative to
+// the current directory, falling back to the config files matching what is typed
+func completeConfigPath(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	paths, err := config.DiscoverAll(".")
+	if err != nil {
+		return configFileExtensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+	cwd, _ := os.Getwd()
+	var suggestions []string
+	for _, path := range paths {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+		}
+		if strings.HasPrefix(path, toComplete) {
+			suggestions = append(suggestions, path)
+		}
+	}
+	if len(suggestions) == 0 {
+		return configFileExtensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfile suggests the profiles defined by the config, including the global config. The
+// config is only parsed, so completing never contacts providers
+func completeProfile(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path, err := resolveConfigPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := config.ProfileNames(path, config.WithGlobalConfig(config.GlobalConfigPath()))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var suggestions []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
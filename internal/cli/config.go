@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the sstart configuration",
+	Long:  `Commands for working with the sstart configuration file.`,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for the config file",
+	Long: `Print the JSON Schema for .sstart.yml, including the configuration of every provider.
+
+Editors that use yaml-language-server (e.g. VS Code with the YAML extension) can use it
+for autocomplete and validation by adding this line to the top of .sstart.yml:
+
+  # yaml-language-server: $schema=` + schema.ID + `
+
+Example:
+  sstart config schema > sstart.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := schema.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to generate schema: %w", err)
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <path>",
+	Short: "Print a value from the config file",
+	Long: `Print the value at a path in the config file, as written in the file.
+
+Paths use dots for map keys and [n] for list items. Scalars are printed as plain
+text and other values as YAML.
+
+Examples:
+  sstart config get mcp.servers
+  sstart config get providers[0].region`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := editConfigPath()
+		if err != nil {
+			return err
+		}
+		value, err := config.GetValue(path, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "Set a value in the config file",
+	Long: `Set the value at a path in the YAML config file, preserving comments.
+
+The value is parsed as YAML, so lists and maps can be set using flow syntax.
+Missing keys are created, and an index equal to the length of a list appends to it.
+
+Examples:
+  sstart config set providers[0].region us-west-2
+  sstart config set cache.enabled true
+  sstart config set providers[2] '{kind: dotenv, path: .env.local}'`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := editConfigPath()
+		if err != nil {
+			return err
+		}
+		return config.SetValue(path, args[0], args[1])
+	},
+}
+
+var (
+	signPublicKey string
+	signSecretKey string
+)
+
+var configKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a key pair for signing config files",
+	Long: `Generate an Ed25519 key pair for signing config files with 'sstart config sign'.
+
+By default the keys are written to ~/.config/sstart/sign.key and ~/.config/sstart/sign.pub.
+Keep the secret key private; distribute the public key to the machines that run
+sstart with --verify-config. Existing keys are never overwritten.
+
+Example:
+  sstart config keygen`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.GenerateKeyPair(signPublicKey, signSecretKey); err != nil {
+			return err
+		}
+		fmt.Printf("Secret key: %s\nPublic key: %s\n", signSecretKey, signPublicKey)
+		return nil
+	},
+}
+
+var configSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign the config file",
+	Long: `Sign the config file, including the files it includes, and write the signature
+next to it with a .sig suffix (e.g. .sstart.yml.sig). Commit the signature together
+with the config file.
+
+Any change to the config requires signing it again before sstart runs with --verify-config.
+
+Example:
+  sstart config sign`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := editConfigPath()
+		if err != nil {
+			return err
+		}
+		if err := config.SignConfig(path, signSecretKey); err != nil {
+			return err
+		}
+		fmt.Printf("Signed %s (signature: %s)\n", path, path+config.SignatureSuffix)
+		return nil
+	},
+}
+
+var configVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the signature of the config file",
+	Long: `Check that the config file, including the files it includes, matches its signature.
+
+Example:
+  sstart config verify
+  sstart config verify --public-key team.pub`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := editConfigPath()
+		if err != nil {
+			return err
+		}
+		if err := config.VerifyConfig(path, signPublicKey); err != nil {
+			return err
+		}
+		fmt.Printf("%s: signature OK\n", path)
+		return nil
+	},
+}
+
+// editConfigPath returns the config file read and edited by config get/set
+func editConfigPath() (string, error) {
+	path, err := resolveConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", config.ErrNotFound
+	}
+	return path, nil
+}
+
+func init() {
+	configCmd.AddCommand(configSchemaCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	configKeygenCmd.Flags().StringVar(&signPublicKey, "public-key", config.DefaultPublicKeyPath(), "Path to write the public key to")
+	configKeygenCmd.Flags().StringVar(&signSecretKey, "secret-key", config.DefaultSecretKeyPath(), "Path to write the secret key to")
+	configSignCmd.Flags().StringVar(&signSecretKey, "secret-key", config.DefaultSecretKeyPath(), "Path to the secret key")
+	configVerifyCmd.Flags().StringVar(&signPublicKey, "public-key", config.DefaultPublicKeyPath(), "Path to the public key")
+	configCmd.AddCommand(configKeygenCmd)
+	configCmd.AddCommand(configSignCmd)
+	configCmd.AddCommand(configVerifyCmd)
+	rootCmd.AddCommand(configCmd)
+}
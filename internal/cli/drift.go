@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var driftExitCode bool
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Show which secrets changed since they were cached",
+	Long: `Fetch secrets from the providers and compare them with the values cached by the last run,
+to tell whether a long-running service started with those values needs a restart. Requires
+caching to be enabled in the config.
+
+Values are masked. The cache is not updated, so running drift again reports the same changes
+until a command collects the secrets through the cache again.
+
+Example:
+  sstart drift
+  sstart drift --providers aws-prod
+  sstart drift --exit-code && echo "no restart needed"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if !cfg.IsCacheEnabled() {
+			return fmt.Errorf("drift compares with cached secrets; enable 'cache' in the config")
+		}
+
+		drifts, err := newCollector(cfg).Drift(context.Background(), providers)
+		if err != nil {
+			return err
+		}
+
+		drifted := false
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tKEY\tCHANGE\tCACHED\tCURRENT")
+		for _, drift := range drifts {
+			if !drift.Cached {
+				fmt.Fprintf(w, "%s\t-\tnot cached\t\t\n", drift.Provider)
+				continue
+			}
+			for _, change := range drift.Changes {
+				drifted = true
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", drift.Provider, change.Key, change.Change, maskChange(change.Old), maskChange(change.New))
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if !drifted {
+			fmt.Fprintln(os.Stderr, "No secrets changed since they were cached")
+		} else if driftExitCode {
+			return &checkFailedError{code: 1}
+		}
+		return nil
+	},
+}
+
+// maskChange masks a value of a change, showing "-" for a missing one
+func maskChange(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return secrets.Mask(value)
+}
+
+func init() {
+	driftCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to check (default: all providers)")
+	driftCmd.Flags().BoolVar(&driftExitCode, "exit-code", false, "Exit with status 1 if secrets changed")
+	rootCmd.AddCommand(driftCmd)
+}
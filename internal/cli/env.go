@@ -1,68 +1,302 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/dirathea/sstart/internal/config"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/dirathea/sstart/internal/app"
 	"github.com/dirathea/sstart/internal/secrets"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-var envFormat string
+var (
+	envFormat    string
+	envMasked    bool
+	envAnnotate  bool
+	envYes       bool
+	envEncryptTo []string
+)
 
 var envCmd = &cobra.Command{
-	Use:   "env",
-	Short: "Export secrets in environment variable format",
+	Use:     "env [-- <command> [args...]]",
+	Aliases: []string{"export"},
+	Short:   "Export secrets in environment variable format",
 	Long: `Export secrets in a format suitable for --env-file or shell export.
 
+Use --masked to print the same output with values masked, for sharing in tickets or chat.
+When the output is a terminal, sstart asks before printing values in plaintext, and masks
+them unless you confirm; pass --yes to skip the question. Redirected output is unaffected.
+Use --only and --exclude with glob patterns to export a subset of the secrets.
+Use --set KEY=VALUE and --set-env KEY=FROM_ENV_VAR to add ad-hoc values over the collected
+secrets.
+Use --annotate to write a comment above each variable with the provider it came from and
+when it was fetched, to debug which of several providers sets a key.
+
+Use --encrypt-to with age public keys to encrypt the output in any format, ASCII-armored, so
+generated files can be committed or transferred and decrypted only where they are used, e.g.
+with 'age --decrypt -i key.txt'.
+
+Use --format launchd to generate a property list for a macOS launchd job, with the secrets
+in EnvironmentVariables and the command after -- as ProgramArguments. With --wrap, the
+command is run through 'sstart run' instead, so secrets are collected when the job starts
+and never written to the plist.
+
 Example:
   docker run --env-file <(sstart env) alpine sh
-  eval "$(sstart env)"`,
+  eval "$(sstart env)"
+  sstart env --masked --format yaml
+  sstart env --only 'STRIPE_*,DB_*' --exclude DB_ADMIN_PASSWORD
+  sstart env --annotate
+  sstart env --format github >> "$GITHUB_ENV"
+  sstart env --encrypt-to age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p > .env.age
+  sstart export --format launchd --label com.acme.app --wrap -- /usr/local/bin/app > ~/Library/LaunchAgents/com.acme.app.plist`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
+		overrides, err := parseOverrides()
+		if err != nil {
+			return err
+		}
+		recipients, err := parseRecipients(envEncryptTo)
+		if err != nil {
+			return err
+		}
+
+		if envFormat == "launchd" {
+			if launchdLabel == "" {
+				return fmt.Errorf("--label is required with --format launchd")
+			}
+			if launchdWrap {
+				if len(overrides) > 0 {
+					return fmt.Errorf("--set and --set-env cannot be used with --wrap")
+				}
+				if len(args) == 0 {
+					return fmt.Errorf("--wrap requires a command after --")
+				}
+				program, err := launchdWrapper(args)
+				if err != nil {
+					return err
+				}
+				return writeOutput(recipients, func(w io.Writer) error {
+					return writeLaunchd(w, nil, program)
+				})
+			}
+		} else if len(args) > 0 {
+			return fmt.Errorf("a command is only used with --format launchd")
+		}
+		if envAnnotate && envFormat != "shell" && envFormat != "yaml" {
+			return fmt.Errorf("--annotate is only supported with --format shell or yaml")
+		}
+
 		// Load configuration
-		cfg, err := config.Load(configPath)
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		// Encrypted output never reveals values on the terminal
+		if !envMasked && len(recipients) == 0 && !confirmReveal() {
+			envMasked = true
+			fmt.Fprintln(os.Stderr, "Values are masked: pass --yes to print them on a terminal, or redirect the output")
+		}
+
 		// Collect secrets
-		collector := secrets.NewCollector(cfg)
+		collector := newCollector(cfg)
 		envProviders := providers
 		if len(envProviders) == 0 {
 			envProviders = nil // Use all providers
 		}
-		envSecrets, err := collector.Collect(ctx, envProviders)
+		envSecrets, origins, err := collector.CollectWithSources(ctx, envProviders)
 		if err != nil {
 			return fmt.Errorf("failed to collect secrets: %w", err)
 		}
+		envSecrets, err = secrets.FilterKeys(envSecrets, onlyKeys, excludeKeys)
+		if err != nil {
+			return err
+		}
+		if origins == nil {
+			origins = make(map[string]string, len(overrides))
+		}
+		for key, value := range overrides {
+			envSecrets[key] = value
+			origins[key] = app.OverrideSource
+		}
 
-		// Export in requested format
-		switch envFormat {
-		case "json":
-			jsonBytes, err := json.MarshalIndent(envSecrets, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal JSON: %w", err)
-			}
-			fmt.Println(string(jsonBytes))
-		case "yaml":
-			for key, value := range envSecrets {
-				fmt.Printf("%s: %s\n", key, escapeYAML(value))
-			}
-		default: // shell format
+		if envMasked {
 			for key, value := range envSecrets {
-				fmt.Printf("export %s=%s\n", key, escapeShell(value))
+				envSecrets[key] = secrets.Mask(value)
 			}
 		}
 
-		return nil
+		if envFormat == "launchd" {
+			return writeOutput(recipients, func(w io.Writer) error {
+				return writeLaunchd(w, envSecrets, args)
+			})
+		}
+		var comments map[string]string
+		if envAnnotate {
+			comments = sourceComments(collector, envSecrets, origins)
+		}
+		return writeOutput(recipients, func(w io.Writer) error {
+			return writeEnv(w, envSecrets, envFormat, comments)
+		})
 	},
 }
 
+// parseRecipients parses the age public keys given to --encrypt-to
+func parseRecipients(keys []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, key := range keys {
+		r, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient '%s': %w", key, err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// writeOutput calls write with stdout, or with an ASCII-armored age encryption of it when there
+// are recipients
+func writeOutput(recipients []age.Recipient, write func(io.Writer) error) error {
+	if len(recipients) == 0 {
+		return write(os.Stdout)
+	}
+	armored := armor.NewWriter(os.Stdout)
+	encrypted, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt output: %w", err)
+	}
+	if err := write(encrypted); err != nil {
+		return err
+	}
+	if err := encrypted.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt output: %w", err)
+	}
+	if err := armored.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt output: %w", err)
+	}
+	return nil
+}
+
+// confirmReveal reports whether secret values may be printed in plaintext. Output that is not a
+// terminal always is; on a terminal, values are only revealed with --yes or once the user
+// confirms, so that a stray 'sstart env' does not dump secrets on screen
+func confirmReveal() bool {
+	if envYes || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	return askReveal(os.Stdin, os.Stderr)
+}
+
+// askReveal asks on w whether to print secret values in plaintext, and reports whether the
+// answer read from r is yes
+func askReveal(r io.Reader, w io.Writer) bool {
+	fmt.Fprint(w, "⚠️  sstart env prints secret values in plaintext on your terminal. Reveal them? [y/N]: ")
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// sourceComments returns a comment per key naming the provider it came from and when that
+// provider was fetched, if known
+func sourceComments(collector *secrets.Collector, envSecrets, origins map[string]string) map[string]string {
+	comments := make(map[string]string, len(envSecrets))
+	for key := range envSecrets {
+		source := origins[key]
+		if source == "" {
+			continue
+		}
+		comment := "source: " + source
+		if fetchedAt := collector.FetchedAt(source); !fetchedAt.IsZero() {
+			comment += ", fetched " + fetchedAt.Format(time.RFC3339)
+		}
+		comments[key] = comment
+	}
+	return comments
+}
+
+// writeEnv writes secrets in the given format (shell, json, yaml, or github), with keys sorted
+// so output is stable across runs. Comments, if any, are written above their keys in the
+// shell and yaml formats
+func writeEnv(w io.Writer, envSecrets map[string]string, format string, comments map[string]string) error {
+	keys := sortedKeys(envSecrets)
+	switch format {
+	case "json":
+		jsonBytes, err := json.MarshalIndent(envSecrets, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(jsonBytes))
+	case "yaml":
+		for _, key := range keys {
+			writeComment(w, comments[key])
+			fmt.Fprintf(w, "%s: %s\n", key, escapeYAML(envSecrets[key]))
+		}
+	case "github":
+		// Multi-line syntax of $GITHUB_ENV, with a random delimiter so values cannot end the block early
+		delimiter, err := newServeToken()
+		if err != nil {
+			return err
+		}
+		delimiter = "sstart_" + delimiter
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", key, delimiter, envSecrets[key], delimiter)
+		}
+	default: // shell format
+		for _, key := range keys {
+			writeComment(w, comments[key])
+			fmt.Fprintf(w, "export %s=%s\n", key, escapeShell(envSecrets[key]))
+		}
+	}
+	return nil
+}
+
+// writeComment writes a comment line, unless comment is empty
+func writeComment(w io.Writer, comment string) {
+	if comment != "" {
+		fmt.Fprintf(w, "# %s\n", comment)
+	}
+}
+
+// sortedKeys returns the keys of secrets in sorted order
+func sortedKeys(secrets map[string]string) []string {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validEnvName reports whether key is a valid shell variable name: letters, digits, and
+// underscores, not starting with a digit
+func validEnvName(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
 func escapeShell(s string) string {
 	// Escape single quotes by ending the quoted string, escaping the quote, and restarting
 	s = strings.ReplaceAll(s, "'", "'\"'\"'")
@@ -82,7 +316,15 @@ func escapeYAML(s string) string {
 }
 
 func init() {
-	envCmd.Flags().StringVar(&envFormat, "format", "shell", "Output format: shell, json, or yaml")
+	envCmd.Flags().StringVar(&envFormat, "format", "shell", "Output format: shell, json, yaml, github ($GITHUB_ENV syntax), or launchd (plist)")
+	envCmd.Flags().StringVar(&launchdLabel, "label", "", "Label of the launchd job (with --format launchd)")
+	envCmd.Flags().BoolVar(&launchdWrap, "wrap", false, "Run the command through 'sstart run' instead of embedding secrets in the plist (with --format launchd)")
+	envCmd.Flags().BoolVar(&envMasked, "masked", false, "Mask secret values like sstart show does")
+	envCmd.Flags().BoolVar(&envYes, "yes", false, "Print values in plaintext on a terminal without asking")
+	envCmd.Flags().StringSliceVar(&envEncryptTo, "encrypt-to", []string{}, "age public key to encrypt the output for; repeat for several")
+	envCmd.Flags().BoolVar(&envAnnotate, "annotate", false, "Write a comment with the source provider and fetch time above each variable (shell and yaml formats)")
 	envCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	addKeyFilterFlags(envCmd)
+	addOverrideFlags(envCmd)
 	rootCmd.AddCommand(envCmd)
 }
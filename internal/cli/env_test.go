@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAskReveal(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   bool
+	}{
+		{name: "y", answer: "y\n", want: true},
+		{name: "yes", answer: "yes\n", want: true},
+		{name: "upper case", answer: "YES\n", want: true},
+		{name: "surrounding spaces", answer: "  y \r\n", want: true},
+		{name: "without newline", answer: "y", want: true},
+		{name: "n", answer: "n\n", want: false},
+		{name: "empty answer", answer: "\n", want: false},
+		{name: "end of input", answer: "", want: false},
+		{name: "other word", answer: "yess\n", want: false},
+		{name: "only the first line counts", answer: "\ny\n", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt strings.Builder
+			if got := askReveal(strings.NewReader(tt.answer), &prompt); got != tt.want {
+				t.Errorf("askReveal(%q) = %v, want %v", tt.answer, got, tt.want)
+			}
+			if !strings.Contains(prompt.String(), "Reveal them? [y/N]") {
+				t.Errorf("expected the question to be asked, got %q", prompt.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dirathea/sstart/internal/agent"
+	"github.com/dirathea/sstart/internal/app"
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// Exit codes of sstart. When the command run by sstart fails, sstart exits with the command's
+// own exit code (or 128 plus the signal number if it was killed by a signal), like tini. Set
+// --exit-code-offset to add an offset, capped at exitMax, so that it cannot be mistaken for one of these
+const (
+	exitFailure  = 1 // any other error
+	exitUsage    = 2 // invalid command, flags or arguments
+	exitConfig   = 3 // the config file cannot be found, read or validated
+	exitAuth     = 4 // SSO authentication failed, or the SSO identity lacks required claims
+	exitProvider = 5 // a provider could not be created or failed to fetch its secrets
+
+	exitInterrupted = 130 // collecting secrets was interrupted by a signal, like a shell's exit code for Ctrl+C
+
+	exitMax = 255 // the highest exit code a process can report
+)
+
+// Values of --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// providerErrorClasses name the classes of provider errors with --output json, and hint at
+// how to fix them. Hints are formatted with the provider ID
+var providerErrorClasses = []struct {
+	class error
+	name  string
+	hint  string
+}{
+	{provider.ErrAuth, "auth", "provider '%s' rejected its credentials, or they lack access to the secret: check its token or auth settings"},
+	{provider.ErrNotFound, "not_found", "the secret of provider '%s' does not exist: check its path or name in the config"},
+	{provider.ErrRateLimited, "rate_limited", "provider '%s' is throttling requests: retry later, or enable the secret cache to fetch less often"},
+	{provider.ErrNetwork, "network", "provider '%s' could not be reached: check its address and your network connection"},
+}
+
+// providerErrorClass returns the name and hint of the class of a provider error, empty if
+// err is not a provider error or has no class. A hint of the provider itself is preferred
+func providerErrorClass(err error) (string, string) {
+	var fetchErr *secrets.FetchError
+	if !errors.As(err, &fetchErr) {
+		return "", ""
+	}
+	name, hint := "", provider.ErrorHint(err)
+	class := fetchErr.Class()
+	for _, c := range providerErrorClasses {
+		if class == c.class {
+			name = c.name
+			if hint == "" {
+				hint = fmt.Sprintf(c.hint, fetchErr.Provider)
+			}
+		}
+	}
+	return name, hint
+}
+
+// usageError is an error in the command line, reported before the command runs
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+// checkFailedError makes a check like sstart drift --exit-code exit with code, without reporting an
+// error since the check has printed its results. Unlike a failed command, code is not offset
+type checkFailedError struct {
+	code int
+}
+
+func (e *checkFailedError) Error() string { return fmt.Sprintf("check failed with code %d", e.code) }
+
+// configError is an error loading the config file
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+
+func (e *configError) Unwrap() error { return e.err }
+
+// commandExitCode returns the exit code of sstart for a command that exited with code
+func commandExitCode(code int) int {
+	return min(code+exitCodeOffset, exitMax)
+}
+
+// classifyError returns the type of err reported with --output json, and the exit code for it
+func classifyError(err error) (string, int) {
+	var exitErr *app.ExitError
+	var checkErr *checkFailedError
+	var usageErr *usageError
+	var configErr *configError
+	var agentConfigErr *agent.ConfigError
+	var authErr *secrets.AuthError
+	var fetchErr *secrets.FetchError
+	switch {
+	case errors.As(err, &exitErr):
+		return "command", commandExitCode(exitErr.Code)
+	case errors.As(err, &checkErr):
+		return "command", checkErr.code
+	case errors.Is(err, secrets.ErrInterrupted):
+		return "interrupted", exitInterrupted
+	case errors.As(err, &usageErr):
+		return "usage", exitUsage
+	case errors.As(err, &configErr), errors.As(err, &agentConfigErr), errors.Is(err, config.ErrUntrusted):
+		return "config", exitConfig
+	case errors.As(err, &authErr):
+		return "auth", exitAuth
+	case errors.As(err, &fetchErr):
+		return "provider", exitProvider
+	default:
+		return "error", exitFailure
+	}
+}
+
+// jsonError is the error written to stderr with --output json
+type jsonError struct {
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+	Provider string `json:"provider,omitempty"`
+	Class    string `json:"class,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	// CommandExitCode is the exit code of the failed command, before --exit-code-offset
+	CommandExitCode *int `json:"command_exit_code,omitempty"`
+}
+
+// reportError writes err to w in the given output format and returns the exit code for it.
+// In text format, a failing command is not reported since it has reported its own error, and
+// provider errors of a known class are followed by a hint
+func reportError(w io.Writer, err error, format string) int {
+	errType, code := classifyError(err)
+	class, hint := providerErrorClass(err)
+	if format != outputJSON {
+		if errType != "command" {
+			fmt.Fprintf(w, "Error: %v\n", err)
+			if hint != "" {
+				fmt.Fprintf(w, "Hint: %s\n", hint)
+			}
+		}
+		return code
+	}
+
+	report := jsonError{Type: errType, Message: err.Error(), ExitCode: code, Class: class, Hint: hint}
+	var fetchErr *secrets.FetchError
+	if errors.As(err, &fetchErr) {
+		report.Provider = fetchErr.Provider
+	}
+	var exitErr *app.ExitError
+	if errors.As(err, &exitErr) {
+		report.CommandExitCode = &exitErr.Code
+	}
+	data, _ := json.Marshal(map[string]jsonError{"error": report})
+	fmt.Fprintln(w, string(data))
+	return code
+}
@@ -0,0 +1,329 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables holding the state of the shell hook between prompts
+const (
+	// hookConfigEnv is the config file (and its modification time) whose secrets are loaded
+	hookConfigEnv = "SSTART_HOOK_CONFIG"
+	// hookKeysEnv lists the keys loaded by the hook
+	hookKeysEnv = "SSTART_HOOK_KEYS"
+	// hookPrevEnv holds the values the loaded keys had before, restored when the secrets are unloaded
+	hookPrevEnv = "SSTART_HOOK_PREV"
+)
+
+// hookScripts are evaluated by each shell to call 'sstart hook export' before every prompt
+var hookScripts = map[string]string{
+	"bash": `_sstart_hook() {
+  local previous_exit_status=$?
+  eval "$(%[1]q hook export bash)"
+  return $previous_exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_sstart_hook;"* ]]; then
+  PROMPT_COMMAND="_sstart_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`,
+	"zsh": `_sstart_hook() {
+  eval "$(%[1]q hook export zsh)"
+}
+typeset -ag precmd_functions chpwd_functions
+if (( ! ${precmd_functions[(I)_sstart_hook]} )); then
+  precmd_functions=(_sstart_hook $precmd_functions)
+fi
+if (( ! ${chpwd_functions[(I)_sstart_hook]} )); then
+  chpwd_functions=(_sstart_hook $chpwd_functions)
+fi
+`,
+	"fish": `function __sstart_hook --on-event fish_prompt
+  %[1]q hook export fish | source
+end
+`,
+}
+
+// direnvScript defines 'use sstart' for direnv's stdlib. It watches the config files of this
+// directory, so creating one loads it, and those the config was read from, includes and config
+// files of parent directories included
+const direnvScript = `use_sstart() {
+  watch_file .sstart.yml .sstart.toml .sstart.json
+  eval "$(sstart "$@" hook watch)"
+  eval "$(sstart "$@" env --format shell)"
+}
+`
+
+var hookCmd = &cobra.Command{
+	Use:       "hook <bash|zsh|fish>",
+	Short:     "Print a shell hook that loads secrets when entering a directory with a config file",
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Long: `Print a shell hook that loads secrets into the shell when you enter a directory with a
+.sstart.yml (or a subdirectory of it), and unloads them when you leave.
+
+The hook runs before every prompt but only collects secrets when the config file found
+from the current directory changes, so prompts stay fast. Values that the secrets replaced
+are restored when they are unloaded. Run 'unset SSTART_HOOK_CONFIG' to load the secrets again.
+
+Config files can run commands, so secrets are only loaded from config files allowed with
+'sstart allow', which has to be run again after they change.
+
+Add one of these lines to your shell's startup file:
+  eval "$(sstart hook bash)"     # ~/.bashrc
+  eval "$(sstart hook zsh)"      # ~/.zshrc
+  sstart hook fish | source      # ~/.config/fish/config.fish`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		script, ok := hookScripts[args[0]]
+		if !ok {
+			return fmt.Errorf("unsupported shell '%s' (supported: bash, zsh, fish)", args[0])
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			executable = "sstart"
+		}
+		fmt.Printf(script, executable)
+		return nil
+	},
+}
+
+var hookExportCmd = &cobra.Command{
+	Use:    "export <bash|zsh|fish>",
+	Short:  "Print the commands that load or unload secrets for the current directory",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := args[0]
+		if _, ok := hookScripts[shell]; !ok {
+			return fmt.Errorf("unsupported shell '%s' (supported: bash, zsh, fish)", shell)
+		}
+		return hookExport(os.Stdout, shell)
+	},
+}
+
+var hookWatchCmd = &cobra.Command{
+	Use:    "watch",
+	Short:  "Print direnv watch_file commands for the files the config is read from",
+	Hidden: true,
+	// use_sstart passes its arguments to both this and 'sstart env', so flags of env are ignored
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			// 'sstart env' reports the error
+			return nil
+		}
+		for _, file := range cfg.Files {
+			fmt.Printf("watch_file %s\n", escapeShell(file))
+		}
+		return nil
+	},
+}
+
+var direnvCmd = &cobra.Command{
+	Use:   "direnv",
+	Short: "Print a direnv helper that loads secrets with 'use sstart'",
+	Long: `Print a 'use_sstart' function for direnv. direnv only runs it again when .envrc or the
+sstart config files change, and unloads the secrets when you leave the directory.
+
+Add it to direnv's library, then use it in .envrc:
+  sstart direnv >> ~/.config/direnv/direnvrc
+  echo 'use sstart' > .envrc                  # or: use sstart --profile dev
+  direnv allow`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Print(direnvScript)
+		return nil
+	},
+}
+
+// hookExport writes the commands that bring the shell in line with the config file found from
+// the current directory: nothing when it is unchanged, otherwise unloading the previous secrets
+// and loading the new ones. Secrets are only loaded from config files allowed with 'sstart allow'
+func hookExport(w io.Writer, shell string) error {
+	path, err := resolveConfigPath()
+	if err != nil {
+		return err
+	}
+	target := ""
+	var trustErr error
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		target = fmt.Sprintf("%s@%d", path, info.ModTime().UnixNano())
+		// Allowing the config changes the target, so its secrets are loaded at the next prompt
+		if trustErr = config.CheckTrusted(path); trustErr != nil {
+			target += ":denied"
+		}
+	}
+	if target == os.Getenv(hookConfigEnv) {
+		return nil
+	}
+
+	// Unload the previously loaded secrets, restoring the values they replaced
+	env := make(map[string]*string)
+	prev := decodeHookPrev(os.Getenv(hookPrevEnv))
+	loaded := hookLoadedKeys()
+	for _, key := range loaded {
+		if value, ok := prev[key]; ok {
+			env[key] = &value
+		} else {
+			env[key] = nil
+		}
+	}
+	if len(loaded) > 0 {
+		fmt.Fprintf(os.Stderr, "sstart: unloaded %d secrets\n", len(loaded))
+	}
+	env[hookKeysEnv] = nil
+	env[hookPrevEnv] = nil
+	env[hookConfigEnv] = nil
+
+	if path != "" {
+		// Record the config even when loading fails, so a failure is reported once rather than at every prompt
+		env[hookConfigEnv] = &target
+		if trustErr != nil {
+			fmt.Fprintf(os.Stderr, "sstart: %v (run 'sstart allow' to load its secrets)\n", trustErr)
+		} else if collected, err := hookCollect(); err != nil {
+			fmt.Fprintf(os.Stderr, "sstart: %v (run 'unset %s' to retry)\n", err, hookConfigEnv)
+		} else if len(collected) > 0 {
+			newPrev := make(map[string]string)
+			keys := sortedKeys(collected)
+			for _, key := range keys {
+				// The value the key has once the previous secrets are unloaded
+				if restored, ok := env[key]; ok {
+					if restored != nil {
+						newPrev[key] = *restored
+					}
+				} else if value, ok := os.LookupEnv(key); ok {
+					newPrev[key] = value
+				}
+				value := collected[key]
+				env[key] = &value
+			}
+			keyList := strings.Join(keys, ",")
+			env[hookKeysEnv] = &keyList
+			if len(newPrev) > 0 {
+				encoded := encodeHookPrev(newPrev)
+				env[hookPrevEnv] = &encoded
+			}
+			fmt.Fprintf(os.Stderr, "sstart: loaded %d secrets from %s\n", len(keys), path)
+		}
+	}
+
+	for _, key := range sortedEnvKeys(env) {
+		if value := env[key]; value != nil {
+			fmt.Fprintln(w, hookSet(shell, key, *value))
+		} else {
+			fmt.Fprintln(w, hookUnset(shell, key))
+		}
+	}
+	return nil
+}
+
+// hookCollect collects the secrets of the current config, like sstart env. It runs at a prompt,
+// so SSO authentication fails instead of opening a browser or asking to log in again
+func hookCollect() (map[string]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	collected, err := newCollector(cfg, secrets.WithNonInteractive(true)).Collect(context.Background(), providers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect secrets: %w", err)
+	}
+	filtered, err := secrets.FilterKeys(collected, onlyKeys, excludeKeys)
+	if err != nil {
+		return nil, err
+	}
+	// Keys are written into shell commands unquoted, so only names the shell accepts are loaded
+	for key := range filtered {
+		if !validEnvName(key) {
+			fmt.Fprintf(os.Stderr, "sstart: skipped secret '%s': not a valid environment variable name\n", key)
+			delete(filtered, key)
+		}
+	}
+	return filtered, nil
+}
+
+// hookLoadedKeys returns the keys loaded by the hook
+func hookLoadedKeys() []string {
+	value := os.Getenv(hookKeysEnv)
+	if value == "" {
+		return nil
+	}
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if validEnvName(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func encodeHookPrev(prev map[string]string) string {
+	data, _ := json.Marshal(prev)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func decodeHookPrev(value string) map[string]string {
+	prev := make(map[string]string)
+	if data, err := base64.StdEncoding.DecodeString(value); err == nil {
+		_ = json.Unmarshal(data, &prev)
+	}
+	return prev
+}
+
+// sortedEnvKeys returns the keys of env in sorted order
+func sortedEnvKeys(env map[string]*string) []string {
+	keys := make(map[string]string, len(env))
+	for key := range env {
+		keys[key] = ""
+	}
+	return sortedKeys(keys)
+}
+
+// hookSet returns the command setting an environment variable in shell
+func hookSet(shell, key, value string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -gx %s %s;", key, escapeFish(value))
+	}
+	return fmt.Sprintf("export %s=%s;", key, escapeShell(value))
+}
+
+// hookUnset returns the command removing an environment variable in shell
+func hookUnset(shell, key string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -e %s;", key)
+	}
+	return fmt.Sprintf("unset %s;", key)
+}
+
+// escapeFish quotes s for fish, where backslashes and single quotes are escaped inside single quotes
+func escapeFish(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "'", `\'`)
+	return "'" + s + "'"
+}
+
+func init() {
+	hookExportCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	addKeyFilterFlags(hookExportCmd)
+	hookCmd.AddCommand(hookExportCmd)
+	hookCmd.AddCommand(hookWatchCmd)
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(direnvCmd)
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/importer"
+	"github.com/dirathea/sstart/internal/provider/dotenv"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importKeys        []string
+	importEnvironment string
+	importTo          string
+	importDir         string
+	importForce       bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <dotenv-vault|envkey> <file>",
+	Short: "Import secrets from dotenv-vault or EnvKey",
+	Long: `Import the secrets of a dotenv-vault .env.vault file or an EnvKey export, to migrate to sstart.
+
+dotenv-vault environments are decrypted with their DOTENV_KEY (--key, default: $DOTENV_KEY),
+which also names the environment. Several keys can be given to import several environments.
+EnvKey exports are read as a JSON object of names to values, or in .env format; --environment
+names the imported environment.
+
+By default, each environment is written to a .env.<environment> file (mode 0600) in --dir, and
+the sstart config reading them with dotenv providers is printed. Use --to to write the secrets
+of a single environment into a provider of the current config instead, e.g. a Vault path.
+
+Example:
+  sstart import dotenv-vault .env.vault --key "$DOTENV_KEY_PRODUCTION" > .sstart.yml
+  sstart import envkey staging.json --environment staging --dir config
+  sstart import dotenv-vault .env.vault --to vault-prod`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"dotenv-vault", "envkey"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", args[1], err)
+		}
+
+		var envs []importer.Environment
+		switch args[0] {
+		case "dotenv-vault":
+			keys := importKeys
+			if len(keys) == 0 && os.Getenv("DOTENV_KEY") != "" {
+				keys = strings.Split(os.Getenv("DOTENV_KEY"), ",")
+			}
+			envs, err = importer.DotenvVault(data, keys)
+		case "envkey":
+			var secrets map[string]string
+			secrets, err = importer.EnvKey(data)
+			envs = []importer.Environment{{Name: importEnvironment, Secrets: secrets}}
+		default:
+			return fmt.Errorf("unsupported import source '%s' (supported: dotenv-vault, envkey)", args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		if importTo != "" {
+			if len(envs) != 1 {
+				return fmt.Errorf("--to imports a single environment, got %d", len(envs))
+			}
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := newCollector(cfg).Write(context.Background(), importTo, envs[0].Secrets); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Imported %d secrets of environment '%s' into provider '%s'\n", len(envs[0].Secrets), envs[0].Name, importTo)
+			return nil
+		}
+
+		paths := make([]string, len(envs))
+		for i, env := range envs {
+			paths[i] = filepath.Join(importDir, ".env."+env.Name)
+			if err := writeImportedEnv(paths[i], env.Secrets, importForce); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %d secrets of environment '%s' to %s\n", len(env.Secrets), env.Name, paths[i])
+		}
+		writeImportedConfig(os.Stdout, envs, paths)
+		return nil
+	},
+}
+
+// writeImportedEnv writes secrets to a .env file with mode 0600, refusing to replace an existing file unless force is set
+func writeImportedEnv(path string, secrets map[string]string, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use --force to replace it)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	for _, key := range sortedKeys(secrets) {
+		fmt.Fprintf(f, "%s=%s\n", key, dotenv.Quote(secrets[key]))
+	}
+	return f.Close()
+}
+
+// writeImportedConfig writes the sstart config reading the imported .env files
+func writeImportedConfig(w io.Writer, envs []importer.Environment, paths []string) {
+	fmt.Fprintln(w, "providers:")
+	for i, env := range envs {
+		fmt.Fprintf(w, "  - kind: dotenv\n    id: %s\n    path: %s\n", env.Name, paths[i])
+	}
+}
+
+func init() {
+	importCmd.Flags().StringSliceVar(&importKeys, "key", []string{}, "DOTENV_KEY of each dotenv-vault environment to import (default: $DOTENV_KEY)")
+	importCmd.Flags().StringVar(&importEnvironment, "environment", "imported", "Name of the environment imported from EnvKey")
+	importCmd.Flags().StringVar(&importTo, "to", "", "ID of a provider of the current config to write the secrets into, instead of .env files")
+	importCmd.Flags().StringVar(&importDir, "dir", ".", "Directory to write the .env files to")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Replace existing .env files")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	launchdLabel string
+	launchdWrap  bool
+)
+
+// writeLaunchd writes a launchd property list for the job launchdLabel running program.
+// Secrets are embedded in EnvironmentVariables, or, with --wrap, program is run through
+// 'sstart run' so secrets are collected each time the job starts and never stored in the plist.
+func writeLaunchd(w io.Writer, envSecrets map[string]string, program []string) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	writePlistKey(&b, 1, "Label")
+	writePlistString(&b, 1, launchdLabel)
+
+	if len(program) > 0 {
+		writePlistKey(&b, 1, "ProgramArguments")
+		b.WriteString("\t<array>\n")
+		for _, arg := range program {
+			writePlistString(&b, 2, arg)
+		}
+		b.WriteString("\t</array>\n")
+	}
+
+	if !launchdWrap && len(envSecrets) > 0 {
+		writePlistKey(&b, 1, "EnvironmentVariables")
+		b.WriteString("\t<dict>\n")
+		for _, key := range sortedKeys(envSecrets) {
+			writePlistKey(&b, 2, key)
+			writePlistString(&b, 2, envSecrets[key])
+		}
+		b.WriteString("\t</dict>\n")
+	}
+
+	b.WriteString("</dict>\n</plist>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// launchdWrapper returns the arguments that run program through sstart with the current config and profile
+func launchdWrapper(program []string) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the sstart executable: %w", err)
+	}
+	path, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	wrapper := []string{executable}
+	// launchd starts jobs in /, so the config is referenced by its absolute path
+	if path != "" {
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+		wrapper = append(wrapper, "--config", path)
+	}
+	if p := activeProfile(); p != "" {
+		wrapper = append(wrapper, "--profile", p)
+	}
+	if len(providers) > 0 {
+		wrapper = append(wrapper, "--providers", strings.Join(providers, ","))
+	}
+	if len(onlyKeys) > 0 {
+		wrapper = append(wrapper, "--only", strings.Join(onlyKeys, ","))
+	}
+	if len(excludeKeys) > 0 {
+		wrapper = append(wrapper, "--exclude", strings.Join(excludeKeys, ","))
+	}
+	wrapper = append(wrapper, "run", "--")
+	return append(wrapper, program...), nil
+}
+
+func writePlistKey(b *strings.Builder, indent int, key string) {
+	b.WriteString(strings.Repeat("\t", indent) + "<key>")
+	_ = xml.EscapeText(b, []byte(key))
+	b.WriteString("</key>\n")
+}
+
+func writePlistString(b *strings.Builder, indent int, value string) {
+	b.WriteString(strings.Repeat("\t", indent) + "<string>")
+	_ = xml.EscapeText(b, []byte(value))
+	b.WriteString("</string>\n")
+}
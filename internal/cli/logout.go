@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the SSO session and the secrets cached under it",
+	Long: `Remove the stored SSO tokens, and purge the cached secrets and Vault tokens that were
+obtained with them, so that cached values cannot be read after you leave a role until their
+TTL expires. The next command that needs SSO logs in again.
+
+Secrets cached from providers that do not use SSO are kept. An 'sstart agent' keeps its
+secrets in memory; stop it with 'sstart agent stop'.
+
+Example:
+  sstart logout`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cfg.SSO == nil || cfg.SSO.OIDC == nil {
+			return &configError{err: fmt.Errorf("the config has no 'sso' section to log out of")}
+		}
+
+		loggedIn, purged, err := newCollector(cfg).Logout()
+		if err != nil {
+			return fmt.Errorf("failed to log out: %w", err)
+		}
+		if !loggedIn {
+			fmt.Fprintln(os.Stderr, "No SSO session found")
+		} else {
+			fmt.Fprintln(os.Stderr, "Logged out")
+		}
+		if purged > 0 {
+			fmt.Fprintf(os.Stderr, "Removed %d cached provider secret(s) and token(s) obtained with the session\n", purged)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
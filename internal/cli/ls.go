@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsAge      bool
+	lsMetadata bool
+)
+
+var lsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List collected secret keys and their providers",
+	Long: `List the keys of all secrets that would be injected, with the provider each one comes from.
+Values are never printed.
+
+Use --age to also show when each secret was last rotated, for providers that expose it
+(AWS Secrets Manager, Vault KV v2, Doppler). Secrets older than rotation.max_age are flagged.
+
+Use --metadata to also show custom metadata fields of the secrets, e.g. their owner, for providers
+configured to expose them (Vault KV v2 with custom_metadata: true).
+
+Example:
+  sstart ls
+  sstart ls --age --providers aws-prod
+  sstart ls --metadata`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		// Load configuration
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		collector := newCollector(cfg)
+		lsProviders := providers
+		if len(lsProviders) == 0 {
+			lsProviders = nil // Use all providers
+		}
+		envSecrets, sources, err := collector.CollectWithSources(ctx, lsProviders)
+		if err != nil {
+			return fmt.Errorf("failed to collect secrets: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !lsAge && !lsMetadata {
+			fmt.Fprintln(w, "KEY\tPROVIDER")
+			for _, key := range sortedKeys(envSecrets) {
+				fmt.Fprintf(w, "%s\t%s\n", key, sources[key])
+			}
+			return w.Flush()
+		}
+
+		// Fetch metadata once per provider
+		metadata := make(map[string][]provider.SecretMetadata)
+		for _, key := range sortedKeys(envSecrets) {
+			providerID := sources[key]
+			if _, fetched := metadata[providerID]; fetched {
+				continue
+			}
+			if _, err := cfg.GetProvider(providerID); err != nil {
+				// Set from defaults rather than fetched from a provider
+				metadata[providerID] = nil
+				continue
+			}
+			m, err := collector.FetchMetadata(ctx, providerID)
+			if err != nil {
+				return err
+			}
+			metadata[providerID] = m
+		}
+
+		maxAge := cfg.RotationMaxAge()
+		header := "KEY\tPROVIDER\t"
+		if lsAge {
+			header += "LAST ROTATED\tAGE\t\t"
+		}
+		if lsMetadata {
+			header += "METADATA\t"
+		}
+		fmt.Fprintln(w, header)
+		for _, key := range sortedKeys(envSecrets) {
+			row := fmt.Sprintf("%s\t%s\t", key, sources[key])
+			if lsAge {
+				changed, ok := secrets.LastChanged(metadata[sources[key]], key)
+				if !ok {
+					row += "-\t-\t\t"
+				} else {
+					age := time.Since(changed)
+					status := ""
+					if maxAge > 0 && age > maxAge {
+						status = fmt.Sprintf("older than %s", secrets.FormatAge(maxAge))
+					}
+					row += fmt.Sprintf("%s\t%s\t%s\t", changed.UTC().Format(time.DateOnly), secrets.FormatAge(age), status)
+				}
+			}
+			if lsMetadata {
+				row += formatCustomMetadata(secrets.CustomMetadata(metadata[sources[key]], key)) + "\t"
+			}
+			fmt.Fprintln(w, row)
+		}
+		return w.Flush()
+	},
+}
+
+// formatCustomMetadata formats custom metadata fields as sorted field=value pairs, or - if there are none
+func formatCustomMetadata(custom map[string]string) string {
+	if len(custom) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(custom))
+	for _, field := range sortedKeys(custom) {
+		pairs = append(pairs, field+"="+custom[field])
+	}
+	return strings.Join(pairs, " ")
+}
+
+func init() {
+	lsCmd.Flags().BoolVar(&lsAge, "age", false, "Show when each secret was last rotated, for providers that expose it")
+	lsCmd.Flags().BoolVar(&lsMetadata, "metadata", false, "Show custom metadata fields of each secret, for providers that expose them")
+	lsCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	rootCmd.AddCommand(lsCmd)
+}
@@ -2,17 +2,31 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/dirathea/sstart/internal/app"
 	"github.com/dirathea/sstart/internal/config"
 	"github.com/dirathea/sstart/internal/mcp"
 	"github.com/dirathea/sstart/internal/secrets"
 	"github.com/spf13/cobra"
 )
 
+// mcpTokenEnv sets the bearer token required by sstart mcp --listen
+const mcpTokenEnv = "SSTART_MCP_TOKEN"
+
+var (
+	mcpListen       string
+	mcpDrainTimeout time.Duration
+	mcpRecord       string
+)
+
 var mcpCmd = &cobra.Command{
 	Use:   "mcp",
 	Short: "Run as MCP proxy with secret injection",
@@ -42,6 +56,25 @@ Example configuration (.sstart.yml):
         command: npx
         args: ["@modelcontextprotocol/server-filesystem", "/allowed/path"]
 
+Use --listen to serve several hosts at once over HTTP instead of stdin/stdout, for
+example to point a team's IDEs at one proxy. Hosts connect with the streamable HTTP
+transport at /mcp, or with the HTTP+SSE transport at /sse. Each host gets its own
+session, and all sessions share the downstream servers. Requests must send the bearer
+token from $SSTART_MCP_TOKEN; without it, a random token is generated and printed to
+stderr.
+
+On SIGINT or SIGTERM, the proxy stops accepting requests, waits up to --drain-timeout
+for the tool calls in flight to be answered, then closes the stdin of the downstream
+servers so they can exit on their own, before terminating them.
+
+Use --metrics-listen to expose Prometheus metrics (provider fetches, cache hits,
+downstream request latency, server restarts) while the proxy runs.
+
+Use --record to debug protocol incompatibilities between a host and a server: every
+JSON-RPC message between the hosts, the proxy and the downstream servers is written to
+the file as a JSON line, with a timestamp and its direction. The values of the
+collected secrets are replaced with asterisks.
+
 Example usage in Claude Desktop config:
   {
     "mcpServers": {
@@ -64,7 +97,7 @@ Example usage in Claude Desktop config:
 		}()
 
 		// Load configuration
-		cfg, err := config.Load(configPath)
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -74,29 +107,52 @@ Example usage in Claude Desktop config:
 			return fmt.Errorf("mcp configuration not found in config file")
 		}
 
+		if err := serveMetrics(ctx); err != nil {
+			return err
+		}
+
 		// Collect secrets from providers
-		collector := secrets.NewCollector(cfg, secrets.WithForceAuth(forceAuth))
-		collectedSecrets, err := collector.Collect(ctx, providers)
+		collector := newCollector(cfg, secrets.WithNotifications(true))
+		collectedSecrets, sources, err := collector.CollectWithSources(ctx, providers)
 		if err != nil {
 			return fmt.Errorf("failed to collect secrets: %w", err)
 		}
 
-		// Convert config to MCP server configs
-		serverConfigs := make([]mcp.ServerConfig, 0, len(cfg.MCP.Servers))
-		for _, s := range cfg.MCP.Servers {
-			serverConfig := mcp.ServerConfig{
-				ID:      s.ID,
-				Command: s.Command,
-				Args:    s.Args,
+		// Keep the SSO session alive while the proxy is running
+		collector.StartTokenRefresh(ctx)
+
+		// Convert config to MCP server configs, expanding template variables in commands, args
+		// and env, then references to secrets in args and env
+		servers, err := config.ExpandIn(cfg.MCP.Servers, &config.Environment{Trusted: cfg.CheckExecTrusted})
+		if err != nil {
+			return fmt.Errorf("failed to expand mcp.servers: %w", err)
+		}
+		serverConfigs, err := mcpServerConfigs(servers, app.NewSecretRefs(collectedSecrets, sources))
+		if err != nil {
+			return err
+		}
+
+		var managerOpts []mcp.ManagerOption
+		var recorder *mcp.Recorder
+		if mcpRecord != "" {
+			file, err := os.OpenFile(mcpRecord, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to open --record file: %w", err)
 			}
-			serverConfigs = append(serverConfigs, serverConfig)
+			defer file.Close()
+			recorder = mcp.NewRecorder(file, collectedSecrets)
+			managerOpts = append(managerOpts, mcp.WithRecorder(recorder))
 		}
 
 		// Create server manager with secrets and inherit flag
-		manager := mcp.NewServerManager(serverConfigs, collectedSecrets, cfg.Inherit)
+		manager := mcp.NewServerManager(serverConfigs, collectedSecrets, cfg.Inherit, managerOpts...)
+
+		if mcpListen != "" {
+			return serveMCP(ctx, manager)
+		}
 
 		// Create transport for communication with AI host (stdin/stdout)
-		transport := mcp.NewStdioTransport(os.Stdin, os.Stdout)
+		transport := recorder.Transport(mcp.NewStdioTransport(os.Stdin, os.Stdout), "")
 
 		// Create and run the proxy
 		proxy := mcp.NewProxy(manager, transport, GetVersion())
@@ -104,8 +160,12 @@ Example usage in Claude Desktop config:
 		// Run proxy (blocks until context is cancelled or EOF)
 		err = proxy.Run(ctx)
 
-		// Stop all servers
-		proxy.Stop()
+		// Let requests in flight finish, then stop all servers
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), mcpDrainTimeout)
+		defer cancelShutdown()
+		if shutdownErr := proxy.Shutdown(shutdownCtx); shutdownErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", shutdownErr)
+		}
 
 		if err != nil && err != context.Canceled {
 			return err
@@ -114,6 +174,92 @@ Example usage in Claude Desktop config:
 	},
 }
 
+// mcpServerConfigs converts the configs of mcp.servers to MCP server configs, rendering the
+// references to secrets in their args and env, such as {{ .vault-db.DATABASE_URL }}, for servers
+// that take credentials as flags. References can name any collected secret, even one that is not
+// in the server's secrets list
+func mcpServerConfigs(servers []config.MCPServerConfig, refs app.SecretRefs) ([]mcp.ServerConfig, error) {
+	serverConfigs := make([]mcp.ServerConfig, 0, len(servers))
+	for _, s := range servers {
+		args := make([]string, len(s.Args))
+		for i, arg := range s.Args {
+			var err error
+			if args[i], err = refs.Render(fmt.Sprintf("mcp server '%s' argument %d", s.ID, i), arg); err != nil {
+				return nil, &configError{err}
+			}
+		}
+		var env map[string]string
+		if len(s.Env) > 0 {
+			env = make(map[string]string, len(s.Env))
+		}
+		for key, value := range s.Env {
+			rendered, err := refs.Render(fmt.Sprintf("mcp server '%s' env %s", s.ID, key), value)
+			if err != nil {
+				return nil, &configError{err}
+			}
+			env[key] = rendered
+		}
+		serverConfigs = append(serverConfigs, mcp.ServerConfig{
+			ID:      s.ID,
+			Command: s.Command,
+			Args:    args,
+			Env:     env,
+			Secrets: s.Secrets,
+		})
+	}
+	return serverConfigs, nil
+}
+
+// serveMCP serves the proxy over HTTP on --listen until ctx is cancelled
+func serveMCP(ctx context.Context, manager *mcp.ServerManager) error {
+	token := os.Getenv(mcpTokenEnv)
+	if token == "" {
+		var err error
+		if token, err = newServeToken(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Bearer token: %s\n", token)
+	}
+
+	listener, err := net.Listen("tcp", mcpListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "sstart mcp listening on http://%s/mcp\n", listener.Addr())
+
+	handler := mcp.NewHTTPHandler(ctx, manager, GetVersion())
+	server := &http.Server{
+		Handler:           requireBearerToken(token, handler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+	}
+
+	// Let requests in flight finish, then stop all servers and close the remaining connections
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), mcpDrainTimeout)
+	defer cancelShutdown()
+	if shutdownErr := handler.Shutdown(shutdownCtx); shutdownErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", shutdownErr)
+	}
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		_ = server.Close()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
 func init() {
+	mcpCmd.Flags().StringVar(&mcpListen, "listen", "", "Serve hosts over HTTP on this TCP address instead of stdin/stdout (e.g. 127.0.0.1:8766)")
+	mcpCmd.Flags().DurationVar(&mcpDrainTimeout, "drain-timeout", 30*time.Second, "How long to wait for requests in flight to be answered on shutdown")
+	mcpCmd.Flags().StringVar(&mcpRecord, "record", "", "Record every proxied JSON-RPC message to this JSON Lines file, with secret values redacted")
+	addMetricsFlag(mcpCmd)
 	rootCmd.AddCommand(mcpCmd)
 }
@@ -1,22 +1,38 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"time"
 
 	_ "github.com/dirathea/sstart/internal/provider/aws"
+	_ "github.com/dirathea/sstart/internal/provider/azurekeyvault"
 	_ "github.com/dirathea/sstart/internal/provider/bitwarden"
+	_ "github.com/dirathea/sstart/internal/provider/bucket"
 	_ "github.com/dirathea/sstart/internal/provider/doppler"
 	_ "github.com/dirathea/sstart/internal/provider/dotenv"
+	_ "github.com/dirathea/sstart/internal/provider/env"
 	_ "github.com/dirathea/sstart/internal/provider/gcsm"
 	_ "github.com/dirathea/sstart/internal/provider/infisical"
+	_ "github.com/dirathea/sstart/internal/provider/kubernetes"
 	_ "github.com/dirathea/sstart/internal/provider/onepassword"
 	_ "github.com/dirathea/sstart/internal/provider/template"
 	_ "github.com/dirathea/sstart/internal/provider/vault"
+	_ "github.com/dirathea/sstart/internal/provider/wasm"
+	"github.com/dirathea/sstart/internal/agent"
 	"github.com/dirathea/sstart/internal/app"
+	"github.com/dirathea/sstart/internal/ci"
 	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/metrics"
 	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/dirathea/sstart/internal/telemetry"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -24,6 +40,33 @@ var (
 	verbose    bool
 	providers  []string
 	forceAuth  bool
+	profile    string
+	timings    bool
+
+	// maxCollectTime cancels collecting secrets that takes longer (0 for no limit)
+	maxCollectTime time.Duration
+
+	verifyConfig bool
+	noAgent      bool
+	ciMode       bool
+
+	onlyKeys    []string
+	excludeKeys []string
+
+	setValues    []string
+	setEnvValues []string
+
+	metricsListen string
+
+	// outputFormat is the format errors are reported in: text or json
+	outputFormat string
+	// exitCodeOffset is added to the exit code of a failed command
+	exitCodeOffset int
+	// commandStarted is set once the command line is parsed, so earlier errors are reported as usage errors
+	commandStarted bool
+
+	// commandLine describes the running command for the audit log
+	commandLine string
 )
 
 var rootCmd = &cobra.Command{
@@ -37,7 +80,22 @@ Similar to tini but with automatic secret injection from multiple sources.
 Examples:
   sstart -- node index.js
   sstart --providers aws-prod,dotenv-dev -- node index.js
+  sstart --only 'STRIPE_*,DB_*' -- node index.js
   sstart run -- node index.js  # backward compatible`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != outputText && outputFormat != outputJSON {
+			return fmt.Errorf("invalid --output '%s' (supported: text, json)", outputFormat)
+		}
+		if exitCodeOffset < 0 || exitCodeOffset >= exitMax {
+			return fmt.Errorf("invalid --exit-code-offset %d (must be between 0 and %d)", exitCodeOffset, exitMax-1)
+		}
+		commandStarted = true
+		commandLine = describeCommand(cmd, args)
+		return nil
+	},
+	// Errors are reported by Execute, and usage only for usage errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If no arguments provided, show help
 		if len(args) == 0 {
@@ -48,27 +106,208 @@ Examples:
 		ctx := context.Background()
 
 		// Load configuration
-		cfg, err := config.Load(configPath)
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		// Create collector and runner
-		collector := secrets.NewCollector(cfg, secrets.WithForceAuth(forceAuth))
-		runner := app.NewRunner(collector, cfg.Inherit)
+		collector := newCollector(cfg)
+		runner := app.NewRunner(collector, cfg.Inherit, app.WithKeyFilter(onlyKeys, excludeKeys), app.WithRunHooks(cfg))
 
 		// Run the command
 		return runner.Run(ctx, providers, args)
 	},
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// loadConfig loads the project config layered over the global config, with extra load options
+func loadConfig(extra ...config.LoadOption) (*config.Config, error) {
+	path, err := resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]config.LoadOption{config.WithGlobalConfig(config.GlobalConfigPath()), config.WithProfile(activeProfile())}, extra...)
+	if verifyConfigRequired() {
+		key := config.DefaultPublicKeyPath()
+		if key == "" {
+			return nil, &configError{err: fmt.Errorf("cannot verify the config signature: no public key found; set $%s", config.PublicKeyEnv)}
+		}
+		opts = append(opts, config.WithVerifyKey(key))
+	}
+	cfg, err := config.Load(path, opts...)
+	if err != nil {
+		return nil, &configError{err: err}
+	}
+	return cfg, nil
+}
+
+// verifyConfigRequired reports whether the config must be signed, from --verify-config or $SSTART_VERIFY_CONFIG
+func verifyConfigRequired() bool {
+	if verifyConfig {
+		return true
+	}
+	value := strings.ToLower(os.Getenv(config.VerifyEnv))
+	return value == "1" || value == "true"
+}
+
+// activeProfile returns the --profile flag, falling back to $SSTART_PROFILE
+func activeProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv(config.ProfileEnv)
+}
+
+// resolveConfigPath returns the --config path or, without it, the nearest config file
+// in the current or a parent directory (empty if there is none)
+func resolveConfigPath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	return config.Discover(".")
+}
+
+// newCollector creates a secrets collector configured from the global flags
+func newCollector(cfg *config.Config, opts ...secrets.CollectorOption) *secrets.Collector {
+	opts = append([]secrets.CollectorOption{
+		secrets.WithForceAuth(forceAuth),
+		secrets.WithLogger(newLogger()),
+		secrets.WithReauthPrompt(reauthPrompt),
+		secrets.WithCommand(commandLine),
+		secrets.WithMaxCollectTime(maxCollectTime),
+		secrets.WithInterrupt(os.Interrupt, syscall.SIGTERM),
+	}, opts...)
+	if timings {
+		opts = append(opts, secrets.WithTimings(os.Stderr))
+	}
+	if useAgent() {
+		opts = append(opts, secrets.WithAgent(agent.NewClient("", config.GlobalConfigPath())))
+	}
+	if name := ci.Mode(ciMode); name != "" {
+		opts = append(opts,
+			secrets.WithNonInteractive(true),
+			secrets.WithMasker(ci.NewMasker(name, os.Stderr, newLogger()).Mask),
+		)
+	}
+	return secrets.NewCollector(cfg, opts...)
+}
+
+// describeCommand returns the command path, followed by the program of a wrapped command,
+// e.g. "sstart run -- node". Arguments are left out since they may contain secrets
+func describeCommand(cmd *cobra.Command, args []string) string {
+	description := cmd.CommandPath()
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 && dash < len(args) {
+		description += " -- " + args[dash]
+	}
+	return description
+}
+
+// addKeyFilterFlags adds the --only and --exclude flags used to select a subset of the collected secrets
+func addKeyFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&onlyKeys, "only", []string{}, "Comma-separated glob patterns of secret keys to include, e.g. 'STRIPE_*,DB_*' (default: all keys)")
+	cmd.Flags().StringSliceVar(&excludeKeys, "exclude", []string{}, "Comma-separated glob patterns of secret keys to leave out")
+}
+
+// addOverrideFlags adds the --set and --set-env flags used to inject ad-hoc values over the collected secrets
+func addOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&setValues, "set", []string{}, "Inject KEY=VALUE over the collected secrets (repeatable)")
+	cmd.Flags().StringArrayVar(&setEnvValues, "set-env", []string{}, "Inject KEY=FROM_ENV_VAR, the value of an environment variable of sstart, over the collected secrets (repeatable)")
+}
+
+// parseOverrides returns the values of --set and --set-env, by key
+func parseOverrides() (map[string]string, error) {
+	overrides := make(map[string]string, len(setValues)+len(setEnvValues))
+	for _, value := range setValues {
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--set expects KEY=VALUE")
+		}
+		overrides[key] = v
+	}
+	for _, value := range setEnvValues {
+		key, name, ok := strings.Cut(value, "=")
+		if !ok || key == "" || name == "" {
+			return nil, fmt.Errorf("--set-env expects KEY=FROM_ENV_VAR, got '%s'", value)
+		}
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("--set-env %s: environment variable '%s' is not set", key, name)
+		}
+		overrides[key] = v
+	}
+	return overrides, nil
+}
+
+// addMetricsFlag adds the --metrics-listen flag used by long-running modes to expose Prometheus metrics
+func addMetricsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "Address to serve Prometheus metrics on, e.g. 127.0.0.1:9090 (default: disabled)")
+}
+
+// serveMetrics starts the Prometheus /metrics endpoint when --metrics-listen is set
+func serveMetrics(ctx context.Context) error {
+	if metricsListen == "" {
+		return nil
+	}
+	return metrics.Serve(ctx, metricsListen)
+}
+
+// reauthPrompt asks before opening the browser when an expired SSO session needs an interactive login
+// Without a terminal (e.g. CI or MCP stdio) it does not prompt
+func reauthPrompt(reason string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "\n⚠️  %s.\nPress Enter to log in again in your browser, or Ctrl+C to cancel: ", reason)
+	if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+		return fmt.Errorf("re-authentication cancelled: %w", err)
+	}
+	return nil
+}
+
+// newLogger returns a logger writing to stderr; debug output is only enabled with --verbose
+func newLogger() *slog.Logger {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+// Execute runs the command line, reports its error if any, and returns the exit code
+func Execute() int {
+	// Tracing is only enabled when an OTLP endpoint is configured; failing to set it up must not block the command
+	if err := telemetry.Setup(context.Background(), GetVersion()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+	}
+	defer func() { _ = telemetry.Shutdown() }()
+
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return 0
+	}
+	if !commandStarted {
+		err = &usageError{err: err}
+	}
+	code := reportError(os.Stderr, err, outputFormat)
+	if _, ok := err.(*usageError); ok && outputFormat != outputJSON {
+		fmt.Fprint(os.Stderr, cmd.UsageString())
+	}
+	return code
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", ".sstart.yml", "Path to configuration file")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to configuration file (default: nearest .sstart.yml in the current or a parent directory)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
 	rootCmd.PersistentFlags().BoolVar(&forceAuth, "force-auth", false, "Force re-authentication, ignoring cached SSO tokens")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to use (default: $SSTART_PROFILE)")
+	rootCmd.PersistentFlags().BoolVar(&verifyConfig, "verify-config", false, "Refuse to run unless the config file is signed with the key of ~/.config/sstart/sign.pub (or $SSTART_CONFIG_PUBLIC_KEY)")
+	rootCmd.PersistentFlags().BoolVar(&noAgent, "no-agent", false, "Collect secrets in this process even if an sstart agent is running")
+	rootCmd.PersistentFlags().BoolVar(&ciMode, "ci", false, "Run in CI mode: no browser logins, and secrets are masked in CI logs (default: auto-detected)")
+	rootCmd.PersistentFlags().BoolVar(&timings, "timings", false, "Print a per-provider and per-phase timing summary to stderr")
+	rootCmd.PersistentFlags().DurationVar(&maxCollectTime, "max-collect-time", 0, "Fail if collecting secrets takes longer, e.g. 30s, including SSO login and retries (default: no limit)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "Format of error reports on stderr: text or json")
+	rootCmd.PersistentFlags().IntVar(&exitCodeOffset, "exit-code-offset", 0, "Added to the exit code of a failed command, so it differs from sstart's own exit codes (default: the command's own exit code)")
+	addKeyFilterFlags(rootCmd)
+	registerCompletions(rootCmd)
 }
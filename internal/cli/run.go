@@ -3,6 +3,8 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/dirathea/sstart/internal/app"
 	"github.com/dirathea/sstart/internal/config"
@@ -11,7 +13,13 @@ import (
 )
 
 var (
-	runProviders []string
+	runProviders     []string
+	runWatch         bool
+	runWatchInterval time.Duration
+	runFromBundle    string
+	runIdentity      string
+	runParallel      bool
+	runTemplateArgs  bool
 )
 
 var runCmd = &cobra.Command{
@@ -19,29 +27,136 @@ var runCmd = &cobra.Command{
 	Short: "Run a command with injected secrets",
 	Long: `Run a command with secrets automatically injected from configured providers.
 
+With --watch, the command is restarted with the new secrets whenever they change. Doppler
+configs are watched for changes as they happen; other providers are fetched again every
+--watch-interval. The command gets SIGTERM and 10 seconds to exit before it is restarted.
+
+Without a config file, the .env file of the current directory is loaded if there is one, as if
+a dotenv provider were configured.
+
+With --from-bundle, the secrets are read from a bundle written by 'sstart bundle' instead of
+the providers, so no config file or access to the secret backends is needed.
+
+With --parallel, several commands separated by -- run at the same time with the secrets of one
+collection. A command given as a single argument is run with the shell. Their output lines are
+prefixed with [1], [2], ... and sstart exits with the exit code of the first command that fails,
+once all have exited.
+
+Use --set KEY=VALUE, or --set-env KEY=FROM_ENV_VAR to take the value from an environment
+variable, to inject ad-hoc values over the collected secrets for a quick experiment, without
+editing the config or the secret backends. They are injected even if --only or --exclude
+leave their keys out.
+
+With --template-args, references to secrets in the command's arguments are rendered right before
+it starts, using the syntax of template providers: {{ .provider_id.KEY }}. Arguments can be seen
+by other users of the host (e.g. with ps), so prefer environment variables when the command
+supports them.
+
 Example:
   sstart run -- node index.js
-  sstart run --providers aws-prod,dotenv-dev -- node index.js`,
+  sstart run --providers aws-prod,dotenv-dev -- node index.js
+  sstart run --only 'STRIPE_*' --exclude STRIPE_WEBHOOK_SECRET -- node index.js
+  sstart run --watch -- node index.js
+  sstart run --set LOG_LEVEL=debug --set-env API_KEY=MY_TEST_KEY -- node index.js
+  sstart run --from-bundle secrets.bundle --identity key.txt -- node index.js
+  sstart run --parallel -- "npm run api" -- "npm run worker"
+  sstart run --template-args -- psql "{{ .vault-db.PG_DSN }}"`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
-		// Load configuration
-		cfg, err := config.Load(configPath)
+		overrides, err := parseOverrides()
+		if err != nil {
+			return err
+		}
+
+		var commands [][]string
+		if runParallel {
+			if runWatch {
+				return fmt.Errorf("--watch cannot be used with --parallel")
+			}
+			var err error
+			if commands, err = parallelCommands(args); err != nil {
+				return err
+			}
+		}
+
+		if runFromBundle != "" {
+			if runWatch {
+				return fmt.Errorf("--watch cannot be used with --from-bundle")
+			}
+			b, err := readBundle(runFromBundle, runIdentity)
+			if err != nil {
+				return err
+			}
+			if runTemplateArgs {
+				return fmt.Errorf("--template-args cannot be used with --from-bundle, whose secrets have no providers")
+			}
+			runner := app.NewRunner(nil, b.Inherit, app.WithSecrets(b.Secrets), app.WithKeyFilter(onlyKeys, excludeKeys), app.WithOverrides(overrides))
+			if runParallel {
+				return runner.RunParallel(ctx, nil, commands)
+			}
+			return runner.Run(ctx, nil, args)
+		}
+
+		// Load configuration, using the .env file of a project without one
+		cfg, err := loadConfig(config.WithDotenvFallback("."))
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+		if cfg.DotenvFallback != "" {
+			fmt.Fprintf(os.Stderr, "No %s found, loading secrets from %s\n", config.DefaultFileName, cfg.DotenvFallback)
+		}
 
 		// Create collector and runner
-		collector := secrets.NewCollector(cfg, secrets.WithForceAuth(forceAuth))
-		runner := app.NewRunner(collector, cfg.Inherit)
+		collector := newCollector(cfg, secrets.WithNotifications(runWatch))
+		runnerOpts := []app.RunnerOption{app.WithKeyFilter(onlyKeys, excludeKeys), app.WithOverrides(overrides), app.WithRunHooks(cfg)}
+		if runWatch {
+			if runWatchInterval <= 0 {
+				return fmt.Errorf("--watch-interval must be positive")
+			}
+			runnerOpts = append(runnerOpts, app.WithWatch(runWatchInterval))
+		}
+		if runTemplateArgs {
+			runnerOpts = append(runnerOpts, app.WithArgTemplates())
+		}
+		runner := app.NewRunner(collector, cfg.Inherit, runnerOpts...)
 
 		// Run the command
+		if runParallel {
+			return runner.RunParallel(ctx, runProviders, commands)
+		}
 		return runner.Run(ctx, runProviders, args)
 	},
 }
 
+// parallelCommands splits the arguments of --parallel into commands separated by "--"
+func parallelCommands(args []string) ([][]string, error) {
+	var commands [][]string
+	var command []string
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) && args[i] != "--" {
+			command = append(command, args[i])
+			continue
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("--parallel commands must be separated by '--' and not be empty")
+		}
+		commands = append(commands, command)
+		command = nil
+	}
+	return commands, nil
+}
+
 func init() {
 	runCmd.Flags().StringSliceVar(&runProviders, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	runCmd.Flags().BoolVar(&runWatch, "watch", false, "Restart the command when the secrets change")
+	runCmd.Flags().DurationVar(&runWatchInterval, "watch-interval", time.Minute, "How often providers that cannot be watched are fetched again (with --watch)")
+	runCmd.Flags().StringVar(&runFromBundle, "from-bundle", "", "Read the secrets from a bundle written by 'sstart bundle' instead of the providers")
+	runCmd.Flags().StringVar(&runIdentity, "identity", "", "age identity file to decrypt --from-bundle (default: $SSTART_AGE_IDENTITY, or $SSTART_BUNDLE_PASSPHRASE)")
+	runCmd.Flags().BoolVar(&runParallel, "parallel", false, "Run several commands separated by -- at the same time, with the same secrets")
+	runCmd.Flags().BoolVar(&runTemplateArgs, "template-args", false, "Render references to secrets in the command's arguments, e.g. {{ .vault-db.PG_DSN }}")
+	addKeyFilterFlags(runCmd)
+	addOverrideFlags(runCmd)
 	rootCmd.AddCommand(runCmd)
 }
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/selfupdate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	selfUpdateCheck   bool
+	selfUpdateVersion string
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update sstart to the latest release",
+	Long: `Download the latest sstart release from GitHub and replace the running binary with it.
+
+The release archive for the current platform is checked against the checksums.txt of the
+release, once its minisign signature is verified with the release key built into sstart,
+before the binary is replaced. Binaries installed by Homebrew are not replaced; use
+'brew upgrade sstart' instead.
+
+Set $GITHUB_TOKEN to avoid the GitHub API rate limit, or $SSTART_GITHUB_API_URL to use a mirror
+of the GitHub API.
+
+Example:
+  sstart self-update --check
+  sstart self-update
+  sstart self-update --version v1.4.0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client := selfupdate.NewClient()
+		release, err := client.Release(ctx, selfUpdateVersion)
+		if err != nil {
+			return err
+		}
+
+		if selfUpdateCheck {
+			if release.Newer(version) {
+				fmt.Printf("sstart %s is available (current: %s)\n", release.Tag, version)
+			} else {
+				fmt.Printf("sstart %s is the latest release\n", version)
+			}
+			return nil
+		}
+		if selfUpdateVersion == "" && !release.Newer(version) {
+			fmt.Printf("sstart %s is the latest release\n", version)
+			return nil
+		}
+
+		path, err := selfupdate.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the sstart binary: %w", err)
+		}
+		if strings.Contains(path, "/Cellar/") {
+			return fmt.Errorf("%s is managed by Homebrew; run 'brew upgrade sstart' instead", path)
+		}
+
+		binary, err := client.Download(ctx, release)
+		if err != nil {
+			return err
+		}
+		if err := selfupdate.Replace(path, binary); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Updated %s from %s to %s\n", path, version, release.Tag)
+		return nil
+	},
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "Only check whether a newer release is available")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateVersion, "version", "", "Install this release instead of the latest one (e.g. v1.4.0)")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
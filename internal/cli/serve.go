@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dirathea/sstart/internal/peercred"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+// serveTokenEnv sets the bearer token required by sstart serve
+const serveTokenEnv = "SSTART_SERVE_TOKEN"
+
+var (
+	serveListen string
+	serveSocket string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve secrets over a local HTTP API",
+	Long: `Serve the collected secrets over a local HTTP API, so that scripts and containers in the
+same pod or VM can pull secrets without provider credentials of their own.
+
+Endpoints:
+  GET /env?format=shell|json|yaml  All secrets, formatted like sstart env (default: shell)
+  GET /secret/<KEY>                The value of a single secret, as plain text
+
+Secrets are collected on every request, using the secret cache when it is enabled.
+
+On TCP (--listen), requests must send the bearer token from $SSTART_SERVE_TOKEN; without
+it, a random token is generated and printed to stderr. On a unix socket (--socket), only
+processes of the same user can connect (checked with SO_PEERCRED on Linux), and the token
+is only required when $SSTART_SERVE_TOKEN is set.
+
+Example:
+  SSTART_SERVE_TOKEN=s3cret sstart serve --listen 127.0.0.1:8765 --only 'DB_*'
+  curl -H "Authorization: Bearer s3cret" http://127.0.0.1:8765/secret/DB_PASSWORD
+
+  sstart serve --socket /run/sstart.sock
+  curl --unix-socket /run/sstart.sock http://localhost/env?format=json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		// Validate the key filters before serving
+		if _, err := secrets.FilterKeys(nil, onlyKeys, excludeKeys); err != nil {
+			return err
+		}
+
+		token := os.Getenv(serveTokenEnv)
+		var listener net.Listener
+		if serveSocket != "" {
+			if err := removeStaleSocket(serveSocket); err != nil {
+				return err
+			}
+			listener, err = listenUnix(serveSocket)
+			if err == nil {
+				defer os.Remove(serveSocket)
+				err = os.Chmod(serveSocket, 0600)
+				listener = peerCredListener{Listener: listener, logger: newLogger()}
+			}
+		} else {
+			if token == "" {
+				if token, err = newServeToken(); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Bearer token: %s\n", token)
+			}
+			listener, err = net.Listen("tcp", serveListen)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "sstart serve listening on %s\n", listener.Addr())
+
+		if err := serveMetrics(ctx); err != nil {
+			return err
+		}
+
+		collector := newCollector(cfg)
+		server := &http.Server{
+			Handler:           newServeHandler(ctx, collector, token),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-ctx.Done()
+			_ = server.Close()
+		}()
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// newServeHandler returns the handler of the sstart serve API
+// Requests must carry token as a bearer token, unless token is empty. The SSO session is kept
+// alive until ctx is done, from the first successful collection on, once it has logged in
+func newServeHandler(ctx context.Context, collector *secrets.Collector, token string) http.Handler {
+	var refreshOnce sync.Once
+	collect := func(r *http.Request) (map[string]string, error) {
+		collected, err := collector.Collect(r.Context(), providers)
+		if err != nil {
+			return nil, err
+		}
+		refreshOnce.Do(func() { collector.StartTokenRefresh(ctx) })
+		return secrets.FilterKeys(collected, onlyKeys, excludeKeys)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /env", func(w http.ResponseWriter, r *http.Request) {
+		collected, err := collect(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "json" {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		_ = writeEnv(w, collected, format, nil)
+	})
+	mux.HandleFunc("GET /secret/{key}", func(w http.ResponseWriter, r *http.Request) {
+		collected, err := collect(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		value, ok := collected[r.PathValue("key")]
+		if !ok {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(value))
+	})
+
+	return requireBearerToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// requireBearerToken rejects requests that do not carry token as a bearer token, unless token is empty
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newServeToken returns a random bearer token
+func newServeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// removeStaleSocket removes the socket left at path by a server that exited. Files that are not
+// sockets, and sockets a server still accepts connections on, are kept and reported
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("a server is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// peerCredListener drops unix socket connections from processes of other users
+type peerCredListener struct {
+	net.Listener
+	logger *slog.Logger
+}
+
+func (l peerCredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := peercred.Check(conn); err != nil {
+			l.logger.Warn("rejected connection", "error", err)
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8765", "TCP address to listen on")
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "Unix socket to listen on instead of TCP")
+	serveCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+	addKeyFilterFlags(serveCmd)
+	addMetricsFlag(serveCmd)
+	rootCmd.AddCommand(serveCmd)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/dirathea/sstart/internal/config"
 	"github.com/dirathea/sstart/internal/secrets"
 	"github.com/spf13/cobra"
 )
@@ -18,13 +17,13 @@ Only the first 2 and last 2 characters are shown.`,
 		ctx := context.Background()
 
 		// Load configuration
-		cfg, err := config.Load(configPath)
+		cfg, err := loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		// Collect secrets
-		collector := secrets.NewCollector(cfg, secrets.WithForceAuth(forceAuth))
+		collector := newCollector(cfg)
 		showProviders := providers
 		if len(showProviders) == 0 {
 			showProviders = nil // Use all providers
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cli
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix listens on a unix socket created with 0600 permissions, so that other users cannot
+// connect in the window before it could be chmodded. The umask is process-wide, so this runs at
+// startup, before other files are created
+func listenUnix(path string) (net.Listener, error) {
+	umask := syscall.Umask(0177)
+	defer syscall.Umask(umask)
+	return net.Listen("unix", path)
+}
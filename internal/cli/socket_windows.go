@@ -0,0 +1,10 @@
+//go:build windows
+
+package cli
+
+import "net"
+
+// listenUnix listens on a unix socket; Windows has no umask, so it is protected by the ACL of its directory
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
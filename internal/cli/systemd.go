@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	systemdCredentialsDir string
+	systemdOutput         string
+	systemdUnit           string
+)
+
+// systemdUnitDir is the directory of the drop-ins written with --unit
+const systemdUnitDir = "/etc/systemd/system"
+
+// systemdUnitTypes are the suffixes of systemd unit names
+var systemdUnitTypes = []string{"service", "socket", "target", "device", "mount", "automount", "swap", "timer", "path", "slice", "scope"}
+
+var systemdCmd = &cobra.Command{
+	Use:   "systemd",
+	Short: "Provide secrets to systemd services as credentials",
+	Long: `Provide the collected secrets to systemd services as credentials, which services read
+from files in $CREDENTIALS_DIRECTORY instead of environment variables.`,
+}
+
+var systemdCredentialsCmd = &cobra.Command{
+	Use:   "credentials <dir>",
+	Short: "Write each secret to its own file, in the layout of $CREDENTIALS_DIRECTORY",
+	Long: `Write each secret to a file named after its key in dir, readable only by the current user.
+The directory can be loaded into a service with LoadCredential=, or used directly as
+$CREDENTIALS_DIRECTORY by programs that read systemd credentials.
+
+The directory is dedicated to the secrets: other files in it, such as those of keys that are
+no longer collected, are removed.
+
+Example:
+  sudo sstart systemd credentials /etc/credstore/myapp
+  # in myapp.service:
+  #   LoadCredential=myapp:/etc/credstore/myapp`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collected, err := collectSystemdSecrets()
+		if err != nil {
+			return err
+		}
+		if err := writeCredentials(args[0], collected); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d credentials to %s\n", len(collected), args[0])
+		return nil
+	},
+}
+
+var systemdDropinCmd = &cobra.Command{
+	Use:   "dropin",
+	Short: "Generate a systemd drop-in that passes secrets to a service as credentials",
+	Long: `Generate a drop-in for a service unit that passes each secret as a credential.
+
+With --credentials-dir, the secrets are written to files in that directory, removing other
+files (like 'sstart systemd credentials'), and the drop-in loads them with LoadCredential=. Without it,
+the values are embedded in the drop-in with SetCredential=, so keep the drop-in readable
+only by root.
+
+Services read each secret from $CREDENTIALS_DIRECTORY/<KEY>. With --unit, the drop-in is
+written to /etc/systemd/system/<unit>.d/sstart.conf.
+
+Example:
+  sudo sstart systemd dropin --credentials-dir /etc/credstore/myapp --unit myapp.service
+  sudo systemctl daemon-reload && sudo systemctl restart myapp`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output := systemdOutput
+		if systemdUnit != "" {
+			if output != "" {
+				return &usageError{err: errors.New("--unit and --file cannot be used together")}
+			}
+			unit, err := systemdServiceName(systemdUnit)
+			if err != nil {
+				return &usageError{err: err}
+			}
+			output = filepath.Join(systemdUnitDir, unit+".d", "sstart.conf")
+		}
+		if systemdCredentialsDir != "" && strings.ContainsFunc(systemdCredentialsDir, isControl) {
+			return &usageError{err: errors.New("--credentials-dir must not contain control characters")}
+		}
+
+		collected, err := collectSystemdSecrets()
+		if err != nil {
+			return err
+		}
+		if systemdCredentialsDir != "" {
+			if systemdCredentialsDir, err = filepath.Abs(systemdCredentialsDir); err != nil {
+				return err
+			}
+			if err := writeCredentials(systemdCredentialsDir, collected); err != nil {
+				return err
+			}
+		}
+
+		if output == "" {
+			return writeDropin(os.Stdout, collected, systemdCredentialsDir)
+		}
+		if systemdUnit != "" {
+			if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+				return fmt.Errorf("failed to create drop-in directory: %w", err)
+			}
+		}
+		err = writePrivateFile(output, func(w io.Writer) error {
+			return writeDropin(w, collected, systemdCredentialsDir)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write drop-in: %w", err)
+		}
+		return nil
+	},
+}
+
+// collectSystemdSecrets collects the secrets passed to systemd, checking that each key is a valid credential name
+func collectSystemdSecrets() (map[string]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	collected, err := newCollector(cfg).Collect(context.Background(), providers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect secrets: %w", err)
+	}
+	collected, err = secrets.FilterKeys(collected, onlyKeys, excludeKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(collected) {
+		if err := validateCredentialName(key); err != nil {
+			return nil, fmt.Errorf("secret key '%s' is not a valid systemd credential name: %w", key, err)
+		}
+	}
+	return collected, nil
+}
+
+// validateCredentialName checks that key is a valid systemd credential name: a file name of at
+// most 255 printable ASCII characters without ':'. Characters that unit files would interpret in
+// LoadCredential= and SetCredential= (whitespace, quotes, '\' and '%') are rejected too
+func validateCredentialName(key string) error {
+	switch {
+	case key == "" || key == "." || key == "..":
+		return errors.New("must be a file name")
+	case len(key) > 255:
+		return errors.New("longer than 255 characters")
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c >= 0x7f || strings.IndexByte(`/:\%"'`, c) >= 0 {
+			return fmt.Errorf("contains %q", c)
+		}
+	}
+	return nil
+}
+
+// systemdServiceName validates a service unit name like systemd does, adding the .service
+// suffix when the name has no unit type, and returns it
+func systemdServiceName(name string) (string, error) {
+	base, suffix := name, "service"
+	if i := strings.LastIndexByte(name, '.'); i >= 0 && slices.Contains(systemdUnitTypes, name[i+1:]) {
+		base, suffix = name[:i], name[i+1:]
+	}
+	if suffix != "service" {
+		return "", fmt.Errorf("unit '%s' is not a service; drop-ins set credentials in [Service]", name)
+	}
+	prefix, instance, templated := strings.Cut(base, "@")
+	valid := func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(`:-_.\`, c) >= 0) {
+				return false
+			}
+		}
+		return true
+	}
+	unit := base + "." + suffix
+	if prefix == "" || !valid(prefix) || (templated && !valid(instance)) || len(unit) > 255 {
+		return "", fmt.Errorf("'%s' is not a valid systemd unit name", name)
+	}
+	return unit, nil
+}
+
+// isControl reports whether r is an ASCII control character
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// writeCredentials writes each secret to dir/<KEY> with mode 0600, replacing files atomically
+// so a service starting meanwhile never reads a partial secret. The directory holds the
+// credentials of the secrets only, so other files, e.g. of keys no longer collected, are removed
+func writeCredentials(dir string, collected map[string]string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	for _, key := range sortedKeys(collected) {
+		err := writePrivateFile(filepath.Join(dir, key), func(w io.Writer) error {
+			_, err := io.WriteString(w, collected[key])
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write credential '%s': %w", key, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials directory: %w", err)
+	}
+	for _, entry := range entries {
+		if _, ok := collected[entry.Name()]; ok || !entry.Type().IsRegular() {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove stale credential '%s': %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// writePrivateFile writes path with mode 0600 through a temporary file renamed over it, so that
+// an existing file keeps neither its mode nor partial content
+func writePrivateFile(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// writeDropin writes a [Service] drop-in passing each secret as a credential: loaded from
+// credentialsDir when it is set, otherwise embedded with SetCredential=
+func writeDropin(w io.Writer, collected map[string]string, credentialsDir string) error {
+	fmt.Fprintln(w, "# Generated by sstart; do not edit")
+	fmt.Fprintln(w, "[Service]")
+	for _, key := range sortedKeys(collected) {
+		if credentialsDir != "" {
+			// Paths are not unescaped, only specifiers are resolved
+			fmt.Fprintf(w, "LoadCredential=%s:%s\n", key, strings.ReplaceAll(filepath.Join(credentialsDir, key), "%", "%%"))
+		} else {
+			fmt.Fprintf(w, "SetCredential=%s:%s\n", key, escapeSystemd(collected[key]))
+		}
+	}
+	return nil
+}
+
+// escapeSystemd escapes s for a unit file setting, where C-style escapes are resolved and
+// % starts a specifier. Leading and trailing whitespace, which unit files strip, is escaped too.
+func escapeSystemd(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '%':
+			b.WriteString("%%")
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f || (c == ' ' && (i == 0 || i == len(s)-1)):
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{systemdCredentialsCmd, systemdDropinCmd} {
+		cmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to use (default: all providers)")
+		addKeyFilterFlags(cmd)
+		systemdCmd.AddCommand(cmd)
+	}
+	systemdDropinCmd.Flags().StringVar(&systemdCredentialsDir, "credentials-dir", "", "Write the secrets to files in this directory and load them with LoadCredential= (default: embed them with SetCredential=)")
+	systemdDropinCmd.Flags().StringVarP(&systemdOutput, "file", "f", "", "Write the drop-in to this file, with mode 0600 (default: stdout)")
+	systemdDropinCmd.Flags().StringVar(&systemdUnit, "unit", "", "Write the drop-in for this service to "+systemdUnitDir+"/<unit>.d/sstart.conf")
+	rootCmd.AddCommand(systemdCmd)
+}
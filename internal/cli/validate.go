@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateStrict    bool
+	validateDebugAuth bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config for unused key mappings and shadowed keys",
+	Long: `Load and validate the config, then fetch secrets from the providers to find silent
+misconfigurations:
+
+  unused mapping  a 'keys' mapping whose source key the provider did not return, e.g. after
+                  a typo or a renamed secret
+  shadowed        a key set by a provider and overridden by a later one
+
+Unused mappings make validate exit with status 1. Shadowed keys are often intended, e.g. a
+local .env overriding shared secrets, so they only fail with --strict. Values are never printed.
+
+With --debug-auth, validate fetches no secrets and instead probes how the providers
+authenticate: the credential sources they look at, the credentials they find and the identity
+these map to. It exits with status 1 if a check fails. Providers of kinds aws_secretsmanager and
+aws_ssm support it.
+
+Example:
+  sstart validate
+  sstart validate --providers aws-prod,dotenv-local --strict
+  sstart validate --providers aws-prod --debug-auth`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if validateDebugAuth {
+			return debugAuth(newCollector(cfg))
+		}
+
+		issues, err := newCollector(cfg).Validate(context.Background(), providers)
+		if err != nil {
+			return err
+		}
+		if len(issues) == 0 {
+			fmt.Fprintln(os.Stderr, "No issues found")
+			return nil
+		}
+
+		failed := false
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROVIDER\tKEY\tISSUE\tDETAIL")
+		for _, issue := range issues {
+			if issue.Kind == secrets.IssueUnusedMapping || validateStrict {
+				failed = true
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Provider, issue.Key, issue.Kind, issue.Detail)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if failed {
+			return &checkFailedError{code: 1}
+		}
+		return nil
+	},
+}
+
+// debugAuth prints the authentication checks of the selected providers
+func debugAuth(collector *secrets.Collector) error {
+	checks, err := collector.DiagnoseAuth(context.Background(), providers)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(checks))
+	for id := range checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	failed := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tCHECK\tSTATUS\tDETAIL")
+	for _, id := range ids {
+		if checks[id] == nil {
+			fmt.Fprintf(w, "%s\t-\t%s\tthe provider does not support auth diagnostics\n", id, provider.AuthCheckSkipped)
+			continue
+		}
+		for _, check := range checks[id] {
+			if check.Status == provider.AuthCheckFailed {
+				failed = true
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", id, check.Name, check.Status, check.Detail)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed {
+		return &checkFailedError{code: 1}
+	}
+	return nil
+}
+
+func init() {
+	validateCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to check (default: all providers)")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Also fail on shadowed keys")
+	validateCmd.Flags().BoolVar(&validateDebugAuth, "debug-auth", false, "Probe how the providers authenticate instead of fetching secrets")
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var verifyStrict bool
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check collected secrets against the contract of the config",
+	Long: `Collect secrets and check them against the 'contract' section of the config, which lists the
+variables the service needs and their shapes. Every contract key is reported; values are never
+printed. sstart exits with status 1 if any key breaks the contract, to catch gaps in CI.
+
+Use --strict to also fail on collected keys that the contract does not declare.
+
+Example:
+  sstart verify
+  sstart --profile production verify --strict`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if cfg.Contract == nil || len(cfg.Contract.Keys) == 0 {
+			return &configError{err: fmt.Errorf("the config has no 'contract' section to verify against")}
+		}
+
+		envSecrets, sources, err := newCollector(cfg).CollectWithSources(context.Background(), providers)
+		if err != nil {
+			return fmt.Errorf("failed to collect secrets: %w", err)
+		}
+
+		failed := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tSTATUS\tPROVIDER")
+		for _, result := range secrets.CheckContract(cfg.Contract, envSecrets, sources) {
+			status := "ok"
+			if result.Reason != "" {
+				status = result.Reason
+				failed++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Key, status, orDash(result.Provider))
+		}
+		if verifyStrict {
+			for _, key := range secrets.UndeclaredKeys(cfg.Contract, envSecrets) {
+				fmt.Fprintf(w, "%s\tnot declared in the contract\t%s\n", key, sources[key])
+				failed++
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("contract check failed: %d key(s) do not satisfy the contract", failed)
+		}
+		fmt.Fprintf(os.Stderr, "All %d contract key(s) are satisfied\n", len(cfg.Contract.Keys))
+		return nil
+	},
+}
+
+// orDash returns s, or "-" if it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	verifyCmd.Flags().StringSliceVar(&providers, "providers", []string{}, "Comma-separated list of provider IDs to collect from (default: all providers)")
+	verifyCmd.Flags().BoolVar(&verifyStrict, "strict", false, "Also fail on collected keys the contract does not declare")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,9 +15,42 @@ import (
 type Config struct {
 	Inherit   bool             `yaml:"inherit"` // Whether to inherit system environment variables (default: true)
 	Providers []ProviderConfig `yaml:"providers"`
-	SSO       *SSOConfig       `yaml:"sso,omitempty"`   // SSO configuration
-	Cache     *CacheConfig     `yaml:"cache,omitempty"` // Cache configuration
-	MCP       *MCPConfig       `yaml:"mcp,omitempty"`   // MCP proxy configuration
+	SSO       *SSOConfig       `yaml:"sso,omitempty"`      // SSO configuration
+	Cache     *CacheConfig     `yaml:"cache,omitempty"`    // Cache configuration
+	MCP       *MCPConfig       `yaml:"mcp,omitempty"`      // MCP proxy configuration
+	Audit     *AuditConfig     `yaml:"audit,omitempty"`    // Access audit log configuration
+	Policy    *PolicyConfig    `yaml:"policy,omitempty"`   // Rules checked before secrets are injected
+	Lint      *LintConfig      `yaml:"lint,omitempty"`     // Warnings for values that look misconfigured
+	Rotation  *RotationConfig  `yaml:"rotation,omitempty"` // Warnings for secrets that are due for rotation
+	Limits    *LimitsConfig    `yaml:"limits,omitempty"`   // Maximum number and size of the collected secrets
+	// Maximum request rates to the secret backends
+	RateLimits *RateLimitsConfig `yaml:"rate_limits,omitempty"`
+	// Notifications of collection failures in long-running modes
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
+	// Commands run while secrets are collected
+	Hooks *HooksConfig `yaml:"hooks,omitempty"`
+	// Variables the collected secrets must provide, checked by sstart verify
+	Contract *ContractConfig `yaml:"contract,omitempty"`
+	// Values used for keys that no provider produced
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+	// Default fields per provider kind, merged under each provider entry of that kind on load
+	ProviderDefaults map[string]map[string]interface{} `yaml:"provider_defaults,omitempty"`
+	// Named config overlays, merged over the rest of the config when selected with --profile
+	Profiles map[string]map[string]interface{} `yaml:"profiles,omitempty"`
+	// Profile is the name of the active profile (empty if none)
+	Profile string `yaml:"-"`
+	// Path is the project config file the config was loaded from (empty if none)
+	Path string `yaml:"-"`
+	// DotenvFallback is the .env file loaded because no config file exists (see WithDotenvFallback)
+	DotenvFallback string `yaml:"-"`
+	// Files are the absolute paths of the files the config was read from, including the global
+	// config and included files
+	Files []string `yaml:"-"`
+	// VerifyKey is the public key the config file's signature was verified with (see WithVerifyKey)
+	VerifyKey string `yaml:"-"`
+	// EnabledEnv are the names of the environment variables the 'enabled' conditions of
+	// providers read when the config was loaded
+	EnabledEnv []string `yaml:"-"`
 }
 
 // MCPConfig represents the MCP proxy configuration
@@ -29,20 +64,23 @@ type MCPServerConfig struct {
 	Command string   `yaml:"command"`        // Command to execute
 	Args    []string `yaml:"args,omitempty"` // Command arguments
 	Env     EnvVars  `yaml:"env,omitempty"`  // Additional environment variables
-	// Future: Secrets []string `yaml:"secrets,omitempty"` // Optional: filter which provider secrets to inject
+	// Collected secret keys injected into the server (default: all; an empty list injects none)
+	Secrets []string `yaml:"secrets,omitempty"`
 }
 
 // CacheConfig represents cache configuration
 type CacheConfig struct {
-	Enabled bool          `yaml:"enabled"`       // Whether caching is enabled (default: false)
-	TTL     time.Duration `yaml:"ttl,omitempty"` // Cache TTL (default: 5m)
+	Enabled        bool          `yaml:"enabled"`                  // Whether caching is enabled (default: false)
+	TTL            time.Duration `yaml:"ttl,omitempty"`            // Cache TTL (default: 5m)
+	KeyringService string        `yaml:"keyringService,omitempty"` // Keyring service name (default: sstart-cache)
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling to handle TTL as duration string
 func (c *CacheConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type rawCacheConfig struct {
-		Enabled bool   `yaml:"enabled"`
-		TTL     string `yaml:"ttl,omitempty"`
+		Enabled        bool   `yaml:"enabled"`
+		TTL            string `yaml:"ttl,omitempty"`
+		KeyringService string `yaml:"keyringService,omitempty"`
 	}
 
 	var raw rawCacheConfig
@@ -51,6 +89,7 @@ func (c *CacheConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	c.Enabled = raw.Enabled
+	c.KeyringService = raw.KeyringService
 
 	// Parse TTL if provided
 	if raw.TTL != "" {
@@ -67,9 +106,32 @@ func (c *CacheConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// AuditConfig represents access audit log configuration
+type AuditConfig struct {
+	Enabled bool   `yaml:"enabled"`        // Whether to record which secrets were fetched (default: false)
+	Path    string `yaml:"path,omitempty"` // Audit log file (default: ~/.local/state/sstart/audit.jsonl)
+}
+
 // SSOConfig represents SSO configuration
 type SSOConfig struct {
-	OIDC *OIDCConfig `yaml:"oidc,omitempty"` // OIDC configuration
+	OIDC         *OIDCConfig         `yaml:"oidc,omitempty"`         // OIDC configuration
+	TokenStorage *TokenStorageConfig `yaml:"tokenStorage,omitempty"` // Where SSO tokens are stored (optional)
+}
+
+// Token storage backends
+const (
+	TokenStorageAuto    = "auto"    // Keyring when available, file otherwise (default)
+	TokenStorageKeyring = "keyring" // OS keyring only
+	TokenStorageFile    = "file"    // File only
+	TokenStorageMemory  = "memory"  // Process memory only, tokens are never persisted
+)
+
+// TokenStorageConfig represents SSO token storage configuration
+type TokenStorageConfig struct {
+	Backend        string `yaml:"backend,omitempty"`        // auto, keyring, file or memory (default: auto)
+	KeyringService string `yaml:"keyringService,omitempty"` // Keyring service name (default: sstart)
+	Path           string `yaml:"path,omitempty"`           // Token file path (default: ~/.config/sstart/tokens.json)
+	Encrypt        bool   `yaml:"encrypt,omitempty"`        // Encrypt the token file with the key from SSTART_TOKEN_ENCRYPTION_KEY
 }
 
 // OIDCConfig represents OIDC configuration
@@ -81,6 +143,11 @@ type OIDCConfig struct {
 	RedirectURI  string   `yaml:"redirectUri,omitempty"`  // OIDC redirect URI (optional, can be auto-generated)
 	PKCE         *bool    `yaml:"pkce,omitempty"`         // Enable PKCE flow (optional, auto-enabled if clientSecret is empty)
 	ResponseMode string   `yaml:"responseMode,omitempty"` // OIDC response mode (optional)
+	// Loopback callback server settings for the interactive flow
+	CallbackHost  string `yaml:"callbackHost,omitempty"`  // Loopback host used in the redirect URI: localhost, 127.0.0.1 or ::1 (optional, default: localhost)
+	CallbackPorts []int  `yaml:"callbackPorts,omitempty"` // Ports tried in order for the callback server (optional, default: 5747)
+	// Extra parameters sent with the authorization request, e.g. audience, prompt or organization (optional)
+	AuthParams map[string]string `yaml:"authParams,omitempty"`
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling to handle scopes as either array or space-separated string
@@ -88,12 +155,15 @@ func (o *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	// Create a temporary struct to unmarshal into
 	// Note: clientSecret is intentionally NOT parsed from YAML - it must be provided via SSTART_SSO_SECRET env var
 	type rawOIDCConfig struct {
-		ClientID     string      `yaml:"clientId"`
-		Issuer       string      `yaml:"issuer"`
-		Scopes       interface{} `yaml:"scopes"` // Use interface{} to handle both string and []string
-		RedirectURI  string      `yaml:"redirectUri,omitempty"`
-		PKCE         *bool       `yaml:"pkce,omitempty"`
-		ResponseMode string      `yaml:"responseMode,omitempty"`
+		ClientID      string            `yaml:"clientId"`
+		Issuer        string            `yaml:"issuer"`
+		Scopes        interface{}       `yaml:"scopes"` // Use interface{} to handle both string and []string
+		RedirectURI   string            `yaml:"redirectUri,omitempty"`
+		PKCE          *bool             `yaml:"pkce,omitempty"`
+		ResponseMode  string            `yaml:"responseMode,omitempty"`
+		CallbackHost  string            `yaml:"callbackHost,omitempty"`
+		CallbackPorts []int             `yaml:"callbackPorts,omitempty"`
+		AuthParams    map[string]string `yaml:"authParams,omitempty"`
 	}
 
 	var raw rawOIDCConfig
@@ -107,6 +177,9 @@ func (o *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	o.RedirectURI = raw.RedirectURI
 	o.PKCE = raw.PKCE
 	o.ResponseMode = raw.ResponseMode
+	o.CallbackHost = raw.CallbackHost
+	o.CallbackPorts = raw.CallbackPorts
+	o.AuthParams = raw.AuthParams
 
 	// Handle scopes: can be string (space-separated) or []string
 	if raw.Scopes != nil {
@@ -147,6 +220,78 @@ type ProviderConfig struct {
 	Keys   map[string]string      `yaml:"keys,omitempty"` // Optional key mappings (source_key: target_key, or "==" to keep same name)
 	Env    EnvVars                `yaml:"env,omitempty"`
 	Uses   []string               `yaml:"uses,omitempty"` // Optional list of provider IDs to depend on
+	// Optional SSO ID token claims required before fetching (claim name: value or list of values)
+	RequireClaims map[string]interface{} `yaml:"require_claims,omitempty"`
+	// Optional condition: a boolean or a template evaluating to true/false; disabled providers are dropped on load
+	Enabled interface{} `yaml:"enabled,omitempty"`
+	// Optional: secrets available to providers that use this one but not exported to the environment
+	// true hides all secrets of the provider, a list hides only the listed keys (after key mapping)
+	Hidden interface{} `yaml:"hidden,omitempty"`
+	// Optional values used for keys that no provider produced, preferred over the global defaults
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+	// Additional names of mapped keys, from mappings to a list of target keys (first target key: other target keys)
+	KeyAliases map[string][]string `yaml:"-"`
+	// Transforms applied to the values of mapped keys, from mappings with 'transform' (target key: transform names)
+	KeyTransforms map[string][]string `yaml:"-"`
+}
+
+// IsHidden reports whether key is hidden from the environment by the provider's 'hidden' setting
+func (p *ProviderConfig) IsHidden(key string) bool {
+	switch v := p.Hidden.(type) {
+	case bool:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if item == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addKeyList maps source to a list of target keys: the provider maps it to the first one, and the
+// secret is copied to the others after fetching
+func (p *ProviderConfig) addKeyList(source string, targets []interface{}) error {
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		name, ok := target.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("keys.%s must be a key or a list of keys", source)
+		}
+		if name == "==" {
+			name = source
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("keys.%s must not be an empty list", source)
+	}
+	p.Keys[source] = names[0]
+	if len(names) > 1 {
+		if p.KeyAliases == nil {
+			p.KeyAliases = make(map[string][]string)
+		}
+		p.KeyAliases[names[0]] = append(p.KeyAliases[names[0]], names[1:]...)
+	}
+	return nil
+}
+
+// validateHidden checks that a provider's 'hidden' value is a boolean or a list of keys
+func validateHidden(value interface{}) error {
+	switch v := value.(type) {
+	case nil, bool:
+		return nil
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("hidden must be true, false, or a list of keys")
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("hidden must be true, false, or a list of keys")
+	}
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling to capture provider-specific fields
@@ -171,8 +316,17 @@ func (p *ProviderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if keys, ok := raw["keys"].(map[string]interface{}); ok {
 		p.Keys = make(map[string]string)
 		for k, v := range keys {
-			if str, ok := v.(string); ok {
-				p.Keys[k] = str
+			switch v := v.(type) {
+			case string:
+				p.Keys[k] = v
+			case []interface{}:
+				if err := p.addKeyList(k, v); err != nil {
+					return err
+				}
+			case map[string]interface{}:
+				if err := p.addKeyMapping(k, v); err != nil {
+					return err
+				}
 			}
 		}
 		delete(raw, "keys")
@@ -188,6 +342,18 @@ func (p *ProviderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 		delete(raw, "env")
 	}
 
+	if defaults, ok := raw["defaults"]; ok {
+		values, ok := defaults.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("defaults must be a map of keys to values")
+		}
+		p.Defaults = make(map[string]string, len(values))
+		for k, v := range values {
+			p.Defaults[k] = fmt.Sprint(v)
+		}
+		delete(raw, "defaults")
+	}
+
 	if uses, ok := raw["uses"].([]interface{}); ok {
 		p.Uses = make([]string, 0, len(uses))
 		for _, v := range uses {
@@ -198,6 +364,25 @@ func (p *ProviderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 		delete(raw, "uses")
 	}
 
+	if requireClaims, ok := raw["require_claims"]; ok {
+		claims, ok := requireClaims.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("require_claims must be a map of claim names to required values")
+		}
+		p.RequireClaims = claims
+		delete(raw, "require_claims")
+	}
+
+	if enabled, ok := raw["enabled"]; ok {
+		p.Enabled = enabled
+		delete(raw, "enabled")
+	}
+
+	if hidden, ok := raw["hidden"]; ok {
+		p.Hidden = hidden
+		delete(raw, "hidden")
+	}
+
 	// Everything else goes into Config
 	p.Config = raw
 	if p.Config == nil {
@@ -210,17 +395,52 @@ func (p *ProviderConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 // EnvVars represents environment variable overrides
 type EnvVars map[string]string
 
-// Load reads and parses the configuration file
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// Load reads and parses the configuration file, resolving any included files
+// With WithGlobalConfig, the file is merged over the global config; an empty path loads the global config alone
+func Load(path string, opts ...LoadOption) (*Config, error) {
+	o := &loadOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.verifyKey != "" && path != "" {
+		if err := VerifyConfig(path, o.verifyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []string
+	data, err := readLayeredConfigData(path, o.globalPath, &files)
+	var dotenvFallback string
+	if errors.Is(err, ErrNotFound) && o.dotenvFallback != "" && o.verifyKey == "" {
+		// Without a config there is nothing to verify, so the fallback is not used when the config must be signed
+		data, dotenvFallback, err = dotenvFallbackData(o.dotenvFallback)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err = applyProfile(data, o.profile)
+	if err != nil {
+		return nil, err
+	}
+	data, err = applyProviderDefaults(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	config.Profile = o.profile
+	config.DotenvFallback = dotenvFallback
+	config.Files = files
+	if path != "" {
+		config.VerifyKey = o.verifyKey
+		if config.Path, err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("failed to resolve config path: %w", err)
+		}
+	}
 
 	// Set default value for inherit (defaults to true)
 	// Check if inherit was explicitly set in YAML, if not, default to true
@@ -238,6 +458,25 @@ func Load(path string) (*Config, error) {
 		config.Providers = make([]ProviderConfig, 0)
 	}
 
+	// Drop providers whose 'enabled' condition is false, before IDs are validated
+	// so that alternative providers (e.g. dotenv locally, vault in CI) can share an ID
+	enabledProviders := make([]ProviderConfig, 0, len(config.Providers))
+	enabledEnv := make(map[string]bool)
+	for i, p := range config.Providers {
+		enabled, err := evaluateEnabled(p.Enabled, config.Profile, o.env, enabledEnv)
+		if err != nil {
+			return nil, fmt.Errorf("provider at index %d: invalid 'enabled': %w", i, err)
+		}
+		if enabled {
+			enabledProviders = append(enabledProviders, p)
+		}
+	}
+	config.Providers = enabledProviders
+	for name := range enabledEnv {
+		config.EnabledEnv = append(config.EnabledEnv, name)
+	}
+	sort.Strings(config.EnabledEnv)
+
 	// First pass: count kinds to identify duplicates
 	kindCounts := make(map[string]int)
 	for i := range config.Providers {
@@ -282,6 +521,22 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
+	// Validate hidden keys
+	for _, p := range config.Providers {
+		if err := validateHidden(p.Hidden); err != nil {
+			return nil, fmt.Errorf("provider '%s': %w", p.ID, err)
+		}
+	}
+
+	// Validate required claims
+	for _, p := range config.Providers {
+		for claim, value := range p.RequireClaims {
+			if err := validateRequiredClaim(value); err != nil {
+				return nil, fmt.Errorf("provider '%s': require_claims.%s %w", p.ID, claim, err)
+			}
+		}
+	}
+
 	// Validate SSO configuration if present
 	if config.SSO != nil && config.SSO.OIDC != nil {
 		oidc := config.SSO.OIDC
@@ -294,6 +549,75 @@ func Load(path string) (*Config, error) {
 		if len(oidc.Scopes) == 0 {
 			return nil, fmt.Errorf("sso.oidc.scopes is required and must contain at least one scope")
 		}
+		for i, port := range oidc.CallbackPorts {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("sso.oidc.callbackPorts[%d] must be between 1 and 65535, got %d", i, port)
+			}
+		}
+		for param := range oidc.AuthParams {
+			if reservedAuthParams[param] {
+				return nil, fmt.Errorf("sso.oidc.authParams.%s is managed by sstart and cannot be overridden", param)
+			}
+		}
+	}
+	if config.SSO != nil && config.SSO.TokenStorage != nil {
+		switch config.SSO.TokenStorage.Backend {
+		case "", TokenStorageAuto, TokenStorageKeyring, TokenStorageFile, TokenStorageMemory:
+		default:
+			return nil, fmt.Errorf("sso.tokenStorage.backend must be one of auto, keyring, file, memory, got '%s'", config.SSO.TokenStorage.Backend)
+		}
+	}
+
+	// Validate lint settings if present
+	if config.Lint != nil {
+		if err := validateLint(config.Lint); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate policy rules if present
+	if config.Policy != nil {
+		if err := validatePolicy(config.Policy); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate limits if present
+	if config.Limits != nil {
+		if err := validateLimits(config.Limits); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate rate limits if present
+	if config.RateLimits != nil {
+		if err := validateRateLimits(config.RateLimits); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate notification targets if present
+	if config.Notifications != nil {
+		if err := validateNotifications(config.Notifications); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the contract if present
+	if config.Contract != nil {
+		if err := validateContract(config.Contract); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate hook commands if present
+	if config.Hooks != nil {
+		if err := validateHooks(config.Hooks); err != nil {
+			return nil, err
+		}
+		if config.Path != "" {
+			resolveHookPaths(config.Hooks, filepath.Dir(config.Path))
+		}
 	}
 
 	// Validate MCP configuration if present
@@ -306,6 +630,44 @@ func Load(path string) (*Config, error) {
 	return &config, nil
 }
 
+// reservedAuthParams are authorization request parameters set by sstart itself
+// scope and response_mode are configured through sso.oidc.scopes and sso.oidc.responseMode
+var reservedAuthParams = map[string]bool{
+	"client_id":             true,
+	"client_secret":         true,
+	"grant_type":            true,
+	"redirect_uri":          true,
+	"response_type":         true,
+	"response_mode":         true,
+	"scope":                 true,
+	"state":                 true,
+	"nonce":                 true,
+	"code_challenge":        true,
+	"code_challenge_method": true,
+}
+
+// validateRequiredClaim checks that a required claim value is a scalar or a list of scalars
+func validateRequiredClaim(value interface{}) error {
+	switch v := value.(type) {
+	case string, bool, int, float64:
+		return nil
+	case []interface{}:
+		if len(v) == 0 {
+			return fmt.Errorf("must not be an empty list")
+		}
+		for _, item := range v {
+			switch item.(type) {
+			case string, bool, int, float64:
+			default:
+				return fmt.Errorf("must contain only strings, numbers or booleans")
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("must be a string, number, boolean or list of those")
+	}
+}
+
 // validateMCPConfig validates the MCP proxy configuration
 func validateMCPConfig(mcp *MCPConfig) error {
 	if len(mcp.Servers) == 0 {
@@ -319,10 +681,19 @@ func validateMCPConfig(mcp *MCPConfig) error {
 		if server.ID == "" {
 			return fmt.Errorf("mcp.servers[%d].id is required", i)
 		}
+		if server.ID == "sstart" {
+			return fmt.Errorf("mcp.servers[%d].id 'sstart' is reserved for the proxy's built-in tools", i)
+		}
 		if server.Command == "" {
 			return fmt.Errorf("mcp.servers[%d].command is required", i)
 		}
 
+		for j, key := range server.Secrets {
+			if key == "" {
+				return fmt.Errorf("mcp.servers[%d].secrets[%d] must not be empty", i, j)
+			}
+		}
+
 		// Check for duplicate IDs
 		if _, exists := serverIDs[server.ID]; exists {
 			return fmt.Errorf("duplicate mcp server id '%s' at index %d", server.ID, i)
@@ -356,6 +727,19 @@ func (c *Config) GetCacheTTL() time.Duration {
 	return c.Cache.TTL
 }
 
+// GetCacheKeyringService returns the keyring service name of the cache, or "" if not configured
+func (c *Config) GetCacheKeyringService() string {
+	if c.Cache == nil {
+		return ""
+	}
+	return c.Cache.KeyringService
+}
+
+// IsAuditEnabled returns whether the access audit log is enabled
+func (c *Config) IsAuditEnabled() bool {
+	return c.Audit != nil && c.Audit.Enabled
+}
+
 // HasMCP returns whether MCP configuration is present
 func (c *Config) HasMCP() bool {
 	return c.MCP != nil && len(c.MCP.Servers) > 0
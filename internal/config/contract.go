@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ContractFormats are the formats a contract key can require
+var ContractFormats = []string{"url", "int", "number", "bool", "port", "email", "uuid", "json", "base64", "duration"}
+
+// ContractConfig describes the variables a service needs and their shapes, checked by sstart verify
+type ContractConfig struct {
+	Keys []ContractKey `yaml:"keys"`
+}
+
+// ContractKey describes one variable of the contract
+type ContractKey struct {
+	Name        string `yaml:"name"`                  // Variable name
+	Description string `yaml:"description,omitempty"` // What the variable is for, shown in reports
+	Required    *bool  `yaml:"required,omitempty"`    // Whether the variable must be set to a non-empty value (default: true)
+	Pattern     string `yaml:"pattern,omitempty"`     // Regular expression the whole value must match
+	Format      string `yaml:"format,omitempty"`      // url, int, number, bool, port, email, uuid, json, base64 or duration
+}
+
+// IsRequired reports whether the key must be set, which is the default
+func (k ContractKey) IsRequired() bool {
+	return k.Required == nil || *k.Required
+}
+
+// validateContract checks that keys are named once, with valid patterns and known formats
+func validateContract(contract *ContractConfig) error {
+	names := make(map[string]bool)
+	for i, key := range contract.Keys {
+		if key.Name == "" {
+			return fmt.Errorf("contract.keys[%d] is missing required field 'name'", i)
+		}
+		if names[key.Name] {
+			return fmt.Errorf("duplicate contract key '%s'", key.Name)
+		}
+		names[key.Name] = true
+
+		if key.Pattern != "" {
+			if _, err := regexp.Compile(key.Pattern); err != nil {
+				return fmt.Errorf("contract key '%s': invalid pattern: %w", key.Name, err)
+			}
+		}
+		if key.Format != "" && !slices.Contains(ContractFormats, key.Format) {
+			return fmt.Errorf("contract key '%s': unknown format '%s' (supported: %s)", key.Name, key.Format, strings.Join(ContractFormats, ", "))
+		}
+	}
+	return nil
+}
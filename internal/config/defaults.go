@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderDefaultsKey is the top-level key holding default fields per provider kind
+const ProviderDefaultsKey = "provider_defaults"
+
+// applyProviderDefaults merges the provider_defaults of each provider's kind under its fields
+// Fields set on a provider entry override the defaults; nested maps (e.g. auth) are merged key by key
+// Config data without provider_defaults is returned unchanged
+func applyProviderDefaults(data []byte) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	value, ok := raw[ProviderDefaultsKey]
+	if !ok || value == nil {
+		return data, nil
+	}
+
+	defaults, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a map of provider kinds to default fields", ProviderDefaultsKey)
+	}
+	for kind, fields := range defaults {
+		fieldMap, ok := fields.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a map of default fields", ProviderDefaultsKey, kind)
+		}
+		_, hasKind := fieldMap["kind"]
+		_, hasID := fieldMap["id"]
+		if hasKind || hasID {
+			return nil, fmt.Errorf("%s.%s cannot set 'kind' or 'id'", ProviderDefaultsKey, kind)
+		}
+	}
+
+	providers, _ := raw["providers"].([]interface{})
+	for i, item := range providers {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := entry["kind"].(string)
+		if fields, ok := defaults[kind].(map[string]interface{}); ok {
+			providers[i] = mergeMaps(fields, entry, ProviderDefaultsKey)
+		}
+	}
+
+	return yaml.Marshal(raw)
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultFileName is the name of the project config file looked up in parent directories
+	DefaultFileName = ".sstart.yml"
+	// GlobalConfigEnv overrides the location of the global config file
+	GlobalConfigEnv = "SSTART_GLOBAL_CONFIG"
+)
+
+// ErrNotFound is returned when no project or global config file exists
+var ErrNotFound = errors.New("no " + DefaultFileName + " (or .sstart.toml, .sstart.json) found in the current directory or any parent directory")
+
+// LoadOption configures how a config file is loaded
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	globalPath     string
+	profile        string
+	verifyKey      string
+	dotenvFallback string
+	env            *Environment
+}
+
+// WithGlobalConfig layers the config file at path under the project config
+// A missing global config file is ignored
+func WithGlobalConfig(path string) LoadOption {
+	return func(o *loadOptions) {
+		o.globalPath = path
+	}
+}
+
+// WithEnvironment evaluates the 'enabled' conditions of providers in env instead of the
+// environment of this process, e.g. in the agent loading the config for a client
+func WithEnvironment(env *Environment) LoadOption {
+	return func(o *loadOptions) {
+		o.env = env
+	}
+}
+
+// WithDotenvFallback loads the .env file in dir with a dotenv provider when there is neither a
+// project nor a global config file, so that a project can be used without a config
+func WithDotenvFallback(dir string) LoadOption {
+	return func(o *loadOptions) {
+		o.dotenvFallback = filepath.Join(dir, ".env")
+	}
+}
+
+// dotenvFallbackData returns the config of the dotenv fallback, or ErrNotFound if the .env file
+// does not exist
+func dotenvFallbackData(envPath string) ([]byte, string, error) {
+	info, err := os.Stat(envPath)
+	if err != nil || info.IsDir() {
+		return nil, "", ErrNotFound
+	}
+	envPath, err = filepath.Abs(envPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve '%s': %w", envPath, err)
+	}
+	data, err := yaml.Marshal(map[string]interface{}{
+		"providers": []map[string]interface{}{{"kind": "dotenv", "path": envPath}},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return data, envPath, nil
+}
+
+// GlobalConfigPath returns the path of the global config file:
+// $SSTART_GLOBAL_CONFIG, or config.yml (or config.toml, config.json) in $XDG_CONFIG_HOME/sstart (default ~/.config/sstart)
+func GlobalConfigPath() string {
+	if path := os.Getenv(GlobalConfigEnv); path != "" {
+		return path
+	}
+
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	for _, name := range []string{"config.toml", "config.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name)
+		}
+	}
+	return filepath.Join(dir, "config.yml")
+}
+
+// configDir returns $XDG_CONFIG_HOME/sstart (default ~/.config/sstart), or an empty string
+// if the home directory cannot be determined
+func configDir() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "sstart")
+}
+
+// Discover walks up from dir to the filesystem root and returns the path of the nearest
+// config file (see FileNames), or an empty string if there is none
+func Discover(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory '%s': %w", dir, err)
+	}
+
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				return path, nil
+			}
+			if err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("failed to check '%s': %w", path, err)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// DiscoverAll walks up from dir to the filesystem root and returns the paths of all config files
+// (see FileNames), nearest first
+func DiscoverAll(dir string) ([]string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory '%s': %w", dir, err)
+	}
+
+	var paths []string
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				paths = append(paths, path)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return paths, nil
+		}
+		dir = parent
+	}
+}
+
+// readLayeredConfigData reads the project config at path merged over the global config
+// An empty path loads the global config alone. The paths of the files read are appended to files (if not nil)
+func readLayeredConfigData(path, globalPath string, files *[]string) ([]byte, error) {
+	globalExists := false
+	if globalPath != "" {
+		if _, err := os.Stat(globalPath); err == nil {
+			globalExists = true
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read global config file '%s': %w", globalPath, err)
+		}
+	}
+
+	if !globalExists {
+		if path == "" {
+			return nil, ErrNotFound
+		}
+		return readConfigDataFiles(path, files)
+	}
+
+	globalData, err := readConfigDataFiles(globalPath, files)
+	if err != nil {
+		return nil, fmt.Errorf("global config '%s': %w", globalPath, err)
+	}
+	if path == "" {
+		return globalData, nil
+	}
+
+	data, err := readConfigDataFiles(path, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var global, project map[string]interface{}
+	if err := yaml.Unmarshal(globalData, &global); err != nil {
+		return nil, fmt.Errorf("failed to parse global config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if global == nil {
+		global = make(map[string]interface{})
+	}
+	if project == nil {
+		project = make(map[string]interface{})
+	}
+
+	return yaml.Marshal(mergeConfigMaps(global, project))
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pathSegment is one step of a config path: a map key or a list index
+type pathSegment struct {
+	key   string
+	index int // -1 for map keys
+}
+
+// parsePath parses a config path such as providers[0].region or mcp.servers
+func parsePath(expr string) ([]pathSegment, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	var segments []pathSegment
+	for _, part := range strings.Split(expr, ".") {
+		key := part
+		var indexes []int
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("invalid path '%s'", expr)
+				}
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index '%s' in path '%s'", rest[1:end], expr)
+				}
+				indexes = append(indexes, index)
+				rest = rest[end+1:]
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid path '%s'", expr)
+		}
+
+		segments = append(segments, pathSegment{key: key, index: -1})
+		for _, index := range indexes {
+			segments = append(segments, pathSegment{index: index})
+		}
+	}
+	return segments, nil
+}
+
+// GetValue returns the YAML encoding of the value at expr in the config file at path
+// Scalars are returned as plain text; the file is read as written, without includes or defaults
+func GetValue(path, expr string) (string, error) {
+	segments, err := parsePath(expr)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := readConfigFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return "", fmt.Errorf("'%s' is not set", expr)
+	}
+
+	node := doc.Content[0]
+	for _, segment := range segments {
+		node = childNode(node, segment)
+		if node == nil {
+			return "", fmt.Errorf("'%s' is not set", expr)
+		}
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode '%s': %w", expr, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// SetValue sets the value at expr in the YAML config file at path, preserving comments
+// The value is parsed as YAML, so "true" is a boolean and "[a, b]" a list. Missing map keys are
+// created, and an index equal to the length of a list appends to it
+func SetValue(path, expr, value string) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".toml" || ext == ".json" {
+		return fmt.Errorf("config set only supports YAML config files")
+	}
+
+	segments, err := parsePath(expr)
+	if err != nil {
+		return err
+	}
+
+	var valueDoc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &valueDoc); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	if len(valueDoc.Content) > 0 {
+		valueNode = valueDoc.Content[0]
+		blockStyle(valueNode)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	node := doc.Content[0]
+	for i, segment := range segments {
+		var next *pathSegment
+		if i+1 < len(segments) {
+			next = &segments[i+1]
+		}
+		node, err = setChild(node, segment, next, valueNode)
+		if err != nil {
+			return fmt.Errorf("cannot set '%s': %w", expr, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// childNode returns the child of node selected by segment, or nil if there is none
+func childNode(node *yaml.Node, segment pathSegment) *yaml.Node {
+	if segment.index >= 0 {
+		if node.Kind != yaml.SequenceNode || segment.index >= len(node.Content) {
+			return nil
+		}
+		return node.Content[segment.index]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == segment.key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setChild returns the child of node selected by segment, creating it if needed
+// For the last segment (next is nil), the child is replaced by value
+func setChild(node *yaml.Node, segment pathSegment, next *pathSegment, value *yaml.Node) (*yaml.Node, error) {
+	last := next == nil
+
+	// New intermediate nodes are a list or a map depending on the next segment
+	newNode := func() *yaml.Node {
+		if last {
+			return value
+		}
+		if next.index >= 0 {
+			return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	if segment.index >= 0 {
+		if node.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("[%d] used on a value that is not a list", segment.index)
+		}
+		switch {
+		case segment.index < len(node.Content):
+			if last {
+				node.Content[segment.index] = withComments(value, node.Content[segment.index])
+			}
+			return node.Content[segment.index], nil
+		case segment.index == len(node.Content):
+			child := newNode()
+			node.Content = append(node.Content, child)
+			return child, nil
+		default:
+			return nil, fmt.Errorf("index %d is out of range (list has %d items)", segment.index, len(node.Content))
+		}
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("'%s' used on a value that is not a map", segment.key)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == segment.key {
+			if last {
+				node.Content[i+1] = withComments(value, node.Content[i+1])
+			}
+			return node.Content[i+1], nil
+		}
+	}
+
+	child := newNode()
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment.key}, child)
+	return child, nil
+}
+
+// withComments copies the comments of the replaced node onto its replacement
+func withComments(value, replaced *yaml.Node) *yaml.Node {
+	copied := *value
+	copied.HeadComment = replaced.HeadComment
+	copied.LineComment = replaced.LineComment
+	copied.FootComment = replaced.FootComment
+	return &copied
+}
+
+// blockStyle clears the flow style of node and its children so values given as
+// '{kind: dotenv}' are written like the rest of the file
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
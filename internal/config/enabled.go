@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// enabledData is the data available to 'enabled' templates
+type enabledData struct {
+	OS      string // runtime.GOOS, e.g. linux, darwin, windows
+	Arch    string // runtime.GOARCH, e.g. amd64, arm64
+	Profile string // Active profile, empty if none
+}
+
+// enabledFuncs returns the functions available to 'enabled' templates, reading variables from env
+// and recording their names in read
+func enabledFuncs(env *Environment, read map[string]bool) template.FuncMap {
+	return template.FuncMap{
+		"env": func(name string) string {
+			read[name] = true
+			return env.Getenv(name)
+		},
+	}
+}
+
+// evaluateEnabled evaluates a provider's 'enabled' value in env, recording the names of the
+// variables it reads in read
+// The value is a boolean, or a template that must render to "true" or "false"
+// Example: '{{ eq (env "CI") "true" }}' or '{{ ne .OS "windows" }}'
+func evaluateEnabled(value interface{}, profile string, env *Environment, read map[string]bool) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return true, nil
+	case bool:
+		return v, nil
+	case string:
+		tmpl, err := template.New("enabled").Funcs(enabledFuncs(env, read)).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, enabledData{OS: runtime.GOOS, Arch: runtime.GOARCH, Profile: profile}); err != nil {
+			return false, fmt.Errorf("failed to execute template: %w", err)
+		}
+
+		result := strings.TrimSpace(buf.String())
+		enabled, err := strconv.ParseBool(result)
+		if err != nil {
+			return false, fmt.Errorf("must evaluate to true or false, got '%s'", result)
+		}
+		return enabled, nil
+	default:
+		return false, fmt.Errorf("must be a boolean or a template string")
+	}
+}
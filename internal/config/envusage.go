@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// templateEnvRef matches the variables template providers read from their Env metadata by name:
+// .Env.NAME or index .Env "NAME"
+var templateEnvRef = regexp.MustCompile(`\.Env\.([A-Za-z_][A-Za-z0-9_]*)|index\s+\.Env\s+"([^"]*)"`)
+
+// VolatileEnv are variables that shells change between commands of the same session, and that
+// do not tell configs apart, so that they are left out when any variable may be read
+var VolatileEnv = []string{"_", "OLDPWD", "PWD", "SHLVL"}
+
+// EnvUsage describes the environment variables the templates and providers of a config read
+// from the Environment they are collected in
+type EnvUsage struct {
+	// Names are the variables read by name, e.g. with get_env() or $VAR, mapped by env providers,
+	// or allowed for wasm plugins
+	Names []string
+	// Patterns are the glob patterns of the variables selected by env providers' 'vars'
+	Patterns []string
+	// All is set when any variable may be read: exec() commands, and template providers that
+	// use their Env metadata other than by name, see the whole environment
+	All bool
+}
+
+// EnvUsage returns the environment variables the config reads when its secrets are collected
+func (c *Config) EnvUsage() EnvUsage {
+	var usage EnvUsage
+	names := make(map[string]bool)
+
+	walkStrings(reflect.ValueOf(c).Elem(), func(s string) {
+		os.Expand(s, func(name string) string {
+			names[name] = true
+			return ""
+		})
+		for _, expr := range templateExprs(s) {
+			if !isTemplateCall(expr) {
+				continue
+			}
+			p := &templateParser{input: expr}
+			name, args, err := p.call(true)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "get_env":
+				names[args["name"]] = true
+			case "exec":
+				usage.All = true
+			}
+		}
+	})
+
+	for _, p := range c.Providers {
+		switch p.Kind {
+		case "env":
+			for key := range p.Keys {
+				names[key] = true
+			}
+			if vars, ok := p.Config["vars"].([]interface{}); ok {
+				for _, v := range vars {
+					if pattern, ok := v.(string); ok {
+						usage.Patterns = append(usage.Patterns, pattern)
+					}
+				}
+			}
+		case "wasm":
+			if allowed, ok := p.Config["allowed_env"].([]interface{}); ok {
+				for _, v := range allowed {
+					if name, ok := v.(string); ok {
+						names[name] = true
+					}
+				}
+			}
+		case "template":
+			walkStrings(reflect.ValueOf(p.Config), func(s string) {
+				for _, m := range templateEnvRef.FindAllStringSubmatch(s, -1) {
+					names[m[1]+m[2]] = true
+				}
+				if strings.Contains(templateEnvRef.ReplaceAllString(s, ""), ".Env") {
+					usage.All = true
+				}
+			})
+		}
+	}
+	for _, name := range c.EnabledEnv {
+		names[name] = true
+	}
+
+	delete(names, "")
+	for name := range names {
+		usage.Names = append(usage.Names, name)
+	}
+	sort.Strings(usage.Names)
+	sort.Strings(usage.Patterns)
+	return usage
+}
+
+// templateExprs returns the contents of the {{ ... }} expressions in s
+func templateExprs(s string) []string {
+	var exprs []string
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			return exprs
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return exprs
+		}
+		exprs = append(exprs, strings.TrimSpace(s[start+2:start+end]))
+		s = s[start+end+2:]
+	}
+}
+
+// walkStrings calls fn with every string in v, including map keys
+func walkStrings(v reflect.Value, fn func(string)) {
+	switch v.Kind() {
+	case reflect.String:
+		fn(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walkStrings(v.Field(i), fn)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkStrings(iter.Key(), fn)
+			walkStrings(iter.Value(), fn)
+		}
+	}
+}
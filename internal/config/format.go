@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the project config file names looked up in each directory, in order of precedence
+var FileNames = []string{DefaultFileName, ".sstart.toml", ".sstart.json"}
+
+// readConfigFile reads a config file and returns its content as YAML
+// The format is detected by extension: .toml files are converted, while .json
+// is read as is since JSON is valid YAML; anything else is treated as YAML
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) != ".toml" {
+		return data, nil
+	}
+
+	var raw map[string]interface{}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse TOML config file '%s': %w", path, err)
+	}
+	return yaml.Marshal(raw)
+}
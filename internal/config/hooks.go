@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// HooksConfig runs commands at points of the collection of secrets, to validate, report or
+// transform them, and around the commands run with them
+type HooksConfig struct {
+	PreFetch  []HookCommand `yaml:"pre_fetch,omitempty"`  // Run before secrets are fetched from a provider
+	PostFetch []HookCommand `yaml:"post_fetch,omitempty"` // Run with the secrets fetched from a provider, before they are cached
+	PreInject []HookCommand `yaml:"pre_inject,omitempty"` // Run with all collected secrets, before they are injected
+	PreRun    []HookCommand `yaml:"pre_run,omitempty"`    // Run with the environment of the command, before it is started by sstart run
+	PostRun   []HookCommand `yaml:"post_run,omitempty"`   // Run with the environment of the command, after it exits
+}
+
+// HookCommand is a command run by a hook
+type HookCommand struct {
+	Command   string   `yaml:"command"`             // Command to execute
+	Args      []string `yaml:"args,omitempty"`      // Command arguments
+	Providers []string `yaml:"providers,omitempty"` // Providers whose fetches run the command (default: all); fetch hooks only
+}
+
+// validateHooks checks that every hook has a command
+func validateHooks(h *HooksConfig) error {
+	hooks := []struct {
+		name     string
+		commands []HookCommand
+	}{{"pre_fetch", h.PreFetch}, {"post_fetch", h.PostFetch}, {"pre_inject", h.PreInject}, {"pre_run", h.PreRun}, {"post_run", h.PostRun}}
+	for _, hook := range hooks {
+		for i, command := range hook.commands {
+			if command.Command == "" {
+				return fmt.Errorf("hooks.%s[%d] requires 'command'", hook.name, i)
+			}
+			if hook.name != "pre_fetch" && hook.name != "post_fetch" && len(command.Providers) > 0 {
+				return fmt.Errorf("hooks.%s[%d]: 'providers' only applies to fetch hooks", hook.name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveHookPaths resolves the relative paths of hook commands, e.g. ./scripts/check.sh, against
+// dir, the directory of the config file. Commands without a path separator are looked up in PATH
+func resolveHookPaths(h *HooksConfig, dir string) {
+	for _, commands := range [][]HookCommand{h.PreFetch, h.PostFetch, h.PreInject, h.PreRun, h.PostRun} {
+		for i := range commands {
+			command := commands[i].Command
+			if strings.ContainsAny(command, "/"+string(filepath.Separator)) && !filepath.IsAbs(command) {
+				commands[i].Command = filepath.Join(dir, command)
+			}
+		}
+	}
+}
+
+// CheckHooksTrusted returns an error wrapping ErrUntrusted if the config has hook commands but
+// its project config file was not allowed with Trust, so that entering a directory or running
+// a command does not run the commands of a config file checked out from elsewhere
+func (c *Config) CheckHooksTrusted() error {
+	if c.Hooks == nil || c.Path == "" {
+		return nil
+	}
+	if err := CheckTrusted(c.Path); err != nil {
+		return fmt.Errorf("hooks: %w (run 'sstart allow' to run its hook commands)", err)
+	}
+	return nil
+}
+
+// CheckExecTrusted returns an error wrapping ErrUntrusted unless the project config file, if any,
+// was allowed with Trust, so that exec() templates, like hook commands, do not run the commands
+// of a config file checked out from elsewhere
+func (c *Config) CheckExecTrusted() error {
+	if c.Path == "" {
+		return nil
+	}
+	if err := CheckTrusted(c.Path); err != nil {
+		return fmt.Errorf("exec(): %w (run 'sstart allow' to run its commands)", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeKey is the top-level key listing other config files to merge into this one
+const IncludeKey = "include"
+
+// readConfigData reads a config file and resolves its includes
+// Files without includes are returned unchanged so parse errors keep their line numbers
+func readConfigData(path string) ([]byte, error) {
+	return readConfigDataFiles(path, nil)
+}
+
+// readConfigDataFiles is like readConfigData, and appends the paths of the files it reads,
+// including the included ones, to files (if not nil)
+func readConfigDataFiles(path string, files *[]string) ([]byte, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	addFile(files, path)
+	if _, ok := raw[IncludeKey]; !ok {
+		return data, nil
+	}
+
+	merged, err := resolveIncludes(path, raw, map[string]bool{}, files)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// loadRawConfig reads a config file into a raw map with its includes resolved
+func loadRawConfig(path string, visiting map[string]bool, files *[]string) (map[string]interface{}, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included config file '%s': %w", path, err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse included config file '%s': %w", path, err)
+	}
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+	addFile(files, path)
+
+	return resolveIncludes(path, raw, visiting, files)
+}
+
+// resolveIncludes merges the files listed under 'include' (in order) and then raw on top of them
+// Include paths are relative to the directory of the file that includes them
+func resolveIncludes(path string, raw map[string]interface{}, visiting map[string]bool, files *[]string) (map[string]interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path '%s': %w", path, err)
+	}
+	if visiting[absPath] {
+		return nil, fmt.Errorf("config include cycle detected at '%s'", path)
+	}
+	visiting[absPath] = true
+	defer delete(visiting, absPath)
+
+	includes, err := includePaths(raw[IncludeKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid include in '%s': %w", path, err)
+	}
+	delete(raw, IncludeKey)
+
+	merged := make(map[string]interface{})
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(absPath), include)
+		}
+		included, err := loadRawConfig(include, visiting, files)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeConfigMaps(merged, included)
+	}
+
+	return mergeConfigMaps(merged, raw), nil
+}
+
+// addFile appends the absolute path of a file that was read to files (if not nil)
+func addFile(files *[]string, path string) {
+	if files == nil {
+		return
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	*files = append(*files, path)
+}
+
+// includePaths converts the raw 'include' value (a string or list of strings) to paths
+func includePaths(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		paths := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok || str == "" {
+				return nil, fmt.Errorf("include entries must be non-empty file paths")
+			}
+			paths = append(paths, str)
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("include must be a file path or a list of file paths")
+	}
+}
+
+// mergeConfigMaps merges override into base and returns the result:
+//   - providers and mcp.servers are concatenated; an entry with the same id replaces the earlier one in place
+//   - other maps are merged recursively
+//   - any other value in override replaces the value in base
+func mergeConfigMaps(base, override map[string]interface{}) map[string]interface{} {
+	return mergeMaps(base, override, "")
+}
+
+func mergeMaps(base, override map[string]interface{}, path string) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		result[k] = v
+	}
+
+	for k, v := range override {
+		keyPath := k
+		if path != "" {
+			keyPath = path + "." + k
+		}
+
+		existing, exists := result[k]
+		if !exists {
+			result[k] = v
+			continue
+		}
+
+		switch keyPath {
+		case "providers", "mcp.servers":
+			baseList, baseOK := existing.([]interface{})
+			overrideList, overrideOK := v.([]interface{})
+			if baseOK && overrideOK {
+				result[k] = mergeByID(baseList, overrideList)
+				continue
+			}
+		}
+
+		baseMap, baseOK := existing.(map[string]interface{})
+		overrideMap, overrideOK := v.(map[string]interface{})
+		if baseOK && overrideOK {
+			result[k] = mergeMaps(baseMap, overrideMap, keyPath)
+			continue
+		}
+
+		result[k] = v
+	}
+
+	return result
+}
+
+// mergeByID appends override entries to base, replacing base entries that have the same id
+// Providers without an explicit id are identified by their kind, matching the default id
+func mergeByID(base, override []interface{}) []interface{} {
+	result := make([]interface{}, len(base))
+	copy(result, base)
+
+	index := make(map[string]int)
+	for i, item := range result {
+		if id := entryID(item); id != "" {
+			index[id] = i
+		}
+	}
+
+	for _, item := range override {
+		id := entryID(item)
+		if i, ok := index[id]; ok && id != "" {
+			result[i] = item
+			continue
+		}
+		if id != "" {
+			index[id] = len(result)
+		}
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// entryID returns the id of a raw provider or MCP server entry
+func entryID(item interface{}) string {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if id, ok := m["id"].(string); ok && id != "" {
+		return id
+	}
+	if kind, ok := m["kind"].(string); ok {
+		return kind
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package config
+
+import "fmt"
+
+// Actions taken when the collected secrets exceed the limits
+const (
+	LimitActionError = "error" // Refuse to inject the secrets
+	LimitActionWarn  = "warn"  // Warn on stderr and inject the secrets
+)
+
+// LimitsConfig caps the number and size of the collected secrets, so that a provider that
+// exports too much (e.g. a large JSON blob) is caught before the environment exceeds the
+// size the OS accepts for a new process
+type LimitsConfig struct {
+	MaxKeys      int    `yaml:"max_keys,omitempty"`       // Maximum number of keys
+	MaxValueSize int    `yaml:"max_value_size,omitempty"` // Maximum size of a single value, in bytes
+	MaxTotalSize int    `yaml:"max_total_size,omitempty"` // Maximum size of all KEY=value entries, in bytes
+	Action       string `yaml:"action,omitempty"`         // error (default) or warn
+}
+
+// IsWarnOnly reports whether exceeding the limits only warns
+func (l *LimitsConfig) IsWarnOnly() bool {
+	return l.Action == LimitActionWarn
+}
+
+// validateLimits checks that the limits are positive and the action is known
+func validateLimits(limits *LimitsConfig) error {
+	values := []struct {
+		name  string
+		value int
+	}{{"max_keys", limits.MaxKeys}, {"max_value_size", limits.MaxValueSize}, {"max_total_size", limits.MaxTotalSize}}
+	for _, v := range values {
+		if v.value < 0 {
+			return fmt.Errorf("limits.%s must be positive, got %d", v.name, v.value)
+		}
+	}
+	switch limits.Action {
+	case "", LimitActionError, LimitActionWarn:
+		return nil
+	default:
+		return fmt.Errorf("limits.action must be '%s' or '%s', got '%s'", LimitActionError, LimitActionWarn, limits.Action)
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Defaults for secret value linting
+var (
+	// DefaultLintSecretKeys are the key patterns checked for low entropy
+	DefaultLintSecretKeys = []string{"*_KEY", "*_SECRET", "*_TOKEN"}
+	// DefaultLintMinEntropy is the minimum Shannon entropy, in bits per character, of secret values
+	DefaultLintMinEntropy = 3.0
+)
+
+// LintConfig enables warnings for collected values that look misconfigured
+type LintConfig struct {
+	Enabled      bool     `yaml:"enabled"`                // Whether to check collected values (default: false)
+	Placeholders []string `yaml:"placeholders,omitempty"` // Extra placeholder values, compared case-insensitively
+	SecretKeys   []string `yaml:"secret_keys,omitempty"`  // Key patterns checked for low entropy (default: *_KEY, *_SECRET, *_TOKEN)
+	MinEntropy   float64  `yaml:"min_entropy,omitempty"`  // Minimum entropy in bits per character (default: 3.0)
+}
+
+// IsLintEnabled returns whether secret value linting is enabled
+func (c *Config) IsLintEnabled() bool {
+	return c.Lint != nil && c.Lint.Enabled
+}
+
+// validateLint checks the lint key patterns and entropy threshold
+func validateLint(lint *LintConfig) error {
+	for _, pattern := range lint.SecretKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("lint.secret_keys: invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	if lint.MinEntropy < 0 {
+		return fmt.Errorf("lint.min_entropy must be positive, got %g", lint.MinEntropy)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Kinds of notification targets
+const (
+	NotifySlack   = "slack"
+	NotifyWebhook = "webhook"
+	NotifyDesktop = "desktop"
+)
+
+// NotificationsConfig sends notifications when collecting secrets fails in long-running modes
+// (run --watch, agent and mcp), or when required keys are missing
+type NotificationsConfig struct {
+	RequiredKeys []string             `yaml:"required_keys,omitempty"` // Keys (glob patterns) each collection must produce
+	Targets      []NotificationTarget `yaml:"targets"`                 // Where notifications are sent
+}
+
+// NotificationTarget is a destination of notifications
+type NotificationTarget struct {
+	Kind    string            `yaml:"kind"`              // slack, webhook or desktop
+	URL     string            `yaml:"url,omitempty"`     // Webhook URL of slack and webhook targets; environment variables are expanded
+	Headers map[string]string `yaml:"headers,omitempty"` // Extra HTTP headers of webhook targets; environment variables are expanded
+}
+
+// validateNotifications checks the required key patterns and the notification targets
+func validateNotifications(n *NotificationsConfig) error {
+	for _, pattern := range n.RequiredKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("notifications.required_keys: invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	if len(n.Targets) == 0 {
+		return fmt.Errorf("notifications.targets must contain at least one target")
+	}
+	for i, target := range n.Targets {
+		switch target.Kind {
+		case NotifySlack, NotifyWebhook:
+			if target.URL == "" {
+				return fmt.Errorf("notifications.targets[%d]: %s target requires 'url'", i, target.Kind)
+			}
+		case NotifyDesktop:
+		default:
+			return fmt.Errorf("notifications.targets[%d]: unknown kind '%s' (supported: slack, webhook, desktop)", i, target.Kind)
+		}
+	}
+	return nil
+}
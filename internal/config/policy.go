@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// PolicyConfig holds rules checked against the collected secrets before they are injected
+type PolicyConfig struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule restricts the secrets whose keys match Keys
+// A rule applies to every profile unless Profiles is set; keys, profiles and providers are glob patterns
+type PolicyRule struct {
+	Name      string   `yaml:"name"`                 // Rule name shown in the policy report
+	Keys      []string `yaml:"keys,omitempty"`       // Keys the rule applies to (default: all keys)
+	Profiles  []string `yaml:"profiles,omitempty"`   // Profiles the rule applies to (default: all, including no profile)
+	Deny      bool     `yaml:"deny,omitempty"`       // Matching keys must not be injected
+	MaxLength int      `yaml:"max_length,omitempty"` // Maximum value length of matching keys
+	Providers []string `yaml:"providers,omitempty"`  // Matching keys must come from one of these providers
+}
+
+// AppliesTo reports whether the rule is active for profile
+func (r PolicyRule) AppliesTo(profile string) bool {
+	return len(r.Profiles) == 0 || MatchesAny(r.Profiles, profile)
+}
+
+// MatchesKey reports whether the rule covers key
+func (r PolicyRule) MatchesKey(key string) bool {
+	return len(r.Keys) == 0 || MatchesAny(r.Keys, key)
+}
+
+// MatchesAny reports whether s matches any of the glob patterns
+func MatchesAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePolicy checks that every rule is named, has valid patterns, and restricts something
+func validatePolicy(policy *PolicyConfig) error {
+	names := make(map[string]bool)
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("policy.rules[%d] is missing required field 'name'", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate policy rule name '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+
+		for _, patterns := range [][]string{rule.Keys, rule.Profiles, rule.Providers} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("policy rule '%s': invalid pattern '%s': %w", rule.Name, pattern, err)
+				}
+			}
+		}
+		if rule.MaxLength < 0 {
+			return fmt.Errorf("policy rule '%s': max_length must be positive, got %d", rule.Name, rule.MaxLength)
+		}
+		if !rule.Deny && rule.MaxLength == 0 && len(rule.Providers) == 0 {
+			return fmt.Errorf("policy rule '%s' must set at least one of 'deny', 'max_length' or 'providers'", rule.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProfilesKey is the top-level key holding named config overlays
+	ProfilesKey = "profiles"
+	// ProfileEnv selects the active profile when --profile is not set
+	ProfileEnv = "SSTART_PROFILE"
+)
+
+// WithProfile merges the named entry of 'profiles' over the rest of the config
+func WithProfile(name string) LoadOption {
+	return func(o *loadOptions) {
+		o.profile = name
+	}
+}
+
+// applyProfile merges the named profile over the config data
+// Profiles use the same merge rules as includes; an empty name leaves the data unchanged
+func applyProfile(data []byte, name string) ([]byte, error) {
+	if name == "" {
+		return data, nil
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	profiles, _ := raw[ProfilesKey].(map[string]interface{})
+	value, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' is not defined (available: %v)", name, profileNames(profiles))
+	}
+	profile, ok := value.(map[string]interface{})
+	if !ok {
+		if value != nil {
+			return nil, fmt.Errorf("profile '%s' must be a map of config settings", name)
+		}
+		profile = make(map[string]interface{})
+	}
+	if _, nested := profile[ProfilesKey]; nested {
+		return nil, fmt.Errorf("profile '%s' cannot define profiles", name)
+	}
+
+	return yaml.Marshal(mergeConfigMaps(raw, profile))
+}
+
+// ProfileNames returns the sorted names of the profiles defined by the config at path, with its
+// includes and the global config of the options. The config is only parsed, not validated, so
+// this is fast enough for shell completion
+func ProfileNames(path string, opts ...LoadOption) ([]string, error) {
+	o := &loadOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data, err := readLayeredConfigData(path, o.globalPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	profiles, _ := raw[ProfilesKey].(map[string]interface{})
+	return profileNames(profiles), nil
+}
+
+// profileNames returns the sorted names of the defined profiles
+func profileNames(profiles map[string]interface{}) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
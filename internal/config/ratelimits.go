@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitsConfig limits the requests sstart makes to secret backends, so that watch mode and
+// parallel fetches stay under the API rate limits of an organization
+type RateLimitsConfig struct {
+	// Maximum request rate per provider kind, as requests per second, minute or hour, e.g. "2/s" or "60/m"
+	Kinds map[string]string `yaml:"kinds,omitempty"`
+	// Maximum number of requests to all providers during one invocation of sstart (default: unlimited)
+	Budget int `yaml:"budget,omitempty"`
+}
+
+// ParseRate parses a request rate such as "2/s", "60/m" or "1000/h", returning the number of
+// requests and the period they are allowed in
+func ParseRate(s string) (int, time.Duration, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid rate '%s': expected requests per unit, e.g. 2/s or 60/m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate '%s': the number of requests must be a positive integer", s)
+	}
+	switch strings.TrimSpace(unit) {
+	case "s":
+		return n, time.Second, nil
+	case "m":
+		return n, time.Minute, nil
+	case "h":
+		return n, time.Hour, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid rate '%s': the unit must be s, m or h", s)
+	}
+}
+
+// validateRateLimits checks that the rates parse and the budget is positive
+func validateRateLimits(limits *RateLimitsConfig) error {
+	for kind, rate := range limits.Kinds {
+		if _, _, err := ParseRate(rate); err != nil {
+			return fmt.Errorf("rate_limits.kinds.%s: %w", kind, err)
+		}
+	}
+	if limits.Budget < 0 {
+		return fmt.Errorf("rate_limits.budget must be positive, got %d", limits.Budget)
+	}
+	return nil
+}
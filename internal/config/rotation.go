@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RotationConfig represents secret rotation age checks
+type RotationConfig struct {
+	MaxAge time.Duration `yaml:"max_age"` // Warn about secrets last rotated longer ago than this
+}
+
+// UnmarshalYAML implements custom YAML unmarshaling to handle max_age as a duration string
+func (r *RotationConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw struct {
+		MaxAge string `yaml:"max_age"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if raw.MaxAge == "" {
+		return nil
+	}
+
+	maxAge, err := ParseAge(raw.MaxAge)
+	if err != nil {
+		return fmt.Errorf("invalid rotation max_age '%s': %w", raw.MaxAge, err)
+	}
+	if maxAge <= 0 {
+		return fmt.Errorf("rotation max_age must be positive, got '%s'", raw.MaxAge)
+	}
+	r.MaxAge = maxAge
+	return nil
+}
+
+// ParseAge parses a duration like time.ParseDuration, also accepting a number of days such as 90d
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days")
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// RotationMaxAge returns the maximum secret age, or 0 if rotation age checks are disabled
+func (c *Config) RotationMaxAge() time.Duration {
+	if c.Rotation == nil {
+		return 0
+	}
+	return c.Rotation.MaxAge
+}
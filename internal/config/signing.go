@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SignatureSuffix is appended to a config file path to get the path of its signature
+	SignatureSuffix = ".sig"
+	// PublicKeyEnv overrides the public key used to verify config files
+	PublicKeyEnv = "SSTART_CONFIG_PUBLIC_KEY"
+	// VerifyEnv enables config verification when set to "1" or "true", like --verify-config
+	VerifyEnv = "SSTART_VERIFY_CONFIG"
+)
+
+// ErrUnsigned is returned when config verification is required but the config file has no signature
+var ErrUnsigned = errors.New("config file is not signed")
+
+// WithVerifyKey requires the project config file to carry a valid signature made with the
+// secret key belonging to the public key at path; Load fails for unsigned or modified files
+func WithVerifyKey(path string) LoadOption {
+	return func(o *loadOptions) {
+		o.verifyKey = path
+	}
+}
+
+// DefaultPublicKeyPath returns the public key used to verify config files:
+// $SSTART_CONFIG_PUBLIC_KEY, or sign.pub in $XDG_CONFIG_HOME/sstart (default ~/.config/sstart)
+func DefaultPublicKeyPath() string {
+	if path := os.Getenv(PublicKeyEnv); path != "" {
+		return path
+	}
+	if dir := configDir(); dir != "" {
+		return filepath.Join(dir, "sign.pub")
+	}
+	return ""
+}
+
+// DefaultSecretKeyPath returns the secret key used to sign config files: sign.key next to the default public key
+func DefaultSecretKeyPath() string {
+	if dir := configDir(); dir != "" {
+		return filepath.Join(dir, "sign.key")
+	}
+	return ""
+}
+
+// GenerateKeyPair writes a new Ed25519 key pair for signing config files
+// The secret key is written with 0600 permissions; existing keys are never overwritten
+func GenerateKeyPair(publicKeyPath, secretKeyPath string) error {
+	for _, path := range []string{publicKeyPath, secretKeyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("'%s' already exists", path)
+		}
+	}
+
+	publicKey, secretKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key pair: %w", err)
+	}
+	if err := writeKeyFile(secretKeyPath, "sstart secret key", secretKey.Seed(), 0600); err != nil {
+		return err
+	}
+	return writeKeyFile(publicKeyPath, "sstart public key", publicKey, 0644)
+}
+
+// SignConfig signs the config file at path, and the files it includes, with the secret key
+// at secretKeyPath and writes the signature to path + SignatureSuffix
+func SignConfig(path, secretKeyPath string) error {
+	seed, err := readKeyFile(secretKeyPath, ed25519.SeedSize)
+	if err != nil {
+		return fmt.Errorf("failed to read secret key: %w", err)
+	}
+	data, err := readConfigData(path)
+	if err != nil {
+		return err
+	}
+
+	signature := ed25519.Sign(ed25519.NewKeyFromSeed(seed), data)
+	comment := "signature of " + filepath.Base(path)
+	return writeKeyFile(path+SignatureSuffix, comment, signature, 0644)
+}
+
+// VerifyConfig checks that the config file at path, and the files it includes, match the
+// signature at path + SignatureSuffix made with the secret key of the public key at publicKeyPath
+func VerifyConfig(path, publicKeyPath string) error {
+	publicKey, err := readKeyFile(publicKeyPath, ed25519.PublicKeySize)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	signature, err := readKeyFile(path+SignatureSuffix, ed25519.SignatureSize)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s not found", ErrUnsigned, path+SignatureSuffix)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config signature: %w", err)
+	}
+	data, err := readConfigData(path)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), data, signature) {
+		return fmt.Errorf("config file '%s' does not match its signature: it was modified after signing, or signed with another key", path)
+	}
+	return nil
+}
+
+// writeKeyFile writes a key or signature in the minisign layout: an untrusted comment line
+// followed by the base64 encoded bytes
+func writeKeyFile(path, comment string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", path, err)
+	}
+	content := "untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(data) + "\n"
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", path, err)
+	}
+	return nil
+}
+
+// readKeyFile reads a file written by writeKeyFile and checks the size of the decoded bytes
+func readKeyFile(path string, size int) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	encoded := strings.TrimSpace(lines[len(lines)-1])
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) != size {
+		return nil, fmt.Errorf("'%s' is not a valid sstart key or signature file", path)
+	}
+	return data, nil
+}
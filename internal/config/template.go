@@ -0,0 +1,426 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Environment is the working directory and environment variables config templates are expanded
+// in, when they are not those of this process, e.g. in the agent collecting for a client
+type Environment struct {
+	// Dir is the working directory (empty for this process's)
+	Dir string
+	// Env holds KEY=VALUE entries like os.Environ (nil for this process's)
+	Env []string
+	// Trusted is called before exec() runs a command, to check that the config file may run
+	// commands (see Config.CheckExecTrusted). If nil, commands run unchecked
+	Trusted func() error
+}
+
+// Getenv returns the value of an environment variable, like os.Getenv. A nil Environment is the
+// environment of this process
+func (e *Environment) Getenv(key string) string {
+	if e == nil || e.Env == nil {
+		return os.Getenv(key)
+	}
+	for i := len(e.Env) - 1; i >= 0; i-- {
+		if name, value, ok := strings.Cut(e.Env[i], "="); ok && name == key {
+			return value
+		}
+	}
+	return ""
+}
+
+// Environ returns the environment variables, like os.Environ
+func (e *Environment) Environ() []string {
+	if e == nil || e.Env == nil {
+		return os.Environ()
+	}
+	return e.Env
+}
+
+// path resolves a relative path against the working directory
+func (e *Environment) path(path string) string {
+	if e == nil || e.Dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(e.Dir, path)
+}
+
+// templateFunc is a config template function called with named string arguments
+type templateFunc func(env *Environment, args map[string]string) (string, error)
+
+// templateFuncs are the functions available in config templates, e.g. {{ get_env(name="HOME") }}
+var templateFuncs = map[string]templateFunc{
+	"get_env": func(env *Environment, args map[string]string) (string, error) {
+		if value := env.Getenv(args["name"]); value != "" {
+			return value, nil
+		}
+		return args["default"], nil
+	},
+	"file": func(env *Environment, args map[string]string) (string, error) {
+		path, err := expandHome(args["path"])
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(env.path(path))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	},
+	"exec": func(env *Environment, args map[string]string) (string, error) {
+		if env != nil && env.Trusted != nil {
+			if err := env.Trusted(); err != nil {
+				return "", err
+			}
+		}
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", args["command"])
+		} else {
+			cmd = exec.Command("sh", "-c", args["command"])
+		}
+		if env != nil {
+			cmd.Dir = env.Dir
+			cmd.Env = env.Env
+		}
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("command '%s' failed: %w", args["command"], err)
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	},
+	"homedir": func(env *Environment, args map[string]string) (string, error) {
+		return os.UserHomeDir()
+	},
+}
+
+// templateRequiredArgs lists the arguments each template function requires
+var templateRequiredArgs = map[string][]string{
+	"get_env": {"name"},
+	"file":    {"path"},
+	"exec":    {"command"},
+}
+
+// templateFilters are the filters that can be applied to a function result, e.g. {{ get_env(name="X") | upper }}
+var templateFilters = map[string]func(value string, args map[string]string) string{
+	"default": func(value string, args map[string]string) string {
+		if value == "" {
+			return args["value"]
+		}
+		return value
+	},
+	"trim":  func(value string, args map[string]string) string { return strings.TrimSpace(value) },
+	"upper": func(value string, args map[string]string) string { return strings.ToUpper(value) },
+	"lower": func(value string, args map[string]string) string { return strings.ToLower(value) },
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// Expand returns a copy of v with config templates expanded in every string it contains
+// It is applied to provider settings, SSO settings, and MCP servers right before they are used,
+// so exec() and file() only run for the parts of the config that are needed
+func Expand[T any](v T) (T, error) {
+	return ExpandIn(v, nil)
+}
+
+// ExpandIn is like Expand, in the working directory and environment of env (nil for this process's)
+func ExpandIn[T any](v T, env *Environment) (T, error) {
+	expanded, err := expandValue(reflect.ValueOf(&v).Elem(), env)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return expanded.Interface().(T), nil
+}
+
+// expandValue returns a deep copy of v with config templates expanded in all strings
+func expandValue(v reflect.Value, env *Environment) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, err := expandString(v.String(), env)
+		if err != nil {
+			return v, err
+		}
+		return reflect.ValueOf(expanded).Convert(v.Type()), nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := expandValue(v.Elem(), env)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := expandValue(v.Elem(), env)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, nil
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !out.Field(i).CanSet() {
+				continue
+			}
+			field, err := expandValue(v.Field(i), env)
+			if err != nil {
+				return v, err
+			}
+			out.Field(i).Set(field)
+		}
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := expandValue(v.Index(i), env)
+			if err != nil {
+				return v, err
+			}
+			out.Index(i).Set(item)
+		}
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := expandValue(iter.Value(), env)
+			if err != nil {
+				return v, err
+			}
+			out.SetMapIndex(iter.Key(), value)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// expandString expands config templates in s:
+//   - {{ func(arg="value", ...) | filter(arg="value") }} using templateFuncs and templateFilters
+//   - ${VAR} and $VAR environment variable references, outside of function results
+//
+// Other {{ ... }} expressions (e.g. Go templates used by the template provider) are left as is
+func expandString(s string, env *Environment) (string, error) {
+	expandEnv := func(s string) string { return os.Expand(s, env.Getenv) }
+	if !strings.Contains(s, "{{") {
+		return expandEnv(s), nil
+	}
+
+	var out strings.Builder
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			out.WriteString(expandEnv(rest))
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			out.WriteString(expandEnv(rest))
+			break
+		}
+		end += start
+
+		expr := strings.TrimSpace(rest[start+2 : end])
+		out.WriteString(expandEnv(rest[:start]))
+		if isTemplateCall(expr) {
+			value, err := evalTemplate(expr, env)
+			if err != nil {
+				return "", fmt.Errorf("failed to expand '{{ %s }}': %w", expr, err)
+			}
+			out.WriteString(value)
+		} else {
+			out.WriteString(expandEnv(rest[start : end+2]))
+		}
+		rest = rest[end+2:]
+	}
+
+	return out.String(), nil
+}
+
+// isTemplateCall reports whether expr starts with a call to a known template function
+func isTemplateCall(expr string) bool {
+	end := strings.IndexFunc(expr, isNotIdent)
+	if end < 0 {
+		return false
+	}
+	_, ok := templateFuncs[expr[:end]]
+	return ok && strings.HasPrefix(strings.TrimSpace(expr[end:]), "(")
+}
+
+func isNotIdent(r rune) bool {
+	return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// evalTemplate evaluates a function call followed by optional filters
+func evalTemplate(expr string, env *Environment) (string, error) {
+	p := &templateParser{input: expr}
+
+	name, args, err := p.call(true)
+	if err != nil {
+		return "", err
+	}
+	for _, required := range templateRequiredArgs[name] {
+		if _, ok := args[required]; !ok {
+			return "", fmt.Errorf("%s() requires the '%s' argument", name, required)
+		}
+	}
+	value, err := templateFuncs[name](env, args)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			return value, nil
+		}
+		if !p.consume('|') {
+			return "", fmt.Errorf("unexpected '%s'", p.input[p.pos:])
+		}
+		name, args, err := p.call(false)
+		if err != nil {
+			return "", err
+		}
+		filter, ok := templateFilters[name]
+		if !ok {
+			return "", fmt.Errorf("unknown filter '%s'", name)
+		}
+		value = filter(value, args)
+	}
+}
+
+// templateParser parses name(arg="value", ...) calls; parentheses are optional for filters
+type templateParser struct {
+	input string
+	pos   int
+}
+
+func (p *templateParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *templateParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *templateParser) consume(c byte) bool {
+	p.skipSpace()
+	if !p.done() && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *templateParser) ident() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for !p.done() && !isNotIdent(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", fmt.Errorf("expected a name at '%s'", p.input[start:])
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *templateParser) str() (string, error) {
+	p.skipSpace()
+	if p.done() || (p.input[p.pos] != '"' && p.input[p.pos] != '\'') {
+		return "", fmt.Errorf("expected a quoted string at '%s'", p.input[p.pos:])
+	}
+	quote := p.input[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && !p.done():
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == quote:
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *templateParser) call(requireParens bool) (string, map[string]string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make(map[string]string)
+	if !p.consume('(') {
+		if requireParens {
+			return "", nil, fmt.Errorf("expected '(' after %s", name)
+		}
+		return name, args, nil
+	}
+	if p.consume(')') {
+		return name, args, nil
+	}
+	for {
+		key, err := p.ident()
+		if err != nil {
+			return "", nil, err
+		}
+		if !p.consume('=') {
+			return "", nil, fmt.Errorf("expected '=' after argument '%s'", key)
+		}
+		value, err := p.str()
+		if err != nil {
+			return "", nil, err
+		}
+		args[key] = value
+
+		if p.consume(')') {
+			return name, args, nil
+		}
+		if !p.consume(',') {
+			return "", nil, fmt.Errorf("expected ',' or ')' in %s()", name)
+		}
+	}
+}
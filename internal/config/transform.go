@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// valueTransforms are the transforms that can be applied to values in keys mappings
+var valueTransforms = map[string]func(string) string{
+	// trim removes leading and trailing whitespace, including trailing newlines
+	"trim": strings.TrimSpace,
+	// unquote removes one pair of matching single or double quotes around the value
+	"unquote": func(value string) string {
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			return value[1 : len(value)-1]
+		}
+		return value
+	},
+	// newlines converts CRLF and CR line endings to LF
+	"newlines": func(value string) string {
+		value = strings.ReplaceAll(value, "\r\n", "\n")
+		return strings.ReplaceAll(value, "\r", "\n")
+	},
+}
+
+// TransformNames returns the names of the available value transforms, sorted
+func TransformNames() []string {
+	names := make([]string, 0, len(valueTransforms))
+	for name := range valueTransforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TransformValue applies the transforms set in the keys mapping of key (after key mapping) to value
+func (p *ProviderConfig) TransformValue(key, value string) string {
+	for _, name := range p.KeyTransforms[key] {
+		value = valueTransforms[name](value)
+	}
+	return value
+}
+
+// addKeyMapping maps source with the long form of a keys mapping: target keys in 'to'
+// (default: keep the name) and the transforms applied to the value in 'transform'
+func (p *ProviderConfig) addKeyMapping(source string, mapping map[string]interface{}) error {
+	targets := []interface{}{"=="}
+	var transforms []string
+	for field, value := range mapping {
+		switch field {
+		case "to":
+			switch v := value.(type) {
+			case string:
+				targets = []interface{}{v}
+			case []interface{}:
+				targets = v
+			default:
+				return fmt.Errorf("keys.%s.to must be a key or a list of keys", source)
+			}
+		case "transform":
+			names, err := parseTransforms(value)
+			if err != nil {
+				return fmt.Errorf("keys.%s.transform: %w", source, err)
+			}
+			transforms = names
+		default:
+			return fmt.Errorf("keys.%s: unknown field '%s' (expected 'to' or 'transform')", source, field)
+		}
+	}
+
+	if err := p.addKeyList(source, targets); err != nil {
+		return err
+	}
+	if len(transforms) > 0 {
+		if p.KeyTransforms == nil {
+			p.KeyTransforms = make(map[string][]string)
+		}
+		p.KeyTransforms[p.Keys[source]] = transforms
+	}
+	return nil
+}
+
+// parseTransforms returns the transform names of a 'transform' value, a name or a list of names
+func parseTransforms(value interface{}) ([]string, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case string:
+		items = []interface{}{v}
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("must be a transform or a list of transforms")
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		name, _ := item.(string)
+		if _, ok := valueTransforms[name]; !ok {
+			return nil, fmt.Errorf("unknown transform '%v' (available: %s)", item, strings.Join(TransformNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
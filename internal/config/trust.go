@@ -0,0 +1,97 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUntrusted is returned for config files that were not allowed with Trust, or changed since
+var ErrUntrusted = errors.New("config file is not allowed")
+
+// Trust allows the config file at path, as it is now, to be loaded without being asked for,
+// e.g. by the shell hook when entering its directory. Changing the file, or a file it includes,
+// revokes the trust until Trust is called again
+func Trust(path string) error {
+	trustPath, digest, err := trustEntry(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(trustPath), 0700); err != nil {
+		return fmt.Errorf("failed to create trust directory: %w", err)
+	}
+	if err := os.WriteFile(trustPath, []byte(digest+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to allow config file: %w", err)
+	}
+	return nil
+}
+
+// Untrust revokes the trust given to the config file at path by Trust
+func Untrust(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file path: %w", err)
+	}
+	trustPath, err := trustFilePath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(trustPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to deny config file: %w", err)
+	}
+	return nil
+}
+
+// CheckTrusted returns ErrUntrusted unless the config file at path was allowed with Trust and
+// has not changed since
+func CheckTrusted(path string) error {
+	trustPath, digest, err := trustEntry(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(trustPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrUntrusted, path)
+		}
+		return fmt.Errorf("failed to read trust of config file: %w", err)
+	}
+	if strings.TrimSpace(string(data)) != digest {
+		return fmt.Errorf("%w: %s changed since it was allowed", ErrUntrusted, path)
+	}
+	return nil
+}
+
+// trustEntry returns the file recording the trust of the config file at path, and the digest
+// of its current contents, includes resolved
+func trustEntry(path string) (string, string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve config file path: %w", err)
+	}
+	data, err := readConfigData(path)
+	if err != nil {
+		return "", "", err
+	}
+	trustPath, err := trustFilePath(path)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return trustPath, hex.EncodeToString(sum[:]), nil
+}
+
+// trustFilePath returns the file recording the trust of the config file at the absolute path:
+// named by the digest of the path in the trusted directory of $XDG_CONFIG_HOME/sstart
+func trustFilePath(path string) (string, error) {
+	dir := configDir()
+	if dir == "" {
+		return "", errors.New("failed to resolve trust directory: home directory not found")
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, "trusted", hex.EncodeToString(sum[:])), nil
+}
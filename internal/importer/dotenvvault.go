@@ -0,0 +1,115 @@
+// Package importer reads the secrets of other secret managers, for migrating to sstart
+package importer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/provider/dotenv"
+)
+
+// Environment is a named set of imported secrets, e.g. the production environment of a .env.vault file
+type Environment struct {
+	Name    string
+	Secrets map[string]string
+}
+
+// dotenvVaultPrefix prefixes the variables of a .env.vault file holding each encrypted environment
+const dotenvVaultPrefix = "DOTENV_VAULT_"
+
+// DotenvVault decrypts the environments of a dotenv-vault .env.vault file with their DOTENV_KEYs,
+// e.g. dotenv://:key_1234...@dotenv.org/vault/.env.vault?environment=production
+func DotenvVault(data []byte, keys []string) ([]Environment, error) {
+	vault, err := dotenv.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .env.vault file: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a DOTENV_KEY is required to decrypt the .env.vault file (available environments: %s)", strings.Join(vaultEnvironments(vault), ", "))
+	}
+
+	envs := make([]Environment, 0, len(keys))
+	for _, dotenvKey := range keys {
+		name, key, err := parseDotenvKey(dotenvKey)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, ok := vault[dotenvVaultPrefix+strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("environment '%s' not found in .env.vault file (available environments: %s)", name, strings.Join(vaultEnvironments(vault), ", "))
+		}
+		plaintext, err := decryptDotenvVault(ciphertext, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt environment '%s': %w", name, err)
+		}
+		secrets, err := dotenv.Parse(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse environment '%s': %w", name, err)
+		}
+		envs = append(envs, Environment{Name: name, Secrets: secrets})
+	}
+	return envs, nil
+}
+
+// parseDotenvKey returns the environment and the AES-256 key of a DOTENV_KEY
+func parseDotenvKey(dotenvKey string) (string, []byte, error) {
+	u, err := url.Parse(strings.TrimSpace(dotenvKey))
+	if err != nil || u.Scheme != "dotenv" {
+		return "", nil, fmt.Errorf("invalid DOTENV_KEY: expected dotenv://:key_...@dotenv.org/vault/.env.vault?environment=...")
+	}
+	password, _ := u.User.Password()
+	if len(password) < 64 {
+		return "", nil, fmt.Errorf("invalid DOTENV_KEY: missing key part")
+	}
+	key, err := hex.DecodeString(password[len(password)-64:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid DOTENV_KEY: key part is not hexadecimal")
+	}
+	environment := u.Query().Get("environment")
+	if environment == "" {
+		return "", nil, fmt.Errorf("invalid DOTENV_KEY: missing environment parameter")
+	}
+	return environment, key, nil
+}
+
+// decryptDotenvVault decrypts a base64 AES-256-GCM ciphertext, prefixed with its 12-byte nonce
+func decryptDotenvVault(ciphertext string, key []byte) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("ciphertext is not base64: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong DOTENV_KEY or corrupted ciphertext")
+	}
+	return plaintext, nil
+}
+
+// vaultEnvironments returns the environment names of a parsed .env.vault file, in lower case
+func vaultEnvironments(vault map[string]string) []string {
+	var names []string
+	for name := range vault {
+		if env, ok := strings.CutPrefix(name, dotenvVaultPrefix); ok {
+			names = append(names, strings.ToLower(env))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
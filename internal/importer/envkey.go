@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dirathea/sstart/internal/provider/dotenv"
+)
+
+// EnvKey reads the secrets of an environment exported from EnvKey, either as a JSON object of
+// names to values or in .env format
+func EnvKey(data []byte) (map[string]string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		secrets, err := dotenv.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EnvKey export: %w", err)
+		}
+		return secrets, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse EnvKey export: %w", err)
+	}
+	secrets := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			secrets[key] = v
+		case nil:
+			// Variables without a value in the environment are left out
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode value of %s: %w", key, err)
+			}
+			secrets[key] = string(encoded)
+		}
+	}
+	return secrets, nil
+}
@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	// HealthToolName is the name of the built-in tool reporting the health of the downstream servers.
+	// Server IDs cannot be "sstart", so it never collides with a namespaced tool
+	HealthToolName = "sstart" + NamespaceSeparator + "health"
+
+	// HealthMetaKey is the key of the health report in the _meta of ping results
+	HealthMetaKey = "sstart/health"
+
+	// healthPingTimeout bounds the ping of each downstream server by the health tool
+	healthPingTimeout = 2 * time.Second
+)
+
+// Health statuses of the proxy
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// ServerHealth is the status of a downstream server
+type ServerHealth struct {
+	ID          string `json:"id"`
+	State       string `json:"state"`
+	Initialized bool   `json:"initialized"`
+	Restarts    int    `json:"restarts"`
+	// LatencyMs is the round trip of a ping, set when the server was pinged and answered
+	LatencyMs *int64 `json:"latencyMs,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Health is the aggregated status of the downstream servers. The status is degraded when a
+// server is in the error state or failed its ping; servers not started yet are healthy
+type Health struct {
+	Status  string         `json:"status"`
+	Servers []ServerHealth `json:"servers"`
+}
+
+// Health reports the status of all servers, sorted by ID. With ping, running servers that are
+// initialized are pinged to check that they still answer
+func (m *ServerManager) Health(ctx context.Context, ping bool) Health {
+	ids := m.Servers()
+	sort.Strings(ids)
+
+	health := Health{Status: HealthOK, Servers: make([]ServerHealth, 0, len(ids))}
+	for _, id := range ids {
+		server, ok := m.GetServer(id)
+		if !ok {
+			continue
+		}
+		status := server.health(ctx, ping)
+		if status.State == ServerStateError.String() || status.Error != "" {
+			health.Status = HealthDegraded
+		}
+		health.Servers = append(health.Servers, status)
+	}
+	return health
+}
+
+// health returns the status of the server, pinging it if requested and possible
+func (s *Server) health(ctx context.Context, ping bool) ServerHealth {
+	state := s.State()
+	status := ServerHealth{
+		ID:          s.config.ID,
+		State:       state.String(),
+		Initialized: s.Capabilities() != nil,
+		Restarts:    int(s.restarts.Load()),
+	}
+	if !ping || state != ServerStateRunning || !status.Initialized {
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := s.SendRequest(ctx, MethodPing, nil)
+	switch {
+	case err != nil:
+		status.Error = err.Error()
+	case resp.Error != nil:
+		status.Error = resp.Error.Message
+	default:
+		latency := time.Since(start).Milliseconds()
+		status.LatencyMs = &latency
+	}
+	return status
+}
+
+// healthTool is the tool listed for the built-in health check
+func healthTool() Tool {
+	return Tool{
+		Name:        HealthToolName,
+		Description: "Report the status of the MCP servers behind the sstart proxy: their state, restarts, and ping latency",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+	}
+}
+
+// handlePing answers a ping of the host, with the last known status of the downstream servers
+// in the _meta of the result. Servers are not pinged, so that the answer stays fast
+func (p *Proxy) handlePing(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+	result := map[string]interface{}{
+		"_meta": map[string]interface{}{HealthMetaKey: p.manager.Health(p.ctx, false)},
+	}
+	return NewJSONRPCResponse(msg.ID.Value(), result)
+}
+
+// handleHealthTool answers a call of the built-in health tool, pinging the running servers. The
+// report is returned as JSON text and as structured content
+func (p *Proxy) handleHealthTool(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+	health := p.manager.Health(ctx, true)
+	data, err := json.MarshalIndent(health, "", "  ")
+	if err != nil {
+		return NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
+	}
+	result := map[string]interface{}{
+		"content":           []map[string]interface{}{{"type": "text", "text": string(data)}},
+		"structuredContent": health,
+		"isError":           health.Status != HealthOK,
+	}
+	return NewJSONRPCResponse(msg.ID.Value(), result)
+}
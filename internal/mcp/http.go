@@ -0,0 +1,366 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// SessionHeader carries the session ID of streamable HTTP clients
+	SessionHeader = "Mcp-Session-Id"
+
+	// maxHTTPMessageSize is the largest message clients may POST
+	maxHTTPMessageSize = 4 << 20
+
+	// sessionBufferSize is the number of messages queued for a session's event stream
+	sessionBufferSize = 64
+)
+
+// errShuttingDown is returned for new sessions once the handler is shutting down
+var errShuttingDown = errors.New("sstart MCP proxy is shutting down")
+
+// HTTPHandler serves the proxy to several clients at once, over the streamable HTTP transport
+// on /mcp and the HTTP+SSE transport on /sse and /message. Each client gets a session with
+// a proxy of its own, and all sessions share the downstream servers of the manager
+type HTTPHandler struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	manager *ServerManager
+	version string
+	mux     *http.ServeMux
+
+	sessions map[string]*httpSession
+	draining bool
+	mu       sync.Mutex
+}
+
+// httpSession is the connection of one client
+type httpSession struct {
+	id        string
+	proxy     *Proxy
+	transport *sessionTransport
+}
+
+// NewHTTPHandler creates a handler serving the downstream servers of manager. Servers are
+// started with the values of ctx but not its cancellation, so that they outlive the sessions
+// that start them and are only stopped by Shutdown or the manager
+func NewHTTPHandler(ctx context.Context, manager *ServerManager, version string) *HTTPHandler {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	h := &HTTPHandler{
+		ctx:      ctx,
+		cancel:   cancel,
+		manager:  manager,
+		version:  version,
+		mux:      http.NewServeMux(),
+		sessions: make(map[string]*httpSession),
+	}
+	h.mux.HandleFunc("POST /mcp", h.handlePost)
+	h.mux.HandleFunc("GET /mcp", h.handleStream)
+	h.mux.HandleFunc("DELETE /mcp", h.handleDelete)
+	h.mux.HandleFunc("GET /sse", h.handleSSE)
+	h.mux.HandleFunc("POST /message", h.handleSSEMessage)
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Close ends all sessions, and cancels the requests they forwarded downstream
+func (h *HTTPHandler) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, session := range h.sessions {
+		session.proxy.close()
+		delete(h.sessions, id)
+	}
+	h.cancel()
+}
+
+// Shutdown refuses new sessions and requests, waits for the requests being handled to be
+// answered until ctx is done, then stops the downstream servers gracefully and ends all sessions
+func (h *HTTPHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	sessions := make([]*httpSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+
+	var drainErr error
+	for _, session := range sessions {
+		if err := session.proxy.Drain(ctx); err != nil {
+			drainErr = err
+		}
+	}
+	stopErr := h.manager.ShutdownAll()
+	h.Close()
+	if drainErr != nil {
+		return drainErr
+	}
+	return stopErr
+}
+
+// newSession creates a session with a proxy of its own
+func (h *HTTPHandler) newSession() (*httpSession, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.draining {
+		return nil, errShuttingDown
+	}
+	transport := newSessionTransport()
+	id := hex.EncodeToString(b)
+	session := &httpSession{
+		id:        id,
+		proxy:     NewProxy(h.manager, h.manager.recorder.Transport(transport, id), h.version),
+		transport: transport,
+	}
+	session.proxy.ctx = h.ctx
+	h.sessions[session.id] = session
+	return session, nil
+}
+
+// session returns the session with an ID, or nil
+func (h *HTTPHandler) session(id string) *httpSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sessions[id]
+}
+
+// endSession removes a session and stops its proxy
+func (h *HTTPHandler) endSession(id string) bool {
+	h.mu.Lock()
+	session, ok := h.sessions[id]
+	delete(h.sessions, id)
+	h.mu.Unlock()
+
+	if ok {
+		session.proxy.close()
+	}
+	return ok
+}
+
+// handlePost handles a message POSTed by a streamable HTTP client. Responses are returned in
+// the response body; an initialize request starts a session
+func (h *HTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	msg, ok := readHTTPMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var session *httpSession
+	if msg.Method == MethodInitialize {
+		var err error
+		if session, err = h.newSession(); err != nil {
+			http.Error(w, err.Error(), sessionErrorStatus(err))
+			return
+		}
+		w.Header().Set(SessionHeader, session.id)
+	} else {
+		id := r.Header.Get(SessionHeader)
+		if id == "" {
+			http.Error(w, "missing "+SessionHeader+" header", http.StatusBadRequest)
+			return
+		}
+		if session = h.session(id); session == nil {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+	}
+
+	replied := false
+	h.process(r.Context(), session, msg, func(resp *JSONRPCMessage) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+		}
+		replied = true
+	})
+	if !replied {
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// handleStream opens the event stream on which a streamable HTTP client receives notifications
+func (h *HTTPHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Accept must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	session := h.session(r.Header.Get(SessionHeader))
+	if session == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	h.streamEvents(w, r, session, "")
+}
+
+// handleDelete ends the session of a streamable HTTP client
+func (h *HTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if !h.endSession(r.Header.Get(SessionHeader)) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSSE starts the session of an HTTP+SSE client. The stream first tells the client where
+// to POST its messages, then carries the responses and notifications, until the client disconnects
+func (h *HTTPHandler) handleSSE(w http.ResponseWriter, r *http.Request) {
+	session, err := h.newSession()
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+	defer h.endSession(session.id)
+
+	h.streamEvents(w, r, session, "/message?sessionId="+session.id)
+}
+
+// handleSSEMessage handles a message POSTed by an HTTP+SSE client. The response is sent on the
+// client's event stream
+func (h *HTTPHandler) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	session := h.session(r.URL.Query().Get("sessionId"))
+	if session == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	msg, ok := readHTTPMessage(w, r)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	go h.process(h.ctx, session, msg, func(resp *JSONRPCMessage) {
+		_ = session.transport.WriteMessage(resp)
+	})
+}
+
+// process passes a message of the host of a session to its proxy, recording the message and the
+// response. Notifications the proxy sends are recorded by its transport
+func (h *HTTPHandler) process(ctx context.Context, session *httpSession, msg *JSONRPCMessage, reply func(*JSONRPCMessage)) {
+	h.manager.recorder.Record(DirectionHostToProxy, "", session.id, msg)
+	session.proxy.process(ctx, msg, func(resp *JSONRPCMessage) {
+		h.manager.recorder.Record(DirectionProxyToHost, "", session.id, resp)
+		reply(resp)
+	})
+}
+
+// streamEvents writes the messages of a session as server-sent events until the client
+// disconnects or the session ends. If endpoint is set, it is sent first as an endpoint event
+func (h *HTTPHandler) streamEvents(w http.ResponseWriter, r *http.Request, session *httpSession, endpoint string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if endpoint != "" {
+		fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-session.transport.done:
+			return
+		case msg := <-session.transport.messages:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// sessionErrorStatus returns the HTTP status for an error creating a session
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, errShuttingDown) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// readHTTPMessage reads the JSON-RPC message in the body of a request, writing an error
+// response if it is invalid
+func readHTTPMessage(w http.ResponseWriter, r *http.Request) (*JSONRPCMessage, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read message", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	var msg JSONRPCMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, "invalid JSON-RPC message", http.StatusBadRequest)
+		return nil, false
+	}
+	return &msg, true
+}
+
+// sessionTransport is the transport of a session's proxy. Messages the proxy writes are queued
+// for the session's event stream; messages of the client arrive as HTTP requests instead of
+// being read
+type sessionTransport struct {
+	messages  chan *JSONRPCMessage
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSessionTransport() *sessionTransport {
+	return &sessionTransport{
+		messages: make(chan *JSONRPCMessage, sessionBufferSize),
+		done:     make(chan struct{}),
+	}
+}
+
+// ReadMessage returns io.EOF: messages of the client are handled as they are POSTed
+func (t *sessionTransport) ReadMessage() (*JSONRPCMessage, error) {
+	return nil, io.EOF
+}
+
+// WriteMessage queues a message for the session's event stream. Messages are dropped while the
+// queue is full, for example when the client has no stream open
+func (t *sessionTransport) WriteMessage(msg *JSONRPCMessage) error {
+	select {
+	case <-t.done:
+		return fmt.Errorf("transport is closed")
+	default:
+	}
+	select {
+	case t.messages <- msg:
+		return nil
+	default:
+		return fmt.Errorf("event stream of the session is full")
+	}
+}
+
+// Close ends the session's event stream
+func (t *sessionTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	return nil
+}
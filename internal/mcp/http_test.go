@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionTransport_WriteMessage(t *testing.T) {
+	transport := newSessionTransport()
+	msg, _ := NewJSONRPCNotification(MethodPromptsListChanged, nil)
+
+	// Messages are queued until the queue is full
+	for i := 0; i < sessionBufferSize; i++ {
+		if err := transport.WriteMessage(msg); err != nil {
+			t.Fatalf("unexpected error on message %d: %v", i, err)
+		}
+	}
+	if err := transport.WriteMessage(msg); err == nil {
+		t.Error("expected error writing to a full queue")
+	}
+
+	<-transport.messages
+	if err := transport.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if err := transport.Close(); err != nil {
+		t.Fatalf("failed to close twice: %v", err)
+	}
+	if err := transport.WriteMessage(msg); err == nil {
+		t.Error("expected error writing to closed transport")
+	}
+}
+
+func TestHTTPHandler_Sessions(t *testing.T) {
+	handler := NewHTTPHandler(context.Background(), NewServerManager(nil, nil, false), "test")
+	defer handler.Close()
+
+	post := func(session, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		if session != "" {
+			req.Header.Set(SessionHeader, session)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	session := rec.Header().Get(SessionHeader)
+	if rec.Code != http.StatusOK || session == "" {
+		t.Fatalf("expected a session, got status %d: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name    string
+		session string
+		body    string
+		want    int
+	}{
+		{"request", session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, http.StatusOK},
+		{"notification", session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, http.StatusAccepted},
+		{"missing session", "", `{"jsonrpc":"2.0","id":2,"method":"ping"}`, http.StatusBadRequest},
+		{"unknown session", "unknown", `{"jsonrpc":"2.0","id":2,"method":"ping"}`, http.StatusNotFound},
+		{"invalid message", session, `{invalid json}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.session, tt.body); rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -39,6 +39,7 @@ const (
 	MethodResourcesTemplatesList = "resources/templates/list"
 	MethodPromptsList            = "prompts/list"
 	MethodPromptsGet             = "prompts/get"
+	MethodPromptsListChanged     = "notifications/prompts/list_changed"
 	MethodPing                   = "ping"
 	MethodCancelled              = "notifications/cancelled"
 	MethodProgress               = "notifications/progress"
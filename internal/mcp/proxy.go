@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+
+	"github.com/dirathea/sstart/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	// NamespaceSeparator is used to prefix primitive names with server ID
 	NamespaceSeparator = "/"
+
+	// proxyInstructions opens the instructions the proxy returns at initialization
+	proxyInstructions = "sstart MCP proxy - aggregates multiple MCP servers with secret injection"
 )
 
 // Proxy implements the MCP proxy that aggregates multiple downstream servers
@@ -25,6 +33,14 @@ type Proxy struct {
 	// Proxy info
 	proxyInfo Implementation
 
+	// Removes the handler of server notifications
+	removeNotificationHandler func()
+
+	// Requests being handled, and whether new ones are refused (see Drain)
+	inflight sync.WaitGroup
+	draining bool
+	drainMu  sync.Mutex
+
 	// Client info (received during initialization)
 	clientInfo         *Implementation
 	clientCapabilities *ClientCapabilities
@@ -40,7 +56,7 @@ type Proxy struct {
 
 // NewProxy creates a new MCP proxy
 func NewProxy(manager *ServerManager, transport Transport, version string) *Proxy {
-	return &Proxy{
+	p := &Proxy{
 		manager:   manager,
 		transport: transport,
 		proxyInfo: Implementation{
@@ -48,20 +64,31 @@ func NewProxy(manager *ServerManager, transport Transport, version string) *Prox
 			Version: version,
 		},
 	}
+	p.removeNotificationHandler = manager.OnNotification(p.handleServerNotification)
+	return p
 }
 
-// Run starts the proxy and processes messages until the context is cancelled or EOF
+// Run starts the proxy and processes messages until the context is cancelled or EOF. Once ctx
+// is cancelled, new requests are refused; the request being handled and the downstream servers
+// keep running until Shutdown or Stop
 func (p *Proxy) Run(ctx context.Context) error {
-	p.ctx, p.cancel = context.WithCancel(ctx)
-	defer p.cancel()
+	p.ctx, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
 
-	for {
-		select {
-		case <-p.ctx.Done():
-			return p.ctx.Err()
-		default:
-		}
+	done := make(chan error, 1)
+	go func() { done <- p.serve() }()
+
+	select {
+	case <-ctx.Done():
+		p.refuseRequests()
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
 
+// serve reads and processes the messages of the client until EOF
+func (p *Proxy) serve() error {
+	for {
 		msg, err := p.transport.ReadMessage()
 		if err != nil {
 			if err == io.EOF {
@@ -70,35 +97,106 @@ func (p *Proxy) Run(ctx context.Context) error {
 			return fmt.Errorf("failed to read message: %w", err)
 		}
 
-		resp, err := p.handleMessage(msg)
-		if err != nil {
-			// Log error but continue
-			fmt.Fprintf(os.Stderr, "Error handling message: %v\n", err)
-			if msg.ID != nil {
-				errResp, _ := NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
-				p.transport.WriteMessage(errResp)
-			}
-			continue
-		}
-
-		if resp != nil {
+		p.process(p.ctx, msg, func(resp *JSONRPCMessage) {
 			if err := p.transport.WriteMessage(resp); err != nil {
 				fmt.Fprintf(os.Stderr, "Error writing response: %v\n", err)
 			}
+		})
+	}
+}
+
+// process handles a message of the client and passes the response, if any, to reply. While
+// the proxy drains, requests are answered with an error instead
+func (p *Proxy) process(ctx context.Context, msg *JSONRPCMessage, reply func(*JSONRPCMessage)) {
+	p.drainMu.Lock()
+	if p.draining {
+		p.drainMu.Unlock()
+		if msg.ID != nil {
+			resp, _ := NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, errShuttingDown.Error(), nil)
+			reply(resp)
+		}
+		return
+	}
+	p.inflight.Add(1)
+	p.drainMu.Unlock()
+	defer p.inflight.Done()
+
+	if resp := p.handle(ctx, msg); resp != nil {
+		reply(resp)
+	}
+}
+
+// refuseRequests makes the proxy answer new requests with an error
+func (p *Proxy) refuseRequests() {
+	p.drainMu.Lock()
+	defer p.drainMu.Unlock()
+	p.draining = true
+}
+
+// Drain refuses new requests and waits for the requests being handled to be answered, until
+// ctx is done
+func (p *Proxy) Drain(ctx context.Context) error {
+	p.refuseRequests()
+
+	done := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("requests still in flight: %w", ctx.Err())
+	}
+}
+
+// handle handles a message of the client in a span, and returns the response to send, if any
+func (p *Proxy) handle(ctx context.Context, msg *JSONRPCMessage) *JSONRPCMessage {
+	ctx, span := telemetry.Tracer().Start(ctx, "mcp "+msg.Method, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("rpc.method", msg.Method)))
+	resp, err := p.handleMessage(ctx, msg)
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		// Log error but continue
+		fmt.Fprintf(os.Stderr, "Error handling message: %v\n", err)
+		if msg.ID != nil {
+			resp, _ = NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
 		}
 	}
+	return resp
 }
 
-// Stop stops the proxy and all downstream servers
+// Stop stops the proxy and kills all downstream servers
 func (p *Proxy) Stop() error {
+	p.close()
+	return p.manager.StopAll()
+}
+
+// Shutdown drains the proxy (see Drain), then stops the downstream servers gracefully (see
+// Server.Shutdown) and the proxy. The servers are stopped even if draining times out
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	drainErr := p.Drain(ctx)
+	stopErr := p.manager.ShutdownAll()
+	p.close()
+	if drainErr != nil {
+		return drainErr
+	}
+	return stopErr
+}
+
+// close stops the proxy, leaving the downstream servers running for other clients
+func (p *Proxy) close() {
 	if p.cancel != nil {
 		p.cancel()
 	}
-	return p.manager.StopAll()
+	p.removeNotificationHandler()
+	p.transport.Close()
 }
 
 // handleMessage routes and handles an incoming JSON-RPC message
-func (p *Proxy) handleMessage(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+// ctx carries the request span and is used for requests forwarded downstream
+func (p *Proxy) handleMessage(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	switch msg.Method {
 	case MethodInitialize:
 		return p.handleInitialize(msg)
@@ -110,17 +208,17 @@ func (p *Proxy) handleMessage(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	case MethodToolsList:
 		return p.handleToolsList(msg)
 	case MethodToolsCall:
-		return p.handleToolsCall(msg)
+		return p.handleToolsCall(ctx, msg)
 	case MethodResourcesList:
 		return p.handleResourcesList(msg)
 	case MethodResourcesRead:
-		return p.handleResourcesRead(msg)
+		return p.handleResourcesRead(ctx, msg)
 	case MethodResourcesTemplatesList:
 		return p.handleResourcesTemplatesList(msg)
 	case MethodPromptsList:
 		return p.handlePromptsList(msg)
 	case MethodPromptsGet:
-		return p.handlePromptsGet(msg)
+		return p.handlePromptsGet(ctx, msg)
 	default:
 		if msg.ID != nil {
 			return NewJSONRPCErrorResponse(msg.ID.Value(), MethodNotFound, fmt.Sprintf("method not found: %s", msg.Method), nil)
@@ -139,29 +237,21 @@ func (p *Proxy) handleInitialize(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	p.clientInfo = &params.ClientInfo
 	p.clientCapabilities = &params.Capabilities
 
-	// Start all downstream servers lazily or eagerly based on config
-	// For now, we'll start them lazily when first accessed
-
-	// Return our aggregated capabilities
+	// Return our aggregated capabilities, and the instructions of the downstream servers
 	result := InitializeResult{
 		ProtocolVersion: MCPProtocolVersion,
 		Capabilities: &ServerCapabilities{
 			Tools:     &ToolCapabilities{ListChanged: false},
 			Resources: &ResourceCapabilities{Subscribe: false, ListChanged: false},
-			Prompts:   &PromptCapabilities{ListChanged: false},
+			Prompts:   &PromptCapabilities{ListChanged: true},
 		},
 		ServerInfo:   &p.proxyInfo,
-		Instructions: "sstart MCP proxy - aggregates multiple MCP servers with secret injection",
+		Instructions: p.aggregatedInstructions(),
 	}
 
 	return NewJSONRPCResponse(msg.ID.Value(), result)
 }
 
-// handlePing handles the ping request
-func (p *Proxy) handlePing(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
-	return NewJSONRPCResponse(msg.ID.Value(), struct{}{})
-}
-
 // handleToolsList aggregates tools from all downstream servers
 func (p *Proxy) handleToolsList(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	tools, err := p.getAggregatedTools()
@@ -177,11 +267,14 @@ func (p *Proxy) handleToolsList(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 }
 
 // handleToolsCall routes a tool call to the appropriate downstream server
-func (p *Proxy) handleToolsCall(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+func (p *Proxy) handleToolsCall(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	var params ToolCallParams
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, "invalid tool call params", nil)
 	}
+	if params.Name == HealthToolName {
+		return p.handleHealthTool(ctx, msg)
+	}
 
 	// Parse server ID from tool name (format: serverID/toolName)
 	serverID, toolName, err := p.parseNamespacedName(params.Name)
@@ -189,6 +282,8 @@ func (p *Proxy) handleToolsCall(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, err.Error(), nil)
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("mcp.server.id", serverID))
+
 	// Get or start the server
 	server, err := p.manager.GetOrStartServer(p.ctx, serverID)
 	if err != nil {
@@ -212,7 +307,7 @@ func (p *Proxy) handleToolsCall(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	}
 
 	// Forward to downstream server
-	resp, err := server.ForwardRequest(p.ctx, forwardMsg)
+	resp, err := server.ForwardRequest(ctx, forwardMsg)
 	if err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
 	}
@@ -235,7 +330,7 @@ func (p *Proxy) handleResourcesList(msg *JSONRPCMessage) (*JSONRPCMessage, error
 }
 
 // handleResourcesRead routes a resource read to the appropriate downstream server
-func (p *Proxy) handleResourcesRead(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+func (p *Proxy) handleResourcesRead(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	var params ResourcesReadParams
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, "invalid resource read params", nil)
@@ -247,6 +342,8 @@ func (p *Proxy) handleResourcesRead(msg *JSONRPCMessage) (*JSONRPCMessage, error
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, err.Error(), nil)
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("mcp.server.id", serverID))
+
 	// Get or start the server
 	server, err := p.manager.GetOrStartServer(p.ctx, serverID)
 	if err != nil {
@@ -269,7 +366,7 @@ func (p *Proxy) handleResourcesRead(msg *JSONRPCMessage) (*JSONRPCMessage, error
 	}
 
 	// Forward to downstream server
-	resp, err := server.ForwardRequest(p.ctx, forwardMsg)
+	resp, err := server.ForwardRequest(ctx, forwardMsg)
 	if err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
 	}
@@ -306,7 +403,7 @@ func (p *Proxy) handlePromptsList(msg *JSONRPCMessage) (*JSONRPCMessage, error)
 }
 
 // handlePromptsGet routes a prompt get to the appropriate downstream server
-func (p *Proxy) handlePromptsGet(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+func (p *Proxy) handlePromptsGet(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	var params PromptsGetParams
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, "invalid prompt get params", nil)
@@ -318,6 +415,8 @@ func (p *Proxy) handlePromptsGet(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InvalidParams, err.Error(), nil)
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("mcp.server.id", serverID))
+
 	// Get or start the server
 	server, err := p.manager.GetOrStartServer(p.ctx, serverID)
 	if err != nil {
@@ -341,7 +440,7 @@ func (p *Proxy) handlePromptsGet(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	}
 
 	// Forward to downstream server
-	resp, err := server.ForwardRequest(p.ctx, forwardMsg)
+	resp, err := server.ForwardRequest(ctx, forwardMsg)
 	if err != nil {
 		return NewJSONRPCErrorResponse(msg.ID.Value(), InternalError, err.Error(), nil)
 	}
@@ -349,6 +448,22 @@ func (p *Proxy) handlePromptsGet(msg *JSONRPCMessage) (*JSONRPCMessage, error) {
 	return resp, nil
 }
 
+// handleServerNotification forwards notifications of downstream servers that concern the
+// aggregated primitives to the client
+func (p *Proxy) handleServerNotification(serverID string, msg *JSONRPCMessage) {
+	switch msg.Method {
+	case MethodPromptsListChanged:
+		// The client lists prompts again, which are aggregated anew from all servers
+		notification, err := NewJSONRPCNotification(MethodPromptsListChanged, nil)
+		if err != nil {
+			return
+		}
+		if err := p.transport.WriteMessage(notification); err != nil {
+			fmt.Fprintf(os.Stderr, "Error forwarding prompts list change of server '%s': %v\n", serverID, err)
+		}
+	}
+}
+
 // parseNamespacedName parses a namespaced name (serverID/name) into its components
 func (p *Proxy) parseNamespacedName(namespacedName string) (serverID, name string, err error) {
 	idx := strings.Index(namespacedName, NamespaceSeparator)
@@ -376,10 +491,6 @@ func (p *Proxy) namespaceName(serverID, name string) string {
 
 // ensureServerInitialized ensures the server is started and initialized
 func (p *Proxy) ensureServerInitialized(server *Server) error {
-	if server.Capabilities() != nil {
-		return nil // Already initialized
-	}
-
 	clientInfo := Implementation{
 		Name:    "sstart-mcp-proxy",
 		Version: "0.1.0",
@@ -391,7 +502,37 @@ func (p *Proxy) ensureServerInitialized(server *Server) error {
 		clientCapabilities = *p.clientCapabilities
 	}
 
-	return server.Initialize(p.ctx, clientInfo, clientCapabilities)
+	return server.EnsureInitialized(p.ctx, clientInfo, clientCapabilities)
+}
+
+// aggregatedInstructions starts and initializes all servers, and returns the proxy's instructions
+// followed by those of each server under a header with its ID, telling the host how its
+// primitives are namespaced
+func (p *Proxy) aggregatedInstructions() string {
+	serverIDs := p.manager.Servers()
+	sort.Strings(serverIDs)
+
+	var b strings.Builder
+	b.WriteString(proxyInstructions)
+	for _, serverID := range serverIDs {
+		server, err := p.manager.GetOrStartServer(p.ctx, serverID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to start server '%s': %v\n", serverID, err)
+			continue
+		}
+		if err := p.ensureServerInitialized(server); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to initialize server '%s': %v\n", serverID, err)
+			continue
+		}
+
+		instructions := strings.TrimSpace(server.Instructions())
+		if instructions == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n## %s\n\nTools, resources and prompts of this server are prefixed with '%s'.\n\n%s",
+			serverID, p.namespaceName(serverID, ""), instructions)
+	}
+	return b.String()
 }
 
 // getAggregatedTools fetches and aggregates tools from all servers
@@ -399,7 +540,7 @@ func (p *Proxy) getAggregatedTools() ([]Tool, error) {
 	p.cacheMu.Lock()
 	defer p.cacheMu.Unlock()
 
-	var allTools []Tool
+	allTools := []Tool{healthTool()}
 
 	for _, serverID := range p.manager.Servers() {
 		server, err := p.manager.GetOrStartServer(p.ctx, serverID)
@@ -538,14 +679,11 @@ func (p *Proxy) getAggregatedPrompts() ([]Prompt, error) {
 			continue
 		}
 
-		// Namespace the prompts
+		// Namespace the prompts, passing the rest through so that clients see the title and
+		// the arguments with their descriptions and whether they are required
 		for _, prompt := range prompts {
-			namespacedPrompt := Prompt{
-				Name:        p.namespaceName(serverID, prompt.Name),
-				Description: prompt.Description,
-				Arguments:   prompt.Arguments,
-			}
-			allPrompts = append(allPrompts, namespacedPrompt)
+			prompt.Name = p.namespaceName(serverID, prompt.Name)
+			allPrompts = append(allPrompts, prompt)
 		}
 	}
 
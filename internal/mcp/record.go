@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directions of recorded messages
+const (
+	DirectionHostToProxy   = "host_to_proxy"
+	DirectionProxyToHost   = "proxy_to_host"
+	DirectionProxyToServer = "proxy_to_server"
+	DirectionServerToProxy = "server_to_proxy"
+)
+
+// RecordedMessage is a line of a recording
+type RecordedMessage struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"`
+	Server    string          `json:"server,omitempty"`  // Downstream server, for messages to and from servers
+	Session   string          `json:"session,omitempty"` // HTTP session of the host, with --listen
+	Message   json.RawMessage `json:"message"`
+}
+
+// Recorder writes the JSON-RPC messages passing through the proxy as JSON Lines, with the values
+// of secrets redacted, to debug protocol incompatibilities between hosts and servers. A nil
+// Recorder records nothing
+type Recorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets []string
+	failed  bool
+}
+
+// NewRecorder creates a recorder writing to w, redacting the values of secrets
+func NewRecorder(w io.Writer, secrets map[string]string) *Recorder {
+	r := &Recorder{w: w}
+	for _, value := range secrets {
+		if value != "" {
+			r.secrets = append(r.secrets, value)
+		}
+	}
+	return r
+}
+
+// Record writes a message. Recording must not break the proxy, so the first failure is only
+// reported on stderr
+func (r *Recorder) Record(direction, server, session string, msg *JSONRPCMessage) {
+	if r == nil || msg == nil {
+		return
+	}
+	line, err := r.encode(RecordedMessage{
+		Time:      time.Now().UTC(),
+		Direction: direction,
+		Server:    server,
+		Session:   session,
+	}, msg)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		_, err = r.w.Write(line)
+	}
+	if err != nil && !r.failed {
+		r.failed = true
+		fmt.Fprintf(os.Stderr, "Warning: failed to record MCP messages: %v\n", err)
+	}
+}
+
+// encode returns the recorded message as a line of JSON, with secrets redacted from the strings
+// of the message. Strings are redacted once decoded, so that escaped values are found too and
+// the message stays valid JSON
+func (r *Recorder) encode(record RecordedMessage, msg *JSONRPCMessage) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	if record.Message, err = json.Marshal(r.redact(value)); err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// redact replaces the values of secrets in the strings of a decoded JSON value
+func (r *Recorder) redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		for _, secret := range r.secrets {
+			v = strings.ReplaceAll(v, secret, strings.Repeat("*", len(secret)))
+		}
+		return v
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = r.redact(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = r.redact(element)
+		}
+	}
+	return value
+}
+
+// Transport returns a transport recording the messages read from and written to the host on t,
+// or t itself for a nil Recorder. session identifies the host's HTTP session, if any
+func (r *Recorder) Transport(t Transport, session string) Transport {
+	if r == nil {
+		return t
+	}
+	return &recordingTransport{Transport: t, recorder: r, session: session}
+}
+
+// recordingTransport records the messages of a host transport
+type recordingTransport struct {
+	Transport
+	recorder *Recorder
+	session  string
+}
+
+func (t *recordingTransport) ReadMessage() (*JSONRPCMessage, error) {
+	msg, err := t.Transport.ReadMessage()
+	if err == nil {
+		t.recorder.Record(DirectionHostToProxy, "", t.session, msg)
+	}
+	return msg, err
+}
+
+func (t *recordingTransport) WriteMessage(msg *JSONRPCMessage) error {
+	t.recorder.Record(DirectionProxyToHost, "", t.session, msg)
+	return t.Transport.WriteMessage(msg)
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_Record(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf, map[string]string{"PASSWORD": `p"a<ss`, "EMPTY": ""})
+
+	msg := &JSONRPCMessage{
+		JSONRPC: "2.0",
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"db/query","arguments":{"dsn":"postgres://u:p\"a<ss@db","limit":10}}`),
+	}
+	id := NewRequestID(7)
+	msg.ID = &id
+	recorder.Record(DirectionProxyToServer, "db", "", msg)
+
+	var record RecordedMessage
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a line of JSON, got %q: %v", buf.String(), err)
+	}
+	if record.Direction != DirectionProxyToServer || record.Server != "db" || record.Time.IsZero() {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	var recorded JSONRPCMessage
+	if err := json.Unmarshal(record.Message, &recorded); err != nil {
+		t.Fatalf("Expected the recorded message to be valid JSON-RPC: %v", err)
+	}
+	var params struct {
+		Arguments struct {
+			DSN   string `json:"dsn"`
+			Limit int    `json:"limit"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(recorded.Params, &params); err != nil {
+		t.Fatalf("Failed to parse params: %v", err)
+	}
+	if params.Arguments.DSN != "postgres://u:******@db" {
+		t.Errorf("Expected the escaped secret to be redacted, got %q", params.Arguments.DSN)
+	}
+	if params.Arguments.Limit != 10 || recorded.ID == nil || fmt.Sprint(recorded.ID.Value()) != "7" {
+		t.Errorf("Expected other values to be kept, got %+v and ID %v", params, recorded.ID)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") {
+		t.Errorf("Expected the record to end with a newline, got %q", buf.String())
+	}
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var recorder *Recorder
+	recorder.Record(DirectionHostToProxy, "", "", &JSONRPCMessage{JSONRPC: "2.0", Method: "ping"})
+
+	transport := NewStdioTransport(strings.NewReader(""), &bytes.Buffer{})
+	if recorder.Transport(transport, "") != Transport(transport) {
+		t.Error("Expected a nil recorder to return the transport as is")
+	}
+}
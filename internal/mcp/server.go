@@ -8,6 +8,10 @@ import (
 	"os/exec"
 	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/dirathea/sstart/internal/metrics"
 )
 
 // ServerConfig represents the configuration for a downstream MCP server
@@ -15,9 +19,16 @@ type ServerConfig struct {
 	ID      string   `yaml:"id"`
 	Command string   `yaml:"command"`
 	Args    []string `yaml:"args"`
-	// Future: Secrets []string `yaml:"secrets"` for selective injection
+	// Env holds additional environment variables, set over the injected secrets
+	Env map[string]string `yaml:"env"`
+	// Secrets lists the collected keys injected into the server, all of them if nil
+	Secrets []string `yaml:"secrets"`
 }
 
+// ServerStopTimeout is how long a server has to exit once its stdin is closed on shutdown, and
+// again after SIGTERM, before it is killed
+const ServerStopTimeout = 5 * time.Second
+
 // ServerState represents the current state of a server
 type ServerState int
 
@@ -29,6 +40,23 @@ const (
 	ServerStateError
 )
 
+// String returns the name of the state, as reported by the health tool
+func (s ServerState) String() string {
+	switch s {
+	case ServerStateStopped:
+		return "stopped"
+	case ServerStateStarting:
+		return "starting"
+	case ServerStateRunning:
+		return "running"
+	case ServerStateStopping:
+		return "stopping"
+	case ServerStateError:
+		return "error"
+	}
+	return "unknown"
+}
+
 // Server represents a downstream MCP server instance
 type Server struct {
 	config     ServerConfig
@@ -40,10 +68,19 @@ type Server struct {
 	secrets    map[string]string
 	inherit    bool
 	cancelFunc context.CancelFunc
+	started    bool
+	// restarts counts the starts after the first one
+	restarts atomic.Int32
+	// recorder records the messages sent to and read from the server
+	recorder *Recorder
+	// exited is closed when the process exits
+	exited chan struct{}
 
 	// Cached capabilities after initialization
+	initMu       sync.Mutex
 	capabilities *ServerCapabilities
 	serverInfo   *Implementation
+	instructions string
 
 	// Cached primitives (populated lazily)
 	tools             []Tool
@@ -53,6 +90,9 @@ type Server struct {
 	primitivesOnce    sync.Once
 	primitivesErr     error
 
+	// Called with notifications sent by the server
+	onNotification func(serverID string, msg *JSONRPCMessage)
+
 	// Request tracking for responses
 	pendingRequests   map[interface{}]chan *JSONRPCMessage
 	pendingRequestsMu sync.Mutex
@@ -98,6 +138,11 @@ func (s *Server) buildEnv() []string {
 		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
 
+	// Add the variables of the server config, which win over secrets of the same name
+	for key, value := range s.config.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
 	return env
 }
 
@@ -148,11 +193,17 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	s.state.Store(int32(ServerStateRunning))
+	metrics.ObserveMCPServerStart(s.config.ID, s.started)
+	if s.started {
+		s.restarts.Add(1)
+	}
+	s.started = true
 
 	// Start goroutine to read responses
 	go s.readResponses(serverCtx)
 
 	// Start goroutine to wait for process exit
+	s.exited = make(chan struct{})
 	go s.waitForExit()
 
 	return nil
@@ -168,6 +219,9 @@ func (s *Server) readResponses(ctx context.Context) {
 		}
 
 		msg, err := s.transport.ReadMessage()
+		if err == nil {
+			s.recorder.Record(DirectionServerToProxy, s.config.ID, "", msg)
+		}
 		if err != nil {
 			// Check if we're shutting down
 			if s.State() != ServerStateRunning {
@@ -188,9 +242,10 @@ func (s *Server) readResponses(ctx context.Context) {
 				delete(s.pendingRequests, normalizedID)
 			}
 			s.pendingRequestsMu.Unlock()
+		} else if msg.IsNotification() && s.onNotification != nil {
+			s.onNotification(s.config.ID, msg)
 		}
-		// Note: Server-initiated requests/notifications are not handled in this POC
-		// They would need to be forwarded to the proxy for handling
+		// Note: Server-initiated requests are not handled in this POC
 	}
 }
 
@@ -213,9 +268,20 @@ func normalizeID(id interface{}) interface{} {
 
 // waitForExit waits for the server process to exit
 func (s *Server) waitForExit() {
-	if s.cmd != nil && s.cmd.Process != nil {
-		s.cmd.Wait()
-		s.state.Store(int32(ServerStateStopped))
+	s.cmd.Wait()
+	s.state.Store(int32(ServerStateStopped))
+	close(s.exited)
+}
+
+// waitExited reports whether the server process exits within timeout
+func (s *Server) waitExited(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.exited:
+		return true
+	case <-timer.C:
+		return false
 	}
 }
 
@@ -237,10 +303,36 @@ func (s *Server) Stop() error {
 		s.transport.Close()
 	}
 
-	// Wait for process to exit (with timeout handled by context)
-	if s.cmd != nil && s.cmd.Process != nil {
-		s.cmd.Process.Kill()
-		s.cmd.Wait()
+	// Kill the process and wait for it to exit
+	s.cmd.Process.Kill()
+	<-s.exited
+
+	s.state.Store(int32(ServerStateStopped))
+	return nil
+}
+
+// Shutdown stops the downstream MCP server gracefully, as the MCP stdio transport specifies: its
+// stdin is closed so that it can exit on its own, then it gets SIGTERM, and it is killed if it
+// still runs after that. Each step waits up to ServerStopTimeout
+func (s *Server) Shutdown() error {
+	if s.State() != ServerStateRunning {
+		return nil
+	}
+
+	s.state.Store(int32(ServerStateStopping))
+
+	_ = s.transport.stdin.Close()
+	if !s.waitExited(ServerStopTimeout) {
+		_ = s.cmd.Process.Signal(syscall.SIGTERM)
+		if !s.waitExited(ServerStopTimeout) {
+			_ = s.cmd.Process.Kill()
+			<-s.exited
+		}
+	}
+
+	s.transport.Close()
+	if s.cancelFunc != nil {
+		s.cancelFunc()
 	}
 
 	s.state.Store(int32(ServerStateStopped))
@@ -277,7 +369,7 @@ func (s *Server) SendRequest(ctx context.Context, method string, params interfac
 	}()
 
 	// Send request
-	if err := s.transport.WriteMessage(req); err != nil {
+	if err := s.send(req); err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
@@ -290,6 +382,12 @@ func (s *Server) SendRequest(ctx context.Context, method string, params interfac
 	}
 }
 
+// send writes a message to the server
+func (s *Server) send(msg *JSONRPCMessage) error {
+	s.recorder.Record(DirectionProxyToServer, s.config.ID, "", msg)
+	return s.transport.WriteMessage(msg)
+}
+
 // SendNotification sends a JSON-RPC notification to the server (no response expected)
 func (s *Server) SendNotification(method string, params interface{}) error {
 	if s.State() != ServerStateRunning {
@@ -301,38 +399,52 @@ func (s *Server) SendNotification(method string, params interface{}) error {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
-	return s.transport.WriteMessage(notification)
+	return s.send(notification)
 }
 
 // ForwardRequest forwards a raw JSON-RPC message to the server and waits for a response
-func (s *Server) ForwardRequest(ctx context.Context, msg *JSONRPCMessage) (*JSONRPCMessage, error) {
+func (s *Server) ForwardRequest(ctx context.Context, msg *JSONRPCMessage) (resp *JSONRPCMessage, err error) {
 	if s.State() != ServerStateRunning {
 		return nil, fmt.Errorf("server %s is not running", s.config.ID)
 	}
 
 	if msg.ID == nil {
 		// This is a notification, just forward it
-		return nil, s.transport.WriteMessage(msg)
+		return nil, s.send(msg)
 	}
 
-	// Normalize the ID for consistent map key comparison
-	normalizedID := normalizeID(msg.ID.Value())
+	// Clients pick their request IDs independently, so the request is sent with an ID of its own
+	// and the response is returned with the ID of the client
+	id := s.nextRequestID.Add(1)
+	forwardID := NewRequestID(id)
+	forwarded := *msg
+	forwarded.ID = &forwardID
 
 	// Create response channel
 	respCh := make(chan *JSONRPCMessage, 1)
 
 	s.pendingRequestsMu.Lock()
-	s.pendingRequests[normalizedID] = respCh
+	s.pendingRequests[id] = respCh
 	s.pendingRequestsMu.Unlock()
 
 	defer func() {
 		s.pendingRequestsMu.Lock()
-		delete(s.pendingRequests, normalizedID)
+		delete(s.pendingRequests, id)
 		s.pendingRequestsMu.Unlock()
 	}()
 
+	start := time.Now()
+	defer func() {
+		// JSON-RPC errors from the downstream server count as failed requests too
+		if err == nil && resp != nil && resp.Error != nil {
+			metrics.ObserveMCPRequest(s.config.ID, msg.Method, time.Since(start), fmt.Errorf("%s", resp.Error.Message))
+			return
+		}
+		metrics.ObserveMCPRequest(s.config.ID, msg.Method, time.Since(start), err)
+	}()
+
 	// Send the message
-	if err := s.transport.WriteMessage(msg); err != nil {
+	if err := s.send(&forwarded); err != nil {
 		return nil, fmt.Errorf("failed to forward request: %w", err)
 	}
 
@@ -341,6 +453,7 @@ func (s *Server) ForwardRequest(ctx context.Context, msg *JSONRPCMessage) (*JSON
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case resp := <-respCh:
+		resp.ID = msg.ID
 		return resp, nil
 	}
 }
@@ -369,6 +482,7 @@ func (s *Server) Initialize(ctx context.Context, clientInfo Implementation, clie
 
 	s.capabilities = result.Capabilities
 	s.serverInfo = result.ServerInfo
+	s.instructions = result.Instructions
 
 	// Send initialized notification
 	if err := s.SendNotification(MethodInitialized, nil); err != nil {
@@ -378,6 +492,18 @@ func (s *Server) Initialize(ctx context.Context, clientInfo Implementation, clie
 	return nil
 }
 
+// EnsureInitialized initializes the server unless it already is. Clients of the proxy share
+// servers, so only the first of them to use a server initializes it
+func (s *Server) EnsureInitialized(ctx context.Context, clientInfo Implementation, clientCapabilities ClientCapabilities) error {
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
+
+	if s.capabilities != nil {
+		return nil // Already initialized
+	}
+	return s.Initialize(ctx, clientInfo, clientCapabilities)
+}
+
 // Capabilities returns the server's capabilities (available after initialization)
 func (s *Server) Capabilities() *ServerCapabilities {
 	return s.capabilities
@@ -388,6 +514,11 @@ func (s *Server) ServerInfo() *Implementation {
 	return s.serverInfo
 }
 
+// Instructions returns the usage guidance the server gave at initialization, if any
+func (s *Server) Instructions() string {
+	return s.instructions
+}
+
 // FetchTools fetches the list of tools from the server
 func (s *Server) FetchTools(ctx context.Context) ([]Tool, error) {
 	if s.capabilities == nil || s.capabilities.Tools == nil {
@@ -459,28 +590,35 @@ func (s *Server) FetchResourceTemplates(ctx context.Context) ([]ResourceTemplate
 	return result.ResourceTemplates, nil
 }
 
-// FetchPrompts fetches the list of prompts from the server
+// FetchPrompts fetches the list of prompts from the server, following pagination
 func (s *Server) FetchPrompts(ctx context.Context) ([]Prompt, error) {
 	if s.capabilities == nil || s.capabilities.Prompts == nil {
 		return nil, nil // Server doesn't support prompts
 	}
 
-	resp, err := s.SendRequest(ctx, MethodPromptsList, &PaginatedRequest{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch prompts: %w", err)
-	}
+	var prompts []Prompt
+	params := &PaginatedRequest{}
+	for {
+		resp, err := s.SendRequest(ctx, MethodPromptsList, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch prompts: %w", err)
+		}
 
-	if resp.Error != nil {
-		return nil, fmt.Errorf("prompts/list failed: %s", resp.Error.Message)
-	}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("prompts/list failed: %s", resp.Error.Message)
+		}
 
-	var result PromptsListResult
-	if err := json.Unmarshal(resp.Result, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal prompts list: %w", err)
-	}
+		var result PromptsListResult
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal prompts list: %w", err)
+		}
 
-	// TODO: Handle pagination with NextCursor
-	return result.Prompts, nil
+		prompts = append(prompts, result.Prompts...)
+		if result.NextCursor == nil || *result.NextCursor == "" {
+			return prompts, nil
+		}
+		params = &PaginatedRequest{Cursor: result.NextCursor}
+	}
 }
 
 // ServerManager manages multiple downstream MCP servers
@@ -489,20 +627,59 @@ type ServerManager struct {
 	secrets map[string]string
 	inherit bool
 	mu      sync.RWMutex
+
+	// Handlers of server notifications, by registration
+	handlers    map[int]func(serverID string, msg *JSONRPCMessage)
+	nextHandler int
+	handlersMu  sync.RWMutex
+
+	// recorder records the messages of the servers and of the hosts of proxies (see WithRecorder)
+	recorder *Recorder
+}
+
+// ManagerOption configures a ServerManager
+type ManagerOption func(*ServerManager)
+
+// WithRecorder returns an option that records the messages exchanged with the servers, and with
+// the hosts of the HTTP sessions served with the manager
+func WithRecorder(recorder *Recorder) ManagerOption {
+	return func(m *ServerManager) {
+		m.recorder = recorder
+	}
 }
 
 // NewServerManager creates a new server manager
-func NewServerManager(configs []ServerConfig, secrets map[string]string, inherit bool) *ServerManager {
-	servers := make(map[string]*Server)
+func NewServerManager(configs []ServerConfig, secrets map[string]string, inherit bool, opts ...ManagerOption) *ServerManager {
+	m := &ServerManager{
+		servers:  make(map[string]*Server),
+		secrets:  secrets,
+		inherit:  inherit,
+		handlers: make(map[int]func(serverID string, msg *JSONRPCMessage)),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
 	for _, cfg := range configs {
-		servers[cfg.ID] = NewServer(cfg, secrets, inherit)
+		server := NewServer(cfg, selectSecrets(secrets, cfg.Secrets), inherit)
+		server.onNotification = m.notify
+		server.recorder = m.recorder
+		m.servers[cfg.ID] = server
 	}
+	return m
+}
 
-	return &ServerManager{
-		servers: servers,
-		secrets: secrets,
-		inherit: inherit,
+// selectSecrets returns the secrets with the given keys, or all secrets if keys is nil
+func selectSecrets(secrets map[string]string, keys []string) map[string]string {
+	if keys == nil {
+		return secrets
+	}
+	selected := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := secrets[key]; ok {
+			selected[key] = value
+		}
 	}
+	return selected
 }
 
 // GetServer returns a server by ID (does not start it)
@@ -535,6 +712,32 @@ func (m *ServerManager) GetOrStartServer(ctx context.Context, id string) (*Serve
 	return server, nil
 }
 
+// OnNotification adds a handler called with the notifications servers send, such as
+// notifications/prompts/list_changed, and returns a function that removes it
+func (m *ServerManager) OnNotification(handler func(serverID string, msg *JSONRPCMessage)) (remove func()) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+
+	id := m.nextHandler
+	m.nextHandler++
+	m.handlers[id] = handler
+	return func() {
+		m.handlersMu.Lock()
+		defer m.handlersMu.Unlock()
+		delete(m.handlers, id)
+	}
+}
+
+// notify calls every notification handler with a notification of a server
+func (m *ServerManager) notify(serverID string, msg *JSONRPCMessage) {
+	m.handlersMu.RLock()
+	defer m.handlersMu.RUnlock()
+
+	for _, handler := range m.handlers {
+		handler(serverID, msg)
+	}
+}
+
 // StartAll starts all configured servers
 func (m *ServerManager) StartAll(ctx context.Context) error {
 	m.mu.RLock()
@@ -571,6 +774,33 @@ func (m *ServerManager) StopAll() error {
 	return nil
 }
 
+// ShutdownAll stops all running servers gracefully, in parallel (see Server.Shutdown)
+func (m *ServerManager) ShutdownAll() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+	for id, server := range m.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(); err != nil {
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("failed to stop server '%s': %w", id, err))
+				errsMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("errors stopping servers: %v", errs)
+	}
+	return nil
+}
+
 // Servers returns a list of all server IDs
 func (m *ServerManager) Servers() []string {
 	m.mu.RLock()
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Result label values
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	providerFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sstart_provider_fetches_total",
+		Help: "Secret fetches from provider backends, by provider and result.",
+	}, []string{"provider", "kind", "result"})
+
+	providerFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sstart_provider_fetch_duration_seconds",
+		Help:    "Duration of secret fetches from provider backends.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider", "kind"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sstart_cache_lookups_total",
+		Help: "Secret cache lookups, by provider and result (hit or miss).",
+	}, []string{"provider", "result"})
+
+	mcpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sstart_mcp_requests_total",
+		Help: "Requests forwarded to downstream MCP servers, by server, method and result.",
+	}, []string{"server", "method", "result"})
+
+	mcpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sstart_mcp_request_duration_seconds",
+		Help:    "Latency of requests forwarded to downstream MCP servers.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"server", "method"})
+
+	mcpServerStarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sstart_mcp_server_starts_total",
+		Help: "Downstream MCP server process starts.",
+	}, []string{"server"})
+
+	mcpServerRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sstart_mcp_server_restarts_total",
+		Help: "Downstream MCP server processes started again after exiting.",
+	}, []string{"server"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		providerFetches,
+		providerFetchDuration,
+		cacheLookups,
+		mcpRequests,
+		mcpRequestDuration,
+		mcpServerStarts,
+		mcpServerRestarts,
+	)
+}
+
+// result returns the result label value for err
+func result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// ObserveProviderFetch records a fetch from a provider backend
+func ObserveProviderFetch(providerID, kind string, duration time.Duration, err error) {
+	providerFetches.WithLabelValues(providerID, kind, result(err)).Inc()
+	providerFetchDuration.WithLabelValues(providerID, kind).Observe(duration.Seconds())
+}
+
+// ObserveCacheLookup records a secret cache lookup for a provider
+func ObserveCacheLookup(providerID string, hit bool) {
+	value := "miss"
+	if hit {
+		value = "hit"
+	}
+	cacheLookups.WithLabelValues(providerID, value).Inc()
+}
+
+// ObserveMCPRequest records a request forwarded to a downstream MCP server
+func ObserveMCPRequest(serverID, method string, duration time.Duration, err error) {
+	mcpRequests.WithLabelValues(serverID, method, result(err)).Inc()
+	mcpRequestDuration.WithLabelValues(serverID, method).Observe(duration.Seconds())
+}
+
+// ObserveMCPServerStart records a downstream MCP server start; restart is true when it had exited before
+func ObserveMCPServerStart(serverID string, restart bool) {
+	mcpServerStarts.WithLabelValues(serverID).Inc()
+	if restart {
+		mcpServerRestarts.WithLabelValues(serverID).Inc()
+	}
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Serve starts serving /metrics on addr in the background until ctx is cancelled
+// The listener is opened before Serve returns, so a bad address fails immediately
+func Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "metrics server stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package notify sends notifications when long-running sstart modes fail to collect secrets
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+)
+
+// Types of events
+const (
+	// EventCollectionFailed is sent when collecting secrets fails
+	EventCollectionFailed = "collection_failed"
+	// EventMissingKeys is sent when a collection lacks required keys
+	EventMissingKeys = "missing_keys"
+)
+
+// sendTimeout bounds how long sending a notification to one target may take
+const sendTimeout = 10 * time.Second
+
+// Event describes a problem with the collected secrets
+type Event struct {
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	Keys    []string  `json:"keys,omitempty"`
+	Config  string    `json:"config,omitempty"`
+	Profile string    `json:"profile,omitempty"`
+	Host    string    `json:"host,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Notifier sends the notifications configured in a config file. A problem is only reported
+// once until a collection succeeds again, so polling does not repeat it
+type Notifier struct {
+	cfg     *config.NotificationsConfig
+	config  string
+	profile string
+	logger  *slog.Logger
+	client  *http.Client
+
+	mu   sync.Mutex
+	last string
+}
+
+// New returns a notifier for the notifications of cfg, or nil if none are configured
+func New(cfg *config.Config, logger *slog.Logger) *Notifier {
+	if cfg.Notifications == nil {
+		return nil
+	}
+	return &Notifier{
+		cfg:     cfg.Notifications,
+		config:  cfg.Path,
+		profile: cfg.Profile,
+		logger:  logger,
+		client:  &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Check notifies when collecting failed or the collected secrets lack required keys
+func (n *Notifier) Check(ctx context.Context, collected map[string]string, err error) {
+	var event *Event
+	if err != nil {
+		event = &Event{Type: EventCollectionFailed, Message: fmt.Sprintf("failed to collect secrets: %v", err)}
+	} else if missing := n.missingKeys(collected); len(missing) > 0 {
+		event = &Event{Type: EventMissingKeys, Message: "required secrets are missing: " + strings.Join(missing, ", "), Keys: missing}
+	}
+
+	n.mu.Lock()
+	if event == nil {
+		n.last = ""
+		n.mu.Unlock()
+		return
+	}
+	if n.last == event.Message {
+		n.mu.Unlock()
+		return
+	}
+	n.last = event.Message
+	n.mu.Unlock()
+
+	event.Config = n.config
+	event.Profile = n.profile
+	event.Host, _ = os.Hostname()
+	event.Time = time.Now().UTC()
+	n.Send(ctx, event)
+}
+
+// missingKeys returns the required key patterns that no collected key matches
+func (n *Notifier) missingKeys(collected map[string]string) []string {
+	var missing []string
+	for _, pattern := range n.cfg.RequiredKeys {
+		found := false
+		for key := range collected {
+			if config.MatchesAny([]string{pattern}, key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, pattern)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Send sends event to every target; failures are logged as warnings
+func (n *Notifier) Send(ctx context.Context, event *Event) {
+	// Notifications are also sent while shutting down after a failure
+	ctx = context.WithoutCancel(ctx)
+	for _, target := range n.cfg.Targets {
+		var err error
+		switch target.Kind {
+		case config.NotifySlack:
+			err = n.post(ctx, target, map[string]string{"text": ":warning: " + summary(event)})
+		case config.NotifyWebhook:
+			err = n.post(ctx, target, event)
+		case config.NotifyDesktop:
+			err = desktopNotify("sstart", summary(event))
+		}
+		if err != nil {
+			n.logger.Warn("failed to send notification", "kind", target.Kind, "error", err)
+		}
+	}
+}
+
+// summary describes event in one line for chat and desktop notifications
+func summary(event *Event) string {
+	where := event.Host
+	if event.Config != "" {
+		where += " " + event.Config
+	}
+	if event.Profile != "" {
+		where += " (profile " + event.Profile + ")"
+	}
+	return fmt.Sprintf("sstart on %s: %s", strings.TrimSpace(where), event.Message)
+}
+
+// post sends body as JSON to the URL of target
+func (n *Notifier) post(ctx context.Context, target config.NotificationTarget, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(target.URL), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range target.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// DefaultCallbackHost is the default loopback host used in the redirect URI
+const DefaultCallbackHost = "localhost"
+
+// callbackEndpoint describes a loopback address the callback server can listen on
+type callbackEndpoint struct {
+	Host string
+	Port int
+	Path string
+}
+
+// RedirectURI returns the redirect URI registered with the identity provider for this endpoint
+func (e callbackEndpoint) RedirectURI() string {
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), e.Path)
+}
+
+// LoginURL returns the local URL that starts the authentication flow
+func (e callbackEndpoint) LoginURL() string {
+	return fmt.Sprintf("http://%s/login", net.JoinHostPort(e.Host, strconv.Itoa(e.Port)))
+}
+
+// ListenAddr returns the address the callback server binds to
+// "localhost" is bound on the IPv4 loopback so the server is never exposed on other interfaces
+func (e callbackEndpoint) ListenAddr() string {
+	host := e.Host
+	if host == "localhost" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(e.Port))
+}
+
+// callbackEndpoints returns the loopback endpoints to try, in order
+// The port from redirectUri (if any) is tried first, followed by callbackPorts
+func (c *Client) callbackEndpoints() ([]callbackEndpoint, error) {
+	host := DefaultCallbackHost
+	if c.config.CallbackHost != "" {
+		host = c.config.CallbackHost
+	}
+	path := DefaultCallbackPath
+	var ports []int
+
+	if c.config.RedirectURI != "" {
+		u, err := url.Parse(c.config.RedirectURI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirectUri: %w", err)
+		}
+		if u.Scheme != "http" {
+			return nil, fmt.Errorf("invalid redirectUri %q: only http loopback redirect URIs are supported", c.config.RedirectURI)
+		}
+		host = u.Hostname()
+		if u.Path != "" {
+			path = u.Path
+		}
+		if u.Port() != "" {
+			port, err := strconv.Atoi(u.Port())
+			if err != nil {
+				return nil, fmt.Errorf("invalid redirectUri port: %w", err)
+			}
+			ports = append(ports, port)
+		}
+	}
+
+	if !isLoopbackHost(host) {
+		return nil, fmt.Errorf("callback host %q is not a loopback address (use localhost, 127.0.0.1 or ::1)", host)
+	}
+
+	for _, port := range c.config.CallbackPorts {
+		if !containsPort(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		ports = []int{DefaultPort}
+	}
+
+	endpoints := make([]callbackEndpoint, 0, len(ports))
+	for _, port := range ports {
+		endpoints = append(endpoints, callbackEndpoint{Host: host, Port: port, Path: path})
+	}
+	return endpoints, nil
+}
+
+// listenCallback binds the first endpoint that is available, falling back to the next one on failure
+func listenCallback(endpoints []callbackEndpoint) (net.Listener, callbackEndpoint, error) {
+	var errs []error
+	for _, endpoint := range endpoints {
+		listener, err := net.Listen("tcp", endpoint.ListenAddr())
+		if err == nil {
+			return listener, endpoint, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, callbackEndpoint{}, fmt.Errorf("failed to start callback server on any configured port: %w", errors.Join(errs...))
+}
+
+// isLoopbackHost reports whether host refers to the local loopback interface
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseIDTokenClaims decodes the claims of an ID token without verifying its signature
+// The token is only read from sstart's own token storage, where it was verified at login
+func ParseIDTokenClaims(idToken string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid ID token: expected 3 parts, got %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token payload: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+// Identity returns an opaque identifier of the user of an ID token, hashed from its issuer and
+// subject, or an empty string if the token has no readable subject
+func Identity(idToken string) string {
+	claims, err := ParseIDTokenClaims(idToken)
+	if err != nil {
+		return ""
+	}
+	iss, _ := claims["iss"].(string)
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(iss + "\x00" + sub))
+	return hex.EncodeToString(sum[:])
+}
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dirathea/sstart/internal/config"
@@ -27,6 +29,13 @@ const (
 	DefaultCallbackPath = "/auth/sstart"
 	// DefaultTimeout is the default timeout for the authentication flow
 	DefaultTimeout = 5 * time.Minute
+	// DefaultRefreshSkew is how long before expiry tokens are refreshed proactively
+	DefaultRefreshSkew = time.Minute
+	// SessionWarningThreshold is how long before expiry sstart warns about the SSO session
+	// and tries to extend it silently
+	SessionWarningThreshold = 5 * time.Minute
+	// refreshRetryInterval is the delay between background refresh attempts after a failure
+	refreshRetryInterval = 30 * time.Second
 )
 
 // Client represents an OIDC client for SSO authentication
@@ -35,6 +44,14 @@ type Client struct {
 	provider  rp.RelyingParty
 	logger    *slog.Logger
 	tokenPath string
+	// Token storage settings
+	storageBackend string
+	keyringService string
+	encryptFile    bool
+	encryptionKey  string
+	memTokens      *Tokens
+	// refreshMu serializes refreshes so a rotated refresh token is never used twice
+	refreshMu sync.Mutex
 }
 
 // Tokens represents the OIDC tokens received after authentication
@@ -82,8 +99,30 @@ type tokenResponse struct {
 	Scope        string `json:"scope,omitempty"`
 }
 
+// ClientOption is a functional option for configuring the Client
+type ClientOption func(*Client)
+
+// WithTokenStorage returns an option that configures where tokens are stored
+func WithTokenStorage(storageCfg *config.TokenStorageConfig) ClientOption {
+	return func(c *Client) {
+		if storageCfg == nil {
+			return
+		}
+		if storageCfg.Backend != "" {
+			c.storageBackend = storageCfg.Backend
+		}
+		if storageCfg.KeyringService != "" {
+			c.keyringService = storageCfg.KeyringService
+		}
+		if storageCfg.Path != "" {
+			c.tokenPath = storageCfg.Path
+		}
+		c.encryptFile = storageCfg.Encrypt
+	}
+}
+
 // NewClient creates a new OIDC client from the provided configuration
-func NewClient(cfg *config.OIDCConfig) (*Client, error) {
+func NewClient(cfg *config.OIDCConfig, opts ...ClientOption) (*Client, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("OIDC configuration is required")
 	}
@@ -113,9 +152,31 @@ func NewClient(cfg *config.OIDCConfig) (*Client, error) {
 	)
 
 	client := &Client{
-		config:    cfg,
-		logger:    logger,
-		tokenPath: getDefaultTokenPath(),
+		config:         cfg,
+		logger:         logger,
+		tokenPath:      getDefaultTokenPath(),
+		storageBackend: config.TokenStorageAuto,
+		keyringService: KeyringService,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	// The environment overrides the configured backend, e.g. SSTART_TOKEN_STORAGE=memory on shared CI runners
+	if backend := os.Getenv(TokenStorageEnvVar); backend != "" {
+		client.storageBackend = backend
+	}
+	switch client.storageBackend {
+	case config.TokenStorageAuto, config.TokenStorageKeyring, config.TokenStorageFile, config.TokenStorageMemory:
+	default:
+		return nil, fmt.Errorf("unsupported token storage backend %q (supported: auto, keyring, file, memory)", client.storageBackend)
+	}
+
+	client.encryptionKey = os.Getenv(TokenEncryptionKeyEnvVar)
+	if client.encryptFile && client.encryptionKey == "" {
+		return nil, fmt.Errorf("token file encryption requires the %s environment variable", TokenEncryptionKeyEnvVar)
 	}
 
 	return client, nil
@@ -125,16 +186,17 @@ func NewClient(cfg *config.OIDCConfig) (*Client, error) {
 // It starts a local HTTP server to handle the callback, opens the browser for authentication,
 // and returns the tokens upon successful authentication
 func (c *Client) Login(ctx context.Context) (*AuthResult, error) {
-	port := DefaultPort
-	callbackPath := DefaultCallbackPath
-
-	// Parse redirect URI if provided
-	if c.config.RedirectURI != "" {
-		// Extract port from redirect URI if possible
-		// For now, we use the default port
+	endpoints, err := c.callbackEndpoints()
+	if err != nil {
+		return nil, err
 	}
 
-	redirectURI := fmt.Sprintf("http://localhost:%d%s", port, callbackPath)
+	// Bind the callback server first so the redirect URI matches the port we actually got
+	listener, endpoint, err := listenCallback(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	redirectURI := endpoint.RedirectURI()
 
 	// Create cookie handler for secure state management
 	key := []byte(uuid.New().String()[:16]) // Generate random key for this session
@@ -166,6 +228,7 @@ func (c *Client) Login(ctx context.Context) (*AuthResult, error) {
 	// Create the relying party (OIDC client)
 	provider, err := rp.NewRelyingPartyOIDC(ctx, c.config.Issuer, c.config.ClientID, c.config.ClientSecret, redirectURI, c.config.Scopes, options...)
 	if err != nil {
+		_ = listener.Close()
 		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
 	}
 
@@ -188,6 +251,9 @@ func (c *Client) Login(ctx context.Context) (*AuthResult, error) {
 	if c.config.ResponseMode != "" {
 		urlOptions = append(urlOptions, rp.WithResponseModeURLParam(oidc.ResponseMode(c.config.ResponseMode)))
 	}
+	for _, key := range sortedKeys(c.config.AuthParams) {
+		urlOptions = append(urlOptions, rp.WithURLParam(key, c.config.AuthParams[key]))
+	}
 
 	// Register login handler
 	mux.Handle("/login", rp.AuthURLHandler(state, provider, urlOptions...))
@@ -225,27 +291,24 @@ func (c *Client) Login(ctx context.Context) (*AuthResult, error) {
 	}
 
 	// Register callback handler
-	mux.Handle(callbackPath, rp.CodeExchangeHandler(rp.UserinfoCallback(marshalUserinfo), provider))
+	mux.Handle(endpoint.Path, rp.CodeExchangeHandler(rp.UserinfoCallback(marshalUserinfo), provider))
 
 	// Create the HTTP server
 	server := &http.Server{
-		Addr:    fmt.Sprintf("127.0.0.1:%d", port),
+		Addr:    listener.Addr().String(),
 		Handler: mux,
 	}
 
 	// Start the server in a goroutine
 	go func() {
 		c.logger.Info("starting authentication server", "addr", server.Addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			errorChan <- fmt.Errorf("failed to start callback server: %w", err)
 		}
 	}()
 
-	// Give the server a moment to start
-	time.Sleep(100 * time.Millisecond)
-
 	// Print login URL
-	loginURL := fmt.Sprintf("http://localhost:%d/login", port)
+	loginURL := endpoint.LoginURL()
 	fmt.Printf("\n🔐 Opening browser for authentication...\n")
 	fmt.Printf("   If the browser doesn't open, visit: %s\n\n", loginURL)
 
@@ -267,7 +330,10 @@ func (c *Client) Login(ctx context.Context) (*AuthResult, error) {
 		_ = server.Shutdown(ctx)
 		return nil, err
 	case <-timeoutCtx.Done():
-		_ = server.Shutdown(ctx)
+		_ = server.Shutdown(context.Background())
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("authentication cancelled: %w", context.Cause(ctx))
+		}
 		return nil, fmt.Errorf("authentication timed out after %v", DefaultTimeout)
 	}
 
@@ -316,6 +382,10 @@ func (c *Client) LoginWithClientCredentials(ctx context.Context) (*AuthResult, e
 	if len(c.config.Scopes) > 0 {
 		data.Set("scope", strings.Join(c.config.Scopes, " "))
 	}
+	// Extra parameters such as audience also apply to the client credentials grant
+	for key, value := range c.config.AuthParams {
+		data.Set(key, value)
+	}
 
 	// Create the HTTP request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(data.Encode()))
@@ -417,13 +487,31 @@ func (c *Client) RefreshTokens(ctx context.Context) (*Tokens, error) {
 		return nil, fmt.Errorf("failed to load tokens: %w", err)
 	}
 
+	return c.refresh(ctx, tokens)
+}
+
+// refresh exchanges the refresh token of the given tokens for new ones and persists the result
+func (c *Client) refresh(ctx context.Context, tokens *Tokens) (*Tokens, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Another refresh may have completed while we were waiting; reuse its result
+	// instead of presenting a refresh token that the IdP may have already rotated
+	if stored, err := c.LoadTokens(); err == nil && stored.AccessToken != tokens.AccessToken && !needsRefresh(stored) {
+		return stored, nil
+	}
+
 	if tokens.RefreshToken == "" {
 		return nil, fmt.Errorf("no refresh token available")
 	}
 
 	// Initialize provider if not already done
 	if c.provider == nil {
-		redirectURI := fmt.Sprintf("http://localhost:%d%s", DefaultPort, DefaultCallbackPath)
+		// The redirect URI is not used for refresh, but the relying party requires one
+		redirectURI := callbackEndpoint{Host: DefaultCallbackHost, Port: DefaultPort, Path: DefaultCallbackPath}.RedirectURI()
+		if endpoints, err := c.callbackEndpoints(); err == nil {
+			redirectURI = endpoints[0].RedirectURI()
+		}
 
 		key := []byte(uuid.New().String()[:16])
 		cookieHandler := httphelper.NewCookieHandler(key, key, httphelper.WithUnsecure())
@@ -459,14 +547,29 @@ func (c *Client) RefreshTokens(ctx context.Context) (*Tokens, error) {
 		Expiry:       newTokens.Expiry,
 	}
 
-	// Save the new tokens
+	// IdPs that don't rotate refresh tokens (or don't reissue ID tokens) omit them from
+	// the refresh response - keep the previous ones so the session can be refreshed again
+	if result.RefreshToken == "" {
+		result.RefreshToken = tokens.RefreshToken
+	}
+	if result.IDToken == "" {
+		result.IDToken = tokens.IDToken
+	}
+
+	// Save the new tokens. With refresh token rotation the previous refresh token is now
+	// invalid, so failing to persist means the next run will need to log in again.
 	if err := c.SaveTokens(result); err != nil {
-		c.logger.Warn("failed to save refreshed tokens", "error", err)
+		c.logger.Warn("failed to save refreshed tokens; the next run will require a new login", "error", err)
 	}
 
 	return result, nil
 }
 
+// needsRefresh reports whether tokens are expired or about to expire
+func needsRefresh(tokens *Tokens) bool {
+	return !tokens.Expiry.IsZero() && time.Until(tokens.Expiry) < DefaultRefreshSkew
+}
+
 // IsAuthenticated checks if valid tokens exist
 func (c *Client) IsAuthenticated() bool {
 	tokens, err := c.LoadTokens()
@@ -488,27 +591,118 @@ func (c *Client) IsAuthenticated() bool {
 	return true
 }
 
+// HasRefreshToken checks if a refresh token is stored, so the session can be renewed without logging in
+func (c *Client) HasRefreshToken() bool {
+	tokens, err := c.LoadTokens()
+	return err == nil && tokens.RefreshToken != ""
+}
+
 // GetAccessToken returns the current access token, refreshing if needed
 func (c *Client) GetAccessToken(ctx context.Context) (string, error) {
+	tokens, err := c.GetValidTokens(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// GetValidTokens returns the stored tokens, refreshing them first if they are expired
+// or will expire within DefaultRefreshSkew
+func (c *Client) GetValidTokens(ctx context.Context) (*Tokens, error) {
 	tokens, err := c.LoadTokens()
 	if err != nil {
-		return "", fmt.Errorf("not authenticated: %w", err)
+		return nil, fmt.Errorf("not authenticated: %w", err)
 	}
 
-	// Check if token is expired
-	if !tokens.Expiry.IsZero() && tokens.Expiry.Before(time.Now()) {
-		// Try to refresh
-		if tokens.RefreshToken != "" {
-			newTokens, err := c.RefreshTokens(ctx)
-			if err != nil {
-				return "", fmt.Errorf("token expired and refresh failed: %w", err)
-			}
-			return newTokens.AccessToken, nil
+	if !needsRefresh(tokens) {
+		return tokens, nil
+	}
+
+	expired := tokens.Expiry.Before(time.Now())
+	if tokens.RefreshToken == "" {
+		if expired {
+			return nil, fmt.Errorf("token expired and no refresh token available")
 		}
-		return "", fmt.Errorf("token expired and no refresh token available")
+		return tokens, nil
 	}
 
-	return tokens.AccessToken, nil
+	newTokens, err := c.refresh(ctx, tokens)
+	if err != nil {
+		if expired {
+			return nil, fmt.Errorf("token expired and refresh failed: %w", err)
+		}
+		// Still valid for a little while - use it and try again next time
+		c.logger.Warn("failed to refresh tokens before expiry", "error", err)
+		return tokens, nil
+	}
+	return newTokens, nil
+}
+
+// StartAutoRefresh refreshes the tokens in the background shortly before they expire,
+// keeping the SSO session alive for long-running commands such as `sstart mcp`.
+// onRefresh is called with the new tokens after every successful refresh.
+// The loop stops when ctx is cancelled or the session can no longer be refreshed.
+func (c *Client) StartAutoRefresh(ctx context.Context, onRefresh func(*Tokens)) {
+	tokens, err := c.LoadTokens()
+	if err != nil || tokens.RefreshToken == "" || tokens.Expiry.IsZero() {
+		return
+	}
+
+	go func() {
+		for {
+			wait := time.Until(tokens.Expiry) - DefaultRefreshSkew
+			if wait < 0 {
+				wait = 0
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			newTokens, err := c.refresh(ctx, tokens)
+			for err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if tokens.Expiry.Before(time.Now()) {
+					c.logger.Warn("SSO session expired and could not be refreshed", "error", err)
+					return
+				}
+				c.logger.Warn("failed to refresh tokens, retrying", "error", err, "retry_in", refreshRetryInterval)
+
+				timer := time.NewTimer(refreshRetryInterval)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				newTokens, err = c.refresh(ctx, tokens)
+			}
+
+			tokens = newTokens
+			if onRefresh != nil {
+				onRefresh(tokens)
+			}
+			if tokens.Expiry.IsZero() {
+				return
+			}
+		}
+	}()
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // successHTML is the HTML page shown after successful authentication
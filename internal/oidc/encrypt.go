@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// TokenEncryptionKeyEnvVar is the environment variable holding the passphrase used to encrypt the token file
+const TokenEncryptionKeyEnvVar = "SSTART_TOKEN_ENCRYPTION_KEY"
+
+// encryptedTokenFileVersion is the current version of the encrypted token file format
+const encryptedTokenFileVersion = 1
+
+// scrypt parameters for deriving the file encryption key from the passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+// encryptedTokenFile is the on-disk format of an encrypted token file
+type encryptedTokenFile struct {
+	Encrypted  int    `json:"sstart_encrypted"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptTokenData encrypts data with AES-256-GCM using a key derived from passphrase
+func encryptTokenData(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newTokenCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return json.MarshalIndent(encryptedTokenFile{
+		Encrypted:  encryptedTokenFileVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, data, nil),
+	}, "", "  ")
+}
+
+// decryptTokenData decrypts a token file written by encryptTokenData
+func decryptTokenData(file *encryptedTokenFile, passphrase string) ([]byte, error) {
+	if file.Encrypted != encryptedTokenFileVersion {
+		return nil, fmt.Errorf("unsupported encrypted token file version %d", file.Encrypted)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("token file is encrypted but %s is not set", TokenEncryptionKeyEnvVar)
+	}
+
+	gcm, err := newTokenCipher(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token file (wrong %s?): %w", TokenEncryptionKeyEnvVar, err)
+	}
+	return data, nil
+}
+
+// newTokenCipher derives an AES-256-GCM cipher from passphrase and salt
+func newTokenCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
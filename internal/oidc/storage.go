@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/dirathea/sstart/internal/config"
 	"github.com/zalando/go-keyring"
 )
 
@@ -14,7 +15,7 @@ const (
 	TokenFileName = "tokens.json"
 	// ConfigDirName is the name of the directory where sstart stores its configuration
 	ConfigDirName = "sstart"
-	// KeyringService is the service name used for keyring storage
+	// KeyringService is the default service name used for keyring storage
 	KeyringService = "sstart"
 	// KeyringUser is the user/account name used for keyring storage
 	KeyringUser = "sso-tokens"
@@ -28,8 +29,13 @@ const (
 	StorageBackendKeyring StorageBackend = "keyring"
 	// StorageBackendFile indicates tokens are stored in a file
 	StorageBackendFile StorageBackend = "file"
+	// StorageBackendMemory indicates tokens are only kept in process memory
+	StorageBackendMemory StorageBackend = "memory"
 )
 
+// TokenStorageEnvVar is the environment variable that overrides the configured token storage backend
+const TokenStorageEnvVar = "SSTART_TOKEN_STORAGE"
+
 // storageState tracks which storage backend is being used
 type storageState struct {
 	backend         StorageBackend
@@ -55,7 +61,7 @@ func getDefaultTokenPath() string {
 }
 
 // isKeyringAvailable checks if keyring is available on this system
-func isKeyringAvailable() bool {
+func isKeyringAvailable(service string) bool {
 	if storage.keyringTested {
 		return !storage.keyringDisabled
 	}
@@ -64,7 +70,7 @@ func isKeyringAvailable() bool {
 
 	// Try to access keyring with a test operation
 	// We try to get a non-existent key - if keyring is unavailable, it returns a specific error
-	_, err := keyring.Get(KeyringService, "test-availability")
+	_, err := keyring.Get(service, "test-availability")
 	if err != nil {
 		// ErrNotFound means keyring is working but key doesn't exist - that's fine
 		if err == keyring.ErrNotFound {
@@ -95,12 +101,37 @@ func (c *Client) GetStorageBackend() StorageBackend {
 	return storage.backend
 }
 
-// SaveTokens saves the tokens, trying keyring first then falling back to file
+// useKeyring reports whether the keyring should be tried for the configured backend
+func (c *Client) useKeyring() bool {
+	switch c.storageBackend {
+	case config.TokenStorageKeyring:
+		return true
+	case config.TokenStorageAuto:
+		return isKeyringAvailable(c.keyringService)
+	default:
+		return false
+	}
+}
+
+// useFile reports whether file storage should be used for the configured backend
+func (c *Client) useFile() bool {
+	return c.storageBackend == config.TokenStorageAuto || c.storageBackend == config.TokenStorageFile
+}
+
+// SaveTokens saves the tokens to the configured backend
+// With the default "auto" backend, the keyring is tried first with a fallback to file
 func (c *Client) SaveTokens(tokens *Tokens) error {
 	if tokens == nil {
 		return fmt.Errorf("tokens cannot be nil")
 	}
 
+	if c.storageBackend == config.TokenStorageMemory {
+		stored := *tokens
+		c.memTokens = &stored
+		storage.backend = StorageBackendMemory
+		return nil
+	}
+
 	// Marshal tokens to JSON
 	data, err := json.Marshal(tokens)
 	if err != nil {
@@ -108,14 +139,19 @@ func (c *Client) SaveTokens(tokens *Tokens) error {
 	}
 
 	// Try keyring first
-	if isKeyringAvailable() {
-		err := keyring.Set(KeyringService, KeyringUser, string(data))
+	if c.useKeyring() {
+		err := keyring.Set(c.keyringService, KeyringUser, string(data))
 		if err == nil {
 			storage.backend = StorageBackendKeyring
 			// Clean up any old file storage
-			_ = os.Remove(c.tokenPath)
+			if c.useFile() {
+				_ = os.Remove(c.tokenPath)
+			}
 			return nil
 		}
+		if !c.useFile() {
+			return fmt.Errorf("failed to save tokens to keyring: %w", err)
+		}
 		// Keyring failed, fall back to file
 	}
 
@@ -137,6 +173,16 @@ func (c *Client) saveTokensToFile(tokens *Tokens) error {
 		return fmt.Errorf("failed to marshal tokens: %w", err)
 	}
 
+	if c.encryptFile {
+		if c.encryptionKey == "" {
+			return fmt.Errorf("token file encryption is enabled but %s is not set", TokenEncryptionKeyEnvVar)
+		}
+		data, err = encryptTokenData(data, c.encryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt tokens: %w", err)
+		}
+	}
+
 	// Write to file with secure permissions (owner read/write only)
 	if err := os.WriteFile(c.tokenPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write tokens file: %w", err)
@@ -146,21 +192,34 @@ func (c *Client) saveTokensToFile(tokens *Tokens) error {
 	return nil
 }
 
-// LoadTokens loads the tokens, trying keyring first then falling back to file
+// LoadTokens loads the tokens from the configured backend
+// With the default "auto" backend, the keyring is tried first with a fallback to file
 func (c *Client) LoadTokens() (*Tokens, error) {
+	if c.storageBackend == config.TokenStorageMemory {
+		if c.memTokens == nil {
+			return nil, fmt.Errorf("no tokens found (not authenticated)")
+		}
+		tokens := *c.memTokens
+		storage.backend = StorageBackendMemory
+		return &tokens, nil
+	}
+
 	// Try keyring first
-	if isKeyringAvailable() {
-		data, err := keyring.Get(KeyringService, KeyringUser)
+	if c.useKeyring() {
+		data, err := keyring.Get(c.keyringService, KeyringUser)
 		if err == nil {
 			var tokens Tokens
 			if err := json.Unmarshal([]byte(data), &tokens); err != nil {
 				// Invalid data in keyring, try to clean up and check file
-				_ = keyring.Delete(KeyringService, KeyringUser)
+				_ = keyring.Delete(c.keyringService, KeyringUser)
 			} else {
 				storage.backend = StorageBackendKeyring
 				return &tokens, nil
 			}
 		}
+		if !c.useFile() {
+			return nil, fmt.Errorf("no tokens found (not authenticated)")
+		}
 		// Keyring doesn't have tokens or failed, try file
 	}
 
@@ -178,6 +237,16 @@ func (c *Client) loadTokensFromFile() (*Tokens, error) {
 		return nil, fmt.Errorf("failed to read tokens file: %w", err)
 	}
 
+	// Encrypted files are recognized by their envelope, so plain files written
+	// before encryption was enabled can still be read
+	var envelope encryptedTokenFile
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Encrypted != 0 {
+		data, err = decryptTokenData(&envelope, c.encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var tokens Tokens
 	if err := json.Unmarshal(data, &tokens); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
@@ -187,36 +256,50 @@ func (c *Client) loadTokensFromFile() (*Tokens, error) {
 	return &tokens, nil
 }
 
-// ClearTokens removes the stored tokens from both keyring and file
+// ClearTokens removes the stored tokens from the configured backend
 func (c *Client) ClearTokens() error {
 	var lastErr error
 
+	if c.storageBackend == config.TokenStorageMemory {
+		c.memTokens = nil
+		return nil
+	}
+
 	// Try to clear from keyring
-	if isKeyringAvailable() {
-		if err := keyring.Delete(KeyringService, KeyringUser); err != nil && err != keyring.ErrNotFound {
+	if c.useKeyring() {
+		if err := keyring.Delete(c.keyringService, KeyringUser); err != nil && err != keyring.ErrNotFound {
 			lastErr = fmt.Errorf("failed to remove tokens from keyring: %w", err)
 		}
 	}
 
 	// Also try to clear from file
-	if err := os.Remove(c.tokenPath); err != nil && !os.IsNotExist(err) {
-		lastErr = fmt.Errorf("failed to remove tokens file: %w", err)
+	if c.useFile() {
+		if err := os.Remove(c.tokenPath); err != nil && !os.IsNotExist(err) {
+			lastErr = fmt.Errorf("failed to remove tokens file: %w", err)
+		}
 	}
 
 	return lastErr
 }
 
-// TokensExist checks if tokens exist in either keyring or file
+// TokensExist checks if tokens exist in the configured backend
 func (c *Client) TokensExist() bool {
+	if c.storageBackend == config.TokenStorageMemory {
+		return c.memTokens != nil
+	}
+
 	// Check keyring first
-	if isKeyringAvailable() {
-		_, err := keyring.Get(KeyringService, KeyringUser)
+	if c.useKeyring() {
+		_, err := keyring.Get(c.keyringService, KeyringUser)
 		if err == nil {
 			return true
 		}
 	}
 
 	// Check file
+	if !c.useFile() {
+		return false
+	}
 	_, err := os.Stat(c.tokenPath)
 	return err == nil
 }
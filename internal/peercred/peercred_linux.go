@@ -0,0 +1,37 @@
+//go:build linux
+
+// Package peercred checks the credentials of the peers of unix socket connections
+package peercred
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Check rejects unix socket connections from processes of another user
+func Check(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+	if credErr != nil {
+		return fmt.Errorf("failed to read peer credentials: %w", credErr)
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("connection from uid %d rejected", cred.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package peercred
+
+import "net"
+
+// Check accepts all connections; outside Linux, unix sockets are protected by their file permissions only
+func Check(conn net.Conn) error {
+	return nil
+}
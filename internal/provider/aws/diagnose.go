@@ -0,0 +1,211 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// credentialSources returns the sources of the SDK default credential chain, in the order the
+// SDK tries them, with how each one is configured in this environment. The EC2 instance metadata
+// service cannot be checked without calling it, so it is skipped only when disabled
+func credentialSources(ctx context.Context) []provider.AuthCheck {
+	env, err := config.NewEnvConfig()
+	if err != nil {
+		return []provider.AuthCheck{{Name: "environment", Status: provider.AuthCheckFailed, Detail: err.Error()}}
+	}
+
+	var sources []provider.AuthCheck
+	if env.Credentials.HasKeys() {
+		sources = append(sources, provider.AuthCheck{Name: "environment", Status: provider.AuthCheckOK, Detail: "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are set"})
+	} else {
+		sources = append(sources, provider.AuthCheck{Name: "environment", Status: provider.AuthCheckSkipped, Detail: "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set"})
+	}
+
+	profile := env.SharedConfigProfile
+	if profile == "" {
+		profile = "default"
+	}
+	sources = append(sources, sharedConfigSource(ctx, profile, env))
+
+	switch {
+	case env.WebIdentityTokenFilePath != "" && env.RoleARN != "":
+		sources = append(sources, provider.AuthCheck{Name: "web identity", Status: provider.AuthCheckOK, Detail: fmt.Sprintf("assumes role %s with the token in %s", env.RoleARN, env.WebIdentityTokenFilePath)})
+	default:
+		sources = append(sources, provider.AuthCheck{Name: "web identity", Status: provider.AuthCheckSkipped, Detail: "AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN are not set"})
+	}
+
+	switch {
+	case env.ContainerCredentialsRelativePath != "" || env.ContainerCredentialsEndpoint != "":
+		sources = append(sources, provider.AuthCheck{Name: "container", Status: provider.AuthCheckOK, Detail: "the ECS or EKS Pod Identity credentials endpoint is set"})
+	default:
+		sources = append(sources, provider.AuthCheck{Name: "container", Status: provider.AuthCheckSkipped, Detail: "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI and AWS_CONTAINER_CREDENTIALS_FULL_URI are not set"})
+	}
+
+	if env.EC2IMDSClientEnableState == imds.ClientDisabled {
+		sources = append(sources, provider.AuthCheck{Name: "EC2 instance metadata", Status: provider.AuthCheckSkipped, Detail: "disabled by AWS_EC2_METADATA_DISABLED"})
+	} else {
+		sources = append(sources, provider.AuthCheck{Name: "EC2 instance metadata", Status: provider.AuthCheckOK, Detail: "tried last, only answers on EC2 instances with an instance profile"})
+	}
+	return sources
+}
+
+// sharedConfigSource checks the profile of the shared config and credentials files, at the
+// locations set in the environment or the default ones
+func sharedConfigSource(ctx context.Context, profile string, env config.EnvConfig) provider.AuthCheck {
+	check := provider.AuthCheck{Name: "shared config"}
+	configFile, credentialsFile := env.SharedConfigFile, env.SharedCredentialsFile
+	if configFile == "" {
+		configFile = config.DefaultSharedConfigFilename()
+	}
+	if credentialsFile == "" {
+		credentialsFile = config.DefaultSharedCredentialsFilename()
+	}
+	shared, err := config.LoadSharedConfigProfile(ctx, profile, func(o *config.LoadSharedConfigOptions) {
+		o.ConfigFiles = []string{configFile}
+		o.CredentialsFiles = []string{credentialsFile}
+	})
+	if err != nil {
+		var notExist config.SharedConfigProfileNotExistError
+		if errors.As(err, &notExist) && notExist.Profile == profile {
+			check.Status = provider.AuthCheckSkipped
+			check.Detail = fmt.Sprintf("profile '%s' is not in %s or %s", profile, configFile, credentialsFile)
+			return check
+		}
+		check.Status = provider.AuthCheckFailed
+		check.Detail = fmt.Sprintf("profile '%s': %v", profile, err)
+		return check
+	}
+
+	check.Status = provider.AuthCheckOK
+	switch {
+	case shared.RoleARN != "":
+		check.Detail = fmt.Sprintf("profile '%s' assumes role %s", profile, shared.RoleARN)
+	case shared.SSOSessionName != "" || shared.SSOStartURL != "":
+		check.Detail = fmt.Sprintf("profile '%s' uses IAM Identity Center (run 'aws sso login' when its session expires)", profile)
+	case shared.CredentialProcess != "":
+		check.Detail = fmt.Sprintf("profile '%s' uses credential_process", profile)
+	case shared.Credentials.HasKeys():
+		check.Detail = fmt.Sprintf("profile '%s' has access keys", profile)
+	default:
+		check.Status = provider.AuthCheckSkipped
+		check.Detail = fmt.Sprintf("profile '%s' has no credentials", profile)
+	}
+	return check
+}
+
+// credentialChainError is a failure of the SDK default credential chain to find credentials, e.g.
+// in CI where the chain falls through to the EC2 instance metadata service. Its hint lists the
+// sources that were tried and what to set
+type credentialChainError struct {
+	err     error
+	sources []provider.AuthCheck
+}
+
+func (e *credentialChainError) Error() string {
+	return fmt.Sprintf("no AWS credentials found: %v", e.err)
+}
+
+func (e *credentialChainError) Unwrap() []error { return []error{e.err, provider.ErrAuth} }
+
+// Hint lists the credential sources that were tried and how to configure one of them
+func (e *credentialChainError) Hint() string {
+	tried := make([]string, 0, len(e.sources))
+	for _, source := range e.sources {
+		tried = append(tried, fmt.Sprintf("%s (%s)", source.Name, source.Detail))
+	}
+	return fmt.Sprintf("AWS credential sources tried: %s. Set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or AWS_PROFILE; "+
+		"set 'endpoint' for a local emulator, or 'auth: {method: sso, role_arn: ...}' to assume a role with sstart SSO. "+
+		"Run 'sstart validate --providers <id> --debug-auth' to probe the credentials",
+		strings.Join(tried, ", "))
+}
+
+// diagnoseAuth checks the credentials a provider would use: the sources of the default credential
+// chain, or the SSO token, then the credentials they give and the identity STS reports for them
+func diagnoseAuth(ctx context.Context, region, endpoint string, auth *AWSAuthConfig, ssoIDToken string) []provider.AuthCheck {
+	var checks []provider.AuthCheck
+	sso := auth != nil && strings.ToLower(auth.Method) == AuthMethodSSO
+	switch {
+	case sso && ssoIDToken == "":
+		checks = append(checks, provider.AuthCheck{Name: "sso", Status: provider.AuthCheckFailed, Detail: "no SSO ID token available: configure the sso section"})
+	case sso:
+		checks = append(checks, provider.AuthCheck{Name: "sso", Status: provider.AuthCheckOK, Detail: fmt.Sprintf("assumes role %s with the SSO ID token", auth.RoleARN)})
+	case endpoint != "":
+		checks = append(checks, provider.AuthCheck{Name: "endpoint", Status: provider.AuthCheckOK, Detail: fmt.Sprintf("static test credentials for %s", endpoint)})
+	default:
+		checks = append(checks, credentialSources(ctx)...)
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, endpoint, auth, ssoIDToken)
+	var creds aws.Credentials
+	if err == nil {
+		creds, err = cfg.Credentials.Retrieve(ctx)
+	}
+	if err != nil {
+		var chainErr *credentialChainError
+		if errors.As(err, &chainErr) {
+			err = chainErr.err
+		}
+		return append(checks, provider.AuthCheck{Name: "credentials", Status: provider.AuthCheckFailed, Detail: err.Error()})
+	}
+	detail := fmt.Sprintf("from %s, access key %s", creds.Source, maskAccessKey(creds.AccessKeyID))
+	if creds.CanExpire {
+		detail += fmt.Sprintf(", expire %s", creds.Expires.Format("2006-01-02 15:04:05 MST"))
+	}
+	checks = append(checks, provider.AuthCheck{Name: "credentials", Status: provider.AuthCheckOK, Detail: detail})
+
+	stsOpts := []func(*sts.Options){}
+	if endpoint != "" {
+		stsOpts = append(stsOpts, func(o *sts.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+		})
+	}
+	identity, err := sts.NewFromConfig(cfg, stsOpts...).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return append(checks, provider.AuthCheck{Name: "identity", Status: provider.AuthCheckFailed, Detail: err.Error()})
+	}
+	return append(checks, provider.AuthCheck{Name: "identity", Status: provider.AuthCheckOK, Detail: fmt.Sprintf("%s in region %s", aws.ToString(identity.Arn), cfg.Region)})
+}
+
+// maskAccessKey shows the first characters of an access key ID, enough to tell keys apart
+func maskAccessKey(id string) string {
+	if len(id) <= 8 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:8] + strings.Repeat("*", len(id)-8)
+}
+
+// DiagnoseAuth implements provider.AuthDiagnoser
+func (p *SecretsManagerProvider) DiagnoseAuth(secretContext provider.SecretContext, mapID string, config map[string]interface{}) ([]provider.AuthCheck, error) {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_secretsmanager configuration: %w", err)
+	}
+	regions, err := regionList(cfg.Region, cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_secretsmanager configuration: %w", err)
+	}
+	// Credentials do not depend on the region, so checking them in the primary region is enough
+	return diagnoseAuth(secretContext.Ctx, regions[0], cfg.Endpoint, cfg.Auth, cfg.SSOIDToken), nil
+}
+
+// DiagnoseAuth implements provider.AuthDiagnoser
+func (p *SSMProvider) DiagnoseAuth(secretContext provider.SecretContext, mapID string, config map[string]interface{}) ([]provider.AuthCheck, error) {
+	cfg, err := parseSSMConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_ssm configuration: %w", err)
+	}
+	regions, err := regionList(cfg.Region, cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_ssm configuration: %w", err)
+	}
+	// Credentials do not depend on the region, so checking them in the primary region is enough
+	return diagnoseAuth(secretContext.Ctx, regions[0], cfg.Endpoint, cfg.Auth, cfg.SSOIDToken), nil
+}
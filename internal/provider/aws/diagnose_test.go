@@ -0,0 +1,116 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// isolateAWSEnv clears the AWS credential sources of the environment and disables IMDS
+func isolateAWSEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_DEFAULT_PROFILE",
+		"AWS_CONFIG_FILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return home
+}
+
+func TestCredentialSources(t *testing.T) {
+	home := isolateAWSEnv(t)
+
+	statuses := func() map[string]string {
+		got := make(map[string]string)
+		for _, source := range credentialSources(context.Background()) {
+			got[source.Name] = source.Status
+		}
+		return got
+	}
+
+	got := statuses()
+	for _, name := range []string{"environment", "shared config", "web identity", "container", "EC2 instance metadata"} {
+		if got[name] != provider.AuthCheckSkipped {
+			t.Errorf("source %q = %q, want skipped without credentials", name, got[name])
+		}
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	configFile := filepath.Join(home, "aws-config")
+	if err := os.WriteFile(configFile, []byte("[profile ci]\nrole_arn = arn:aws:iam::123456789012:role/ci\nsource_profile = base\n\n[profile base]\naws_access_key_id = AKIABASE\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_PROFILE", "ci")
+
+	got = statuses()
+	if got["environment"] != provider.AuthCheckOK || got["shared config"] != provider.AuthCheckOK {
+		t.Errorf("sources = %v, want environment and shared config ok", got)
+	}
+}
+
+func TestLoadAWSConfig_NoCredentials(t *testing.T) {
+	isolateAWSEnv(t)
+
+	_, err := loadAWSConfig(context.Background(), "us-east-1", "", nil, "")
+	if err == nil {
+		t.Fatal("expected an error without credentials")
+	}
+	if !errors.Is(err, provider.ErrAuth) {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+	hint := provider.ErrorHint(err)
+	for _, want := range []string{"environment (AWS_ACCESS_KEY_ID", "EC2 instance metadata (disabled", "--debug-auth"} {
+		if !strings.Contains(hint, want) {
+			t.Errorf("hint %q does not contain %q", hint, want)
+		}
+	}
+}
+
+func TestSecretsManagerProvider_DiagnoseAuth(t *testing.T) {
+	isolateAWSEnv(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "GetCallerIdentity" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><GetCallerIdentityResult><Arn>arn:aws:iam::123456789012:user/ci</Arn><UserId>AIDEXAMPLE</UserId><Account>123456789012</Account></GetCallerIdentityResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></GetCallerIdentityResponse>`))
+	}))
+	defer server.Close()
+
+	p := &SecretsManagerProvider{}
+	checks, err := p.DiagnoseAuth(provider.SecretContext{Ctx: context.Background()}, "aws-prod", map[string]interface{}{
+		"secret_id": "myapp",
+		"region":    "us-east-1",
+		"endpoint":  server.URL,
+	})
+	if err != nil {
+		t.Fatalf("DiagnoseAuth() error = %v", err)
+	}
+
+	got := make(map[string]provider.AuthCheck)
+	for _, check := range checks {
+		got[check.Name] = check
+	}
+	if got["credentials"].Status != provider.AuthCheckOK || !strings.Contains(got["credentials"].Detail, "access key ****") {
+		t.Errorf("credentials check = %+v, want the masked static test credentials", got["credentials"])
+	}
+	if identity := got["identity"]; identity.Status != provider.AuthCheckOK || !strings.Contains(identity.Detail, "arn:aws:iam::123456789012:user/ci") {
+		t.Errorf("identity check = %+v, want the caller ARN", identity)
+	}
+}
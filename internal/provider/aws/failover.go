@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// regionList returns the regions to fetch from, in order: 'regions', or else 'region' alone (empty
+// for the SDK default)
+func regionList(region string, regions []string) ([]string, error) {
+	if len(regions) == 0 {
+		return []string{region}, nil
+	}
+	if region != "" {
+		return nil, fmt.Errorf("'region' and 'regions' cannot be used together")
+	}
+	for _, r := range regions {
+		if r == "" {
+			return nil, fmt.Errorf("'regions' cannot contain an empty region")
+		}
+	}
+	return regions, nil
+}
+
+// withFailover calls fetch with each region in turn until it succeeds, for secrets replicated to
+// several regions. Each failover is logged, and the errors of all regions are returned if none
+// succeeds
+func withFailover[T any](ctx context.Context, mapID string, regions []string, fetch func(region string) (T, error)) (T, error) {
+	if len(regions) == 1 {
+		return fetch(regions[0])
+	}
+	var zero T
+	var errs []error
+	for i, region := range regions {
+		result, err := fetch(region)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("region %s: %w", region, err))
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(regions)-1 {
+			log.Printf("WARN: provider '%s' failed in region %s, failing over to %s: %v", mapID, region, regions[i+1], err)
+		}
+	}
+	return zero, fmt.Errorf("failed in all regions: %w", errors.Join(errs...))
+}
+
+// regionalSecretID returns the ARN of the replica of a secret in region, since replicas share the
+// name of the primary secret but not its ARN. Names are returned as is
+func regionalSecretID(secretID, region string) string {
+	parts := strings.SplitN(secretID, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" || region == "" {
+		return secretID
+	}
+	parts[3] = region
+	return strings.Join(parts, ":")
+}
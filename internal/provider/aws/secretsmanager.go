@@ -10,24 +10,56 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/dirathea/sstart/internal/provider"
 )
 
+const (
+	// AuthMethodDefault uses the AWS SDK default credential chain
+	AuthMethodDefault = "default"
+	// AuthMethodSSO exchanges the sstart SSO ID token for AWS credentials via STS AssumeRoleWithWebIdentity
+	AuthMethodSSO = "sso"
+
+	// DefaultRoleSessionName is the role session name used when none is configured
+	DefaultRoleSessionName = "sstart"
+)
+
+// AWSAuthConfig represents authentication configuration for AWS
+type AWSAuthConfig struct {
+	// Method specifies the authentication method: "default" (SDK credential chain) or "sso"
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// RoleARN is the IAM role to assume with the SSO ID token (required when using sso auth)
+	RoleARN string `json:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+	// SessionName is the role session name (optional, defaults to "sstart")
+	SessionName string `json:"session_name,omitempty" yaml:"session_name,omitempty"`
+}
+
 // SecretsManagerConfig represents the configuration for AWS Secrets Manager provider
 type SecretsManagerConfig struct {
 	// SecretID is the ARN or name of the secret in AWS Secrets Manager (required)
 	SecretID string `json:"secret_id" yaml:"secret_id"`
 	// Region is the AWS region where the secret is stored (optional)
 	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	// Regions are the regions of a replicated secret, tried in order until one succeeds (instead of region)
+	Regions []string `json:"regions,omitempty" yaml:"regions,omitempty"`
 	// Endpoint is a custom endpoint URL for AWS Secrets Manager (optional, for local testing)
 	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Auth contains authentication configuration (optional, defaults to the SDK credential chain)
+	Auth *AWSAuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// KeyName loads the whole secret value as is under this key instead of parsing it as a JSON
+	// object, for plaintext and binary secrets such as API tokens or certificates (optional)
+	KeyName string `json:"key_name,omitempty" yaml:"key_name,omitempty"`
+
+	// Internal: SSO ID token injected by the collector
+	SSOIDToken string `json:"-" yaml:"-"`
 }
 
 // SecretsManagerProvider implements the provider interface for AWS Secrets Manager
 type SecretsManagerProvider struct {
-	client *secretsmanager.Client
-	region string
+	// clients by configured region, empty for the SDK default
+	clients map[string]*secretsmanager.Client
 }
 
 func init() {
@@ -41,6 +73,20 @@ func (p *SecretsManagerProvider) Name() string {
 	return "aws_secretsmanager"
 }
 
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *SecretsManagerProvider) ConfigStruct() interface{} {
+	return &SecretsManagerConfig{}
+}
+
+// UsesSSO reports whether the configuration uses sso auth, which needs the collector's SSO tokens
+func (p *SecretsManagerProvider) UsesSSO(config map[string]interface{}) bool {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return false
+	}
+	return cfg.Auth != nil && strings.ToLower(cfg.Auth.Method) == AuthMethodSSO
+}
+
 // Fetch fetches secrets from AWS Secrets Manager
 func (p *SecretsManagerProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -55,33 +101,51 @@ func (p *SecretsManagerProvider) Fetch(secretContext provider.SecretContext, map
 		return nil, fmt.Errorf("aws_secretsmanager provider requires 'secret_id' field in configuration")
 	}
 
-	// Set region if provided
-	if cfg.Region != "" {
-		p.region = cfg.Region
-	}
-
-	if err := p.ensureClient(ctx, cfg.Endpoint); err != nil {
-		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	regions, err := regionList(cfg.Region, cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_secretsmanager configuration: %w", err)
 	}
 
-	// Fetch the secret from Secrets Manager
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(cfg.SecretID),
+	// Fetch the secret from Secrets Manager, failing over to the next region of a replicated secret
+	result, err := withFailover(ctx, mapID, regions, func(region string) (*secretsmanager.GetSecretValueOutput, error) {
+		client, err := p.clientFor(ctx, cfg, region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+		}
+		result, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(p.secretID(cfg, region)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch secret from AWS Secrets Manager: %w", err)
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := p.client.GetSecretValue(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secret from AWS Secrets Manager: %w", err)
+	// Binary secrets have no string value
+	var secretValue string
+	switch {
+	case result.SecretString != nil:
+		secretValue = *result.SecretString
+	case result.SecretBinary != nil:
+		secretValue = string(result.SecretBinary)
+		if cfg.KeyName == "" {
+			return nil, fmt.Errorf("secret '%s' is binary; set 'key_name' to load it under a key", cfg.SecretID)
+		}
 	}
 
-	// Parse the secret value (assuming JSON format)
+	// Parse the secret value as a JSON object, unless it is loaded as is
 	var secretData map[string]interface{}
-	if err := json.Unmarshal([]byte(*result.SecretString), &secretData); err != nil {
+	if cfg.KeyName != "" {
+		secretData = map[string]interface{}{cfg.KeyName: secretValue}
+	} else if err := json.Unmarshal([]byte(secretValue), &secretData); err != nil || secretData == nil {
 		// If not JSON, treat as a single value
 		secretKey := strings.ToUpper(strings.ReplaceAll(mapID, "-", "_")) + "_SECRET"
-		log.Printf("WARN: Secret from provider '%s' is not JSON format. Secret loaded to %s", mapID, secretKey)
+		log.Printf("WARN: Secret from provider '%s' is not JSON format. Secret loaded to %s; set 'key_name' to choose the key", mapID, secretKey)
 		return []provider.KeyValue{
-			{Key: secretKey, Value: *result.SecretString},
+			{Key: secretKey, Value: secretValue},
 		}, nil
 	}
 
@@ -115,22 +179,106 @@ func (p *SecretsManagerProvider) Fetch(secretContext provider.SecretContext, map
 	return kvs, nil
 }
 
-func (p *SecretsManagerProvider) ensureClient(ctx context.Context, endpoint string) error {
-	if p.client != nil {
-		return nil
+// FetchMetadata returns when the secret was created and last rotated or changed
+// The times apply to every key, since all keys come from the same Secrets Manager secret
+func (p *SecretsManagerProvider) FetchMetadata(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.SecretMetadata, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_secretsmanager configuration: %w", err)
+	}
+	if cfg.SecretID == "" {
+		return nil, fmt.Errorf("aws_secretsmanager provider requires 'secret_id' field in configuration")
+	}
+	regions, err := regionList(cfg.Region, cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_secretsmanager configuration: %w", err)
+	}
+
+	result, err := withFailover(ctx, mapID, regions, func(region string) (*secretsmanager.DescribeSecretOutput, error) {
+		client, err := p.clientFor(ctx, cfg, region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+		}
+		result, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(p.secretID(cfg, region)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe secret in AWS Secrets Manager: %w", err)
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := provider.SecretMetadata{CreatedAt: aws.ToTime(result.CreatedDate)}
+	// Prefer the rotation date, since the last changed date also moves when tags or the description change
+	if result.LastRotatedDate != nil {
+		metadata.UpdatedAt = *result.LastRotatedDate
+	} else if result.LastChangedDate != nil {
+		metadata.UpdatedAt = *result.LastChangedDate
+	}
+	return []provider.SecretMetadata{metadata}, nil
+}
+
+// secretID returns the ID of the secret in region: with 'regions', an ARN names the replica in region
+func (p *SecretsManagerProvider) secretID(smCfg *SecretsManagerConfig, region string) string {
+	if len(smCfg.Regions) == 0 {
+		return smCfg.SecretID
+	}
+	return regionalSecretID(smCfg.SecretID, region)
+}
+
+// clientFor returns the client for a region, empty for the SDK default, creating it on first use
+func (p *SecretsManagerProvider) clientFor(ctx context.Context, smCfg *SecretsManagerConfig, region string) (*secretsmanager.Client, error) {
+	if client, ok := p.clients[region]; ok {
+		return client, nil
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, smCfg.Endpoint, smCfg.Auth, smCfg.SSOIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply custom endpoint if provided
+	opts := []func(*secretsmanager.Options){}
+	if smCfg.Endpoint != "" {
+		opts = append(opts, func(o *secretsmanager.Options) {
+			o.BaseEndpoint = aws.String(smCfg.Endpoint)
+		})
+	}
+
+	if p.clients == nil {
+		p.clients = make(map[string]*secretsmanager.Client)
+	}
+	p.clients[region] = secretsmanager.NewFromConfig(cfg, opts...)
+	return p.clients[region], nil
+}
+
+// loadAWSConfig loads the AWS config for a provider in region (empty for the SDK default), using the
+// SDK credential chain or, with sso auth, credentials obtained with the SSO ID token
+func loadAWSConfig(ctx context.Context, region, endpoint string, auth *AWSAuthConfig, ssoIDToken string) (aws.Config, error) {
+	// Determine auth method
+	authMethod := AuthMethodDefault
+	if auth != nil && auth.Method != "" {
+		authMethod = strings.ToLower(auth.Method)
+	}
+	if authMethod != AuthMethodDefault && authMethod != AuthMethodSSO {
+		return aws.Config{}, fmt.Errorf("unsupported auth method: %s (supported: default, sso)", authMethod)
 	}
 
 	// Build config options
 	cfgOpts := []func(*config.LoadOptions) error{}
 
 	// Use configured region if set
-	if p.region != "" {
-		cfgOpts = append(cfgOpts, config.WithRegion(p.region))
+	if region != "" {
+		cfgOpts = append(cfgOpts, config.WithRegion(region))
 	}
 
 	// When using a custom endpoint (e.g., LocalStack), use static credentials
 	// to avoid trying to use EC2 IMDS or other credential sources that won't work
-	if endpoint != "" {
+	if endpoint != "" && authMethod == AuthMethodDefault {
 		cfgOpts = append(cfgOpts, config.WithCredentialsProvider(
 			credentials.NewStaticCredentialsProvider("test", "test", ""),
 		))
@@ -138,29 +286,71 @@ func (p *SecretsManagerProvider) ensureClient(ctx context.Context, endpoint stri
 
 	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
 	if err != nil {
-		return err
+		return aws.Config{}, err
 	}
 
 	// If no region was configured, use the one from AWS config or default
-	if p.region == "" {
-		p.region = cfg.Region
-		if p.region == "" {
-			p.region = "us-east-1"
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	// Resolve the credentials of the default chain now, so that a chain without any is reported
+	// with the sources it tried. They are cached for the requests of the client
+	if authMethod == AuthMethodDefault && endpoint == "" {
+		if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+			return aws.Config{}, &credentialChainError{err: err, sources: credentialSources(ctx)}
 		}
 	}
 
-	// Apply custom endpoint if provided
-	opts := []func(*secretsmanager.Options){}
+	// Exchange the SSO ID token for temporary role credentials
+	if authMethod == AuthMethodSSO {
+		if err := configureSSOCredentials(&cfg, endpoint, auth, ssoIDToken); err != nil {
+			return aws.Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// configureSSOCredentials replaces the config's credentials with ones obtained via
+// STS AssumeRoleWithWebIdentity using the SSO ID token
+func configureSSOCredentials(awsCfg *aws.Config, endpoint string, auth *AWSAuthConfig, ssoIDToken string) error {
+	if ssoIDToken == "" {
+		return fmt.Errorf("aws sso authentication requires SSO to be configured - no SSO ID token available")
+	}
+	if auth.RoleARN == "" {
+		return fmt.Errorf("aws sso authentication requires 'auth.role_arn' field in configuration")
+	}
+
+	sessionName := auth.SessionName
+	if sessionName == "" {
+		sessionName = DefaultRoleSessionName
+	}
+
+	// AssumeRoleWithWebIdentity is an unsigned call, so the STS client needs no prior credentials
+	stsOpts := []func(*sts.Options){}
 	if endpoint != "" {
-		opts = append(opts, func(o *secretsmanager.Options) {
+		stsOpts = append(stsOpts, func(o *sts.Options) {
 			o.BaseEndpoint = aws.String(endpoint)
 		})
 	}
+	stsClient := sts.NewFromConfig(*awsCfg, stsOpts...)
+
+	roleProvider := stscreds.NewWebIdentityRoleProvider(stsClient, auth.RoleARN, ssoTokenRetriever(ssoIDToken), func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = sessionName
+	})
+	awsCfg.Credentials = aws.NewCredentialsCache(roleProvider)
 
-	p.client = secretsmanager.NewFromConfig(cfg, opts...)
 	return nil
 }
 
+// ssoTokenRetriever supplies the SSO ID token to the STS web identity provider
+type ssoTokenRetriever string
+
+// GetIdentityToken implements stscreds.IdentityTokenRetriever
+func (t ssoTokenRetriever) GetIdentityToken() ([]byte, error) {
+	return []byte(t), nil
+}
+
 // parseConfig converts a map[string]interface{} to SecretsManagerConfig
 func parseConfig(config map[string]interface{}) (*SecretsManagerConfig, error) {
 	// Use JSON marshaling/unmarshaling for clean conversion
@@ -174,5 +364,10 @@ func parseConfig(config map[string]interface{}) (*SecretsManagerConfig, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Extract SSO ID token from the config map (injected by the collector)
+	if idToken, ok := config["_sso_id_token"].(string); ok {
+		cfg.SSOIDToken = idToken
+	}
+
 	return &cfg, nil
 }
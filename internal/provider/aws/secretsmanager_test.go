@@ -2,6 +2,10 @@ package aws
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/dirathea/sstart/internal/secrets"
@@ -103,8 +107,6 @@ func TestParseConfig(t *testing.T) {
 }
 
 func TestSecretsManagerProvider_Fetch_ConfigValidation(t *testing.T) {
-	provider := &SecretsManagerProvider{}
-
 	tests := []struct {
 		name    string
 		config  map[string]interface{}
@@ -127,10 +129,48 @@ func TestSecretsManagerProvider_Fetch_ConfigValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "aws_secretsmanager provider requires 'secret_id' field",
 		},
+		{
+			name: "sso auth without SSO token",
+			config: map[string]interface{}{
+				"secret_id": "my-secret",
+				"region":    "us-east-1",
+				"auth": map[string]interface{}{
+					"method":   "sso",
+					"role_arn": "arn:aws:iam::123456789012:role/sstart",
+				},
+			},
+			wantErr: true,
+			errMsg:  "no SSO ID token available",
+		},
+		{
+			name: "sso auth without role_arn",
+			config: map[string]interface{}{
+				"secret_id":     "my-secret",
+				"region":        "us-east-1",
+				"_sso_id_token": "id-token",
+				"auth": map[string]interface{}{
+					"method": "sso",
+				},
+			},
+			wantErr: true,
+			errMsg:  "requires 'auth.role_arn'",
+		},
+		{
+			name: "unsupported auth method",
+			config: map[string]interface{}{
+				"secret_id": "my-secret",
+				"auth": map[string]interface{}{
+					"method": "magic",
+				},
+			},
+			wantErr: true,
+			errMsg:  "unsupported auth method",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			provider := &SecretsManagerProvider{}
 			ctx := context.Background()
 			secretContext := secrets.NewEmptySecretContext(ctx)
 			_, err := provider.Fetch(secretContext, "test-map", tt.config, nil)
@@ -264,3 +304,235 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSecretsManagerProvider_ConfigWithSSOAuth(t *testing.T) {
+	config := map[string]interface{}{
+		"secret_id":     "my-secret",
+		"_sso_id_token": "id-token",
+		"auth": map[string]interface{}{
+			"method":       "sso",
+			"role_arn":     "arn:aws:iam::123456789012:role/sstart",
+			"session_name": "dev-session",
+		},
+	}
+
+	cfg, err := parseConfig(config)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	if cfg.Auth == nil {
+		t.Fatal("expected auth config to be parsed")
+	}
+	if cfg.Auth.Method != AuthMethodSSO {
+		t.Errorf("Auth.Method = %v, want %v", cfg.Auth.Method, AuthMethodSSO)
+	}
+	if cfg.Auth.RoleARN != "arn:aws:iam::123456789012:role/sstart" {
+		t.Errorf("Auth.RoleARN = %v", cfg.Auth.RoleARN)
+	}
+	if cfg.Auth.SessionName != "dev-session" {
+		t.Errorf("Auth.SessionName = %v, want dev-session", cfg.Auth.SessionName)
+	}
+	if cfg.SSOIDToken != "id-token" {
+		t.Errorf("SSOIDToken = %v, want id-token", cfg.SSOIDToken)
+	}
+}
+
+// newFakeSecretsManager serves GetSecretValue with the given response body for any secret
+func newFakeSecretsManager(t *testing.T, body string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestSecretsManagerProvider_Fetch_KeyName(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		keyName string
+		keys    map[string]string
+		want    map[string]string
+		errMsg  string
+	}{
+		{
+			name:    "plaintext secret under key_name",
+			body:    `{"Name":"api-token","SecretString":"tok-123"}`,
+			keyName: "API_TOKEN",
+			want:    map[string]string{"API_TOKEN": "tok-123"},
+		},
+		{
+			name:    "JSON secret loaded as is under key_name",
+			body:    `{"Name":"sa","SecretString":"{\"type\":\"service_account\"}"}`,
+			keyName: "SERVICE_ACCOUNT_JSON",
+			want:    map[string]string{"SERVICE_ACCOUNT_JSON": `{"type":"service_account"}`},
+		},
+		{
+			name:    "key_name renamed by keys",
+			body:    `{"Name":"api-token","SecretString":"tok-123"}`,
+			keyName: "API_TOKEN",
+			keys:    map[string]string{"API_TOKEN": "GITHUB_TOKEN"},
+			want:    map[string]string{"GITHUB_TOKEN": "tok-123"},
+		},
+		{
+			name:    "binary secret under key_name",
+			body:    `{"Name":"cert","SecretBinary":"Y2VydC1ieXRlcw=="}`,
+			keyName: "TLS_CERT",
+			want:    map[string]string{"TLS_CERT": "cert-bytes"},
+		},
+		{
+			name:   "binary secret without key_name",
+			body:   `{"Name":"cert","SecretBinary":"Y2VydC1ieXRlcw=="}`,
+			errMsg: "set 'key_name'",
+		},
+		{
+			name: "plaintext secret without key_name",
+			body: `{"Name":"api-token","SecretString":"tok-123"}`,
+			want: map[string]string{"AWS_PROD_SECRET": "tok-123"},
+		},
+		{
+			name: "JSON secret",
+			body: `{"Name":"db","SecretString":"{\"DB_USER\":\"app\"}"}`,
+			want: map[string]string{"DB_USER": "app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"secret_id": "my-secret",
+				"region":    "us-east-1",
+				"endpoint":  newFakeSecretsManager(t, tt.body),
+			}
+			if tt.keyName != "" {
+				config["key_name"] = tt.keyName
+			}
+
+			kvs, err := (&SecretsManagerProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "aws-prod", config, tt.keys)
+			if tt.errMsg != "" {
+				if err == nil || !containsSubstring(err.Error(), tt.errMsg) {
+					t.Fatalf("Fetch() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, kv := range kvs {
+				got[kv.Key] = kv.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fetch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newFailingRegionsSecretsManager serves a secret that fails in the given regions, read from the
+// credential scope of the signed request
+func newFailingRegionsSecretsManager(t *testing.T, failing ...string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, region := range failing {
+			if strings.Contains(r.Header.Get("Authorization"), "/"+region+"/secretsmanager/") {
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"__type":"ServiceUnavailable","message":"region down"}`))
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Name":"db","SecretString":"{\"DB_USER\":\"app\"}"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestSecretsManagerProvider_Fetch_Regions(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+
+	tests := []struct {
+		name    string
+		failing []string
+		config  map[string]interface{}
+		errMsgs []string
+	}{
+		{
+			name:    "primary region",
+			failing: []string{"us-west-2"},
+		},
+		{
+			name:    "fails over to the next region",
+			failing: []string{"us-east-1"},
+		},
+		{
+			name:    "fails in all regions",
+			failing: []string{"us-east-1", "us-west-2"},
+			errMsgs: []string{"failed in all regions", "region us-east-1:", "region us-west-2:"},
+		},
+		{
+			name:    "region and regions together",
+			config:  map[string]interface{}{"region": "us-east-1"},
+			errMsgs: []string{"'region' and 'regions' cannot be used together"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{
+				"secret_id": "my-secret",
+				"regions":   []interface{}{"us-east-1", "us-west-2"},
+				"endpoint":  newFailingRegionsSecretsManager(t, tt.failing...),
+			}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+
+			kvs, err := (&SecretsManagerProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "aws-prod", config, nil)
+			if len(tt.errMsgs) > 0 {
+				if err == nil {
+					t.Fatal("Fetch() error = nil, want error")
+				}
+				for _, msg := range tt.errMsgs {
+					if !containsSubstring(err.Error(), msg) {
+						t.Errorf("Fetch() error = %v, want error containing %q", err, msg)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if len(kvs) != 1 || kvs[0].Key != "DB_USER" || kvs[0].Value != "app" {
+				t.Errorf("Fetch() = %v, want DB_USER=app", kvs)
+			}
+		})
+	}
+}
+
+func TestRegionalSecretID(t *testing.T) {
+	tests := []struct {
+		secretID string
+		region   string
+		want     string
+	}{
+		{"my-secret", "us-west-2", "my-secret"},
+		{"arn:aws:secretsmanager:us-east-1:123456789012:secret:db-AbCdEf", "us-west-2", "arn:aws:secretsmanager:us-west-2:123456789012:secret:db-AbCdEf"},
+		{"arn:aws:secretsmanager:us-east-1:123456789012:secret:db-AbCdEf", "", "arn:aws:secretsmanager:us-east-1:123456789012:secret:db-AbCdEf"},
+	}
+	for _, tt := range tests {
+		if got := regionalSecretID(tt.secretID, tt.region); got != tt.want {
+			t.Errorf("regionalSecretID(%q, %q) = %q, want %q", tt.secretID, tt.region, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// SSMConfig represents the configuration for the AWS SSM Parameter Store provider
+type SSMConfig struct {
+	// Path is the parameter path to load recursively, e.g. /myapp/production (required unless services is set)
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Services loads parameters with chamber conventions: /<service>/<key>, with keys uppercased.
+	// Later services override earlier ones, like 'chamber exec service1 service2'
+	Services []string `json:"services,omitempty" yaml:"services,omitempty"`
+	// Region is the AWS region of the parameters (optional)
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	// Regions are the regions of replicated parameters, tried in order until one succeeds (instead of region)
+	Regions []string `json:"regions,omitempty" yaml:"regions,omitempty"`
+	// Endpoint is a custom endpoint URL for AWS SSM (optional, for local testing)
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Auth contains authentication configuration (optional, defaults to the SDK credential chain)
+	Auth *AWSAuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	// Internal: SSO ID token injected by the collector
+	SSOIDToken string `json:"-" yaml:"-"`
+}
+
+// SSMProvider implements the provider interface for AWS SSM Parameter Store
+type SSMProvider struct {
+	// clients by configured region, empty for the SDK default
+	clients map[string]*ssm.Client
+}
+
+func init() {
+	provider.Register("aws_ssm", func() provider.Provider {
+		return &SSMProvider{}
+	})
+}
+
+// Name returns the provider name
+func (p *SSMProvider) Name() string {
+	return "aws_ssm"
+}
+
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *SSMProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *SSMProvider) ConfigStruct() interface{} {
+	return &SSMConfig{}
+}
+
+// UsesSSO reports whether the configuration uses sso auth, which needs the collector's SSO tokens
+func (p *SSMProvider) UsesSSO(config map[string]interface{}) bool {
+	cfg, err := parseSSMConfig(config)
+	if err != nil {
+		return false
+	}
+	return cfg.Auth != nil && strings.ToLower(cfg.Auth.Method) == AuthMethodSSO
+}
+
+// Fetch fetches the parameters under a path, or of chamber services, decrypting SecureString parameters
+func (p *SSMProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseSSMConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_ssm configuration: %w", err)
+	}
+	if cfg.Path == "" && len(cfg.Services) == 0 {
+		return nil, fmt.Errorf("aws_ssm provider requires 'path' or 'services' field in configuration")
+	}
+	if cfg.Path != "" && len(cfg.Services) > 0 {
+		return nil, fmt.Errorf("aws_ssm provider accepts either 'path' or 'services', not both")
+	}
+
+	regions, err := regionList(cfg.Region, cfg.Regions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws_ssm configuration: %w", err)
+	}
+
+	// All parameters come from the same region, failing over to the next region of replicated parameters
+	values, err := withFailover(ctx, mapID, regions, func(region string) (map[string]string, error) {
+		client, err := p.clientFor(ctx, cfg, region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+		}
+		return fetchParameters(ctx, client, cfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Map keys according to configuration
+	kvs := make([]provider.KeyValue, 0, len(values))
+	for k, v := range values {
+		targetKey := k
+		if mappedKey, exists := keys[k]; exists {
+			if mappedKey != "==" {
+				targetKey = mappedKey
+			}
+		} else if len(keys) > 0 {
+			// Skip keys not in the mapping
+			continue
+		}
+		kvs = append(kvs, provider.KeyValue{Key: targetKey, Value: v})
+	}
+	return kvs, nil
+}
+
+// fetchParameters returns the parameters under the path, or of the chamber services, of the configuration
+func fetchParameters(ctx context.Context, client *ssm.Client, cfg *SSMConfig) (map[string]string, error) {
+	values := make(map[string]string)
+	if cfg.Path != "" {
+		path := "/" + strings.Trim(cfg.Path, "/")
+		if err := fetchPath(ctx, client, path, true, values, func(name string) string {
+			// Nested parameters are named after their path below the configured path
+			return strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(name, path), "/"), "/", "_")
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for _, service := range cfg.Services {
+		// chamber stores services and keys in lowercase, and exports keys in uppercase
+		path := "/" + strings.ToLower(strings.Trim(service, "/"))
+		if err := fetchPath(ctx, client, path, false, values, chamberKey); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// fetchPath stores the parameters under path in values, keyed by keyName of each parameter name
+func fetchPath(ctx context.Context, client *ssm.Client, path string, recursive bool, values map[string]string, keyName func(string) string) error {
+	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(recursive),
+		WithDecryption: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch parameters under '%s' from AWS SSM: %w", path, err)
+		}
+		for _, parameter := range page.Parameters {
+			values[keyName(aws.ToString(parameter.Name))] = aws.ToString(parameter.Value)
+		}
+	}
+	return nil
+}
+
+// chamberKey returns the environment variable name chamber exports for a parameter name:
+// its last path segment in uppercase, with dashes and dots replaced by underscores
+func chamberKey(name string) string {
+	key := name[strings.LastIndex(name, "/")+1:]
+	key = strings.NewReplacer("-", "_", ".", "_").Replace(key)
+	return strings.ToUpper(key)
+}
+
+// clientFor returns the client for a region, empty for the SDK default, creating it on first use
+func (p *SSMProvider) clientFor(ctx context.Context, ssmCfg *SSMConfig, region string) (*ssm.Client, error) {
+	if client, ok := p.clients[region]; ok {
+		return client, nil
+	}
+
+	cfg, err := loadAWSConfig(ctx, region, ssmCfg.Endpoint, ssmCfg.Auth, ssmCfg.SSOIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []func(*ssm.Options){}
+	if ssmCfg.Endpoint != "" {
+		opts = append(opts, func(o *ssm.Options) {
+			o.BaseEndpoint = aws.String(ssmCfg.Endpoint)
+		})
+	}
+	if p.clients == nil {
+		p.clients = make(map[string]*ssm.Client)
+	}
+	p.clients[region] = ssm.NewFromConfig(cfg, opts...)
+	return p.clients[region], nil
+}
+
+// parseSSMConfig converts a map[string]interface{} to SSMConfig
+func parseSSMConfig(config map[string]interface{}) (*SSMConfig, error) {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var cfg SSMConfig
+	if err := json.Unmarshal(jsonData, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	// Extract SSO ID token from the config map (injected by the collector)
+	if idToken, ok := config["_sso_id_token"].(string); ok {
+		cfg.SSOIDToken = idToken
+	}
+
+	return &cfg, nil
+}
@@ -4,17 +4,36 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
 	"github.com/dirathea/sstart/internal/provider"
 )
 
+const (
+	// AuthMethodDefault uses DefaultAzureCredential
+	AuthMethodDefault = "default"
+	// AuthMethodSSO uses the sstart SSO ID token as a federated client assertion
+	AuthMethodSSO = "sso"
+)
+
+// AzureAuthConfig represents authentication configuration for Azure
+type AzureAuthConfig struct {
+	// Method specifies the authentication method: "default" (DefaultAzureCredential) or "sso"
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// TenantID is the Microsoft Entra tenant ID (required when using sso auth)
+	TenantID string `json:"tenant_id,omitempty" yaml:"tenant_id,omitempty"`
+	// ClientID is the application (client) ID with the federated identity credential (required when using sso auth)
+	ClientID string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+}
+
 // AzureKeyVaultConfig represents the configuration for Azure Key Vault provider
 type AzureKeyVaultConfig struct {
 	// VaultURL is the URL of the Azure Key Vault (required)
@@ -24,6 +43,11 @@ type AzureKeyVaultConfig struct {
 	SecretName string `json:"secret_name" yaml:"secret_name"`
 	// Version is the secret version to fetch (optional, defaults to latest)
 	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Auth contains authentication configuration (optional, defaults to DefaultAzureCredential)
+	Auth *AzureAuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	// Internal: SSO ID token injected by the collector
+	SSOIDToken string `json:"-" yaml:"-"`
 }
 
 // AzureKeyVaultProvider implements the provider interface for Azure Key Vault
@@ -42,6 +66,20 @@ func (p *AzureKeyVaultProvider) Name() string {
 	return "azure_keyvault"
 }
 
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *AzureKeyVaultProvider) ConfigStruct() interface{} {
+	return &AzureKeyVaultConfig{}
+}
+
+// UsesSSO reports whether the configuration uses sso auth, which needs the collector's SSO tokens
+func (p *AzureKeyVaultProvider) UsesSSO(config map[string]interface{}) bool {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return false
+	}
+	return cfg.Auth != nil && strings.ToLower(cfg.Auth.Method) == AuthMethodSSO
+}
+
 // Fetch fetches secrets from Azure Key Vault
 func (p *AzureKeyVaultProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -59,7 +97,7 @@ func (p *AzureKeyVaultProvider) Fetch(secretContext provider.SecretContext, mapI
 		return nil, fmt.Errorf("azure_keyvault provider requires 'secret_name' field in configuration")
 	}
 
-	if err := p.ensureClient(ctx, cfg.VaultURL); err != nil {
+	if err := p.ensureClient(ctx, cfg); err != nil {
 		return nil, fmt.Errorf("failed to initialize Azure Key Vault client: %w", err)
 	}
 
@@ -74,7 +112,7 @@ func (p *AzureKeyVaultProvider) Fetch(secretContext provider.SecretContext, mapI
 		resp, err = p.client.GetSecret(ctx, cfg.SecretName, "", nil)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secret from Azure Key Vault: %w", err)
+		return nil, classifyError(fmt.Errorf("failed to fetch secret from Azure Key Vault: %w", err))
 	}
 
 	// Get the secret value
@@ -128,20 +166,21 @@ func (p *AzureKeyVaultProvider) Fetch(secretContext provider.SecretContext, mapI
 	return kvs, nil
 }
 
-func (p *AzureKeyVaultProvider) ensureClient(ctx context.Context, vaultURL string) error {
+func (p *AzureKeyVaultProvider) ensureClient(ctx context.Context, cfg *AzureKeyVaultConfig) error {
 	if p.client != nil {
 		return nil
 	}
 
+	vaultURL := cfg.VaultURL
+
 	// For emulator/local testing, use DefaultAzureCredential which will fall back to
 	// various authentication methods. For emulator, we typically use environment variables
 	// or managed identity, but for local testing with emulator, we can use DefaultAzureCredential
 	// which will try to authenticate. For emulator, we might need to disable SSL verification.
 
-	// Create credential - DefaultAzureCredential will try multiple authentication methods
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	cred, err := newCredential(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create Azure credential: %w", err)
+		return err
 	}
 
 	// Check if this is an emulator (localhost or lowkey-vault)
@@ -180,6 +219,55 @@ func (p *AzureKeyVaultProvider) ensureClient(ctx context.Context, vaultURL strin
 	return nil
 }
 
+// newCredential creates the Azure credential for the configured auth method
+func newCredential(cfg *AzureKeyVaultConfig) (azcore.TokenCredential, error) {
+	authMethod := AuthMethodDefault
+	if cfg.Auth != nil && cfg.Auth.Method != "" {
+		authMethod = strings.ToLower(cfg.Auth.Method)
+	}
+
+	switch authMethod {
+	case AuthMethodDefault:
+		// DefaultAzureCredential will try multiple authentication methods
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		return cred, nil
+	case AuthMethodSSO:
+		if cfg.SSOIDToken == "" {
+			return nil, fmt.Errorf("azure sso authentication requires SSO to be configured - no SSO ID token available")
+		}
+		if cfg.Auth.TenantID == "" {
+			return nil, fmt.Errorf("azure sso authentication requires 'auth.tenant_id' field in configuration")
+		}
+		if cfg.Auth.ClientID == "" {
+			return nil, fmt.Errorf("azure sso authentication requires 'auth.client_id' field in configuration")
+		}
+
+		// The SSO ID token is presented as the client assertion of a federated identity credential
+		idToken := cfg.SSOIDToken
+		cred, err := azidentity.NewClientAssertionCredential(cfg.Auth.TenantID, cfg.Auth.ClientID, func(ctx context.Context) (string, error) {
+			return idToken, nil
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure federated credential: %w", err)
+		}
+		return cred, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth method: %s (supported: default, sso)", authMethod)
+	}
+}
+
+// classifyError marks an error of the Azure SDK with the provider error class of its HTTP status
+func classifyError(err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return provider.Classify(provider.StatusClass(respErr.StatusCode), err)
+	}
+	return err
+}
+
 // parseConfig converts a map[string]interface{} to AzureKeyVaultConfig
 func parseConfig(config map[string]interface{}) (*AzureKeyVaultConfig, error) {
 	// Use JSON marshaling/unmarshaling for clean conversion
@@ -193,5 +281,10 @@ func parseConfig(config map[string]interface{}) (*AzureKeyVaultConfig, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Extract SSO ID token from the config map (injected by the collector)
+	if idToken, ok := config["_sso_id_token"].(string); ok {
+		cfg.SSOIDToken = idToken
+	}
+
 	return &cfg, nil
 }
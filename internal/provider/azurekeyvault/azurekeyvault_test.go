@@ -0,0 +1,92 @@
+package azurekeyvault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+func TestParseConfig_SSOIDToken(t *testing.T) {
+	cfg, err := parseConfig(map[string]interface{}{
+		"vault_url":     "https://my-vault.vault.azure.net/",
+		"secret_name":   "my-secret",
+		"auth":          map[string]interface{}{"method": "sso", "tenant_id": "tenant", "client_id": "client"},
+		"_sso_id_token": "id-token",
+	})
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+	if cfg.SSOIDToken != "id-token" {
+		t.Errorf("SSOIDToken = %q, want the injected ID token", cfg.SSOIDToken)
+	}
+	if cfg.Auth == nil || cfg.Auth.Method != "sso" || cfg.Auth.TenantID != "tenant" || cfg.Auth.ClientID != "client" {
+		t.Errorf("Auth = %+v, want method sso with tenant and client IDs", cfg.Auth)
+	}
+}
+
+func TestNewCredential(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           *AzureKeyVaultConfig
+		wantAssertion bool
+		wantErr       string
+	}{
+		{
+			name: "default without auth",
+			cfg:  &AzureKeyVaultConfig{},
+		},
+		{
+			name: "default method",
+			cfg:  &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "default"}},
+		},
+		{
+			name:          "sso",
+			cfg:           &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "sso", TenantID: "tenant", ClientID: "client"}, SSOIDToken: "id-token"},
+			wantAssertion: true,
+		},
+		{
+			name:          "sso method is case insensitive",
+			cfg:           &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "SSO", TenantID: "tenant", ClientID: "client"}, SSOIDToken: "id-token"},
+			wantAssertion: true,
+		},
+		{
+			name:    "sso without ID token",
+			cfg:     &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "sso", TenantID: "tenant", ClientID: "client"}},
+			wantErr: "no SSO ID token available",
+		},
+		{
+			name:    "sso without tenant",
+			cfg:     &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "sso", ClientID: "client"}, SSOIDToken: "id-token"},
+			wantErr: "'auth.tenant_id'",
+		},
+		{
+			name:    "sso without client",
+			cfg:     &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "sso", TenantID: "tenant"}, SSOIDToken: "id-token"},
+			wantErr: "'auth.client_id'",
+		},
+		{
+			name:    "unsupported method",
+			cfg:     &AzureKeyVaultConfig{Auth: &AzureAuthConfig{Method: "password"}},
+			wantErr: "unsupported auth method: password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := newCredential(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newCredential() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCredential() error = %v", err)
+			}
+			if _, ok := cred.(*azidentity.ClientAssertionCredential); ok != tt.wantAssertion {
+				t.Errorf("newCredential() = %T, want a client assertion credential: %v", cred, tt.wantAssertion)
+			}
+		})
+	}
+}
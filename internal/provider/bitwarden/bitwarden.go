@@ -95,6 +95,14 @@ func (p *BitwardenProvider) Name() string {
 	return "bitwarden"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *BitwardenProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *BitwardenProvider) ConfigStruct() interface{} {
+	return &BitwardenConfig{}
+}
+
 // Fetch fetches secrets from personal Bitwarden vault using REST API
 func (p *BitwardenProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -594,8 +602,16 @@ func (p *BitwardenProvider) getOrStartBWServe(ctx context.Context, bwPath string
 				break
 			}
 		}
+		if ctx.Err() != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, ctx.Err()
+		}
 		if i < maxRetries-1 {
-			time.Sleep(retryDelay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
 		} else {
 			cmd.Process.Kill()
 			cmd.Wait()
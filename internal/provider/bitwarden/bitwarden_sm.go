@@ -38,6 +38,14 @@ func (p *BitwardenSMProvider) Name() string {
 	return "bitwarden_sm"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *BitwardenSMProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *BitwardenSMProvider) ConfigStruct() interface{} {
+	return &BitwardenSMConfig{}
+}
+
 // Fetch fetches all secrets from a Bitwarden Secret Manager project
 // Only Key-Value pairs are extracted from secrets. Note fields are ignored.
 func (p *BitwardenSMProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
@@ -0,0 +1,268 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/dirathea/sstart/internal/bundle"
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/provider/dotenv"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+)
+
+const (
+	// FormatDotenv parses the object as a .env file
+	FormatDotenv = "dotenv"
+	// FormatJSON parses the object as a flat JSON object
+	FormatJSON = "json"
+)
+
+// AgeConfig configures client-side decryption of age-encrypted objects
+type AgeConfig struct {
+	// IdentityFile is the path of an age identity file, as written by age-keygen (environment variables are expanded)
+	IdentityFile string `json:"identity_file,omitempty" yaml:"identity_file,omitempty"`
+	// IdentityEnv is the name of an environment variable holding the age identity (default: SSTART_AGE_IDENTITY)
+	IdentityEnv string `json:"identity_env,omitempty" yaml:"identity_env,omitempty"`
+}
+
+// BucketConfig represents the configuration for the bucket provider
+type BucketConfig struct {
+	// URL is the object to download: s3://<bucket>/<key> or gs://<bucket>/<object> (required)
+	URL string `json:"url" yaml:"url"`
+	// Format is how the object is parsed: dotenv or json (optional, defaults to json for .json objects and dotenv otherwise)
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Region is the AWS region of an S3 bucket (optional)
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	// Endpoint is a custom S3 or GCS endpoint URL (optional, for S3-compatible stores and local testing)
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Age decrypts the object with an age identity after downloading it (optional)
+	Age *AgeConfig `json:"age,omitempty" yaml:"age,omitempty"`
+}
+
+// BucketProvider implements the provider interface for env files stored in S3 or GCS buckets
+type BucketProvider struct{}
+
+func init() {
+	provider.Register("bucket", func() provider.Provider {
+		return &BucketProvider{}
+	})
+}
+
+// Name returns the provider name
+func (p *BucketProvider) Name() string {
+	return "bucket"
+}
+
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *BucketProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *BucketProvider) ConfigStruct() interface{} {
+	return &BucketConfig{}
+}
+
+// Fetch downloads the object, decrypts it if configured, and parses it as dotenv or JSON
+func (p *BucketProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bucket configuration: %w", err)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("bucket provider requires 'url' field in configuration")
+	}
+	objectURL, err := url.Parse(cfg.URL)
+	if err != nil || objectURL.Host == "" || strings.TrimPrefix(objectURL.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid bucket url '%s' (expected s3://<bucket>/<key> or gs://<bucket>/<object>)", cfg.URL)
+	}
+	bucket, key := objectURL.Host, strings.TrimPrefix(objectURL.Path, "/")
+
+	var data []byte
+	switch objectURL.Scheme {
+	case "s3":
+		data, err = p.downloadS3(ctx, cfg, bucket, key)
+	case "gs":
+		data, err = p.downloadGCS(ctx, cfg, bucket, key)
+	default:
+		return nil, fmt.Errorf("unsupported bucket url scheme '%s' (supported: s3, gs)", objectURL.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download '%s': %w", cfg.URL, err)
+	}
+
+	if cfg.Age != nil {
+		if data, err = decryptAge(cfg.Age, data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt '%s': %w", cfg.URL, err)
+		}
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format = FormatDotenv
+		if path.Ext(strings.TrimSuffix(key, ".age")) == ".json" {
+			format = FormatJSON
+		}
+	}
+	values, err := parseObject(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", cfg.URL, err)
+	}
+
+	// Map keys according to configuration
+	kvs := make([]provider.KeyValue, 0, len(values))
+	for k, v := range values {
+		targetKey := k
+		if mappedKey, exists := keys[k]; exists {
+			if mappedKey != "==" {
+				targetKey = mappedKey
+			}
+		} else if len(keys) > 0 {
+			// Skip keys not in the mapping
+			continue
+		}
+		kvs = append(kvs, provider.KeyValue{Key: targetKey, Value: v})
+	}
+	return kvs, nil
+}
+
+// downloadS3 downloads an object from S3 with the AWS SDK default credential chain
+// Objects encrypted with SSE-S3 or SSE-KMS are decrypted by S3, given kms:Decrypt on the key
+func (p *BucketProvider) downloadS3(ctx context.Context, cfg *BucketConfig, bucket, key string) ([]byte, error) {
+	cfgOpts := []func(*awsconfig.LoadOptions) error{}
+	if cfg.Region != "" {
+		cfgOpts = append(cfgOpts, awsconfig.WithRegion(cfg.Region))
+	}
+	// When using a custom endpoint without credentials in the environment (e.g., LocalStack), use static credentials
+	if cfg.Endpoint != "" && os.Getenv("AWS_ACCESS_KEY_ID") == "" {
+		cfgOpts = append(cfgOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider("test", "test", ""),
+		))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, cfgOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if awsCfg.Region == "" {
+		awsCfg.Region = "us-east-1"
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+			// S3-compatible stores often return no checksums, which the SDK would warn about on every download
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		}
+	})
+	result, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
+
+// downloadGCS downloads an object from Google Cloud Storage with Application Default Credentials
+// Objects encrypted with Cloud KMS keys are decrypted by GCS
+func (p *BucketProvider) downloadGCS(ctx context.Context, cfg *BucketConfig, bucket, object string) ([]byte, error) {
+	opts := []option.ClientOption{option.WithScopes(storage.DevstorageReadOnlyScope)}
+	if cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(strings.TrimSuffix(cfg.Endpoint, "/")+"/storage/v1/"), option.WithoutAuthentication())
+	}
+	service, err := storage.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := service.Objects.Get(bucket, object).Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// decryptAge decrypts an age-encrypted (binary or armored) object with the configured identity
+func decryptAge(cfg *AgeConfig, data []byte) ([]byte, error) {
+	var identityData string
+	if cfg.IdentityFile != "" {
+		content, err := os.ReadFile(os.ExpandEnv(cfg.IdentityFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read age identity: %w", err)
+		}
+		identityData = string(content)
+	} else {
+		envName := cfg.IdentityEnv
+		if envName == "" {
+			envName = bundle.IdentityEnv
+		}
+		identityData = os.Getenv(envName)
+		if identityData == "" {
+			return nil, fmt.Errorf("no age identity: set 'age.identity_file' or $%s", envName)
+		}
+	}
+	identities, err := age.ParseIdentities(strings.NewReader(identityData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identity: %w", err)
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// parseObject parses data as a .env file or a flat JSON object
+func parseObject(data []byte, format string) (map[string]string, error) {
+	switch format {
+	case FormatDotenv:
+		return dotenv.Parse(data)
+	case FormatJSON:
+		var object map[string]interface{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+		values := make(map[string]string, len(object))
+		for k, v := range object {
+			values[k] = fmt.Sprintf("%v", v)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported format '%s' (supported: dotenv, json)", format)
+	}
+}
+
+// parseConfig converts a map[string]interface{} to BucketConfig
+func parseConfig(config map[string]interface{}) (*BucketConfig, error) {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var cfg BucketConfig
+	if err := json.Unmarshal(jsonData, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -1,12 +1,15 @@
 package doppler
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/dirathea/sstart/internal/provider"
@@ -36,6 +39,13 @@ type dopplerSecretsResponse struct {
 	Secrets map[string]dopplerSecretInfo `json:"secrets"`
 }
 
+// dopplerLogsResponse represents the response from the Doppler API config logs endpoint, newest first
+type dopplerLogsResponse struct {
+	Logs []struct {
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"logs"`
+}
+
 // DopplerProvider implements the provider interface for Doppler
 type DopplerProvider struct {
 	client *http.Client
@@ -56,6 +66,14 @@ func (p *DopplerProvider) Name() string {
 	return "doppler"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *DopplerProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *DopplerProvider) ConfigStruct() interface{} {
+	return &DopplerConfig{}
+}
+
 // Fetch fetches secrets from Doppler
 func (p *DopplerProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -84,33 +102,9 @@ func (p *DopplerProvider) Fetch(secretContext provider.SecretContext, mapID stri
 	apiURL := fmt.Sprintf("%s/v3/configs/config/secrets?project=%s&config=%s&include_managed_secrets=false",
 		apiHost, url.QueryEscape(cfg.Project), url.QueryEscape(cfg.Config))
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set authentication header
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceToken))
-	req.Header.Set("Accept", "application/json")
-
-	// Make HTTP request
-	resp, err := p.client.Do(req)
+	body, err := p.get(ctx, apiURL, serviceToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secrets from Doppler: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("doppler API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var response dopplerSecretsResponse
@@ -149,6 +143,180 @@ func (p *DopplerProvider) Fetch(secretContext provider.SecretContext, mapID stri
 	return kvs, nil
 }
 
+// FetchMetadata returns when the Doppler config last changed, from its most recent activity log
+// Doppler does not expose per-secret times, so the time applies to every key of the config
+func (p *DopplerProvider) FetchMetadata(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.SecretMetadata, error) {
+	cfg, err := validateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	serviceToken := os.Getenv("DOPPLER_TOKEN")
+	if serviceToken == "" {
+		return nil, fmt.Errorf("doppler provider requires 'DOPPLER_TOKEN' environment variable")
+	}
+	apiHost := cfg.APIHost
+	if apiHost == "" {
+		apiHost = "https://api.doppler.com"
+	}
+
+	apiURL := fmt.Sprintf("%s/v3/configs/config/logs?project=%s&config=%s&per_page=1",
+		apiHost, url.QueryEscape(cfg.Project), url.QueryEscape(cfg.Config))
+	body, err := p.get(secretContext.Ctx, apiURL, serviceToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var response dopplerLogsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if len(response.Logs) == 0 {
+		return nil, nil
+	}
+	return []provider.SecretMetadata{{UpdatedAt: response.Logs[0].CreatedAt}}, nil
+}
+
+// dopplerPollInterval is how often the secrets download is polled when the watch stream is unavailable
+const dopplerPollInterval = 15 * time.Second
+
+// Watch blocks until the secrets of the Doppler config change. It listens to Doppler's secrets
+// watch stream, and falls back to polling the secrets download with ETags when the stream is
+// unavailable, so unchanged secrets are never downloaded again
+func (p *DopplerProvider) Watch(secretContext provider.SecretContext, mapID string, config map[string]interface{}) error {
+	ctx := secretContext.Ctx
+	cfg, err := validateConfig(config)
+	if err != nil {
+		return err
+	}
+	serviceToken := os.Getenv("DOPPLER_TOKEN")
+	if serviceToken == "" {
+		return fmt.Errorf("doppler provider requires 'DOPPLER_TOKEN' environment variable")
+	}
+	apiHost := cfg.APIHost
+	if apiHost == "" {
+		apiHost = "https://api.doppler.com"
+	}
+	query := fmt.Sprintf("project=%s&config=%s", url.QueryEscape(cfg.Project), url.QueryEscape(cfg.Config))
+
+	err = p.watchStream(ctx, apiHost+"/v3/configs/config/secrets/watch?"+query, serviceToken)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	return p.pollDownload(ctx, apiHost+"/v3/configs/config/secrets/download?format=json&include_managed_secrets=false&"+query, serviceToken)
+}
+
+// watchStream reads the server-sent events of the watch endpoint until a secrets.update event,
+// reconnecting when the server closes the stream. It fails if the stream cannot be opened
+func (p *DopplerProvider) watchStream(ctx context.Context, apiURL, serviceToken string) error {
+	// The stream stays open for long, so it must not be cut by the client timeout
+	client := &http.Client{Transport: p.client.Transport}
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceToken))
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call the Doppler API: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("doppler API returned status %d: %s", resp.StatusCode, string(body))
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if event, ok := strings.CutPrefix(scanner.Text(), "event:"); ok && strings.TrimSpace(event) == "secrets.update" {
+				resp.Body.Close()
+				return nil
+			}
+		}
+		resp.Body.Close()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// pollDownload polls the secrets download until its ETag changes
+func (p *DopplerProvider) pollDownload(ctx context.Context, apiURL, serviceToken string) error {
+	etag := ""
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceToken))
+		req.Header.Set("Accept", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call the Doppler API: %w", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+		case http.StatusOK:
+			if etag != "" && resp.Header.Get("ETag") != etag {
+				return nil
+			}
+			etag = resp.Header.Get("ETag")
+			if etag == "" {
+				return fmt.Errorf("doppler API did not return an ETag for the secrets download")
+			}
+		default:
+			return fmt.Errorf("doppler API returned status %d", resp.StatusCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dopplerPollInterval):
+		}
+	}
+}
+
+// get sends an authenticated GET request to the Doppler API and returns the response body
+func (p *DopplerProvider) get(ctx context.Context, apiURL, serviceToken string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set authentication header
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceToken))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call the Doppler API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, provider.Classify(provider.StatusClass(resp.StatusCode), fmt.Errorf("doppler API returned status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
+
 // validateConfig parses and validates the Doppler configuration
 func validateConfig(config map[string]interface{}) (*DopplerConfig, error) {
 	// Parse config map to strongly typed struct
@@ -1,13 +1,42 @@
 package dotenv
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 
-	"github.com/joho/godotenv"
 	"github.com/dirathea/sstart/internal/provider"
 )
 
+// DotEnvConfig represents the configuration for the dotenv provider
+type DotEnvConfig struct {
+	// Path is the path to the .env file (environment variables are expanded)
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Paths are several .env files loaded in order, later files overriding earlier ones (instead of path)
+	Paths []DotEnvFile `json:"paths,omitempty" yaml:"paths,omitempty"`
+}
+
+// DotEnvFile is an entry of 'paths': a path, or an object with the path and whether it is optional
+type DotEnvFile struct {
+	// Path is the path to the .env file (environment variables are expanded)
+	Path string `json:"path" yaml:"path"`
+	// Optional skips the file if it does not exist instead of failing
+	Optional bool `json:"optional,omitempty" yaml:"optional,omitempty"`
+}
+
+// UnmarshalJSON accepts a path string as well as an object
+func (f *DotEnvFile) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		f.Path = path
+		return nil
+	}
+	type rawFile DotEnvFile
+	return json.Unmarshal(data, (*rawFile)(f))
+}
+
 // DotEnvProvider implements the provider interface for .env files
 type DotEnvProvider struct{}
 
@@ -22,21 +51,40 @@ func (p *DotEnvProvider) Name() string {
 	return "dotenv"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *DotEnvProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *DotEnvProvider) ConfigStruct() interface{} {
+	return &DotEnvConfig{}
+}
+
 // Fetch fetches secrets from a .env file
 func (p *DotEnvProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
-	// Extract path from config
-	path, ok := config["path"].(string)
-	if !ok || path == "" {
-		return nil, fmt.Errorf("dotenv provider requires 'path' field in configuration")
+	files, err := dotenvFiles(config)
+	if err != nil {
+		return nil, err
 	}
 
-	// Expand path if it contains environment variables
-	expandedPath := os.ExpandEnv(path)
+	// Load the .env files in order; later files override earlier ones and can reference their keys
+	envMap := make(map[string]string)
+	for _, file := range files {
+		// Expand path if it contains environment variables
+		expandedPath := os.Expand(file.Path, func(name string) string {
+			value, _ := secretContext.LookupEnv(name)
+			return value
+		})
 
-	// Load the .env file
-	envMap, err := godotenv.Read(expandedPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read .env file at '%s': %w", expandedPath, err)
+		data, err := os.ReadFile(expandedPath)
+		if err != nil {
+			if file.Optional && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read .env file at '%s': %w", expandedPath, err)
+		}
+		if err := parse(data, envMap, secretContext.LookupEnv); err != nil {
+			return nil, fmt.Errorf("failed to parse .env file at '%s': %w", expandedPath, err)
+		}
 	}
 
 	// If no keys specified, return all
@@ -68,3 +116,31 @@ func (p *DotEnvProvider) Fetch(secretContext provider.SecretContext, mapID strin
 	return kvs, nil
 }
 
+// dotenvFiles returns the files to load, from either 'path' or 'paths'
+func dotenvFiles(config map[string]interface{}) ([]DotEnvFile, error) {
+	if paths, ok := config["paths"]; ok {
+		jsonData, err := json.Marshal(paths)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dotenv configuration: %w", err)
+		}
+		var files []DotEnvFile
+		if err := json.Unmarshal(jsonData, &files); err != nil {
+			return nil, fmt.Errorf("invalid dotenv configuration: 'paths' must be a list of paths or {path, optional} objects")
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("dotenv provider requires at least one entry in 'paths'")
+		}
+		for _, file := range files {
+			if file.Path == "" {
+				return nil, fmt.Errorf("dotenv provider requires 'path' for every entry in 'paths'")
+			}
+		}
+		return files, nil
+	}
+
+	path, ok := config["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("dotenv provider requires 'path' field in configuration (or 'paths')")
+	}
+	return []DotEnvFile{{Path: path}}, nil
+}
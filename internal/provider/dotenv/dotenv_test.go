@@ -282,3 +282,259 @@ func containsSubstring(s, substr string) bool {
 	return false
 }
 
+func TestParse(t *testing.T) {
+	t.Setenv("SSTART_DOTENV_TEST_HOST", "from-env")
+
+	content := `# comment
+export EXPORTED=yes
+PLAIN=value # inline comment
+HASH=abc#def
+SPACED = spaced value  
+EMPTY=
+DQ="double # not a comment" # comment
+SQ='single ${PLAIN} \n raw'
+BACKTICK=` + "`backtick ${PLAIN}`" + `
+ESC="tab\there \"quoted\" back\\slash \$PLAIN nl\nend"
+MULTI="line1
+line2"
+MULTISQ='a
+b'
+REF=${PLAIN}-$EXPORTED
+REFQ="pre-${HASH}"
+HOST=$SSTART_DOTENV_TEST_HOST
+DEFAULT=${SSTART_DOTENV_TEST_UNSET:-fallback}
+DEFAULT_EMPTY=${EMPTY:-fallback}
+DEFAULT_SET=${EMPTY-fallback}
+DOLLAR=cost $5
+`
+	want := map[string]string{
+		"EXPORTED":      "yes",
+		"PLAIN":         "value",
+		"HASH":          "abc#def",
+		"SPACED":        "spaced value",
+		"EMPTY":         "",
+		"DQ":            "double # not a comment",
+		"SQ":            `single ${PLAIN} \n raw`,
+		"BACKTICK":      "backtick ${PLAIN}",
+		"ESC":           "tab\there \"quoted\" back\\slash $PLAIN nl\nend",
+		"MULTI":         "line1\nline2",
+		"MULTISQ":       "a\nb",
+		"REF":           "value-yes",
+		"REFQ":          "pre-abc#def",
+		"HOST":          "from-env",
+		"DEFAULT":       "fallback",
+		"DEFAULT_EMPTY": "fallback",
+		"DEFAULT_SET":   "",
+		"DOLLAR":        "cost $5",
+	}
+
+	got, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Parse() returned %d keys, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{name: "unterminated quote", content: "A=1\nB=\"open\nC=2\n", errMsg: "line 2: value of 'B': unterminated quoted value"},
+		{name: "missing equals", content: "A=1\nJUST_A_KEY\n", errMsg: "line 2: expected '=' after key 'JUST_A_KEY'"},
+		{name: "text after quotes", content: "A='x' y\n", errMsg: "line 1: value of 'A': unexpected characters after quoted value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if err == nil || err.Error() != tt.errMsg {
+				t.Errorf("Parse() error = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDotEnvProvider_Fetch_MultiplePaths(t *testing.T) {
+	provider := &DotEnvProvider{}
+
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	localFile := filepath.Join(tmpDir, ".env.local")
+	if err := os.WriteFile(envFile, []byte("HOST=localhost\nPORT=5432\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test .env file: %v", err)
+	}
+	if err := os.WriteFile(localFile, []byte("PORT=6543\nURL=${HOST}:${PORT}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test .env.local file: %v", err)
+	}
+
+	config := map[string]interface{}{
+		"paths": []interface{}{
+			envFile,
+			map[string]interface{}{"path": localFile, "optional": true},
+			map[string]interface{}{"path": filepath.Join(tmpDir, ".env.missing"), "optional": true},
+		},
+	}
+
+	secretContext := secrets.NewEmptySecretContext(context.Background())
+	result, err := provider.Fetch(secretContext, "test-map", config, nil)
+	if err != nil {
+		t.Fatalf("DotEnvProvider.Fetch() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, kv := range result {
+		got[kv.Key] = kv.Value
+	}
+	want := map[string]string{"HOST": "localhost", "PORT": "6543", "URL": "localhost:6543"}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d key-value pairs, got %v", len(want), got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Key %s: got value %q, want %q", key, got[key], value)
+		}
+	}
+
+	// A missing file that is not optional is an error
+	config["paths"] = []interface{}{envFile, filepath.Join(tmpDir, ".env.missing")}
+	if _, err := provider.Fetch(secretContext, "test-map", config, nil); err == nil || !containsSubstring(err.Error(), "failed to read .env file") {
+		t.Errorf("DotEnvProvider.Fetch() error = %v, want a read error", err)
+	}
+}
+
+func TestDotEnvProvider_Write(t *testing.T) {
+	provider := &DotEnvProvider{}
+	secretContext := secrets.NewEmptySecretContext(context.Background())
+
+	tests := []struct {
+		name    string
+		content string
+		secrets map[string]string
+		want    string
+	}{
+		{
+			name:    "new file",
+			secrets: map[string]string{"B": "two words", "A": "1"},
+			want:    "A=1\nB='two words'\n",
+		},
+		{
+			name:    "keeps comments and order",
+			content: "# Database\nDB_HOST=localhost # local only\nexport DB_PASS=\"old\"\n\n# API\nAPI_KEY='old'\n",
+			secrets: map[string]string{"DB_PASS": "it's new", "API_KEY": "new", "ADDED": "$HOME"},
+			want:    "# Database\nDB_HOST=localhost # local only\nexport DB_PASS=\"it's new\"\n\n# API\nAPI_KEY=new\nADDED='$HOME'\n",
+		},
+		{
+			name:    "keeps inline comments",
+			content: "HOST=old   # the host\nEMPTY= # not set yet\n",
+			secrets: map[string]string{"HOST": "new", "EMPTY": "set"},
+			want:    "HOST=new   # the host\nEMPTY= set # not set yet\n",
+		},
+		{
+			name:    "replaces the last assignment",
+			content: "KEY=first\nKEY=second\n",
+			secrets: map[string]string{"KEY": "third"},
+			want:    "KEY=first\nKEY=third\n",
+		},
+		{
+			name:    "multi-line value",
+			content: "CERT=\"line1\nline2\"\nOTHER=x",
+			secrets: map[string]string{"CERT": "new1\nnew2", "ADDED": "y"},
+			want:    "CERT=\"new1\\nnew2\"\nOTHER=x\nADDED=y\n",
+		},
+		{
+			name:    "CRLF line endings",
+			content: "# comment\r\nKEY=old\r\n",
+			secrets: map[string]string{"KEY": "new", "ADDED": "y"},
+			want:    "# comment\r\nKEY=new\r\nADDED=y\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("Failed to write .env file: %v", err)
+				}
+			}
+			config := map[string]interface{}{"path": path}
+			if err := provider.Write(secretContext, "test", config, tt.secrets); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read .env file: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Write() wrote %q, want %q", data, tt.want)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat .env file: %v", err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+			}
+
+			// The written values are read back as they were written
+			kvs, err := provider.Fetch(secretContext, "test", config, nil)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			fetched := make(map[string]string)
+			for _, kv := range kvs {
+				fetched[kv.Key] = kv.Value
+			}
+			for key, value := range tt.secrets {
+				if fetched[key] != value {
+					t.Errorf("Fetch() %s = %q, want %q", key, fetched[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestDotEnvProvider_Write_Errors(t *testing.T) {
+	provider := &DotEnvProvider{}
+	secretContext := secrets.NewEmptySecretContext(context.Background())
+	dir := t.TempDir()
+
+	// With paths, the last file is written
+	first := filepath.Join(dir, ".env")
+	last := filepath.Join(dir, ".env.local")
+	config := map[string]interface{}{"paths": []interface{}{first, map[string]interface{}{"path": last, "optional": true}}}
+	if err := provider.Write(secretContext, "test", config, map[string]string{"KEY": "value"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("Expected %s not to be written, got %v", first, err)
+	}
+	if data, _ := os.ReadFile(last); string(data) != "KEY=value\n" {
+		t.Errorf("Expected %s to be written, got %q", last, data)
+	}
+
+	if err := provider.Write(secretContext, "test", map[string]interface{}{"path": last}, map[string]string{"BAD KEY": "x"}); err == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+
+	// A file that cannot be parsed is not replaced
+	broken := filepath.Join(dir, ".env.broken")
+	if err := os.WriteFile(broken, []byte("KEY=\"unterminated\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	if err := provider.Write(secretContext, "test", map[string]interface{}{"path": broken}, map[string]string{"KEY": "x"}); err == nil {
+		t.Error("Expected an error for a file that cannot be parsed")
+	}
+	if data, _ := os.ReadFile(broken); string(data) != "KEY=\"unterminated\n" {
+		t.Errorf("Expected the broken file to be kept, got %q", data)
+	}
+}
@@ -0,0 +1,298 @@
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Parse parses the content of a .env file. Besides KEY=value lines and # comments, it supports
+// an optional 'export' prefix, inline comments after unquoted values, single-, double- and
+// backtick-quoted values spanning several lines, escape sequences in double quotes, and $VAR,
+// ${VAR} and ${VAR:-default} expansion of earlier keys and of the environment
+func Parse(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	if err := parse(data, values, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parse parses a .env file into values, where keys already set can be referenced and are overridden,
+// and other references are looked up with lookupEnv
+func parse(data []byte, values map[string]string, lookupEnv func(string) (string, bool)) error {
+	_, err := parseAssignments(strings.ReplaceAll(string(data), "\r\n", "\n"), values, lookupEnv)
+	return err
+}
+
+// parseAssignments parses the source of a .env file with LF line endings into values, and returns
+// where the value of each assignment is, in order
+func parseAssignments(src string, values map[string]string, lookupEnv func(string) (string, bool)) ([]assignmentSpan, error) {
+	p := &parser{src: src, line: 1, values: values, lookupEnv: lookupEnv}
+	for {
+		p.skipBlankLines()
+		if p.eof() {
+			return p.spans, nil
+		}
+		line := p.line
+		key, value, err := p.assignment()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p.values[key] = value
+	}
+}
+
+type parser struct {
+	src    string
+	pos    int
+	line   int
+	values map[string]string
+	// lookupEnv looks up the environment variables referenced by values
+	lookupEnv func(string) (string, bool)
+	// spans locates the value of each assignment, and valueEnd the end of the value being parsed
+	spans    []assignmentSpan
+	valueEnd int
+}
+
+// assignmentSpan locates the value of an assignment in a .env file, quotes included
+type assignmentSpan struct {
+	key        string
+	start, end int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *parser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlankLines skips whitespace and comment lines
+func (p *parser) skipBlankLines() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n', '\r':
+			p.next()
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+// skipLine skips the rest of the current line, including the newline
+func (p *parser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+// endLine expects only whitespace or a comment until the end of the line
+func (p *parser) endLine() error {
+	p.skipSpaces()
+	switch p.peek() {
+	case 0, '\n', '\r':
+	case '#':
+	default:
+		return fmt.Errorf("unexpected characters after quoted value")
+	}
+	p.skipLine()
+	return nil
+}
+
+// assignment parses a KEY=value line
+func (p *parser) assignment() (string, string, error) {
+	if strings.HasPrefix(p.src[p.pos:], "export") && len(p.src) > p.pos+6 && (p.src[p.pos+6] == ' ' || p.src[p.pos+6] == '\t') {
+		p.pos += 6
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for !p.eof() && isKeyChar(p.peek()) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return "", "", fmt.Errorf("expected a key")
+	}
+	p.skipSpaces()
+	if c := p.peek(); c != '=' && c != ':' {
+		return "", "", fmt.Errorf("expected '=' after key '%s'", key)
+	}
+	p.pos++
+	p.skipSpaces()
+
+	valueStart := p.pos
+	var value string
+	var err error
+	switch p.peek() {
+	case '\'', '`':
+		value, err = p.literal(p.next())
+	case '"':
+		p.next()
+		value, err = p.doubleQuoted()
+	default:
+		value = p.unquoted()
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("value of '%s': %w", key, err)
+	}
+	p.spans = append(p.spans, assignmentSpan{key: key, start: valueStart, end: p.valueEnd})
+	return key, value, nil
+}
+
+// literal parses a single- or backtick-quoted value, which is used as is
+func (p *parser) literal(quote byte) (string, error) {
+	start := p.pos
+	for !p.eof() {
+		if p.peek() == quote {
+			value := p.src[start:p.pos]
+			p.next()
+			p.valueEnd = p.pos
+			return value, p.endLine()
+		}
+		p.next()
+	}
+	return "", fmt.Errorf("unterminated quoted value")
+}
+
+// doubleQuoted parses a double-quoted value with escape sequences and expansion
+func (p *parser) doubleQuoted() (string, error) {
+	var b strings.Builder
+	for !p.eof() {
+		c := p.next()
+		switch c {
+		case '"':
+			p.valueEnd = p.pos
+			return b.String(), p.endLine()
+		case '\\':
+			if p.eof() {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			switch e := p.next(); e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$', '`', '\'':
+				b.WriteByte(e)
+			case '\n':
+				// A line continuation
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		case '$':
+			b.WriteString(p.expand())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted value")
+}
+
+// unquoted parses the rest of the line as a value, without an inline comment after whitespace
+func (p *parser) unquoted() string {
+	start := p.pos
+	var b strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		if c == '\n' {
+			break
+		}
+		if c == '#' && p.pos > 0 && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.next()
+		if c == '$' {
+			b.WriteString(p.expand())
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	p.valueEnd = p.pos
+	for p.valueEnd > start && strings.IndexByte(" \t\r", p.src[p.valueEnd-1]) >= 0 {
+		p.valueEnd--
+	}
+	p.skipLine()
+	return strings.TrimSpace(b.String())
+}
+
+// expand returns the value of the $VAR, ${VAR}, ${VAR:-default} or ${VAR-default} reference after
+// a '$', or '$' itself if none follows
+func (p *parser) expand() string {
+	if p.peek() != '{' {
+		start := p.pos
+		if c := p.peek(); c >= '0' && c <= '9' {
+			return "$"
+		}
+		for !p.eof() && isNameChar(p.peek()) {
+			p.pos++
+		}
+		if start == p.pos {
+			return "$"
+		}
+		value, _ := p.lookup(p.src[start:p.pos])
+		return value
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end < 0 || strings.ContainsRune(p.src[p.pos:p.pos+end], '\n') {
+		return "$"
+	}
+	ref := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	if name, fallback, ok := strings.Cut(ref, ":-"); ok {
+		if value, _ := p.lookup(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+	if name, fallback, ok := strings.Cut(ref, "-"); ok {
+		if value, set := p.lookup(name); set {
+			return value
+		}
+		return fallback
+	}
+	value, _ := p.lookup(ref)
+	return value
+}
+
+// lookup returns the value of an earlier key of the file, or else of an environment variable
+func (p *parser) lookup(name string) (string, bool) {
+	if value, ok := p.values[name]; ok {
+		return value, true
+	}
+	return p.lookupEnv(name)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isKeyChar(c byte) bool {
+	return isNameChar(c) || c == '.' || c == '-'
+}
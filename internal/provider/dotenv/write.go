@@ -0,0 +1,151 @@
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// Quote single-quotes values that the dotenv provider would otherwise parse differently, and
+// double-quotes those with single quotes or newlines
+func Quote(value string) string {
+	if !strings.ContainsAny(value, " \t\n\r#'\"`$\\") {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// Write adds secrets to the .env file, replacing the values of the keys already there in place
+// and appending the others, so that comments and the order of the file are kept. With 'paths',
+// the last file is written, since it overrides the others. The file is replaced atomically and
+// left with mode 0600
+func (p *DotEnvProvider) Write(secretContext provider.SecretContext, mapID string, config map[string]interface{}, secrets map[string]string) error {
+	files, err := dotenvFiles(config)
+	if err != nil {
+		return err
+	}
+	path := os.Expand(files[len(files)-1].Path, func(name string) string {
+		value, _ := secretContext.LookupEnv(name)
+		return value
+	})
+	for key := range secrets {
+		if !validKey(key) {
+			return fmt.Errorf("cannot write key '%s' to a .env file: keys may only contain letters, digits, '_', '.' and '-'", key)
+		}
+	}
+
+	// Replace the file a symlink points to, not the symlink
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read .env file at '%s': %w", path, err)
+	}
+	updated, err := update(data, secrets)
+	if err != nil {
+		return fmt.Errorf("failed to parse .env file at '%s': %w", path, err)
+	}
+	return writeAtomic(path, updated)
+}
+
+// update returns the content of a .env file with the secrets set. CRLF line endings are kept
+func update(data []byte, secrets map[string]string) ([]byte, error) {
+	crlf := strings.Contains(string(data), "\r\n")
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+	// Only the positions of the values are used, so references need not be looked up
+	assignments, err := parseAssignments(src, make(map[string]string), func(string) (string, bool) { return "", false })
+	if err != nil {
+		return nil, err
+	}
+
+	// The last assignment of a key is the one loaded
+	spans := make(map[string]assignmentSpan)
+	for _, span := range assignments {
+		spans[span.key] = span
+	}
+	var replaced []assignmentSpan
+	var added []string
+	for key := range secrets {
+		if span, ok := spans[key]; ok {
+			replaced = append(replaced, span)
+		} else {
+			added = append(added, key)
+		}
+	}
+
+	// Replace values from the end, so the offsets of earlier ones stay valid
+	sort.Slice(replaced, func(i, j int) bool { return replaced[i].start > replaced[j].start })
+	for _, span := range replaced {
+		value := Quote(secrets[span.key])
+		if span.end < len(src) && src[span.end] == '#' {
+			// Keep an inline comment that directly followed an empty value
+			value += " "
+		}
+		src = src[:span.start] + value + src[span.end:]
+	}
+
+	sort.Strings(added)
+	var b strings.Builder
+	b.WriteString(src)
+	if len(added) > 0 && src != "" && !strings.HasSuffix(src, "\n") {
+		b.WriteByte('\n')
+	}
+	for _, key := range added {
+		fmt.Fprintf(&b, "%s=%s\n", key, Quote(secrets[key]))
+	}
+
+	out := b.String()
+	if crlf {
+		out = strings.ReplaceAll(out, "\n", "\r\n")
+	}
+	return []byte(out), nil
+}
+
+// writeAtomic replaces the file at path with data, through a temporary file in the same directory
+// so that readers never see a partial file
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write .env file at '%s': %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write .env file at '%s': %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write .env file at '%s': %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write .env file at '%s': %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write .env file at '%s': %w", path, err)
+	}
+	return nil
+}
+
+// validKey reports whether a key can be written to a .env file and read back
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
+}
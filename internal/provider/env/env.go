@@ -0,0 +1,108 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// EnvConfig represents the configuration for the env provider
+type EnvConfig struct {
+	// Vars lists the host environment variables to select, by name or glob pattern, e.g. AWS_* (optional if keys is set)
+	Vars []string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	// StripPrefix removes a prefix from the names of variables selected by vars, e.g. APP_ exports APP_PORT as PORT (optional)
+	StripPrefix string `json:"strip_prefix,omitempty" yaml:"strip_prefix,omitempty"`
+}
+
+// EnvProvider implements the provider interface for variables of the host environment
+type EnvProvider struct{}
+
+func init() {
+	provider.Register("env", func() provider.Provider {
+		return &EnvProvider{}
+	})
+}
+
+// Name returns the provider name
+func (p *EnvProvider) Name() string {
+	return "env"
+}
+
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *EnvProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *EnvProvider) ConfigStruct() interface{} {
+	return &EnvConfig{}
+}
+
+// SkipCache reports that host environment variables are never cached, since they are read
+// from the environment sstart runs in
+func (p *EnvProvider) SkipCache() bool {
+	return true
+}
+
+// Fetch selects variables of the host environment matching vars, and the variables named in keys
+// Variables named in keys are renamed by the mapping; others selected by vars have strip_prefix removed
+func (p *EnvProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid env configuration: %w", err)
+	}
+	if len(cfg.Vars) == 0 && len(keys) == 0 {
+		return nil, fmt.Errorf("env provider requires 'vars' or 'keys' field in configuration")
+	}
+	for _, pattern := range cfg.Vars {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid env var pattern '%s': %w", pattern, err)
+		}
+	}
+
+	kvs := make([]provider.KeyValue, 0)
+	for _, entry := range secretContext.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if mappedKey, exists := keys[name]; exists {
+			if mappedKey == "==" {
+				mappedKey = name // Keep same name
+			}
+			kvs = append(kvs, provider.KeyValue{Key: mappedKey, Value: value})
+			continue
+		}
+		if !matchAny(cfg.Vars, name) {
+			continue
+		}
+		key := strings.TrimPrefix(name, cfg.StripPrefix)
+		if key == "" {
+			continue
+		}
+		kvs = append(kvs, provider.KeyValue{Key: key, Value: value})
+	}
+	return kvs, nil
+}
+
+// matchAny reports whether name matches any of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfig converts a map[string]interface{} to EnvConfig
+func parseConfig(config map[string]interface{}) (*EnvConfig, error) {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var cfg EnvConfig
+	if err := json.Unmarshal(jsonData, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+)
+
+// Classes of provider errors. Providers mark their errors with Classify, and the collector and
+// CLI use ErrorClass to decide whether to retry and what to tell the user
+var (
+	// ErrAuth means the backend rejected the credentials, or they lack access to the secret
+	ErrAuth = errors.New("authentication failed")
+	// ErrNotFound means the secret, path or file does not exist
+	ErrNotFound = errors.New("secret not found")
+	// ErrRateLimited means the backend throttled the requests
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNetwork means the backend could not be reached
+	ErrNetwork = errors.New("network error")
+)
+
+// errorClasses are the classes of provider errors, in the order they are checked
+var errorClasses = []error{ErrAuth, ErrNotFound, ErrRateLimited, ErrNetwork}
+
+// classifiedError is an error marked with a class, keeping the message of the error
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+
+func (e *classifiedError) Unwrap() []error { return []error{e.err, e.class} }
+
+// Classify marks err with one of the error classes, so that errors.Is(err, class) is true,
+// without changing its message. A nil err or class returns err unchanged
+func Classify(class, err error) error {
+	if err == nil || class == nil {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// StatusClass returns the error class of an HTTP status code, or nil if it has none
+func StatusClass(statusCode int) error {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrNetwork
+	}
+	return nil
+}
+
+// ErrorHint returns how to fix a provider error, for errors that know better than their class,
+// such as which credentials a provider looked for. It returns "" if no error in the chain of err
+// has a Hint() string method
+func ErrorHint(err error) string {
+	var hinted interface{ Hint() string }
+	if errors.As(err, &hinted) {
+		return hinted.Hint()
+	}
+	return ""
+}
+
+// ErrorClass returns the class of a provider error: the class it was marked with, or one
+// inferred from well-known errors in its chain (missing files, network errors, and SDK errors
+// carrying an HTTP status code). It returns nil if the error has no known class
+func ErrorClass(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		if class := StatusClass(statusErr.HTTPStatusCode()); class != nil {
+			return class
+		}
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotFound
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return ErrNetwork
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+type statusError struct{ code int }
+
+func (e *statusError) Error() string       { return fmt.Sprintf("status %d", e.code) }
+func (e *statusError) HTTPStatusCode() int { return e.code }
+
+func TestErrorClass(t *testing.T) {
+	_, missingFileErr := os.ReadFile("/nonexistent/.env")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"unclassified", errors.New("boom"), nil},
+		{"classified", Classify(ErrRateLimited, errors.New("slow down")), ErrRateLimited},
+		{"wrapped classified", fmt.Errorf("fetch: %w", Classify(ErrAuth, errors.New("denied"))), ErrAuth},
+		{"missing file", fmt.Errorf("failed to read: %w", missingFileErr), ErrNotFound},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrNetwork},
+		{"status 403", fmt.Errorf("api: %w", &statusError{403}), ErrAuth},
+		{"status 429", &statusError{429}, ErrRateLimited},
+		{"status 500", &statusError{500}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorClass(tt.err); got != tt.want {
+				t.Errorf("ErrorClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify_KeepsMessage(t *testing.T) {
+	err := Classify(ErrNotFound, errors.New("secret 'db' does not exist"))
+	if err.Error() != "secret 'db' does not exist" {
+		t.Errorf("Error() = %q, want the original message", err.Error())
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("expected errors.Is(err, ErrNotFound)")
+	}
+	if Classify(ErrAuth, nil) != nil {
+		t.Error("expected Classify of a nil error to be nil")
+	}
+}
@@ -10,11 +10,41 @@ import (
 	"cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/dirathea/sstart/internal/provider"
+	"golang.org/x/oauth2/google/externalaccount"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	// AuthMethodDefault uses Application Default Credentials
+	AuthMethodDefault = "default"
+	// AuthMethodSSO exchanges the sstart SSO ID token via workload identity federation
+	AuthMethodSSO = "sso"
+
+	// DefaultSTSTokenURL is the Google STS token exchange endpoint
+	DefaultSTSTokenURL = "https://sts.googleapis.com/v1/token"
+	// jwtSubjectTokenType is the token type of an OIDC ID token
+	jwtSubjectTokenType = "urn:ietf:params:oauth:token-type:jwt"
+	// cloudPlatformScope is the OAuth scope required to access Secret Manager
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// GCSMAuthConfig represents authentication configuration for Google Cloud
+type GCSMAuthConfig struct {
+	// Method specifies the authentication method: "default" (ADC) or "sso"
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Audience is the full resource name of the workload identity pool provider (required when using sso auth)
+	// Format: //iam.googleapis.com/projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider>
+	Audience string `json:"audience,omitempty" yaml:"audience,omitempty"`
+	// ServiceAccount is an optional service account email to impersonate after the token exchange
+	ServiceAccount string `json:"service_account,omitempty" yaml:"service_account,omitempty"`
+	// TokenURL overrides the STS token exchange endpoint (optional)
+	TokenURL string `json:"token_url,omitempty" yaml:"token_url,omitempty"`
+}
+
 // GCSMConfig represents the configuration for Google Cloud Secret Manager provider
 type GCSMConfig struct {
 	// ProjectID is the GCP project ID where the secret is stored (required)
@@ -25,6 +55,11 @@ type GCSMConfig struct {
 	Version string `json:"version,omitempty" yaml:"version,omitempty"`
 	// Endpoint is a custom endpoint URL for GCSM (optional, for local testing/emulator)
 	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Auth contains authentication configuration (optional, defaults to Application Default Credentials)
+	Auth *GCSMAuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	// Internal: SSO ID token injected by the collector
+	SSOIDToken string `json:"-" yaml:"-"`
 }
 
 // GCSMProvider implements the provider interface for Google Cloud Secret Manager
@@ -43,6 +78,23 @@ func (p *GCSMProvider) Name() string {
 	return "gcloud_secretmanager"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *GCSMProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *GCSMProvider) ConfigStruct() interface{} {
+	return &GCSMConfig{}
+}
+
+// UsesSSO reports whether the configuration uses sso auth, which needs the collector's SSO tokens
+func (p *GCSMProvider) UsesSSO(config map[string]interface{}) bool {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return false
+	}
+	return cfg.Auth != nil && strings.ToLower(cfg.Auth.Method) == AuthMethodSSO
+}
+
 // Fetch fetches secrets from Google Cloud Secret Manager
 func (p *GCSMProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -60,7 +112,7 @@ func (p *GCSMProvider) Fetch(secretContext provider.SecretContext, mapID string,
 		return nil, fmt.Errorf("gcloud_secretmanager provider requires 'secret_id' field in configuration")
 	}
 
-	if err := p.ensureClient(ctx, cfg.Endpoint); err != nil {
+	if err := p.ensureClient(ctx, cfg); err != nil {
 		return nil, fmt.Errorf("failed to initialize GCSM client: %w", err)
 	}
 
@@ -80,7 +132,7 @@ func (p *GCSMProvider) Fetch(secretContext provider.SecretContext, mapID string,
 
 	result, err := p.client.AccessSecretVersion(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch secret from Google Cloud Secret Manager: %w", err)
+		return nil, classifyError(fmt.Errorf("failed to fetch secret from Google Cloud Secret Manager: %w", err))
 	}
 
 	// Parse the secret value (assuming JSON format)
@@ -125,16 +177,40 @@ func (p *GCSMProvider) Fetch(secretContext provider.SecretContext, mapID string,
 	return kvs, nil
 }
 
-func (p *GCSMProvider) ensureClient(ctx context.Context, endpoint string) error {
+func (p *GCSMProvider) ensureClient(ctx context.Context, cfg *GCSMConfig) error {
 	if p.client != nil {
 		return nil
 	}
 
+	endpoint := cfg.Endpoint
+
+	// Determine auth method
+	authMethod := AuthMethodDefault
+	if cfg.Auth != nil && cfg.Auth.Method != "" {
+		authMethod = strings.ToLower(cfg.Auth.Method)
+	}
+
 	// Build client options
 	opts := []option.ClientOption{}
 
+	switch authMethod {
+	case AuthMethodSSO:
+		tokenOpt, err := ssoTokenSourceOption(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, tokenOpt)
+		if endpoint != "" {
+			opts = append(opts, option.WithEndpoint(endpoint))
+		}
+	case AuthMethodDefault:
+		// Handled below
+	default:
+		return fmt.Errorf("unsupported auth method: %s (supported: default, sso)", authMethod)
+	}
+
 	// If using a custom endpoint (e.g., emulator), configure it
-	if endpoint != "" {
+	if endpoint != "" && authMethod == AuthMethodDefault {
 		opts = append(opts, option.WithEndpoint(endpoint))
 		opts = append(opts, option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
 		// For emulator, we don't need real credentials
@@ -155,6 +231,65 @@ func (p *GCSMProvider) ensureClient(ctx context.Context, endpoint string) error
 	return nil
 }
 
+// ssoTokenSourceOption builds a client option that exchanges the SSO ID token for Google
+// credentials via workload identity federation, optionally impersonating a service account
+func ssoTokenSourceOption(ctx context.Context, cfg *GCSMConfig) (option.ClientOption, error) {
+	if cfg.SSOIDToken == "" {
+		return nil, fmt.Errorf("gcloud sso authentication requires SSO to be configured - no SSO ID token available")
+	}
+	if cfg.Auth.Audience == "" {
+		return nil, fmt.Errorf("gcloud sso authentication requires 'auth.audience' field in configuration")
+	}
+
+	tokenURL := cfg.Auth.TokenURL
+	if tokenURL == "" {
+		tokenURL = DefaultSTSTokenURL
+	}
+
+	externalCfg := externalaccount.Config{
+		Audience:             cfg.Auth.Audience,
+		SubjectTokenType:     jwtSubjectTokenType,
+		TokenURL:             tokenURL,
+		Scopes:               []string{cloudPlatformScope},
+		SubjectTokenSupplier: ssoTokenSupplier(cfg.SSOIDToken),
+	}
+	if cfg.Auth.ServiceAccount != "" {
+		externalCfg.ServiceAccountImpersonationURL = fmt.Sprintf(
+			"https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken",
+			cfg.Auth.ServiceAccount)
+	}
+
+	tokenSource, err := externalaccount.NewTokenSource(ctx, externalCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workload identity federation token source: %w", err)
+	}
+
+	return option.WithTokenSource(tokenSource), nil
+}
+
+// ssoTokenSupplier supplies the SSO ID token as the subject token for the STS exchange
+type ssoTokenSupplier string
+
+// SubjectToken implements externalaccount.SubjectTokenSupplier
+func (t ssoTokenSupplier) SubjectToken(ctx context.Context, options externalaccount.SupplierOptions) (string, error) {
+	return string(t), nil
+}
+
+// classifyError marks a gRPC error of Secret Manager with the provider error class of its code
+func classifyError(err error) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return provider.Classify(provider.ErrAuth, err)
+	case codes.NotFound:
+		return provider.Classify(provider.ErrNotFound, err)
+	case codes.ResourceExhausted:
+		return provider.Classify(provider.ErrRateLimited, err)
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return provider.Classify(provider.ErrNetwork, err)
+	}
+	return err
+}
+
 // parseConfig converts a map[string]interface{} to GCSMConfig
 func parseConfig(config map[string]interface{}) (*GCSMConfig, error) {
 	// Use JSON marshaling/unmarshaling for clean conversion
@@ -168,6 +303,11 @@ func parseConfig(config map[string]interface{}) (*GCSMConfig, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Extract SSO ID token from the config map (injected by the collector)
+	if idToken, ok := config["_sso_id_token"].(string); ok {
+		cfg.SSOIDToken = idToken
+	}
+
 	return &cfg, nil
 }
 
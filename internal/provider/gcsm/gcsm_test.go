@@ -75,7 +75,9 @@ func TestParseConfig(t *testing.T) {
 				"project_id": "",
 				"secret_id":  "my-secret",
 			},
-			wantErr: false, // parseConfig doesn't validate, Fetch does
+			wantProjectID: "",
+			wantSecretID:  "my-secret",
+			wantErr:       false, // parseConfig doesn't validate, Fetch does
 		},
 		{
 			name: "config with missing project_id field",
@@ -158,6 +160,32 @@ func TestGCSMProvider_Fetch_ConfigValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "gcloud_secretmanager provider requires 'secret_id' field",
 		},
+		{
+			name: "sso auth without SSO token",
+			config: map[string]interface{}{
+				"project_id": "my-project",
+				"secret_id":  "my-secret",
+				"auth": map[string]interface{}{
+					"method":   "sso",
+					"audience": "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/sstart",
+				},
+			},
+			wantErr: true,
+			errMsg:  "no SSO ID token available",
+		},
+		{
+			name: "sso auth without audience",
+			config: map[string]interface{}{
+				"project_id":    "my-project",
+				"secret_id":     "my-secret",
+				"_sso_id_token": "id-token",
+				"auth": map[string]interface{}{
+					"method": "sso",
+				},
+			},
+			wantErr: true,
+			errMsg:  "requires 'auth.audience'",
+		},
 	}
 
 	for _, tt := range tests {
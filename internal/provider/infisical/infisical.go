@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/dirathea/sstart/internal/provider"
 	infisical "github.com/infisical/go-sdk"
@@ -20,6 +21,12 @@ type InfisicalConfig struct {
 	Path string `json:"path" yaml:"path"`
 	// Recursive indicates whether to fetch secrets recursively from subdirectories (optional, default: false)
 	Recursive *bool `json:"recursive,omitempty" yaml:"recursive,omitempty"`
+	// FolderPrefix prefixes the keys of secrets in subfolders with their folder path relative to path,
+	// e.g. HOST in /db becomes db_HOST, so that keys in different folders do not overwrite each other
+	// (optional, requires recursive, default: false)
+	FolderPrefix *bool `json:"folder_prefix,omitempty" yaml:"folder_prefix,omitempty"`
+	// FolderSeparator joins the folder names and the key when folder_prefix is set (optional, default: "_")
+	FolderSeparator string `json:"folder_separator,omitempty" yaml:"folder_separator,omitempty"`
 	// IncludeImports specifies whether to include imported secrets (optional, default: false)
 	IncludeImports *bool `json:"include_imports,omitempty" yaml:"include_imports,omitempty"`
 	// ExpandSecrets determines whether to expand secret references (optional, default: false)
@@ -42,6 +49,14 @@ func (p *InfisicalProvider) Name() string {
 	return "infisical"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *InfisicalProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *InfisicalProvider) ConfigStruct() interface{} {
+	return &InfisicalConfig{}
+}
+
 // Fetch fetches secrets from Infisical
 func (p *InfisicalProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -83,6 +98,15 @@ func (p *InfisicalProvider) Fetch(secretContext provider.SecretContext, mapID st
 		expandSecrets = *cfg.ExpandSecrets
 	}
 
+	folderPrefix := cfg.FolderPrefix != nil && *cfg.FolderPrefix
+	if folderPrefix && !recursive {
+		return nil, fmt.Errorf("infisical provider requires 'recursive: true' for 'folder_prefix'")
+	}
+	separator := cfg.FolderSeparator
+	if separator == "" {
+		separator = "_"
+	}
+
 	// Build ListSecretsOptions
 	listOptions := infisical.ListSecretsOptions{
 		ProjectID:              cfg.ProjectID,
@@ -103,7 +127,11 @@ func (p *InfisicalProvider) Fetch(secretContext provider.SecretContext, mapID st
 	secretData := make(map[string]interface{})
 	for _, secret := range secrets {
 		// Use the secret key as the key, and the secret value as the value
-		secretData[secret.SecretKey] = secret.SecretValue
+		key := secret.SecretKey
+		if folderPrefix {
+			key = prefixWithFolder(cfg.Path, secret.SecretPath, key, separator)
+		}
+		secretData[key] = secret.SecretValue
 	}
 
 	// Map keys according to configuration
@@ -136,6 +164,24 @@ func (p *InfisicalProvider) Fetch(secretContext provider.SecretContext, mapID st
 	return kvs, nil
 }
 
+// prefixWithFolder prefixes key with the folder names of secretPath below basePath, joined by
+// separator. Keys of secrets directly in basePath are returned unchanged
+func prefixWithFolder(basePath, secretPath, key, separator string) string {
+	base := strings.Trim(basePath, "/")
+	folder := strings.Trim(secretPath, "/")
+	if base != "" {
+		if folder == base {
+			folder = ""
+		} else {
+			folder = strings.TrimPrefix(folder, base+"/")
+		}
+	}
+	if folder == "" {
+		return key
+	}
+	return strings.ReplaceAll(folder, "/", separator) + separator + key
+}
+
 // ensureClient initializes the Infisical client if not already initialized
 func (p *InfisicalProvider) ensureClient(ctx context.Context) error {
 	if p.client != nil {
@@ -159,8 +205,10 @@ func (p *InfisicalProvider) ensureClient(ctx context.Context) error {
 		clientConfig.SiteUrl = siteURL
 	}
 
-	// Create client with config
-	client := infisical.NewInfisicalClient(ctx, clientConfig)
+	// Create client with config. The client is reused by later fetches and refreshes its token in
+	// the background until its context is done, so it must outlive the fetch creating it. Its
+	// requests take no context; collections are cancelled around them
+	client := infisical.NewInfisicalClient(context.WithoutCancel(ctx), clientConfig)
 
 	// Authenticate using universal auth (pass env vars as parameters)
 	_, err := client.Auth().UniversalAuthLogin(clientID, clientSecret)
@@ -0,0 +1,29 @@
+package infisical
+
+import "testing"
+
+func TestPrefixWithFolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		basePath   string
+		secretPath string
+		separator  string
+		want       string
+	}{
+		{name: "root path, secret at root", basePath: "/", secretPath: "/", separator: "_", want: "HOST"},
+		{name: "root path, secret in folder", basePath: "/", secretPath: "/db", separator: "_", want: "db_HOST"},
+		{name: "root path, nested folders", basePath: "/", secretPath: "/db/primary", separator: "_", want: "db_primary_HOST"},
+		{name: "secret directly in path", basePath: "/app", secretPath: "/app", separator: "_", want: "HOST"},
+		{name: "folder below path", basePath: "/app", secretPath: "/app/db", separator: "_", want: "db_HOST"},
+		{name: "trailing slashes", basePath: "/app/", secretPath: "/app/db/", separator: "_", want: "db_HOST"},
+		{name: "custom separator", basePath: "/", secretPath: "/db/primary", separator: "__", want: "db__primary__HOST"},
+		{name: "empty secret path", basePath: "/app", secretPath: "", separator: "_", want: "HOST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prefixWithFolder(tt.basePath, tt.secretPath, "HOST", tt.separator); got != tt.want {
+				t.Errorf("prefixWithFolder(%q, %q) = %q, want %q", tt.basePath, tt.secretPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ package provider
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 )
 
 // Secrets represents a collection of secret key-value pairs
@@ -16,6 +19,8 @@ type ProviderSecretsMap map[string]Secrets
 type KeyValue struct {
 	Key   string
 	Value string
+	// ExpiresAt is when the secret stops being valid, e.g. at the end of a Vault lease (zero if it does not expire)
+	ExpiresAt time.Time
 }
 
 // SecretsResolver provides access to secrets from other providers
@@ -31,6 +36,31 @@ type SecretsResolver interface {
 type SecretContext struct {
 	Ctx             context.Context
 	SecretsResolver SecretsResolver
+	Profile         string // Active config profile, empty if none
+	// Env holds the environment variables of the command the secrets are collected for, like
+	// os.Environ, when it is not this process (nil for this process's), e.g. in the agent
+	Env []string
+}
+
+// Environ returns the environment variables the secrets are collected for, like os.Environ
+func (c SecretContext) Environ() []string {
+	if c.Env == nil {
+		return os.Environ()
+	}
+	return c.Env
+}
+
+// LookupEnv returns an environment variable the secrets are collected for, like os.LookupEnv
+func (c SecretContext) LookupEnv(key string) (string, bool) {
+	if c.Env == nil {
+		return os.LookupEnv(key)
+	}
+	for i := len(c.Env) - 1; i >= 0; i-- {
+		if name, value, ok := strings.Cut(c.Env[i], "="); ok && name == key {
+			return value, true
+		}
+	}
+	return "", false
 }
 
 // Provider is the interface that all secret providers must implement
@@ -43,6 +73,104 @@ type Provider interface {
 	Fetch(secretContext SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]KeyValue, error)
 }
 
+// IDIndependent is implemented by providers whose fetched secrets depend only on their config and
+// keys, not on mapID, so that identical entries with different IDs (e.g. repeated by includes or
+// profiles) can share one fetch in a collection
+type IDIndependent interface {
+	IDIndependent()
+}
+
+// ConfigDescriber is implemented by providers that expose their configuration struct
+// The struct's json tags are used to generate the JSON Schema for the config file
+type ConfigDescriber interface {
+	// ConfigStruct returns a pointer to a zero value of the provider's configuration struct
+	ConfigStruct() interface{}
+}
+
+// SSOConsumer is implemented by providers that can authenticate with the SSO tokens injected by the collector
+// The collector only authenticates with SSO when a selected provider uses it, or requires SSO claims
+type SSOConsumer interface {
+	// UsesSSO reports whether the provider needs SSO tokens with the given configuration
+	UsesSSO(config map[string]interface{}) bool
+}
+
+// Uncacheable is implemented by providers whose secrets must never be cached, e.g. because they
+// are read from the sstart process itself and may differ between invocations
+type Uncacheable interface {
+	// SkipCache reports whether the secrets of the provider bypass the secret cache
+	SkipCache() bool
+}
+
+// SessionPurger is implemented by providers that cache credentials obtained with an SSO session,
+// so that 'sstart logout' removes them together with the session
+type SessionPurger interface {
+	// PurgeSession removes the credentials cached for the SSO identity (see oidc.Identity), with
+	// the keyring service of the secret cache ("" for the default). It returns how many were removed
+	PurgeSession(keyringService, identity string) (int, error)
+}
+
+// Watcher is implemented by providers whose backend can notify of changes, so that
+// 'sstart run --watch' detects them quickly instead of polling full downloads
+type Watcher interface {
+	// Watch blocks until the secrets Fetch returns for the configuration may have changed, or the
+	// context of secretContext is done. An error makes the caller fall back to polling
+	Watch(secretContext SecretContext, mapID string, config map[string]interface{}) error
+}
+
+// Writer is implemented by providers that can store secrets, so that 'sstart import' can move
+// secrets into them
+type Writer interface {
+	// Write stores the secrets in the source of the configuration, adding them to the secrets
+	// already there and replacing those with the same keys
+	Write(secretContext SecretContext, mapID string, config map[string]interface{}, secrets map[string]string) error
+}
+
+// SecretMetadata describes when a secret was created and last changed (rotated)
+// An empty Key applies to every key returned by the provider, e.g. when one backend secret holds all keys
+type SecretMetadata struct {
+	Key       string
+	CreatedAt time.Time // Zero if unknown
+	UpdatedAt time.Time // Zero if unknown
+	// Custom holds metadata fields set by users in the backend, e.g. an owner (nil if none)
+	Custom map[string]string
+}
+
+// LastChanged returns when the secret was last changed, falling back to its creation time
+func (m SecretMetadata) LastChanged() time.Time {
+	if !m.UpdatedAt.IsZero() {
+		return m.UpdatedAt
+	}
+	return m.CreatedAt
+}
+
+// MetadataProvider is implemented by providers whose backend exposes when secrets were created or rotated
+type MetadataProvider interface {
+	// FetchMetadata returns the metadata of the secrets Fetch would return for the same configuration
+	FetchMetadata(secretContext SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]SecretMetadata, error)
+}
+
+// Statuses of an AuthCheck
+const (
+	AuthCheckOK      = "ok"
+	AuthCheckSkipped = "skipped"
+	AuthCheckFailed  = "failed"
+)
+
+// AuthCheck is a step of an authentication diagnosis, e.g. a credential source the provider looked at
+type AuthCheck struct {
+	Name   string
+	Status string // AuthCheckOK, AuthCheckSkipped or AuthCheckFailed
+	Detail string
+}
+
+// AuthDiagnoser is implemented by providers that can explain how they authenticate, so that
+// 'sstart validate --debug-auth' can probe their credentials without fetching secrets
+type AuthDiagnoser interface {
+	// DiagnoseAuth checks the credentials of the configuration. Failed checks are reported as
+	// checks; the error is for configurations that cannot be checked at all
+	DiagnoseAuth(secretContext SecretContext, mapID string, config map[string]interface{}) ([]AuthCheck, error)
+}
+
 // Registry holds all registered providers
 var registry = make(map[string]func() Provider)
 
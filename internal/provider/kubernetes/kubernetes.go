@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dirathea/sstart/internal/provider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubernetesConfig represents the configuration for the Kubernetes provider
+type KubernetesConfig struct {
+	// Secret is the name of the Secret to read (required unless configmap is set)
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// ConfigMap is the name of the ConfigMap to read, for non-sensitive config (required unless secret is set)
+	ConfigMap string `json:"configmap,omitempty" yaml:"configmap,omitempty"`
+	// Namespace is the namespace of the object (optional, defaults to the namespace of the kubeconfig context)
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Kubeconfig is the path of the kubeconfig file (optional, defaults to $KUBECONFIG or ~/.kube/config,
+	// and to the pod's service account when running in a cluster)
+	Kubeconfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+	// Context is the kubeconfig context to use (optional, defaults to the current context)
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+}
+
+// KubernetesProvider implements the provider interface for Kubernetes Secrets and ConfigMaps
+type KubernetesProvider struct {
+	client    k8s.Interface
+	namespace string
+}
+
+func init() {
+	provider.Register("kubernetes", func() provider.Provider {
+		return &KubernetesProvider{}
+	})
+}
+
+// Name returns the provider name
+func (p *KubernetesProvider) Name() string {
+	return "kubernetes"
+}
+
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *KubernetesProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *KubernetesProvider) ConfigStruct() interface{} {
+	return &KubernetesConfig{}
+}
+
+// Fetch reads the data of a Secret or ConfigMap
+func (p *KubernetesProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubernetes configuration: %w", err)
+	}
+	if (cfg.Secret == "") == (cfg.ConfigMap == "") {
+		return nil, fmt.Errorf("kubernetes provider requires exactly one of 'secret' or 'configmap' in configuration")
+	}
+	if err := p.ensureClient(cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize Kubernetes client: %w", err)
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = p.namespace
+	}
+
+	data := make(map[string]string)
+	if cfg.Secret != "" {
+		secret, err := p.client.CoreV1().Secrets(namespace).Get(ctx, cfg.Secret, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret '%s/%s': %w", namespace, cfg.Secret, err)
+		}
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+	} else {
+		configMap, err := p.client.CoreV1().ConfigMaps(namespace).Get(ctx, cfg.ConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configmap '%s/%s': %w", namespace, cfg.ConfigMap, err)
+		}
+		for k, v := range configMap.BinaryData {
+			data[k] = string(v)
+		}
+		for k, v := range configMap.Data {
+			data[k] = v
+		}
+	}
+
+	// Map keys according to configuration
+	kvs := make([]provider.KeyValue, 0, len(data))
+	for k, v := range data {
+		targetKey := k
+		if mappedKey, exists := keys[k]; exists {
+			if mappedKey != "==" {
+				targetKey = mappedKey
+			}
+		} else if len(keys) > 0 {
+			// Skip keys not in the mapping
+			continue
+		}
+		kvs = append(kvs, provider.KeyValue{Key: targetKey, Value: v})
+	}
+	return kvs, nil
+}
+
+// ensureClient creates the client from the kubeconfig, falling back to the in-cluster service account
+func (p *KubernetesProvider) ensureClient(cfg *KubernetesConfig) error {
+	if p.client != nil {
+		return nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cfg.Kubeconfig != "" {
+		loadingRules.ExplicitPath = os.ExpandEnv(cfg.Kubeconfig)
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return err
+	}
+	client, err := k8s.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	p.client = client
+	p.namespace = namespace
+	return nil
+}
+
+// parseConfig converts a map[string]interface{} to KubernetesConfig
+func parseConfig(config map[string]interface{}) (*KubernetesConfig, error) {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var cfg KubernetesConfig
+	if err := json.Unmarshal(jsonData, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &cfg, nil
+}
@@ -43,6 +43,14 @@ func (p *OnePasswordProvider) Name() string {
 	return "1password"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *OnePasswordProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *OnePasswordProvider) ConfigStruct() interface{} {
+	return &OnePasswordConfig{}
+}
+
 // Fetch fetches secrets from 1Password
 func (p *OnePasswordProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -4,15 +4,34 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
 
 	"github.com/dirathea/sstart/internal/provider"
 )
 
+// Values of TemplateConfig.OnMissing
+const (
+	// OnMissingError fails when a template references a missing secret
+	OnMissingError = "error"
+	// OnMissingEmpty renders missing secrets as empty strings
+	OnMissingEmpty = "empty"
+	// OnMissingDefault renders missing secrets as TemplateConfig.DefaultValue
+	OnMissingDefault = "default"
+)
+
 // TemplateConfig represents the configuration for template provider
 type TemplateConfig struct {
 	// Templates is a map of template expressions using dot notation: PG_URI: pgsql://{{.aws_prod.PG_USERNAME}}:{{.aws_prod.PG_PASSWORD}}@{{.aws_generic.PG_HOST}}
 	Templates map[string]string `yaml:"templates"`
+	// OnMissing controls references to secrets that don't exist: error, empty, or default (optional, default: Go's "<no value>")
+	OnMissing string `json:"on_missing,omitempty" yaml:"on_missing,omitempty"`
+	// DefaultValue is rendered for missing secrets when OnMissing is default (optional)
+	DefaultValue string `json:"default_value,omitempty" yaml:"default_value,omitempty"`
 }
 
 // TemplateProvider implements the provider interface for template-based secret manipulation
@@ -29,6 +48,11 @@ func (p *TemplateProvider) Name() string {
 	return "template"
 }
 
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *TemplateProvider) ConfigStruct() interface{} {
+	return &TemplateConfig{}
+}
+
 // parseConfig converts a map[string]interface{} to TemplateConfig
 func parseConfig(config map[string]interface{}) (*TemplateConfig, error) {
 	// Use JSON marshaling/unmarshaling for clean conversion
@@ -59,14 +83,46 @@ func (p *TemplateProvider) Fetch(secretContext provider.SecretContext, mapID str
 	if len(cfg.Templates) == 0 {
 		return nil, fmt.Errorf("template provider requires 'templates' field with template expressions")
 	}
+	switch cfg.OnMissing {
+	case "", OnMissingError, OnMissingEmpty, OnMissingDefault:
+	default:
+		return nil, fmt.Errorf("template provider 'on_missing' must be one of error, empty, default, got '%s'", cfg.OnMissing)
+	}
 
-	// Resolve each template expression
-	kvs := make([]provider.KeyValue, 0, len(cfg.Templates))
+	// Parse all templates first so references between them can be ordered
+	templates := make(map[string]*template.Template, len(cfg.Templates))
 	for targetKey, templateExpr := range cfg.Templates {
-		resolvedValue, err := p.resolveTemplate(templateExpr, resolver)
+		tmpl, err := template.New(targetKey).Parse(templateExpr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve template for key '%s': failed to parse template: %w", targetKey, err)
+		}
+		templates[targetKey] = tmpl
+	}
+	order, err := templateOrder(mapID, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Template data: provider secrets, built-in metadata, and the templates of this provider
+	// resolved so far, available as {{.<this provider id>.KEY}}
+	data := make(map[string]interface{})
+	for providerID, secrets := range resolver.Map() {
+		data[providerID] = secrets
+	}
+	for key, value := range newTemplateMetadata(secretContext) {
+		data[key] = value
+	}
+	resolved := make(map[string]string, len(templates))
+	data[mapID] = resolved
+
+	// Resolve each template expression after the templates it references
+	kvs := make([]provider.KeyValue, 0, len(cfg.Templates))
+	for _, targetKey := range order {
+		resolvedValue, err := resolveTemplate(templates[targetKey], data, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve template for key '%s': %w", targetKey, err)
 		}
+		resolved[targetKey] = resolvedValue
 		kvs = append(kvs, provider.KeyValue{
 			Key:   targetKey,
 			Value: resolvedValue,
@@ -76,25 +132,168 @@ func (p *TemplateProvider) Fetch(secretContext provider.SecretContext, mapID str
 	return kvs, nil
 }
 
-// resolveTemplate resolves a template expression using Go's text/template package
+// resolveTemplate executes a parsed template using Go's text/template package
 // Template syntax: {{.provider_id.secret_key}} (dot notation, similar to Helm templates)
 // Example: {{.aws_prod.PG_USERNAME}} or {{.aws_generic.PG_HOST}}
-func (p *TemplateProvider) resolveTemplate(templateStr string, resolver provider.SecretsResolver) (string, error) {
-	// Build template data structure from resolver
-	// Structure: { "provider_id": { "secret_key": "value", ... }, ... }
-	providerSecrets := resolver.Map()
-
-	// Parse the template
-	tmpl, err := template.New("secret_template").Parse(templateStr)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+// Built-in metadata is available alongside provider secrets: {{.Env.USER}}, {{.Profile}}, {{.Hostname}}, {{.Timestamp}}
+func resolveTemplate(tmpl *template.Template, data map[string]interface{}, cfg *TemplateConfig) (string, error) {
+	// Handle references to missing secrets before executing
+	if cfg.OnMissing != "" {
+		filled := make(map[string]interface{}, len(data))
+		for key, value := range data {
+			filled[key] = value
+		}
+		if err := fillMissing(tmpl.Tree.Root, filled, cfg); err != nil {
+			return "", err
+		}
+		data = filled
+		tmpl.Option("missingkey=error")
 	}
 
-	// Execute the template with the data structure
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, providerSecrets); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
 	return buf.String(), nil
 }
+
+// templateOrder returns the template keys ordered so that each template comes after the
+// templates of the same provider it references through {{.<mapID>.KEY}}
+// Keys without dependencies keep alphabetical order; reference cycles are reported as an error
+func templateOrder(mapID string, templates map[string]*template.Template) ([]string, error) {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(keys))
+	order := make([]string, 0, len(keys))
+
+	var visit func(key string, path []string) error
+	visit = func(key string, path []string) error {
+		switch state[key] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("template reference cycle: %s", strings.Join(append(path, key), " -> "))
+		}
+		state[key] = visiting
+		for _, ref := range secretRefs(templates[key].Tree.Root, nil) {
+			if ref[0] != mapID {
+				continue
+			}
+			if _, ok := templates[ref[1]]; ok {
+				if err := visit(ref[1], append(path, key)); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = done
+		order = append(order, key)
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := visit(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// newTemplateMetadata returns the built-in template data: host environment, profile, hostname and timestamp
+// Metadata keys start with an upper case letter and take precedence over provider IDs with the same name
+func newTemplateMetadata(secretContext provider.SecretContext) map[string]interface{} {
+	env := make(map[string]string)
+	for _, entry := range secretContext.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+
+	hostname, _ := os.Hostname()
+
+	return map[string]interface{}{
+		"Env":       env,
+		"Profile":   secretContext.Profile,
+		"Hostname":  hostname,
+		"Timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// fillMissing sets the secrets referenced by the template but missing from data according to
+// cfg.OnMissing, or returns an error naming the first missing secret in error mode
+// Maps in data are copied before they are modified
+func fillMissing(root parse.Node, data map[string]interface{}, cfg *TemplateConfig) error {
+	for _, ref := range secretRefs(root, nil) {
+		name, key := ref[0], ref[1]
+		secrets, isMap := data[name].(map[string]string)
+		if _, exists := data[name]; exists && !isMap {
+			// Not a secret reference, e.g. a field of built-in metadata
+			continue
+		}
+		if _, ok := secrets[key]; ok {
+			continue
+		}
+		if cfg.OnMissing == OnMissingError {
+			return fmt.Errorf("template references missing secret '%s.%s'", name, key)
+		}
+
+		value := ""
+		if cfg.OnMissing == OnMissingDefault {
+			value = cfg.DefaultValue
+		}
+		filled := make(map[string]string, len(secrets)+1)
+		for k, v := range secrets {
+			filled[k] = v
+		}
+		filled[key] = value
+		data[name] = filled
+	}
+	return nil
+}
+
+// secretRefs collects the {{.provider_id.secret_key}} references in a template
+// Bodies of range and with are skipped since dot no longer refers to the provider secrets there
+func secretRefs(node parse.Node, refs [][2]string) [][2]string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				refs = secretRefs(child, refs)
+			}
+		}
+	case *parse.ActionNode:
+		refs = secretRefs(n.Pipe, refs)
+	case *parse.IfNode:
+		refs = secretRefs(n.Pipe, refs)
+		refs = secretRefs(n.List, refs)
+		refs = secretRefs(n.ElseList, refs)
+	case *parse.RangeNode:
+		refs = secretRefs(n.Pipe, refs)
+	case *parse.WithNode:
+		refs = secretRefs(n.Pipe, refs)
+	case *parse.PipeNode:
+		if n != nil {
+			for _, cmd := range n.Cmds {
+				refs = secretRefs(cmd, refs)
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			refs = secretRefs(arg, refs)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) >= 2 {
+			refs = append(refs, [2]string{n.Ident[0], n.Ident[1]})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dirathea/sstart/internal/oidc"
+	"github.com/hashicorp/vault/api"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// TokenKeyringService is the keyring service name Vault tokens obtained by logging in are cached under
+	TokenKeyringService = "sstart-vault"
+
+	// identityIndexPrefix prefixes the keyring entries listing the cached tokens of an identity
+	identityIndexPrefix = "identity:"
+
+	// tokenExpiryMargin is the lifetime a cached token must have left to be reused, so that it does
+	// not expire while secrets are fetched
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// cachedToken is a Vault token cached in the keyring
+type cachedToken struct {
+	Token string `json:"token"`
+	// ExpiresAt is when the token expires, zero if it does not
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// TokenKeyringServiceFor returns the keyring service Vault tokens are cached under when the
+// secrets cache is stored under cacheService, so that separate deployments keep their tokens apart
+func TokenKeyringServiceFor(cacheService string) string {
+	if cacheService == "" {
+		return TokenKeyringService
+	}
+	return cacheService + "-vault"
+}
+
+// tokenCacheKey identifies the token of a login: the Vault server, the auth mount and role, and
+// the identity in the JWT. A JWT whose identity cannot be read only matches itself
+func tokenCacheKey(address, mount, role, jwt string) string {
+	identity := jwt
+	if claims, err := oidc.ParseIDTokenClaims(jwt); err == nil {
+		iss, _ := claims["iss"].(string)
+		sub, _ := claims["sub"].(string)
+		if sub != "" {
+			identity = iss + "\x00" + sub
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{address, mount, role, identity}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// useCachedToken sets the cached token of key on client if it is still valid, renewing it once
+// it is past half of its lifetime. It reports whether a token was set
+func useCachedToken(ctx context.Context, client *api.Client, service, identity, key string) bool {
+	data, err := keyring.Get(service, key)
+	if err != nil {
+		return false
+	}
+	var cached cachedToken
+	if err := json.Unmarshal([]byte(data), &cached); err != nil || cached.Token == "" {
+		_ = keyring.Delete(service, key)
+		return false
+	}
+	if !cached.ExpiresAt.IsZero() && time.Until(cached.ExpiresAt) < tokenExpiryMargin {
+		_ = keyring.Delete(service, key)
+		return false
+	}
+
+	// The token may have been revoked since it was cached
+	client.SetToken(cached.Token)
+	self, err := client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil || self == nil {
+		client.ClearToken()
+		_ = keyring.Delete(service, key)
+		return false
+	}
+	ttl, _ := self.TokenTTL()
+	if renewable, _ := self.TokenIsRenewable(); renewable && ttl < creationTTL(self)/2 {
+		if renewed, err := client.Auth().Token().RenewSelfWithContext(ctx, 0); err == nil && renewed != nil && renewed.Auth != nil {
+			ttl = time.Duration(renewed.Auth.LeaseDuration) * time.Second
+		}
+	}
+	if ttl > 0 && ttl < tokenExpiryMargin {
+		client.ClearToken()
+		_ = keyring.Delete(service, key)
+		return false
+	}
+	cacheToken(service, identity, key, cached.Token, ttl)
+	return true
+}
+
+// creationTTL returns the TTL a token was created with, or 0 if unknown
+func creationTTL(self *api.Secret) time.Duration {
+	value, ok := self.Data["creation_ttl"].(json.Number)
+	if !ok {
+		return 0
+	}
+	seconds, err := value.Int64()
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// cacheToken stores the token of key in the keyring for ttl (0 if it does not expire), and lists
+// it under identity so that logging out removes it. Failures are ignored: without a keyring,
+// sstart logs in on every run
+func cacheToken(service, identity, key, token string, ttl time.Duration) {
+	cached := cachedToken{Token: token}
+	if ttl > 0 {
+		cached.ExpiresAt = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := keyring.Set(service, key, string(data)); err != nil {
+		return
+	}
+	if identity == "" {
+		return
+	}
+	keys := identityTokens(service, identity)
+	for _, k := range keys {
+		if k == key {
+			return
+		}
+	}
+	if data, err := json.Marshal(append(keys, key)); err == nil {
+		_ = keyring.Set(service, identityIndexPrefix+identity, string(data))
+	}
+}
+
+// identityTokens returns the keys of the tokens cached under identity
+func identityTokens(service, identity string) []string {
+	data, err := keyring.Get(service, identityIndexPrefix+identity)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(data), &keys); err != nil {
+		return nil
+	}
+	return keys
+}
+
+// PurgeSession removes the tokens cached by logging in with the SSO identity
+func (p *VaultProvider) PurgeSession(keyringService, identity string) (int, error) {
+	return clearIdentityTokens(TokenKeyringServiceFor(keyringService), identity)
+}
+
+// clearIdentityTokens removes the tokens cached under service by logging in with the identity.
+// It returns how many tokens were removed
+func clearIdentityTokens(service, identity string) (int, error) {
+	removed := 0
+	for _, key := range identityTokens(service, identity) {
+		err := keyring.Delete(service, key)
+		if err == nil {
+			removed++
+		} else if err != keyring.ErrNotFound {
+			return removed, fmt.Errorf("failed to remove cached Vault token: %w", err)
+		}
+	}
+	if err := keyring.Delete(service, identityIndexPrefix+identity); err != nil && err != keyring.ErrNotFound {
+		return removed, fmt.Errorf("failed to remove cached Vault tokens: %w", err)
+	}
+	return removed, nil
+}
@@ -3,10 +3,16 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/dirathea/sstart/internal/oidc"
 	"github.com/dirathea/sstart/internal/provider"
 	"github.com/hashicorp/vault/api"
 )
@@ -18,6 +24,9 @@ const (
 	AuthMethodOIDC = "oidc"
 	// AuthMethodJWT is an alias for OIDC authentication
 	AuthMethodJWT = "jwt"
+	// AuthMethodAgent sends requests without a token to a local Vault Agent or OpenBao proxy,
+	// which authenticates them with its auto-auth token
+	AuthMethodAgent = "agent"
 
 	// DefaultJWTAuthMount is the default mount path for JWT auth
 	DefaultJWTAuthMount = "jwt"
@@ -25,7 +34,7 @@ const (
 
 // VaultAuthConfig represents authentication configuration for Vault
 type VaultAuthConfig struct {
-	// Method specifies the authentication method: "token" (default), "oidc", or "jwt"
+	// Method specifies the authentication method: "token" (default), "oidc", "jwt", or "agent"
 	Method string `json:"method,omitempty" yaml:"method,omitempty"`
 	// Role is the Vault role to authenticate as (required when using oidc/jwt auth)
 	Role string `json:"role,omitempty" yaml:"role,omitempty"`
@@ -33,22 +42,83 @@ type VaultAuthConfig struct {
 	Mount string `json:"mount,omitempty" yaml:"mount,omitempty"`
 	// Token is the Vault authentication token (optional, defaults to VAULT_TOKEN env var)
 	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// CacheToken caches the token obtained by oidc/jwt login in the system keyring and reuses it,
+	// renewing it when renewable, until it expires (optional, defaults to true)
+	CacheToken *bool `json:"cache_token,omitempty" yaml:"cache_token,omitempty"`
 }
 
 // VaultConfig represents the configuration for HashiCorp Vault provider
 type VaultConfig struct {
-	// Address is the Vault server address (optional, defaults to VAULT_ADDR env var)
+	// Address is the Vault server address (optional, defaults to VAULT_ADDR env var). With agent
+	// auth it is the local agent listener, e.g. unix:///var/run/vault-agent.sock (optional,
+	// defaults to VAULT_AGENT_ADDR env var)
 	Address string `json:"address,omitempty" yaml:"address,omitempty"`
-	// Path is the path to the secret in Vault (required)
-	Path string `json:"path" yaml:"path"`
+	// Path is the path to the secret in Vault (required unless paths is set)
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Paths are several secrets fetched concurrently with the same client and merged in order,
+	// later secrets overriding earlier ones (instead of path)
+	Paths []VaultPath `json:"paths,omitempty" yaml:"paths,omitempty"`
 	// Mount is the secret engine mount path (optional, defaults to "secret")
 	Mount string `json:"mount,omitempty" yaml:"mount,omitempty"`
 	// Auth contains authentication configuration
 	Auth *VaultAuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// CustomMetadata exposes the KV v2 custom_metadata of the secret, e.g. its owner (optional)
+	CustomMetadata bool `json:"custom_metadata,omitempty" yaml:"custom_metadata,omitempty"`
+	// RotationDateField is the custom_metadata field holding when the secret was last rotated, as an
+	// RFC 3339 time or a YYYY-MM-DD date; it replaces updated_time for rotation age warnings (optional)
+	RotationDateField string `json:"rotation_date_field,omitempty" yaml:"rotation_date_field,omitempty"`
 
 	// Internal: SSO tokens injected by the collector
 	SSOAccessToken string `json:"-" yaml:"-"`
 	SSOIDToken     string `json:"-" yaml:"-"`
+	// Internal: keyring service of the secrets cache, injected by the collector
+	KeyringService string `json:"-" yaml:"-"`
+}
+
+// VaultPath is an entry of 'paths': a path, or an object with the path and a prefix
+type VaultPath struct {
+	// Path is the path to the secret in Vault
+	Path string `json:"path" yaml:"path"`
+	// Prefix is prepended to the names of the secret's keys, before they are mapped by keys (optional)
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+// UnmarshalJSON accepts a path string as well as an object
+func (v *VaultPath) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		v.Path = path
+		return nil
+	}
+	type rawPath VaultPath
+	return json.Unmarshal(data, (*rawPath)(v))
+}
+
+// secretPaths returns the secrets to fetch, from either 'path' or 'paths'
+func (cfg *VaultConfig) secretPaths() ([]VaultPath, error) {
+	if len(cfg.Paths) > 0 {
+		if cfg.Path != "" {
+			return nil, fmt.Errorf("vault provider accepts either 'path' or 'paths', not both")
+		}
+		for i, path := range cfg.Paths {
+			if path.Path == "" {
+				return nil, fmt.Errorf("vault provider requires a path in paths[%d]", i)
+			}
+		}
+		return cfg.Paths, nil
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("vault provider requires 'path' field in configuration")
+	}
+	return []VaultPath{{Path: cfg.Path}}, nil
+}
+
+// mountPath returns the secret engine mount path, defaulting to "secret"
+func (cfg *VaultConfig) mountPath() string {
+	if cfg.Mount == "" {
+		return "secret"
+	}
+	return cfg.Mount
 }
 
 // VaultProvider implements the provider interface for HashiCorp Vault
@@ -67,6 +137,27 @@ func (p *VaultProvider) Name() string {
 	return "vault"
 }
 
+// IDIndependent implements provider.IDIndependent: the fetched secrets do not depend on mapID
+func (p *VaultProvider) IDIndependent() {}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *VaultProvider) ConfigStruct() interface{} {
+	return &VaultConfig{}
+}
+
+// UsesSSO reports whether the configuration uses oidc or jwt auth, which needs the collector's SSO tokens
+func (p *VaultProvider) UsesSSO(config map[string]interface{}) bool {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return false
+	}
+	method := ""
+	if cfg.Auth != nil {
+		method = strings.ToLower(cfg.Auth.Method)
+	}
+	return method == AuthMethodOIDC || method == AuthMethodJWT
+}
+
 // Fetch fetches secrets from HashiCorp Vault
 func (p *VaultProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
 	ctx := secretContext.Ctx
@@ -77,22 +168,51 @@ func (p *VaultProvider) Fetch(secretContext provider.SecretContext, mapID string
 	}
 
 	// Validate required fields
-	if cfg.Path == "" {
-		return nil, fmt.Errorf("vault provider requires 'path' field in configuration")
+	paths, err := cfg.secretPaths()
+	if err != nil {
+		return nil, err
 	}
 
 	if err := p.ensureClient(ctx, cfg); err != nil {
 		return nil, fmt.Errorf("failed to initialize Vault client: %w", err)
 	}
 
-	// Determine mount path (default to "secret")
-	mount := cfg.Mount
-	if mount == "" {
-		mount = "secret"
+	// Read all secrets concurrently, then merge them in order
+	results := make([][]provider.KeyValue, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, expiresAt, err := p.readSecret(ctx, cfg.mountPath(), path.Path)
+			if err == nil {
+				results[i], err = mapSecretData(data, path.Prefix, keys)
+			}
+			for j := range results[i] {
+				results[i][j].ExpiresAt = expiresAt
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	kvs := make([]provider.KeyValue, 0)
+	for i := range paths {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		kvs = append(kvs, results[i]...)
 	}
+	return kvs, nil
+}
 
+// readSecret reads the data of the secret at path, trying the KV v2 format first and then KV v1.
+// It also returns when the lease of the secret ends, for dynamic secrets such as database
+// credentials, or the zero time for secrets without a lease
+func (p *VaultProvider) readSecret(ctx context.Context, mount, path string) (map[string]interface{}, time.Time, error) {
 	// Clean the path
-	cleanPath := strings.TrimPrefix(cfg.Path, "/")
+	cleanPath := strings.TrimPrefix(path, "/")
 
 	// Try KV v2 format first (mount/data/path)
 	secretPath := fmt.Sprintf("%s/data/%s", mount, cleanPath)
@@ -105,11 +225,11 @@ func (p *VaultProvider) Fetch(secretContext provider.SecretContext, mapID string
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read secret from Vault at path '%s': %w", secretPath, err)
+		return nil, time.Time{}, classifyError(fmt.Errorf("failed to read secret from Vault at path '%s': %w", secretPath, err))
 	}
 
 	if secret == nil {
-		return nil, fmt.Errorf("secret not found at path '%s' (tried both KV v1 and v2 formats)", cfg.Path)
+		return nil, time.Time{}, provider.Classify(provider.ErrNotFound, fmt.Errorf("secret not found at path '%s' (tried both KV v1 and v2 formats)", path))
 	}
 
 	// Extract data from the secret (KV v2 format stores data under "data" key)
@@ -125,12 +245,23 @@ func (p *VaultProvider) Fetch(secretContext provider.SecretContext, mapID string
 	}
 
 	if secretData == nil {
-		return nil, fmt.Errorf("no data found in secret at path '%s'", secretPath)
+		return nil, time.Time{}, fmt.Errorf("no data found in secret at path '%s'", secretPath)
+	}
+
+	// KV secrets report no lease, or a refresh interval when they are not renewable
+	var expiresAt time.Time
+	if secret.LeaseID != "" && secret.LeaseDuration > 0 {
+		expiresAt = time.Now().Add(time.Duration(secret.LeaseDuration) * time.Second)
 	}
+	return secretData, expiresAt, nil
+}
 
-	// Map keys according to configuration
+// mapSecretData maps the data of a secret to key-values according to keys, after prepending
+// prefix to its key names
+func mapSecretData(secretData map[string]interface{}, prefix string, keys map[string]string) ([]provider.KeyValue, error) {
 	kvs := make([]provider.KeyValue, 0)
-	for k, v := range secretData {
+	for dataKey, v := range secretData {
+		k := prefix + dataKey
 		targetKey := k
 
 		// Check if there's a specific mapping
@@ -173,6 +304,185 @@ func (p *VaultProvider) Fetch(secretContext provider.SecretContext, mapID string
 	return kvs, nil
 }
 
+// FetchMetadata returns when the secret was created and last updated, from the KV v2 metadata endpoint
+// KV v1 secrets have no metadata, so no times are returned for them. With several paths, the
+// metadata of each secret is returned for the keys it provides
+func (p *VaultProvider) FetchMetadata(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.SecretMetadata, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault configuration: %w", err)
+	}
+	paths, err := cfg.secretPaths()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ensureClient(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize Vault client: %w", err)
+	}
+
+	if len(cfg.Paths) == 0 {
+		metadata, err := p.readMetadata(ctx, cfg, cfg.Path)
+		if err != nil || metadata == nil {
+			return nil, err
+		}
+		return []provider.SecretMetadata{*metadata}, nil
+	}
+
+	var all []provider.SecretMetadata
+	for _, path := range paths {
+		metadata, err := p.readMetadata(ctx, cfg, path.Path)
+		if err != nil {
+			return nil, err
+		}
+		if metadata == nil {
+			continue
+		}
+		data, _, err := p.readSecret(ctx, cfg.mountPath(), path.Path)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := mapSecretData(data, path.Prefix, keys)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range kvs {
+			entry := *metadata
+			entry.Key = kv.Key
+			all = append(all, entry)
+		}
+	}
+	return all, nil
+}
+
+// readMetadata reads the KV v2 metadata of the secret at path, or returns nil if there is none
+func (p *VaultProvider) readMetadata(ctx context.Context, cfg *VaultConfig, path string) (*provider.SecretMetadata, error) {
+	metadataPath := fmt.Sprintf("%s/metadata/%s", cfg.mountPath(), strings.TrimPrefix(path, "/"))
+	secret, err := p.client.Logical().ReadWithContext(ctx, metadataPath)
+	if err != nil {
+		return nil, classifyError(fmt.Errorf("failed to read secret metadata from Vault at path '%s': %w", metadataPath, err))
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	var metadata provider.SecretMetadata
+	if created, ok := secret.Data["created_time"].(string); ok {
+		metadata.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+	}
+	if updated, ok := secret.Data["updated_time"].(string); ok {
+		metadata.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+	}
+
+	custom, _ := secret.Data["custom_metadata"].(map[string]interface{})
+	if cfg.RotationDateField != "" {
+		if value, ok := custom[cfg.RotationDateField].(string); ok {
+			rotated, err := parseRotationDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid custom_metadata field '%s' at path '%s': %w", cfg.RotationDateField, metadataPath, err)
+			}
+			metadata.UpdatedAt = rotated
+		}
+	}
+	if cfg.CustomMetadata && len(custom) > 0 {
+		metadata.Custom = make(map[string]string, len(custom))
+		for field, value := range custom {
+			metadata.Custom[field] = fmt.Sprint(value)
+		}
+	}
+	return &metadata, nil
+}
+
+// Write adds secrets to the secret at the configured path, keeping its other keys. A new secret is
+// created as KV v2 unless the mount is a KV v1 engine
+func (p *VaultProvider) Write(secretContext provider.SecretContext, mapID string, config map[string]interface{}, secrets map[string]string) error {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid vault configuration: %w", err)
+	}
+	if len(cfg.Paths) > 0 {
+		return fmt.Errorf("vault provider can only write to a single 'path', not 'paths'")
+	}
+	if cfg.Path == "" {
+		return fmt.Errorf("vault provider requires 'path' field in configuration")
+	}
+	if err := p.ensureClient(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to initialize Vault client: %w", err)
+	}
+
+	mount := cfg.mountPath()
+	cleanPath := strings.TrimPrefix(cfg.Path, "/")
+
+	// Find the secret like Fetch does, to merge with its current data
+	v2Path := fmt.Sprintf("%s/data/%s", mount, cleanPath)
+	v1Path := fmt.Sprintf("%s/%s", mount, cleanPath)
+	data := make(map[string]interface{})
+	kvV2 := true
+	secret, err := p.client.Logical().ReadWithContext(ctx, v2Path)
+	if err != nil {
+		return fmt.Errorf("failed to read secret from Vault at path '%s': %w", v2Path, err)
+	}
+	if secret != nil {
+		if current, ok := secret.Data["data"].(map[string]interface{}); ok {
+			data = current
+		}
+	} else {
+		secret, err = p.client.Logical().ReadWithContext(ctx, v1Path)
+		if err != nil {
+			return fmt.Errorf("failed to read secret from Vault at path '%s': %w", v1Path, err)
+		}
+		if secret != nil {
+			kvV2 = false
+			data = secret.Data
+		} else {
+			kvV2 = p.mountVersion(ctx, mount) != "1"
+		}
+	}
+
+	for key, value := range secrets {
+		data[key] = value
+	}
+	if kvV2 {
+		_, err = p.client.Logical().WriteWithContext(ctx, v2Path, map[string]interface{}{"data": data})
+		if err != nil {
+			return fmt.Errorf("failed to write secret to Vault at path '%s': %w", v2Path, err)
+		}
+		return nil
+	}
+	if _, err := p.client.Logical().WriteWithContext(ctx, v1Path, data); err != nil {
+		return fmt.Errorf("failed to write secret to Vault at path '%s': %w", v1Path, err)
+	}
+	return nil
+}
+
+// mountVersion returns the KV version of a secrets engine mount ("1" or "2"), or "" if it cannot be read
+func (p *VaultProvider) mountVersion(ctx context.Context, mount string) string {
+	secret, err := p.client.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+mount)
+	if err != nil || secret == nil {
+		return ""
+	}
+	options, _ := secret.Data["options"].(map[string]interface{})
+	version, _ := options["version"].(string)
+	if version == "" && secret.Data["type"] == "kv" {
+		// KV mounts without a version option are KV v1
+		return "1"
+	}
+	return version
+}
+
+// parseRotationDate parses an RFC 3339 time or a YYYY-MM-DD date
+func parseRotationDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 time or a YYYY-MM-DD date, got '%s'", value)
+	}
+	return t, nil
+}
+
 func (p *VaultProvider) ensureClient(ctx context.Context, cfg *VaultConfig) error {
 	if p.client != nil {
 		return nil
@@ -186,8 +496,21 @@ func (p *VaultProvider) ensureClient(ctx context.Context, cfg *VaultConfig) erro
 		return fmt.Errorf("failed to read environment: %w", err)
 	}
 
+	// Determine auth method
+	authMethod := AuthMethodToken
+	if cfg.Auth != nil && cfg.Auth.Method != "" {
+		authMethod = strings.ToLower(cfg.Auth.Method)
+	}
+
 	// Override address if provided
-	if cfg.Address != "" {
+	if authMethod == AuthMethodAgent {
+		address, err := agentAddress(cfg.Address, apiCfg.AgentAddress)
+		if err != nil {
+			return err
+		}
+		apiCfg.Address = address
+		apiCfg.AgentAddress = ""
+	} else if cfg.Address != "" {
 		apiCfg.Address = cfg.Address
 	} else if apiCfg.Address == "" {
 		// If VAULT_ADDR is not set, use default
@@ -200,12 +523,6 @@ func (p *VaultProvider) ensureClient(ctx context.Context, cfg *VaultConfig) erro
 		return fmt.Errorf("failed to create Vault client: %w", err)
 	}
 
-	// Determine auth method
-	authMethod := AuthMethodToken
-	if cfg.Auth != nil && cfg.Auth.Method != "" {
-		authMethod = strings.ToLower(cfg.Auth.Method)
-	}
-
 	switch authMethod {
 	case AuthMethodOIDC, AuthMethodJWT:
 		// Use JWT/OIDC authentication with SSO tokens
@@ -221,8 +538,14 @@ func (p *VaultProvider) ensureClient(ctx context.Context, cfg *VaultConfig) erro
 		if err := p.authenticateWithToken(client, token); err != nil {
 			return err
 		}
+	case AuthMethodAgent:
+		// The agent adds its auto-auth token to requests that carry none
+		if cfg.Auth.Token != "" {
+			return fmt.Errorf("vault agent authentication does not use a token - remove 'auth.token' from the config")
+		}
+		client.ClearToken()
 	default:
-		return fmt.Errorf("unsupported auth method: %s (supported: token, oidc, jwt)", authMethod)
+		return fmt.Errorf("unsupported auth method: %s (supported: token, oidc, jwt, agent)", authMethod)
 	}
 
 	p.client = client
@@ -243,12 +566,36 @@ func (p *VaultProvider) authenticateWithToken(client *api.Client, token string)
 
 	// Verify client has a token
 	if client.Token() == "" {
-		return fmt.Errorf("vault authentication token is required (set 'auth.token' in config or VAULT_TOKEN environment variable)")
+		return provider.Classify(provider.ErrAuth, fmt.Errorf("vault authentication token is required (set 'auth.token' in config or VAULT_TOKEN environment variable)"))
 	}
 
 	return nil
 }
 
+// agentAddress returns the address of the local agent listener: the configured address, or
+// VAULT_AGENT_ADDR. Only unix sockets and loopback addresses are accepted, since requests are
+// sent without a token and the agent authenticates whoever can reach it
+func agentAddress(address, envAddress string) (string, error) {
+	if address == "" {
+		address = envAddress
+	}
+	if address == "" {
+		return "", fmt.Errorf("vault agent authentication requires 'address' in config or VAULT_AGENT_ADDR environment variable")
+	}
+	if strings.HasPrefix(address, "unix://") {
+		return address, nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault agent address '%s': %w", address, err)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("vault agent address '%s' is not local: use a unix:// socket or a loopback address", address)
+	}
+	return address, nil
+}
+
 // authenticateWithJWT authenticates using JWT/OIDC with SSO tokens
 func (p *VaultProvider) authenticateWithJWT(ctx context.Context, client *api.Client, cfg *VaultConfig) error {
 	// Get the JWT token - prefer ID token for OIDC, fall back to access token
@@ -277,6 +624,15 @@ func (p *VaultProvider) authenticateWithJWT(ctx context.Context, client *api.Cli
 		authMount = DefaultJWTAuthMount
 	}
 
+	// Reuse the token of an earlier login instead of logging in on every run
+	cacheTokens := cfg.Auth.CacheToken == nil || *cfg.Auth.CacheToken
+	service := TokenKeyringServiceFor(cfg.KeyringService)
+	identity := oidc.Identity(jwtToken)
+	cacheKey := tokenCacheKey(client.Address(), authMount, cfg.Auth.Role, jwtToken)
+	if cacheTokens && useCachedToken(ctx, client, service, identity, cacheKey) {
+		return nil
+	}
+
 	// Authenticate with Vault using JWT auth
 	loginPath := fmt.Sprintf("auth/%s/login", authMount)
 	loginData := map[string]interface{}{
@@ -286,7 +642,7 @@ func (p *VaultProvider) authenticateWithJWT(ctx context.Context, client *api.Cli
 
 	secret, err := client.Logical().WriteWithContext(ctx, loginPath, loginData)
 	if err != nil {
-		return fmt.Errorf("vault JWT authentication failed: %w", err)
+		return provider.Classify(provider.ErrAuth, fmt.Errorf("vault JWT authentication failed: %w", err))
 	}
 
 	if secret == nil || secret.Auth == nil {
@@ -295,10 +651,22 @@ func (p *VaultProvider) authenticateWithJWT(ctx context.Context, client *api.Cli
 
 	// Set the client token from the auth response
 	client.SetToken(secret.Auth.ClientToken)
+	if cacheTokens {
+		cacheToken(service, identity, cacheKey, secret.Auth.ClientToken, time.Duration(secret.Auth.LeaseDuration)*time.Second)
+	}
 
 	return nil
 }
 
+// classifyError marks an error of the Vault client with the provider error class of its HTTP status
+func classifyError(err error) error {
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return provider.Classify(provider.StatusClass(respErr.StatusCode), err)
+	}
+	return err
+}
+
 // parseConfig converts a map[string]interface{} to VaultConfig
 func parseConfig(config map[string]interface{}) (*VaultConfig, error) {
 	// Use JSON marshaling/unmarshaling for clean conversion
@@ -319,6 +687,9 @@ func parseConfig(config map[string]interface{}) (*VaultConfig, error) {
 	if idToken, ok := config["_sso_id_token"].(string); ok {
 		cfg.SSOIDToken = idToken
 	}
+	if service, ok := config["_keyring_service"].(string); ok {
+		cfg.KeyringService = service
+	}
 
 	// Support top-level 'token' field for backward compatibility with simpler configs
 	// e.g., `token: my-token` instead of `auth: { method: token, token: my-token }`
@@ -2,16 +2,26 @@ package vault
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dirathea/sstart/internal/oidc"
+	"github.com/dirathea/sstart/internal/provider"
 	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/zalando/go-keyring"
 )
 
 func TestParseConfigWithAuthOptions(t *testing.T) {
 	tests := []struct {
 		name          string
 		config        map[string]interface{}
-		wantAuth      string
+		wantMethod    string
 		wantAuthMount string
 		wantRole      string
 		wantErr       bool
@@ -21,7 +31,7 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 			config: map[string]interface{}{
 				"path": "myapp/secret",
 			},
-			wantAuth:      "",
+			wantMethod:    "",
 			wantAuthMount: "",
 			wantRole:      "",
 			wantErr:       false,
@@ -30,9 +40,11 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 			name: "config with explicit token auth",
 			config: map[string]interface{}{
 				"path": "myapp/secret",
-				"auth": "token",
+				"auth": map[string]interface{}{
+					"method": "token",
+				},
 			},
-			wantAuth:      "token",
+			wantMethod:    "token",
 			wantAuthMount: "",
 			wantRole:      "",
 			wantErr:       false,
@@ -41,10 +53,12 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 			name: "config with oidc auth",
 			config: map[string]interface{}{
 				"path": "myapp/secret",
-				"auth": "oidc",
-				"role": "my-role",
+				"auth": map[string]interface{}{
+					"method": "oidc",
+					"role":   "my-role",
+				},
 			},
-			wantAuth:      "oidc",
+			wantMethod:    "oidc",
 			wantAuthMount: "",
 			wantRole:      "my-role",
 			wantErr:       false,
@@ -52,12 +66,14 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 		{
 			name: "config with jwt auth and custom mount",
 			config: map[string]interface{}{
-				"path":      "myapp/secret",
-				"auth":      "jwt",
-				"authMount": "custom-jwt",
-				"role":      "app-role",
+				"path": "myapp/secret",
+				"auth": map[string]interface{}{
+					"method": "jwt",
+					"mount":  "custom-jwt",
+					"role":   "app-role",
+				},
 			},
-			wantAuth:      "jwt",
+			wantMethod:    "jwt",
 			wantAuthMount: "custom-jwt",
 			wantRole:      "app-role",
 			wantErr:       false,
@@ -75,14 +91,18 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 				return
 			}
 
-			if cfg.Auth != tt.wantAuth {
-				t.Errorf("parseConfig() Auth = %v, want %v", cfg.Auth, tt.wantAuth)
+			auth := cfg.Auth
+			if auth == nil {
+				auth = &VaultAuthConfig{}
+			}
+			if auth.Method != tt.wantMethod {
+				t.Errorf("parseConfig() Auth.Method = %v, want %v", auth.Method, tt.wantMethod)
 			}
-			if cfg.AuthMount != tt.wantAuthMount {
-				t.Errorf("parseConfig() AuthMount = %v, want %v", cfg.AuthMount, tt.wantAuthMount)
+			if auth.Mount != tt.wantAuthMount {
+				t.Errorf("parseConfig() Auth.Mount = %v, want %v", auth.Mount, tt.wantAuthMount)
 			}
-			if cfg.Role != tt.wantRole {
-				t.Errorf("parseConfig() Role = %v, want %v", cfg.Role, tt.wantRole)
+			if auth.Role != tt.wantRole {
+				t.Errorf("parseConfig() Auth.Role = %v, want %v", auth.Role, tt.wantRole)
 			}
 		})
 	}
@@ -90,9 +110,11 @@ func TestParseConfigWithAuthOptions(t *testing.T) {
 
 func TestParseConfigWithSSOTokens(t *testing.T) {
 	config := map[string]interface{}{
-		"path":              "myapp/secret",
-		"auth":              "oidc",
-		"role":              "my-role",
+		"path": "myapp/secret",
+		"auth": map[string]interface{}{
+			"method": "oidc",
+			"role":   "my-role",
+		},
 		"_sso_access_token": "test-access-token-123",
 		"_sso_id_token":     "test-id-token-456",
 	}
@@ -122,19 +144,23 @@ func TestVaultProvider_Fetch_OIDCAuthValidation(t *testing.T) {
 		{
 			name: "oidc auth without role",
 			config: map[string]interface{}{
-				"path":              "myapp/secret",
-				"auth":              "oidc",
+				"path": "myapp/secret",
+				"auth": map[string]interface{}{
+					"method": "oidc",
+				},
 				"_sso_access_token": "test-token",
 			},
 			wantErr: true,
-			errMsg:  "requires 'role' field",
+			errMsg:  "requires 'auth.role' field",
 		},
 		{
 			name: "oidc auth without SSO token",
 			config: map[string]interface{}{
 				"path": "myapp/secret",
-				"auth": "oidc",
-				"role": "my-role",
+				"auth": map[string]interface{}{
+					"method": "oidc",
+					"role":   "my-role",
+				},
 			},
 			wantErr: true,
 			errMsg:  "no SSO token available",
@@ -142,18 +168,22 @@ func TestVaultProvider_Fetch_OIDCAuthValidation(t *testing.T) {
 		{
 			name: "jwt auth without role",
 			config: map[string]interface{}{
-				"path":          "myapp/secret",
-				"auth":          "jwt",
+				"path": "myapp/secret",
+				"auth": map[string]interface{}{
+					"method": "jwt",
+				},
 				"_sso_id_token": "test-token",
 			},
 			wantErr: true,
-			errMsg:  "requires 'role' field",
+			errMsg:  "requires 'auth.role' field",
 		},
 		{
 			name: "unsupported auth method",
 			config: map[string]interface{}{
 				"path": "myapp/secret",
-				"auth": "invalid-method",
+				"auth": map[string]interface{}{
+					"method": "invalid-method",
+				},
 			},
 			wantErr: true,
 			errMsg:  "unsupported auth method",
@@ -197,7 +227,7 @@ func TestParseConfig(t *testing.T) {
 			config: map[string]interface{}{
 				"path":    "myapp/secret",
 				"address": "https://vault.example.com:8200",
-				"token":   "test-token",
+				"auth":    map[string]interface{}{"token": "test-token"},
 				"mount":   "secret-v2",
 			},
 			wantPath:    "myapp/secret",
@@ -222,7 +252,7 @@ func TestParseConfig(t *testing.T) {
 			config: map[string]interface{}{
 				"path":    "myapp/secret",
 				"address": "http://localhost:8200",
-				"token":   "dev-token",
+				"auth":    map[string]interface{}{"token": "dev-token"},
 			},
 			wantPath:    "myapp/secret",
 			wantAddress: "http://localhost:8200",
@@ -286,8 +316,8 @@ func TestParseConfig(t *testing.T) {
 			if cfg.Address != tt.wantAddress {
 				t.Errorf("parseConfig() Address = %v, want %v", cfg.Address, tt.wantAddress)
 			}
-			if cfg.Token != tt.wantToken {
-				t.Errorf("parseConfig() Token = %v, want %v", cfg.Token, tt.wantToken)
+			if token := authToken(cfg); token != tt.wantToken {
+				t.Errorf("parseConfig() Auth.Token = %v, want %v", token, tt.wantToken)
 			}
 			if cfg.Mount != tt.wantMount {
 				t.Errorf("parseConfig() Mount = %v, want %v", cfg.Mount, tt.wantMount)
@@ -321,6 +351,23 @@ func TestVaultProvider_Fetch_ConfigValidation(t *testing.T) {
 			wantErr: true,
 			errMsg:  "vault provider requires 'path' field",
 		},
+		{
+			name: "both path and paths",
+			config: map[string]interface{}{
+				"path":  "myapp/secret",
+				"paths": []interface{}{"app/db"},
+			},
+			wantErr: true,
+			errMsg:  "either 'path' or 'paths'",
+		},
+		{
+			name: "empty entry in paths",
+			config: map[string]interface{}{
+				"paths": []interface{}{"app/db", map[string]interface{}{"prefix": "CACHE_"}},
+			},
+			wantErr: true,
+			errMsg:  "requires a path in paths[1]",
+		},
 		{
 			name: "valid path but no token",
 			config: map[string]interface{}{
@@ -366,7 +413,7 @@ func TestVaultProvider_ConfigFields(t *testing.T) {
 	config := map[string]interface{}{
 		"path":    "test/path",
 		"address": "https://custom-vault.example.com:8200",
-		"token":   "custom-token-123",
+		"auth":    map[string]interface{}{"token": "custom-token-123"},
 		"mount":   "custom-secret-engine",
 	}
 
@@ -381,8 +428,8 @@ func TestVaultProvider_ConfigFields(t *testing.T) {
 	if cfg.Address != "https://custom-vault.example.com:8200" {
 		t.Errorf("Config.Address = %v, want %v", cfg.Address, "https://custom-vault.example.com:8200")
 	}
-	if cfg.Token != "custom-token-123" {
-		t.Errorf("Config.Token = %v, want %v", cfg.Token, "custom-token-123")
+	if token := authToken(cfg); token != "custom-token-123" {
+		t.Errorf("Config.Auth.Token = %v, want %v", token, "custom-token-123")
 	}
 	if cfg.Mount != "custom-secret-engine" {
 		t.Errorf("Config.Mount = %v, want %v", cfg.Mount, "custom-secret-engine")
@@ -406,8 +453,8 @@ func TestVaultProvider_ConfigWithOptionalFields(t *testing.T) {
 	if cfg.Address != "" {
 		t.Errorf("Config.Address = %v, want empty string", cfg.Address)
 	}
-	if cfg.Token != "" {
-		t.Errorf("Config.Token = %v, want empty string", cfg.Token)
+	if cfg.Auth != nil {
+		t.Errorf("Config.Auth = %v, want nil", cfg.Auth)
 	}
 	if cfg.Mount != "" {
 		t.Errorf("Config.Mount = %v, want empty string", cfg.Mount)
@@ -437,6 +484,14 @@ func TestVaultProvider_ConfigWithExtraFields(t *testing.T) {
 	}
 }
 
+// authToken returns the configured auth token, or an empty string if auth is not set
+func authToken(cfg *VaultConfig) string {
+	if cfg.Auth == nil {
+		return ""
+	}
+	return cfg.Auth.Token
+}
+
 // Helper function to check if a string contains a substring
 func containsSubstring(s, substr string) bool {
 	if len(substr) == 0 {
@@ -453,3 +508,339 @@ func containsSubstring(s, substr string) bool {
 	return false
 }
 
+// fakeJWTVault is a Vault server with JWT login, token lookup and renewal, and one KV v2 secret
+type fakeJWTVault struct {
+	*httptest.Server
+	logins  int
+	renews  int
+	ttl     int // Remaining TTL reported for valid tokens, in seconds
+	revoked map[string]bool
+}
+
+func newFakeJWTVault(t *testing.T) *fakeJWTVault {
+	t.Helper()
+	v := &fakeJWTVault{ttl: 3600, revoked: map[string]bool{}}
+	v.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		valid := strings.HasPrefix(token, "login-") && !v.revoked[token]
+		switch r.URL.Path {
+		case "/v1/auth/jwt/login":
+			v.logins++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": fmt.Sprintf("login-%d", v.logins), "lease_duration": 3600, "renewable": true},
+			})
+		case "/v1/auth/token/lookup-self":
+			if !valid {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"ttl": v.ttl, "creation_ttl": 3600, "renewable": true},
+			})
+		case "/v1/auth/token/renew-self":
+			v.renews++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": token, "lease_duration": 3600, "renewable": true},
+			})
+		case "/v1/secret/data/myapp":
+			if !valid {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"API_KEY": "secret"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(v.Close)
+	return v
+}
+
+func TestVaultProvider_Fetch_JWTTokenCaching(t *testing.T) {
+	keyring.MockInit()
+	server := newFakeJWTVault(t)
+
+	// An unsigned JWT is enough: Vault verifies it, and sstart only reads the identity
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://auth.example.com","sub":"alice"}`))
+	config := func(cacheToken bool) map[string]interface{} {
+		return map[string]interface{}{
+			"address":       server.URL,
+			"path":          "myapp",
+			"auth":          map[string]interface{}{"method": "jwt", "role": "dev", "cache_token": cacheToken},
+			"_sso_id_token": "header." + claims + ".signature",
+		}
+	}
+	fetch := func(cacheToken bool) {
+		t.Helper()
+		// A new provider per fetch, like separate sstart invocations
+		kvs, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config(cacheToken), nil)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if len(kvs) != 1 || kvs[0].Value != "secret" {
+			t.Fatalf("Fetch() = %v, want API_KEY", kvs)
+		}
+	}
+
+	fetch(true)
+	fetch(true)
+	if server.logins != 1 {
+		t.Errorf("expected the cached token to be reused, got %d logins", server.logins)
+	}
+
+	// Tokens past half of their lifetime are renewed
+	server.ttl = 600
+	fetch(true)
+	if server.logins != 1 || server.renews != 1 {
+		t.Errorf("expected the cached token to be renewed, got %d logins and %d renewals", server.logins, server.renews)
+	}
+
+	// A revoked token is replaced by a new login
+	server.ttl = 3600
+	server.revoked["login-1"] = true
+	fetch(true)
+	if server.logins != 2 {
+		t.Errorf("expected a new login after revocation, got %d logins", server.logins)
+	}
+
+	// Caching can be turned off
+	fetch(false)
+	if server.logins != 3 {
+		t.Errorf("expected a login with cache_token disabled, got %d logins", server.logins)
+	}
+}
+
+func TestVaultProvider_PurgeSession(t *testing.T) {
+	keyring.MockInit()
+	server := newFakeJWTVault(t)
+
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://auth.example.com","sub":"bob"}`))
+	idToken := "header." + claims + ".signature"
+	config := map[string]interface{}{
+		"address":          server.URL,
+		"path":             "myapp",
+		"auth":             map[string]interface{}{"method": "jwt", "role": "dev"},
+		"_sso_id_token":    idToken,
+		"_keyring_service": "sstart-cache-work",
+	}
+	fetch := func() {
+		t.Helper()
+		if _, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+	}
+
+	fetch()
+	if _, err := keyring.Get("sstart-cache-work-vault", identityIndexPrefix+oidc.Identity(idToken)); err != nil {
+		t.Fatalf("expected the token to be cached under the keyring service of the cache: %v", err)
+	}
+
+	// Tokens of other keyring services are kept
+	if n, err := (&VaultProvider{}).PurgeSession("", oidc.Identity(idToken)); err != nil || n != 0 {
+		t.Errorf("PurgeSession() with the default service = %d, %v, want 0", n, err)
+	}
+	n, err := (&VaultProvider{}).PurgeSession("sstart-cache-work", oidc.Identity(idToken))
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeSession() = %d, %v, want 1", n, err)
+	}
+
+	// The next fetch logs in again
+	fetch()
+	if server.logins != 2 {
+		t.Errorf("expected a new login after the session was purged, got %d logins", server.logins)
+	}
+}
+
+func TestVaultProvider_Fetch_Paths(t *testing.T) {
+	data := map[string]map[string]interface{}{
+		"/v1/secret/data/app/db":      {"data": map[string]interface{}{"HOST": "db.internal", "PASSWORD": "db-secret"}},
+		"/v1/secret/data/app/cache":   {"data": map[string]interface{}{"HOST": "cache.internal"}},
+		"/v1/secret/data/shared/smtp": {"data": map[string]interface{}{"SMTP_PASSWORD": "smtp-secret", "HOST": "smtp.internal"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		secret, ok := data[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": secret})
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"address": server.URL,
+		"token":   "test-token",
+		"paths": []interface{}{
+			map[string]interface{}{"path": "app/db", "prefix": "DB_"},
+			map[string]interface{}{"path": "app/cache", "prefix": "CACHE_"},
+			"shared/smtp",
+		},
+	}
+	kvs, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, kv := range kvs {
+		got[kv.Key] = kv.Value
+	}
+	want := map[string]string{
+		"DB_HOST":       "db.internal",
+		"DB_PASSWORD":   "db-secret",
+		"CACHE_HOST":    "cache.internal",
+		"SMTP_PASSWORD": "smtp-secret",
+		"HOST":          "smtp.internal",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Fetch() = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("Fetch() %s = %q, want %q", key, got[key], value)
+		}
+	}
+
+	// Keys are matched against the prefixed names
+	kvs, err = (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, map[string]string{"DB_PASSWORD": "DATABASE_PASSWORD"})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "DATABASE_PASSWORD" || kvs[0].Value != "db-secret" {
+		t.Errorf("Fetch() with keys = %v, want DATABASE_PASSWORD", kvs)
+	}
+
+	// A missing secret fails the whole fetch
+	config["paths"] = []interface{}{"app/db", "app/missing"}
+	if _, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil); err == nil || !strings.Contains(err.Error(), "app/missing") {
+		t.Errorf("Fetch() error = %v, want error for app/missing", err)
+	}
+}
+
+func TestVaultProvider_Fetch_AgentAuth(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "env-token")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The agent adds its auto-auth token, so requests must carry none
+		if token := r.Header.Get("X-Vault-Token"); token != "" {
+			t.Errorf("request sent with token %q", token)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]interface{}{"API_KEY": "secret"}},
+		})
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"address": server.URL,
+		"path":    "myapp",
+		"auth":    map[string]interface{}{"method": "agent"},
+	}
+	kvs, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(kvs) != 1 || kvs[0].Value != "secret" {
+		t.Fatalf("Fetch() = %v, want API_KEY", kvs)
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "remote address",
+			config:  map[string]interface{}{"address": "https://vault.example.com", "path": "myapp", "auth": map[string]interface{}{"method": "agent"}},
+			wantErr: "is not local",
+		},
+		{
+			name:    "token set",
+			config:  map[string]interface{}{"address": server.URL, "path": "myapp", "token": "my-token", "auth": map[string]interface{}{"method": "agent"}},
+			wantErr: "does not use a token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", tt.config, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Fetch() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultProvider_Fetch_ErrorClass(t *testing.T) {
+	// The Vault client retries throttled requests itself; the collector decides on retries
+	t.Setenv("VAULT_MAX_RETRIES", "0")
+
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusForbidden, provider.ErrAuth},
+		{http.StatusNotFound, provider.ErrNotFound},
+		{http.StatusTooManyRequests, provider.ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"errors":["denied"]}`))
+			}))
+			defer server.Close()
+
+			config := map[string]interface{}{"address": server.URL, "path": "myapp", "token": "test-token"}
+			_, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil)
+			if got := provider.ErrorClass(err); got != tt.want {
+				t.Errorf("ErrorClass(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProvider_Fetch_LeaseExpiry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/database/creds/app":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"lease_id":       "database/creds/app/abc",
+				"lease_duration": 600,
+				"data":           map[string]interface{}{"username": "v-app", "password": "pw"},
+			})
+		case "/v1/secret/data/app":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]interface{}{"API_KEY": "key"}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetch := func(mount, path string) []provider.KeyValue {
+		t.Helper()
+		config := map[string]interface{}{"address": server.URL, "token": "test-token", "mount": mount, "path": path}
+		kvs, err := (&VaultProvider{}).Fetch(secrets.NewEmptySecretContext(context.Background()), "test-map", config, nil)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		return kvs
+	}
+
+	before := time.Now()
+	for _, kv := range fetch("database", "creds/app") {
+		if kv.ExpiresAt.Before(before.Add(600*time.Second)) || kv.ExpiresAt.After(time.Now().Add(600*time.Second)) {
+			t.Errorf("%s expires at %v, want the end of the 10m lease", kv.Key, kv.ExpiresAt)
+		}
+	}
+	for _, kv := range fetch("secret", "app") {
+		if !kv.ExpiresAt.IsZero() {
+			t.Errorf("%s of a KV secret expires at %v, want no expiry", kv.Key, kv.ExpiresAt)
+		}
+	}
+}
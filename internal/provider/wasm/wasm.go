@@ -0,0 +1,387 @@
+// Package wasm runs provider plugins compiled to WebAssembly (WASI). Plugins are sandboxed: they
+// see no files, only the environment variables listed in their configuration, and can only make
+// HTTP requests to the hosts their configuration allows, through the host functions of sstart
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dirathea/sstart/internal/provider"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// PluginsDirEnv overrides the directory plugins are loaded from by name
+const PluginsDirEnv = "SSTART_PLUGINS_DIR"
+
+// hostModule is the name of the module of host functions imported by plugins
+const hostModule = "sstart"
+
+// maxHTTPBody bounds the size of HTTP response bodies returned to plugins
+const maxHTTPBody = 10 << 20
+
+// maxMemoryPages bounds the memory of plugins, in 64 KiB pages (256 MiB)
+const maxMemoryPages = 4096
+
+// maxOutput bounds what plugins write to stdout and stderr: the response is read into memory
+const maxOutput = 10 << 20
+
+// defaultTimeout bounds how long plugins run, unless their configuration sets a timeout
+const defaultTimeout = time.Minute
+
+// WasmConfig represents the configuration for WASM provider plugins
+type WasmConfig struct {
+	// Plugin is the name of a plugin in the plugins directory (<name>.wasm), or the path of a .wasm file
+	Plugin string `json:"plugin" yaml:"plugin"`
+	// Config is passed to the plugin as is
+	Config map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
+	// AllowedEnv lists the environment variables the plugin can read (default: none)
+	AllowedEnv []string `json:"allowed_env,omitempty" yaml:"allowed_env,omitempty"`
+	// AllowedHosts lists the hosts the plugin can send HTTP requests to (default: none)
+	AllowedHosts []string `json:"allowed_hosts,omitempty" yaml:"allowed_hosts,omitempty"`
+	// Timeout is how long the plugin can run, e.g. 30s (default: 1m)
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// pluginRequest is written to the standard input of a plugin
+type pluginRequest struct {
+	ID      string                 `json:"id"`
+	Profile string                 `json:"profile,omitempty"`
+	Config  map[string]interface{} `json:"config"`
+}
+
+// pluginResponse is read from the standard output of a plugin
+type pluginResponse struct {
+	Secrets map[string]string `json:"secrets"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// httpRequest is a request a plugin sends with the http_request host function
+type httpRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// httpResponse is the result of an http_request, read with the http_response host function
+type httpResponse struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// WasmProvider implements the provider interface for WASM plugins
+type WasmProvider struct {
+	client *http.Client
+}
+
+func init() {
+	provider.Register("wasm", func() provider.Provider {
+		return &WasmProvider{
+			client: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+		}
+	})
+}
+
+// Name returns the provider name
+func (p *WasmProvider) Name() string {
+	return "wasm"
+}
+
+// ConfigStruct returns the provider's configuration struct, used to generate the config JSON Schema
+func (p *WasmProvider) ConfigStruct() interface{} {
+	return &WasmConfig{}
+}
+
+// Fetch runs the plugin and returns the secrets it writes
+func (p *WasmProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	ctx := secretContext.Ctx
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	path, err := pluginPath(cfg.Plugin)
+	if err != nil {
+		return nil, err
+	}
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin '%s': %w", cfg.Plugin, err)
+	}
+
+	input, err := json.Marshal(pluginRequest{ID: mapID, Profile: secretContext.Profile, Config: cfg.Config})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := p.run(ctx, cfg, binary, secretContext.LookupEnv, bytes.NewReader(input), &stdout, &stderr); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin '%s' failed: %w: %s", cfg.Plugin, err, msg)
+		}
+		return nil, fmt.Errorf("plugin '%s' failed: %w", cfg.Plugin, err)
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin '%s' wrote an invalid response: %w", cfg.Plugin, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin '%s' failed: %s", cfg.Plugin, response.Error)
+	}
+
+	// Map keys according to configuration
+	kvs := make([]provider.KeyValue, 0, len(response.Secrets))
+	for key, value := range response.Secrets {
+		targetKey := key
+		if len(keys) > 0 {
+			mappedKey, exists := keys[key]
+			if !exists {
+				continue
+			}
+			if mappedKey != "==" {
+				targetKey = mappedKey
+			}
+		}
+		kvs = append(kvs, provider.KeyValue{Key: targetKey, Value: value})
+	}
+	return kvs, nil
+}
+
+// run instantiates the plugin as a WASI command with the given standard streams, and the allowed
+// environment variables looked up with lookupEnv. The plugin is stopped once its timeout expires
+// or it writes more than maxOutput to a stream, and cannot grow its memory past maxMemoryPages
+func (p *WasmProvider) run(ctx context.Context, cfg *WasmConfig, binary []byte, lookupEnv func(string) (string, bool), stdin io.Reader, stdout, stderr io.Writer) error {
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	ctx, cancel := context.WithTimeoutCause(ctx, cfg.timeout, fmt.Errorf("plugin did not finish within %s", cfg.timeout))
+	defer cancel()
+	limit := func(w io.Writer, stream string) io.Writer {
+		return &limitedWriter{w: w, n: maxOutput, exceeded: func() {
+			stop(fmt.Errorf("plugin wrote more than %d MiB to %s", maxOutput>>20, stream))
+		}}
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(maxMemoryPages)
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(cacheDir, "sstart", "wasm")); err == nil {
+			defer cache.Close(ctx)
+			runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+		}
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer r.Close(ctx)
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	host := newHTTPHost(p.client, cfg.AllowedHosts)
+	if _, err := r.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().WithFunc(host.request).Export("http_request").
+		NewFunctionBuilder().WithFunc(host.response).Export("http_response").
+		Instantiate(ctx); err != nil {
+		return err
+	}
+
+	// No file system is mounted, so the plugin cannot read files
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(cfg.Plugin).
+		WithStdin(stdin).
+		WithStdout(limit(stdout, "stdout")).
+		WithStderr(limit(stderr, "stderr")).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	for _, name := range cfg.AllowedEnv {
+		if value, ok := lookupEnv(name); ok {
+			moduleConfig = moduleConfig.WithEnv(name, value)
+		}
+	}
+
+	_, err := r.InstantiateWithConfig(ctx, binary, moduleConfig)
+	if ctx.Err() != nil {
+		// The output of a plugin that exits successfully after exceeding maxOutput is truncated
+		return context.Cause(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		return nil
+	}
+	return err
+}
+
+// limitedWriter writes at most n bytes to w, and calls exceeded when a write would go past them
+type limitedWriter struct {
+	w        io.Writer
+	n        int64
+	exceeded func()
+}
+
+func (l *limitedWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) > l.n {
+		l.exceeded()
+		return 0, errors.New("output limit exceeded")
+	}
+	n, err := l.w.Write(b)
+	l.n -= int64(n)
+	return n, err
+}
+
+// httpHost implements the HTTP host functions for one plugin run:
+//
+//	http_request(ptr, len uint32) uint32 sends the JSON httpRequest at ptr and returns the length of
+//	the JSON httpResponse, which http_response(ptr uint32) then copies to ptr
+type httpHost struct {
+	client       *http.Client
+	allowedHosts []string
+	last         []byte
+}
+
+// newHTTPHost returns the HTTP host functions for a plugin that can send requests to allowedHosts.
+// Redirects are followed only to allowed hosts
+func newHTTPHost(client *http.Client, allowedHosts []string) *httpHost {
+	h := &httpHost{allowedHosts: allowedHosts}
+	restricted := *client
+	restricted.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := h.checkURL(req.URL); err != nil {
+			return err
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	h.client = &restricted
+	return h
+}
+
+// checkURL returns an error unless u is an HTTP URL of an allowed host
+func (h *httpHost) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL '%s'", u)
+	}
+	if !slices.Contains(h.allowedHosts, u.Hostname()) {
+		return fmt.Errorf("host '%s' is not in allowed_hosts", u.Hostname())
+	}
+	return nil
+}
+
+func (h *httpHost) request(ctx context.Context, m api.Module, ptr, length uint32) uint32 {
+	data, ok := m.Memory().Read(ptr, length)
+	if !ok {
+		h.last, _ = json.Marshal(httpResponse{Error: "request is out of the plugin memory"})
+		return uint32(len(h.last))
+	}
+	h.last, _ = json.Marshal(h.do(ctx, data))
+	return uint32(len(h.last))
+}
+
+func (h *httpHost) response(_ context.Context, m api.Module, ptr uint32) {
+	m.Memory().Write(ptr, h.last)
+}
+
+// do sends an HTTP request of the plugin, if its host is allowed
+func (h *httpHost) do(ctx context.Context, data []byte) httpResponse {
+	var req httpRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return httpResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return httpResponse{Error: fmt.Sprintf("invalid URL '%s'", req.URL)}
+	}
+	if err := h.checkURL(u); err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, strings.NewReader(req.Body))
+	if err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	if err != nil {
+		return httpResponse{Error: err.Error()}
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		headers[name] = resp.Header.Get(name)
+	}
+	return httpResponse{Status: resp.StatusCode, Headers: headers, Body: string(body)}
+}
+
+// pluginPath returns the path of a plugin given by path, or by name in the plugins directory:
+// $SSTART_PLUGINS_DIR, or plugins in $XDG_CONFIG_HOME/sstart (default ~/.config/sstart/plugins)
+func pluginPath(plugin string) (string, error) {
+	if strings.HasSuffix(plugin, ".wasm") || strings.ContainsRune(plugin, filepath.Separator) {
+		return os.ExpandEnv(plugin), nil
+	}
+	dir := os.Getenv(PluginsDirEnv)
+	if dir == "" {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to find the plugins directory: %w", err)
+			}
+			configHome = filepath.Join(homeDir, ".config")
+		}
+		dir = filepath.Join(configHome, "sstart", "plugins")
+	}
+	return filepath.Join(dir, plugin+".wasm"), nil
+}
+
+func parseConfig(config map[string]interface{}) (*WasmConfig, error) {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	cfg := &WasmConfig{}
+	if err := json.Unmarshal(jsonData, cfg); err != nil {
+		return nil, fmt.Errorf("invalid wasm configuration: %w", err)
+	}
+	if cfg.Plugin == "" {
+		return nil, fmt.Errorf("wasm provider requires 'plugin' field")
+	}
+	if cfg.Config == nil {
+		cfg.Config = map[string]interface{}{}
+	}
+	cfg.timeout = defaultTimeout
+	if cfg.Timeout != "" {
+		if cfg.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || cfg.timeout <= 0 {
+			return nil, fmt.Errorf("invalid wasm timeout '%s': must be a positive duration such as 30s", cfg.Timeout)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,331 @@
+// Package schema generates the JSON Schema for the sstart configuration file.
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// ID is the URL the published schema is served from
+const ID = "https://raw.githubusercontent.com/dirathea/sstart/main/schema/sstart.schema.json"
+
+// optionalFields lists config fields without omitempty that are nevertheless optional
+var optionalFields = map[string]bool{
+	"Config.inherit":      true,
+	"Config.providers":    true,
+	"CacheConfig.enabled": true,
+}
+
+// fieldOverrides replaces the generated schema of fields with custom YAML decoding
+var fieldOverrides = map[string]map[string]interface{}{
+	"OIDCConfig.scopes": {
+		"description": "OIDC scopes, as a list or a space-separated string",
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	},
+	"Config.provider_defaults": {
+		"type":                 "object",
+		"description":          "Default fields per provider kind, overridden by fields set on each provider",
+		"additionalProperties": map[string]interface{}{"type": "object"},
+	},
+	"Config.profiles": {
+		"type":                 "object",
+		"description":          "Named config overlays, merged over the rest of the config when selected with --profile",
+		"additionalProperties": map[string]interface{}{"type": "object"},
+	},
+	"Config.defaults": {
+		"type":                 "object",
+		"description":          "Values used for keys that no provider produced",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	},
+	"DotEnvConfig.paths": {
+		"type":        "array",
+		"description": ".env files loaded in order, later files overriding earlier ones",
+		"items": map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{
+					"type":     "object",
+					"required": []string{"path"},
+					"properties": map[string]interface{}{
+						"path":     map[string]interface{}{"type": "string"},
+						"optional": map[string]interface{}{"type": "boolean", "description": "Skip the file if it does not exist"},
+					},
+				},
+			},
+		},
+	},
+	"VaultConfig.paths": {
+		"type":        "array",
+		"description": "Secrets fetched concurrently and merged in order, later secrets overriding earlier ones",
+		"items": map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{
+					"type":     "object",
+					"required": []string{"path"},
+					"properties": map[string]interface{}{
+						"path":   map[string]interface{}{"type": "string"},
+						"prefix": map[string]interface{}{"type": "string", "description": "Prepended to the names of the secret's keys"},
+					},
+				},
+			},
+		},
+	},
+	"CacheConfig.ttl": {
+		"type":        "string",
+		"description": "Cache TTL as a duration, e.g. 5m or 1h",
+	},
+	"RotationConfig.max_age": {
+		"type":        "string",
+		"description": "Maximum age of secrets since they were last rotated, as a duration or a number of days, e.g. 720h or 90d",
+	},
+}
+
+// Generate returns the JSON Schema for the config file, including the configuration
+// of every registered provider that implements provider.ConfigDescriber
+func Generate() map[string]interface{} {
+	root := structSchema(reflect.TypeOf(config.Config{}), "yaml")
+	properties := root["properties"].(map[string]interface{})
+
+	properties["providers"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Secret providers, fetched in order",
+		"items":       providerSchema(),
+	}
+	properties[config.IncludeKey] = map[string]interface{}{
+		"description": "Other config files to merge into this one, relative to this file",
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = ID
+	root["title"] = "sstart configuration"
+	return root
+}
+
+// JSON returns the indented JSON encoding of the schema
+func JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(Generate(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// providerSchema returns the schema of a provider entry
+// Common fields are always allowed; provider-specific fields are selected by kind
+func providerSchema() map[string]interface{} {
+	kinds := provider.List()
+	sort.Strings(kinds)
+
+	stringMap := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}
+
+	var conditions []interface{}
+	for _, kind := range kinds {
+		prov, err := provider.New(kind)
+		if err != nil {
+			continue
+		}
+		describer, ok := prov.(provider.ConfigDescriber)
+		if !ok {
+			continue
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{"kind": map[string]interface{}{"const": kind}},
+				"required":   []string{"kind"},
+			},
+			"then": structSchema(reflect.TypeOf(describer.ConfigStruct()), "json"),
+		})
+	}
+
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"kind"},
+		"properties": map[string]interface{}{
+			"kind": map[string]interface{}{
+				"description": "Provider kind",
+				"enum":        kinds,
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Provider ID (defaults to kind; required if several providers share a kind)",
+			},
+			"keys": map[string]interface{}{
+				"type":        "object",
+				"description": "Key mappings (source key: target key, list of target keys, or {to, transform}; == keeps the name)",
+				"additionalProperties": map[string]interface{}{
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						map[string]interface{}{"type": "array", "minItems": 1, "items": map[string]interface{}{"type": "string"}},
+						map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": false,
+							"properties": map[string]interface{}{
+								"to": map[string]interface{}{
+									"description": "Target key or list of target keys (default: keep the name)",
+									"oneOf": []interface{}{
+										map[string]interface{}{"type": "string"},
+										map[string]interface{}{"type": "array", "minItems": 1, "items": map[string]interface{}{"type": "string"}},
+									},
+								},
+								"transform": map[string]interface{}{
+									"description": "Transforms applied to the value, in order",
+									"oneOf": []interface{}{
+										map[string]interface{}{"type": "string", "enum": config.TransformNames()},
+										map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": config.TransformNames()}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"env": stringMap,
+			"uses": map[string]interface{}{
+				"type":        "array",
+				"description": "IDs of providers whose secrets this provider can access",
+				"items":       map[string]interface{}{"type": "string"},
+			},
+			"require_claims": map[string]interface{}{
+				"type":        "object",
+				"description": "SSO ID token claims required before fetching (claim: value or list of values)",
+			},
+			"hidden": map[string]interface{}{
+				"description": "Keep secrets out of the environment (true, or a list of keys) while still available to providers that use this one",
+				"oneOf": []interface{}{
+					map[string]interface{}{"type": "boolean"},
+					map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				},
+			},
+			"defaults": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Values used for keys that no provider produced, preferred over the global defaults",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"enabled": map[string]interface{}{
+				"description": "Whether the provider is used: a boolean or a template evaluating to true/false",
+				"type":        []string{"boolean", "string"},
+			},
+		},
+	}
+	if len(conditions) > 0 {
+		schema["allOf"] = conditions
+	}
+	return schema
+}
+
+// structSchema builds an object schema from a struct type using the given tag for field names
+// Fields without omitempty are required
+func structSchema(t reflect.Type, tag string) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty, skip := fieldName(field, tag)
+		if skip {
+			continue
+		}
+
+		key := t.Name() + "." + name
+		if override, ok := fieldOverrides[key]; ok {
+			properties[name] = override
+		} else {
+			properties[name] = typeSchema(field.Type, tag)
+		}
+		if !omitempty && !optionalFields[key] {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldName returns the config key of a struct field
+func fieldName(field reflect.StructField, tag string) (name string, omitempty bool, skip bool) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok && tag == "json" {
+		// Fall back to the yaml tag; encoding/json matches field names case-insensitively
+		value, ok = field.Tag.Lookup("yaml")
+	}
+	if !ok {
+		return strings.ToLower(field.Name), false, false
+	}
+
+	parts := strings.Split(value, ",")
+	if parts[0] == "-" {
+		return "", false, true
+	}
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// typeSchema returns the schema of a Go type
+func typeSchema(t reflect.Type, tag string) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), tag)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), tag)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), tag)}
+	case reflect.Struct:
+		return structSchema(t, tag)
+	default:
+		// interface{} and anything else accepts any value
+		return map[string]interface{}{}
+	}
+}
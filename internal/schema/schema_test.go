@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	_ "github.com/dirathea/sstart/internal/provider/aws"
+	_ "github.com/dirathea/sstart/internal/provider/azurekeyvault"
+	_ "github.com/dirathea/sstart/internal/provider/bitwarden"
+	_ "github.com/dirathea/sstart/internal/provider/bucket"
+	_ "github.com/dirathea/sstart/internal/provider/doppler"
+	_ "github.com/dirathea/sstart/internal/provider/dotenv"
+	_ "github.com/dirathea/sstart/internal/provider/env"
+	_ "github.com/dirathea/sstart/internal/provider/gcsm"
+	_ "github.com/dirathea/sstart/internal/provider/infisical"
+	_ "github.com/dirathea/sstart/internal/provider/kubernetes"
+	_ "github.com/dirathea/sstart/internal/provider/onepassword"
+	_ "github.com/dirathea/sstart/internal/provider/template"
+	_ "github.com/dirathea/sstart/internal/provider/vault"
+	_ "github.com/dirathea/sstart/internal/provider/wasm"
+)
+
+func TestGenerate(t *testing.T) {
+	s := Generate()
+
+	if s["$id"] != ID {
+		t.Errorf("expected $id %q, got %v", ID, s["$id"])
+	}
+
+	properties, ok := s["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected properties in schema")
+	}
+	for _, name := range []string{"providers", "sso", "cache", "mcp", "include", "inherit"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("expected property %q in schema", name)
+		}
+	}
+
+	items := properties["providers"].(map[string]interface{})["items"].(map[string]interface{})
+	kind := items["properties"].(map[string]interface{})["kind"].(map[string]interface{})
+	kinds, ok := kind["enum"].([]string)
+	if !ok {
+		t.Fatalf("expected kind enum, got %v", kind["enum"])
+	}
+	for _, want := range []string{"aws_secretsmanager", "vault", "dotenv", "template"} {
+		found := false
+		for _, k := range kinds {
+			if k == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected kind %q in enum %v", want, kinds)
+		}
+	}
+}
+
+func TestPublishedSchemaUpToDate(t *testing.T) {
+	want, err := JSON()
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+	got, err := os.ReadFile("../../schema/sstart.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read published schema: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("schema/sstart.schema.json is out of date, run 'make schema'")
+	}
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	// ErrInterrupted is the cause of collections cancelled by one of the signals of WithInterrupt
+	ErrInterrupted = errors.New("interrupted")
+	// ErrCollectTimeout is the cause of collections that exceeded the time of WithMaxCollectTime
+	ErrCollectTimeout = errors.New("maximum collect time exceeded")
+)
+
+// WithMaxCollectTime returns an option that cancels collections taking longer than d, including
+// SSO authentication and retries (0 for no limit)
+func WithMaxCollectTime(d time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.maxCollectTime = d
+	}
+}
+
+// WithInterrupt returns an option that cancels collections when the process receives one of
+// signals, e.g. on Ctrl+C, instead of waiting for a hung backend. Only the first signal is
+// caught, so a second one has its default effect
+func WithInterrupt(signals ...os.Signal) CollectorOption {
+	return func(c *Collector) {
+		c.interruptSignals = signals
+	}
+}
+
+// collectContext returns the context of a collection, cancelled with ErrInterrupted or
+// ErrCollectTimeout as configured, and the function releasing it
+func (c *Collector) collectContext(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	release := func() { cancel(nil) }
+	if c.maxCollectTime > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, c.maxCollectTime, fmt.Errorf("%w (%s)", ErrCollectTimeout, c.maxCollectTime))
+		release = func() { cancelTimeout(); cancel(nil) }
+	}
+	if len(c.interruptSignals) == 0 {
+		return ctx, release
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, c.interruptSignals...)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(fmt.Errorf("%w (%s)", ErrInterrupted, sig))
+		case <-ctx.Done():
+		}
+	}()
+	releaseTimeout := release
+	return ctx, func() {
+		signal.Stop(signals)
+		releaseTimeout()
+	}
+}
+
+// cancellable calls fn, returning early with the cause of ctx once it is done, so that a
+// provider library or prompt that ignores its context cannot hold up the collection. fn is
+// left to finish in the background, so whatever it uses must not be shared once ctx is done
+func cancellable[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil && ctx.Err() != nil {
+			// Report why the context was cancelled rather than how fn noticed
+			return r.value, context.Cause(ctx)
+		}
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
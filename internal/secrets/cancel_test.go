@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// hangingProvider blocks its fetches until release is closed, ignoring their context
+type hangingProvider struct {
+	release chan struct{}
+}
+
+func (p *hangingProvider) Name() string { return "test_hanging" }
+
+func (p *hangingProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	<-p.release
+	return []provider.KeyValue{{Key: "KEY", Value: "value"}}, nil
+}
+
+func TestFetchWithRetry_CancelledFetchDropsInstance(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	provider.Register("test_hanging", func() provider.Provider { return &hangingProvider{release: release} })
+
+	c := NewCollector(&config.Config{})
+	providerCfg := &config.ProviderConfig{ID: "hung", Kind: "test_hanging"}
+	client, err := c.clients.acquire(providerCfg, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to acquire client: %v", err)
+	}
+	hung := client.prov
+
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 50*time.Millisecond, ErrCollectTimeout)
+	defer cancel()
+	_, err = c.fetchWithRetry(ctx, client, NewEmptySecretContext(ctx), providerCfg.ID, nil, nil)
+	client.release()
+	if !errors.Is(err, ErrCollectTimeout) {
+		t.Fatalf("expected the fetch to stop with the cause of the context, got %v", err)
+	}
+
+	// The next fetch must not share the instance still running the abandoned fetch
+	client, err = c.clients.acquire(providerCfg, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed to acquire client: %v", err)
+	}
+	defer client.release()
+	if client.prov == hung {
+		t.Error("expected the instance of the cancelled fetch to be replaced")
+	}
+}
@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/oidc"
+)
+
+// checkRequiredClaims verifies that the SSO ID token satisfies the provider's require_claims
+// Every required value must be present in the claim: list claims (e.g. groups) must contain it,
+// scalar claims must equal it
+func (c *Collector) checkRequiredClaims(providerID string, required map[string]interface{}) error {
+	if len(required) == 0 {
+		return nil
+	}
+
+	c.tokenMu.RLock()
+	idToken := c.idToken
+	c.tokenMu.RUnlock()
+
+	if idToken == "" {
+		return fmt.Errorf("provider '%s' requires SSO claims but no SSO ID token is available (configure sso.oidc and log in)", providerID)
+	}
+
+	claims, err := oidc.ParseIDTokenClaims(idToken)
+	if err != nil {
+		return fmt.Errorf("provider '%s': failed to read SSO identity claims: %w", providerID, err)
+	}
+
+	for name, want := range required {
+		got, ok := claims[name]
+		if !ok {
+			return fmt.Errorf("provider '%s': your SSO identity has no %q claim (required by require_claims)", providerID, name)
+		}
+
+		have := claimValues(got)
+		for _, value := range claimValues(want) {
+			if !containsString(have, value) {
+				return fmt.Errorf("provider '%s': your SSO identity lacks %s %q (has: %s)", providerID, name, value, strings.Join(have, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// claimValues flattens a claim value into its string representations
+func claimValues(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// providerClient is a provider instance reused across fetches, so that the clients it
+// authenticates (Vault tokens, AWS SDK clients, Infisical sessions) are not recreated on every collection
+type providerClient struct {
+	// mu serializes fetches, since provider instances are not safe for concurrent use
+	mu          sync.Mutex
+	prov        provider.Provider
+	kind        string
+	fingerprint string
+	// used is set after the first fetch, when the instance may hold an authenticated client
+	used bool
+}
+
+// renew replaces the provider instance with a new one, dropping its client
+func (pc *providerClient) renew() error {
+	prov, err := provider.New(pc.kind)
+	if err != nil {
+		return err
+	}
+	pc.prov = prov
+	pc.used = false
+	return nil
+}
+
+// clientRegistry holds one provider instance per provider ID
+type clientRegistry struct {
+	mu      sync.Mutex
+	clients map[string]*providerClient
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{clients: make(map[string]*providerClient)}
+}
+
+// acquire returns the provider instance for providerCfg, locked for the caller until it calls release
+// A new instance replaces the previous one when the expanded config changed, e.g. after an SSO token refresh
+func (r *clientRegistry) acquire(providerCfg *config.ProviderConfig, expandedConfig map[string]interface{}) (*providerClient, error) {
+	fingerprint, err := configFingerprint(providerCfg.Kind, expandedConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	pc, ok := r.clients[providerCfg.ID]
+	if !ok || pc.fingerprint != fingerprint {
+		pc = &providerClient{kind: providerCfg.Kind, fingerprint: fingerprint}
+		if err := pc.renew(); err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+		r.clients[providerCfg.ID] = pc
+	}
+	r.mu.Unlock()
+
+	pc.mu.Lock()
+	return pc, nil
+}
+
+// release marks the instance as used and unlocks it
+func (pc *providerClient) release() {
+	pc.used = true
+	pc.mu.Unlock()
+}
+
+// configFingerprint returns a hash of a provider's kind and expanded config, including injected SSO tokens
+func configFingerprint(kind string, expandedConfig map[string]interface{}) (string, error) {
+	data, err := json.Marshal(expandedConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint provider config: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(kind+"\x00"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
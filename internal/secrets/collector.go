@@ -2,15 +2,25 @@ package secrets
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/dirathea/sstart/internal/audit"
 	"github.com/dirathea/sstart/internal/cache"
 	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/metrics"
+	"github.com/dirathea/sstart/internal/notify"
 	"github.com/dirathea/sstart/internal/oidc"
 	"github.com/dirathea/sstart/internal/provider"
+	"github.com/dirathea/sstart/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -18,16 +28,68 @@ const (
 	AccessTokenConfigKey = "_sso_access_token"
 	// IDTokenConfigKey is the key used to inject ID token into provider config
 	IDTokenConfigKey = "_sso_id_token"
+	// KeyringServiceConfigKey is the key used to inject the keyring service of the cache into
+	// provider config, which the keyring entries of providers follow
+	KeyringServiceConfigKey = "_keyring_service"
 )
 
+// ErrAgentUnavailable is returned by an Agent when no agent is running; the collector then collects locally
+var ErrAgentUnavailable = errors.New("sstart agent is not running")
+
+// Agent collects secrets in another process that keeps SSO sessions and secrets in memory (see sstart agent)
+type Agent interface {
+	// Collect returns the secrets of the given providers of cfg, and the ID of the provider each came from
+	Collect(ctx context.Context, cfg *config.Config, providerIDs []string) (provider.Secrets, map[string]string, error)
+}
+
 // Collector collects secrets from all configured providers
 type Collector struct {
-	config      *config.Config
+	config *config.Config
+	// ssoClient is created on first use, so that runs whose providers don't use SSO skip it
+	ssoOnce     sync.Once
 	ssoClient   *oidc.Client
+	ssoErr      error
+	tokenMu     sync.RWMutex
 	accessToken string
 	idToken     string
 	forceAuth   bool
-	cache       *cache.Cache
+	// reauthPrompt is called before an interactive login that replaces an expired session
+	reauthPrompt func(reason string) error
+	cache        *cache.Cache
+	logger       *slog.Logger
+	// command is the command line recorded in the audit log
+	command  string
+	audit    *audit.Logger
+	auditErr error
+	// timingsOut receives a per-phase timing summary after each collection (nil to disable)
+	timingsOut io.Writer
+	// clients reuses provider instances, and the clients they authenticate, across collections
+	clients *clientRegistry
+	// limiter spaces the requests to the backends according to rate_limits (nil if none)
+	limiter *rateLimiter
+	// agent collects on behalf of this collector when it is running (nil to always collect locally)
+	agent Agent
+	// nonInteractive fails instead of starting a browser login (e.g. in CI or the shell hook)
+	nonInteractive bool
+	// mask receives the values of collected secrets, to redact them from CI logs (nil to disable)
+	mask func(values []string)
+	// notifications enables the notifications configured in the config, sent by notifier
+	notifications bool
+	notifier      *notify.Notifier
+	// hooks are called while secrets are collected, starting with the hook commands of the config
+	hooks []Hooks
+	// fetchedAt records when the secrets of each provider were fetched from its backend
+	fetchedAt   map[string]time.Time
+	fetchedAtMu sync.Mutex
+	// expiresAt records when the secrets of each provider expire, guarded by fetchedAtMu
+	expiresAt map[string]time.Time
+	// maxCollectTime cancels collections taking longer (0 for no limit)
+	maxCollectTime time.Duration
+	// interruptSignals cancel the collection in progress when received
+	interruptSignals []os.Signal
+	// environment is the one config templates are expanded in: that of the command collected
+	// for, or this process's when its Dir and Env are empty
+	environment *config.Environment
 }
 
 // CollectorOption is a functional option for configuring the Collector
@@ -40,45 +102,199 @@ func WithForceAuth(forceAuth bool) CollectorOption {
 	}
 }
 
+// WithReauthPrompt returns an option that sets a function called before an interactive login
+// when the stored SSO session has expired and could not be refreshed. Returning an error aborts the login.
+func WithReauthPrompt(prompt func(reason string) error) CollectorOption {
+	return func(c *Collector) {
+		c.reauthPrompt = prompt
+	}
+}
+
+// WithLogger returns an option that sets the logger used for debug output (e.g., cache hits and misses)
+func WithLogger(logger *slog.Logger) CollectorOption {
+	return func(c *Collector) {
+		c.logger = logger
+	}
+}
+
+// WithCommand returns an option that sets the command recorded in the audit log for each access
+func WithCommand(command string) CollectorOption {
+	return func(c *Collector) {
+		c.command = command
+	}
+}
+
+// WithTimings returns an option that writes a per-provider and per-phase timing summary to w
+// after each collection, to help diagnose slow startups
+func WithTimings(w io.Writer) CollectorOption {
+	return func(c *Collector) {
+		c.timingsOut = w
+	}
+}
+
+// WithAgent returns an option that collects through a running agent, falling back to collecting
+// locally when it returns ErrAgentUnavailable
+func WithAgent(agent Agent) CollectorOption {
+	return func(c *Collector) {
+		c.agent = agent
+	}
+}
+
+// WithNonInteractive returns an option that makes SSO authentication fail instead of starting
+// a browser login, for environments where nobody can complete it (e.g. CI)
+func WithNonInteractive(nonInteractive bool) CollectorOption {
+	return func(c *Collector) {
+		c.nonInteractive = nonInteractive
+	}
+}
+
+// WithMasker returns an option that passes the values of collected secrets to mask after each
+// collection, e.g. to register them with the log masking of a CI
+func WithMasker(mask func(values []string)) CollectorOption {
+	return func(c *Collector) {
+		c.mask = mask
+	}
+}
+
+// WithNotifications returns an option that sends the notifications configured in the config
+// when collecting fails or required keys are missing, for long-running modes
+func WithNotifications(enabled bool) CollectorOption {
+	return func(c *Collector) {
+		c.notifications = enabled
+	}
+}
+
+// WithEnvironment returns an option that expands config templates, and reads the variables of env
+// providers, in the working directory and environment of env instead of this process's, e.g.
+// those of the client of the agent
+func WithEnvironment(env *config.Environment) CollectorOption {
+	return func(c *Collector) {
+		c.environment = env
+	}
+}
+
+// environ returns the environment providers read, nil for this process's
+func (c *Collector) environ() []string {
+	if c.environment == nil {
+		return nil
+	}
+	return c.environment.Env
+}
+
 // NewCollector creates a new secrets collector
 func NewCollector(cfg *config.Config, opts ...CollectorOption) *Collector {
-	collector := &Collector{config: cfg}
+	collector := &Collector{
+		config:  cfg,
+		logger:  slog.New(slog.DiscardHandler),
+		clients: newClientRegistry(),
+		limiter: newRateLimiter(cfg.RateLimits),
+	}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(collector)
 	}
 
-	// Initialize SSO client if configured
-	if cfg.SSO != nil && cfg.SSO.OIDC != nil {
-		client, err := oidc.NewClient(cfg.SSO.OIDC)
+	if cfg.Hooks != nil {
+		collector.hooks = append(commandHooks(cfg), collector.hooks...)
+	}
+
+	// exec() templates run commands, so like hooks they need the config file to be allowed
+	env := config.Environment{}
+	if collector.environment != nil {
+		env = *collector.environment
+	}
+	env.Trusted = cfg.CheckExecTrusted
+	collector.environment = &env
+
+	// Initialize the audit log if enabled
+	if cfg.IsAuditEnabled() {
+		auditCfg, err := config.ExpandIn(cfg.Audit, collector.environment)
 		if err == nil {
-			collector.ssoClient = client
+			collector.audit, err = audit.New(auditCfg.Path, collector.command)
+		}
+		if err != nil {
+			collector.auditErr = fmt.Errorf("audit log: %w", err)
 		}
 	}
 
+	if collector.notifications {
+		collector.notifier = notify.New(cfg, collector.logger)
+	}
+
 	// Initialize cache if enabled
 	if cfg.IsCacheEnabled() {
-		cacheOpts := []cache.Option{}
-		if ttl := cfg.GetCacheTTL(); ttl > 0 {
-			cacheOpts = append(cacheOpts, cache.WithTTL(ttl))
-		}
-		collector.cache = cache.New(cacheOpts...)
+		collector.cache = newCache(cfg, collector.logger)
 	}
 
 	return collector
 }
 
+// newCache returns the secret cache with the TTL and keyring service of the config
+func newCache(cfg *config.Config, logger *slog.Logger) *cache.Cache {
+	cacheOpts := []cache.Option{cache.WithLogger(logger)}
+	if ttl := cfg.GetCacheTTL(); ttl > 0 {
+		cacheOpts = append(cacheOpts, cache.WithTTL(ttl))
+	}
+	if service := cfg.GetCacheKeyringService(); service != "" {
+		cacheOpts = append(cacheOpts, cache.WithKeyringService(service))
+	}
+	return cache.New(cacheOpts...)
+}
+
 // Collect fetches secrets from all providers and combines them
 func (c *Collector) Collect(ctx context.Context, providerIDs []string) (provider.Secrets, error) {
+	secrets, _, err := c.CollectWithSources(ctx, providerIDs)
+	return secrets, err
+}
+
+// CollectWithSources is like Collect, and also returns the ID of the provider each secret came from
+func (c *Collector) CollectWithSources(ctx context.Context, providerIDs []string) (provider.Secrets, map[string]string, error) {
+	collectCtx, release := c.collectContext(ctx)
+	secrets, origins, err := c.collectWithSources(collectCtx, providerIDs)
+	release()
+	if c.notifier != nil {
+		c.notifier.Check(ctx, secrets, err)
+	}
+	return secrets, origins, err
+}
+
+func (c *Collector) collectWithSources(ctx context.Context, providerIDs []string) (_ provider.Secrets, _ map[string]string, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "sstart.collect")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if c.agent != nil && !isRefresh(ctx) {
+		secrets, origins, err := c.agent.Collect(ctx, c.config, providerIDs)
+		if !errors.Is(err, ErrAgentUnavailable) {
+			span.SetAttributes(attribute.Bool("sstart.agent", true))
+			if err == nil {
+				c.maskSecrets(secrets)
+			}
+			return secrets, origins, err
+		}
+		c.logger.Debug("collecting locally", "reason", err)
+	}
+
+	if c.auditErr != nil {
+		return nil, nil, c.auditErr
+	}
+
+	if c.cache != nil {
+		// Persist the cache lookups of this collection once, instead of on every lookup
+		defer c.cache.FlushMetrics()
+	}
+
+	var t *timings
+	if c.timingsOut != nil {
+		t = newTimings()
+		defer t.write(c.timingsOut)
+	}
+
 	secrets := make(provider.Secrets)
 	// Track secrets by provider ID for template providers
 	providerSecrets := make(provider.ProviderSecretsMap)
-
-	// Authenticate with SSO if configured
-	if err := c.authenticateSSO(ctx); err != nil {
-		return nil, fmt.Errorf("SSO authentication failed: %w", err)
-	}
+	// Track which provider each merged secret came from, for policy checks
+	origins := make(map[string]string)
 
 	// If no providers specified, use all providers in order
 	if len(providerIDs) == 0 {
@@ -87,101 +303,559 @@ func (c *Collector) Collect(ctx context.Context, providerIDs []string) (provider
 		}
 	}
 
-	// Collect from each provider
+	span.SetAttributes(attribute.StringSlice("sstart.providers", providerIDs))
+
+	// Authenticate with SSO if configured and one of the selected providers uses it
+	if c.needsSSO(providerIDs) {
+		authStart := time.Now()
+		if err := c.authenticateSSO(ctx); err != nil {
+			return nil, nil, &AuthError{Err: fmt.Errorf("SSO authentication failed: %w", err)}
+		}
+		if c.ssoClient != nil {
+			t.add("sso", PhaseAuth, "", authStart)
+		}
+	}
+
+	// Collect from each provider, after the providers it uses
+	ctx = withFetches(ctx)
+	order, err := dependencyOrder(c.config, providerIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	fetchedByID := make(map[string]provider.Secrets, len(order))
+	for _, providerID := range order {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fetched, err := c.fetchProvider(ctx, providerCfg, providerSecrets, t)
+		if err != nil {
+			return nil, nil, err
+		}
+		fetchedByID[providerID] = fetched
+	}
+
 	for _, providerID := range providerIDs {
 		providerCfg, err := c.config.GetProvider(providerID)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		// Merge secrets in the selected order (later providers override earlier ones)
+		// Hidden secrets stay available to other providers through providerSecrets only
+		for k, v := range fetchedByID[providerID] {
+			if !providerCfg.IsHidden(k) {
+				secrets[k] = v
+				origins[k] = providerID
+			}
+		}
+	}
+
+	applyDefaults(c.config, providerIDs, secrets, origins)
+
+	if secrets, err = c.preInject(ctx, secrets, origins); err != nil {
+		return nil, nil, err
+	}
+
+	if c.config.IsLintEnabled() {
+		for _, w := range lintSecrets(c.config.Lint, secrets, origins) {
+			c.logger.Warn("secret value looks misconfigured", "key", w.Key, "provider", w.Provider, "reason", w.Reason)
+		}
+	}
+
+	if err := checkPolicy(c.config.Policy, c.config.Profile, secrets, origins); err != nil {
+		return nil, nil, err
+	}
+
+	if violations := checkLimits(c.config.Limits, secrets, origins); len(violations) > 0 {
+		if !c.config.Limits.IsWarnOnly() {
+			return nil, nil, &LimitError{Violations: violations}
+		}
+		for _, v := range violations {
+			attrs := []any{"limit", v.Limit}
+			if v.Key != "" {
+				attrs = append(attrs, "key", v.Key, "provider", v.Provider)
+			}
+			c.logger.Warn("secrets exceed a limit", append(attrs, "reason", v.Reason)...)
 		}
+	}
 
-		// Expand template variables in config (e.g., in path fields)
-		expandedConfig := expandConfigTemplates(providerCfg.Config)
+	c.maskSecrets(secrets)
+	return secrets, origins, nil
+}
 
-		// Generate cache key based on provider configuration
-		cacheKey := cache.GenerateCacheKey(providerID, providerCfg.Kind, expandedConfig)
+// dependencyOrder returns the selected providers ordered so that each provider comes after the
+// selected providers it uses. Independent providers keep the selected order; dependency cycles are
+// reported as an error
+func dependencyOrder(cfg *config.Config, providerIDs []string) ([]string, error) {
+	selected := make(map[string]bool, len(providerIDs))
+	for _, providerID := range providerIDs {
+		selected[providerID] = true
+	}
 
-		// Try to get secrets from cache if enabled
-		if c.cache != nil {
-			if cachedSecrets, found := c.cache.Get(cacheKey); found {
-				// Use cached secrets
-				providerSecrets[providerID] = cachedSecrets
-				for k, v := range cachedSecrets {
-					secrets[k] = v
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(providerIDs))
+	order := make([]string, 0, len(providerIDs))
+
+	var visit func(providerID string, path []string) error
+	visit = func(providerID string, path []string) error {
+		switch state[providerID] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("provider dependency cycle: %s", strings.Join(append(path, providerID), " -> "))
+		}
+		state[providerID] = visiting
+		if providerCfg, err := cfg.GetProvider(providerID); err == nil {
+			for _, dep := range providerCfg.Uses {
+				// Providers that are not selected are not fetched, so their secrets stay empty
+				if dep == providerID || !selected[dep] {
+					continue
+				}
+				if err := visit(dep, append(path, providerID)); err != nil {
+					return err
 				}
-				continue
 			}
 		}
+		state[providerID] = done
+		order = append(order, providerID)
+		return nil
+	}
 
-		// Create provider instance
-		prov, err := provider.New(providerCfg.Kind)
+	for _, providerID := range providerIDs {
+		if err := visit(providerID, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// defaultsOrigin is the origin of secrets set from the global defaults; secrets set from the
+// defaults of a provider come from "defaults:<provider ID>"
+const defaultsOrigin = "defaults"
+
+// applyDefaults sets the keys that no provider produced from the defaults of the config and of the
+// selected providers. Provider defaults win over global ones, and later providers over earlier ones
+func applyDefaults(cfg *config.Config, providerIDs []string, secrets provider.Secrets, origins map[string]string) {
+	values := make(map[string]string)
+	sources := make(map[string]string)
+	for k, v := range cfg.Defaults {
+		values[k] = v
+		sources[k] = defaultsOrigin
+	}
+	for _, providerID := range providerIDs {
+		providerCfg, err := cfg.GetProvider(providerID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create provider '%s': %w", providerID, err)
+			continue
+		}
+		for k, v := range providerCfg.Defaults {
+			if !providerCfg.IsHidden(k) {
+				values[k] = v
+				sources[k] = defaultsOrigin + ":" + providerID
+			}
 		}
+	}
+
+	for k, v := range values {
+		if _, exists := secrets[k]; !exists {
+			secrets[k] = v
+			origins[k] = sources[k]
+		}
+	}
+}
+
+// maskSecrets passes the values of secrets to the masker, if one is set
+func (c *Collector) maskSecrets(secrets provider.Secrets) {
+	if c.mask == nil {
+		return
+	}
+	values := make([]string, 0, len(secrets))
+	for _, value := range secrets {
+		values = append(values, value)
+	}
+	c.mask(values)
+}
+
+// fetchProvider returns the secrets of a single provider, from the cache when possible,
+// and records them in providerSecrets for providers that use them
+func (c *Collector) fetchProvider(ctx context.Context, providerCfg *config.ProviderConfig, providerSecrets provider.ProviderSecretsMap, t *timings) (_ provider.Secrets, err error) {
+	providerID := providerCfg.ID
+	ctx, span := telemetry.Tracer().Start(ctx, "sstart.provider.fetch", trace.WithAttributes(
+		attribute.String("sstart.provider.id", providerID),
+		attribute.String("sstart.provider.kind", providerCfg.Kind),
+	))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	// Check SSO identity claims before touching the cache or the backend
+	if err := c.checkRequiredClaims(providerID, providerCfg.RequireClaims); err != nil {
+		return nil, &AuthError{Err: err}
+	}
 
-		// Inject SSO tokens into provider config if available
-		c.injectTokensIntoConfig(expandedConfig)
+	// Expand template variables in config (e.g., in path fields)
+	expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", providerID, err)
+	}
 
-		// Create SecretContext with resolver for providers
-		// Providers can optionally use SecretsResolver to access secrets from other providers
-		// This follows the principle of least privilege - providers only access secrets they explicitly request
-		// If 'uses' is specified, create a filtered resolver that only includes secrets from allowed providers
-		// If 'uses' is not specified, pass an empty resolver (no access to other providers' secrets)
-		var secretContext provider.SecretContext
-		if len(providerCfg.Uses) > 0 {
-			secretContext = NewSecretContext(ctx, providerSecrets, providerCfg.Uses)
+	// Generate cache key based on provider configuration
+	cacheKey := c.cacheKey(providerCfg, expandedConfig)
+
+	// Try to get secrets from cache if enabled
+	useCache := c.cache != nil && cacheable(providerCfg.Kind) && !skipsCache(ctx)
+	if useCache && !isRefresh(ctx) {
+		_, cacheSpan := telemetry.Tracer().Start(ctx, "sstart.cache.get")
+		cacheStart := time.Now()
+		cachedSecrets, cachedAt, found := c.cache.GetWithTime(cacheKey)
+		if found && expired(cachedExpiry(cachedSecrets, providerID)) {
+			// Secrets past the end of their lease are fetched again
+			found = false
+		}
+		if found {
+			t.add(providerID, PhaseCache, "hit", cacheStart)
 		} else {
-			// Pass empty provider secrets map when 'uses' is not defined
-			secretContext = NewEmptySecretContext(ctx)
+			t.add(providerID, PhaseCache, "miss", cacheStart)
+		}
+		metrics.ObserveCacheLookup(providerID, found)
+		cacheSpan.SetAttributes(attribute.Bool("sstart.cache.hit", found))
+		cacheSpan.End()
+		span.SetAttributes(attribute.Bool("sstart.cache.hit", found))
+		if found {
+			// Use cached secrets
+			c.setFetchedAt(providerID, cachedAt)
+			c.setExpiresAt(providerID, cachedExpiry(cachedSecrets, providerID))
+			providerSecrets[providerID] = cachedSecrets
+			span.SetAttributes(attribute.Int("sstart.secrets.count", len(cachedSecrets)))
+			if err := c.recordAccess(providerCfg, audit.SourceCache, cachedSecrets); err != nil {
+				return nil, err
+			}
+			return cachedSecrets, nil
 		}
+	}
+
+	if err := c.preFetch(ctx, providerID); err != nil {
+		return nil, err
+	}
+
+	// Identify the fetch before SSO tokens are injected, which differ between collections
+	dedupKey := fetchKey(providerCfg, expandedConfig)
+
+	// Inject SSO tokens into provider config if available
+	c.injectTokensIntoConfig(expandedConfig)
+
+	// Reuse the provider instance of earlier fetches, so its authenticated client is reused too
+	client, err := c.clients.acquire(providerCfg, expandedConfig)
+	if err != nil {
+		return nil, &FetchError{Provider: providerID, Err: fmt.Errorf("failed to create provider '%s': %w", providerID, err)}
+	}
+	defer client.release()
+	if _, ok := client.prov.(provider.IDIndependent); !ok {
+		// The secrets depend on the provider ID, so they are not shared with other providers
+		dedupKey = ""
+	}
 
-		// Fetch secrets from this provider's single source
-		kvs, err := prov.Fetch(secretContext, providerCfg.ID, expandedConfig, providerCfg.Keys)
+	// Create SecretContext with resolver for providers
+	// Providers can optionally use SecretsResolver to access secrets from other providers
+	// This follows the principle of least privilege - providers only access secrets they explicitly request
+	// If 'uses' is specified, create a filtered resolver that only includes secrets from allowed providers
+	// If 'uses' is not specified, pass an empty resolver (no access to other providers' secrets)
+	var secretContext provider.SecretContext
+	if len(providerCfg.Uses) > 0 {
+		secretContext = NewSecretContext(ctx, providerSecrets, providerCfg.Uses)
+	} else {
+		// Pass empty provider secrets map when 'uses' is not defined
+		secretContext = NewEmptySecretContext(ctx)
+	}
+	secretContext.Profile = c.config.Profile
+	secretContext.Env = c.environ()
+
+	// Fetch secrets from this provider's single source, unless an identical provider already did
+	start := time.Now()
+	var kvs []provider.KeyValue
+	if earlier, ok := fetchesFrom(ctx).get(dedupKey); ok {
+		c.logger.Debug("reusing the secrets of an identical provider", "provider", providerID, "from", earlier.providerID)
+		kvs = earlier.kvs
+		t.add(providerID, PhaseFetch, "same as "+earlier.providerID, start)
+	} else {
+		kvs, err = c.fetchWithRetry(ctx, client, secretContext, providerCfg.ID, expandedConfig, providerCfg.Keys)
+		metrics.ObserveProviderFetch(providerID, providerCfg.Kind, time.Since(start), err)
+		if providerCfg.Kind == "template" {
+			t.add(providerID, PhaseTemplate, "", start)
+		} else {
+			t.add(providerID, PhaseFetch, providerCfg.Kind, start)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch from provider '%s': %w", providerID, err)
+			return nil, &FetchError{Provider: providerID, Err: fmt.Errorf("failed to fetch from provider '%s': %w", providerID, err)}
+		}
+		fetchesFrom(ctx).put(dedupKey, providerID, kvs)
+	}
+	c.setFetchedAt(providerID, start)
+
+	// Store secrets by provider ID for resolver
+	fetched := make(provider.Secrets)
+	for _, kv := range kvs {
+		value := providerCfg.TransformValue(kv.Key, kv.Value)
+		fetched[kv.Key] = value
+		// Inject the secret under every name of a list in 'keys'
+		for _, alias := range providerCfg.KeyAliases[kv.Key] {
+			fetched[alias] = value
 		}
+	}
+	// Tell the command when secrets with a lease expire
+	expiresAt := earliestExpiry(kvs)
+	if !expiresAt.IsZero() {
+		fetched[ExpiryKey(providerID)] = expiresAt.UTC().Format(time.RFC3339)
+	}
+	c.setExpiresAt(providerID, expiresAt)
+	if fetched, err = c.postFetch(ctx, providerID, fetched); err != nil {
+		return nil, err
+	}
+	providerSecrets[providerID] = fetched
+	span.SetAttributes(attribute.Int("sstart.secrets.count", len(fetched)))
+
+	// Cache the secrets if caching is enabled
+	if useCache {
+		_, cacheSpan := telemetry.Tracer().Start(ctx, "sstart.cache.set")
+		_ = c.cache.SetWithIdentity(cacheKey, fetched, c.cacheIdentity(providerID))
+		cacheSpan.End()
+	}
 
-		// Store secrets by provider ID for resolver
-		providerSecrets[providerID] = make(provider.Secrets)
-		for _, kv := range kvs {
-			providerSecrets[providerID][kv.Key] = kv.Value
+	if err := c.recordAccess(providerCfg, audit.SourceProvider, fetched); err != nil {
+		return nil, err
+	}
+
+	// Warn about secrets due for rotation; a metadata failure must not block the fetch itself
+	if maxAge := c.config.RotationMaxAge(); maxAge > 0 {
+		metadata, err := fetchMetadata(client.prov, secretContext, providerCfg, expandedConfig)
+		if err != nil {
+			c.logger.Warn("failed to fetch secret metadata", "provider", providerID, "error", err)
+		}
+		for _, key := range sortedSecretKeys(fetched) {
+			if age, ok := SecretAge(metadata, key); ok && age > maxAge {
+				c.logger.Warn("secret is due for rotation", "key", key, "provider", providerID, "age", FormatAge(age), "max_age", FormatAge(maxAge))
+			}
 		}
+	}
+
+	return fetched, nil
+}
 
-		// Cache the secrets if caching is enabled
-		if c.cache != nil {
-			_ = c.cache.Set(cacheKey, providerSecrets[providerID])
+// FetchedAt returns when the secrets of a provider were last fetched from its backend by this
+// collector, which is before the collection when they came from the cache. It returns the zero
+// time for providers that were not fetched, e.g. when an agent collected the secrets
+func (c *Collector) FetchedAt(providerID string) time.Time {
+	c.fetchedAtMu.Lock()
+	defer c.fetchedAtMu.Unlock()
+	return c.fetchedAt[providerID]
+}
+
+// setFetchedAt records when the secrets of a provider were fetched
+func (c *Collector) setFetchedAt(providerID string, t time.Time) {
+	c.fetchedAtMu.Lock()
+	defer c.fetchedAtMu.Unlock()
+	if c.fetchedAt == nil {
+		c.fetchedAt = make(map[string]time.Time)
+	}
+	c.fetchedAt[providerID] = t
+}
+
+// FetchMetadata returns when the secrets of a provider were created and last rotated
+// It returns nil for providers whose backend does not expose these times
+func (c *Collector) FetchMetadata(ctx context.Context, providerID string) ([]provider.SecretMetadata, error) {
+	providerCfg, err := c.config.GetProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", providerID, err)
+	}
+	c.injectTokensIntoConfig(expandedConfig)
+	client, err := c.clients.acquire(providerCfg, expandedConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider '%s': %w", providerID, err)
+	}
+	defer client.release()
+
+	secretContext := NewEmptySecretContext(ctx)
+	secretContext.Profile = c.config.Profile
+	secretContext.Env = c.environ()
+	return fetchMetadata(client.prov, secretContext, providerCfg, expandedConfig)
+}
+
+// Write stores secrets in a provider that implements provider.Writer, and drops the provider's
+// cached secrets so the next collection fetches them again
+func (c *Collector) Write(ctx context.Context, providerID string, values map[string]string) error {
+	providerCfg, err := c.config.GetProvider(providerID)
+	if err != nil {
+		return err
+	}
+	if c.needsSSO([]string{providerID}) {
+		if err := c.authenticateSSO(ctx); err != nil {
+			return &AuthError{Err: fmt.Errorf("SSO authentication failed: %w", err)}
 		}
+	}
+	expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+	if err != nil {
+		return fmt.Errorf("provider '%s': %w", providerID, err)
+	}
+	cacheKey := c.cacheKey(providerCfg, expandedConfig)
+	c.injectTokensIntoConfig(expandedConfig)
+	client, err := c.clients.acquire(providerCfg, expandedConfig)
+	if err != nil {
+		return &FetchError{Provider: providerID, Err: fmt.Errorf("failed to create provider '%s': %w", providerID, err)}
+	}
+	defer client.release()
+
+	writer, ok := client.prov.(provider.Writer)
+	if !ok {
+		return fmt.Errorf("provider '%s' of kind '%s' does not support writing secrets", providerID, providerCfg.Kind)
+	}
+	secretContext := NewEmptySecretContext(ctx)
+	secretContext.Profile = c.config.Profile
+	secretContext.Env = c.environ()
+	if err := writer.Write(secretContext, providerID, expandedConfig, values); err != nil {
+		return &FetchError{Provider: providerID, Err: fmt.Errorf("failed to write to provider '%s': %w", providerID, err)}
+	}
+
+	if c.cache != nil {
+		_ = c.cache.ClearProvider(cacheKey)
+	}
+	return nil
+}
+
+// cacheKey returns the key of the cached secrets of a provider, from its expanded config, its key
+// mappings, aliases and value transforms, the post_fetch hook commands run for it, and the active
+// profile, since the secrets are cached after all of them applied
+func (c *Collector) cacheKey(providerCfg *config.ProviderConfig, expandedConfig map[string]interface{}) string {
+	transforms := make(map[string]interface{})
+	if len(providerCfg.KeyAliases) > 0 {
+		transforms["key_aliases"] = providerCfg.KeyAliases
+	}
+	if len(providerCfg.KeyTransforms) > 0 {
+		transforms["key_transforms"] = providerCfg.KeyTransforms
+	}
+	if commands := postFetchCommands(c.config, providerCfg.ID); len(commands) > 0 {
+		transforms["post_fetch"] = commands
+	}
+	return cache.GenerateCacheKey(providerCfg.ID, providerCfg.Kind, c.config.Profile, expandedConfig, providerCfg.Keys, transforms)
+}
 
-		// Merge secrets (later providers override earlier ones)
-		for _, kv := range kvs {
-			secrets[kv.Key] = kv.Value
+// fetchMetadata returns the metadata of a provider instance, or nil if it does not implement provider.MetadataProvider
+func fetchMetadata(prov provider.Provider, secretContext provider.SecretContext, providerCfg *config.ProviderConfig, expandedConfig map[string]interface{}) ([]provider.SecretMetadata, error) {
+	metadataProvider, ok := prov.(provider.MetadataProvider)
+	if !ok {
+		return nil, nil
+	}
+	metadata, err := metadataProvider.FetchMetadata(secretContext, providerCfg.ID, expandedConfig, providerCfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata from provider '%s': %w", providerCfg.ID, err)
+	}
+	return metadata, nil
+}
+
+// recordAccess writes the keys fetched from a provider to the audit log, if enabled
+// Access is denied when it cannot be recorded
+func (c *Collector) recordAccess(providerCfg *config.ProviderConfig, source string, fetched provider.Secrets) error {
+	if c.audit == nil {
+		return nil
+	}
+	err := c.audit.Record(audit.Entry{
+		Config:   c.config.Path,
+		Profile:  c.config.Profile,
+		Provider: providerCfg.ID,
+		Kind:     providerCfg.Kind,
+		Source:   source,
+		Keys:     audit.HashValues(fetched),
+	})
+	if err != nil {
+		return fmt.Errorf("provider '%s': %w", providerCfg.ID, err)
+	}
+	return nil
+}
+
+// needsSSO reports whether any of the providers authenticates with SSO or requires SSO claims
+func (c *Collector) needsSSO(providerIDs []string) bool {
+	if c.config.SSO == nil || c.config.SSO.OIDC == nil {
+		return false
+	}
+	for _, providerID := range providerIDs {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			continue
+		}
+		if len(providerCfg.RequireClaims) > 0 {
+			return true
+		}
+		prov, err := provider.New(providerCfg.Kind)
+		if err != nil {
+			continue
+		}
+		if consumer, ok := prov.(provider.SSOConsumer); ok && consumer.UsesSSO(providerCfg.Config) {
+			return true
 		}
 	}
+	return false
+}
 
-	return secrets, nil
+// cacheable reports whether secrets of the provider kind may be cached
+func cacheable(kind string) bool {
+	prov, err := provider.New(kind)
+	if err != nil {
+		return false
+	}
+	uncacheable, ok := prov.(provider.Uncacheable)
+	return !ok || !uncacheable.SkipCache()
+}
+
+// initSSO creates the SSO client from the expanded SSO config
+func (c *Collector) initSSO() {
+	if c.config.SSO == nil || c.config.SSO.OIDC == nil {
+		return
+	}
+	sso, err := config.ExpandIn(c.config.SSO, c.environment)
+	if err != nil {
+		c.ssoErr = err
+		return
+	}
+	c.ssoClient, c.ssoErr = oidc.NewClient(sso.OIDC, oidc.WithTokenStorage(sso.TokenStorage))
 }
 
 // authenticateSSO handles SSO authentication if configured
-func (c *Collector) authenticateSSO(ctx context.Context) error {
+func (c *Collector) authenticateSSO(ctx context.Context) (err error) {
+	c.ssoOnce.Do(c.initSSO)
+	if c.ssoErr != nil {
+		return c.ssoErr
+	}
 	if c.ssoClient == nil {
 		return nil
 	}
 
+	ctx, span := telemetry.Tracer().Start(ctx, "sstart.sso.authenticate")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	// Check if already authenticated (skip if --force-auth is set)
-	if !c.forceAuth && c.ssoClient.IsAuthenticated() {
-		// Try to get the access token
-		token, err := c.ssoClient.GetAccessToken(ctx)
+	// Tokens that are expired or about to expire are refreshed when a refresh token is available
+	var expiredReason string
+	if !c.forceAuth && (c.ssoClient.IsAuthenticated() || c.ssoClient.HasRefreshToken()) {
+		tokens, err := c.ssoClient.GetValidTokens(ctx)
 		if err == nil {
-			c.accessToken = token
-			// Also get ID token if available
-			tokens, err := c.ssoClient.GetTokens()
-			if err == nil && tokens.IDToken != "" {
-				c.idToken = tokens.IDToken
-			}
+			c.setTokens(c.checkSessionExpiry(ctx, tokens))
 			return nil
 		}
 		// Token expired or invalid, need to re-authenticate
+		expiredReason = fmt.Sprintf("your SSO session has expired (%v)", err)
+	} else if !c.forceAuth && c.ssoClient.TokensExist() {
+		expiredReason = "your SSO session has expired"
 	}
 
+	// The stored session, if any, is invalid or being replaced: its cached secrets must not outlive it
+	c.purgeSessionCache()
+
 	// If client credentials are configured, use client credentials flow (non-interactive)
 	// This is for CI/CD and service accounts - never fall back to browser
 	if c.ssoClient.HasClientCredentials() {
@@ -190,87 +864,98 @@ func (c *Collector) authenticateSSO(ctx context.Context) error {
 			return fmt.Errorf("client credentials authentication failed: %w", err)
 		}
 		// Store tokens
-		if result.Tokens != nil {
-			c.accessToken = result.Tokens.AccessToken
-			c.idToken = result.Tokens.IDToken
-		}
+		c.setTokens(result.Tokens)
 		return nil
 	}
 
 	// No client secret configured - use interactive login flow (browser-based)
+	if c.nonInteractive {
+		if expiredReason == "" {
+			expiredReason = "no SSO session found"
+		}
+		return fmt.Errorf("%s and interactive login is disabled in CI; set SSTART_SSO_SECRET to use the client credentials flow", expiredReason)
+	}
+	// Ask first when this replaces an expired session, so the login happens before the
+	// child process starts rather than surprising the user
+	if expiredReason != "" && c.reauthPrompt != nil {
+		// The prompt reads stdin, which the context cannot interrupt
+		if _, err := cancellable(ctx, func() (struct{}, error) { return struct{}{}, c.reauthPrompt(expiredReason) }); err != nil {
+			return err
+		}
+	}
 	result, err := c.ssoClient.Login(ctx)
 	if err != nil {
 		return err
 	}
 
 	// Store tokens
-	if result.Tokens != nil {
-		c.accessToken = result.Tokens.AccessToken
-		c.idToken = result.Tokens.IDToken
-	}
+	c.setTokens(result.Tokens)
 
 	return nil
 }
 
+// checkSessionExpiry warns when the SSO session is about to expire and tries to extend it silently
+func (c *Collector) checkSessionExpiry(ctx context.Context, tokens *oidc.Tokens) *oidc.Tokens {
+	if tokens.Expiry.IsZero() {
+		return tokens
+	}
+	remaining := time.Until(tokens.Expiry)
+	if remaining >= oidc.SessionWarningThreshold {
+		return tokens
+	}
+
+	if tokens.RefreshToken != "" {
+		refreshed, err := c.ssoClient.RefreshTokens(ctx)
+		if err == nil {
+			c.logger.Debug("SSO session refreshed before expiry")
+			return refreshed
+		}
+		c.logger.Warn("SSO session expires soon and could not be refreshed silently; run with --force-auth to log in again",
+			"expires_in", remaining.Round(time.Second), "error", err)
+		return tokens
+	}
+
+	c.logger.Warn("SSO session expires soon; run with --force-auth to log in again", "expires_in", remaining.Round(time.Second))
+	return tokens
+}
+
+// setTokens stores the SSO tokens injected into provider configs
+func (c *Collector) setTokens(tokens *oidc.Tokens) {
+	if tokens == nil {
+		return
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.accessToken = tokens.AccessToken
+	if tokens.IDToken != "" {
+		c.idToken = tokens.IDToken
+	}
+}
+
+// StartTokenRefresh keeps the SSO session alive for long-running commands by refreshing
+// tokens in the background before they expire. It stops when ctx is cancelled.
+// It only starts once a session exists, so call it after the first successful collection.
+func (c *Collector) StartTokenRefresh(ctx context.Context) {
+	c.ssoOnce.Do(c.initSSO)
+	if c.ssoClient == nil {
+		return
+	}
+	c.ssoClient.StartAutoRefresh(ctx, c.setTokens)
+}
+
 // injectTokensIntoConfig adds SSO tokens to the provider config for provider authentication
 func (c *Collector) injectTokensIntoConfig(config map[string]interface{}) {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
 	if c.accessToken != "" {
 		config[AccessTokenConfigKey] = c.accessToken
 	}
 	if c.idToken != "" {
 		config[IDTokenConfigKey] = c.idToken
 	}
-}
-
-// expandConfigTemplates expands template variables in config values
-// Supports {{ get_env(name="VAR", default="default") }} syntax
-func expandConfigTemplates(config map[string]interface{}) map[string]interface{} {
-	expanded := make(map[string]interface{})
-	for k, v := range config {
-		switch val := v.(type) {
-		case string:
-			expanded[k] = expandTemplate(val)
-		case map[string]interface{}:
-			expanded[k] = expandConfigTemplates(val)
-		case []interface{}:
-			expandedSlice := make([]interface{}, len(val))
-			for i, item := range val {
-				if str, ok := item.(string); ok {
-					expandedSlice[i] = expandTemplate(str)
-				} else {
-					expandedSlice[i] = item
-				}
-			}
-			expanded[k] = expandedSlice
-		default:
-			expanded[k] = v
-		}
-	}
-	return expanded
-}
-
-// expandTemplate expands template variables in a string
-// Supports {{ get_env(name="VAR", default="default") }} syntax
-func expandTemplate(template string) string {
-	// Simple implementation: expand environment variables
-	re := regexp.MustCompile(`\{\{\s*get_env\(name="([^"]+)",\s*default="([^"]+)"\)\s*\}\}`)
-	result := re.ReplaceAllStringFunc(template, func(match string) string {
-		matches := re.FindStringSubmatch(match)
-		if len(matches) == 3 {
-			envVar := matches[1]
-			defaultValue := matches[2]
-			if value := os.Getenv(envVar); value != "" {
-				return value
-			}
-			return defaultValue
-		}
-		return match
-	})
-
-	// Also support simple ${VAR} or $VAR syntax
-	result = os.ExpandEnv(result)
-
-	return result
+	if service := c.config.GetCacheKeyringService(); service != "" {
+		config[KeyringServiceConfigKey] = service
+	}
 }
 
 // Redact redacts secrets from text
@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+func TestCacheKey_KeyAliasesAndTransforms(t *testing.T) {
+	c := NewCollector(&config.Config{})
+	plain := &config.ProviderConfig{ID: "app", Kind: "env", Keys: map[string]string{"TOKEN": "API_TOKEN"}}
+	aliased := &config.ProviderConfig{ID: "app", Kind: "env", Keys: plain.Keys, KeyAliases: map[string][]string{"API_TOKEN": {"GH_TOKEN"}}}
+	transformed := &config.ProviderConfig{ID: "app", Kind: "env", Keys: plain.Keys, KeyTransforms: map[string][]string{"API_TOKEN": {"base64_decode"}}}
+
+	keys := map[string]string{"plain": c.cacheKey(plain, nil), "aliased": c.cacheKey(aliased, nil), "transformed": c.cacheKey(transformed, nil)}
+	if keys["plain"] == keys["aliased"] || keys["plain"] == keys["transformed"] || keys["aliased"] == keys["transformed"] {
+		t.Errorf("expected key aliases and transforms to change the cache key, got %v", keys)
+	}
+}
+
+// countingProvider counts its fetches and returns the provider ID as the value of KEY
+type countingProvider struct {
+	fetches *int
+}
+
+func (p *countingProvider) Name() string { return "test_counting" }
+
+func (p *countingProvider) Fetch(secretContext provider.SecretContext, mapID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	*p.fetches++
+	return []provider.KeyValue{{Key: "KEY", Value: mapID}}, nil
+}
+
+// sharedProvider is a countingProvider whose fetches may be shared between provider IDs
+type sharedProvider struct {
+	countingProvider
+}
+
+func (p *sharedProvider) IDIndependent() {}
+
+func TestCollect_DedupsOnlyIDIndependentProviders(t *testing.T) {
+	var counted, shared int
+	provider.Register("test_counting", func() provider.Provider { return &countingProvider{fetches: &counted} })
+	provider.Register("test_shared", func() provider.Provider { return &sharedProvider{countingProvider{fetches: &shared}} })
+
+	for _, tc := range []struct {
+		kind    string
+		fetches *int
+		want    int
+	}{
+		{"test_counting", &counted, 2},
+		{"test_shared", &shared, 1},
+	} {
+		cfg := &config.Config{Providers: []config.ProviderConfig{
+			{ID: "first", Kind: tc.kind, Config: map[string]interface{}{"path": "app"}},
+			{ID: "second", Kind: tc.kind, Config: map[string]interface{}{"path": "app"}},
+		}}
+		if _, err := NewCollector(cfg).Collect(context.Background(), nil); err != nil {
+			t.Fatalf("%s: Collect failed: %v", tc.kind, err)
+		}
+		if *tc.fetches != tc.want {
+			t.Errorf("%s: expected %d fetches, got %d", tc.kind, tc.want, *tc.fetches)
+		}
+	}
+}
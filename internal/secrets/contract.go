@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ContractResult is the outcome of checking one key of the contract
+type ContractResult struct {
+	Key      string
+	Provider string // ID of the provider the secret came from, empty if missing
+	Reason   string // Why the key breaks the contract, empty if it satisfies it
+}
+
+// CheckContract checks the collected secrets against the keys of the contract, in contract order.
+// Reasons never include values
+func CheckContract(contract *config.ContractConfig, secrets provider.Secrets, origins map[string]string) []ContractResult {
+	results := make([]ContractResult, 0, len(contract.Keys))
+	for _, key := range contract.Keys {
+		result := ContractResult{Key: key.Name, Provider: origins[key.Name]}
+		value, exists := secrets[key.Name]
+		switch {
+		case !exists:
+			if key.IsRequired() {
+				result.Reason = "missing"
+			}
+		case value == "":
+			if key.IsRequired() {
+				result.Reason = "empty"
+			}
+		case key.Pattern != "" && !matchesPattern(key.Pattern, value):
+			result.Reason = fmt.Sprintf("does not match pattern '%s'", key.Pattern)
+		case key.Format != "" && !matchesFormat(key.Format, value):
+			result.Reason = fmt.Sprintf("is not a valid %s", key.Format)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// UndeclaredKeys returns the collected keys that the contract does not declare, sorted
+func UndeclaredKeys(contract *config.ContractConfig, secrets provider.Secrets) []string {
+	declared := make(map[string]bool, len(contract.Keys))
+	for _, key := range contract.Keys {
+		declared[key.Name] = true
+	}
+	var undeclared []string
+	for _, key := range sortedSecretKeys(secrets) {
+		if !declared[key] {
+			undeclared = append(undeclared, key)
+		}
+	}
+	return undeclared
+}
+
+// matchesPattern reports whether the whole value matches the regular expression pattern
+func matchesPattern(pattern, value string) bool {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	return err == nil && re.MatchString(value)
+}
+
+// matchesFormat reports whether value is in one of config.ContractFormats
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "url":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Path != "")
+	case "int":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "number":
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case "bool":
+		_, err := strconv.ParseBool(value)
+		return err == nil
+	case "port":
+		port, err := strconv.Atoi(value)
+		return err == nil && port >= 1 && port <= 65535
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "json":
+		return json.Valid([]byte(value))
+	case "base64":
+		if _, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return true
+		}
+		_, err := base64.RawStdEncoding.DecodeString(value)
+		return err == nil
+	case "duration":
+		_, err := time.ParseDuration(value)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dirathea/sstart/internal/cache"
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// fetchesKey is the context key of the fetches of a collection
+type fetchesKey struct{}
+
+// fetchResult is what a provider fetched from its backend
+type fetchResult struct {
+	providerID string
+	kvs        []provider.KeyValue
+}
+
+// fetches records what providers fetched during one collection, so that providers whose
+// entries resolve to the same backend, path and keys (e.g. repeated by includes or profiles)
+// are fetched only once
+type fetches struct {
+	mu      sync.Mutex
+	results map[string]fetchResult
+}
+
+// withFetches returns a context in which identical fetches are made once
+func withFetches(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fetchesKey{}, &fetches{results: make(map[string]fetchResult)})
+}
+
+// fetchesFrom returns the fetches of the collection of ctx, or nil outside of a collection
+func fetchesFrom(ctx context.Context) *fetches {
+	f, _ := ctx.Value(fetchesKey{}).(*fetches)
+	return f
+}
+
+// fetchKey identifies what a provider fetches, regardless of its ID. Providers that use the
+// secrets of other providers have no key, since their result depends on those secrets. The key
+// is only used for providers whose result does not depend on their ID (see provider.IDIndependent)
+func fetchKey(providerCfg *config.ProviderConfig, expandedConfig map[string]interface{}) string {
+	if len(providerCfg.Uses) > 0 || providerCfg.Kind == "template" {
+		return ""
+	}
+	return cache.GenerateCacheKey("", providerCfg.Kind, "", map[string]interface{}{
+		"config": expandedConfig,
+		"keys":   providerCfg.Keys,
+	}, nil, nil)
+}
+
+// get returns the result of an identical fetch made earlier in the collection
+func (f *fetches) get(key string) (fetchResult, bool) {
+	if f == nil || key == "" {
+		return fetchResult{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result, ok := f.results[key]
+	return result, ok
+}
+
+// put records the result of a fetch
+func (f *fetches) put(key, providerID string, kvs []provider.KeyValue) {
+	if f == nil || key == "" {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[key] = fetchResult{providerID: providerID, kvs: kvs}
+}
@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// Kinds of changes reported by Drift
+const (
+	DriftAdded   = "added"
+	DriftRemoved = "removed"
+	DriftChanged = "changed"
+)
+
+// skipCacheKey marks a context whose collection neither reads nor updates the cache
+type skipCacheKey struct{}
+
+// skipsCache reports whether ctx belongs to a collection that must leave the cache alone
+func skipsCache(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skip
+}
+
+// ProviderDrift describes how the secrets of a provider changed since they were cached
+type ProviderDrift struct {
+	Provider string
+	// Cached reports whether the provider had cached secrets to compare with
+	Cached   bool
+	CachedAt time.Time
+	Changes  []KeyChange
+}
+
+// KeyChange is a key whose value differs between the cache and the provider
+type KeyChange struct {
+	Key    string
+	Change string // DriftAdded, DriftRemoved or DriftChanged
+	Old    string // Cached value, empty if added
+	New    string // Fetched value, empty if removed
+}
+
+// Drift fetches the secrets of the given providers (all if empty) and compares them with the
+// secrets cached for them by the last run. The cache is left untouched, so that it keeps
+// describing what running commands were started with
+func (c *Collector) Drift(ctx context.Context, providerIDs []string) ([]ProviderDrift, error) {
+	if c.cache == nil {
+		return nil, fmt.Errorf("caching is disabled in the config, so there are no cached secrets to compare with")
+	}
+	if !c.cache.IsAvailable() {
+		return nil, fmt.Errorf("the system keyring holding cached secrets is not available")
+	}
+	if c.auditErr != nil {
+		return nil, c.auditErr
+	}
+	if len(providerIDs) == 0 {
+		for _, providerCfg := range c.config.Providers {
+			providerIDs = append(providerIDs, providerCfg.ID)
+		}
+	}
+
+	// Read the cache before fetching
+	drifts := make([]ProviderDrift, len(providerIDs))
+	cached := make(map[string]provider.Secrets, len(providerIDs))
+	for i, providerID := range providerIDs {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, err
+		}
+		drifts[i].Provider = providerID
+		if !cacheable(providerCfg.Kind) {
+			continue
+		}
+		expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+		if err != nil {
+			return nil, fmt.Errorf("provider '%s': %w", providerID, err)
+		}
+		secrets, cachedAt, found := c.cache.Peek(c.cacheKey(providerCfg, expandedConfig))
+		if found {
+			cached[providerID] = secrets
+			drifts[i].Cached = true
+			drifts[i].CachedAt = cachedAt
+		}
+	}
+
+	if c.needsSSO(providerIDs) {
+		if err := c.authenticateSSO(ctx); err != nil {
+			return nil, &AuthError{Err: fmt.Errorf("SSO authentication failed: %w", err)}
+		}
+	}
+	order, err := dependencyOrder(c.config, providerIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, skipCacheKey{}, true)
+	providerSecrets := make(provider.ProviderSecretsMap)
+	for _, providerID := range order {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.fetchProvider(ctx, providerCfg, providerSecrets, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range drifts {
+		if drifts[i].Cached {
+			drifts[i].Changes = diffSecrets(cached[drifts[i].Provider], providerSecrets[drifts[i].Provider])
+		}
+	}
+	return drifts, nil
+}
+
+// diffSecrets returns the keys added, removed or changed from old to new, sorted by key
+func diffSecrets(old, new provider.Secrets) []KeyChange {
+	var changes []KeyChange
+	for key, value := range new {
+		oldValue, exists := old[key]
+		switch {
+		case !exists:
+			changes = append(changes, KeyChange{Key: key, Change: DriftAdded, New: value})
+		case oldValue != value:
+			changes = append(changes, KeyChange{Key: key, Change: DriftChanged, Old: oldValue, New: value})
+		}
+	}
+	for key, value := range old {
+		if _, exists := new[key]; !exists {
+			changes = append(changes, KeyChange{Key: key, Change: DriftRemoved, Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
@@ -0,0 +1,27 @@
+package secrets
+
+import "github.com/dirathea/sstart/internal/provider"
+
+// AuthError is returned when SSO authentication fails, or the SSO identity lacks claims
+// that a provider requires
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string { return e.Err.Error() }
+
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// FetchError is returned when a provider cannot be created or fails to fetch its secrets
+type FetchError struct {
+	Provider string
+	Err      error
+}
+
+func (e *FetchError) Error() string { return e.Err.Error() }
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// Class returns the class of the provider error (provider.ErrAuth, ErrNotFound, ErrRateLimited
+// or ErrNetwork), or nil if it has none
+func (e *FetchError) Class() error { return provider.ErrorClass(e.Err) }
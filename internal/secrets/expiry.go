@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+const (
+	// expiryRefreshRatio is the part of the lifetime of expiring secrets after which watch mode
+	// fetches them again, leaving the rest for the command to restart with the new ones
+	expiryRefreshRatio = 0.8
+	// minExpiryRefreshWait keeps watch mode from fetching in a loop secrets that expire right away
+	minExpiryRefreshWait = time.Second
+)
+
+// ExpiryKey returns the variable injected with when the secrets of a provider expire, e.g.
+// DATABASE_CREDS_EXPIRES_AT for the provider database-creds
+func ExpiryKey(providerID string) string {
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, providerID)
+	return key + "_EXPIRES_AT"
+}
+
+// earliestExpiry returns when the first of kvs expires, or the zero time if none expires
+func earliestExpiry(kvs []provider.KeyValue) time.Time {
+	var earliest time.Time
+	for _, kv := range kvs {
+		if !kv.ExpiresAt.IsZero() && (earliest.IsZero() || kv.ExpiresAt.Before(earliest)) {
+			earliest = kv.ExpiresAt
+		}
+	}
+	return earliest
+}
+
+// cachedExpiry returns the expiry of cached secrets of a provider, read from their companion
+// variable, or the zero time if they do not expire
+func cachedExpiry(secrets provider.Secrets, providerID string) time.Time {
+	expiresAt, err := time.Parse(time.RFC3339, secrets[ExpiryKey(providerID)])
+	if err != nil {
+		return time.Time{}
+	}
+	return expiresAt
+}
+
+// expired reports whether secrets expiring at t have expired
+func expired(t time.Time) bool {
+	return !t.IsZero() && !time.Now().Before(t)
+}
+
+// ExpiresAt returns when the secrets of a provider last collected by this collector expire, or
+// the zero time if they do not expire or were not collected
+func (c *Collector) ExpiresAt(providerID string) time.Time {
+	c.fetchedAtMu.Lock()
+	defer c.fetchedAtMu.Unlock()
+	return c.expiresAt[providerID]
+}
+
+// setExpiresAt records when the secrets of a provider expire
+func (c *Collector) setExpiresAt(providerID string, t time.Time) {
+	c.fetchedAtMu.Lock()
+	defer c.fetchedAtMu.Unlock()
+	if c.expiresAt == nil {
+		c.expiresAt = make(map[string]time.Time)
+	}
+	c.expiresAt[providerID] = t
+}
+
+// expiryRefreshAt returns when watch mode should fetch the secrets of the providers again before
+// they expire, or the zero time if none of them expires
+func (c *Collector) expiryRefreshAt(providerIDs []string) time.Time {
+	var refreshAt time.Time
+	for _, providerID := range providerIDs {
+		expiresAt := c.ExpiresAt(providerID)
+		if expiresAt.IsZero() {
+			continue
+		}
+		lifetime := expiresAt.Sub(c.FetchedAt(providerID))
+		at := expiresAt.Add(-time.Duration(float64(lifetime) * (1 - expiryRefreshRatio)))
+		if refreshAt.IsZero() || at.Before(refreshAt) {
+			refreshAt = at
+		}
+	}
+	return refreshAt
+}
@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// FilterKeys returns the secrets whose keys match at least one of the only patterns (all keys
+// if only is empty) and none of the exclude patterns. Patterns are globs such as STRIPE_* or DB_?
+func FilterKeys(secrets provider.Secrets, only, exclude []string) (provider.Secrets, error) {
+	for _, pattern := range append(append([]string{}, only...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern '%s': %w", pattern, err)
+		}
+	}
+	if len(only) == 0 && len(exclude) == 0 {
+		return secrets, nil
+	}
+
+	filtered := make(provider.Secrets, len(secrets))
+	for key, value := range secrets {
+		if len(only) > 0 && !matchAny(only, key) {
+			continue
+		}
+		if matchAny(exclude, key) {
+			continue
+		}
+		filtered[key] = value
+	}
+	return filtered, nil
+}
+
+// matchAny reports whether key matches any of the glob patterns
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// hooksOrigin is the origin of secrets added by pre-inject hooks
+const hooksOrigin = "hooks"
+
+// Hooks plug custom logic into the collection of secrets, to validate, report or transform them.
+// Each function is optional, and an error aborts the collection
+type Hooks struct {
+	// PreFetch is called before secrets are fetched from a provider, but not when they come from the cache
+	PreFetch func(ctx context.Context, providerID string) error
+	// PostFetch is called with the secrets fetched from a provider before they are cached or used by
+	// other providers, and returns the secrets to keep
+	PostFetch func(ctx context.Context, providerID string, secrets provider.Secrets) (provider.Secrets, error)
+	// PreInject is called with all collected secrets before policy checks, and returns the secrets to inject
+	PreInject func(ctx context.Context, secrets provider.Secrets) (provider.Secrets, error)
+}
+
+// WithHooks returns an option that adds hooks, called after the hook commands of the config
+func WithHooks(hooks Hooks) CollectorOption {
+	return func(c *Collector) {
+		c.hooks = append(c.hooks, hooks)
+	}
+}
+
+// preFetch calls the pre-fetch hooks for a provider
+func (c *Collector) preFetch(ctx context.Context, providerID string) error {
+	for _, hooks := range c.hooks {
+		if hooks.PreFetch == nil {
+			continue
+		}
+		if err := hooks.PreFetch(ctx, providerID); err != nil {
+			return fmt.Errorf("pre_fetch hook for provider '%s': %w", providerID, err)
+		}
+	}
+	return nil
+}
+
+// postFetch passes the secrets fetched from a provider through the post-fetch hooks
+func (c *Collector) postFetch(ctx context.Context, providerID string, secrets provider.Secrets) (provider.Secrets, error) {
+	for _, hooks := range c.hooks {
+		if hooks.PostFetch == nil {
+			continue
+		}
+		var err error
+		if secrets, err = hooks.PostFetch(ctx, providerID, secrets); err != nil {
+			return nil, fmt.Errorf("post_fetch hook for provider '%s': %w", providerID, err)
+		}
+	}
+	return secrets, nil
+}
+
+// preInject passes the collected secrets through the pre-inject hooks, keeping origins in sync
+func (c *Collector) preInject(ctx context.Context, secrets provider.Secrets, origins map[string]string) (provider.Secrets, error) {
+	transformed := false
+	for _, hooks := range c.hooks {
+		if hooks.PreInject == nil {
+			continue
+		}
+		var err error
+		if secrets, err = hooks.PreInject(ctx, secrets); err != nil {
+			return nil, fmt.Errorf("pre_inject hook: %w", err)
+		}
+		transformed = true
+	}
+	if transformed {
+		for key := range origins {
+			if _, exists := secrets[key]; !exists {
+				delete(origins, key)
+			}
+		}
+		for key := range secrets {
+			if _, exists := origins[key]; !exists {
+				origins[key] = hooksOrigin
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// commandHooks returns hooks running the hook commands of cfg, which fail unless the config file
+// is trusted (see config.Config.CheckHooksTrusted)
+func commandHooks(cfg *config.Config) []Hooks {
+	var hooks []Hooks
+	for _, command := range cfg.Hooks.PreFetch {
+		hooks = append(hooks, Hooks{PreFetch: func(ctx context.Context, providerID string) error {
+			if !appliesTo(command, providerID) {
+				return nil
+			}
+			if err := cfg.CheckHooksTrusted(); err != nil {
+				return err
+			}
+			_, err := runHookCommand(ctx, command, "pre_fetch", providerID, nil)
+			return err
+		}})
+	}
+	for _, command := range cfg.Hooks.PostFetch {
+		hooks = append(hooks, Hooks{PostFetch: func(ctx context.Context, providerID string, secrets provider.Secrets) (provider.Secrets, error) {
+			if !appliesTo(command, providerID) {
+				return secrets, nil
+			}
+			if err := cfg.CheckHooksTrusted(); err != nil {
+				return nil, err
+			}
+			return runHookCommand(ctx, command, "post_fetch", providerID, secrets)
+		}})
+	}
+	for _, command := range cfg.Hooks.PreInject {
+		hooks = append(hooks, Hooks{PreInject: func(ctx context.Context, secrets provider.Secrets) (provider.Secrets, error) {
+			if err := cfg.CheckHooksTrusted(); err != nil {
+				return nil, err
+			}
+			return runHookCommand(ctx, command, "pre_inject", "", secrets)
+		}})
+	}
+	return hooks
+}
+
+// postFetchCommands returns the post_fetch commands of the config run for fetches of a provider,
+// which change the secrets cached for it
+func postFetchCommands(cfg *config.Config, providerID string) []config.HookCommand {
+	if cfg.Hooks == nil {
+		return nil
+	}
+	var commands []config.HookCommand
+	for _, command := range cfg.Hooks.PostFetch {
+		if appliesTo(command, providerID) {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// appliesTo reports whether a hook command runs for fetches of a provider
+func appliesTo(command config.HookCommand, providerID string) bool {
+	return len(command.Providers) == 0 || slices.Contains(command.Providers, providerID)
+}
+
+// runHookCommand runs a hook command with SSTART_HOOK and SSTART_PROVIDER set. Secrets, if any,
+// are written to its stdin as a JSON object; if it prints a JSON object, that object replaces them
+func runHookCommand(ctx context.Context, command config.HookCommand, event, providerID string, secrets provider.Secrets) (provider.Secrets, error) {
+	cmd := exec.CommandContext(ctx, command.Command, command.Args...)
+	cmd.Env = append(os.Environ(), "SSTART_HOOK="+event)
+	if providerID != "" {
+		cmd.Env = append(cmd.Env, "SSTART_PROVIDER="+providerID)
+	}
+	if secrets != nil {
+		input, err := json.Marshal(secrets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode secrets: %w", err)
+		}
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("command '%s' failed: %w", command.Command, err)
+	}
+	output := bytes.TrimSpace(stdout.Bytes())
+	if secrets == nil || len(output) == 0 {
+		return secrets, nil
+	}
+	replaced := make(provider.Secrets)
+	if err := json.Unmarshal(output, &replaced); err != nil {
+		// The decoding error may quote the output, which may hold secrets
+		return nil, fmt.Errorf("command '%s' printed invalid JSON: expected an object of string values", command.Command)
+	}
+	return replaced, nil
+}
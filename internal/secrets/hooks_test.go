@@ -0,0 +1,162 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// loadHooksConfig writes a config with the given hooks section to a temporary directory and loads it
+func loadHooksConfig(t *testing.T, hooks string) *config.Config {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), ".sstart.yml")
+	if err := os.WriteFile(path, []byte("providers:\n  - kind: env\n    vars: [HOME]\n"+hooks), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func TestCommandHooks_RequireTrust(t *testing.T) {
+	cfg := loadHooksConfig(t, `
+hooks:
+  post_fetch:
+    - command: sed
+      args: ["s/API_KEY/RENAMED_KEY/"]
+`)
+	hooks := commandHooks(cfg)
+	if len(hooks) != 1 {
+		t.Fatalf("expected 1 hook, got %d", len(hooks))
+	}
+
+	secrets := provider.Secrets{"API_KEY": "value"}
+	if _, err := hooks[0].PostFetch(context.Background(), "env", secrets); !errors.Is(err, config.ErrUntrusted) {
+		t.Fatalf("expected hooks of an untrusted config to fail with ErrUntrusted, got %v", err)
+	}
+
+	if err := config.Trust(cfg.Path); err != nil {
+		t.Fatalf("Failed to trust config: %v", err)
+	}
+	transformed, err := hooks[0].PostFetch(context.Background(), "env", secrets)
+	if err != nil {
+		t.Fatalf("post_fetch hook failed: %v", err)
+	}
+	if transformed["RENAMED_KEY"] != "value" || len(transformed) != 1 {
+		t.Errorf("expected the hook to rename API_KEY, got %v", transformed)
+	}
+}
+
+func TestCommandHooks_RelativePaths(t *testing.T) {
+	cfg := loadHooksConfig(t, `
+hooks:
+  pre_fetch:
+    - command: ./scripts/check.sh
+    - command: sh
+  pre_run:
+    - command: bin/migrate
+`)
+	dir := filepath.Dir(cfg.Path)
+	if got, want := cfg.Hooks.PreFetch[0].Command, filepath.Join(dir, "scripts", "check.sh"); got != want {
+		t.Errorf("expected a relative command to resolve against the config directory, got %s, want %s", got, want)
+	}
+	if got := cfg.Hooks.PreFetch[1].Command; got != "sh" {
+		t.Errorf("expected a command name to be left to PATH, got %s", got)
+	}
+	if got, want := cfg.Hooks.PreRun[0].Command, filepath.Join(dir, "bin", "migrate"); got != want {
+		t.Errorf("expected a relative run hook to resolve against the config directory, got %s, want %s", got, want)
+	}
+}
+
+func TestPostFetchCommands(t *testing.T) {
+	cfg := loadHooksConfig(t, `
+hooks:
+  post_fetch:
+    - command: all
+    - command: app-only
+      providers: [app]
+`)
+	if got := postFetchCommands(cfg, "app"); len(got) != 2 {
+		t.Errorf("expected both commands to run for app, got %v", got)
+	}
+	if got := postFetchCommands(cfg, "other"); len(got) != 1 || got[0].Command != "all" {
+		t.Errorf("expected only the unfiltered command to run for other, got %v", got)
+	}
+
+	c := NewCollector(cfg)
+	providerCfg := &config.ProviderConfig{ID: "app", Kind: "env"}
+	unhooked := NewCollector(&config.Config{})
+	if c.cacheKey(providerCfg, nil) == unhooked.cacheKey(providerCfg, nil) {
+		t.Error("expected post_fetch hooks to change the cache key")
+	}
+}
+
+func TestRunHookCommand(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replaces secrets with its output", func(t *testing.T) {
+		command := config.HookCommand{Command: "sh", Args: []string{"-c", `echo "{\"EVENT\": \"$SSTART_HOOK $SSTART_PROVIDER\"}"`}}
+		got, err := runHookCommand(ctx, command, "post_fetch", "app", provider.Secrets{"API_KEY": "value"})
+		if err != nil {
+			t.Fatalf("runHookCommand failed: %v", err)
+		}
+		if got["EVENT"] != "post_fetch app" || len(got) != 1 {
+			t.Errorf("expected the secrets printed by the command, got %v", got)
+		}
+	})
+
+	t.Run("keeps secrets without output", func(t *testing.T) {
+		command := config.HookCommand{Command: "sh", Args: []string{"-c", "cat > /dev/null"}}
+		got, err := runHookCommand(ctx, command, "pre_inject", "", provider.Secrets{"API_KEY": "value"})
+		if err != nil {
+			t.Fatalf("runHookCommand failed: %v", err)
+		}
+		if got["API_KEY"] != "value" {
+			t.Errorf("expected the secrets to be kept, got %v", got)
+		}
+	})
+
+	t.Run("does not leak invalid output", func(t *testing.T) {
+		command := config.HookCommand{Command: "sh", Args: []string{"-c", "echo secret-value"}}
+		_, err := runHookCommand(ctx, command, "post_fetch", "app", provider.Secrets{"API_KEY": "value"})
+		if err == nil {
+			t.Fatal("expected invalid output to fail")
+		}
+		if got := err.Error(); got != "command 'sh' printed invalid JSON: expected an object of string values" {
+			t.Errorf("unexpected error: %s", got)
+		}
+	})
+
+	t.Run("fails with the command", func(t *testing.T) {
+		command := config.HookCommand{Command: "sh", Args: []string{"-c", "exit 3"}}
+		if _, err := runHookCommand(ctx, command, "pre_fetch", "app", nil); err == nil {
+			t.Error("expected a failing command to fail the hook")
+		}
+	})
+}
+
+func TestPreInject_KeepsOriginsInSync(t *testing.T) {
+	c := NewCollector(&config.Config{}, WithHooks(Hooks{PreInject: func(ctx context.Context, secrets provider.Secrets) (provider.Secrets, error) {
+		return provider.Secrets{"KEPT": secrets["KEPT"], "ADDED": "new"}, nil
+	}}))
+	origins := map[string]string{"KEPT": "app", "DROPPED": "app"}
+	got, err := c.preInject(context.Background(), provider.Secrets{"KEPT": "a", "DROPPED": "b"}, origins)
+	if err != nil {
+		t.Fatalf("preInject failed: %v", err)
+	}
+	if len(got) != 2 || got["ADDED"] != "new" {
+		t.Errorf("unexpected secrets: %v", got)
+	}
+	want := map[string]string{"KEPT": "app", "ADDED": hooksOrigin}
+	if len(origins) != len(want) || origins["KEPT"] != want["KEPT"] || origins["ADDED"] != want["ADDED"] {
+		t.Errorf("expected origins %v, got %v", want, origins)
+	}
+}
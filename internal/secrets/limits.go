@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// LimitViolation describes how the collected secrets exceed a limit
+type LimitViolation struct {
+	Limit    string // Name of the exceeded limit
+	Key      string // Secret key, empty for limits on all secrets
+	Provider string // ID of the provider the secret came from, empty for limits on all secrets
+	Reason   string // By how much the limit is exceeded
+}
+
+// String describes the violation in one line
+func (v LimitViolation) String() string {
+	if v.Key == "" {
+		return fmt.Sprintf("[%s] %s", v.Limit, v.Reason)
+	}
+	return fmt.Sprintf("[%s] %s (from provider '%s'): %s", v.Limit, v.Key, v.Provider, v.Reason)
+}
+
+// LimitError is returned by Collect when the collected secrets exceed the configured limits
+type LimitError struct {
+	Violations []LimitViolation
+}
+
+// Error returns the limits report, one line per violation
+func (e *LimitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "secrets exceed %d limit(s):", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  - %s", v)
+	}
+	return b.String()
+}
+
+// checkLimits checks the secrets about to be injected against the limits. The size of an entry
+// is that of KEY=value in the environment block of a process
+func checkLimits(limits *config.LimitsConfig, secrets provider.Secrets, origins map[string]string) []LimitViolation {
+	if limits == nil {
+		return nil
+	}
+
+	var violations []LimitViolation
+	total := 0
+	for _, key := range sortedSecretKeys(secrets) {
+		value := secrets[key]
+		total += len(key) + len(value) + 2 // '=' and the terminating NUL
+		if limits.MaxValueSize > 0 && len(value) > limits.MaxValueSize {
+			violations = append(violations, LimitViolation{
+				Limit:    "max_value_size",
+				Key:      key,
+				Provider: origins[key],
+				Reason:   fmt.Sprintf("value is %s, more than the maximum of %s", formatSize(len(value)), formatSize(limits.MaxValueSize)),
+			})
+		}
+	}
+	if limits.MaxKeys > 0 && len(secrets) > limits.MaxKeys {
+		violations = append(violations, LimitViolation{
+			Limit:  "max_keys",
+			Reason: fmt.Sprintf("%d keys, more than the maximum of %d", len(secrets), limits.MaxKeys),
+		})
+	}
+	if limits.MaxTotalSize > 0 && total > limits.MaxTotalSize {
+		violations = append(violations, LimitViolation{
+			Limit:  "max_total_size",
+			Reason: fmt.Sprintf("secrets take %s, more than the maximum of %s", formatSize(total), formatSize(limits.MaxTotalSize)),
+		})
+	}
+	return violations
+}
+
+// formatSize formats a size in bytes with a binary unit
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
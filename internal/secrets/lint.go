@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// placeholderValues are values that are left in place of a real secret, compared case-insensitively
+var placeholderValues = []string{
+	"changeme", "change-me", "change_me", "replaceme", "replace-me", "replace_me",
+	"todo", "fixme", "tbd", "placeholder", "dummy", "xxx", "xxxx", "...", "<secret>", "<changeme>",
+}
+
+// LintWarning describes a collected value that looks misconfigured
+type LintWarning struct {
+	Key      string // Secret key
+	Provider string // ID of the provider the value came from
+	Reason   string // What looks wrong
+}
+
+// lintSecrets checks the secrets about to be injected for empty values, placeholders,
+// and low entropy values of secret-looking keys
+func lintSecrets(lint *config.LintConfig, secrets provider.Secrets, origins map[string]string) []LintWarning {
+	placeholders := make(map[string]bool)
+	for _, value := range append(append([]string{}, placeholderValues...), lint.Placeholders...) {
+		placeholders[strings.ToLower(value)] = true
+	}
+	secretKeys := lint.SecretKeys
+	if len(secretKeys) == 0 {
+		secretKeys = config.DefaultLintSecretKeys
+	}
+	minEntropy := lint.MinEntropy
+	if minEntropy == 0 {
+		minEntropy = config.DefaultLintMinEntropy
+	}
+
+	keys := sortedSecretKeys(secrets)
+
+	var warnings []LintWarning
+	for _, key := range keys {
+		value := strings.TrimSpace(secrets[key])
+		var reason string
+		switch {
+		case value == "":
+			reason = "value is empty"
+		case placeholders[strings.ToLower(value)]:
+			reason = fmt.Sprintf("value looks like a placeholder (%q)", value)
+		case config.MatchesAny(secretKeys, key):
+			if entropy := shannonEntropy(value); entropy < minEntropy {
+				reason = fmt.Sprintf("value has low entropy for a secret (%.1f bits per character, expected at least %.1f)", entropy, minEntropy)
+			}
+		}
+		if reason != "" {
+			warnings = append(warnings, LintWarning{Key: key, Provider: origins[key], Reason: reason})
+		}
+	}
+	return warnings
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
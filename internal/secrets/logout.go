@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"sort"
+
+	"github.com/dirathea/sstart/internal/oidc"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// cacheIdentity returns the SSO identity that the secrets of a provider are fetched under, or
+// an empty string if the provider does not use SSO
+func (c *Collector) cacheIdentity(providerID string) string {
+	if !c.needsSSO([]string{providerID}) {
+		return ""
+	}
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return oidc.Identity(c.idToken)
+}
+
+// purgeSessionCache removes the cached secrets and credentials obtained under the identity of
+// the stored SSO session, so that they cannot be used once the session is invalidated. It
+// returns how many entries were removed
+func (c *Collector) purgeSessionCache() int {
+	tokens, err := c.ssoClient.LoadTokens()
+	if err != nil {
+		return 0
+	}
+	identity := oidc.Identity(tokens.IDToken)
+	if identity == "" {
+		return 0
+	}
+	store := c.cache
+	if store == nil {
+		// Entries cached before caching was turned off are purged too
+		store = newCache(c.config, c.logger)
+	}
+	removed, err := store.ClearIdentity(identity)
+	if err != nil {
+		c.logger.Warn("failed to purge the cached secrets of the SSO session", "error", err)
+	} else if removed > 0 {
+		c.logger.Debug("purged the cached secrets of the SSO session", "entries", removed)
+	}
+
+	// Providers cache credentials obtained with the session under the same identity, e.g. Vault tokens
+	kinds := provider.List()
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		p, err := provider.New(kind)
+		if err != nil {
+			continue
+		}
+		purger, ok := p.(provider.SessionPurger)
+		if !ok {
+			continue
+		}
+		n, err := purger.PurgeSession(c.config.GetCacheKeyringService(), identity)
+		if err != nil {
+			c.logger.Warn("failed to purge the cached credentials of the SSO session", "kind", kind, "error", err)
+		} else if n > 0 {
+			c.logger.Debug("purged the cached credentials of the SSO session", "kind", kind, "entries", n)
+		}
+		removed += n
+	}
+	return removed
+}
+
+// Logout removes the stored SSO session and the cached secrets and credentials obtained under its
+// identity. It reports whether a session was stored, and how many entries were removed
+func (c *Collector) Logout() (bool, int, error) {
+	c.ssoOnce.Do(c.initSSO)
+	if c.ssoErr != nil {
+		return false, 0, c.ssoErr
+	}
+	if c.ssoClient == nil {
+		return false, 0, nil
+	}
+
+	loggedIn := c.ssoClient.TokensExist()
+	removed := c.purgeSessionCache()
+	if err := c.ssoClient.ClearTokens(); err != nil {
+		return loggedIn, removed, err
+	}
+	return loggedIn, removed, nil
+}
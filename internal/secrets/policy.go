@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// PolicyViolation describes a collected secret that breaks a policy rule
+type PolicyViolation struct {
+	Rule     string // Name of the violated rule
+	Key      string // Secret key
+	Provider string // ID of the provider the secret came from
+	Reason   string // Why the rule was violated
+}
+
+// PolicyError is returned by Collect when the collected secrets violate the configured policy
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+// Error returns the policy report, one line per violation
+func (e *PolicyError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "policy check failed with %d violation(s):", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  - [%s] %s (from provider '%s'): %s", v.Rule, v.Key, v.Provider, v.Reason)
+	}
+	return b.String()
+}
+
+// checkPolicy checks the secrets about to be injected against the policy rules active for profile
+// origins maps each key to the ID of the provider it came from
+func checkPolicy(policy *config.PolicyConfig, profile string, secrets provider.Secrets, origins map[string]string) error {
+	if policy == nil {
+		return nil
+	}
+
+	keys := sortedSecretKeys(secrets)
+
+	var violations []PolicyViolation
+	for _, rule := range policy.Rules {
+		if !rule.AppliesTo(profile) {
+			continue
+		}
+		for _, key := range keys {
+			if !rule.MatchesKey(key) {
+				continue
+			}
+			violation := PolicyViolation{Rule: rule.Name, Key: key, Provider: origins[key]}
+			switch {
+			case rule.Deny:
+				if profile != "" {
+					violation.Reason = fmt.Sprintf("denied in profile '%s'", profile)
+				} else {
+					violation.Reason = "denied"
+				}
+			case rule.MaxLength > 0 && len(secrets[key]) > rule.MaxLength:
+				violation.Reason = fmt.Sprintf("value is %d characters long, more than the maximum of %d", len(secrets[key]), rule.MaxLength)
+			case len(rule.Providers) > 0 && !config.MatchesAny(rule.Providers, origins[key]):
+				violation.Reason = fmt.Sprintf("must come from one of: %s", strings.Join(rule.Providers, ", "))
+			default:
+				continue
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
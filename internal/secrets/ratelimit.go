@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// errBudgetExhausted is returned for requests beyond the budget of rate_limits
+var errBudgetExhausted = errors.New("request budget exhausted")
+
+// rateLimiter spaces the requests to the providers of each kind evenly over their period, and
+// counts all requests against the budget of the invocation
+type rateLimiter struct {
+	limiters map[string]*rate.Limiter
+	budget   int
+
+	mu   sync.Mutex
+	used int
+}
+
+// newRateLimiter returns the rate limiter of the config, or nil if it has no rate limits. Rates
+// are validated on load
+func newRateLimiter(cfg *config.RateLimitsConfig) *rateLimiter {
+	if cfg == nil || (len(cfg.Kinds) == 0 && cfg.Budget == 0) {
+		return nil
+	}
+	l := &rateLimiter{limiters: make(map[string]*rate.Limiter, len(cfg.Kinds)), budget: cfg.Budget}
+	for kind, r := range cfg.Kinds {
+		n, period, err := config.ParseRate(r)
+		if err != nil {
+			continue
+		}
+		l.limiters[kind] = rate.NewLimiter(rate.Every(period/time.Duration(n)), 1)
+	}
+	return l
+}
+
+// wait blocks until a request to a provider of the kind is allowed, and counts it against the
+// budget. It fails once the budget is spent, or if ctx is done first
+func (l *rateLimiter) wait(ctx context.Context, kind string) error {
+	if l == nil {
+		return nil
+	}
+	if l.budget > 0 {
+		l.mu.Lock()
+		if l.used >= l.budget {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: rate_limits.budget allows %d requests per invocation", errBudgetExhausted, l.budget)
+		}
+		l.used++
+		l.mu.Unlock()
+	}
+	if limiter, ok := l.limiters[kind]; ok {
+		return limiter.Wait(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// Retries of fetches failing with a transient error (see fetchWithRetry)
+var (
+	// fetchRetries is how many times a fetch that was rate limited or hit a network error is retried
+	fetchRetries = 2
+	// fetchRetryDelay is the delay before the first retry, doubled before each further one
+	fetchRetryDelay = 500 * time.Millisecond
+)
+
+// fetchWithRetry fetches the secrets of a provider with its client, retrying according to the
+// class of the error: rate limits and network errors are retried with backoff; authentication
+// and unclassified errors of a reused client are retried once with a new client, since it may
+// hold an expired token or session; missing secrets are never retried. Every attempt waits for
+// the rate limit of the provider's kind and counts against the request budget
+func (c *Collector) fetchWithRetry(ctx context.Context, client *providerClient, secretContext provider.SecretContext, providerID string, config map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	fetch := func() ([]provider.KeyValue, error) {
+		if err := c.limiter.wait(ctx, client.kind); err != nil {
+			return nil, err
+		}
+		prov := client.prov
+		kvs, err := cancellable(ctx, func() ([]provider.KeyValue, error) {
+			return prov.Fetch(secretContext, providerID, config, keys)
+		})
+		if ctx.Err() != nil {
+			// The fetch may go on in the background after the client is released, so its
+			// instance is dropped rather than used concurrently by the next fetch
+			if renewErr := client.renew(); renewErr != nil {
+				c.logger.Debug("failed to replace the provider instance of a cancelled fetch", "provider", providerID, "error", renewErr)
+			}
+		}
+		return kvs, err
+	}
+
+	kvs, err := fetch()
+	delay := fetchRetryDelay
+	for attempt := 0; err != nil && attempt < fetchRetries; attempt++ {
+		if errors.Is(err, errBudgetExhausted) || ctx.Err() != nil {
+			return nil, err
+		}
+		class := provider.ErrorClass(err)
+		switch {
+		case errors.Is(class, provider.ErrRateLimited), errors.Is(class, provider.ErrNetwork):
+			c.logger.Debug("fetch failed, retrying", "provider", providerID, "class", class, "retry_in", delay, "error", err)
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(delay):
+			}
+			delay *= 2
+			kvs, err = fetch()
+		case class == nil || errors.Is(class, provider.ErrAuth):
+			if !client.used {
+				return nil, err
+			}
+			c.logger.Debug("fetch with reused client failed, retrying with a new client", "provider", providerID, "error", err)
+			if err = client.renew(); err != nil {
+				return nil, err
+			}
+			kvs, err = fetch()
+		default:
+			return nil, err
+		}
+	}
+	return kvs, err
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// SecretAge returns how long ago key was last changed according to metadata
+// An entry for the key itself takes precedence over a provider-wide entry (empty Key)
+func SecretAge(metadata []provider.SecretMetadata, key string) (time.Duration, bool) {
+	changed, ok := LastChanged(metadata, key)
+	if !ok {
+		return 0, false
+	}
+	return time.Since(changed), true
+}
+
+// LastChanged returns when key was last changed according to metadata
+func LastChanged(metadata []provider.SecretMetadata, key string) (time.Time, bool) {
+	var providerWide time.Time
+	for _, m := range metadata {
+		switch m.Key {
+		case key:
+			if changed := m.LastChanged(); !changed.IsZero() {
+				return changed, true
+			}
+		case "":
+			providerWide = m.LastChanged()
+		}
+	}
+	return providerWide, !providerWide.IsZero()
+}
+
+// CustomMetadata returns the custom metadata fields of key, where fields of an entry for the key
+// itself take precedence over those of a provider-wide entry (empty Key)
+func CustomMetadata(metadata []provider.SecretMetadata, key string) map[string]string {
+	custom := make(map[string]string)
+	for _, entryKey := range []string{"", key} {
+		for _, m := range metadata {
+			if m.Key == entryKey {
+				for field, value := range m.Custom {
+					custom[field] = value
+				}
+			}
+		}
+	}
+	return custom
+}
+
+// FormatAge formats an age in days, or hours below one day
+func FormatAge(age time.Duration) string {
+	if age < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
+
+// sortedSecretKeys returns the keys of secrets in sorted order
+func sortedSecretKeys(secrets provider.Secrets) []string {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
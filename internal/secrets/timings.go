@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Timing phases reported by --timings
+const (
+	PhaseAuth     = "auth"     // SSO authentication
+	PhaseCache    = "cache"    // Cache lookup
+	PhaseFetch    = "fetch"    // Fetch from the provider backend
+	PhaseTemplate = "template" // Template resolution by a template provider
+)
+
+// timing is the duration of one phase, for a provider or for the whole collection
+type timing struct {
+	provider string
+	phase    string
+	note     string
+	duration time.Duration
+}
+
+// timings collects phase durations during Collect and writes them as a summary
+type timings struct {
+	start   time.Time
+	entries []timing
+}
+
+func newTimings() *timings {
+	return &timings{start: time.Now()}
+}
+
+// add records that phase took the time since start
+func (t *timings) add(providerID, phase, note string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.entries = append(t.entries, timing{provider: providerID, phase: phase, note: note, duration: time.Since(start)})
+}
+
+// write prints the summary table, ending with the total collection time
+func (t *timings) write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "sstart timings:")
+	for _, e := range t.entries {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", e.provider, e.phase, formatDuration(e.duration), e.note)
+	}
+	fmt.Fprintf(tw, "  %s\t\t%s\t\n", "total", formatDuration(time.Since(t.start)))
+	tw.Flush()
+}
+
+// formatDuration rounds d for display
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// Kinds of issues reported by Validate
+const (
+	// IssueUnusedMapping is a key mapping whose source key the provider did not return
+	IssueUnusedMapping = "unused mapping"
+	// IssueShadowed is a key set by a provider and overridden by a later one
+	IssueShadowed = "shadowed"
+)
+
+// ConfigIssue is a silent misconfiguration found by Validate
+type ConfigIssue struct {
+	Provider string
+	Key      string
+	Kind     string // IssueUnusedMapping or IssueShadowed
+	Detail   string
+}
+
+// Validate fetches the secrets of the given providers (all if empty) and reports key mappings
+// that match no secret of their provider, and keys that a later provider overrides. Template
+// providers are not checked for unused mappings, since their keys are not mappings
+func (c *Collector) Validate(ctx context.Context, providerIDs []string) ([]ConfigIssue, error) {
+	if c.auditErr != nil {
+		return nil, c.auditErr
+	}
+	if len(providerIDs) == 0 {
+		for _, providerCfg := range c.config.Providers {
+			providerIDs = append(providerIDs, providerCfg.ID)
+		}
+	}
+
+	if c.needsSSO(providerIDs) {
+		if err := c.authenticateSSO(ctx); err != nil {
+			return nil, &AuthError{Err: fmt.Errorf("SSO authentication failed: %w", err)}
+		}
+	}
+	order, err := dependencyOrder(c.config, providerIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withFetches(ctx)
+	providerSecrets := make(provider.ProviderSecretsMap)
+	for _, providerID := range order {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.fetchProvider(ctx, providerCfg, providerSecrets, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var issues []ConfigIssue
+	setBy := make(map[string]string)
+	for _, providerID := range providerIDs {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, err
+		}
+		fetched := providerSecrets[providerID]
+
+		if providerCfg.Kind != "template" {
+			sources := make([]string, 0, len(providerCfg.Keys))
+			for source := range providerCfg.Keys {
+				sources = append(sources, source)
+			}
+			sort.Strings(sources)
+			for _, source := range sources {
+				target := providerCfg.Keys[source]
+				if target == "==" {
+					target = source
+				}
+				if _, ok := fetched[target]; !ok {
+					issues = append(issues, ConfigIssue{
+						Provider: providerID,
+						Key:      source,
+						Kind:     IssueUnusedMapping,
+						Detail:   fmt.Sprintf("the provider returned no '%s' to map to '%s'", source, target),
+					})
+				}
+			}
+		}
+
+		for _, key := range sortedSecretKeys(fetched) {
+			if providerCfg.IsHidden(key) {
+				continue
+			}
+			if earlier, ok := setBy[key]; ok && earlier != providerID {
+				detail := fmt.Sprintf("overridden by provider '%s'", providerID)
+				if providerSecrets[earlier][key] == fetched[key] {
+					detail += " with the same value"
+				}
+				issues = append(issues, ConfigIssue{
+					Provider: earlier,
+					Key:      key,
+					Kind:     IssueShadowed,
+					Detail:   detail,
+				})
+			}
+			setBy[key] = providerID
+		}
+	}
+	return issues, nil
+}
+
+// DiagnoseAuth probes the credentials of the given providers (all if empty) without fetching
+// secrets, for providers that implement provider.AuthDiagnoser. Other providers have no checks
+func (c *Collector) DiagnoseAuth(ctx context.Context, providerIDs []string) (map[string][]provider.AuthCheck, error) {
+	if len(providerIDs) == 0 {
+		for _, providerCfg := range c.config.Providers {
+			providerIDs = append(providerIDs, providerCfg.ID)
+		}
+	}
+
+	if c.needsSSO(providerIDs) {
+		if err := c.authenticateSSO(ctx); err != nil {
+			return nil, &AuthError{Err: fmt.Errorf("SSO authentication failed: %w", err)}
+		}
+	}
+
+	checks := make(map[string][]provider.AuthCheck, len(providerIDs))
+	for _, providerID := range providerIDs {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return nil, err
+		}
+		expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+		if err != nil {
+			return nil, fmt.Errorf("provider '%s': %w", providerID, err)
+		}
+		c.injectTokensIntoConfig(expandedConfig)
+
+		prov, err := provider.New(providerCfg.Kind)
+		if err != nil {
+			return nil, &FetchError{Provider: providerID, Err: fmt.Errorf("failed to create provider '%s': %w", providerID, err)}
+		}
+		diagnoser, ok := prov.(provider.AuthDiagnoser)
+		if !ok {
+			checks[providerID] = nil
+			continue
+		}
+		secretContext := NewEmptySecretContext(ctx)
+		secretContext.Profile = c.config.Profile
+		secretContext.Env = c.environ()
+		if checks[providerID], err = diagnoser.DiagnoseAuth(secretContext, providerID, expandedConfig); err != nil {
+			return nil, &FetchError{Provider: providerID, Err: err}
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+)
+
+// refreshKey marks a context whose collection bypasses cache lookups and the agent
+type refreshKey struct{}
+
+// isRefresh reports whether ctx belongs to a collection started by Refresh
+func isRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// Refresh is like Collect, but fetches from every provider even if its secrets are cached,
+// and updates the cache with the result
+func (c *Collector) Refresh(ctx context.Context, providerIDs []string) (provider.Secrets, error) {
+	return c.Collect(context.WithValue(ctx, refreshKey{}, true), providerIDs)
+}
+
+// RefreshWithSources is like Refresh, but also returns the provider each secret came from
+func (c *Collector) RefreshWithSources(ctx context.Context, providerIDs []string) (provider.Secrets, map[string]string, error) {
+	return c.CollectWithSources(context.WithValue(ctx, refreshKey{}, true), providerIDs)
+}
+
+// WaitForChange blocks until the secrets of one of the given providers (all if empty) may have
+// changed, or ctx is done. Providers that implement provider.Watcher are watched for changes;
+// for the others, it returns after pollInterval so that the caller fetches them again. Secrets
+// that expire, such as Vault dynamic secrets, are fetched again before they expire instead of
+// every pollInterval, since each fetch issues new ones
+func (c *Collector) WaitForChange(ctx context.Context, providerIDs []string, pollInterval time.Duration) error {
+	if len(providerIDs) == 0 {
+		for _, providerCfg := range c.config.Providers {
+			providerIDs = append(providerIDs, providerCfg.ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changed := make(chan string, len(providerIDs))
+	poll := false
+	for _, providerID := range providerIDs {
+		providerCfg, err := c.config.GetProvider(providerID)
+		if err != nil {
+			return err
+		}
+		prov, err := provider.New(providerCfg.Kind)
+		if err != nil {
+			return err
+		}
+		if _, ok := prov.(provider.Watcher); !ok {
+			if c.ExpiresAt(providerID).IsZero() {
+				poll = true
+			}
+			continue
+		}
+		go func() {
+			if err := c.watchProvider(ctx, providerCfg); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// Fall back to polling the provider
+				c.logger.Warn("failed to watch provider for changes", "provider", providerCfg.ID, "error", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(pollInterval):
+					changed <- providerCfg.ID
+				}
+				return
+			}
+			changed <- providerCfg.ID
+		}()
+	}
+
+	var pollC <-chan time.Time
+	if poll {
+		timer := time.NewTimer(pollInterval)
+		defer timer.Stop()
+		pollC = timer.C
+	}
+
+	var expiryC <-chan time.Time
+	if refreshAt := c.expiryRefreshAt(providerIDs); !refreshAt.IsZero() {
+		timer := time.NewTimer(max(time.Until(refreshAt), minExpiryRefreshWait))
+		defer timer.Stop()
+		expiryC = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case providerID := <-changed:
+		c.logger.Debug("provider secrets may have changed", "provider", providerID)
+		return nil
+	case <-pollC:
+		return nil
+	case <-expiryC:
+		c.logger.Debug("refreshing secrets before they expire")
+		return nil
+	}
+}
+
+// watchProvider blocks until the provider reports that its secrets may have changed
+func (c *Collector) watchProvider(ctx context.Context, providerCfg *config.ProviderConfig) error {
+	expandedConfig, err := config.ExpandIn(providerCfg.Config, c.environment)
+	if err != nil {
+		return fmt.Errorf("provider '%s': %w", providerCfg.ID, err)
+	}
+	c.injectTokensIntoConfig(expandedConfig)
+	client, err := c.clients.acquire(providerCfg, expandedConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create provider '%s': %w", providerCfg.ID, err)
+	}
+	defer client.release()
+
+	secretContext := NewEmptySecretContext(ctx)
+	secretContext.Profile = c.config.Profile
+	secretContext.Env = c.environ()
+	return client.prov.(provider.Watcher).Watch(secretContext, providerCfg.ID, expandedConfig)
+}
@@ -0,0 +1,71 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Signature algorithms of minisign: Ed25519 over the data, or over its BLAKE2b-512 hash (the
+// default since minisign 0.10)
+const (
+	algorithmLegacy    = "Ed"
+	algorithmPrehashed = "ED"
+)
+
+// minisignKey is a minisign public key
+type minisignKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey parses a minisign public key: the base64 line of a minisign.pub file
+func parseMinisignKey(encoded string) (*minisignKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != algorithmLegacy {
+		return nil, errors.New("invalid minisign public key")
+	}
+	return &minisignKey{id: data[2:10], key: ed25519.PublicKey(data[10:])}, nil
+}
+
+// verify checks that sig, the contents of a .minisig file, is a signature of data made with the
+// secret key of k, and that its trusted comment was signed along with it
+func (k *minisignKey) verify(data, sig []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment: ") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return errors.New("invalid minisign signature")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(signature) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+
+	if !bytes.Equal(signature[2:10], k.id) {
+		return fmt.Errorf("signed with key %X, expected %X", signature[2:10], k.id)
+	}
+	switch string(signature[:2]) {
+	case algorithmLegacy:
+	case algorithmPrehashed:
+		sum := blake2b.Sum512(data)
+		data = sum[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm '%s'", signature[:2])
+	}
+	if !ed25519.Verify(k.key, data, signature[10:]) {
+		return errors.New("signature does not match")
+	}
+	trusted := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ed25519.Verify(k.key, append(bytes.Clone(signature[10:]), trusted...), global) {
+		return errors.New("trusted comment does not match its signature")
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Package selfupdate finds sstart releases on GitHub and replaces the running binary with one,
+// after checking the release archive against the release checksums, signed with minisign
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// APIURLEnv overrides the GitHub API URL, e.g. for a mirror
+const APIURLEnv = "SSTART_GITHUB_API_URL"
+
+const (
+	defaultAPIURL = "https://api.github.com"
+	repository    = "dirathea/sstart"
+	checksumsName = "checksums.txt"
+	signatureName = checksumsName + ".minisig"
+	binaryName    = "sstart"
+)
+
+// publicKey is the minisign public key the checksums of releases are signed with, set at build
+// time with -ldflags "-X github.com/dirathea/sstart/internal/selfupdate.publicKey=..."
+var publicKey string
+
+// Release is a GitHub release of sstart
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the version of the release, without the "v" prefix
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// ArchiveName returns the name of the release archive for the current platform
+func (r *Release) ArchiveName() string {
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", binaryName, r.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Newer reports whether the release is newer than the current version. Development builds,
+// whose version is not a semantic version, are older than any release
+func (r *Release) Newer(current string) bool {
+	current = "v" + strings.TrimPrefix(current, "v")
+	if !semver.IsValid(current) {
+		return true
+	}
+	return semver.Compare(r.Tag, current) > 0
+}
+
+// Client talks to the GitHub releases API
+type Client struct {
+	apiURL string
+	http   *http.Client
+}
+
+// NewClient returns a client of the GitHub API, or of $SSTART_GITHUB_API_URL if set
+func NewClient() *Client {
+	apiURL := os.Getenv(APIURLEnv)
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &Client{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		http:   &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Release returns the release with the given tag, or the latest release if tag is empty
+func (c *Client) Release(ctx context.Context, tag string) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", c.apiURL, repository)
+	if tag != "" {
+		url = fmt.Sprintf("%s/repos/%s/releases/tags/v%s", c.apiURL, repository, strings.TrimPrefix(tag, "v"))
+	}
+	body, err := c.get(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find release: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if !semver.IsValid(release.Tag) {
+		return nil, fmt.Errorf("release has an invalid version tag '%s'", release.Tag)
+	}
+	return &release, nil
+}
+
+// Download downloads the archive of the release for the current platform, checks it against the
+// checksums of the release once their signature is verified, and returns the sstart binary it contains
+func (c *Client) Download(ctx context.Context, release *Release) ([]byte, error) {
+	if publicKey == "" {
+		return nil, errors.New("this build of sstart has no key to verify releases with; download the release from GitHub instead")
+	}
+	key, err := parseMinisignKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	name := release.ArchiveName()
+	archive, ok := release.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no archive for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums, ok := release.asset(checksumsName)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify the archive with", release.Tag, checksumsName)
+	}
+	signature, ok := release.asset(signatureName)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify %s with", release.Tag, signatureName, checksumsName)
+	}
+
+	sums, err := c.get(ctx, checksums.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", checksumsName, err)
+	}
+	sig, err := c.get(ctx, signature.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", signatureName, err)
+	}
+	if err := key.verify(sums, sig); err != nil {
+		return nil, fmt.Errorf("failed to verify %s of release %s: %w", checksumsName, release.Tag, err)
+	}
+	want, err := checksum(sums, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.get(ctx, archive.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return extractBinary(data)
+}
+
+func (c *Client) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, c.apiURL) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// checksum returns the SHA-256 checksum of name in a sha256sum checksums file
+func checksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsName, name)
+}
+
+// extractBinary returns the sstart binary at the root of a release archive
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("archive does not contain %s", binaryName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Clean(header.Name) == binaryName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// Executable returns the path of the running binary, with symlinks resolved
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// Replace atomically replaces the binary at path with binary, keeping its permissions
+func Replace(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sstart-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write next to %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// testSigner signs data like minisign, with a key generated for the test
+type testSigner struct {
+	id  []byte
+	key ed25519.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &testSigner{id: id, key: key}
+}
+
+// publicKey returns the base64 line of the minisign.pub of the signer
+func (s *testSigner) publicKey() string {
+	data := append([]byte(algorithmLegacy), s.id...)
+	return base64.StdEncoding.EncodeToString(append(data, s.key.Public().(ed25519.PublicKey)...))
+}
+
+// sign returns the contents of a .minisig file for data, signed with algorithm
+func (s *testSigner) sign(data []byte, algorithm, trusted string) []byte {
+	if algorithm == algorithmPrehashed {
+		sum := blake2b.Sum512(data)
+		data = sum[:]
+	}
+	signature := ed25519.Sign(s.key, data)
+	global := ed25519.Sign(s.key, append(bytes.Clone(signature), trusted...))
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), s.id...), signature...))
+	return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		encoded, trusted, base64.StdEncoding.EncodeToString(global)))
+}
+
+// testArchive returns a gzipped tar archive with the given regular files
+func testArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		_, _ = tw.Write([]byte(content))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+func TestChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		sums    string
+		want    string
+		wantErr bool
+	}{
+		{name: "text mode", sums: sum + "  sstart-1.2.0-linux-amd64.tar.gz\n", want: sum},
+		{name: "binary mode", sums: sum + " *sstart-1.2.0-linux-amd64.tar.gz\n", want: sum},
+		{name: "upper case", sums: strings.ToUpper(sum) + "  sstart-1.2.0-linux-amd64.tar.gz\n", want: sum},
+		{name: "among other files", sums: strings.Repeat("cd", 32) + "  sstart-1.2.0-darwin-arm64.tar.gz\n" + sum + "  sstart-1.2.0-linux-amd64.tar.gz\n", want: sum},
+		{name: "missing", sums: sum + "  sstart-1.2.0-darwin-arm64.tar.gz\n", wantErr: true},
+		{name: "malformed line", sums: sum + "  sstart-1.2.0-linux-amd64.tar.gz extra\n", wantErr: true},
+		{name: "empty", sums: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checksum([]byte(tt.sums), "sstart-1.2.0-linux-amd64.tar.gz")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got checksum %s", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("checksum() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "at the root", archive: testArchive(t, map[string]string{"./sstart": "binary", "./README.md": "readme"}), want: "binary"},
+		{name: "without ./", archive: testArchive(t, map[string]string{"sstart": "binary"}), want: "binary"},
+		{name: "in a subdirectory", archive: testArchive(t, map[string]string{"bin/sstart": "binary"}), wantErr: true},
+		{name: "missing", archive: testArchive(t, map[string]string{"./README.md": "readme"}), wantErr: true},
+		{name: "not gzipped", archive: []byte("not an archive"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractBinary(tt.archive)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("extractBinary() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		tag     string
+		current string
+		want    bool
+	}{
+		{tag: "v1.2.0", current: "1.1.0", want: true},
+		{tag: "v1.2.0", current: "v1.1.9", want: true},
+		{tag: "v1.2.0", current: "1.2.0", want: false},
+		{tag: "v1.2.0", current: "1.10.0", want: false},
+		{tag: "v1.2.0", current: "1.2.0-rc.1", want: true},
+		{tag: "v1.2.0-rc.1", current: "1.2.0", want: false},
+		{tag: "v1.2.0", current: "dev", want: true},
+		{tag: "v1.2.0", current: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+" over "+tt.current, func(t *testing.T) {
+			release := &Release{Tag: tt.tag}
+			if got := release.Newer(tt.current); got != tt.want {
+				t.Errorf("Newer(%q) = %v, want %v", tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinisignVerify(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	data := []byte("checksums\n")
+	key, err := parseMinisignKey(signer.publicKey())
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		sig     []byte
+		wantErr string
+	}{
+		{name: "prehashed", data: data, sig: signer.sign(data, algorithmPrehashed, "sstart v1.2.0")},
+		{name: "legacy", data: data, sig: signer.sign(data, algorithmLegacy, "sstart v1.2.0")},
+		{name: "modified data", data: []byte("checksums!\n"), sig: signer.sign(data, algorithmPrehashed, "sstart v1.2.0"), wantErr: "signature does not match"},
+		{name: "other key", data: data, sig: other.sign(data, algorithmPrehashed, "sstart v1.2.0"), wantErr: "signed with key"},
+		{
+			name:    "modified trusted comment",
+			data:    data,
+			sig:     bytes.Replace(signer.sign(data, algorithmPrehashed, "sstart v1.2.0"), []byte("v1.2.0"), []byte("v9.9.9"), 1),
+			wantErr: "trusted comment does not match",
+		},
+		{name: "unknown algorithm", data: data, sig: signer.sign(data, "XX", "sstart v1.2.0"), wantErr: "unsupported signature algorithm"},
+		{name: "malformed", data: data, sig: []byte("not a signature"), wantErr: "invalid minisign signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := key.verify(tt.data, tt.sig)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected the signature to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		if _, err := parseMinisignKey(invalid); err == nil {
+			t.Errorf("expected parsing public key %q to fail", invalid)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	signer := newTestSigner(t)
+	release := &Release{Tag: "v1.2.0"}
+	name := release.ArchiveName()
+	archive := testArchive(t, map[string]string{"./sstart": "new binary"})
+	sum := sha256.Sum256(archive)
+	sums := []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	wrongSum := sha256.Sum256([]byte("other archive"))
+	wrongSums := []byte(hex.EncodeToString(wrongSum[:]) + "  " + name + "\n")
+
+	tests := []struct {
+		name      string
+		publicKey string
+		sums      []byte
+		sig       []byte
+		wantErr   string
+	}{
+		{name: "valid", publicKey: signer.publicKey(), sums: sums, sig: signer.sign(sums, algorithmPrehashed, "sstart v1.2.0")},
+		{name: "checksum mismatch", publicKey: signer.publicKey(), sums: wrongSums, sig: signer.sign(wrongSums, algorithmPrehashed, "sstart v1.2.0"), wantErr: "checksum mismatch"},
+		{name: "checksums not signed", publicKey: signer.publicKey(), sums: sums, wantErr: "has no checksums.txt.minisig"},
+		{name: "checksums replaced", publicKey: signer.publicKey(), sums: wrongSums, sig: signer.sign(sums, algorithmPrehashed, "sstart v1.2.0"), wantErr: "signature does not match"},
+		{name: "signed with another key", publicKey: newTestSigner(t).publicKey(), sums: sums, sig: signer.sign(sums, algorithmPrehashed, "sstart v1.2.0"), wantErr: "signed with key"},
+		{name: "no public key", sums: sums, sig: signer.sign(sums, algorithmPrehashed, "sstart v1.2.0"), wantErr: "no key to verify releases with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{name: archive, checksumsName: tt.sums}
+			if tt.sig != nil {
+				files[signatureName] = tt.sig
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(files[strings.TrimPrefix(r.URL.Path, "/")])
+			}))
+			defer server.Close()
+
+			release := &Release{Tag: release.Tag}
+			for file := range files {
+				release.Assets = append(release.Assets, Asset{Name: file, URL: server.URL + "/" + file})
+			}
+			previous := publicKey
+			publicKey = tt.publicKey
+			defer func() { publicKey = previous }()
+
+			client := &Client{apiURL: server.URL, http: server.Client()}
+			binary, err := client.Download(context.Background(), release)
+			if tt.wantErr == "" {
+				if err != nil || string(binary) != "new binary" {
+					t.Errorf("Download() = %q, %v; want the binary of the archive", binary, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package end2end
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dirathea/sstart/internal/agent"
+	"github.com/dirathea/sstart/internal/config"
+)
+
+// TestE2E_Agent tests that commands collect through a running agent, which keeps secrets in memory
+func TestE2E_Agent(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=first\n")
+	socketPath := filepath.Join(tmpDir, "agent", "agent.sock")
+	agentEnv := append(os.Environ(), agent.SocketEnv+"="+socketPath)
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile}, args...)...)
+		cmd.Env = agentEnv
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	// Without an agent, commands collect locally
+	if output, err := run(t, "env"); err != nil || output != "export API_KEY='first'\n" {
+		t.Fatalf("sstart env without agent: %v\n%s", err, output)
+	}
+	if _, err := run(t, "agent", "status"); err == nil {
+		t.Fatal("expected agent status to fail without a running agent")
+	}
+
+	agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1h")
+	agentCmd.Env = agentEnv
+	if err := agentCmd.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer func() { _ = agentCmd.Process.Kill() }()
+	waitForFile(t, socketPath+".token")
+
+	if info, err := os.Stat(filepath.Dir(socketPath)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected the socket directory to be 0700, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	if output, err := run(t, "env"); err != nil || output != "export API_KEY='first'\n" {
+		t.Fatalf("sstart env with agent: %v\n%s", err, output)
+	}
+
+	// The agent serves the collected secrets from memory; --no-agent collects locally
+	if err := os.WriteFile(filepath.Join(tmpDir, ".env"), []byte("API_KEY=second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	if output, err := run(t, "env"); err != nil || output != "export API_KEY='first'\n" {
+		t.Errorf("expected the agent to serve the secret from memory: %v\n%s", err, output)
+	}
+	if output, err := run(t, "--no-agent", "env"); err != nil || output != "export API_KEY='second'\n" {
+		t.Errorf("expected --no-agent to collect locally: %v\n%s", err, output)
+	}
+	if output, err := run(t, "agent", "get", "API_KEY"); err != nil || output != "first\n" {
+		t.Errorf("sstart agent get: %v\n%s", err, output)
+	}
+	if output, err := run(t, "agent", "status"); err != nil || !strings.Contains(output, "Configs: 1") {
+		t.Errorf("sstart agent status: %v\n%s", err, output)
+	}
+
+	// Requests without the agent's token are rejected
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to agent: %v", err)
+	}
+	_ = json.NewEncoder(conn).Encode(agent.Request{Token: "wrong", Op: agent.OpCollect, Config: configFile})
+	var resp agent.Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("Failed to read agent response: %v", err)
+	}
+	conn.Close()
+	if resp.Error == "" || len(resp.Secrets) != 0 {
+		t.Errorf("expected an unauthenticated request to be rejected, got %+v", resp)
+	}
+
+	if output, err := run(t, "agent", "stop"); err != nil {
+		t.Fatalf("sstart agent stop: %v\n%s", err, output)
+	}
+	done := make(chan error, 1)
+	go func() { done <- agentCmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not exit after stop")
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed, got %v", err)
+	}
+}
+
+// waitForFile waits until path exists
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", path)
+}
+
+// TestE2E_Agent_Warming tests that the agent fetches secrets again before they expire while they are in use
+func TestE2E_Agent_Warming(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=first\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	auditPath := filepath.Join(tmpDir, "audit.jsonl")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+audit:
+  enabled: true
+  path: ` + auditPath + `
+providers:
+  - kind: dotenv
+    path: ` + envFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// fetches returns how many times the provider was fetched
+	fetches := func() int {
+		data, _ := os.ReadFile(auditPath)
+		return strings.Count(string(data), "\n")
+	}
+
+	for _, tc := range []struct {
+		name     string
+		warmIdle string
+	}{
+		{name: "warming", warmIdle: "2s"},
+		{name: "disabled", warmIdle: "0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_ = os.Remove(auditPath)
+			if err := os.WriteFile(envFile, []byte("API_KEY=first\n"), 0644); err != nil {
+				t.Fatalf("Failed to write .env file: %v", err)
+			}
+			socketPath := filepath.Join(tmpDir, tc.name, "agent.sock")
+			agentEnv := append(os.Environ(), agent.SocketEnv+"="+socketPath)
+			agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1s", "--warm-idle", tc.warmIdle)
+			agentCmd.Env = agentEnv
+			if err := agentCmd.Start(); err != nil {
+				t.Fatalf("Failed to start agent: %v", err)
+			}
+			defer func() {
+				_ = agentCmd.Process.Kill()
+				_ = agentCmd.Wait()
+			}()
+			waitForFile(t, socketPath+".token")
+
+			cmd := exec.Command(binaryPath, "--config", configFile, "env")
+			cmd.Env = agentEnv
+			if output, err := cmd.CombinedOutput(); err != nil || string(output) != "export API_KEY='first'\n" {
+				t.Fatalf("sstart env with agent: %v\n%s", err, output)
+			}
+			if err := os.WriteFile(envFile, []byte("API_KEY=second\n"), 0644); err != nil {
+				t.Fatalf("Failed to write .env file: %v", err)
+			}
+
+			// The agent fetches again before the secrets expire, without a request
+			time.Sleep(1500 * time.Millisecond)
+			if tc.warmIdle == "0" {
+				if n := fetches(); n != 1 {
+					t.Fatalf("expected no refresh with warming disabled, got %d fetches", n)
+				}
+				return
+			}
+			if n := fetches(); n < 2 {
+				t.Fatalf("expected the agent to refresh the secrets, got %d fetches", n)
+			}
+
+			// Once nobody asks for the secrets, they are no longer refreshed
+			time.Sleep(2500 * time.Millisecond)
+			idle := fetches()
+			time.Sleep(1500 * time.Millisecond)
+			if n := fetches(); n != idle {
+				t.Errorf("expected idle secrets not to be refreshed, got %d fetches after %d", n, idle)
+			}
+		})
+	}
+}
+
+// TestE2E_Agent_ClientEnvironment tests that the agent resolves configs in the working directory and
+// environment of each command rather than its own
+func TestE2E_Agent_ClientEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: env
+    id: app
+    keys:
+      APP_TOKEN: ==
+  - kind: env
+    id: extra
+    keys:
+      EXTRA: ==
+    enabled: '{{ eq (env "USE_EXTRA") "1" }}'
+  - kind: template
+    id: greeting
+    templates:
+      GREETING: 'hi {{ .Env.APP_TOKEN }}'
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	socketPath := filepath.Join(tmpDir, "agent", "agent.sock")
+
+	agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1h")
+	agentCmd.Env = append(os.Environ(), agent.SocketEnv+"="+socketPath, "APP_TOKEN=agent", "USE_EXTRA=1")
+	if err := agentCmd.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer func() { _ = agentCmd.Process.Kill() }()
+	waitForFile(t, socketPath+".token")
+
+	for i, tc := range []struct{ token, useExtra string }{{"first", ""}, {"second", ""}, {"first", ""}, {"first", "1"}} {
+		cmd := exec.Command(binaryPath, "--config", configFile, "env")
+		// Variables the config does not read, like a shell's, share the collected secrets
+		cmd.Env = append(os.Environ(), agent.SocketEnv+"="+socketPath, "APP_TOKEN="+tc.token, "EXTRA=extra", "USE_EXTRA="+tc.useExtra, fmt.Sprintf("SHLVL=%d", i), fmt.Sprintf("PWD=%s/%d", tmpDir, i))
+		output, err := cmd.CombinedOutput()
+		if err != nil || !strings.Contains(string(output), "export APP_TOKEN='"+tc.token+"'\n") || !strings.Contains(string(output), "export GREETING='hi "+tc.token+"'\n") {
+			t.Errorf("expected the agent to read the command's environment: %v\n%s", err, output)
+		}
+		// 'enabled' conditions are evaluated in the command's environment
+		if strings.Contains(string(output), "EXTRA") != (tc.useExtra == "1") {
+			t.Errorf("expected the extra provider only with USE_EXTRA=1, got:\n%s", output)
+		}
+	}
+
+	cmd := exec.Command(binaryPath, "agent", "status")
+	cmd.Env = append(os.Environ(), agent.SocketEnv+"="+socketPath)
+	if output, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(output), "Configs: 3") {
+		t.Errorf("expected one session per value of APP_TOKEN and USE_EXTRA: %v\n%s", err, output)
+	}
+}
+
+// TestE2E_Agent_Includes tests that the agent loads a config again when a file it includes changes
+func TestE2E_Agent_Includes(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configFile, []byte("include: shared.yml\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	writeShared := func(value string) {
+		t.Helper()
+		sharedYAML := `
+providers:
+  - kind: env
+    keys:
+      APP_TOKEN: ` + value + `
+`
+		if err := os.WriteFile(filepath.Join(tmpDir, "shared.yml"), []byte(sharedYAML), 0644); err != nil {
+			t.Fatalf("Failed to write included config file: %v", err)
+		}
+	}
+	writeShared("FIRST")
+	socketPath := filepath.Join(tmpDir, "agent", "agent.sock")
+	agentEnv := append(os.Environ(), agent.SocketEnv+"="+socketPath, "APP_TOKEN=token")
+
+	agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1h")
+	agentCmd.Env = agentEnv
+	if err := agentCmd.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer func() { _ = agentCmd.Process.Kill() }()
+	waitForFile(t, socketPath+".token")
+
+	env := func() string {
+		t.Helper()
+		cmd := exec.Command(binaryPath, "--config", configFile, "env")
+		cmd.Env = agentEnv
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("sstart env with agent: %v\n%s", err, output)
+		}
+		return string(output)
+	}
+
+	if output := env(); output != "export FIRST='token'\n" {
+		t.Fatalf("unexpected output: %s", output)
+	}
+	writeShared("SECOND")
+	// Make sure the modification time changes on filesystems with a coarse resolution
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(filepath.Join(tmpDir, "shared.yml"), future, future)
+	if output := env(); output != "export SECOND='token'\n" {
+		t.Errorf("expected the agent to load the changed include, got: %s", output)
+	}
+}
+
+// TestE2E_Agent_Errors tests that errors of the agent exit with the same code as local collections
+func TestE2E_Agent_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: dotenv
+    path: ` + filepath.Join(tmpDir, "missing.env") + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	socketPath := filepath.Join(tmpDir, "agent", "agent.sock")
+	agentEnv := append(os.Environ(), agent.SocketEnv+"="+socketPath)
+
+	agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1h")
+	agentCmd.Env = agentEnv
+	if err := agentCmd.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer func() { _ = agentCmd.Process.Kill() }()
+	waitForFile(t, socketPath+".token")
+
+	cmd := exec.Command(binaryPath, "--output", "json", "--config", configFile, "env")
+	cmd.Env = agentEnv
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+		t.Fatalf("expected exit code 5 for a provider error, got %v", err)
+	}
+	var report struct {
+		Error struct {
+			Type     string `json:"type"`
+			Provider string `json:"provider"`
+			Class    string `json:"class"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(exitErr.Stderr, &report); err != nil {
+		t.Fatalf("expected a JSON error report, got %v: %s%s", err, output, exitErr.Stderr)
+	}
+	if report.Error.Type != "provider" || report.Error.Provider != "dotenv" || report.Error.Class != "not_found" {
+		t.Errorf("expected the provider error of the agent, got %+v", report.Error)
+	}
+}
+
+// TestE2E_Agent_VerifyConfig tests that the agent verifies the signature of the config itself,
+// and that --verify-config fails when there is no public key to verify with
+func TestE2E_Agent_VerifyConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=signed\n")
+	publicKey := filepath.Join(tmpDir, "keys", "sign.pub")
+	secretKey := filepath.Join(tmpDir, "keys", "sign.key")
+	if err := config.GenerateKeyPair(publicKey, secretKey); err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	if err := config.SignConfig(configFile, secretKey); err != nil {
+		t.Fatalf("Failed to sign config: %v", err)
+	}
+
+	// Without $HOME, $XDG_CONFIG_HOME and $SSTART_CONFIG_PUBLIC_KEY there is no key to verify with
+	var noKeyEnv []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name != "HOME" && name != "XDG_CONFIG_HOME" && name != config.PublicKeyEnv {
+			noKeyEnv = append(noKeyEnv, kv)
+		}
+	}
+	cmd := exec.Command(binaryPath, "--config", configFile, "--verify-config", "--no-agent", "env")
+	cmd.Env = noKeyEnv
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 || !strings.Contains(string(output), "no public key") {
+		t.Errorf("expected --verify-config to fail without a public key, got %v: %s", err, output)
+	}
+
+	socketPath := filepath.Join(tmpDir, "agent", "agent.sock")
+	agentEnv := append(os.Environ(), agent.SocketEnv+"="+socketPath, config.PublicKeyEnv+"="+publicKey)
+	agentCmd := exec.Command(binaryPath, "agent", "--ttl", "1h")
+	agentCmd.Env = agentEnv
+	if err := agentCmd.Start(); err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	defer func() { _ = agentCmd.Process.Kill() }()
+	waitForFile(t, socketPath+".token")
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "--verify-config", "env")
+	cmd.Env = agentEnv
+	if output, err := cmd.CombinedOutput(); err != nil || string(output) != "export API_KEY='signed'\n" {
+		t.Fatalf("sstart env with agent: %v\n%s", err, output)
+	}
+
+	// A file changed after the client verified it is rejected by the agent
+	if err := os.WriteFile(configFile, []byte("providers:\n  - kind: env\n    keys:\n      HOME: STOLEN\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(configFile, future, future)
+	_, err = agent.NewClient(socketPath, "").Do(context.Background(), &agent.Request{Op: agent.OpCollect, Config: configFile, VerifyKey: publicKey})
+	var configErr *agent.ConfigError
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), "does not match its signature") {
+		t.Errorf("expected the agent to reject the modified config, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package end2end
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestE2E_Audit_Log tests that fetched keys are recorded in the audit log with hashed values
+func TestE2E_Audit_Log(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=super-secret-value\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	auditPath := filepath.Join(tmpDir, "logs", "audit.jsonl")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+audit:
+  enabled: true
+  path: ` + auditPath + `
+providers:
+  - kind: dotenv
+    id: local
+    path: ` + envFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput(); err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	if output, err := exec.Command(binaryPath, "--config", configFile, "run", "--", "sh", "-c", "true").CombinedOutput(); err != nil {
+		t.Fatalf("sstart run failed: %v\n%s", err, output)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if strings.Contains(string(data), "super-secret-value") {
+		t.Fatalf("audit log must not contain secret values:\n%s", data)
+	}
+
+	type entry struct {
+		Time     time.Time         `json:"time"`
+		Command  string            `json:"command"`
+		Config   string            `json:"config"`
+		Provider string            `json:"provider"`
+		Kind     string            `json:"kind"`
+		Source   string            `json:"source"`
+		Keys     map[string]string `json:"keys"`
+	}
+	var entries []entry
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid audit log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d:\n%s", len(entries), data)
+	}
+
+	sum := sha256.Sum256([]byte("super-secret-value"))
+	wantHash := "sha256:" + hex.EncodeToString(sum[:])
+	for i, wantCommand := range []string{"sstart env", "sstart run -- sh"} {
+		e := entries[i]
+		if e.Command != wantCommand {
+			t.Errorf("entry %d: expected command %q, got %q", i, wantCommand, e.Command)
+		}
+		if e.Config != configFile || e.Provider != "local" || e.Kind != "dotenv" || e.Source != "provider" {
+			t.Errorf("entry %d: unexpected fields %+v", i, e)
+		}
+		if e.Keys["API_KEY"] != wantHash {
+			t.Errorf("entry %d: expected API_KEY hash %s, got %v", i, wantHash, e.Keys)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("entry %d: missing time", i)
+		}
+	}
+
+	info, err := os.Stat(auditPath)
+	if err != nil {
+		t.Fatalf("Failed to stat audit log: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected audit log permissions 0600, got %o", perm)
+	}
+}
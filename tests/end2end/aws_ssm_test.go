@@ -0,0 +1,100 @@
+package end2end
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newFakeSSMAPI serves GetParametersByPath for the given parameters, returning one parameter per page
+func newFakeSSMAPI(t *testing.T, parameters map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParametersByPath" {
+			http.Error(w, "unsupported operation", http.StatusBadRequest)
+			return
+		}
+		var input struct {
+			Path           string
+			Recursive      bool
+			WithDecryption bool
+			NextToken      string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || !input.WithDecryption {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		prefix := strings.TrimSuffix(input.Path, "/") + "/"
+		var names []string
+		for name := range parameters {
+			rest, ok := strings.CutPrefix(name, prefix)
+			if ok && (input.Recursive || !strings.Contains(rest, "/")) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		output := map[string]interface{}{"Parameters": []interface{}{}}
+		for i, name := range names {
+			if input.NextToken != "" && name <= input.NextToken {
+				continue
+			}
+			output["Parameters"] = []interface{}{map[string]string{"Name": name, "Value": parameters[name], "Type": "SecureString"}}
+			if i < len(names)-1 {
+				output["NextToken"] = name
+			}
+			break
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(output)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestE2E_AWSSSM tests loading parameters by path and with chamber conventions
+func TestE2E_AWSSSM(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	server := newFakeSSMAPI(t, map[string]string{
+		"/myapp/db-password":        "from-myapp",
+		"/myapp/api.key":            "api",
+		"/myapp/nested/ignored":     "nested",
+		"/shared/db-password":       "from-shared",
+		"/shared/sentry_dsn":        "sentry",
+		"/platform/prod/DB/HOST":    "db.internal",
+		"/platform/prod/CACHE_HOST": "cache.internal",
+	})
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: aws_ssm
+    id: chamber
+    services: [shared, MyApp]
+    region: us-east-1
+    endpoint: ` + server.URL + `
+  - kind: aws_ssm
+    id: platform
+    path: /platform/prod/
+    region: us-east-1
+    endpoint: ` + server.URL + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export API_KEY='api'\nexport CACHE_HOST='cache.internal'\nexport DB_HOST='db.internal'\nexport DB_PASSWORD='from-myapp'\nexport SENTRY_DSN='sentry'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+}
@@ -0,0 +1,65 @@
+package end2end
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestE2E_Browse tests revealing and copying a secret in the terminal UI, driven through stdin
+func TestE2E_Browse(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abcdefgh\nDB_PASSWORD=p@ssw0rd-123\nINTERNAL=hidden-value\n")
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "browse", "--exclude", "INTERNAL")
+	cmd.Env = append(os.Environ(), "DISPLAY=", "WAYLAND_DISPLAY=", "PATH="+tmpDir)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to open stdin: %v", err)
+	}
+	var output strings.Builder
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart browse: %v", err)
+	}
+
+	// Select DB_PASSWORD, reveal and copy it, then quit
+	for _, key := range []string{"j", " ", "c", "q"} {
+		time.Sleep(300 * time.Millisecond)
+		if _, err := io.WriteString(stdin, key); err != nil {
+			t.Fatalf("Failed to send key %q: %v", key, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sstart browse failed: %v\nOutput: %s", err, output.String())
+		}
+	case <-time.After(10 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatalf("sstart browse did not quit\nOutput: %s", output.String())
+	}
+
+	out := output.String()
+	for _, want := range []string{"2 secrets from 1 providers", "API_KEY", "ab****", "p@ssw0rd-123", "Copied DB_PASSWORD to the clipboard"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q\nOutput: %q", want, out)
+		}
+	}
+	if strings.Contains(out, "INTERNAL") || strings.Contains(out, "abcdefgh") {
+		t.Errorf("expected excluded and unrevealed values to stay hidden\nOutput: %q", out)
+	}
+	// Without a system clipboard, the value is copied with OSC 52
+	if osc52 := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("p@ssw0rd-123")) + "\a"; !strings.Contains(out, osc52) {
+		t.Errorf("expected output to contain the OSC 52 sequence %q\nOutput: %q", osc52, out)
+	}
+}
@@ -0,0 +1,159 @@
+package end2end
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// newFakeBucketServer serves objects over the S3 path-style and GCS JSON API download paths
+func newFakeBucketServer(t *testing.T, objects map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+		// GCS: /storage/v1/b/<bucket>/o/<object>?alt=media
+		if bucket, object, ok := cutGCSPath(name); ok && r.URL.Query().Get("alt") == "media" {
+			name = "/" + bucket + "/" + object
+		}
+		data, ok := objects[name]
+		if !ok || r.Method != http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func cutGCSPath(path string) (string, string, bool) {
+	rest, ok := bytes.CutPrefix([]byte(path), []byte("/storage/v1/b/"))
+	if !ok {
+		return "", "", false
+	}
+	bucket, object, ok := bytes.Cut(rest, []byte("/o/"))
+	return string(bucket), string(object), ok
+}
+
+// TestE2E_Bucket tests loading dotenv and JSON objects, optionally age-encrypted, from S3 and GCS buckets
+func TestE2E_Bucket(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate age identity: %v", err)
+	}
+	var encrypted bytes.Buffer
+	armored := armor.NewWriter(&encrypted)
+	w, err := age.Encrypt(armored, identity.Recipient())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	_, _ = w.Write([]byte("ENCRYPTED_KEY=from-age\n"))
+	_ = w.Close()
+	_ = armored.Close()
+	identityFile := filepath.Join(tmpDir, "identity.txt")
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write identity: %v", err)
+	}
+
+	server := newFakeBucketServer(t, map[string][]byte{
+		"/secrets/app/prod.env":     []byte("S3_KEY=from-s3\nOTHER=skip\n"),
+		"/secrets/app/prod.env.age": encrypted.Bytes(),
+		"/gcs-bucket/app/prod.json": []byte(`{"GCS_KEY": "from-gcs", "PORT": 8080}`),
+	})
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: bucket
+    id: s3
+    url: s3://secrets/app/prod.env
+    endpoint: ` + server.URL + `
+    keys:
+      S3_KEY: ==
+  - kind: bucket
+    id: s3-age
+    url: s3://secrets/app/prod.env.age
+    endpoint: ` + server.URL + `
+    age:
+      identity_file: ` + identityFile + `
+  - kind: bucket
+    id: gcs
+    url: gs://gcs-bucket/app/prod.json
+    endpoint: ` + server.URL + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "env")
+	cmd.Env = append(os.Environ(), "AWS_REGION=us-east-1", "AWS_ACCESS_KEY_ID=", "AWS_SECRET_ACCESS_KEY=")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export ENCRYPTED_KEY='from-age'\nexport GCS_KEY='from-gcs'\nexport PORT='8080'\nexport S3_KEY='from-s3'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+// TestE2E_Bucket_Dedup tests that providers that fetch the same object with the same keys are
+// fetched once per collection
+func TestE2E_Bucket_Dedup(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	var requests atomic.Int32
+	objects := newFakeBucketServer(t, map[string][]byte{
+		"/gcs-bucket/app/prod.json": []byte(`{"DB_URL": "postgres://db", "API_KEY": "key"}`),
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		objects.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: bucket
+    id: shared
+    url: gs://gcs-bucket/app/prod.json
+    endpoint: ` + server.URL + `
+  - kind: bucket
+    id: shared-again
+    url: gs://gcs-bucket/app/prod.json
+    endpoint: ` + server.URL + `
+  - kind: bucket
+    id: db-only
+    url: gs://gcs-bucket/app/prod.json
+    endpoint: ` + server.URL + `
+    keys:
+      DB_URL: DATABASE_URL
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export API_KEY='key'\nexport DATABASE_URL='postgres://db'\nexport DB_URL='postgres://db'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+	// The first two providers are identical; the third maps different keys
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests to the bucket, got %d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+// TestE2E_Bundle tests writing an encrypted bundle and running a command from it without a config
+func TestE2E_Bundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\nDB_PASSWORD=p@ss\nINTERNAL=hidden\n")
+	offlineDir := t.TempDir()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate age identity: %v", err)
+	}
+	identityFile := filepath.Join(tmpDir, "key.txt")
+	if err := os.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write identity file: %v", err)
+	}
+
+	bundle := func(t *testing.T, env []string, args ...string) string {
+		t.Helper()
+		bundleFile := filepath.Join(tmpDir, t.Name()[strings.LastIndex(t.Name(), "/")+1:]+".bundle")
+		// An existing bundle does not keep its mode
+		if err := os.WriteFile(bundleFile, []byte("stale"), 0644); err != nil {
+			t.Fatalf("Failed to write bundle: %v", err)
+		}
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile, "bundle", "--file", bundleFile, "--exclude", "INTERNAL"}, args...)...)
+		cmd.Env = append(os.Environ(), env...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("sstart bundle failed: %v\n%s", err, output)
+		}
+		if info, err := os.Stat(bundleFile); err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("expected a bundle with mode 0600, got %v", err)
+		}
+		return bundleFile
+	}
+	run := func(t *testing.T, env []string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append(append([]string{"run"}, args...), "--", "sh", "-c", `echo "$API_KEY:$DB_PASSWORD:$INTERNAL"`)...)
+		// No config file is reachable from the working directory
+		cmd.Dir = offlineDir
+		cmd.Env = append(os.Environ(), env...)
+		output, err := cmd.CombinedOutput()
+		return strings.TrimSpace(string(output)), err
+	}
+
+	t.Run("recipient", func(t *testing.T) {
+		bundleFile := bundle(t, nil, "--recipient", identity.Recipient().String())
+		if output, err := run(t, nil, "--from-bundle", bundleFile, "--identity", identityFile); err != nil || output != "abc:p@ss:" {
+			t.Errorf("run --from-bundle = %q, %v", output, err)
+		}
+		if output, err := run(t, []string{"SSTART_AGE_IDENTITY=" + identity.String()}, "--from-bundle", bundleFile, "--only", "API_KEY"); err != nil || output != "abc::" {
+			t.Errorf("run --from-bundle --only API_KEY = %q, %v", output, err)
+		}
+		other, _ := age.GenerateX25519Identity()
+		if output, err := run(t, []string{"SSTART_AGE_IDENTITY=" + other.String()}, "--from-bundle", bundleFile); err == nil || !strings.Contains(output, "failed to decrypt bundle") {
+			t.Errorf("expected a decryption error with another identity, got %q, %v", output, err)
+		}
+	})
+
+	t.Run("passphrase", func(t *testing.T) {
+		bundleFile := bundle(t, []string{"SSTART_BUNDLE_PASSPHRASE=correct horse"})
+		if output, err := run(t, []string{"SSTART_BUNDLE_PASSPHRASE=correct horse"}, "--from-bundle", bundleFile); err != nil || output != "abc:p@ss:" {
+			t.Errorf("run --from-bundle = %q, %v", output, err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		bundleFile := bundle(t, []string{"SSTART_BUNDLE_PASSPHRASE=secret"}, "--expires", "1s")
+		time.Sleep(1500 * time.Millisecond)
+		if output, err := run(t, []string{"SSTART_BUNDLE_PASSPHRASE=secret"}, "--from-bundle", bundleFile); err == nil || !strings.Contains(output, "bundle expired at") {
+			t.Errorf("expected an expired bundle error, got %q, %v", output, err)
+		}
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", configFile, "bundle", "--file", filepath.Join(tmpDir, "unencrypted.bundle"))
+		cmd.Env = append(os.Environ(), "SSTART_BUNDLE_PASSPHRASE=")
+		if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "set --recipient or $SSTART_BUNDLE_PASSPHRASE") {
+			t.Errorf("expected an error without an encryption key, got %v: %s", err, output)
+		}
+	})
+}
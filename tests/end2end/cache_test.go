@@ -339,3 +339,65 @@ providers:
 		})
 	}
 }
+
+// TestE2E_Cache_Profiles tests that profiles never share cached secrets, even when they resolve
+// to the same provider config
+func TestE2E_Cache_Profiles(t *testing.T) {
+	// Skip if keyring not available
+	testCache := cache.New()
+	if !testCache.IsAvailable() {
+		t.Skip("keyring not available, skipping cache test")
+	}
+	_ = testCache.Clear()
+	defer func() { _ = testCache.Clear() }()
+
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(envFile, []byte("API_KEY=dev-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	configContent := `
+cache:
+  enabled: true
+  ttl: 1m
+
+providers:
+  - kind: dotenv
+    id: test-env
+    path: ` + envFile + `
+
+profiles:
+  dev:
+  prod:
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	collect := func(profile string) string {
+		t.Helper()
+		cfg, err := config.Load(configFile, config.WithProfile(profile))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		collected, err := secrets.NewCollector(cfg).Collect(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Failed to collect secrets: %v", err)
+		}
+		return collected["API_KEY"]
+	}
+
+	if got := collect("dev"); got != "dev-secret" {
+		t.Fatalf("Expected API_KEY=dev-secret with profile dev, got %s", got)
+	}
+	if err := os.WriteFile(envFile, []byte("API_KEY=prod-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if got := collect("prod"); got != "prod-secret" {
+		t.Errorf("Expected API_KEY=prod-secret with profile prod, got %s (served from the cache of profile dev)", got)
+	}
+	if got := collect("dev"); got != "dev-secret" {
+		t.Errorf("Expected cached API_KEY=dev-secret with profile dev, got %s", got)
+	}
+}
@@ -0,0 +1,96 @@
+package end2end
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeHangingVaultConfig writes a config with a Vault provider whose server never answers, and
+// returns the config path
+func writeHangingVaultConfig(t *testing.T, tmpDir string) string {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := fmt.Sprintf(`
+providers:
+  - kind: vault
+    id: hung
+    address: %s
+    token: test-token
+    path: secret/app
+`, server.URL)
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configFile
+}
+
+// TestE2E_Cancel tests that collecting from a backend that hangs can be interrupted or limited
+// in time, without waiting for the timeouts of the provider's library
+func TestE2E_Cancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeHangingVaultConfig(t, tmpDir)
+
+	t.Run("max collect time", func(t *testing.T) {
+		start := time.Now()
+		output, err := exec.Command(binaryPath, "--config", configFile, "--max-collect-time", "1s", "env").CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+			t.Fatalf("expected exit code 5, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "failed to fetch from provider 'hung': maximum collect time exceeded (1s)") {
+			t.Errorf("expected a max collect time error, got:\n%s", output)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("expected sstart to give up after 1s, took %s", elapsed)
+		}
+	})
+
+	t.Run("interrupt", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", configFile, "--output", "json", "env")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start sstart env: %v", err)
+		}
+		time.Sleep(time.Second)
+		if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+			t.Fatalf("Failed to interrupt sstart env: %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case err := <-done:
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 130 {
+				t.Fatalf("expected exit code 130, got %v\n%s", err, stderr.String())
+			}
+		case <-time.After(10 * time.Second):
+			_ = cmd.Process.Kill()
+			t.Fatal("sstart env did not exit after SIGINT")
+		}
+		if !strings.Contains(stderr.String(), `"type":"interrupted"`) || !strings.Contains(stderr.String(), "interrupted (interrupt)") {
+			t.Errorf("expected an interrupted error, got:\n%s", stderr.String())
+		}
+	})
+}
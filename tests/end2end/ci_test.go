@@ -0,0 +1,91 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_CI_Masking tests that secrets are registered with the log masking of GitHub Actions
+func TestE2E_CI_Masking(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc123\nCERT=\"line1\nline2\"\n")
+
+	run := func(t *testing.T, env []string, args ...string) (string, string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile}, args...)...)
+		cmd.Env = append(os.Environ(), env...)
+		var stdout, stderr strings.Builder
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("sstart %v failed: %v\n%s", args, err, stderr.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	stdout, stderr := run(t, []string{"SSTART_CI=", "GITHUB_ACTIONS=true"}, "env", "--format", "json")
+	for _, mask := range []string{"::add-mask::abc123\n", "::add-mask::line1\n", "::add-mask::line2\n"} {
+		if !strings.Contains(stderr, mask) {
+			t.Errorf("expected %q on stderr, got:\n%s", mask, stderr)
+		}
+	}
+	if strings.Contains(stdout, "::add-mask::") {
+		t.Errorf("expected masking commands to stay out of stdout, got:\n%s", stdout)
+	}
+
+	// SSTART_CI=0 turns off detection; --ci forces CI mode without native masking
+	if _, stderr := run(t, []string{"SSTART_CI=0", "GITHUB_ACTIONS=true"}, "env"); strings.Contains(stderr, "::add-mask::") {
+		t.Errorf("expected SSTART_CI=0 to turn off CI mode, got:\n%s", stderr)
+	}
+	if _, stderr := run(t, nil, "--ci", "env"); strings.Contains(stderr, "::add-mask::") {
+		t.Errorf("expected no GitHub masking outside GitHub Actions, got:\n%s", stderr)
+	}
+
+	// The github format uses the multi-line syntax of $GITHUB_ENV
+	stdout, _ = run(t, nil, "env", "--format", "github", "--only", "CERT")
+	lines := strings.Split(stdout, "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "CERT<<sstart_") || lines[1] != "line1" || lines[2] != "line2" || lines[3] != strings.TrimPrefix(lines[0], "CERT<<") {
+		t.Errorf("unexpected github format output:\n%s", stdout)
+	}
+}
+
+// TestE2E_CI_NonInteractiveSSO tests that CI mode fails instead of starting a browser login
+func TestE2E_CI_NonInteractiveSSO(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=abc\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `sso:
+  oidc:
+    clientId: sstart
+    issuer: https://issuer.invalid
+    scopes: [openid]
+  tokenStorage:
+    backend: memory
+providers:
+  - kind: dotenv
+    path: ` + envFile + `
+    require_claims:
+      sub: alice
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "--ci", "env")
+	cmd.Env = append(os.Environ(), "HOME="+tmpDir)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected sstart env to fail without a browser login, got:\n%s", output)
+	}
+	if !strings.Contains(string(output), "interactive login is disabled in CI") {
+		t.Errorf("expected a CI login error, got:\n%s", output)
+	}
+}
@@ -0,0 +1,76 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_Completion tests completing --config with discovered config files and --profile with
+// the profiles they define
+func TestE2E_Completion(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	projectDir := filepath.Join(tmpDir, "project")
+	subDir := filepath.Join(projectDir, "sub")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	// The provider is never contacted: completion only parses the config
+	configYAML := `
+providers:
+  - kind: vault
+    id: vault
+    address: http://127.0.0.1:1
+    path: myapp
+profiles:
+  dev: {}
+  prod: {}
+  preview: {}
+`
+	if err := os.WriteFile(filepath.Join(projectDir, ".sstart.yml"), []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, ".sstart.toml"), []byte("providers = []\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	complete := func(t *testing.T, dir string, args ...string) []string {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"__complete"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "SSTART_GLOBAL_CONFIG="+filepath.Join(tmpDir, "missing.yml"))
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sstart __complete failed: %v", err)
+		}
+		// The last line is the completion directive
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return lines[:len(lines)-1]
+	}
+
+	t.Run("config paths", func(t *testing.T) {
+		got := complete(t, subDir, "--config", "")
+		want := []string{".sstart.toml", filepath.Join("..", ".sstart.yml")}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		got := complete(t, projectDir, "run", "--profile", "p")
+		if strings.Join(got, ",") != "preview,prod" {
+			t.Errorf("Expected [preview prod], got %v", got)
+		}
+	})
+
+	t.Run("profiles of --config", func(t *testing.T) {
+		got := complete(t, subDir, "--config", filepath.Join("..", ".sstart.yml"), "env", "--profile", "")
+		if strings.Join(got, ",") != "dev,preview,prod" {
+			t.Errorf("Expected [dev preview prod], got %v", got)
+		}
+	})
+}
@@ -1,11 +1,15 @@
 package end2end
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dirathea/sstart/internal/config"
 	"github.com/dirathea/sstart/internal/provider"
@@ -392,6 +396,759 @@ providers:
 	}
 }
 
+// TestE2E_Config_Includes tests merging of included config files
+func TestE2E_Config_Includes(t *testing.T) {
+	tmpDir := t.TempDir()
+	sharedDir := filepath.Join(tmpDir, "shared")
+	serviceDir := filepath.Join(tmpDir, "services", "api")
+	for _, dir := range []string{sharedDir, serviceDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	writeFile(filepath.Join(sharedDir, "providers.yml"), `
+inherit: false
+cache:
+  enabled: true
+  ttl: 10m
+providers:
+  - kind: vault
+    id: vault-shared
+    path: shared/secret
+    address: https://vault.example.com
+  - kind: dotenv
+    path: .env.shared
+`)
+	writeFile(filepath.Join(serviceDir, "team-overrides.yml"), `
+cache:
+  ttl: 1m
+providers:
+  - kind: dotenv
+    path: .env.team
+`)
+
+	t.Run("merge order and semantics", func(t *testing.T) {
+		configFile := filepath.Join(serviceDir, ".sstart.yml")
+		writeFile(configFile, `
+include:
+  - ../../shared/providers.yml
+  - team-overrides.yml
+providers:
+  - kind: aws_secretsmanager
+    id: aws-api
+    secret_id: api/secret
+`)
+
+		cfg, err := config.Load(configFile)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+
+		// Scalars come from the last file that sets them
+		if cfg.Inherit {
+			t.Error("expected inherit=false from the included file")
+		}
+		// Maps are merged recursively
+		if !cfg.IsCacheEnabled() || cfg.GetCacheTTL() != time.Minute {
+			t.Errorf("expected cache enabled with TTL 1m, got enabled=%v ttl=%v", cfg.IsCacheEnabled(), cfg.GetCacheTTL())
+		}
+
+		// Providers are concatenated in include order; the same id replaces the earlier entry in place
+		wantIDs := []string{"vault-shared", "dotenv", "aws-api"}
+		if len(cfg.Providers) != len(wantIDs) {
+			t.Fatalf("expected %d providers, got %d", len(wantIDs), len(cfg.Providers))
+		}
+		for i, id := range wantIDs {
+			if cfg.Providers[i].ID != id {
+				t.Errorf("provider[%d].ID = %q, want %q", i, cfg.Providers[i].ID, id)
+			}
+		}
+		dotenvCfg, _ := cfg.GetProvider("dotenv")
+		if dotenvCfg.Config["path"] != ".env.team" {
+			t.Errorf("dotenv path = %v, want '.env.team'", dotenvCfg.Config["path"])
+		}
+	})
+
+	t.Run("include cycle", func(t *testing.T) {
+		a := filepath.Join(tmpDir, "a.yml")
+		b := filepath.Join(tmpDir, "b.yml")
+		writeFile(a, "include: b.yml\n")
+		writeFile(b, "include: a.yml\n")
+
+		_, err := config.Load(a)
+		if err == nil || !strings.Contains(err.Error(), "include cycle") {
+			t.Fatalf("expected include cycle error, got: %v", err)
+		}
+	})
+
+	t.Run("missing include", func(t *testing.T) {
+		configFile := filepath.Join(tmpDir, "missing.yml")
+		writeFile(configFile, "include: [does-not-exist.yml]\n")
+
+		_, err := config.Load(configFile)
+		if err == nil || !strings.Contains(err.Error(), "failed to read included config file") {
+			t.Fatalf("expected missing include error, got: %v", err)
+		}
+	})
+}
+
+// TestE2E_Config_DiscoveryAndGlobal tests config discovery in parent directories and layering over the global config
+func TestE2E_Config_DiscoveryAndGlobal(t *testing.T) {
+	tmpDir := t.TempDir()
+	nestedDir := filepath.Join(tmpDir, "project", "src", "pkg")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	projectConfig := filepath.Join(tmpDir, "project", ".sstart.yml")
+	if err := os.WriteFile(projectConfig, []byte(`
+cache:
+  ttl: 1m
+providers:
+  - kind: dotenv
+    path: .env
+`), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	globalConfig := filepath.Join(tmpDir, "global.yml")
+	if err := os.WriteFile(globalConfig, []byte(`
+inherit: false
+cache:
+  enabled: true
+  ttl: 10m
+`), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+
+	t.Run("discover nearest config", func(t *testing.T) {
+		path, err := config.Discover(nestedDir)
+		if err != nil {
+			t.Fatalf("Discover failed: %v", err)
+		}
+		if path != projectConfig {
+			t.Errorf("Discover() = %q, want %q", path, projectConfig)
+		}
+
+		path, err = config.Discover(tmpDir)
+		if err != nil {
+			t.Fatalf("Discover failed: %v", err)
+		}
+		if path != "" {
+			t.Errorf("expected no config above the project, got %q", path)
+		}
+	})
+
+	t.Run("project config over global config", func(t *testing.T) {
+		cfg, err := config.Load(projectConfig, config.WithGlobalConfig(globalConfig))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Inherit {
+			t.Error("expected inherit=false from the global config")
+		}
+		if !cfg.IsCacheEnabled() || cfg.GetCacheTTL() != time.Minute {
+			t.Errorf("expected cache enabled with TTL 1m, got enabled=%v ttl=%v", cfg.IsCacheEnabled(), cfg.GetCacheTTL())
+		}
+		if len(cfg.Providers) != 1 || cfg.Providers[0].ID != "dotenv" {
+			t.Errorf("expected the project dotenv provider, got %+v", cfg.Providers)
+		}
+	})
+
+	t.Run("global config alone", func(t *testing.T) {
+		cfg, err := config.Load("", config.WithGlobalConfig(globalConfig))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.GetCacheTTL() != 10*time.Minute {
+			t.Errorf("expected TTL 10m from the global config, got %v", cfg.GetCacheTTL())
+		}
+	})
+
+	t.Run("missing global config is ignored", func(t *testing.T) {
+		cfg, err := config.Load(projectConfig, config.WithGlobalConfig(filepath.Join(tmpDir, "missing.yml")))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if !cfg.Inherit {
+			t.Error("expected default inherit=true without a global config")
+		}
+	})
+
+	t.Run("no config at all", func(t *testing.T) {
+		_, err := config.Load("", config.WithGlobalConfig(filepath.Join(tmpDir, "missing.yml")))
+		if !errors.Is(err, config.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("dotenv fallback without config", func(t *testing.T) {
+		missingGlobal := config.WithGlobalConfig(filepath.Join(tmpDir, "missing.yml"))
+		_, err := config.Load("", missingGlobal, config.WithDotenvFallback(nestedDir))
+		if !errors.Is(err, config.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound without a .env file, got: %v", err)
+		}
+
+		envPath := filepath.Join(nestedDir, ".env")
+		if err := os.WriteFile(envPath, []byte("FOO=bar\n"), 0644); err != nil {
+			t.Fatalf("Failed to write .env file: %v", err)
+		}
+		cfg, err := config.Load("", missingGlobal, config.WithDotenvFallback(nestedDir))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.DotenvFallback != envPath || !cfg.Inherit {
+			t.Errorf("expected the dotenv fallback %q with inherit=true, got %q inherit=%v", envPath, cfg.DotenvFallback, cfg.Inherit)
+		}
+		if len(cfg.Providers) != 1 || cfg.Providers[0].Kind != "dotenv" || cfg.Providers[0].Config["path"] != envPath {
+			t.Errorf("expected a dotenv provider reading %q, got %+v", envPath, cfg.Providers)
+		}
+
+		// A config file takes precedence over the .env file
+		cfg, err = config.Load(projectConfig, missingGlobal, config.WithDotenvFallback(nestedDir))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.DotenvFallback != "" {
+			t.Errorf("expected no dotenv fallback with a config file, got %q", cfg.DotenvFallback)
+		}
+	})
+}
+
+// TestE2E_Config_EnabledProviders tests that providers with a false 'enabled' condition are dropped
+func TestE2E_Config_EnabledProviders(t *testing.T) {
+	t.Setenv("SSTART_TEST_CI", "true")
+
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `
+providers:
+  - kind: dotenv
+    id: app
+    path: .env
+    enabled: '{{ ne (env "SSTART_TEST_CI") "true" }}'
+  - kind: vault
+    id: app
+    path: secret/app
+    enabled: '{{ eq (env "SSTART_TEST_CI") "true" }}'
+  - kind: aws_secretsmanager
+    secret_id: app/secret
+    enabled: false
+  - kind: dotenv
+    id: platform
+    path: .env.platform
+    enabled: '{{ or (eq .OS "linux") (eq .OS "darwin") (eq .OS "windows") }}'
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Providers) != 2 {
+		t.Fatalf("expected 2 enabled providers, got %d: %+v", len(cfg.Providers), cfg.Providers)
+	}
+	if cfg.Providers[0].ID != "app" || cfg.Providers[0].Kind != "vault" {
+		t.Errorf("expected vault provider 'app' to be enabled, got %s (%s)", cfg.Providers[0].ID, cfg.Providers[0].Kind)
+	}
+	if cfg.Providers[1].ID != "platform" {
+		t.Errorf("expected provider 'platform' to be enabled, got %s", cfg.Providers[1].ID)
+	}
+	if _, ok := cfg.Providers[0].Config["enabled"]; ok {
+		t.Error("'enabled' should not be passed to the provider config")
+	}
+
+	for name, enabled := range map[string]string{
+		"not a boolean":  `'{{ env "SSTART_TEST_CI" }}-x'`,
+		"invalid syntax": `'{{ eq }'`,
+		"invalid type":   `[true]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			content := "providers:\n  - kind: dotenv\n    enabled: " + enabled + "\n"
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			_, err := config.Load(configFile)
+			if err == nil || !strings.Contains(err.Error(), "invalid 'enabled'") {
+				t.Fatalf("expected invalid enabled error, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestE2E_Config_ProviderDefaults tests that provider_defaults are merged under provider entries of the same kind
+func TestE2E_Config_ProviderDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `
+provider_defaults:
+  vault:
+    address: https://vault.example.com
+    mount: kv
+    auth:
+      method: approle
+      role: myapp
+
+providers:
+  - kind: vault
+    id: vault-app
+    path: secret/app
+  - kind: vault
+    id: vault-db
+    path: secret/db
+    mount: database
+    auth:
+      role: db
+  - kind: dotenv
+    path: .env
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	app, err := cfg.GetProvider("vault-app")
+	if err != nil {
+		t.Fatalf("Failed to get provider: %v", err)
+	}
+	if app.Config["address"] != "https://vault.example.com" || app.Config["mount"] != "kv" {
+		t.Errorf("expected defaults to apply to vault-app, got %v", app.Config)
+	}
+
+	db, err := cfg.GetProvider("vault-db")
+	if err != nil {
+		t.Fatalf("Failed to get provider: %v", err)
+	}
+	if db.Config["mount"] != "database" {
+		t.Errorf("expected mount override 'database', got %v", db.Config["mount"])
+	}
+	auth, ok := db.Config["auth"].(map[string]interface{})
+	if !ok || auth["method"] != "approle" || auth["role"] != "db" {
+		t.Errorf("expected auth merged with method=approle role=db, got %v", db.Config["auth"])
+	}
+
+	dotenv, err := cfg.GetProvider("dotenv")
+	if err != nil {
+		t.Fatalf("Failed to get provider: %v", err)
+	}
+	if _, ok := dotenv.Config["address"]; ok {
+		t.Error("vault defaults should not apply to other kinds")
+	}
+
+	t.Run("kind in defaults", func(t *testing.T) {
+		content := "provider_defaults:\n  vault:\n    kind: dotenv\nproviders: []\n"
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		_, err := config.Load(configFile)
+		if err == nil || !strings.Contains(err.Error(), "cannot set 'kind' or 'id'") {
+			t.Fatalf("expected error for kind in provider_defaults, got: %v", err)
+		}
+	})
+}
+
+// TestE2E_Config_Formats tests loading TOML and JSON config files
+func TestE2E_Config_Formats(t *testing.T) {
+	files := map[string]string{
+		".sstart.toml": `
+inherit = false
+
+[cache]
+enabled = true
+ttl = "10m"
+
+[[providers]]
+kind = "aws_secretsmanager"
+secret_id = "myapp/production"
+region = "us-east-1"
+
+[providers.keys]
+API_KEY = "=="
+`,
+		".sstart.json": `{
+  "inherit": false,
+  "cache": {"enabled": true, "ttl": "10m"},
+  "providers": [
+    {
+      "kind": "aws_secretsmanager",
+      "secret_id": "myapp/production",
+      "region": "us-east-1",
+      "keys": {"API_KEY": "=="}
+    }
+  ]
+}
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			configFile := filepath.Join(tmpDir, name)
+			if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			discovered, err := config.Discover(tmpDir)
+			if err != nil || discovered != configFile {
+				t.Fatalf("Discover() = %q, %v; want %q", discovered, err, configFile)
+			}
+
+			cfg, err := config.Load(configFile)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.Inherit {
+				t.Error("expected inherit=false")
+			}
+			if !cfg.IsCacheEnabled() || cfg.GetCacheTTL() != 10*time.Minute {
+				t.Errorf("expected cache enabled with TTL 10m, got enabled=%v ttl=%v", cfg.IsCacheEnabled(), cfg.GetCacheTTL())
+			}
+			if len(cfg.Providers) != 1 {
+				t.Fatalf("expected 1 provider, got %d", len(cfg.Providers))
+			}
+			p := cfg.Providers[0]
+			if p.ID != "aws_secretsmanager" || p.Config["secret_id"] != "myapp/production" || p.Config["region"] != "us-east-1" {
+				t.Errorf("unexpected provider config: %+v", p)
+			}
+			if p.Keys["API_KEY"] != "==" {
+				t.Errorf("expected key mapping API_KEY: ==, got %v", p.Keys)
+			}
+		})
+	}
+
+	t.Run("invalid TOML", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), ".sstart.toml")
+		if err := os.WriteFile(configFile, []byte("providers = [\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		_, err := config.Load(configFile)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse TOML") {
+			t.Fatalf("expected TOML parse error, got: %v", err)
+		}
+	})
+}
+
+// TestE2E_Config_GetSet tests reading and editing config values while preserving comments
+func TestE2E_Config_GetSet(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `# Project config
+providers:
+  # Production secrets
+  - kind: aws_secretsmanager
+    secret_id: myapp/production # shared with staging
+    region: us-east-1
+mcp:
+  servers:
+    - id: postgres
+      command: npx
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	steps := map[string]string{
+		"providers[0].region": "us-west-2",
+		"cache.enabled":       "true",
+		"providers[1]":        "{kind: dotenv, path: .env.local}",
+	}
+	for _, path := range []string{"providers[0].region", "cache.enabled", "providers[1]"} {
+		if err := config.SetValue(configFile, path, steps[path]); err != nil {
+			t.Fatalf("SetValue(%s) failed: %v", path, err)
+		}
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, comment := range []string{"# Project config", "# Production secrets", "# shared with staging"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("expected comment %q to be preserved, got:\n%s", comment, data)
+		}
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load edited config: %v", err)
+	}
+	if cfg.Providers[0].Config["region"] != "us-west-2" {
+		t.Errorf("expected region us-west-2, got %v", cfg.Providers[0].Config["region"])
+	}
+	if !cfg.IsCacheEnabled() {
+		t.Error("expected cache to be enabled")
+	}
+	if len(cfg.Providers) != 2 || cfg.Providers[1].Kind != "dotenv" {
+		t.Errorf("expected appended dotenv provider, got %+v", cfg.Providers)
+	}
+
+	value, err := config.GetValue(configFile, "providers[0].region")
+	if err != nil || value != "us-west-2" {
+		t.Errorf("GetValue(providers[0].region) = %q, %v", value, err)
+	}
+	value, err = config.GetValue(configFile, "mcp.servers")
+	if err != nil || value != "- id: postgres\n  command: npx" {
+		t.Errorf("GetValue(mcp.servers) = %q, %v", value, err)
+	}
+
+	errorCases := map[string]func() error{
+		"get missing key": func() error {
+			_, err := config.GetValue(configFile, "sso.oidc")
+			return err
+		},
+		"set index out of range": func() error {
+			return config.SetValue(configFile, "providers[5].region", "x")
+		},
+		"set key on a list": func() error {
+			return config.SetValue(configFile, "providers.region", "x")
+		},
+		"invalid path": func() error {
+			return config.SetValue(configFile, "providers[x]", "x")
+		},
+	}
+	for name, fn := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if err := fn(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestE2E_Config_TemplateFunctions tests config template functions and filters across config sections
+func TestE2E_Config_TemplateFunctions(t *testing.T) {
+	t.Setenv("SSTART_TEST_STAGE", "staging")
+	t.Setenv("SSTART_TEST_EMPTY", "")
+
+	tmpDir := t.TempDir()
+	regionFile := filepath.Join(tmpDir, "region")
+	if err := os.WriteFile(regionFile, []byte("eu-west-1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write region file: %v", err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("home directory not available: %v", err)
+	}
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `
+sso:
+  oidc:
+    clientId: '{{ get_env(name="SSTART_TEST_EMPTY") | default(value="sstart-cli") }}'
+    issuer: https://auth.example.com/{{ get_env(name="SSTART_TEST_STAGE") }}
+    scopes: [openid]
+
+providers:
+  - kind: aws_secretsmanager
+    secret_id: myapp/{{ get_env(name="SSTART_TEST_STAGE") | upper }}
+    region: '{{ file(path="` + regionFile + `") }}'
+    endpoint: '{{ exec(command="echo http://localhost:4566") }}'
+  - kind: template
+    uses: [aws_secretsmanager]
+    templates:
+      DSN: '{{ .aws_secretsmanager.USER }}@{{ homedir() }}'
+
+mcp:
+  servers:
+    - id: filesystem
+      command: npx
+      args: ["server-filesystem", "{{ homedir() }}/projects"]
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	aws, err := config.Expand(cfg.Providers[0].Config)
+	if err != nil {
+		t.Fatalf("Failed to expand provider config: %v", err)
+	}
+	if aws["secret_id"] != "myapp/STAGING" {
+		t.Errorf("expected secret_id 'myapp/STAGING', got %v", aws["secret_id"])
+	}
+	if aws["region"] != "eu-west-1" {
+		t.Errorf("expected region from file 'eu-west-1', got %v", aws["region"])
+	}
+	if aws["endpoint"] != "http://localhost:4566" {
+		t.Errorf("expected endpoint from exec, got %v", aws["endpoint"])
+	}
+
+	tmpl, err := config.Expand(cfg.Providers[1].Config)
+	if err != nil {
+		t.Fatalf("Failed to expand template provider config: %v", err)
+	}
+	templates := tmpl["templates"].(map[string]interface{})
+	if want := "{{ .aws_secretsmanager.USER }}@" + home; templates["DSN"] != want {
+		t.Errorf("expected Go template to be kept with homedir expanded, got %v", templates["DSN"])
+	}
+
+	sso, err := config.Expand(cfg.SSO)
+	if err != nil {
+		t.Fatalf("Failed to expand SSO config: %v", err)
+	}
+	if sso.OIDC.ClientID != "sstart-cli" || sso.OIDC.Issuer != "https://auth.example.com/staging" {
+		t.Errorf("unexpected SSO config: clientId=%s issuer=%s", sso.OIDC.ClientID, sso.OIDC.Issuer)
+	}
+
+	servers, err := config.Expand(cfg.MCP.Servers)
+	if err != nil {
+		t.Fatalf("Failed to expand MCP servers: %v", err)
+	}
+	if servers[0].Args[1] != home+"/projects" {
+		t.Errorf("expected MCP arg with home directory, got %v", servers[0].Args[1])
+	}
+
+	// Expansion returns a copy; the loaded config keeps the templates
+	if cfg.MCP.Servers[0].Args[1] != "{{ homedir() }}/projects" {
+		t.Errorf("expected loaded config to be unchanged, got %v", cfg.MCP.Servers[0].Args[1])
+	}
+
+	for name, value := range map[string]string{
+		"missing file":        `{{ file(path="` + filepath.Join(tmpDir, "missing") + `") }}`,
+		"failing command":     `{{ exec(command="exit 3") }}`,
+		"missing argument":    `{{ get_env() }}`,
+		"unknown filter":      `{{ homedir() | reverse }}`,
+		"unterminated string": `{{ get_env(name="X) }}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := config.Expand(map[string]interface{}{"path": value}); err == nil {
+				t.Errorf("expected an error expanding %s", value)
+			}
+		})
+	}
+}
+
+// TestE2E_Config_TemplateExecTrust tests that exec() templates of a project config file only
+// run once the file is allowed
+func TestE2E_Config_TemplateExecTrust(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	envFile := filepath.Join(tmpDir, "app.env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=app-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `
+providers:
+  - kind: dotenv
+    path: '{{ exec(command="echo ` + envFile + `") }}'
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if _, err := secrets.NewCollector(cfg).Collect(context.Background(), nil); !errors.Is(err, config.ErrUntrusted) {
+		t.Fatalf("Expected exec() of a config file that is not allowed to fail, got %v", err)
+	}
+
+	if err := config.Trust(configFile); err != nil {
+		t.Fatalf("Failed to allow config file: %v", err)
+	}
+	collected, err := secrets.NewCollector(cfg).Collect(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to collect secrets: %v", err)
+	}
+	if collected["API_KEY"] != "app-secret" {
+		t.Errorf("Expected API_KEY from the file named by exec(), got %q", collected["API_KEY"])
+	}
+}
+
+// TestE2E_Config_Profiles tests merging a named profile over the config
+func TestE2E_Config_Profiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	yamlContent := `
+cache:
+  ttl: 1m
+providers:
+  - kind: aws_secretsmanager
+    id: app
+    secret_id: myapp/dev
+  - kind: dotenv
+    path: .env.ci
+    enabled: '{{ eq .Profile "ci" }}'
+
+profiles:
+  prod:
+    cache:
+      enabled: true
+    providers:
+      - kind: aws_secretsmanager
+        id: app
+        secret_id: myapp/prod
+  ci: {}
+`
+	if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	t.Run("no profile", func(t *testing.T) {
+		cfg, err := config.Load(configFile)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Profile != "" || len(cfg.Providers) != 1 || cfg.Providers[0].Config["secret_id"] != "myapp/dev" {
+			t.Errorf("expected the base config, got profile=%q providers=%+v", cfg.Profile, cfg.Providers)
+		}
+	})
+
+	t.Run("prod profile", func(t *testing.T) {
+		cfg, err := config.Load(configFile, config.WithProfile("prod"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Profile != "prod" {
+			t.Errorf("expected active profile 'prod', got %q", cfg.Profile)
+		}
+		if len(cfg.Providers) != 1 || cfg.Providers[0].Config["secret_id"] != "myapp/prod" {
+			t.Errorf("expected the prod secret_id, got %+v", cfg.Providers)
+		}
+		if !cfg.IsCacheEnabled() || cfg.GetCacheTTL() != time.Minute {
+			t.Errorf("expected cache enabled with TTL 1m, got enabled=%v ttl=%v", cfg.IsCacheEnabled(), cfg.GetCacheTTL())
+		}
+	})
+
+	t.Run("profile in enabled condition", func(t *testing.T) {
+		cfg, err := config.Load(configFile, config.WithProfile("ci"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if _, err := cfg.GetProvider("dotenv"); err != nil {
+			t.Errorf("expected dotenv provider to be enabled in the ci profile: %v", err)
+		}
+	})
+
+	t.Run("undefined profile", func(t *testing.T) {
+		_, err := config.Load(configFile, config.WithProfile("staging"))
+		if err == nil || !strings.Contains(err.Error(), "profile 'staging' is not defined") {
+			t.Fatalf("expected undefined profile error, got: %v", err)
+		}
+	})
+}
+
 // TestE2E_Config_ProviderSpecificFields tests that provider-specific fields are properly isolated
 func TestE2E_Config_ProviderSpecificFields(t *testing.T) {
 	yamlContent := `
@@ -814,6 +1571,119 @@ sso:
 				}
 			},
 		},
+		{
+			name: "SSO config with callback host and ports",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+    callbackHost: 127.0.0.1
+    callbackPorts: [8250, 8251, 8252]
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				if cfg.SSO.OIDC.CallbackHost != "127.0.0.1" {
+					t.Errorf("expected CallbackHost='127.0.0.1', got '%s'", cfg.SSO.OIDC.CallbackHost)
+				}
+				if len(cfg.SSO.OIDC.CallbackPorts) != 3 || cfg.SSO.OIDC.CallbackPorts[0] != 8250 || cfg.SSO.OIDC.CallbackPorts[2] != 8252 {
+					t.Errorf("expected CallbackPorts=[8250 8251 8252], got %v", cfg.SSO.OIDC.CallbackPorts)
+				}
+			},
+		},
+		{
+			name: "SSO config with invalid callback port",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+    callbackPorts: [70000]
+`,
+			expectError:   true,
+			errorContains: "sso.oidc.callbackPorts[0] must be between 1 and 65535",
+		},
+		{
+			name: "SSO config with token storage",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+  tokenStorage:
+    backend: file
+    keyringService: sstart-ci
+    path: /tmp/sstart/tokens.json
+    encrypt: true
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				ts := cfg.SSO.TokenStorage
+				if ts == nil {
+					t.Fatal("expected TokenStorage to be set")
+				}
+				if ts.Backend != "file" || ts.KeyringService != "sstart-ci" || ts.Path != "/tmp/sstart/tokens.json" || !ts.Encrypt {
+					t.Errorf("unexpected TokenStorage: %+v", ts)
+				}
+			},
+		},
+		{
+			name: "SSO config with invalid token storage backend",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+  tokenStorage:
+    backend: vault
+`,
+			expectError:   true,
+			errorContains: "sso.tokenStorage.backend must be one of",
+		},
+		{
+			name: "SSO config with authParams",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+    authParams:
+      audience: https://api.example.com
+      prompt: login
+`,
+			expectError: false,
+			validateFunc: func(t *testing.T, cfg *config.Config) {
+				params := cfg.SSO.OIDC.AuthParams
+				if params["audience"] != "https://api.example.com" || params["prompt"] != "login" {
+					t.Errorf("unexpected AuthParams: %v", params)
+				}
+			},
+		},
+		{
+			name: "SSO config with reserved authParams",
+			yamlContent: `
+sso:
+  oidc:
+    clientId: my-sso-client-id
+    issuer: https://example.com/oidc
+    scopes:
+      - openid
+    authParams:
+      redirect_uri: http://evil.example.com
+`,
+			expectError:   true,
+			errorContains: "sso.oidc.authParams.redirect_uri is managed by sstart",
+		},
 		{
 			name: "SSO config with responseMode",
 			yamlContent: `
@@ -946,3 +1816,378 @@ sso:
 		})
 	}
 }
+
+// TestE2E_Config_Policy tests that policy rules are validated on load and enforced by Collect
+func TestE2E_Config_Policy(t *testing.T) {
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	envContent := "AWS_SECRET_ACCESS_KEY=aws-secret\nSTRIPE_KEY=sk_test\nCERT=" + strings.Repeat("x", 20) + "\nAPP_NAME=demo\n"
+	if err := os.WriteFile(envFile, []byte(envContent), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+
+	writeConfig := func(t *testing.T, policy string) string {
+		t.Helper()
+		configFile := filepath.Join(t.TempDir(), ".sstart.yml")
+		yamlContent := `
+providers:
+  - kind: dotenv
+    id: local
+    path: ` + envFile + `
+profiles:
+  dev: {}
+  prod: {}
+policy:
+` + policy
+		if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return configFile
+	}
+
+	collect := func(t *testing.T, configFile, profile string) error {
+		t.Helper()
+		cfg, err := config.Load(configFile, config.WithProfile(profile))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		_, err = secrets.NewCollector(cfg).Collect(context.Background(), nil)
+		return err
+	}
+
+	tests := []struct {
+		name       string
+		policy     string
+		profile    string
+		violations []secrets.PolicyViolation
+	}{
+		{
+			name: "deny in dev profile",
+			policy: `  rules:
+    - name: no-aws-keys-in-dev
+      keys: [AWS_SECRET_ACCESS_KEY]
+      profiles: [dev]
+      deny: true
+`,
+			profile: "dev",
+			violations: []secrets.PolicyViolation{
+				{Rule: "no-aws-keys-in-dev", Key: "AWS_SECRET_ACCESS_KEY", Provider: "local", Reason: "denied in profile 'dev'"},
+			},
+		},
+		{
+			name: "deny does not apply to other profiles",
+			policy: `  rules:
+    - name: no-aws-keys-in-dev
+      keys: [AWS_SECRET_ACCESS_KEY]
+      profiles: [dev]
+      deny: true
+`,
+			profile: "prod",
+		},
+		{
+			name: "max length",
+			policy: `  rules:
+    - name: bounded-values
+      max_length: 10
+`,
+			violations: []secrets.PolicyViolation{
+				{Rule: "bounded-values", Key: "CERT", Provider: "local", Reason: "value is 20 characters long, more than the maximum of 10"},
+			},
+		},
+		{
+			name: "required providers",
+			policy: `  rules:
+    - name: stripe-from-vault
+      keys: ["STRIPE_*"]
+      providers: ["vault-*"]
+`,
+			violations: []secrets.PolicyViolation{
+				{Rule: "stripe-from-vault", Key: "STRIPE_KEY", Provider: "local", Reason: "must come from one of: vault-*"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := collect(t, writeConfig(t, tt.policy), tt.profile)
+			if len(tt.violations) == 0 {
+				if err != nil {
+					t.Fatalf("expected no policy violations, got: %v", err)
+				}
+				return
+			}
+			var policyErr *secrets.PolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("expected a PolicyError, got: %v", err)
+			}
+			if len(policyErr.Violations) != len(tt.violations) {
+				t.Fatalf("expected %d violations, got %+v", len(tt.violations), policyErr.Violations)
+			}
+			for i, want := range tt.violations {
+				if policyErr.Violations[i] != want {
+					t.Errorf("violation %d: expected %+v, got %+v", i, want, policyErr.Violations[i])
+				}
+			}
+			if !strings.Contains(err.Error(), "policy check failed") {
+				t.Errorf("expected a policy report, got: %v", err)
+			}
+		})
+	}
+
+	t.Run("rule without restrictions", func(t *testing.T) {
+		_, err := config.Load(writeConfig(t, "  rules:\n    - name: noop\n      keys: [\"*\"]\n"))
+		if err == nil || !strings.Contains(err.Error(), "must set at least one of") {
+			t.Fatalf("expected a validation error, got: %v", err)
+		}
+	})
+}
+
+// TestE2E_Config_Limits tests that limits on the number and size of secrets are validated on
+// load and enforced by Collect
+func TestE2E_Config_Limits(t *testing.T) {
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	envContent := "BLOB=" + strings.Repeat("x", 3000) + "\nAPP_NAME=demo\nDEBUG=1\n"
+	if err := os.WriteFile(envFile, []byte(envContent), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+
+	writeConfig := func(t *testing.T, limits string) string {
+		t.Helper()
+		configFile := filepath.Join(t.TempDir(), ".sstart.yml")
+		yamlContent := `
+providers:
+  - kind: dotenv
+    id: local
+    path: ` + envFile + `
+limits:
+` + limits
+		if err := os.WriteFile(configFile, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return configFile
+	}
+
+	tests := []struct {
+		name       string
+		limits     string
+		violations []secrets.LimitViolation
+	}{
+		{
+			name:   "within limits",
+			limits: "  max_keys: 3\n  max_value_size: 4096\n  max_total_size: 8192\n",
+		},
+		{
+			name:   "value size",
+			limits: "  max_value_size: 1024\n",
+			violations: []secrets.LimitViolation{
+				{Limit: "max_value_size", Key: "BLOB", Provider: "local", Reason: "value is 2.9 KiB, more than the maximum of 1.0 KiB"},
+			},
+		},
+		{
+			name:   "key count and total size",
+			limits: "  max_keys: 2\n  max_total_size: 2048\n",
+			violations: []secrets.LimitViolation{
+				{Limit: "max_keys", Reason: "3 keys, more than the maximum of 2"},
+				{Limit: "max_total_size", Reason: "secrets take 3.0 KiB, more than the maximum of 2.0 KiB"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Load(writeConfig(t, tt.limits))
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			_, err = secrets.NewCollector(cfg).Collect(context.Background(), nil)
+			if len(tt.violations) == 0 {
+				if err != nil {
+					t.Fatalf("expected no limit violations, got: %v", err)
+				}
+				return
+			}
+			var limitErr *secrets.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected a LimitError, got: %v", err)
+			}
+			if len(limitErr.Violations) != len(tt.violations) {
+				t.Fatalf("expected %d violations, got %+v", len(tt.violations), limitErr.Violations)
+			}
+			for i, want := range tt.violations {
+				if limitErr.Violations[i] != want {
+					t.Errorf("violation %d: expected %+v, got %+v", i, want, limitErr.Violations[i])
+				}
+			}
+		})
+	}
+
+	t.Run("warn only", func(t *testing.T) {
+		cfg, err := config.Load(writeConfig(t, "  max_value_size: 1024\n  action: warn\n"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, nil))
+		collected, err := secrets.NewCollector(cfg, secrets.WithLogger(logger)).Collect(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("expected only a warning, got: %v", err)
+		}
+		if len(collected["BLOB"]) != 3000 {
+			t.Errorf("expected BLOB to be injected")
+		}
+		if !strings.Contains(logs.String(), "secrets exceed a limit") || !strings.Contains(logs.String(), "key=BLOB") {
+			t.Errorf("expected a warning about BLOB, got: %s", logs.String())
+		}
+	})
+
+	t.Run("invalid action", func(t *testing.T) {
+		_, err := config.Load(writeConfig(t, "  action: ignore\n"))
+		if err == nil || !strings.Contains(err.Error(), "limits.action must be") {
+			t.Fatalf("expected a validation error, got: %v", err)
+		}
+	})
+}
+
+// TestE2E_Config_Signing tests that a config loaded with a verify key must match its signature
+func TestE2E_Config_Signing(t *testing.T) {
+	tmpDir := t.TempDir()
+	publicKey := filepath.Join(tmpDir, "keys", "sign.pub")
+	secretKey := filepath.Join(tmpDir, "keys", "sign.key")
+	if err := config.GenerateKeyPair(publicKey, secretKey); err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	if info, err := os.Stat(secretKey); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the secret key to be written with 0600 permissions, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if err := config.GenerateKeyPair(publicKey, secretKey); err == nil {
+		t.Error("expected keygen to refuse to overwrite existing keys")
+	}
+
+	includeFile := filepath.Join(tmpDir, "shared.yml")
+	if err := os.WriteFile(includeFile, []byte("providers:\n  - kind: dotenv\n    id: shared\n    path: .env.shared\n"), 0644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := "include: shared.yml\nproviders:\n  - kind: dotenv\n    id: local\n    path: .env\n"
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	load := func() error {
+		_, err := config.Load(configFile, config.WithVerifyKey(publicKey))
+		return err
+	}
+
+	if err := load(); !errors.Is(err, config.ErrUnsigned) {
+		t.Fatalf("expected ErrUnsigned for an unsigned config, got %v", err)
+	}
+
+	if err := config.SignConfig(configFile, secretKey); err != nil {
+		t.Fatalf("Failed to sign config: %v", err)
+	}
+	if err := load(); err != nil {
+		t.Fatalf("expected the signed config to load, got %v", err)
+	}
+
+	// An attacker-added provider in an included file invalidates the signature
+	tampered := "providers:\n  - kind: dotenv\n    id: shared\n    path: .env.shared\n  - kind: template\n    id: exfil\n    templates:\n      X: '{{ .shared.TOKEN }}'\n"
+	if err := os.WriteFile(includeFile, []byte(tampered), 0644); err != nil {
+		t.Fatalf("Failed to write include file: %v", err)
+	}
+	if err := load(); err == nil || !strings.Contains(err.Error(), "does not match its signature") {
+		t.Fatalf("expected a modified include to fail verification, got %v", err)
+	}
+
+	// A signature made with another key is rejected
+	if err := config.SignConfig(configFile, secretKey); err != nil {
+		t.Fatalf("Failed to sign config: %v", err)
+	}
+	otherPublic := filepath.Join(tmpDir, "other.pub")
+	if err := config.GenerateKeyPair(otherPublic, filepath.Join(tmpDir, "other.key")); err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	if _, err := config.Load(configFile, config.WithVerifyKey(otherPublic)); err == nil {
+		t.Fatal("expected verification with another public key to fail")
+	}
+
+	// Without a verify key, signatures are not checked
+	if err := os.WriteFile(configFile, []byte(configYAML+"inherit: false\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := config.Load(configFile); err != nil {
+		t.Fatalf("expected config to load without verification, got %v", err)
+	}
+}
+
+// sessionProvider is a test provider that authenticates once per instance, like the Vault and AWS providers
+type sessionProvider struct {
+	session int
+}
+
+var (
+	sessionLogins  int
+	sessionExpired bool
+)
+
+func (p *sessionProvider) Name() string { return "test_session" }
+
+func (p *sessionProvider) Fetch(secretContext provider.SecretContext, mapID string, cfg map[string]interface{}, keys map[string]string) ([]provider.KeyValue, error) {
+	if p.session == 0 {
+		sessionLogins++
+		p.session = sessionLogins
+	} else if sessionExpired {
+		sessionExpired = false
+		return nil, errors.New("session expired")
+	}
+	return []provider.KeyValue{{Key: "VALUE", Value: cfg["value"].(string)}}, nil
+}
+
+// TestE2E_Config_ProviderClientReuse tests that a collector reuses provider instances, and their
+// authenticated clients, across collections
+func TestE2E_Config_ProviderClientReuse(t *testing.T) {
+	provider.Register("test_session", func() provider.Provider { return &sessionProvider{} })
+
+	configFile := filepath.Join(t.TempDir(), ".sstart.yml")
+	if err := os.WriteFile(configFile, []byte("providers:\n  - kind: test_session\n    value: one\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	collector := secrets.NewCollector(cfg)
+	collect := func() string {
+		t.Helper()
+		collected, err := collector.Collect(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		return collected["VALUE"]
+	}
+
+	collect()
+	collect()
+	if sessionLogins != 1 {
+		t.Errorf("expected the provider to log in once across collections, got %d logins", sessionLogins)
+	}
+
+	// An expired session is replaced transparently
+	sessionExpired = true
+	if value := collect(); value != "one" {
+		t.Errorf("expected 'one' after re-login, got %q", value)
+	}
+	if sessionLogins != 2 {
+		t.Errorf("expected a new login after the session expired, got %d logins", sessionLogins)
+	}
+
+	// A changed provider config (e.g. a refreshed SSO token) gets a new instance
+	cfg.Providers[0].Config["value"] = "two"
+	if value := collect(); value != "two" {
+		t.Errorf("expected 'two', got %q", value)
+	}
+	if sessionLogins != 3 {
+		t.Errorf("expected a new login after the config changed, got %d logins", sessionLogins)
+	}
+}
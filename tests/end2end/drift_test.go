@@ -0,0 +1,102 @@
+package end2end
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/cache"
+	"github.com/dirathea/sstart/internal/config"
+	_ "github.com/dirathea/sstart/internal/provider/dotenv"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// TestE2E_Drift tests comparing cached secrets with the current provider values
+func TestE2E_Drift(t *testing.T) {
+	// Skip if keyring not available
+	testCache := cache.New()
+	if !testCache.IsAvailable() {
+		t.Skip("keyring not available, skipping drift test")
+	}
+	_ = testCache.Clear()
+	defer testCache.Clear()
+
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(envFile, []byte("API_KEY=initial-secret\nDB_PASS=initial-password\nOLD=gone-soon\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	configContent := `
+cache:
+  enabled: true
+  ttl: 1m
+
+providers:
+  - kind: dotenv
+    id: test-env
+    path: ` + envFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	ctx := context.Background()
+	collector := secrets.NewCollector(cfg)
+	if _, err := collector.Collect(ctx, nil); err != nil {
+		t.Fatalf("Failed to collect secrets: %v", err)
+	}
+
+	drifts, err := collector.Drift(ctx, nil)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(drifts) != 1 || !drifts[0].Cached || len(drifts[0].Changes) != 0 {
+		t.Fatalf("expected no drift right after caching, got %+v", drifts)
+	}
+
+	if err := os.WriteFile(envFile, []byte("API_KEY=rotated-secret\nDB_PASS=initial-password\nNEW=added\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		drifts, err = collector.Drift(ctx, nil)
+		if err != nil {
+			t.Fatalf("Drift failed: %v", err)
+		}
+		changes := drifts[0].Changes
+		if len(changes) != 3 ||
+			changes[0] != (secrets.KeyChange{Key: "API_KEY", Change: secrets.DriftChanged, Old: "initial-secret", New: "rotated-secret"}) ||
+			changes[1] != (secrets.KeyChange{Key: "NEW", Change: secrets.DriftAdded, New: "added"}) ||
+			changes[2] != (secrets.KeyChange{Key: "OLD", Change: secrets.DriftRemoved, Old: "gone-soon"}) {
+			t.Fatalf("unexpected changes (run %d): %+v", i+1, changes)
+		}
+	}
+
+	// The cache still holds the values the last run used
+	collected, err := collector.Collect(ctx, nil)
+	if err != nil {
+		t.Fatalf("Failed to collect secrets: %v", err)
+	}
+	if collected["API_KEY"] != "initial-secret" {
+		t.Errorf("expected drift to leave the cache untouched, got API_KEY=%s", collected["API_KEY"])
+	}
+}
+
+// TestE2E_Drift_CacheDisabled tests that drift requires caching
+func TestE2E_Drift_CacheDisabled(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\n")
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "drift").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "enable 'cache' in the config") {
+		t.Errorf("expected drift to require caching, got %v: %s", err, output)
+	}
+}
@@ -0,0 +1,613 @@
+package end2end
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// writeEnvTestConfig writes a .env file with the given content and a config using it, and returns the config path
+func writeEnvTestConfig(t *testing.T, tmpDir, envContent string) string {
+	t.Helper()
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte(envContent), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := "providers:\n  - kind: dotenv\n    path: " + envFile + "\n"
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configFile
+}
+
+// TestE2E_Env_SortedOutput tests that env output is sorted by key in every format
+func TestE2E_Env_SortedOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "ZETA=1\nALPHA=2\nMIKE=3\nBRAVO=4\n")
+
+	expected := map[string]string{
+		"shell": "export ALPHA='2'\nexport BRAVO='4'\nexport MIKE='3'\nexport ZETA='1'\n",
+		"yaml":  "ALPHA: 2\nBRAVO: 4\nMIKE: 3\nZETA: 1\n",
+		"json":  "{\n  \"ALPHA\": \"2\",\n  \"BRAVO\": \"4\",\n  \"MIKE\": \"3\",\n  \"ZETA\": \"1\"\n}\n",
+	}
+
+	for format, want := range expected {
+		t.Run(format, func(t *testing.T) {
+			// Run several times to catch map iteration order leaking into the output
+			for i := 0; i < 5; i++ {
+				cmd := exec.Command(binaryPath, "--config", configFile, "env", "--format", format)
+				output, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("sstart env failed: %v", err)
+				}
+				if string(output) != want {
+					t.Fatalf("unexpected %s output:\n%s\nwant:\n%s", format, output, want)
+				}
+			}
+		})
+	}
+}
+
+// TestE2E_Env_Masked tests that env --masked prints masked values in every format
+func TestE2E_Env_Masked(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=sk_live_1234567890\nPIN=1234\n")
+
+	expected := map[string]string{
+		"shell": "export API_KEY='sk****90'\nexport PIN='****'\n",
+		"yaml":  "API_KEY: \"sk****90\"\nPIN: \"****\"\n",
+		"json":  "{\n  \"API_KEY\": \"sk****90\",\n  \"PIN\": \"****\"\n}\n",
+	}
+
+	for format, want := range expected {
+		t.Run(format, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, "--config", configFile, "env", "--masked", "--format", format)
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("sstart env --masked failed: %v", err)
+			}
+			if string(output) != want {
+				t.Errorf("unexpected %s output:\n%s\nwant:\n%s", format, output, want)
+			}
+		})
+	}
+}
+
+// TestE2E_Env_KeyFilters tests that --only and --exclude select a subset of the secrets for env and run
+func TestE2E_Env_KeyFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "STRIPE_KEY=sk\nSTRIPE_WEBHOOK_SECRET=wh\nDB_URL=postgres\nDB_ADMIN_PASSWORD=admin\nOTHER=x\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "only",
+			args: []string{"--only", "STRIPE_*,DB_*"},
+			want: "export DB_ADMIN_PASSWORD='admin'\nexport DB_URL='postgres'\nexport STRIPE_KEY='sk'\nexport STRIPE_WEBHOOK_SECRET='wh'\n",
+		},
+		{
+			name: "exclude",
+			args: []string{"--exclude", "STRIPE_*"},
+			want: "export DB_ADMIN_PASSWORD='admin'\nexport DB_URL='postgres'\nexport OTHER='x'\n",
+		},
+		{
+			name: "only and exclude",
+			args: []string{"--only", "STRIPE_*,DB_*", "--exclude", "*_SECRET", "--exclude", "DB_ADMIN_*"},
+			want: "export DB_URL='postgres'\nexport STRIPE_KEY='sk'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--config", configFile, "env"}, tt.args...)
+			output, err := exec.Command(binaryPath, args...).Output()
+			if err != nil {
+				t.Fatalf("sstart env failed: %v", err)
+			}
+			if string(output) != tt.want {
+				t.Errorf("unexpected output:\n%s\nwant:\n%s", output, tt.want)
+			}
+		})
+	}
+
+	t.Run("run", func(t *testing.T) {
+		for _, subcommand := range [][]string{{"run"}, {}} {
+			args := append([]string{"--config", configFile}, subcommand...)
+			args = append(args, "--only", "DB_*", "--exclude", "DB_ADMIN_*", "--", "sh", "-c", "echo \"$DB_URL|$DB_ADMIN_PASSWORD|$STRIPE_KEY\"")
+			cmd := exec.Command(binaryPath, args...)
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("sstart %v failed: %v\n%s", subcommand, err, output)
+			}
+			if string(output) != "postgres||\n" {
+				t.Errorf("sstart %v: unexpected output %q", subcommand, output)
+			}
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "--config", configFile, "env", "--only", "[").CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected an error for an invalid pattern, got:\n%s", output)
+		}
+		if !strings.Contains(string(output), "invalid key pattern") {
+			t.Errorf("unexpected error output:\n%s", output)
+		}
+	})
+}
+
+// TestE2E_Env_Timings tests that --timings prints a per-phase summary on stderr without changing stdout
+func TestE2E_Env_Timings(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=value\n")
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "--timings", "env")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart env --timings failed: %v\n%s", err, stderr.String())
+	}
+	if string(output) != "export API_KEY='value'\n" {
+		t.Errorf("unexpected stdout: %q", output)
+	}
+
+	summary := stderr.String()
+	if !strings.HasPrefix(summary, "sstart timings:\n") {
+		t.Fatalf("expected a timing summary on stderr, got:\n%s", summary)
+	}
+	for _, want := range []string{"dotenv", "fetch", "total"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected timing summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+
+	// Without the flag, nothing is printed
+	cmd = exec.Command(binaryPath, "--config", configFile, "env")
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	if _, err := cmd.Output(); err != nil {
+		t.Fatalf("sstart env failed: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected no stderr output without --timings, got:\n%s", stderr.String())
+	}
+}
+
+// TestE2E_Env_Lint tests that lint warns about empty, placeholder and low entropy values on stderr
+func TestE2E_Env_Lint(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "EMPTY=\nDB_PASSWORD=changeme\nAPI_KEY=aaaaaaaaab\nSTRIPE_SECRET=sk_live_8fj2KdP0qLz9\nGREETING=hello\nSTAGE=pending\n")
+
+	run := func(t *testing.T, configFile string) string {
+		t.Helper()
+		cmd := exec.Command(binaryPath, "--config", configFile, "env")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if _, err := cmd.Output(); err != nil {
+			t.Fatalf("sstart env failed: %v\n%s", err, stderr.String())
+		}
+		return stderr.String()
+	}
+
+	if warnings := run(t, configFile); warnings != "" {
+		t.Fatalf("expected no warnings without lint enabled, got:\n%s", warnings)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	lintConfig := "lint:\n  enabled: true\n  placeholders: [pending]\n" + string(data)
+	if err := os.WriteFile(configFile, []byte(lintConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	warnings := run(t, configFile)
+	for _, want := range []string{
+		`key=EMPTY provider=dotenv reason="value is empty"`,
+		`key=DB_PASSWORD provider=dotenv reason="value looks like a placeholder`,
+		`key=STAGE provider=dotenv reason="value looks like a placeholder`,
+		`key=API_KEY provider=dotenv reason="value has low entropy`,
+	} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected a warning containing %q, got:\n%s", want, warnings)
+		}
+	}
+	for _, key := range []string{"STRIPE_SECRET", "GREETING"} {
+		if strings.Contains(warnings, "key="+key+" ") {
+			t.Errorf("expected no warning for %s, got:\n%s", key, warnings)
+		}
+	}
+}
+
+// TestE2E_Env_Launchd tests generating launchd property lists with embedded secrets or a wrapper invocation
+func TestE2E_Env_Launchd(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=a<b&c\nINTERNAL=hidden\n")
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile}, args...)...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("sstart %v failed: %v\n%s", args, err, output)
+		}
+		return string(output)
+	}
+
+	header := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.acme.app</string>
+	<key>ProgramArguments</key>
+	<array>
+`
+	want := header + `		<string>/usr/local/bin/app</string>
+		<string>--port=8080</string>
+	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>API_KEY</key>
+		<string>a&lt;b&amp;c</string>
+	</dict>
+</dict>
+</plist>
+`
+	if output := run(t, "export", "--format", "launchd", "--label", "com.acme.app", "--exclude", "INTERNAL", "--", "/usr/local/bin/app", "--port=8080"); output != want {
+		t.Errorf("unexpected plist:\n%s\nwant:\n%s", output, want)
+	}
+
+	want = header + `		<string>` + binaryPath + `</string>
+		<string>--config</string>
+		<string>` + configFile + `</string>
+		<string>--exclude</string>
+		<string>INTERNAL</string>
+		<string>run</string>
+		<string>--</string>
+		<string>/usr/local/bin/app</string>
+	</array>
+</dict>
+</plist>
+`
+	if output := run(t, "env", "--format", "launchd", "--label", "com.acme.app", "--wrap", "--exclude", "INTERNAL", "--", "/usr/local/bin/app"); output != want {
+		t.Errorf("unexpected wrapper plist:\n%s\nwant:\n%s", output, want)
+	}
+
+	if output, err := exec.Command(binaryPath, "--config", configFile, "env", "--format", "launchd").CombinedOutput(); err == nil || !strings.Contains(string(output), "--label is required") {
+		t.Errorf("expected --label to be required: %v\n%s", err, output)
+	}
+}
+
+// TestE2E_Env_Provider tests selecting and renaming host environment variables with the env provider
+func TestE2E_Env_Provider(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: env
+    id: host
+    vars: ["APP_*"]
+    strip_prefix: APP_
+    keys:
+      CI_JOB_TOKEN: JOB_TOKEN
+    hidden: [JOB_TOKEN]
+  - kind: template
+    uses: [host]
+    templates:
+      DATABASE_URL: postgres://{{.host.DB_USER}}@{{.host.DB_HOST}}/app?token={{.host.JOB_TOKEN}}
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "env")
+	cmd.Env = append(os.Environ(), "APP_DB_USER=alice", "APP_DB_HOST=db.internal", "CI_JOB_TOKEN=t0k", "OTHER=ignored")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export DATABASE_URL='postgres://alice@db.internal/app?token=t0k'\nexport DB_HOST='db.internal'\nexport DB_USER='alice'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+}
+
+// TestE2E_Env_KeyAliases tests injecting one secret under several names with a list in 'keys'
+func TestE2E_Env_KeyAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("DB_PASSWORD=p@ss\nAPI_TOKEN=tok\nUNUSED=x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: dotenv
+    path: ` + envFile + `
+    keys:
+      DB_PASSWORD: [PGPASSWORD, DATABASE_PASSWORD]
+      API_TOKEN: [==, GITHUB_TOKEN]
+    hidden: [DATABASE_PASSWORD]
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export API_TOKEN='tok'\nexport GITHUB_TOKEN='tok'\nexport PGPASSWORD='p@ss'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+
+	t.Run("empty list", func(t *testing.T) {
+		invalidConfig := filepath.Join(tmpDir, "invalid.yml")
+		invalidYAML := "providers:\n  - kind: dotenv\n    path: " + envFile + "\n    keys:\n      DB_PASSWORD: []\n"
+		if err := os.WriteFile(invalidConfig, []byte(invalidYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		output, err := exec.Command(binaryPath, "--config", invalidConfig, "env").CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "keys.DB_PASSWORD must not be an empty list") {
+			t.Errorf("expected an empty list error, got %v:\n%s", err, output)
+		}
+	})
+}
+
+// TestE2E_Env_Defaults tests that defaults fill in keys that no provider produced
+func TestE2E_Env_Defaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("LOG_LEVEL=debug\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `defaults:
+  LOG_LEVEL: info
+  PORT: 8080
+  REGION: us-east-1
+providers:
+  - kind: dotenv
+    id: local
+    path: ` + envFile + `
+    defaults:
+      REGION: eu-west-1
+      LOG_LEVEL: warn
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export LOG_LEVEL='debug'\nexport PORT='8080'\nexport REGION='eu-west-1'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+
+	output, err = exec.Command(binaryPath, "--config", configFile, "ls").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart ls failed: %v\n%s", err, output)
+	}
+	for _, line := range []string{"LOG_LEVEL  local", "PORT       defaults", "REGION     defaults:local"} {
+		if !strings.Contains(string(output), line) {
+			t.Errorf("expected sstart ls output to contain %q, got:\n%s", line, output)
+		}
+	}
+}
+
+// TestE2E_Env_KeyTransforms tests transforming values in the long form of keys mappings
+func TestE2E_Env_KeyTransforms(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: env
+    keys:
+      APP_DB_URL:
+        to: [DATABASE_URL, PG_URL]
+        transform: [trim, unquote]
+      APP_CERT:
+        transform: newlines
+      APP_PLAIN: ==
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "env", "--format", "json")
+	cmd.Env = append(os.Environ(), "APP_DB_URL=  \"postgres://db/app\"\n", "APP_CERT=line1\r\nline2\r", "APP_PLAIN= keep ")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(output, &got); err != nil {
+		t.Fatalf("Failed to parse output: %v\n%s", err, output)
+	}
+	want := map[string]string{
+		"DATABASE_URL": "postgres://db/app",
+		"PG_URL":       "postgres://db/app",
+		"APP_CERT":     "line1\nline2\n",
+		"APP_PLAIN":    " keep ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	t.Run("unknown transform", func(t *testing.T) {
+		invalidConfig := filepath.Join(tmpDir, "invalid.yml")
+		invalidYAML := "providers:\n  - kind: env\n    keys:\n      HOME:\n        transform: reverse\n"
+		if err := os.WriteFile(invalidConfig, []byte(invalidYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		output, err := exec.Command(binaryPath, "--config", invalidConfig, "env").CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "keys.HOME.transform: unknown transform 'reverse' (available: newlines, trim, unquote)") {
+			t.Errorf("expected an unknown transform error, got %v:\n%s", err, output)
+		}
+	})
+}
+
+// TestE2E_Env_Annotate tests that env --annotate writes the source provider and fetch time above each key
+func TestE2E_Env_Annotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	baseFile := filepath.Join(tmpDir, "base.env")
+	localFile := filepath.Join(tmpDir, "local.env")
+	if err := os.WriteFile(baseFile, []byte("API_URL=https://api.example.com\nDB_URL=postgres://base\n"), 0644); err != nil {
+		t.Fatalf("Failed to write base.env: %v", err)
+	}
+	if err := os.WriteFile(localFile, []byte("DB_URL=postgres://local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write local.env: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := "providers:\n  - kind: dotenv\n    id: base\n    path: " + baseFile + "\n  - kind: dotenv\n    id: local\n    path: " + localFile + "\n"
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	wantPattern := map[string]string{
+		"shell": `^# source: base, fetched \S+\nexport API_URL='https://api.example.com'\n# source: local, fetched \S+\nexport DB_URL='postgres://local'\n$`,
+		"yaml":  `^# source: base, fetched \S+\nAPI_URL: "https://api.example.com"\n# source: local, fetched \S+\nDB_URL: "postgres://local"\n$`,
+	}
+	for format, pattern := range wantPattern {
+		t.Run(format, func(t *testing.T) {
+			output, err := exec.Command(binaryPath, "--config", configFile, "env", "--annotate", "--format", format).Output()
+			if err != nil {
+				t.Fatalf("sstart env --annotate failed: %v", err)
+			}
+			if !regexp.MustCompile(pattern).Match(output) {
+				t.Errorf("unexpected %s output:\n%s\nwant match for:\n%s", format, output, pattern)
+			}
+		})
+	}
+
+	// Formats without comments are rejected
+	output, err := exec.Command(binaryPath, "--config", configFile, "env", "--annotate", "--format", "json").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "--annotate is only supported") {
+		t.Errorf("expected --annotate with json to fail, got err=%v output=%s", err, output)
+	}
+}
+
+// TestE2E_Env_Overrides tests that --set and --set-env inject values over the collected secrets for env and run
+func TestE2E_Env_Overrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=from-provider\nDB_URL=postgres\n")
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "env", "--only", "DB_*",
+		"--set", "API_KEY=from,set=flag", "--set-env", "TOKEN=SSTART_TEST_TOKEN")
+	cmd.Env = append(os.Environ(), "SSTART_TEST_TOKEN=from-env")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart env --set failed: %v", err)
+	}
+	want := "export API_KEY='from,set=flag'\nexport DB_URL='postgres'\nexport TOKEN='from-env'\n"
+	if string(output) != want {
+		t.Errorf("unexpected env output:\n%s\nwant:\n%s", output, want)
+	}
+
+	output, err = exec.Command(binaryPath, "--config", configFile, "env", "--annotate", "--set", "API_KEY=x").Output()
+	if err != nil {
+		t.Fatalf("sstart env --annotate --set failed: %v", err)
+	}
+	if !strings.Contains(string(output), "# source: override\nexport API_KEY='x'\n") {
+		t.Errorf("expected the override to be annotated, got:\n%s", output)
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "run", "--set", "API_KEY=overridden", "--", "sh", "-c", "echo $API_KEY $DB_URL")
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart run --set failed: %v", err)
+	}
+	if string(output) != "overridden postgres\n" {
+		t.Errorf("unexpected run output: %q", output)
+	}
+
+	for _, args := range [][]string{
+		{"--set", "NO_VALUE"},
+		{"--set-env", "TOKEN=SSTART_TEST_UNSET_VARIABLE"},
+	} {
+		output, err := exec.Command(binaryPath, append([]string{"--config", configFile, "env"}, args...)...).CombinedOutput()
+		if err == nil {
+			t.Errorf("expected %v to fail, got:\n%s", args, output)
+		}
+	}
+}
+
+// TestE2E_Env_EncryptTo tests that --encrypt-to writes the output armored and encrypted for
+// each age recipient
+func TestE2E_Env_EncryptTo(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\nDB_PASSWORD=p@ss\n")
+
+	alice, _ := age.GenerateX25519Identity()
+	bob, _ := age.GenerateX25519Identity()
+
+	expected := map[string]string{
+		"shell": "export API_KEY='abc'\nexport DB_PASSWORD='p@ss'\n",
+		"json":  "{\n  \"API_KEY\": \"abc\",\n  \"DB_PASSWORD\": \"p@ss\"\n}\n",
+	}
+	for format, want := range expected {
+		t.Run(format, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, "--config", configFile, "env", "--format", format,
+				"--encrypt-to", alice.Recipient().String(), "--encrypt-to", bob.Recipient().String())
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("sstart env --encrypt-to failed: %v", err)
+			}
+			if !bytes.HasPrefix(output, []byte(armor.Header)) || bytes.Contains(output, []byte("p@ss")) {
+				t.Fatalf("expected armored ciphertext, got:\n%s", output)
+			}
+			for _, identity := range []*age.X25519Identity{alice, bob} {
+				r, err := age.Decrypt(armor.NewReader(bytes.NewReader(output)), identity)
+				if err != nil {
+					t.Fatalf("Failed to decrypt output: %v", err)
+				}
+				var plaintext bytes.Buffer
+				if _, err := plaintext.ReadFrom(r); err != nil {
+					t.Fatalf("Failed to decrypt output: %v", err)
+				}
+				if plaintext.String() != want {
+					t.Errorf("unexpected %s output:\n%s\nwant:\n%s", format, plaintext.String(), want)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid recipient", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "--config", configFile, "env", "--encrypt-to", "age1invalid").CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "invalid age recipient 'age1invalid'") {
+			t.Errorf("expected an invalid recipient error, got %q, %v", output, err)
+		}
+	})
+}
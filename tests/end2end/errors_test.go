@@ -0,0 +1,101 @@
+package end2end
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_ErrorOutput tests the exit codes of each failure type and their --output json reports
+func TestE2E_ErrorOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\n")
+
+	missingEnvConfig := filepath.Join(tmpDir, "missing-env.yml")
+	if err := os.WriteFile(missingEnvConfig, []byte("providers:\n  - kind: dotenv\n    id: local\n    path: "+filepath.Join(tmpDir, "missing.env")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exitCode int
+		errType  string
+		provider string
+		class    string
+	}{
+		{"usage", []string{"--config", configFile, "show", "--bogus"}, 2, "usage", "", ""},
+		{"config", []string{"--config", filepath.Join(tmpDir, "missing.yml"), "show"}, 3, "config", "", ""},
+		{"provider", []string{"--config", missingEnvConfig, "show"}, 5, "provider", "local", "not_found"},
+		{"command", []string{"--config", configFile, "run", "--", "sh", "-c", "exit 7"}, 7, "command", "", ""},
+		{"signal", []string{"--config", configFile, "run", "--", "sh", "-c", "kill -TERM $$"}, 143, "command", "", ""},
+		{"command with offset", []string{"--exit-code-offset", "100", "--config", configFile, "run", "--", "sh", "-c", "exit 7"}, 107, "command", "", ""},
+		{"command above 255", []string{"--exit-code-offset", "100", "--config", configFile, "run", "--", "sh", "-c", "exit 200"}, 255, "command", "", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, append([]string{"--output", "json"}, tc.args...)...)
+			_, err := cmd.Output()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("expected sstart to fail, got: %v", err)
+			}
+			stderr := exitErr.Stderr
+			if code := exitErr.ExitCode(); code != tc.exitCode {
+				t.Errorf("exit code = %d, want %d\nStderr: %s", code, tc.exitCode, stderr)
+			}
+
+			var report struct {
+				Error struct {
+					Type     string `json:"type"`
+					Message  string `json:"message"`
+					ExitCode int    `json:"exit_code"`
+					Provider string `json:"provider"`
+					Class    string `json:"class"`
+					Hint     string `json:"hint"`
+					// CommandExitCode is the command's own exit code, set for command failures
+					CommandExitCode *int `json:"command_exit_code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(stderr, &report); err != nil {
+				t.Fatalf("expected a JSON error report on stderr, got %q: %v", stderr, err)
+			}
+			if report.Error.Type != tc.errType || report.Error.ExitCode != tc.exitCode || report.Error.Provider != tc.provider || report.Error.Class != tc.class || report.Error.Message == "" {
+				t.Errorf("unexpected error report: %+v", report.Error)
+			}
+			if (tc.errType == "command") != (report.Error.CommandExitCode != nil) {
+				t.Errorf("expected command_exit_code only for command failures, got %v", report.Error.CommandExitCode)
+			}
+		})
+	}
+
+	t.Run("text", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", filepath.Join(tmpDir, "missing.yml"), "show")
+		output, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("expected exit code 3, got: %v", err)
+		}
+		if !strings.HasPrefix(string(output), "Error: failed to load config: ") {
+			t.Errorf("expected a text error report, got %q", output)
+		}
+	})
+
+	t.Run("hint", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", missingEnvConfig, "show")
+		output, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+			t.Fatalf("expected exit code 5, got: %v", err)
+		}
+		if !strings.Contains(string(output), "\nHint: the secret of provider 'local' does not exist") {
+			t.Errorf("expected a hint for the missing secret, got %q", output)
+		}
+	})
+}
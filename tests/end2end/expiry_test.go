@@ -0,0 +1,98 @@
+package end2end
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeVaultLease serves a Vault dynamic secret at database/creds/app with a lease of the given
+// duration, issuing a new API_KEY (v1, v2, ...) on every read
+func newFakeVaultLease(t *testing.T, leaseSeconds int) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/database/creds/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		reads++
+		n := reads
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/app/lease%d", n),
+			"lease_duration": leaseSeconds,
+			"renewable":      true,
+			"data":           map[string]interface{}{"API_KEY": fmt.Sprintf("v%d", n)},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestE2E_SecretExpiry tests injecting when leased secrets expire, and refreshing them before
+func TestE2E_SecretExpiry(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	writeConfig := func(t *testing.T, address string) string {
+		t.Helper()
+		configFile := filepath.Join(t.TempDir(), ".sstart.yml")
+		configYAML := fmt.Sprintf(`
+providers:
+  - kind: vault
+    id: db-creds
+    address: %s
+    token: test-token
+    mount: database
+    path: creds/app
+`, address)
+		if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return configFile
+	}
+
+	t.Run("companion variable", func(t *testing.T) {
+		configFile := writeConfig(t, newFakeVaultLease(t, 3600).URL)
+
+		before := time.Now().Add(time.Hour).Add(-time.Minute)
+		output, err := exec.Command(binaryPath, "--config", configFile, "env", "--format", "json").CombinedOutput()
+		if err != nil {
+			t.Fatalf("sstart env failed: %v\n%s", err, output)
+		}
+		var env map[string]string
+		if err := json.Unmarshal(output, &env); err != nil {
+			t.Fatalf("Failed to parse output %q: %v", output, err)
+		}
+		expiresAt, err := time.Parse(time.RFC3339, env["DB_CREDS_EXPIRES_AT"])
+		if err != nil {
+			t.Fatalf("Expected DB_CREDS_EXPIRES_AT in RFC 3339 format, got %v: %v", env, err)
+		}
+		if expiresAt.Before(before) || expiresAt.After(time.Now().Add(time.Hour)) {
+			t.Errorf("Expected DB_CREDS_EXPIRES_AT in an hour, got %s", expiresAt)
+		}
+		if !strings.HasSuffix(env["DB_CREDS_EXPIRES_AT"], "Z") {
+			t.Errorf("Expected DB_CREDS_EXPIRES_AT in UTC, got %s", env["DB_CREDS_EXPIRES_AT"])
+		}
+	})
+
+	t.Run("watch refreshes before expiry", func(t *testing.T) {
+		configFile := writeConfig(t, newFakeVaultLease(t, 2).URL)
+
+		// A long poll interval, so only the expiry of the lease can trigger the restart
+		outFile := startWatchedRun(t, binaryPath, configFile, nil, "--watch-interval", "1h")
+		waitForLines(t, outFile, "v1\n")
+		waitForLines(t, outFile, "v1\nv2\n")
+	})
+}
@@ -0,0 +1,97 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_Hook tests that the shell hook loads secrets when entering a directory with an allowed
+// config file, does nothing while it is unchanged, and restores the environment when leaving
+func TestE2E_Hook(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(filepath.Join(projectDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	writeEnvTestConfig(t, projectDir, "API_KEY=it's-secret\nNEW_KEY=new\nBAD-KEY=x\n")
+
+	script := `
+hook() { eval "$("$SSTART" hook export bash)"; }
+cd project/sub && hook
+echo "denied: $API_KEY ${NEW_KEY-unset}"
+"$SSTART" allow 2>/dev/null && hook
+echo "loaded: $API_KEY $NEW_KEY"
+echo "again: [$("$SSTART" hook export bash)]"
+cd ../.. && hook
+echo "left: $API_KEY ${NEW_KEY-unset} ${SSTART_HOOK_CONFIG-unset}"
+`
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Dir = tmpDir
+	cmd.Env = append(os.Environ(), "SSTART="+binaryPath, "API_KEY=original", "HOME="+tmpDir, "XDG_CONFIG_HOME="+filepath.Join(tmpDir, "config"))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("hook script failed: %v\n%s%s", err, output, stderr.String())
+	}
+
+	want := "denied: original unset\nloaded: it's-secret new\nagain: []\nleft: original unset unset\n"
+	if string(output) != want {
+		t.Errorf("unexpected hook output:\n%s\nwant:\n%s", output, want)
+	}
+	for _, message := range []string{"config file is not allowed", "skipped secret 'BAD-KEY'"} {
+		if !strings.Contains(stderr.String(), message) {
+			t.Errorf("expected %q in the hook's messages, got:\n%s", message, stderr.String())
+		}
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		output, err := exec.Command(binaryPath, "hook", shell).CombinedOutput()
+		if err != nil || !strings.Contains(string(output), "hook export "+shell) {
+			t.Errorf("sstart hook %s: %v\n%s", shell, err, output)
+		}
+	}
+	if output, err := exec.Command(binaryPath, "direnv").CombinedOutput(); err != nil || !strings.Contains(string(output), "use_sstart()") {
+		t.Errorf("sstart direnv: %v\n%s", err, output)
+	}
+}
+
+// TestE2E_Hook_DirenvWatch tests that use_sstart watches every file the config is read from,
+// ignoring the flags meant for 'sstart env'
+func TestE2E_Hook_DirenvWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	sharedFile := filepath.Join(tmpDir, "shared.yml")
+	if err := os.WriteFile(sharedFile, []byte("providers:\n  - kind: dotenv\n    path: .env.shared\n"), 0644); err != nil {
+		t.Fatalf("Failed to write included config: %v", err)
+	}
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(filepath.Join(projectDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	configFile := filepath.Join(projectDir, ".sstart.yml")
+	if err := os.WriteFile(configFile, []byte("include:\n  - ../shared.yml\nproviders:\n  - kind: dotenv\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--only", "API_KEY", "hook", "watch")
+	cmd.Dir = filepath.Join(projectDir, "sub")
+	cmd.Env = append(os.Environ(), "HOME="+tmpDir, "XDG_CONFIG_HOME="+filepath.Join(tmpDir, "config"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart hook watch: %v\n%s", err, output)
+	}
+	for _, file := range []string{configFile, sharedFile} {
+		if line := "watch_file '" + file + "'\n"; !strings.Contains(string(output), line) {
+			t.Errorf("expected %q in the output, got:\n%s", line, output)
+		}
+	}
+}
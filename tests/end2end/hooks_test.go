@@ -0,0 +1,268 @@
+package end2end
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/config"
+	"github.com/dirathea/sstart/internal/provider"
+	_ "github.com/dirathea/sstart/internal/provider/dotenv"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// writeHooksTestConfig writes two dotenv providers, app and other, with the given hooks section,
+// and allows the config file to run its hooks
+func writeHooksTestConfig(t *testing.T, tmpDir, hooks string) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	appEnv := filepath.Join(tmpDir, "app.env")
+	otherEnv := filepath.Join(tmpDir, "other.env")
+	if err := os.WriteFile(appEnv, []byte("API_KEY=app-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	if err := os.WriteFile(otherEnv, []byte("OTHER_KEY=other-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: dotenv
+    id: app
+    path: ` + appEnv + `
+  - kind: dotenv
+    id: other
+    path: ` + otherEnv + `
+` + hooks
+	if err := os.WriteFile(configFile, []byte(configYAML), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := config.Trust(configFile); err != nil {
+		t.Fatalf("Failed to allow config file: %v", err)
+	}
+	return configFile
+}
+
+// TestE2E_Hooks_Commands tests that hook commands run around fetches and before injection, and
+// can transform secrets or abort the collection
+func TestE2E_Hooks_Commands(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	hookLog := filepath.Join(tmpDir, "hooks.log")
+
+	configFile := writeHooksTestConfig(t, tmpDir, `
+hooks:
+  pre_fetch:
+    - command: sh
+      args: ["-c", "echo \"$SSTART_HOOK $SSTART_PROVIDER\" >> `+hookLog+`"]
+      providers: [app]
+  post_fetch:
+    - command: sed
+      args: ["s/API_KEY/RENAMED_KEY/"]
+      providers: [app]
+  pre_inject:
+    - command: sh
+      args: ["-c", "grep -q RENAMED_KEY || { echo 'RENAMED_KEY is missing' >&2; exit 1; }"]
+`)
+	cmd := exec.Command(binaryPath, "--config", configFile, "env", "--format", "json")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, stderr.String())
+	}
+	var env map[string]string
+	if err := json.Unmarshal(output, &env); err != nil {
+		t.Fatalf("Failed to parse env output: %v\n%s", err, output)
+	}
+	if env["RENAMED_KEY"] != "app-secret" || env["OTHER_KEY"] != "other-secret" {
+		t.Errorf("Expected the post_fetch hook to rename API_KEY of app only, got %v", env)
+	}
+	if _, exists := env["API_KEY"]; exists {
+		t.Errorf("Expected API_KEY to be replaced, got %v", env)
+	}
+	log, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatalf("Expected the pre_fetch hook to run: %v", err)
+	}
+	if string(log) != "pre_fetch app\n" {
+		t.Errorf("Expected the pre_fetch hook to run for app only, got %q", log)
+	}
+
+	// A failing hook aborts the collection
+	configFile = writeHooksTestConfig(t, tmpDir, `
+hooks:
+  pre_inject:
+    - command: sh
+      args: ["-c", "echo 'API_KEY must be rotated' >&2; exit 3"]
+`)
+	cmd = exec.Command(binaryPath, "--config", configFile, "run", "--", "echo", "should not run")
+	output, err = cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected the run to fail, got:\n%s", output)
+	}
+	if strings.Contains(string(output), "should not run") {
+		t.Errorf("Expected the command not to run, got:\n%s", output)
+	}
+	for _, want := range []string{"API_KEY must be rotated", "pre_inject hook", "command 'sh' failed"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+// TestE2E_Hooks_Library tests hooks registered as Go callbacks on the collector
+func TestE2E_Hooks_Library(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg, err := config.Load(writeHooksTestConfig(t, tmpDir, ""))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var fetched []string
+	collector := secrets.NewCollector(cfg, secrets.WithHooks(secrets.Hooks{
+		PreFetch: func(ctx context.Context, providerID string) error {
+			fetched = append(fetched, providerID)
+			return nil
+		},
+		PostFetch: func(ctx context.Context, providerID string, s provider.Secrets) (provider.Secrets, error) {
+			if providerID == "other" {
+				delete(s, "OTHER_KEY")
+			}
+			return s, nil
+		},
+		PreInject: func(ctx context.Context, s provider.Secrets) (provider.Secrets, error) {
+			s["DERIVED_URL"] = "https://" + s["API_KEY"] + "@api.example.com"
+			return s, nil
+		},
+	}))
+
+	collected, sources, err := collector.CollectWithSources(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to collect secrets: %v", err)
+	}
+	if strings.Join(fetched, ",") != "app,other" {
+		t.Errorf("Expected PreFetch for each provider, got %v", fetched)
+	}
+	if _, exists := collected["OTHER_KEY"]; exists {
+		t.Errorf("Expected PostFetch to drop OTHER_KEY, got %v", collected)
+	}
+	if collected["DERIVED_URL"] != "https://app-secret@api.example.com" || sources["DERIVED_URL"] != "hooks" {
+		t.Errorf("Expected PreInject to add DERIVED_URL from hooks, got %v (sources %v)", collected, sources)
+	}
+
+	// Errors abort the collection
+	failing := secrets.NewCollector(cfg, secrets.WithHooks(secrets.Hooks{
+		PreFetch: func(ctx context.Context, providerID string) error {
+			return errors.New("not on the VPN")
+		},
+	}))
+	if _, err := failing.Collect(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "pre_fetch hook for provider 'app': not on the VPN") {
+		t.Errorf("Expected the PreFetch error, got %v", err)
+	}
+}
+
+// TestE2E_Hooks_Run tests that pre_run and post_run commands run around the command with its
+// secrets, and that their failures abort the run
+func TestE2E_Hooks_Run(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	hookLog := filepath.Join(tmpDir, "run.log")
+	logTo := func(line string) string {
+		return `["-c", "echo \"` + line + `\" >> ` + hookLog + `"]`
+	}
+
+	readLog := func(t *testing.T) string {
+		t.Helper()
+		data, err := os.ReadFile(hookLog)
+		if err != nil {
+			t.Fatalf("Failed to read hook log: %v", err)
+		}
+		_ = os.Remove(hookLog)
+		return string(data)
+	}
+
+	t.Run("around the command", func(t *testing.T) {
+		configFile := writeHooksTestConfig(t, t.TempDir(), `
+hooks:
+  pre_run:
+    - command: sh
+      args: `+logTo("$SSTART_HOOK $API_KEY")+`
+  post_run:
+    - command: sh
+      args: `+logTo("$SSTART_HOOK $API_KEY $SSTART_EXIT_CODE")+`
+`)
+		cmd := exec.Command(binaryPath, "--config", configFile, "run", "--", "sh", "-c", "echo \"command $API_KEY\" >> "+hookLog+"; exit 3")
+		output, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("Expected the exit code of the command, got %v\n%s", err, output)
+		}
+		want := "pre_run app-secret\ncommand app-secret\npost_run app-secret 3\n"
+		if got := readLog(t); got != want {
+			t.Errorf("Expected hook log %q, got %q", want, got)
+		}
+	})
+
+	t.Run("failing pre_run", func(t *testing.T) {
+		configFile := writeHooksTestConfig(t, t.TempDir(), `
+hooks:
+  pre_run:
+    - command: sh
+      args: ["-c", "echo migration failed >&2; exit 1"]
+  post_run:
+    - command: sh
+      args: `+logTo("post_run")+`
+`)
+		output, err := exec.Command(binaryPath, "--config", configFile, "run", "--", "sh", "-c", "echo should not run").CombinedOutput()
+		if err == nil {
+			t.Fatalf("Expected the run to fail, got:\n%s", output)
+		}
+		if strings.Contains(string(output), "should not run") {
+			t.Errorf("Expected the command not to run, got:\n%s", output)
+		}
+		for _, want := range []string{"migration failed", "pre_run hook: command 'sh' failed"} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+		if _, err := os.Stat(hookLog); !os.IsNotExist(err) {
+			t.Errorf("Expected post_run not to run without the command, got %v", err)
+		}
+	})
+
+	t.Run("failing post_run", func(t *testing.T) {
+		configFile := writeHooksTestConfig(t, t.TempDir(), `
+hooks:
+  post_run:
+    - command: sh
+      args: ["-c", "exit 1"]
+`)
+		output, err := exec.Command(binaryPath, "--config", configFile, "run", "--", "sh", "-c", "echo done").CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			t.Fatalf("Expected exit code 1, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "done") || !strings.Contains(string(output), "post_run hook: command 'sh' failed") {
+			t.Errorf("Expected the command to run and the post_run error, got:\n%s", output)
+		}
+	})
+
+	t.Run("providers", func(t *testing.T) {
+		configFile := writeHooksTestConfig(t, t.TempDir(), `
+hooks:
+  pre_run:
+    - command: "true"
+      providers: [app]
+`)
+		output, err := exec.Command(binaryPath, "--config", configFile, "run", "--", "true").CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "hooks.pre_run[0]: 'providers' only applies to fetch hooks") {
+			t.Errorf("Expected the providers error, got %v\n%s", err, output)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package end2end
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// encryptDotenvVault encrypts an environment like dotenv-vault does and returns the ciphertext and its DOTENV_KEY
+func encryptDotenvVault(t *testing.T, environment, plaintext string) (string, string) {
+	t.Helper()
+	key := make([]byte, 32)
+	nonce := make([]byte, 12)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(nonce)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create GCM: %v", err)
+	}
+	ciphertext := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+	dotenvKey := "dotenv://:key_" + hex.EncodeToString(key) + "@dotenv.org/vault/.env.vault?environment=" + environment
+	return ciphertext, dotenvKey
+}
+
+// TestE2E_Import_DotenvVault tests importing dotenv-vault environments into .env files and a config
+func TestE2E_Import_DotenvVault(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	production := map[string]string{
+		"API_KEY":  "prod-key",
+		"DB_URL":   "postgres://user:p@ss#1@db/app",
+		"GREETING": "it's a\nmulti-line value",
+	}
+	prodCiphertext, prodKey := encryptDotenvVault(t, "production", "API_KEY=prod-key\nDB_URL=\"postgres://user:p@ss#1@db/app\"\nGREETING=\"it's a\nmulti-line value\"\n")
+	ciCiphertext, ciKey := encryptDotenvVault(t, "ci", "API_KEY=ci-key\n")
+	vaultFile := filepath.Join(tmpDir, ".env.vault")
+	vaultContent := "DOTENV_VAULT_PRODUCTION=\"" + prodCiphertext + "\"\nDOTENV_VAULT_CI=\"" + ciCiphertext + "\"\n"
+	if err := os.WriteFile(vaultFile, []byte(vaultContent), 0644); err != nil {
+		t.Fatalf("Failed to write .env.vault: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "import", "dotenv-vault", ".env.vault", "--key", prodKey, "--key", ciKey)
+	cmd.Dir = tmpDir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	configYAML, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart import failed: %v\n%s", err, stderr.String())
+	}
+	if want := "providers:\n  - kind: dotenv\n    id: production\n    path: .env.production\n  - kind: dotenv\n    id: ci\n    path: .env.ci\n"; string(configYAML) != want {
+		t.Errorf("config = %q, want %q", configYAML, want)
+	}
+	if info, err := os.Stat(filepath.Join(tmpDir, ".env.production")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected .env.production with mode 0600, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".sstart.yml"), configYAML, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd = exec.Command(binaryPath, "env", "--format", "json", "--providers", "production")
+	cmd.Dir = tmpDir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(output, &got); err != nil {
+		t.Fatalf("Failed to parse env output: %v", err)
+	}
+	if !reflect.DeepEqual(got, production) {
+		t.Errorf("imported secrets = %v, want %v", got, production)
+	}
+
+	t.Run("existing files", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "import", "dotenv-vault", ".env.vault", "--key", ciKey)
+		cmd.Dir = tmpDir
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "already exists (use --force to replace it)") {
+			t.Errorf("expected an error for an existing .env file, got %v: %s", err, output)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, otherKey := encryptDotenvVault(t, "production", "")
+		cmd := exec.Command(binaryPath, "import", "dotenv-vault", ".env.vault", "--dir", t.TempDir())
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(), "DOTENV_KEY="+otherKey)
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "failed to decrypt environment 'production': wrong DOTENV_KEY") {
+			t.Errorf("expected a decryption error, got %v: %s", err, output)
+		}
+	})
+}
+
+// TestE2E_Import_EnvKeyToVault tests importing an EnvKey export into a Vault KV v2 secret
+func TestE2E_Import_EnvKeyToVault(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	var mu sync.Mutex
+	stored := map[string]interface{}{"EXISTING": "kept"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" || r.URL.Path != "/v1/secret/data/myapp" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": stored}})
+		case http.MethodPut, http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			var req struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			_ = json.Unmarshal(body, &req)
+			stored = req.Data
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": 2}})
+		}
+	}))
+	defer server.Close()
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := "providers:\n  - kind: vault\n    id: vault-prod\n    address: " + server.URL + "\n    path: myapp\n"
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	exportFile := filepath.Join(tmpDir, "staging.json")
+	if err := os.WriteFile(exportFile, []byte(`{"API_KEY": "from-envkey", "PORT": 8080, "UNSET": null}`), 0644); err != nil {
+		t.Fatalf("Failed to write EnvKey export: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "import", "envkey", exportFile, "--environment", "staging", "--to", "vault-prod")
+	cmd.Env = append(os.Environ(), "VAULT_TOKEN=test-token")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart import failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "Imported 2 secrets of environment 'staging' into provider 'vault-prod'") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]interface{}{"EXISTING": "kept", "API_KEY": "from-envkey", "PORT": "8080"}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("Vault secret = %v, want %v", stored, want)
+	}
+}
+
+// TestE2E_Import_EnvKeyToDotenv tests importing secrets into the .env file of a dotenv provider,
+// keeping its comments and other keys
+func TestE2E_Import_EnvKeyToDotenv(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	envFile := filepath.Join(tmpDir, ".env.local")
+	if err := os.WriteFile(envFile, []byte("# Local overrides\nAPI_KEY=old # rotated monthly\nEXISTING=kept\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := "providers:\n  - kind: dotenv\n    id: local\n    path: " + envFile + "\n"
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	exportFile := filepath.Join(tmpDir, "staging.json")
+	if err := os.WriteFile(exportFile, []byte(`{"API_KEY": "from-envkey", "GREETING": "hello world"}`), 0644); err != nil {
+		t.Fatalf("Failed to write EnvKey export: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "import", "envkey", exportFile, "--to", "local").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart import failed: %v\n%s", err, output)
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Failed to read .env file: %v", err)
+	}
+	want := "# Local overrides\nAPI_KEY=from-envkey # rotated monthly\nEXISTING=kept\nGREETING='hello world'\n"
+	if string(data) != want {
+		t.Errorf(".env file = %q, want %q", data, want)
+	}
+	info, err := os.Stat(envFile)
+	if err != nil {
+		t.Fatalf("Failed to stat .env file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	output, err = exec.Command(binaryPath, "--config", configFile, "show").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart show failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "GREETING") {
+		t.Errorf("Expected the imported secrets to be loaded, got:\n%s", output)
+	}
+}
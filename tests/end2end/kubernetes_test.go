@@ -0,0 +1,136 @@
+package end2end
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFakeKubernetesAPI serves Secrets and ConfigMaps of the given namespace by name, over TLS since
+// client-go only sends credentials to HTTPS servers
+func newFakeKubernetesAPI(t *testing.T, namespace string, secrets, configMaps map[string]map[string]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/{resource}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var object map[string]interface{}
+		name := r.PathValue("name")
+		if r.PathValue("namespace") == namespace {
+			switch r.PathValue("resource") {
+			case "secrets":
+				if data, ok := secrets[name]; ok {
+					encoded := make(map[string]string)
+					for k, v := range data {
+						encoded[k] = base64.StdEncoding.EncodeToString([]byte(v))
+					}
+					object = map[string]interface{}{"apiVersion": "v1", "kind": "Secret", "data": encoded}
+				}
+			case "configmaps":
+				if data, ok := configMaps[name]; ok {
+					object = map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "data": data}
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if object == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"apiVersion": "v1", "kind": "Status", "status": "Failure", "reason": "NotFound", "code": http.StatusNotFound,
+				"message": r.PathValue("resource") + " \"" + name + "\" not found",
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(object)
+	})
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestE2E_Kubernetes tests merging a ConfigMap and a Secret from the same cluster
+func TestE2E_Kubernetes(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	server := newFakeKubernetesAPI(t, "myapp",
+		map[string]map[string]string{"myapp-secrets": {"DB_PASSWORD": "p@ss", "LOG_LEVEL": "from-secret"}},
+		map[string]map[string]string{"myapp-config": {"LOG_LEVEL": "debug", "DB_HOST": "db.internal"}},
+	)
+
+	kubeconfig := filepath.Join(tmpDir, "kubeconfig")
+	kubeconfigYAML := `apiVersion: v1
+kind: Config
+clusters:
+  - name: test
+    cluster:
+      server: ` + server.URL + `
+      insecure-skip-tls-verify: true
+users:
+  - name: test
+    user:
+      token: test-token
+contexts:
+  - name: test
+    context:
+      cluster: test
+      user: test
+      namespace: myapp
+current-context: test
+`
+	if err := os.WriteFile(kubeconfig, []byte(kubeconfigYAML), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `providers:
+  - kind: kubernetes
+    id: config
+    configmap: myapp-config
+    kubeconfig: ` + kubeconfig + `
+  - kind: kubernetes
+    id: secrets
+    secret: myapp-secrets
+    kubeconfig: ` + kubeconfig + `
+    keys:
+      DB_PASSWORD: ==
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	want := "export DB_HOST='db.internal'\nexport DB_PASSWORD='p@ss'\nexport LOG_LEVEL='debug'\n"
+	if string(output) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+
+	t.Run("missing object", func(t *testing.T) {
+		missingConfig := filepath.Join(tmpDir, "missing.yml")
+		missingYAML := `providers:
+  - kind: kubernetes
+    secret: absent
+    kubeconfig: ` + kubeconfig + `
+`
+		if err := os.WriteFile(missingConfig, []byte(missingYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		output, err := exec.Command(binaryPath, "--config", missingConfig, "env").CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected sstart env to fail, got:\n%s", output)
+		}
+		if !strings.Contains(string(output), "failed to read secret 'myapp/absent'") {
+			t.Errorf("unexpected error output:\n%s", output)
+		}
+	})
+}
@@ -2,14 +2,18 @@ package end2end
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -489,3 +493,1070 @@ mcp:
 	stdin.Close()
 	cmd.Wait()
 }
+
+// TestE2E_MCP_Metrics tests that sstart mcp --metrics-listen serves Prometheus metrics for fetches and forwarded requests
+func TestE2E_MCP_Metrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	toolJSON := `{"name":"test_tool","description":"A test tool","inputSchema":{"type":"object"}}`
+	serverScript := createMockMCPServer(t, tmpDir, "mockserver", []string{toolJSON})
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: mockserver
+      command: bash
+      args: ["%s"]
+`, envPath, serverScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Pick a free port for the metrics endpoint
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	metricsAddr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath, "--metrics-listen", metricsAddr)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart mcp: %v", err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"mockserver/test_tool","arguments":{}}}`,
+	}
+	for _, req := range requests {
+		if _, err := io.WriteString(stdin, req+"\n"); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		if !scanner.Scan() {
+			t.Fatalf("Failed to read response: %v", scanner.Err())
+		}
+	}
+
+	resp, err := http.Get("http://" + metricsAddr + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		`sstart_provider_fetches_total{kind="dotenv",provider="dotenv",result="success"} 1`,
+		`sstart_mcp_requests_total{method="tools/call",result="success",server="mockserver"} 1`,
+		`sstart_mcp_server_starts_total{server="mockserver"} 1`,
+		`sstart_mcp_request_duration_seconds_count{method="tools/call",server="mockserver"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestE2E_MCP_Prompts tests that prompts of downstream servers are listed across pages with
+// their arguments, fetched by namespaced name, and that list changes reach the client
+func TestE2E_MCP_Prompts(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	// Mock MCP server with two pages of prompts, announcing a list change after each get
+	scriptContent := `#!/bin/bash
+while IFS= read -r line; do
+    method=$(echo "$line" | grep -o '"method":"[^"]*"' | cut -d'"' -f4)
+    id=$(echo "$line" | grep -o '"id":[0-9]*' | cut -d':' -f2)
+
+    case "$method" in
+        "initialize")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","capabilities":{"prompts":{"listChanged":true}},"serverInfo":{"name":"prompter","version":"1.0.0"}}}'
+            ;;
+        "prompts/list")
+            if echo "$line" | grep -q '"cursor":"page2"'; then
+                echo '{"jsonrpc":"2.0","id":'$id',"result":{"prompts":[{"name":"summarize","description":"Summarize text"}]}}'
+            else
+                echo '{"jsonrpc":"2.0","id":'$id',"result":{"prompts":[{"name":"greet","title":"Greeting","description":"Greet someone","arguments":[{"name":"who","description":"Who to greet","required":true}]}],"nextCursor":"page2"}}'
+            fi
+            ;;
+        "prompts/get")
+            who=$(echo "$line" | grep -o '"who":"[^"]*"' | cut -d'"' -f4)
+            name=$(echo "$line" | grep -o '"name":"[^"]*"' | cut -d'"' -f4)
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"messages":[{"role":"user","content":{"type":"text","text":"'$name': hello '$who'"}}]}}'
+            echo '{"jsonrpc":"2.0","method":"notifications/prompts/list_changed"}'
+            ;;
+    esac
+done
+`
+	serverScript := filepath.Join(tmpDir, "mock_mcp_prompter.sh")
+	if err := os.WriteFile(serverScript, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create mock MCP server script: %v", err)
+	}
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: prompter
+      command: bash
+      args: ["%s"]
+`, envPath, serverScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart mcp: %v", err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	messages := make(chan MCPMessage, 10)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			var msg MCPMessage
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err == nil {
+				messages <- msg
+			}
+		}
+		close(messages)
+	}()
+	// next returns the next message matching the method, or the response to the request id.
+	// Notifications can arrive before earlier responses, so skipped ones are kept for later
+	var skipped []MCPMessage
+	next := func(method string, id float64) MCPMessage {
+		t.Helper()
+		for i, msg := range skipped {
+			if msg.Method == method && (method != "" || msg.ID == id) {
+				skipped = append(skipped[:i], skipped[i+1:]...)
+				return msg
+			}
+		}
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					t.Fatal("sstart mcp exited")
+				}
+				if msg.Method == method && (method != "" || msg.ID == id) {
+					return msg
+				}
+				skipped = append(skipped, msg)
+			case <-time.After(10 * time.Second):
+				t.Fatalf("Timeout waiting for message %q (id %v)", method, id)
+			}
+		}
+	}
+	send := func(req string) {
+		t.Helper()
+		if _, err := io.WriteString(stdin, req+"\n"); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`)
+	initResp := next("", 1)
+	if !strings.Contains(string(initResp.Result), `"prompts":{"listChanged":true}`) {
+		t.Errorf("Expected the proxy to announce prompt list changes, got: %s", initResp.Result)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"prompts/list","params":{}}`)
+	listResp := next("", 2)
+	if listResp.Error != nil {
+		t.Fatalf("prompts/list failed: %s", listResp.Error.Message)
+	}
+	var listResult struct {
+		Prompts []struct {
+			Name      string `json:"name"`
+			Title     string `json:"title"`
+			Arguments []struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+				Required    bool   `json:"required"`
+			} `json:"arguments"`
+		} `json:"prompts"`
+	}
+	if err := json.Unmarshal(listResp.Result, &listResult); err != nil {
+		t.Fatalf("Failed to parse prompts/list result: %v", err)
+	}
+	if len(listResult.Prompts) != 2 || listResult.Prompts[0].Name != "prompter/greet" || listResult.Prompts[1].Name != "prompter/summarize" {
+		t.Fatalf("Expected the prompts of both pages, got: %s", listResp.Result)
+	}
+	greet := listResult.Prompts[0]
+	if greet.Title != "Greeting" || len(greet.Arguments) != 1 || greet.Arguments[0].Name != "who" || !greet.Arguments[0].Required || greet.Arguments[0].Description != "Who to greet" {
+		t.Errorf("Expected the title and arguments to pass through, got: %s", listResp.Result)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"prompter/greet","arguments":{"who":"world"}}}`)
+	getResp := next("", 3)
+	if getResp.Error != nil {
+		t.Fatalf("prompts/get failed: %s", getResp.Error.Message)
+	}
+	if !strings.Contains(string(getResp.Result), "greet: hello world") {
+		t.Errorf("Expected the prompt to be fetched by its original name with the arguments, got: %s", getResp.Result)
+	}
+
+	next("notifications/prompts/list_changed", 0)
+}
+
+// TestE2E_MCP_HTTP tests that several clients share the proxy over HTTP, each in its own
+// session, with request IDs that may collide across clients
+func TestE2E_MCP_HTTP(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	toolJSON := `{"name":"test_tool","description":"A test tool","inputSchema":{"type":"object"}}`
+	serverScript := createMockMCPServer(t, tmpDir, "mockserver", []string{toolJSON})
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: mockserver
+      command: bash
+      args: ["%s"]
+`, envPath, serverScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath, "--listen", addr)
+	cmd.Env = append(os.Environ(), "SSTART_MCP_TOKEN=test-token")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart mcp: %v", err)
+	}
+	defer func() {
+		cmd.Process.Signal(os.Interrupt)
+		cmd.Wait()
+	}()
+
+	baseURL := "http://" + addr
+	for i := 0; ; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("sstart mcp did not listen on %s", addr)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// post sends a message to the streamable HTTP endpoint and returns the response
+	post := func(path, session, token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if session != "" {
+			req.Header.Set("Mcp-Session-Id", session)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+	decode := func(resp *http.Response) MCPMessage {
+		t.Helper()
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, body)
+		}
+		var msg MCPMessage
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return msg
+	}
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`
+
+	resp := post("/mcp", "", "wrong-token", initialize)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 without the token, got %d", resp.StatusCode)
+	}
+
+	// Two clients initialize with the same request ID and get separate sessions
+	sessions := make([]string, 2)
+	for i := range sessions {
+		resp := post("/mcp", "", "test-token", initialize)
+		sessions[i] = resp.Header.Get("Mcp-Session-Id")
+		if msg := decode(resp); msg.Error != nil {
+			t.Fatalf("initialize failed: %s", msg.Error.Message)
+		}
+	}
+	if sessions[0] == "" || sessions[0] == sessions[1] {
+		t.Fatalf("Expected two distinct session IDs, got %q", sessions)
+	}
+
+	// Both clients call a tool concurrently with the same request ID
+	results := make(chan MCPMessage, 20)
+	for i := 0; i < 10; i++ {
+		session := sessions[i%2]
+		go func() {
+			resp := post("/mcp", session, "test-token", `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"mockserver/test_tool","arguments":{}}}`)
+			defer resp.Body.Close()
+			var msg MCPMessage
+			_ = json.NewDecoder(resp.Body).Decode(&msg)
+			results <- msg
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		msg := <-results
+		if msg.Error != nil {
+			t.Fatalf("tools/call failed: %s", msg.Error.Message)
+		}
+		if fmt.Sprint(msg.ID) != "2" {
+			t.Errorf("Expected response ID 2, got %v", msg.ID)
+		}
+		if !strings.Contains(string(msg.Result), "DATABASE_URL=postgres://localhost/testdb") {
+			t.Errorf("Expected tool result with DATABASE_URL, got %s", msg.Result)
+		}
+	}
+
+	resp = post("/mcp", "", "test-token", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without a session, got %d", resp.StatusCode)
+	}
+
+	// Ending a session leaves the other one working
+	req, _ := http.NewRequestWithContext(ctx, http.MethodDelete, baseURL+"/mcp", nil)
+	req.Header.Set("Mcp-Session-Id", sessions[0])
+	req.Header.Set("Authorization", "Bearer test-token")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Failed to end session: %v %v", err, resp)
+	}
+	resp = post("/mcp", sessions[0], "test-token", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an ended session, got %d", resp.StatusCode)
+	}
+	msg := decode(post("/mcp", sessions[1], "test-token", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`))
+	if !strings.Contains(string(msg.Result), "mockserver/test_tool") {
+		t.Errorf("Expected tools of the other session, got %s", msg.Result)
+	}
+
+	// A client of the HTTP+SSE transport receives responses on its event stream
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/sse", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer test-token")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewScanner(stream.Body)
+	nextData := func() string {
+		t.Helper()
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				return data
+			}
+		}
+		t.Fatalf("Event stream ended: %v", events.Err())
+		return ""
+	}
+	endpoint := nextData()
+	if !strings.HasPrefix(endpoint, "/message?sessionId=") {
+		t.Fatalf("Expected an endpoint event, got %q", endpoint)
+	}
+	resp = post(endpoint, "", "test-token", initialize)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+	var initResult MCPMessage
+	if err := json.Unmarshal([]byte(nextData()), &initResult); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if fmt.Sprint(initResult.ID) != "1" || !strings.Contains(string(initResult.Result), "sstart-mcp-proxy") {
+		t.Errorf("Expected the initialize response on the event stream, got %+v", initResult)
+	}
+}
+
+// TestE2E_MCP_SecretsFilter tests that the secrets list of a server restricts the collected keys
+// injected into it
+func TestE2E_MCP_SecretsFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	toolJSON := `{"name":"echo_env","description":"Echo environment","inputSchema":{"type":"object"}}`
+	serverScript := createMockMCPServer(t, tmpDir, "envserver", []string{toolJSON})
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\nAPI_KEY=secret-api-key\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %[1]s
+
+mcp:
+  servers:
+    - id: all
+      command: bash
+      args: ["%[2]s"]
+    - id: database
+      command: bash
+      args: ["%[2]s"]
+      secrets: [DATABASE_URL]
+    - id: other
+      command: bash
+      args: ["%[2]s"]
+      secrets: [API_KEY, MISSING_KEY]
+    - id: none
+      command: bash
+      args: ["%[2]s"]
+      secrets: []
+`, envPath, serverScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart mcp: %v", err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	call := func(request string) MCPMessage {
+		t.Helper()
+		if _, err := io.WriteString(stdin, request+"\n"); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		if !scanner.Scan() {
+			t.Fatalf("Failed to read response: %v", scanner.Err())
+		}
+		var msg MCPMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if msg.Error != nil {
+			t.Fatalf("Request failed: %s", msg.Error.Message)
+		}
+		return msg
+	}
+
+	call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+	for i, tt := range []struct {
+		server string
+		want   string
+	}{
+		{"all", "DATABASE_URL=postgres://localhost/testdb"},
+		{"database", "DATABASE_URL=postgres://localhost/testdb"},
+		{"other", "DATABASE_URL=not_set"},
+		{"none", "DATABASE_URL=not_set"},
+	} {
+		msg := call(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"%s/echo_env","arguments":{}}}`, i+2, tt.server))
+		if !strings.Contains(string(msg.Result), tt.want) {
+			t.Errorf("Expected server '%s' to see %s, got: %s", tt.server, tt.want, msg.Result)
+		}
+	}
+}
+
+// TestE2E_MCP_GracefulShutdown tests that on SIGTERM the proxy answers the tool call in flight
+// before closing the downstream server's stdin, so that the server exits on its own
+func TestE2E_MCP_GracefulShutdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	markerPath := filepath.Join(tmpDir, "exited")
+	serverScript := filepath.Join(tmpDir, "slow_server.sh")
+	script := fmt.Sprintf(`#!/bin/bash
+while IFS= read -r line; do
+    method=$(echo "$line" | grep -o '"method":"[^"]*"' | cut -d'"' -f4)
+    id=$(echo "$line" | grep -o '"id":[0-9]*' | cut -d':' -f2)
+    case "$method" in
+        "initialize")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{}},"serverInfo":{"name":"slow","version":"1.0.0"}}}'
+            ;;
+        "tools/list")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"tools":[{"name":"slow","inputSchema":{"type":"object"}}]}}'
+            ;;
+        "tools/call")
+            sleep 2
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"content":[{"type":"text","text":"done"}]}}'
+            ;;
+    esac
+done
+echo clean > %s
+`, markerPath)
+	if err := os.WriteFile(serverScript, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write server script: %v", err)
+	}
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: slow
+      command: bash
+      args: ["%s"]
+`, envPath, serverScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath, "--drain-timeout", "10s")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdin pipe: %v", err)
+	}
+	defer stdin.Close()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to get stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart mcp: %v", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	read := func() MCPMessage {
+		t.Helper()
+		if !scanner.Scan() {
+			t.Fatalf("Failed to read response: %v", scanner.Err())
+		}
+		var msg MCPMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return msg
+	}
+
+	io.WriteString(stdin, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`+"\n")
+	read()
+
+	io.WriteString(stdin, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"slow/slow","arguments":{}}}`+"\n")
+	time.Sleep(500 * time.Millisecond)
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to signal sstart: %v", err)
+	}
+
+	msg := read()
+	if msg.Error != nil {
+		t.Fatalf("Expected the tool call in flight to be answered, got error: %s", msg.Error.Message)
+	}
+	if !strings.Contains(string(msg.Result), "done") {
+		t.Errorf("Expected tool call result, got: %s", msg.Result)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("Expected sstart to exit cleanly, got: %v", err)
+	}
+	if _, err := os.Stat(markerPath); err != nil {
+		t.Errorf("Expected the server to exit on its own after its stdin was closed: %v", err)
+	}
+}
+
+// TestE2E_MCP_Instructions tests that the instructions of the downstream servers are merged
+// into the proxy's initialize result, under a header with the server ID
+func TestE2E_MCP_Instructions(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	scriptContent := `#!/bin/bash
+while IFS= read -r line; do
+    method=$(echo "$line" | grep -o '"method":"[^"]*"' | cut -d'"' -f4)
+    id=$(echo "$line" | grep -o '"id":[0-9]*' | cut -d':' -f2)
+
+    case "$method" in
+        "initialize")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"docs","version":"1.0.0"},"instructions":"Search before fetching a page."}}'
+            ;;
+    esac
+done
+`
+	docsScript := filepath.Join(tmpDir, "mock_mcp_docs.sh")
+	if err := os.WriteFile(docsScript, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create mock MCP server script: %v", err)
+	}
+	plainScript := createMockMCPServer(t, tmpDir, "plain", []string{`{"name":"echo","inputSchema":{"type":"object"}}`})
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: plain
+      command: bash
+      args: ["%s"]
+    - id: docs
+      command: bash
+      args: ["%s"]
+`, envPath, plainScript, docsScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath)
+	cmd.Stdin = strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}` + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart mcp failed: %v", err)
+	}
+
+	var resp MCPMessage
+	if err := json.Unmarshal(bytes.TrimSpace(output), &resp); err != nil {
+		t.Fatalf("Failed to parse initialize response %q: %v", output, err)
+	}
+	var result struct {
+		Instructions string `json:"instructions"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("Failed to parse initialize result: %v", err)
+	}
+
+	want := "sstart MCP proxy - aggregates multiple MCP servers with secret injection\n\n" +
+		"## docs\n\nTools, resources and prompts of this server are prefixed with 'docs/'.\n\nSearch before fetching a page."
+	if result.Instructions != want {
+		t.Errorf("Expected instructions:\n%s\ngot:\n%s", want, result.Instructions)
+	}
+}
+
+// TestE2E_MCP_Health tests the health report in the answer to a ping and the sstart/health tool
+func TestE2E_MCP_Health(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	mockScript := createMockMCPServer(t, tmpDir, "mockserver", []string{`{"name":"echo","inputSchema":{"type":"object"}}`})
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("DATABASE_URL=postgres://localhost/testdb\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    path: %s
+
+mcp:
+  servers:
+    - id: mockserver
+      command: bash
+      args: ["%s"]
+`, envPath, mockScript)
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"sstart/health","arguments":{}}}`,
+	}
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath)
+	cmd.Stdin = strings.NewReader(strings.Join(requests, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart mcp failed: %v", err)
+	}
+
+	type serverHealth struct {
+		ID          string `json:"id"`
+		State       string `json:"state"`
+		Initialized bool   `json:"initialized"`
+		LatencyMs   *int64 `json:"latencyMs"`
+	}
+	type health struct {
+		Status  string         `json:"status"`
+		Servers []serverHealth `json:"servers"`
+	}
+
+	results := make(map[string]json.RawMessage)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		var resp MCPMessage
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Failed to parse response %q: %v", line, err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("Request %v failed: %s", resp.ID, resp.Error.Message)
+		}
+		results[fmt.Sprint(resp.ID)] = resp.Result
+	}
+
+	t.Run("ping", func(t *testing.T) {
+		var result struct {
+			Meta map[string]health `json:"_meta"`
+		}
+		if err := json.Unmarshal(results["2"], &result); err != nil {
+			t.Fatalf("Failed to parse ping result %s: %v", results["2"], err)
+		}
+		got, ok := result.Meta["sstart/health"]
+		if !ok {
+			t.Fatalf("Expected sstart/health in the ping result, got %s", results["2"])
+		}
+		if got.Status != "ok" || len(got.Servers) != 1 || got.Servers[0].ID != "mockserver" || got.Servers[0].State != "running" {
+			t.Errorf("Unexpected health in the ping result: %+v", got)
+		}
+	})
+
+	t.Run("tools/list", func(t *testing.T) {
+		var result MCPToolsListResult
+		if err := json.Unmarshal(results["3"], &result); err != nil {
+			t.Fatalf("Failed to parse tools result: %v", err)
+		}
+		found := false
+		for _, tool := range result.Tools {
+			if tool.Name == "sstart/health" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected tool 'sstart/health', got tools: %+v", result.Tools)
+		}
+	})
+
+	t.Run("tools/call", func(t *testing.T) {
+		var result struct {
+			StructuredContent health `json:"structuredContent"`
+			IsError           bool   `json:"isError"`
+		}
+		if err := json.Unmarshal(results["4"], &result); err != nil {
+			t.Fatalf("Failed to parse health tool result %s: %v", results["4"], err)
+		}
+		got := result.StructuredContent
+		if result.IsError || got.Status != "ok" || len(got.Servers) != 1 {
+			t.Fatalf("Unexpected health tool result: %s", results["4"])
+		}
+		if server := got.Servers[0]; !server.Initialized || server.LatencyMs == nil {
+			t.Errorf("Expected the server to be initialized and pinged, got %+v", server)
+		}
+	})
+}
+
+// TestE2E_MCP_SecretRefs tests references to secrets in the args and env of MCP servers
+func TestE2E_MCP_SecretRefs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	// The server answers tool calls with its --token flag and its API_URL variable
+	scriptContent := `#!/bin/bash
+token="$2"
+while IFS= read -r line; do
+    method=$(echo "$line" | grep -o '"method":"[^"]*"' | cut -d'"' -f4)
+    id=$(echo "$line" | grep -o '"id":[0-9]*' | cut -d':' -f2)
+
+    case "$method" in
+        "initialize")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"flags","version":"1.0.0"}}}'
+            ;;
+        "tools/call")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"content":[{"type":"text","text":"token='$token' url='$API_URL'"}]}}'
+            ;;
+    esac
+done
+`
+	scriptPath := filepath.Join(tmpDir, "mock_mcp_flags.sh")
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create mock MCP server script: %v", err)
+	}
+
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("API_TOKEN=tok123\nAPI_HOST=api.example.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+
+	writeConfig := func(args string) string {
+		config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    id: api-creds
+    path: %s
+
+mcp:
+  servers:
+    - id: flags
+      command: bash
+      args: ["%s", "--token", %s]
+      env:
+        API_URL: "https://{{ .api-creds.API_HOST }}/v1"
+      secrets: []
+`, envPath, scriptPath, args)
+		configPath := filepath.Join(tmpDir, ".sstart.yml")
+		if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return configPath
+	}
+
+	t.Run("rendered", func(t *testing.T) {
+		configPath := writeConfig(`"{{ .api-creds.API_TOKEN }}"`)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		requests := []string{
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`,
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"flags/whoami","arguments":{}}}`,
+		}
+		cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath)
+		cmd.Stdin = strings.NewReader(strings.Join(requests, "\n") + "\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sstart mcp failed: %v", err)
+		}
+
+		if !strings.Contains(string(output), "token=tok123 url=https://api.example.com/v1") {
+			t.Errorf("Expected the server to get the rendered flag and variable, got %s", output)
+		}
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		configPath := writeConfig(`"{{ .api-creds.MISSING }}"`)
+
+		cmd := exec.Command(binaryPath, "mcp", "--config", configPath)
+		cmd.Stdin = strings.NewReader("")
+		output, err := cmd.CombinedOutput()
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok || exitErr.ExitCode() != 3 {
+			t.Fatalf("Expected exit code 3, got %v: %s", err, output)
+		}
+		if !strings.Contains(string(output), "no secret MISSING from provider 'api-creds'") {
+			t.Errorf("Expected the error to name the reference, got %s", output)
+		}
+		if strings.Contains(string(output), "tok123") {
+			t.Errorf("Error output leaked a secret value: %s", output)
+		}
+	})
+}
+
+// TestE2E_MCP_Record tests recording the messages between the host, the proxy and a downstream
+// server, with secret values redacted
+func TestE2E_MCP_Record(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	// The server answers tool calls with its API_TOKEN variable
+	scriptContent := `#!/bin/bash
+while IFS= read -r line; do
+    method=$(echo "$line" | grep -o '"method":"[^"]*"' | cut -d'"' -f4)
+    id=$(echo "$line" | grep -o '"id":[0-9]*' | cut -d':' -f2)
+
+    case "$method" in
+        "initialize")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"protocolVersion":"2024-11-05","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"echo","version":"1.0.0"}}}'
+            ;;
+        "tools/call")
+            echo '{"jsonrpc":"2.0","id":'$id',"result":{"content":[{"type":"text","text":"token=\"'$API_TOKEN'\""}]}}'
+            ;;
+    esac
+done
+`
+	scriptPath := filepath.Join(tmpDir, "mock_mcp_echo.sh")
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create mock MCP server script: %v", err)
+	}
+	envPath := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envPath, []byte("API_TOKEN=tok123\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, ".sstart.yml")
+	config := fmt.Sprintf(`
+providers:
+  - kind: dotenv
+    id: api-creds
+    path: %s
+
+mcp:
+  servers:
+    - id: echo
+      command: bash
+      args: ["%s"]
+`, envPath, scriptPath)
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	recordPath := filepath.Join(tmpDir, "session.jsonl")
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo/whoami","arguments":{"token":"tok123"}}}`,
+	}
+	cmd := exec.CommandContext(ctx, binaryPath, "mcp", "--config", configPath, "--record", recordPath)
+	cmd.Stdin = strings.NewReader(strings.Join(requests, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart mcp failed: %v", err)
+	}
+	if !strings.Contains(string(output), `token=\"tok123\"`) {
+		t.Fatalf("Expected the host to get the secret unredacted, got %s", output)
+	}
+
+	data, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatalf("Failed to read recording: %v", err)
+	}
+	if strings.Contains(string(data), "tok123") {
+		t.Errorf("Expected the secret to be redacted from the recording, got:\n%s", data)
+	}
+
+	directions := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record struct {
+			Time      time.Time       `json:"time"`
+			Direction string          `json:"direction"`
+			Server    string          `json:"server"`
+			Message   json.RawMessage `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse recorded line %q: %v", line, err)
+		}
+		if record.Time.IsZero() || len(record.Message) == 0 {
+			t.Errorf("Expected a timestamp and a message, got %q", line)
+		}
+		serverSide := record.Direction == "proxy_to_server" || record.Direction == "server_to_proxy"
+		if serverSide != (record.Server == "echo") {
+			t.Errorf("Expected the server only on messages to and from servers, got %q", line)
+		}
+		directions[record.Direction]++
+	}
+	for _, direction := range []string{"host_to_proxy", "proxy_to_host", "proxy_to_server", "server_to_proxy"} {
+		if directions[direction] == 0 {
+			t.Errorf("Expected %s messages in the recording, got %v:\n%s", direction, directions, data)
+		}
+	}
+	if !strings.Contains(string(data), `"token":"******"`) || !strings.Contains(string(data), `token=\"******\"`) {
+		t.Errorf("Expected the secret replaced with asterisks in the request and the response, got:\n%s", data)
+	}
+}
@@ -0,0 +1,176 @@
+package end2end
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotificationTargets records the events posted to the webhook and the Slack messages
+type fakeNotificationTargets struct {
+	mu     sync.Mutex
+	events []map[string]interface{}
+	slack  []string
+}
+
+func newFakeNotificationTargets(t *testing.T) (*fakeNotificationTargets, *httptest.Server) {
+	t.Helper()
+	fake := &fakeNotificationTargets{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer notify-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		fake.mu.Lock()
+		fake.events = append(fake.events, event)
+		fake.mu.Unlock()
+	})
+	mux.HandleFunc("POST /slack", func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		fake.mu.Lock()
+		fake.slack = append(fake.slack, message.Text)
+		fake.mu.Unlock()
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// eventTypes returns the types of the events received so far
+func (f *fakeNotificationTargets) eventTypes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	types := make([]string, len(f.events))
+	for i, event := range f.events {
+		types[i], _ = event["type"].(string)
+	}
+	return types
+}
+
+// waitForEvents waits until n events were received
+func (f *fakeNotificationTargets) waitForEvents(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if types := f.eventTypes(); len(types) >= n {
+			return types
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected %d notifications, got %v", n, f.eventTypes())
+	return nil
+}
+
+// writeNotifyConfig writes a config with a dotenv provider and notifications to the fake targets
+func writeNotifyConfig(t *testing.T, tmpDir, serverURL, extra string) (string, string) {
+	t.Helper()
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=abc\nDB_URL=postgres://db\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: dotenv
+    path: ` + envFile + `
+notifications:
+  required_keys: [DB_URL]
+  targets:
+    - kind: webhook
+      url: ` + serverURL + `/webhook
+      headers:
+        Authorization: Bearer ${NOTIFY_TOKEN}
+    - kind: slack
+      url: ` + serverURL + `/slack
+` + extra
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configFile, envFile
+}
+
+// TestE2E_Notifications_RunWatch tests notifications of missing keys and failed collections in watch mode
+func TestE2E_Notifications_RunWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	fake, server := newFakeNotificationTargets(t)
+	configFile, envFile := writeNotifyConfig(t, tmpDir, server.URL, "")
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "run", "--watch", "--watch-interval", "100ms", "--", "sleep", "30")
+	cmd.Env = append(os.Environ(), "NOTIFY_TOKEN=notify-token")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart run --watch: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	// A required key disappears
+	time.Sleep(300 * time.Millisecond)
+	if err := os.WriteFile(envFile, []byte("API_KEY=abc\n"), 0644); err != nil {
+		t.Fatalf("Failed to update .env file: %v", err)
+	}
+	fake.waitForEvents(t, 1)
+
+	// The same problem is reported once, however often the secrets are polled
+	time.Sleep(500 * time.Millisecond)
+	if err := os.Remove(envFile); err != nil {
+		t.Fatalf("Failed to remove .env file: %v", err)
+	}
+	types := fake.waitForEvents(t, 2)
+	time.Sleep(500 * time.Millisecond)
+	types = fake.eventTypes()
+	if len(types) != 2 || types[0] != "missing_keys" || types[1] != "collection_failed" {
+		t.Fatalf("expected missing_keys then collection_failed notifications, got %v", types)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if keys, _ := fake.events[0]["keys"].([]interface{}); len(keys) != 1 || keys[0] != "DB_URL" {
+		t.Errorf("expected the missing DB_URL key in the event, got %v", fake.events[0])
+	}
+	if fake.events[1]["config"] != configFile {
+		t.Errorf("expected the config file in the event, got %v", fake.events[1])
+	}
+	if len(fake.slack) != 2 || !strings.Contains(fake.slack[0], "required secrets are missing: DB_URL") || !strings.Contains(fake.slack[1], "failed to collect secrets") {
+		t.Errorf("unexpected Slack messages: %q", fake.slack)
+	}
+}
+
+// TestE2E_Notifications_MCP tests that a failure to collect secrets for the MCP proxy is notified before it exits
+func TestE2E_Notifications_MCP(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	fake, server := newFakeNotificationTargets(t)
+	configFile, envFile := writeNotifyConfig(t, tmpDir, server.URL, `mcp:
+  servers:
+    - id: test
+      command: echo
+`)
+	if err := os.Remove(envFile); err != nil {
+		t.Fatalf("Failed to remove .env file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "mcp")
+	cmd.Env = append(os.Environ(), "NOTIFY_TOKEN=notify-token")
+	if output, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("expected sstart mcp to fail, got: %s", output)
+	}
+	if types := fake.eventTypes(); len(types) != 1 || types[0] != "collection_failed" {
+		t.Errorf("expected a collection_failed notification, got %v", types)
+	}
+}
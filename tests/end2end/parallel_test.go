@@ -0,0 +1,83 @@
+package end2end
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_RunParallel tests running several commands with the secrets of one collection
+func TestE2E_RunParallel(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("API_KEY=abc123\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	auditPath := filepath.Join(tmpDir, "audit.jsonl")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+inherit: true
+audit:
+  enabled: true
+  path: ` + auditPath + `
+providers:
+  - kind: dotenv
+    path: ` + envFile + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Run("all succeed", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", configFile, "run", "--parallel", "--",
+			"echo first $API_KEY", "--",
+			"printf", "second\npartial", "--",
+			"echo third >&2")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("sstart run --parallel failed: %v\n%s", err, output)
+		}
+		for _, want := range []string{"[1] first abc123\n", "[2] second\n", "[2] partial\n", "[3] third\n"} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("expected %q in output, got:\n%s", want, output)
+			}
+		}
+
+		// The secrets were collected once for all commands
+		data, err := os.ReadFile(auditPath)
+		if err != nil {
+			t.Fatalf("Failed to read audit log: %v", err)
+		}
+		if lines := strings.Count(string(data), "\n"); lines != 1 {
+			t.Errorf("expected one collection, got %d audit entries:\n%s", lines, data)
+		}
+	})
+
+	t.Run("first failure", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", configFile, "run", "--parallel", "--",
+			"sleep 0.5; exit 4", "--",
+			"exit 3", "--",
+			"sleep 0.2; echo done")
+		output, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("expected exit code 3 of the first failing command, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "[3] done") {
+			t.Errorf("expected the other commands to run to completion, got:\n%s", output)
+		}
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "--config", configFile, "run", "--parallel", "--", "echo one", "--", "--", "echo two")
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "must be separated by '--' and not be empty") {
+			t.Errorf("expected an error for an empty command, got %v: %s", err, output)
+		}
+	})
+}
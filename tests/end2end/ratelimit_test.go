@@ -0,0 +1,81 @@
+package end2end
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestE2E_RateLimits tests spacing the requests to providers of a kind, and the request budget
+func TestE2E_RateLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+
+	var providers strings.Builder
+	for i := 1; i <= 3; i++ {
+		envFile := filepath.Join(tmpDir, fmt.Sprintf("%d.env", i))
+		if err := os.WriteFile(envFile, []byte(fmt.Sprintf("KEY_%d=value%d\n", i, i)), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", envFile, err)
+		}
+		fmt.Fprintf(&providers, "  - kind: dotenv\n    id: env%d\n    path: %s\n", i, envFile)
+	}
+
+	writeConfig := func(rateLimits string) {
+		t.Helper()
+		configYAML := "providers:\n" + providers.String() + rateLimits
+		if err := os.WriteFile(configFile, []byte(configYAML), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	t.Run("rate per kind", func(t *testing.T) {
+		writeConfig("rate_limits:\n  kinds:\n    dotenv: 4/s\n")
+
+		start := time.Now()
+		output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+		if err != nil {
+			t.Fatalf("sstart env failed: %v\n%s", err, output)
+		}
+		// The first request is immediate, the next two wait 250ms each
+		if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+			t.Errorf("Expected the 3 requests to take at least 500ms at 4/s, took %v", elapsed)
+		}
+		for i := 1; i <= 3; i++ {
+			if !strings.Contains(string(output), fmt.Sprintf("KEY_%d", i)) {
+				t.Errorf("Expected KEY_%d in the output, got:\n%s", i, output)
+			}
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		writeConfig("rate_limits:\n  budget: 2\n")
+
+		output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+			t.Fatalf("Expected exit code 5, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "request budget exhausted: rate_limits.budget allows 2 requests per invocation") {
+			t.Errorf("Expected the budget error, got:\n%s", output)
+		}
+	})
+
+	t.Run("invalid rate", func(t *testing.T) {
+		writeConfig("rate_limits:\n  kinds:\n    dotenv: 10/day\n")
+
+		output, err := exec.Command(binaryPath, "--config", configFile, "env").CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("Expected exit code 3, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "rate_limits.kinds.dotenv: invalid rate '10/day'") {
+			t.Errorf("Expected the invalid rate error, got:\n%s", output)
+		}
+	})
+}
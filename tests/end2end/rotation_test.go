@@ -0,0 +1,205 @@
+package end2end
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFakeDopplerAPI starts a Doppler API stand-in serving the given secrets, last changed at changedAt
+func newFakeDopplerAPI(t *testing.T, secrets map[string]string, changedAt time.Time) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v3/configs/config/secrets":
+			response := map[string]map[string]map[string]string{"secrets": {}}
+			for key, value := range secrets {
+				response["secrets"][key] = map[string]string{"raw": value, "computed": value}
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case "/v3/configs/config/logs":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"logs": []map[string]interface{}{{"created_at": changedAt}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestE2E_Rotation_Age tests rotation-age warnings and sstart ls --age against a fake Doppler API
+func TestE2E_Rotation_Age(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	server := newFakeDopplerAPI(t, map[string]string{"API_KEY": "doppler-value"}, time.Now().Add(-120*24*time.Hour))
+
+	envFile := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(envFile, []byte("LOCAL=value\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: doppler
+    id: doppler
+    project: app
+    config: prd
+    api_host: ` + server.URL + `
+  - kind: dotenv
+    id: local
+    path: ` + envFile + `
+rotation:
+  max_age: 90d
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	run := func(t *testing.T, args ...string) (string, string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile}, args...)...)
+		cmd.Env = append(os.Environ(), "DOPPLER_TOKEN=test-token")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sstart %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+		}
+		return string(output), stderr.String()
+	}
+
+	t.Run("warns about old secrets", func(t *testing.T) {
+		_, stderr := run(t, "env")
+		if !strings.Contains(stderr, "secret is due for rotation") || !strings.Contains(stderr, "API_KEY") {
+			t.Errorf("expected a rotation warning for API_KEY, got:\n%s", stderr)
+		}
+		if strings.Contains(stderr, "LOCAL") {
+			t.Errorf("expected no rotation warning for dotenv secrets, got:\n%s", stderr)
+		}
+	})
+
+	t.Run("ls", func(t *testing.T) {
+		output, _ := run(t, "ls")
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected a header and 2 keys, got:\n%s", output)
+		}
+		if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "KEY PROVIDER" {
+			t.Errorf("unexpected header: %q", lines[0])
+		}
+		if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "API_KEY doppler" {
+			t.Errorf("unexpected line: %q", lines[1])
+		}
+		if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "LOCAL local" {
+			t.Errorf("unexpected line: %q", lines[2])
+		}
+		if strings.Contains(output, "doppler-value") {
+			t.Errorf("ls must not print secret values, got:\n%s", output)
+		}
+	})
+
+	t.Run("ls --age", func(t *testing.T) {
+		output, _ := run(t, "ls", "--age")
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected a header and 2 keys, got:\n%s", output)
+		}
+		if !strings.Contains(lines[0], "LAST ROTATED") || !strings.Contains(lines[0], "AGE") {
+			t.Errorf("unexpected header: %q", lines[0])
+		}
+		if !strings.Contains(lines[1], "120d") || !strings.Contains(lines[1], "older than 90d") {
+			t.Errorf("expected API_KEY to be 120d old and flagged, got: %q", lines[1])
+		}
+		if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "LOCAL local - -" {
+			t.Errorf("expected no age for dotenv secrets, got: %q", lines[2])
+		}
+	})
+}
+
+// newFakeVaultKV starts a Vault KV v2 stand-in serving one secret with the given custom_metadata
+func newFakeVaultKV(t *testing.T, path string, data map[string]string, updatedAt time.Time, custom map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/" + path:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": data},
+			})
+		case "/v1/secret/metadata/" + path:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"created_time":    updatedAt.Format(time.RFC3339Nano),
+					"updated_time":    updatedAt.Format(time.RFC3339Nano),
+					"custom_metadata": custom,
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestE2E_Rotation_VaultCustomMetadata tests exposing Vault KV v2 custom_metadata and using it for rotation age
+func TestE2E_Rotation_VaultCustomMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	rotated := time.Now().AddDate(0, 0, -200).Format(time.DateOnly)
+	server := newFakeVaultKV(t, "myapp", map[string]string{"DB_PASSWORD": "secret"}, time.Now(),
+		map[string]string{"owner": "team-db", "rotated_on": rotated})
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+rotation:
+  max_age: 90d
+providers:
+  - kind: vault
+    id: vault
+    address: ` + server.URL + `
+    path: myapp
+    custom_metadata: true
+    rotation_date_field: rotated_on
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "ls", "--age", "--metadata")
+	cmd.Env = append(os.Environ(), "VAULT_TOKEN=test-token")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sstart ls failed: %v\n%s", err, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "METADATA") {
+		t.Fatalf("expected a header with METADATA and 1 key, got:\n%s", output)
+	}
+	for _, want := range []string{"DB_PASSWORD", rotated, "200d", "older than 90d", "owner=team-db rotated_on=" + rotated} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("expected %q in %q", want, lines[1])
+		}
+	}
+	// The rotation date in custom_metadata replaces updated_time for rotation age warnings
+	if !strings.Contains(stderr.String(), "secret is due for rotation") {
+		t.Errorf("expected a rotation warning, got:\n%s", stderr.String())
+	}
+}
@@ -528,3 +528,43 @@ exit %d
 
 	t.Logf("Successfully tested exit code propagation")
 }
+
+// TestE2E_RunDotenvFallback tests that sstart run loads the .env file of a project without a config file
+func TestE2E_RunDotenvFallback(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	env := append(os.Environ(), "SSTART_GLOBAL_CONFIG="+filepath.Join(tmpDir, "missing.yml"))
+
+	// Without a .env file, the missing config is an error
+	cmd := exec.Command(binaryPath, "run", "--", "sh", "-c", "echo $FALLBACK_SECRET")
+	cmd.Dir = projectDir
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected exit code 3 without a config, got %v:\n%s", err, output)
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, ".env"), []byte("FALLBACK_SECRET=from-dotenv\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	cmd = exec.Command(binaryPath, "run", "--", "sh", "-c", "echo $FALLBACK_SECRET")
+	cmd.Dir = projectDir
+	cmd.Env = env
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("sstart run failed: %v\n%s", err, stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != "from-dotenv" {
+		t.Errorf("Expected FALLBACK_SECRET from the .env file, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "No .sstart.yml found, loading secrets from") {
+		t.Errorf("Expected a notice about the .env fallback, got %q", stderr.String())
+	}
+}
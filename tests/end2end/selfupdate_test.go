@@ -0,0 +1,188 @@
+package end2end
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// releaseKey is a minisign key pair, like the one releases are signed with
+type releaseKey struct {
+	id  []byte
+	key ed25519.PrivateKey
+}
+
+func newReleaseKey(t *testing.T) *releaseKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate release key: %v", err)
+	}
+	return &releaseKey{id: []byte("sstartid"), key: key}
+}
+
+// publicKey returns the minisign public key, as passed to minisign -P
+func (k *releaseKey) publicKey() string {
+	return base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), k.id...), k.key.Public().(ed25519.PublicKey)...))
+}
+
+// sign returns a minisign signature of data, prehashed like minisign -S does by default
+func (k *releaseKey) sign(data []byte) []byte {
+	sum := blake2b.Sum512(data)
+	signature := ed25519.Sign(k.key, sum[:])
+	trusted := "sstart release"
+	global := ed25519.Sign(k.key, append(append([]byte{}, signature...), trusted...))
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("ED"), k.id...), signature...)) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+// newFakeReleases serves a GitHub release of sstart whose binary is a shell script printing its
+// version, with checksums signed by key
+func newFakeReleases(t *testing.T, key *releaseKey, tag string, corrupt bool) *httptest.Server {
+	t.Helper()
+	version := strings.TrimPrefix(tag, "v")
+	script := []byte("#!/bin/sh\necho \"sstart version " + tag + "\"\n")
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "./README.md", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("hi"))
+	_ = tw.WriteHeader(&tar.Header{Name: "./sstart", Mode: 0755, Size: int64(len(script)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(script)
+	_ = tw.Close()
+	_ = gz.Close()
+
+	archiveName := fmt.Sprintf("sstart-%s-%s-%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive.Bytes())
+	if corrupt {
+		sum[0] ^= 0xff
+	}
+	checksums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), archiveName)
+	signature := key.sign([]byte(checksums))
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/dirathea/sstart/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"tag_name": tag,
+			"assets": []map[string]string{
+				{"name": archiveName, "browser_download_url": server.URL + "/download/" + archiveName},
+				{"name": "checksums.txt", "browser_download_url": server.URL + "/download/checksums.txt"},
+				{"name": "checksums.txt.minisig", "browser_download_url": server.URL + "/download/checksums.txt.minisig"},
+			},
+		})
+	})
+	mux.HandleFunc("GET /download/"+archiveName, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	})
+	mux.HandleFunc("GET /download/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksums))
+	})
+	mux.HandleFunc("GET /download/checksums.txt.minisig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(signature)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// buildSstartVersion builds sstart with the given version, trusting releases signed with key
+func buildSstartVersion(t *testing.T, tmpDir, version string, key *releaseKey) string {
+	t.Helper()
+	binaryPath := filepath.Join(tmpDir, "sstart")
+	ldflags := "-X github.com/dirathea/sstart/internal/cli.version=" + version + " -X github.com/dirathea/sstart/internal/selfupdate.publicKey=" + key.publicKey()
+	cmd := exec.Command("go", "build", "-ldflags", ldflags, "-o", binaryPath, "../../cmd/sstart")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build sstart: %v\n%s", err, output)
+	}
+	return binaryPath
+}
+
+// TestE2E_SelfUpdate tests checking for and installing a newer release
+func TestE2E_SelfUpdate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("release binaries are not published for Windows")
+	}
+	tmpDir := t.TempDir()
+	key := newReleaseKey(t)
+	binaryPath := buildSstartVersion(t, tmpDir, "1.0.0", key)
+
+	selfUpdate := func(server *httptest.Server, args ...string) (string, error) {
+		cmd := exec.Command(binaryPath, append([]string{"self-update"}, args...)...)
+		cmd.Env = append(os.Environ(), "SSTART_GITHUB_API_URL="+server.URL)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	t.Run("up to date", func(t *testing.T) {
+		output, err := selfUpdate(newFakeReleases(t, key, "v1.0.0", false))
+		if err != nil || !strings.Contains(output, "sstart 1.0.0 is the latest release") {
+			t.Fatalf("expected sstart to be up to date, got %v: %s", err, output)
+		}
+	})
+
+	t.Run("check", func(t *testing.T) {
+		output, err := selfUpdate(newFakeReleases(t, key, "v1.2.0", false), "--check")
+		if err != nil || !strings.Contains(output, "sstart v1.2.0 is available (current: 1.0.0)") {
+			t.Fatalf("expected an available update, got %v: %s", err, output)
+		}
+		if output, _ := exec.Command(binaryPath, "version").CombinedOutput(); !strings.Contains(string(output), "v1.0.0") {
+			t.Fatalf("--check must not replace the binary, got: %s", output)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		output, err := selfUpdate(newFakeReleases(t, key, "v1.2.0", true))
+		if err == nil || !strings.Contains(output, "checksum mismatch") {
+			t.Fatalf("expected a checksum mismatch, got %v: %s", err, output)
+		}
+		if output, _ := exec.Command(binaryPath, "version").CombinedOutput(); !strings.Contains(string(output), "v1.0.0") {
+			t.Fatalf("a corrupt release must not replace the binary, got: %s", output)
+		}
+	})
+
+	t.Run("signed with another key", func(t *testing.T) {
+		output, err := selfUpdate(newFakeReleases(t, newReleaseKey(t), "v1.2.0", false))
+		if err == nil || !strings.Contains(output, "failed to verify checksums.txt") {
+			t.Fatalf("expected the signature check to fail, got %v: %s", err, output)
+		}
+		if output, _ := exec.Command(binaryPath, "version").CombinedOutput(); !strings.Contains(string(output), "v1.0.0") {
+			t.Fatalf("a release signed with another key must not replace the binary, got: %s", output)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		output, err := selfUpdate(newFakeReleases(t, key, "v1.2.0", false))
+		if err != nil || !strings.Contains(output, "from 1.0.0 to v1.2.0") {
+			t.Fatalf("expected sstart to be updated, got %v: %s", err, output)
+		}
+		output2, err := exec.Command(binaryPath, "version").CombinedOutput()
+		if err != nil || strings.TrimSpace(string(output2)) != "sstart version v1.2.0" {
+			t.Fatalf("expected the updated binary, got %v: %s", err, output2)
+		}
+		info, err := os.Stat(binaryPath)
+		if err != nil || info.Mode().Perm()&0100 == 0 {
+			t.Fatalf("expected the updated binary to stay executable, got %v", info.Mode())
+		}
+	})
+}
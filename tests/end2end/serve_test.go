@@ -0,0 +1,139 @@
+package end2end
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestE2E_Serve tests the local HTTP secrets API over TCP and a unix socket
+func TestE2E_Serve(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\nDB_PASSWORD=p@ss\nINTERNAL=hidden\n")
+
+	startServe := func(t *testing.T, env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile, "serve", "--exclude", "INTERNAL"}, args...)...)
+		cmd.Env = append(os.Environ(), env...)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start sstart serve: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+	}
+
+	get := func(t *testing.T, client *http.Client, url, token string) (int, string) {
+		t.Helper()
+		var resp *http.Response
+		var err error
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err = client.Do(req)
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to find a free port: %v", err)
+		}
+		addr := listener.Addr().String()
+		listener.Close()
+
+		startServe(t, []string{"SSTART_SERVE_TOKEN=test-token"}, "--listen", addr)
+		base := "http://" + addr
+
+		if status, _ := get(t, http.DefaultClient, base+"/env", ""); status != http.StatusUnauthorized {
+			t.Errorf("expected 401 without a token, got %d", status)
+		}
+		if status, _ := get(t, http.DefaultClient, base+"/env", "wrong-token"); status != http.StatusUnauthorized {
+			t.Errorf("expected 401 with a wrong token, got %d", status)
+		}
+		if status, body := get(t, http.DefaultClient, base+"/secret/DB_PASSWORD", "test-token"); status != http.StatusOK || body != "p@ss" {
+			t.Errorf("GET /secret/DB_PASSWORD = %d %q", status, body)
+		}
+		if status, _ := get(t, http.DefaultClient, base+"/secret/INTERNAL", "test-token"); status != http.StatusNotFound {
+			t.Errorf("expected excluded keys to be hidden, got %d", status)
+		}
+		want := "{\n  \"API_KEY\": \"abc\",\n  \"DB_PASSWORD\": \"p@ss\"\n}\n"
+		if status, body := get(t, http.DefaultClient, base+"/env?format=json", "test-token"); status != http.StatusOK || body != want {
+			t.Errorf("GET /env?format=json = %d %q, want %q", status, body, want)
+		}
+	})
+
+	t.Run("unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(tmpDir, "serve.sock")
+		startServe(t, nil, "--socket", socketPath)
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		}}
+
+		if status, body := get(t, client, "http://sstart/env", ""); status != http.StatusOK || body != "export API_KEY='abc'\nexport DB_PASSWORD='p@ss'\n" {
+			t.Errorf("GET /env = %d %q", status, body)
+		}
+		if info, err := os.Stat(socketPath); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("expected the socket to be 0600, got %v (%v)", info.Mode().Perm(), err)
+		}
+	})
+
+	t.Run("existing socket path", func(t *testing.T) {
+		serve := func(socketPath string) (string, error) {
+			cmd := exec.Command(binaryPath, "--config", configFile, "serve", "--socket", socketPath)
+			output, err := cmd.CombinedOutput()
+			return string(output), err
+		}
+
+		// The killed server of the previous test left a stale socket, which is replaced
+		socketPath := filepath.Join(tmpDir, "serve.sock")
+		startServe(t, nil, "--socket", socketPath)
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		}}
+		if status, _ := get(t, client, "http://sstart/env", ""); status != http.StatusOK {
+			t.Errorf("expected a stale socket to be replaced, got %d", status)
+		}
+
+		if output, err := serve(socketPath); err == nil || !strings.Contains(output, "already listening") {
+			t.Errorf("expected a socket in use to be kept, got %v: %s", err, output)
+		}
+
+		filePath := filepath.Join(tmpDir, "not-a-socket")
+		if err := os.WriteFile(filePath, []byte("keep me"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if output, err := serve(filePath); err == nil || !strings.Contains(output, "is not a socket") {
+			t.Errorf("expected serve to refuse a file that is not a socket, got %v: %s", err, output)
+		}
+		if data, err := os.ReadFile(filePath); err != nil || string(data) != "keep me" {
+			t.Errorf("expected the file to be kept, got %q (%v)", data, err)
+		}
+	})
+}
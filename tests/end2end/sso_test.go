@@ -2,15 +2,24 @@ package end2end
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dirathea/sstart/internal/cache"
 	"github.com/dirathea/sstart/internal/config"
 	"github.com/dirathea/sstart/internal/oidc"
+	_ "github.com/dirathea/sstart/internal/provider/dotenv"
 	_ "github.com/dirathea/sstart/internal/provider/vault"
 	"github.com/dirathea/sstart/internal/secrets"
+	"github.com/zalando/go-keyring"
 )
 
 // Tests for SSO integration with a real OIDC provider
@@ -138,6 +147,491 @@ func TestE2E_SSO_OIDCClient_TokenStorage(t *testing.T) {
 	t.Logf("Successfully tested token storage functionality")
 }
 
+// TestE2E_SSO_OIDCClient_TokenStorageBackends tests the memory and encrypted file token storage backends
+func TestE2E_SSO_OIDCClient_TokenStorageBackends(t *testing.T) {
+	cfg := &config.OIDCConfig{
+		ClientID: "test-client-id",
+		Issuer:   "https://example.com",
+		Scopes:   []string{"openid"},
+	}
+	testTokens := &oidc.Tokens{
+		AccessToken:  "test-access-token",
+		RefreshToken: "test-refresh-token",
+		IDToken:      "test-id-token",
+		TokenType:    "Bearer",
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		tokenPath := filepath.Join(t.TempDir(), "tokens.json")
+		client, err := oidc.NewClient(cfg, oidc.WithTokenStorage(&config.TokenStorageConfig{
+			Backend: config.TokenStorageMemory,
+			Path:    tokenPath,
+		}))
+		if err != nil {
+			t.Fatalf("Failed to create OIDC client: %v", err)
+		}
+
+		if err := client.SaveTokens(testTokens); err != nil {
+			t.Fatalf("Failed to save tokens: %v", err)
+		}
+		if client.GetStorageBackend() != oidc.StorageBackendMemory {
+			t.Errorf("expected storage backend %q, got %q", oidc.StorageBackendMemory, client.GetStorageBackend())
+		}
+		if _, err := os.Stat(tokenPath); !os.IsNotExist(err) {
+			t.Errorf("memory backend must not write a token file, stat error: %v", err)
+		}
+
+		loaded, err := client.LoadTokens()
+		if err != nil {
+			t.Fatalf("Failed to load tokens: %v", err)
+		}
+		if loaded.RefreshToken != testTokens.RefreshToken {
+			t.Errorf("RefreshToken mismatch: expected '%s', got '%s'", testTokens.RefreshToken, loaded.RefreshToken)
+		}
+
+		// A new client (i.e. a new process) starts without tokens
+		other, err := oidc.NewClient(cfg, oidc.WithTokenStorage(&config.TokenStorageConfig{Backend: config.TokenStorageMemory}))
+		if err != nil {
+			t.Fatalf("Failed to create OIDC client: %v", err)
+		}
+		if other.TokensExist() {
+			t.Error("expected a new memory-backed client to have no tokens")
+		}
+	})
+
+	t.Run("encrypted file", func(t *testing.T) {
+		t.Setenv(oidc.TokenEncryptionKeyEnvVar, "correct horse battery staple")
+		tokenPath := filepath.Join(t.TempDir(), "tokens.json")
+		storageCfg := &config.TokenStorageConfig{
+			Backend: config.TokenStorageFile,
+			Path:    tokenPath,
+			Encrypt: true,
+		}
+		client, err := oidc.NewClient(cfg, oidc.WithTokenStorage(storageCfg))
+		if err != nil {
+			t.Fatalf("Failed to create OIDC client: %v", err)
+		}
+
+		if err := client.SaveTokens(testTokens); err != nil {
+			t.Fatalf("Failed to save tokens: %v", err)
+		}
+		data, err := os.ReadFile(tokenPath)
+		if err != nil {
+			t.Fatalf("Failed to read token file: %v", err)
+		}
+		if strings.Contains(string(data), testTokens.RefreshToken) {
+			t.Error("token file must not contain the plaintext refresh token")
+		}
+
+		loaded, err := client.LoadTokens()
+		if err != nil {
+			t.Fatalf("Failed to load tokens: %v", err)
+		}
+		if loaded.AccessToken != testTokens.AccessToken || loaded.IDToken != testTokens.IDToken {
+			t.Errorf("loaded tokens mismatch: got %+v", loaded)
+		}
+
+		// A different key must not decrypt the file
+		t.Setenv(oidc.TokenEncryptionKeyEnvVar, "wrong key")
+		wrongKeyClient, err := oidc.NewClient(cfg, oidc.WithTokenStorage(storageCfg))
+		if err != nil {
+			t.Fatalf("Failed to create OIDC client: %v", err)
+		}
+		if _, err := wrongKeyClient.LoadTokens(); err == nil {
+			t.Error("expected loading with the wrong encryption key to fail")
+		}
+
+		// Encryption without a key is rejected up front
+		t.Setenv(oidc.TokenEncryptionKeyEnvVar, "")
+		if _, err := oidc.NewClient(cfg, oidc.WithTokenStorage(storageCfg)); err == nil {
+			t.Error("expected NewClient to fail when encryption is enabled without a key")
+		}
+	})
+
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv(oidc.TokenStorageEnvVar, config.TokenStorageMemory)
+		tokenPath := filepath.Join(t.TempDir(), "tokens.json")
+		client, err := oidc.NewClient(cfg, oidc.WithTokenStorage(&config.TokenStorageConfig{
+			Backend: config.TokenStorageFile,
+			Path:    tokenPath,
+		}))
+		if err != nil {
+			t.Fatalf("Failed to create OIDC client: %v", err)
+		}
+		if err := client.SaveTokens(testTokens); err != nil {
+			t.Fatalf("Failed to save tokens: %v", err)
+		}
+		if _, err := os.Stat(tokenPath); !os.IsNotExist(err) {
+			t.Errorf("expected %s to override the file backend, stat error: %v", oidc.TokenStorageEnvVar, err)
+		}
+	})
+}
+
+// TestE2E_SSO_RequireClaims tests that providers with require_claims are gated on the SSO ID token claims
+func TestE2E_SSO_RequireClaims(t *testing.T) {
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	tokenFile := filepath.Join(tmpDir, "tokens.json")
+	if err := os.WriteFile(envFile, []byte("PLATFORM_SECRET=platform-value\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	// Store an ID token for the collector to pick up (the signature is not checked for stored tokens)
+	claims := `{"sub":"alice","email":"alice@example.com","groups":["developers","platform-team"]}`
+	idToken := "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+
+	tests := []struct {
+		name          string
+		requireClaims string
+		errorContains string
+	}{
+		{
+			name:          "group present",
+			requireClaims: "groups: platform-team",
+		},
+		{
+			name:          "all listed values present",
+			requireClaims: "groups: [developers, platform-team]\n      email: alice@example.com",
+		},
+		{
+			name:          "group missing",
+			requireClaims: "groups: security-team",
+			errorContains: `your SSO identity lacks groups "security-team"`,
+		},
+		{
+			name:          "claim missing",
+			requireClaims: "department: platform",
+			errorContains: `your SSO identity has no "department" claim`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(oidc.TokenStorageEnvVar, config.TokenStorageFile)
+			configFile := filepath.Join(tmpDir, ".sstart.yml")
+			configContent := fmt.Sprintf(`
+sso:
+  oidc:
+    clientId: test-client-id
+    issuer: https://example.com
+    scopes: openid
+  tokenStorage:
+    path: %s
+
+providers:
+  - kind: dotenv
+    id: platform
+    path: %s
+    require_claims:
+      %s
+`, tokenFile, envFile, tt.requireClaims)
+			if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			cfg, err := config.Load(configFile)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+
+			client, err := oidc.NewClient(cfg.SSO.OIDC, oidc.WithTokenStorage(cfg.SSO.TokenStorage))
+			if err != nil {
+				t.Fatalf("Failed to create OIDC client: %v", err)
+			}
+			err = client.SaveTokens(&oidc.Tokens{
+				AccessToken: "test-access-token",
+				IDToken:     idToken,
+				TokenType:   "Bearer",
+				Expiry:      time.Now().Add(time.Hour),
+			})
+			if err != nil {
+				t.Fatalf("Failed to save tokens: %v", err)
+			}
+
+			collected, err := secrets.NewCollector(cfg).Collect(context.Background(), nil)
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got: %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+			if collected["PLATFORM_SECRET"] != "platform-value" {
+				t.Errorf("expected PLATFORM_SECRET='platform-value', got '%s'", collected["PLATFORM_SECRET"])
+			}
+		})
+	}
+}
+
+// TestE2E_SSO_SessionExpiry_SilentRefresh tests that a session close to expiry is refreshed before collecting
+func TestE2E_SSO_SessionExpiry_SilentRefresh(t *testing.T) {
+	t.Setenv(oidc.TokenStorageEnvVar, config.TokenStorageFile)
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	tokenFile := filepath.Join(tmpDir, "tokens.json")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(envFile, []byte("APP_SECRET=value\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	refreshed := false
+	server := StartMockOIDCProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		refreshed = true
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "refreshed-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+
+	configContent := fmt.Sprintf(`
+sso:
+  oidc:
+    clientId: test-client-id
+    issuer: %s
+    scopes: openid
+  tokenStorage:
+    path: %s
+
+providers:
+  - kind: dotenv
+    path: %s
+    require_claims:
+      sub: alice
+`, server.URL, tokenFile, envFile)
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	client, err := oidc.NewClient(cfg.SSO.OIDC, oidc.WithTokenStorage(cfg.SSO.TokenStorage))
+	if err != nil {
+		t.Fatalf("Failed to create OIDC client: %v", err)
+	}
+	// Still valid, but inside the session warning window
+	// The provider requires SSO claims, since SSO is only used when a selected provider needs it
+	err = client.SaveTokens(&oidc.Tokens{
+		AccessToken:  "expiring-access-token",
+		IDToken:      "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`)) + ".sig",
+		RefreshToken: "test-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(oidc.SessionWarningThreshold / 2),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save tokens: %v", err)
+	}
+
+	if _, err := secrets.NewCollector(cfg).Collect(context.Background(), nil); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if !refreshed {
+		t.Fatal("expected the expiring session to be refreshed")
+	}
+
+	stored, err := client.LoadTokens()
+	if err != nil {
+		t.Fatalf("Failed to load tokens: %v", err)
+	}
+	if stored.AccessToken != "refreshed-access-token" {
+		t.Errorf("expected stored AccessToken='refreshed-access-token', got '%s'", stored.AccessToken)
+	}
+}
+
+// TestE2E_SSO_SkippedWithoutSSOProviders tests that SSO is not set up when no selected provider uses it
+func TestE2E_SSO_SkippedWithoutSSOProviders(t *testing.T) {
+	t.Setenv(oidc.TokenStorageEnvVar, config.TokenStorageMemory)
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(envFile, []byte("APP_SECRET=value\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	contacted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contacted = true
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	configContent := fmt.Sprintf(`
+sso:
+  oidc:
+    clientId: test-client-id
+    issuer: %s
+    scopes: openid
+
+providers:
+  - kind: dotenv
+    path: %s
+  - kind: vault
+    path: app
+    auth:
+      method: oidc
+`, server.URL, envFile)
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Without stored tokens, SSO would need an interactive login; only the dotenv provider is selected
+	collected, err := secrets.NewCollector(cfg).Collect(context.Background(), []string{"dotenv"})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if collected["APP_SECRET"] != "value" {
+		t.Errorf("expected APP_SECRET='value', got '%s'", collected["APP_SECRET"])
+	}
+	if contacted {
+		t.Error("expected the OIDC issuer not to be contacted")
+	}
+}
+
+// TestE2E_SSO_OIDCClient_AuthParams tests that extra authorization parameters are sent with the client credentials grant
+func TestE2E_SSO_OIDCClient_AuthParams(t *testing.T) {
+	var form map[string][]string
+	server := StartMockOIDCProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "m2m-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+
+	t.Setenv(oidc.SSOSecretEnvVar, "test-client-secret")
+	client, err := oidc.NewClient(&config.OIDCConfig{
+		ClientID:   "test-client-id",
+		Issuer:     server.URL,
+		Scopes:     []string{"openid"},
+		AuthParams: map[string]string{"audience": "https://api.example.com"},
+	}, oidc.WithTokenStorage(&config.TokenStorageConfig{Backend: config.TokenStorageMemory}))
+	if err != nil {
+		t.Fatalf("Failed to create OIDC client: %v", err)
+	}
+
+	if _, err := client.LoginWithClientCredentials(context.Background()); err != nil {
+		t.Fatalf("LoginWithClientCredentials() error = %v", err)
+	}
+	if got := form["audience"]; len(got) != 1 || got[0] != "https://api.example.com" {
+		t.Errorf("expected audience parameter 'https://api.example.com', got %v", got)
+	}
+	if got := form["grant_type"]; len(got) != 1 || got[0] != "client_credentials" {
+		t.Errorf("expected grant_type 'client_credentials', got %v", got)
+	}
+}
+
+// TestE2E_SSO_OIDCClient_RefreshTokenRotation tests that refreshed tokens are persisted,
+// keeping the previous refresh/ID token when the IdP does not return new ones
+func TestE2E_SSO_OIDCClient_RefreshTokenRotation(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokenResponse     map[string]interface{}
+		wantRefreshToken  string
+		wantIDToken       string
+		wantPresentedWith string
+	}{
+		{
+			name: "refresh token rotated",
+			tokenResponse: map[string]interface{}{
+				"access_token":  "new-access-token",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+				"refresh_token": "rotated-refresh-token",
+			},
+			wantRefreshToken:  "rotated-refresh-token",
+			wantIDToken:       "old-id-token",
+			wantPresentedWith: "old-refresh-token",
+		},
+		{
+			name: "refresh token not rotated",
+			tokenResponse: map[string]interface{}{
+				"access_token": "new-access-token",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			},
+			wantRefreshToken:  "old-refresh-token",
+			wantIDToken:       "old-id-token",
+			wantPresentedWith: "old-refresh-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var presented string
+			server := StartMockOIDCProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				presented = r.PostForm.Get("refresh_token")
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(tt.tokenResponse)
+			})
+
+			client, err := oidc.NewClient(&config.OIDCConfig{
+				ClientID: "test-client-id",
+				Issuer:   server.URL,
+				Scopes:   []string{"openid"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create OIDC client: %v", err)
+			}
+			client.SetTokenPath(filepath.Join(t.TempDir(), "tokens.json"))
+			t.Cleanup(func() {
+				_ = client.ClearTokens()
+			})
+
+			// Tokens that expire within the refresh skew must be refreshed proactively
+			err = client.SaveTokens(&oidc.Tokens{
+				AccessToken:  "old-access-token",
+				RefreshToken: "old-refresh-token",
+				IDToken:      "old-id-token",
+				TokenType:    "Bearer",
+				Expiry:       time.Now().Add(10 * time.Second),
+			})
+			if err != nil {
+				t.Fatalf("Failed to save tokens: %v", err)
+			}
+
+			tokens, err := client.GetValidTokens(context.Background())
+			if err != nil {
+				t.Fatalf("GetValidTokens() error = %v", err)
+			}
+			if presented != tt.wantPresentedWith {
+				t.Errorf("refresh request used refresh_token=%q, want %q", presented, tt.wantPresentedWith)
+			}
+			if tokens.AccessToken != "new-access-token" {
+				t.Errorf("AccessToken = %q, want %q", tokens.AccessToken, "new-access-token")
+			}
+
+			stored, err := client.LoadTokens()
+			if err != nil {
+				t.Fatalf("Failed to load tokens: %v", err)
+			}
+			if stored.AccessToken != "new-access-token" {
+				t.Errorf("stored AccessToken = %q, want %q", stored.AccessToken, "new-access-token")
+			}
+			if stored.RefreshToken != tt.wantRefreshToken {
+				t.Errorf("stored RefreshToken = %q, want %q", stored.RefreshToken, tt.wantRefreshToken)
+			}
+			if stored.IDToken != tt.wantIDToken {
+				t.Errorf("stored IDToken = %q, want %q", stored.IDToken, tt.wantIDToken)
+			}
+		})
+	}
+}
+
 // TestE2E_SSO_ClientCredentialsFlow tests the client credentials flow for non-interactive authentication
 // This test requires a confidential client with client_credentials grant type enabled
 func TestE2E_SSO_ClientCredentialsFlow(t *testing.T) {
@@ -386,3 +880,97 @@ providers:
 
 	t.Logf("Successfully collected secrets using custom SSO auth mount with client credentials flow")
 }
+
+// TestE2E_SSO_LogoutPurgesCache tests that the cached secrets fetched under an SSO identity are
+// purged on logout and when its session is invalidated, while other cached secrets are kept
+func TestE2E_SSO_LogoutPurgesCache(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv(oidc.TokenStorageEnvVar, config.TokenStorageFile)
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, ".env")
+	tokenFile := filepath.Join(tmpDir, "tokens.json")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(envFile, []byte("APP_SECRET=value\n"), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	server := StartMockOIDCProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	// Both providers read the same file, but only prod is fetched under the SSO identity
+	configContent := fmt.Sprintf(`
+sso:
+  oidc:
+    clientId: test-client-id
+    issuer: %s
+    scopes: openid
+  tokenStorage:
+    path: %s
+
+cache:
+  enabled: true
+  ttl: 1h
+
+providers:
+  - kind: dotenv
+    id: prod
+    path: %s
+    require_claims:
+      sub: alice
+  - kind: dotenv
+    id: local
+    path: %s
+`, server.URL, tokenFile, envFile, envFile)
+	if err := os.WriteFile(configFile, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	client, err := oidc.NewClient(cfg.SSO.OIDC, oidc.WithTokenStorage(cfg.SSO.TokenStorage))
+	if err != nil {
+		t.Fatalf("Failed to create OIDC client: %v", err)
+	}
+	idToken := "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+server.URL+`","sub":"alice"}`)) + ".sig"
+	login := func(expiry time.Time) {
+		t.Helper()
+		if err := client.SaveTokens(&oidc.Tokens{AccessToken: "access-token", IDToken: idToken, TokenType: "Bearer", Expiry: expiry}); err != nil {
+			t.Fatalf("Failed to save tokens: %v", err)
+		}
+		if _, err := secrets.NewCollector(cfg).Collect(context.Background(), nil); err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+	}
+	cachedEntries := func() int {
+		total, _, _ := cache.New().Stats()
+		return total
+	}
+
+	login(time.Now().Add(time.Hour))
+	if n := cachedEntries(); n != 2 {
+		t.Fatalf("expected 2 cached entries, got %d", n)
+	}
+	loggedIn, purged, err := secrets.NewCollector(cfg).Logout()
+	if err != nil || !loggedIn || purged != 1 {
+		t.Fatalf("Logout() = %v, %d, %v, want true, 1, nil", loggedIn, purged, err)
+	}
+	if client.TokensExist() {
+		t.Error("expected the stored tokens to be removed")
+	}
+	if n := cachedEntries(); n != 1 {
+		t.Errorf("expected the cached secrets of local to be kept, got %d entries", n)
+	}
+
+	// A session that expired without a refresh token is invalidated before logging in again
+	login(time.Now().Add(time.Hour))
+	if err := client.SaveTokens(&oidc.Tokens{AccessToken: "access-token", IDToken: idToken, TokenType: "Bearer", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Failed to save tokens: %v", err)
+	}
+	if _, err := secrets.NewCollector(cfg, secrets.WithNonInteractive(true)).Collect(context.Background(), nil); err == nil {
+		t.Fatal("expected Collect() to fail without a valid SSO session")
+	}
+	if n := cachedEntries(); n != 1 {
+		t.Errorf("expected the cached secrets of the expired session to be purged, got %d entries", n)
+	}
+}
@@ -0,0 +1,119 @@
+package end2end
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_Systemd tests writing secrets as systemd credentials and generating drop-ins
+func TestE2E_Systemd(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=abc\nCERT=\"line1\nline2 100%\"\nINTERNAL=hidden\n")
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile}, args...)...)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("sstart %v failed: %v\n%s", args, err, output)
+		}
+		return string(output)
+	}
+
+	credsDir := filepath.Join(tmpDir, "creds")
+	run(t, "systemd", "credentials", credsDir, "--exclude", "INTERNAL")
+	if info, err := os.Stat(credsDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected the credentials directory to be 0700, got %v (%v)", info.Mode().Perm(), err)
+	}
+	for key, want := range map[string]string{"API_KEY": "abc", "CERT": "line1\nline2 100%"} {
+		path := filepath.Join(credsDir, key)
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != want {
+			t.Errorf("credential %s = %q (%v), want %q", key, data, err, want)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("expected credential %s to be 0600, got %v (%v)", key, info.Mode().Perm(), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(credsDir, "INTERNAL")); !os.IsNotExist(err) {
+		t.Errorf("expected excluded keys not to be written, got %v", err)
+	}
+
+	// Credentials of keys that are no longer collected are removed; directories are kept
+	if err := os.Mkdir(filepath.Join(credsDir, "keep"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	run(t, "systemd", "credentials", credsDir, "--only", "API_KEY")
+	entries, err := os.ReadDir(credsDir)
+	if err != nil {
+		t.Fatalf("Failed to read credentials directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if got := strings.Join(names, ","); got != "API_KEY,keep" {
+		t.Errorf("expected only API_KEY and the directory to be left, got %s", got)
+	}
+
+	want := "# Generated by sstart; do not edit\n[Service]\nLoadCredential=API_KEY:" + filepath.Join(credsDir, "API_KEY") + "\n"
+	if output := run(t, "systemd", "dropin", "--credentials-dir", credsDir, "--only", "API_KEY"); output != want {
+		t.Errorf("unexpected LoadCredential drop-in:\n%s\nwant:\n%s", output, want)
+	}
+
+	want = "# Generated by sstart; do not edit\n[Service]\nSetCredential=API_KEY:abc\nSetCredential=CERT:line1\\nline2 100%%\n"
+	dropin := filepath.Join(tmpDir, "sstart.conf")
+	// An existing drop-in does not keep its mode
+	if err := os.WriteFile(dropin, []byte("stale"), 0644); err != nil {
+		t.Fatalf("Failed to write drop-in: %v", err)
+	}
+	run(t, "systemd", "dropin", "--exclude", "INTERNAL", "--file", dropin)
+	data, err := os.ReadFile(dropin)
+	if err != nil || string(data) != want {
+		t.Errorf("unexpected SetCredential drop-in:\n%s\nwant:\n%s", data, want)
+	}
+	if info, err := os.Stat(dropin); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the drop-in to be 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	// Specifiers in credential paths are escaped
+	percentDir := filepath.Join(tmpDir, "100%")
+	want = "# Generated by sstart; do not edit\n[Service]\nLoadCredential=API_KEY:" + filepath.Join(tmpDir, "100%%", "API_KEY") + "\n"
+	if output := run(t, "systemd", "dropin", "--credentials-dir", percentDir, "--only", "API_KEY"); output != want {
+		t.Errorf("unexpected LoadCredential drop-in:\n%s\nwant:\n%s", output, want)
+	}
+
+	// Invalid unit names are rejected before anything is written
+	for _, unit := range []string{"../app", "app.socket", "app@a/b", "@app"} {
+		output, err := exec.Command(binaryPath, "--config", configFile, "systemd", "dropin", "--unit", unit).CombinedOutput()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+			t.Errorf("expected unit '%s' to be rejected with exit code 2, got %v\n%s", unit, err, output)
+		}
+	}
+}
+
+// TestE2E_Systemd_InvalidCredentialNames tests that keys systemd or unit files would not take
+// as credential names are rejected
+func TestE2E_Systemd_InvalidCredentialNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	for _, key := range []string{"A%B", "A:B", `A\\B`, "A B"} {
+		configYAML := "providers:\n  - kind: env\n    keys:\n      SSTART_TEST_VALUE: \"" + key + "\"\n"
+		if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		cmd := exec.Command(binaryPath, "--config", configFile, "systemd", "dropin")
+		cmd.Env = append(os.Environ(), "SSTART_TEST_VALUE=value")
+		output, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(output), "is not a valid systemd credential name") {
+			t.Errorf("expected key %q to be rejected, got %v\n%s", key, err, output)
+		}
+	}
+}
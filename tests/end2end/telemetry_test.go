@@ -0,0 +1,82 @@
+package end2end
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestE2E_Telemetry_OTLPTraces tests that collection and provider fetch spans are exported over OTLP/HTTP
+func TestE2E_Telemetry_OTLPTraces(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	configFile := writeEnvTestConfig(t, tmpDir, "API_KEY=secret\n")
+
+	var mu sync.Mutex
+	spans := make(map[string]map[string]string)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			t.Errorf("failed to decode OTLP request: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					attrs := make(map[string]string)
+					for _, attr := range span.Attributes {
+						attrs[attr.Key] = attr.Value.GetStringValue()
+					}
+					spans[span.Name] = attrs
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(nil)
+	}))
+	defer collector.Close()
+
+	// Without an endpoint, nothing is exported
+	cmd := exec.Command(binaryPath, "--config", configFile, "env")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	if len(spans) != 0 {
+		t.Fatalf("expected no spans without an OTLP endpoint, got %v", spans)
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "env")
+	cmd.Env = append(os.Environ(),
+		"OTEL_EXPORTER_OTLP_ENDPOINT="+collector.URL,
+		"OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf",
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := spans["sstart.collect"]; !ok {
+		t.Errorf("expected a sstart.collect span, got %v", spans)
+	}
+	fetch, ok := spans["sstart.provider.fetch"]
+	if !ok {
+		t.Fatalf("expected a sstart.provider.fetch span, got %v", spans)
+	}
+	if fetch["sstart.provider.kind"] != "dotenv" || fetch["sstart.provider.id"] != "dotenv" {
+		t.Errorf("unexpected provider fetch attributes: %v", fetch)
+	}
+}
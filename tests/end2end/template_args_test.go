@@ -0,0 +1,106 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_RunTemplateArgs tests rendering references to secrets in the arguments of the command
+func TestE2E_RunTemplateArgs(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	appEnv := filepath.Join(tmpDir, "app.env")
+	dbEnv := filepath.Join(tmpDir, "db.env")
+	if err := os.WriteFile(appEnv, []byte("API_KEY=abc123\nINJECTED=\"x'; echo injected; '\"\nSUBST='$(echo injected) `echo injected` $HOME'\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	if err := os.WriteFile(dbEnv, []byte("PG_DSN=postgres://user:pass@db/app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: dotenv
+    id: app-env
+    path: ` + appEnv + `
+  - kind: dotenv
+    id: db
+    path: ` + dbEnv + `
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(binaryPath, append([]string{"--config", configFile, "run"}, args...)...)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	t.Run("rendered", func(t *testing.T) {
+		output, err := run("--template-args", "--", "/bin/echo", "key={{ .app-env.API_KEY }}", "{{.db.PG_DSN}}")
+		if err != nil {
+			t.Fatalf("sstart run failed: %v\n%s", err, output)
+		}
+		if strings.TrimSpace(output) != "key=abc123 postgres://user:pass@db/app" {
+			t.Errorf("unexpected output: %q", output)
+		}
+	})
+
+	t.Run("shell command", func(t *testing.T) {
+		output, err := run("--template-args", "--parallel", "--", "echo {{ .app-env.INJECTED }}", "--", "/bin/echo", "{{ .app-env.INJECTED }}")
+		if err != nil {
+			t.Fatalf("sstart run failed: %v\n%s", err, output)
+		}
+		for _, want := range []string{"[1] x'; echo injected; '\n", "[2] x'; echo injected; '\n"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "] injected") {
+			t.Errorf("expected secrets rendered into shell commands to be quoted, got:\n%s", output)
+		}
+	})
+
+	t.Run("shell command quoting", func(t *testing.T) {
+		// References within quotes of the command are expanded within them, and never parsed
+		output, err := run("--template-args", "--parallel", "--",
+			`echo "subst={{ .app-env.SUBST }}"`, "--",
+			`echo 'subst={{ .app-env.SUBST }}' {{ .app-env.INJECTED }}`)
+		if err != nil {
+			t.Fatalf("sstart run failed: %v\n%s", err, output)
+		}
+		want := "subst=$(echo injected) `echo injected` $HOME"
+		for _, want := range []string{"[1] " + want + "\n", "[2] " + want + " x'; echo injected; '\n"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("opt-in", func(t *testing.T) {
+		output, err := run("--", "/bin/echo", "{{ .db.PG_DSN }}")
+		if err != nil {
+			t.Fatalf("sstart run failed: %v\n%s", err, output)
+		}
+		if strings.TrimSpace(output) != "{{ .db.PG_DSN }}" {
+			t.Errorf("expected arguments to be left as is without --template-args, got %q", output)
+		}
+	})
+
+	t.Run("missing secret", func(t *testing.T) {
+		for _, arg := range []string{"{{ .db.MISSING }}", "{{ .app-env.MISSING }}"} {
+			output, err := run("--template-args", "--", "/bin/echo", arg)
+			if err == nil || !strings.Contains(output, "failed to render argument 1") {
+				t.Errorf("expected a render error for %s, got %v: %s", arg, err, output)
+			}
+			if strings.Contains(output, "abc123") || strings.Contains(output, "pass@db") {
+				t.Errorf("render errors must not contain secret values: %s", output)
+			}
+		}
+	})
+}
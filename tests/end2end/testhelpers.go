@@ -22,6 +22,13 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// TestMain turns off CI detection, so that tests behave the same locally and when the suite runs in CI
+// Tests of CI mode set the CI environment variables on the commands they run
+func TestMain(m *testing.M) {
+	_ = os.Setenv("SSTART_CI", "0")
+	os.Exit(m.Run())
+}
+
 // LocalStackContainer wraps LocalStack container and its endpoint
 type LocalStackContainer struct {
 	Container *localstack.LocalStackContainer
@@ -0,0 +1,83 @@
+package end2end
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_Validate tests reporting unused key mappings and shadowed keys
+func TestE2E_Validate(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	sharedFile := filepath.Join(tmpDir, "shared.env")
+	localFile := filepath.Join(tmpDir, "local.env")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	if err := os.WriteFile(sharedFile, []byte("DB_PASSWORD=shared\nAPI_URL=https://api.example.com\nLOG_LEVEL=info\n"), 0600); err != nil {
+		t.Fatalf("Failed to write shared.env: %v", err)
+	}
+	if err := os.WriteFile(localFile, []byte("API_URL=http://localhost:8080\nLOG_LEVEL=info\n"), 0600); err != nil {
+		t.Fatalf("Failed to write local.env: %v", err)
+	}
+
+	writeConfig := func(keys string) {
+		t.Helper()
+		configYAML := `
+providers:
+  - kind: dotenv
+    id: shared
+    path: ` + sharedFile + `
+    keys:
+` + keys + `
+  - kind: dotenv
+    id: local
+    path: ` + localFile + `
+`
+		if err := os.WriteFile(configFile, []byte(configYAML), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	// Shadowed keys are reported, but only fail with --strict
+	writeConfig("      DB_PASSWORD: DATABASE_PASSWORD\n      API_URL: ==\n      LOG_LEVEL: ==\n")
+	output, err := exec.Command(binaryPath, "--config", configFile, "validate").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart validate failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"shared    API_URL    shadowed  overridden by provider 'local'\n",
+		"shared    LOG_LEVEL  shadowed  overridden by provider 'local' with the same value\n",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(string(output), "localhost") || strings.Contains(string(output), "api.example.com") {
+		t.Errorf("Expected no values in the output, got:\n%s", output)
+	}
+	output, err = exec.Command(binaryPath, "--config", configFile, "validate", "--strict").CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("Expected --strict to exit with status 1, got %v:\n%s", err, output)
+	}
+
+	// A mapping of a key the provider does not return fails
+	writeConfig("      DB_PASSWD: DATABASE_PASSWORD\n")
+	output, err = exec.Command(binaryPath, "--config", configFile, "validate").CombinedOutput()
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("Expected an unused mapping to exit with status 1, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "DB_PASSWD  unused mapping  the provider returned no 'DB_PASSWD' to map to 'DATABASE_PASSWORD'") {
+		t.Errorf("Expected the unused mapping to be reported, got:\n%s", output)
+	}
+
+	// A clean config
+	writeConfig("      DB_PASSWORD: DATABASE_PASSWORD\n")
+	output, err = exec.Command(binaryPath, "--config", configFile, "validate").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "No issues found") {
+		t.Errorf("Expected no issues, got %v:\n%s", err, output)
+	}
+}
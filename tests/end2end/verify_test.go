@@ -0,0 +1,90 @@
+package end2end
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestE2E_Verify tests checking collected secrets against the contract of the config
+func TestE2E_Verify(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	envFile := filepath.Join(tmpDir, ".env")
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+
+	writeConfig := func(env string) {
+		t.Helper()
+		if err := os.WriteFile(envFile, []byte(env), 0600); err != nil {
+			t.Fatalf("Failed to write env file: %v", err)
+		}
+		configYAML := `
+contract:
+  keys:
+    - name: DATABASE_URL
+      format: url
+    - name: PORT
+      format: port
+    - name: STRIPE_KEY
+      pattern: 'sk_(live|test)_[A-Za-z0-9]+'
+    - name: SENTRY_DSN
+      required: false
+      format: url
+providers:
+  - kind: dotenv
+    path: ` + envFile + `
+`
+		if err := os.WriteFile(configFile, []byte(configYAML), 0600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	// Every key satisfies the contract
+	writeConfig("DATABASE_URL=postgres://app:pw@db:5432/app\nPORT=8080\nSTRIPE_KEY=sk_test_abc123\nDEBUG=1\n")
+	output, err := exec.Command(binaryPath, "--config", configFile, "verify").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart verify failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{"DATABASE_URL  ok", "SENTRY_DSN    ok", "All 4 contract key(s) are satisfied"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// Undeclared keys fail with --strict
+	output, err = exec.Command(binaryPath, "--config", configFile, "verify", "--strict").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "DEBUG         not declared in the contract") {
+		t.Errorf("Expected --strict to fail on DEBUG, got %v:\n%s", err, output)
+	}
+
+	// Broken keys are reported without their values
+	writeConfig("DATABASE_URL=localhost:5432\nPORT=99999\nSTRIPE_KEY=pk_live_secretvalue\nSENTRY_DSN=\n")
+	cmd := exec.Command(binaryPath, "--config", configFile, "verify")
+	output, err = cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit code 1, got %v:\n%s", err, output)
+	}
+	for _, want := range []string{
+		"DATABASE_URL  is not a valid url",
+		"PORT          is not a valid port",
+		"STRIPE_KEY    does not match pattern 'sk_(live|test)_[A-Za-z0-9]+'",
+		"SENTRY_DSN    ok",
+		"3 key(s) do not satisfy the contract",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(string(output), "secretvalue") {
+		t.Errorf("Expected values not to be printed, got:\n%s", output)
+	}
+
+	// Required keys must be present
+	writeConfig("PORT=80\n")
+	output, _ = exec.Command(binaryPath, "--config", configFile, "verify").CombinedOutput()
+	if !strings.Contains(string(output), "DATABASE_URL  missing  -") {
+		t.Errorf("Expected DATABASE_URL to be reported missing, got:\n%s", output)
+	}
+}
@@ -0,0 +1,228 @@
+package end2end
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dirathea/sstart/internal/config"
+	_ "github.com/dirathea/sstart/internal/provider/wasm"
+	"github.com/dirathea/sstart/internal/secrets"
+)
+
+// wasmPluginSource is a provider plugin that returns its config, what it can see of the host, and
+// the responses of the HTTP requests it sends
+const wasmPluginSource = `package main
+
+import (
+	"encoding/json"
+	"os"
+	"unsafe"
+)
+
+//go:wasmimport sstart http_request
+func httpRequest(ptr, length uint32) uint32
+
+//go:wasmimport sstart http_response
+func httpResponse(ptr uint32)
+
+func get(url string) map[string]interface{} {
+	req, _ := json.Marshal(map[string]interface{}{"url": url, "headers": map[string]string{"X-Token": os.Getenv("PLUGIN_TOKEN")}})
+	n := httpRequest(uint32(uintptr(unsafe.Pointer(&req[0]))), uint32(len(req)))
+	resp := make([]byte, n)
+	httpResponse(uint32(uintptr(unsafe.Pointer(&resp[0]))))
+	var result map[string]interface{}
+	_ = json.Unmarshal(resp, &result)
+	return result
+}
+
+func main() {
+	var request struct {
+		ID     string                 ` + "`json:\"id\"`" + `
+		Config map[string]interface{} ` + "`json:\"config\"`" + `
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		panic(err)
+	}
+	if request.Config["fail"] == true {
+		json.NewEncoder(os.Stdout).Encode(map[string]string{"error": "backend is down"})
+		return
+	}
+	if request.Config["hang"] == true {
+		for {
+		}
+	}
+	if request.Config["flood"] == true {
+		chunk := make([]byte, 1<<20)
+		for {
+			os.Stdout.Write(chunk)
+		}
+	}
+
+	secrets := map[string]string{
+		"PLUGIN_ID":   request.ID,
+		"GREETING":    request.Config["greeting"].(string),
+		"SECRET_HOME": os.Getenv("SECRET_HOME"),
+	}
+	if _, err := os.ReadFile("/etc/hostname"); err != nil {
+		secrets["FILE_ACCESS"] = "denied"
+	}
+	allowed := get(request.Config["url"].(string))
+	secrets["HTTP_BODY"], _ = allowed["body"].(string)
+	secrets["HTTP_ERROR"], _ = allowed["error"].(string)
+	denied := get("https://example.com/")
+	secrets["HTTP_DENIED"], _ = denied["error"].(string)
+	json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"secrets": secrets})
+}
+`
+
+// buildWasmPlugin compiles the test plugin to WASI
+func buildWasmPlugin(t *testing.T, dir string) string {
+	t.Helper()
+	source := filepath.Join(dir, "plugin.go")
+	if err := os.WriteFile(source, []byte(wasmPluginSource), 0644); err != nil {
+		t.Fatalf("Failed to write plugin source: %v", err)
+	}
+	pluginPath := filepath.Join(dir, "echo.wasm")
+	cmd := exec.Command("go", "build", "-o", pluginPath, source)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "CGO_ENABLED=0", "GO111MODULE=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build WASM plugin: %v\n%s", err, output)
+	}
+	return pluginPath
+}
+
+// TestE2E_WasmProvider tests fetching secrets from a sandboxed WASM plugin
+func TestE2E_WasmProvider(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+	pluginsDir := filepath.Join(tmpDir, "plugins")
+	if err := os.Mkdir(pluginsDir, 0755); err != nil {
+		t.Fatalf("Failed to create plugins directory: %v", err)
+	}
+	buildWasmPlugin(t, pluginsDir)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// Redirect to the same server under a host name that is not allowed
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/secret", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("token=" + r.Header.Get("X-Token")))
+	}))
+	defer server.Close()
+
+	configFile := filepath.Join(tmpDir, ".sstart.yml")
+	configYAML := `
+providers:
+  - kind: wasm
+    id: redirected
+    plugin: echo
+    config:
+      greeting: hello
+      url: ` + server.URL + `/redirect
+    allowed_hosts: [127.0.0.1]
+  - kind: wasm
+    id: hanging
+    plugin: echo
+    config:
+      hang: true
+    timeout: 2s
+  - kind: wasm
+    id: flooding
+    plugin: echo
+    config:
+      flood: true
+  - kind: wasm
+    id: echo
+    plugin: echo
+    config:
+      greeting: hello
+      url: ` + server.URL + `/secret
+    allowed_env: [PLUGIN_TOKEN]
+    allowed_hosts: [127.0.0.1]
+  - kind: wasm
+    id: broken
+    plugin: ` + filepath.Join(pluginsDir, "echo.wasm") + `
+    config:
+      fail: true
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	env := append(os.Environ(), "SSTART_PLUGINS_DIR="+pluginsDir, "PLUGIN_TOKEN=abc123", "SECRET_HOME=/root", "XDG_CACHE_HOME="+filepath.Join(tmpDir, "cache"))
+
+	cmd := exec.Command(binaryPath, "--config", configFile, "env", "--providers", "echo", "--format", "json")
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		`"PLUGIN_ID": "echo"`,
+		`"GREETING": "hello"`,
+		`"SECRET_HOME": ""`,
+		`"FILE_ACCESS": "denied"`,
+		`"HTTP_BODY": "token=abc123"`,
+		`"HTTP_DENIED": "host 'example.com' is not in allowed_hosts"`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("expected %s in output, got:\n%s", want, output)
+		}
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "env", "--providers", "redirected", "--format", "json")
+	cmd.Env = env
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sstart env failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), `host 'localhost' is not in allowed_hosts`) || !strings.Contains(string(output), `"HTTP_BODY": ""`) {
+		t.Errorf("expected redirects to hosts that are not allowed to fail, got:\n%s", output)
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "env", "--providers", "hanging")
+	cmd.Env = env
+	output, err = cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "plugin did not finish within 2s") {
+		t.Errorf("expected the plugin to time out, got %v: %s", err, output)
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "env", "--providers", "flooding")
+	cmd.Env = env
+	output, err = cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "plugin wrote more than 10 MiB to stdout") {
+		t.Errorf("expected the plugin output to be limited, got %v: %s", err, output)
+	}
+
+	cmd = exec.Command(binaryPath, "--config", configFile, "env", "--providers", "broken")
+	cmd.Env = env
+	output, err = cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "backend is down") {
+		t.Errorf("expected the plugin error, got %v: %s", err, output)
+	}
+
+	// Collecting for another environment, e.g. in the agent, passes its variables to the plugin
+	t.Setenv("SSTART_PLUGINS_DIR", pluginsDir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmpDir, "cache"))
+	t.Setenv("PLUGIN_TOKEN", "agent")
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	collector := secrets.NewCollector(cfg, secrets.WithEnvironment(&config.Environment{Env: []string{"PLUGIN_TOKEN=client"}}))
+	collected, err := collector.Collect(context.Background(), []string{"echo"})
+	if err != nil {
+		t.Fatalf("Failed to collect secrets: %v", err)
+	}
+	if collected["HTTP_BODY"] != "token=client" {
+		t.Errorf("expected the plugin to read PLUGIN_TOKEN of the environment collected for, got %q", collected["HTTP_BODY"])
+	}
+}
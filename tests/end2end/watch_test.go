@@ -0,0 +1,128 @@
+package end2end
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeDoppler serves the Doppler secrets and secrets watch endpoints, with updates sent on demand
+type fakeDoppler struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	updates chan struct{}
+}
+
+func newFakeDoppler(t *testing.T, secrets map[string]string) (*fakeDoppler, *httptest.Server) {
+	t.Helper()
+	fake := &fakeDoppler{secrets: secrets, updates: make(chan struct{}, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v3/configs/config/secrets", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		response := map[string]map[string]map[string]string{"secrets": {}}
+		for key, value := range fake.secrets {
+			response["secrets"][key] = map[string]string{"raw": value, "computed": value}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("GET /v3/configs/config/secrets/watch", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: connected\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-fake.updates:
+			fmt.Fprint(w, "event: secrets.update\ndata: {}\n\n")
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// update changes a secret and notifies watchers
+func (f *fakeDoppler) update(key, value string) {
+	f.mu.Lock()
+	f.secrets[key] = value
+	f.mu.Unlock()
+	f.updates <- struct{}{}
+}
+
+// startWatchedRun starts 'sstart run --watch' with a command appending API_KEY to a file, and returns the file
+func startWatchedRun(t *testing.T, binaryPath, configFile string, env []string, args ...string) string {
+	t.Helper()
+	outFile := filepath.Join(t.TempDir(), "out")
+	args = append([]string{"--config", configFile, "run", "--watch"}, args...)
+	args = append(args, "--", "sh", "-c", `echo "$API_KEY" >> "$0"; exec sleep 60`, outFile)
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start sstart run: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		_ = cmd.Wait()
+	})
+	return outFile
+}
+
+// waitForLines waits until the file holds the given lines
+func waitForLines(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		if string(data) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q in %s, got %q", want, path, data)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestE2E_RunWatch tests restarting the command when secrets change
+func TestE2E_RunWatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	binaryPath := buildSstart(t, tmpDir)
+
+	t.Run("doppler watch stream", func(t *testing.T) {
+		fake, server := newFakeDoppler(t, map[string]string{"API_KEY": "v1"})
+		configFile := filepath.Join(t.TempDir(), ".sstart.yml")
+		configYAML := "providers:\n  - kind: doppler\n    project: app\n    config: dev\n    api_host: " + server.URL + "\n"
+		if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		// A long poll interval, so only the watch stream can trigger the restart
+		outFile := startWatchedRun(t, binaryPath, configFile, []string{"DOPPLER_TOKEN=test-token"}, "--watch-interval", "1h")
+		waitForLines(t, outFile, "v1\n")
+		fake.update("API_KEY", "v2")
+		waitForLines(t, outFile, "v1\nv2\n")
+	})
+
+	t.Run("polling", func(t *testing.T) {
+		configFile := writeEnvTestConfig(t, t.TempDir(), "API_KEY=v1\n")
+		envFile := filepath.Join(filepath.Dir(configFile), ".env")
+
+		outFile := startWatchedRun(t, binaryPath, configFile, nil, "--watch-interval", "200ms")
+		waitForLines(t, outFile, "v1\n")
+		// Unchanged secrets do not restart the command
+		time.Sleep(time.Second)
+		waitForLines(t, outFile, "v1\n")
+		if err := os.WriteFile(envFile, []byte("API_KEY=v2\n"), 0644); err != nil {
+			t.Fatalf("Failed to update .env file: %v", err)
+		}
+		waitForLines(t, outFile, "v1\nv2\n")
+	})
+}